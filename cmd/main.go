@@ -2,30 +2,93 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"localagent/pkg/activity"
 	"localagent/pkg/agent"
+	"localagent/pkg/airquality"
+	"localagent/pkg/audit"
 	"localagent/pkg/bus"
 	"localagent/pkg/channels"
+	"localagent/pkg/channels/discord"
+	emailchannel "localagent/pkg/channels/email"
+	"localagent/pkg/channels/matrix"
+	"localagent/pkg/channels/whatsapp"
 	"localagent/pkg/config"
+	"localagent/pkg/constants"
 	"localagent/pkg/cron"
+	"localagent/pkg/db"
+	"localagent/pkg/dnd"
+	"localagent/pkg/docindex"
+	"localagent/pkg/docsum"
+	"localagent/pkg/doctor"
+	"localagent/pkg/email"
+	"localagent/pkg/eventbridge"
+	"localagent/pkg/federation"
+	"localagent/pkg/flashcards"
+	"localagent/pkg/goals"
 	"localagent/pkg/health"
 	"localagent/pkg/heartbeat"
+	"localagent/pkg/hooks"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/i18n"
+	"localagent/pkg/journal"
+	"localagent/pkg/llmcapture"
 	"localagent/pkg/logger"
+	"localagent/pkg/medications"
+	"localagent/pkg/migrate"
+	"localagent/pkg/mqtt"
+	"localagent/pkg/openai"
+	"localagent/pkg/parcels"
 	"localagent/pkg/providers"
 	"localagent/pkg/proxy"
+	"localagent/pkg/readstate"
+	"localagent/pkg/receipt"
+	"localagent/pkg/recipes"
+	"localagent/pkg/redact"
 	"localagent/pkg/reminder"
+	"localagent/pkg/satellite"
+	"localagent/pkg/session"
+	"localagent/pkg/sleep"
+	"localagent/pkg/sports"
+	"localagent/pkg/state"
+	"localagent/pkg/storage"
+	"localagent/pkg/telemetry"
+	"localagent/pkg/templates"
 	"localagent/pkg/tools"
+	"localagent/pkg/transcript"
+	"localagent/pkg/transit"
+	"localagent/pkg/travel"
+	"localagent/pkg/upcoming"
+	"localagent/pkg/vault"
+	"localagent/pkg/watch"
 	"localagent/pkg/webchat"
+	"localagent/pkg/when"
+	"localagent/pkg/worldclock"
 )
 
 func main() {
+	if err := selectProfile(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 	if len(os.Args) < 2 {
 		printHelp()
 		os.Exit(1)
@@ -40,6 +103,24 @@ func main() {
 		gatewayCmd()
 	case "status":
 		statusCmd()
+	case "proxy":
+		proxyCmd()
+	case "audit":
+		auditCmd()
+	case "encrypt":
+		encryptCmd()
+	case "migrate":
+		migrateCmd()
+	case "doctor":
+		doctorCmd()
+	case "profiles":
+		profilesCmd()
+	case "export":
+		exportCmd()
+	case "sessions":
+		sessionsCmd()
+	case "docs":
+		docsCmd()
 	case "version", "--version", "-v":
 		fmt.Printf("localagent %s\n", version)
 	default:
@@ -59,17 +140,65 @@ func printHelp() {
 	fmt.Println("  onboard     Initialize configuration and workspace")
 	fmt.Println("  agent       Interact with the agent directly")
 	fmt.Println("  gateway     Start localagent gateway (channels, heartbeat, health)")
-	fmt.Println("  status      Show localagent status")
+	fmt.Println("  status      Show localagent status (--proxy for whitelist and denied requests)")
+	fmt.Println("  proxy       Manage the egress proxy whitelist (list, add, remove)")
+	fmt.Println("  audit       Show actions the agent took (--since, --tool, --action, --session, -n, --json, prune)")
+	fmt.Println("  encrypt     Encrypt existing sessions, memory and cron files (requires encryption.enabled)")
+	fmt.Println("  migrate     Upgrade on-disk data formats (--dry-run to list pending migrations)")
+	fmt.Println("  doctor      Check config, provider, services, workspace, ports and clock")
+	fmt.Println("  profiles    List profiles")
+	fmt.Println("  export      Export a session as markdown or HTML (--format, -o; no session lists them)")
+	fmt.Println("  sessions    Manage stored sessions (reindex)")
+	fmt.Println("  docs        Manage the docs_qa document index (refresh, status)")
 	fmt.Println("  version     Show version information")
+	fmt.Println()
+	fmt.Println("Global flags:")
+	fmt.Printf("  --profile NAME  Use ~/.localagent/profiles/NAME (or set %s)\n", config.ProfileEnv)
+}
+
+// profile is the selected profile, "" for the default one in ~/.localagent.
+var profile string
+
+// selectProfile takes --profile NAME or --profile=NAME out of os.Args so
+// it works before or after the command, falling back to LOCALAGENT_PROFILE.
+func selectProfile() error {
+	profile = os.Getenv(config.ProfileEnv)
+	args := os.Args[:1]
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		switch {
+		case arg == "--profile":
+			if i+1 >= len(os.Args) {
+				return fmt.Errorf("--profile requires a name")
+			}
+			i++
+			profile = os.Args[i]
+		case strings.HasPrefix(arg, "--profile="):
+			profile = strings.TrimPrefix(arg, "--profile=")
+		default:
+			args = append(args, arg)
+		}
+	}
+	os.Args = args
+	if profile == "" {
+		return nil
+	}
+	return config.ValidateProfileName(profile)
 }
 
 func getConfigPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".localagent", "config.json")
+	return config.ConfigPath(profile)
 }
 
 func loadConfig() (*config.Config, error) {
-	return config.LoadConfig(getConfigPath())
+	cfg, err := config.LoadConfig(getConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	if err := i18n.SetLocale(cfg.Agents.Defaults.Locale); err != nil {
+		logger.Warn("%v", err)
+	}
+	return cfg, nil
 }
 
 func onboardCmd() {
@@ -86,7 +215,7 @@ func onboardCmd() {
 		}
 	}
 
-	cfg := config.DefaultConfig()
+	cfg := config.DefaultProfileConfig(profile)
 	if err := config.SaveConfig(configPath, cfg); err != nil {
 		fmt.Printf("Error saving config: %v\n", err)
 		os.Exit(1)
@@ -98,7 +227,30 @@ func onboardCmd() {
 	fmt.Println("localagent is ready!")
 	fmt.Println("\nNext steps:")
 	fmt.Println("  1. Edit config:", configPath)
-	fmt.Println("  2. Chat: localagent agent -m \"Hello!\"")
+	if profile == "" {
+		fmt.Println("  2. Chat: localagent agent -m \"Hello!\"")
+		return
+	}
+	fmt.Printf("  2. Chat: localagent --profile %s agent -m \"Hello!\"\n", profile)
+	fmt.Println("  3. To run its gateway alongside other profiles, give it its own gateway.port and webchat.port")
+}
+
+func profilesCmd() {
+	names, err := config.Profiles()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	mark := func(name string) string {
+		if name == profile {
+			return "*"
+		}
+		return " "
+	}
+	fmt.Printf("%s (default)  %s\n", mark(""), config.BaseDir())
+	for _, name := range names {
+		fmt.Printf("%s %-10s %s\n", mark(name), name, config.ProfileDir(name))
+	}
 }
 
 func agentCmd() {
@@ -132,14 +284,19 @@ func agentCmd() {
 	p := startProxy(cfg)
 	defer p.Stop(context.Background())
 
-	provider := providers.NewHTTPProvider(
-		cfg.Provider.ResolveAPIKey(),
-		cfg.Provider.APIBase,
-		cfg.Provider.Proxy,
-	)
+	redactor := setupRedaction(cfg)
+	setupEncryption(cfg)
+	runMigrations(cfg)
+	stopTelemetry := setupTelemetry(cfg)
+	defer stopTelemetry()
+	provider := newProvider(cfg, redactor)
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	agentLoop.SetModelRoutes(newModelRoutes(cfg, redactor))
+	agentLoop.SetRedactor(redactor)
+	hookRunner := hooks.New(cfg.Hooks, cfg.WorkspacePath())
+	agentLoop.SetHooks(hookRunner)
 
 	// Add tool-declared domains to proxy whitelist
 	p.Whitelist().Add(agentLoop.GetToolDomains()...)
@@ -148,15 +305,14 @@ func agentCmd() {
 	logger.Info("agent initialized: tools=%d", startupInfo["tools"].(map[string]any)["count"])
 
 	if message != "" {
-		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, message, sessionKey)
+		response, err := processInterruptible(agentLoop, message, sessionKey)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Println(i18n.T("cli.error", err))
 			os.Exit(1)
 		}
 		fmt.Println(response)
 	} else {
-		fmt.Println("localagent interactive mode (type 'exit' to quit)")
+		fmt.Println(i18n.T("cli.interactive"))
 		interactiveMode(agentLoop, sessionKey)
 	}
 }
@@ -167,7 +323,7 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 		fmt.Print("> ")
 		line, err := reader.ReadString('\n')
 		if err != nil {
-			fmt.Println("\nGoodbye!")
+			fmt.Println("\n" + i18n.T("cli.goodbye"))
 			return
 		}
 
@@ -176,20 +332,35 @@ func interactiveMode(agentLoop *agent.AgentLoop, sessionKey string) {
 			continue
 		}
 		if input == "exit" || input == "quit" {
-			fmt.Println("Goodbye!")
+			fmt.Println(i18n.T("cli.goodbye"))
 			return
 		}
 
-		ctx := context.Background()
-		response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
+		response, err := processInterruptible(agentLoop, input, sessionKey)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintln(os.Stderr, i18n.T("cli.error", err))
 			continue
 		}
 		fmt.Println(response)
 	}
 }
 
+// processInterruptible runs a message through the agent, cancelling it on
+// Ctrl+C instead of exiting. Partial output is returned with a marker.
+func processInterruptible(agentLoop *agent.AgentLoop, input, sessionKey string) (string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	response, err := agentLoop.ProcessDirect(ctx, input, sessionKey)
+	if errors.Is(err, agent.ErrCancelled) {
+		if response == "" {
+			return i18n.T("cli.cancelled"), nil
+		}
+		return response + "\n" + i18n.T("cli.cancelled"), nil
+	}
+	return response, err
+}
+
 func gatewayCmd() {
 	args := os.Args[2:]
 	for _, arg := range args {
@@ -207,14 +378,21 @@ func gatewayCmd() {
 
 	p := startProxy(cfg)
 
-	provider := providers.NewHTTPProvider(
-		cfg.Provider.ResolveAPIKey(),
-		cfg.Provider.APIBase,
-		cfg.Provider.Proxy,
-	)
+	redactor := setupRedaction(cfg)
+	setupEncryption(cfg)
+	runMigrations(cfg)
+	stopTelemetry := setupTelemetry(cfg)
+	defer stopTelemetry()
+	provider := newProvider(cfg, redactor)
 
 	msgBus := bus.NewMessageBus()
 	agentLoop := agent.NewAgentLoop(cfg, msgBus, provider)
+	agentLoop.SetModelRoutes(newModelRoutes(cfg, redactor))
+	agentLoop.SetRedactor(redactor)
+	hookRunner := hooks.New(cfg.Hooks, cfg.WorkspacePath())
+	agentLoop.SetHooks(hookRunner)
+	readTracker := readstate.NewTracker(cfg.WorkspacePath())
+	agentLoop.SetReadTracker(readTracker)
 
 	// Add tool-declared domains to proxy whitelist
 	p.Whitelist().Add(agentLoop.GetToolDomains()...)
@@ -225,7 +403,21 @@ func gatewayCmd() {
 	fmt.Printf("Agent: tools=%d skills=%d/%d\n", toolsInfo["count"], skillsInfo["available"], skillsInfo["total"])
 
 	eventQueue := heartbeat.NewEventQueue()
-	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), eventQueue)
+	eventQueue.SetMaxSize(cfg.Heartbeat.MaxQueuedEvents)
+	focus := dnd.NewFocus(cfg.WorkspacePath())
+	agentLoop.RegisterTool(tools.NewFocusTool(focus))
+	dndOutbox := setupDoNotDisturb(cfg, msgBus, focus)
+	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), eventQueue, dndOutbox)
+	agentLoop.RegisterTool(tools.NewTimerTool(cronService, cfg.WorkspacePath()))
+	setupUpcoming(cfg, agentLoop, cronService)
+	places := worldclock.Places(cfg.Places)
+	agentLoop.RegisterTool(tools.NewWorldTimeTool(places))
+	if len(places) > 0 {
+		agentLoop.SetWorldClock(func() string {
+			now := when.Now()
+			return worldclock.Note(places, now, now.Location())
+		})
+	}
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -235,6 +427,8 @@ func gatewayCmd() {
 	)
 	heartbeatService.SetBus(msgBus)
 	heartbeatService.SetEventQueue(eventQueue)
+	heartbeatService.SetReadTracker(readTracker)
+	heartbeatService.SetBusyCheck(dndOutbox)
 	if ah := cfg.Heartbeat.ActiveHours; ah != nil {
 		heartbeatService.SetActiveHours(&heartbeat.ActiveHours{
 			Start:    ah.Start,
@@ -242,7 +436,48 @@ func gatewayCmd() {
 			Timezone: ah.Timezone,
 		})
 	}
+	if idleWork := agentLoop.IdleScheduler(); idleWork != nil {
+		idleWork.SetActive(heartbeatService.Active)
+	}
+	calendarWatcher := setupCalendarReminders(cfg, eventQueue)
+	diskWatcher := setupDiskWatcher(cfg, eventQueue)
+	journalScheduler := setupJournal(cfg, agentLoop, provider)
+	goalService := goals.NewService(agentLoop.GetTodoService().DB())
+	goalService.SetTodoService(agentLoop.GetTodoService())
+	agentLoop.RegisterTool(tools.NewGoalsTool(goalService, cronService, journal.NewStore(cfg.WorkspacePath())))
+	flashcardWatcher := setupFlashcards(cfg, agentLoop, eventQueue)
+	recipeService := recipes.NewService(agentLoop.GetTodoService().DB())
+	recipeService.SetTodoService(agentLoop.GetTodoService())
+	agentLoop.RegisterTool(tools.NewRecipesTool(recipeService))
+	agentLoop.RegisterTool(tools.NewMealPlanTool(recipeService))
+	setupDocsQA(cfg, agentLoop)
+	summarizeModel := cfg.Tools.Summarize.Model
+	if summarizeModel == "" {
+		summarizeModel = cfg.Agents.Defaults.Model
+	}
+	summarizer := docsum.New(cfg.WorkspacePath(), provider, summarizeModel, cfg.Tools.Summarize.ChunkTokens)
+	agentLoop.RegisterTool(tools.NewSummarizeDocumentTool(cfg.WorkspacePath(), summarizer, cfg.Tools.PDF.URL, cfg.Tools.PDF.ResolveAPIKey()))
 	sessions := agentLoop.GetSessionManager()
+	receiptModel := cfg.Tools.Receipt.Model
+	if receiptModel == "" {
+		receiptModel = cfg.Agents.Defaults.Model
+	}
+	agentLoop.RegisterTool(tools.NewParseReceiptTool(receipt.New(provider, receiptModel), sessions, cfg.WorkspacePath()))
+	medicationScheduler := setupMedications(cfg, agentLoop, msgBus)
+	sleepWatcher := setupSleep(cfg, agentLoop, heartbeatService)
+	travelMode := setupTravel(cfg, agentLoop, heartbeatService, cronService)
+	fileWatchers := setupWatchers(cfg, agentLoop, msgBus, eventQueue)
+	setupEmailTriage(cfg, agentLoop, heartbeatService)
+	parcelWatcher := setupParcels(cfg, agentLoop, eventQueue)
+	sportsWatcher := setupSports(cfg, agentLoop, eventQueue)
+	setupTransit(cfg, agentLoop, heartbeatService)
+	setupAirQuality(cfg, agentLoop, heartbeatService)
+	if cfg.Tools.Screenshot.Enabled {
+		agentLoop.RegisterTool(tools.NewScreenshotTool(cfg.Tools.Screenshot, webchat.MediaDir(cfg.DataDir())))
+	}
+	if cfg.Tools.Clipboard.Enabled {
+		agentLoop.RegisterTool(tools.NewClipboardTool(cfg.Tools.Clipboard))
+	}
 	heartbeatService.SetSessionManager(sessions)
 	heartbeatService.SetHandler(func(prompt, channel, chatID string, isCronEvent bool) *tools.ToolResult {
 		if channel == "" || chatID == "" {
@@ -281,15 +516,92 @@ func gatewayCmd() {
 		fmt.Printf("Error creating channel manager: %v\n", err)
 		os.Exit(1)
 	}
+	channelManager.SetReadTracker(readTracker)
+	channelManager.SetHooks(hookRunner)
 
 	webCh := webchat.NewWebChatChannel(&cfg.WebChat, msgBus, cfg.DataDir(), cfg.Tools.STT, cfg.Tools.TTS, cfg.Tools.Image)
 	webCh.SetSessionManager(agentLoop.GetSessionManager())
 	webCh.SetTodoService(agentLoop.GetTodoService())
+	webCh.SetMediaRetention(agentLoop.GetMediaRetention())
+	webCh.SetCanceller(agentLoop.Cancel)
+	webCh.SetTemplates(templates.NewStore(filepath.Join(cfg.WorkspacePath(), "templates")))
+	webCh.SetCronService(cronService)
+	webCh.SetToolLister(agentLoop.GetTools)
+	webCh.SetExportRedactor(redactor.String)
+	webCh.SetReadTracker(readTracker)
+	webCh.SetFocus(focus)
+	if capture := llmCapture(cfg, redactor); capture != nil {
+		webCh.SetLLMCapture(capture)
+		fmt.Printf("LLM capture: %s\n", capture.Dir())
+	}
+	webCh.SetStorage(cfg.Storage.ImageJobsMaxBytes(), func() storage.Usage { return scanStorage(cfg) })
 	agentLoop.GetTodoService().SetListener(webCh.BroadcastTaskEvent)
 	agentLoop.GetTodoService().SetBlockListener(webCh.BroadcastBlockEvent)
 	agentLoop.GetTodoService().SetLinkListener(webCh.BroadcastLinkEvent)
+	commands := setupCommands(agentLoop, cronService, focus)
+	setupReminderShortcut(agentLoop, cronService)
+	allowlist := setupAllowlist(cfg, agentLoop, msgBus, commands)
+	agentLoop.SetCommandHelp(commands.Help)
+	webCh.SetCommands(commands)
 	channelManager.RegisterChannel("web", webCh)
+	if cfg.MQTT.URL != "" {
+		if mqttCh, err := mqtt.NewChannel(cfg.MQTT, msgBus); err != nil {
+			logger.Error("mqtt channel disabled: %v", err)
+		} else {
+			mqttCh.SetCommands(commands)
+			mqttCh.SetAllowlist(allowlist)
+			channelManager.RegisterChannel("mqtt", mqttCh)
+		}
+	}
+	if cfg.Discord.TokenEnv != "" {
+		if discordCh, err := discord.NewChannel(cfg.Discord, msgBus, discord.MediaDir(cfg.DataDir())); err != nil {
+			logger.Error("discord channel disabled: %v", err)
+		} else {
+			discordCh.SetMediaRetention(agentLoop.GetMediaRetention())
+			discordCh.SetCommands(commands)
+			discordCh.SetAllowlist(allowlist)
+			channelManager.RegisterChannel("discord", discordCh)
+		}
+	}
+	if cfg.Matrix.Homeserver != "" {
+		if matrixCh, err := matrix.NewChannel(cfg.Matrix, msgBus, matrix.MediaDir(cfg.WorkspacePath())); err != nil {
+			logger.Error("matrix channel disabled: %v", err)
+		} else {
+			matrixCh.SetMediaRetention(agentLoop.GetMediaRetention())
+			matrixCh.SetCommands(commands)
+			matrixCh.SetAllowlist(allowlist)
+			channelManager.RegisterChannel("matrix", matrixCh)
+		}
+	}
+	if cfg.EmailChannel.IMAPHost != "" {
+		if emailCh, err := emailchannel.NewChannel(cfg.EmailChannel, msgBus, cfg.WorkspacePath()); err != nil {
+			logger.Error("email channel disabled: %v", err)
+		} else {
+			emailCh.SetMediaRetention(agentLoop.GetMediaRetention())
+			emailCh.SetCommands(commands)
+			emailCh.SetAllowlist(allowlist)
+			channelManager.RegisterChannel("email", emailCh)
+		}
+	}
+	var whatsappCh *whatsapp.Channel
+	if cfg.WhatsApp.PhoneNumberID != "" {
+		if ch, err := whatsapp.NewChannel(cfg.WhatsApp, msgBus, cfg.WorkspacePath()); err != nil {
+			logger.Error("whatsapp channel disabled: %v", err)
+		} else {
+			ch.SetMediaRetention(agentLoop.GetMediaRetention())
+			ch.SetCommands(commands)
+			ch.SetAllowlist(allowlist)
+			channelManager.RegisterChannel("whatsapp", ch)
+			whatsappCh = ch
+		}
+	}
 	agentLoop.SetActivityEmitter(webCh)
+	agentLoop.SetStreamSink(webCh.StreamDelta)
+	eventBridge := setupBridge(cfg, redactor)
+	if eventBridge != nil {
+		msgBus.AddObserver(eventBridge)
+		agentLoop.SetActivityEmitter(activity.Multi{webCh, eventBridge})
+	}
 
 	enabledChannels := channelManager.GetEnabledChannels()
 	if len(enabledChannels) > 0 {
@@ -312,6 +624,24 @@ func gatewayCmd() {
 		resp.Body.Close()
 		return resp.StatusCode < 500, fmt.Sprintf("status %d", resp.StatusCode)
 	})
+	healthServer.Handle("/proxy/", proxy.AdminHandler(p, func(add, remove string) error {
+		if add != "" {
+			cfg.AddAllowedDomain(add)
+		} else {
+			cfg.RemoveAllowedDomain(remove)
+		}
+		return config.SaveConfig(getConfigPath(), cfg)
+	}))
+	healthServer.Handle("/allowlist/", channels.AllowlistHandler(allowlist))
+	if whatsappCh != nil {
+		healthServer.Handle("/whatsapp/", whatsappCh)
+	}
+	if slices.ContainsFunc(cfg.Federation.Peers, func(p config.PeerConfig) bool { return p.Accept }) {
+		healthServer.Handle("/federation/", federation.Handler(cfg.Federation.Peers, remoteTaskRunner(agentLoop)))
+	}
+	setupOpenAIAPI(cfg, healthServer, agentLoop)
+	setupSatelliteAPI(cfg, healthServer, agentLoop)
+	setupRuntimeStats(ctx, cfg, healthServer, msgBus)
 	go func() {
 		if err := healthServer.StartContext(ctx); err != nil && err != http.ErrServerClosed {
 			logger.Error("health server error: %v", err)
@@ -326,9 +656,39 @@ func gatewayCmd() {
 		fmt.Printf("Error starting heartbeat service: %v\n", err)
 	}
 
+	if calendarWatcher != nil {
+		calendarWatcher.Start()
+	}
+	if diskWatcher != nil {
+		diskWatcher.Start()
+	}
+	if journalScheduler != nil {
+		journalScheduler.Start()
+	}
+	if flashcardWatcher != nil {
+		flashcardWatcher.Start()
+	}
+	medicationScheduler.Start()
+	if sleepWatcher != nil {
+		sleepWatcher.Start()
+	}
+	travelMode.Start()
+	for _, w := range fileWatchers {
+		w.Start()
+	}
+	if parcelWatcher != nil {
+		parcelWatcher.Start()
+	}
+	if sportsWatcher != nil {
+		sportsWatcher.Start()
+	}
+
 	if err := channelManager.StartAll(ctx); err != nil {
 		fmt.Printf("Error starting channels: %v\n", err)
 	}
+	if eventBridge != nil {
+		eventBridge.Start(ctx)
+	}
 
 	var reminderService *reminder.Service
 	if pm := webCh.GetPushManager(); pm != nil {
@@ -353,10 +713,40 @@ func gatewayCmd() {
 	if reminderService != nil {
 		reminderService.Stop()
 	}
+	if calendarWatcher != nil {
+		calendarWatcher.Stop()
+	}
+	if diskWatcher != nil {
+		diskWatcher.Stop()
+	}
+	if journalScheduler != nil {
+		journalScheduler.Stop()
+	}
+	if flashcardWatcher != nil {
+		flashcardWatcher.Stop()
+	}
+	medicationScheduler.Stop()
+	if sleepWatcher != nil {
+		sleepWatcher.Stop()
+	}
+	travelMode.Stop()
+	for _, w := range fileWatchers {
+		w.Stop()
+	}
+	if parcelWatcher != nil {
+		parcelWatcher.Stop()
+	}
+	if sportsWatcher != nil {
+		sportsWatcher.Stop()
+	}
 	heartbeatService.Stop()
 	cronService.Stop()
+	dndOutbox.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	if eventBridge != nil {
+		eventBridge.Stop()
+	}
 	p.Stop(context.Background())
 	fmt.Println("Gateway stopped")
 }
@@ -372,6 +762,9 @@ func statusCmd() {
 
 	fmt.Printf("localagent v%s\n\n", version)
 
+	if profile != "" {
+		fmt.Println("Profile:", profile)
+	}
 	if _, err := os.Stat(configPath); err == nil {
 		fmt.Println("Config:", configPath)
 	} else {
@@ -394,6 +787,266 @@ func statusCmd() {
 	} else {
 		fmt.Println("API Key: not set")
 	}
+
+	printStorageStatus(cfg)
+
+	if slices.Contains(os.Args[2:], "--proxy") {
+		printProxyStatus(cfg)
+	}
+}
+
+func printStorageStatus(cfg *config.Config) {
+	u := scanStorage(cfg)
+	fmt.Printf("\nStorage: %s", storage.FormatBytes(u.Total))
+	if free := u.Disk.FreePercent(); free >= 0 {
+		fmt.Printf(" (disk %s free of %s, %.1f%%)", storage.FormatBytes(int64(u.Disk.Free)), storage.FormatBytes(int64(u.Disk.Total)), free)
+	}
+	fmt.Println()
+	for _, c := range u.Categories {
+		fmt.Printf("  %-11s %10s  %d files\n", c.Name, storage.FormatBytes(c.Bytes), c.Files)
+	}
+}
+
+func printProxyStatus(cfg *config.Config) {
+	fmt.Println("\nProxy whitelist:")
+	var patterns []string
+	var body struct {
+		Patterns []string `json:"patterns"`
+	}
+	if err := gatewayAdminRequest(cfg, http.MethodGet, "/proxy/whitelist", nil, &body); err == nil {
+		patterns = body.Patterns
+	} else {
+		fmt.Println("  (gateway not running, showing configured allowed_domains)")
+		patterns = cfg.AllowedDomains
+	}
+	for _, p := range patterns {
+		fmt.Println(" ", p)
+	}
+
+	denied, err := proxy.ReadAuditFile(proxyAuditPath(cfg), 20)
+	if err != nil {
+		fmt.Printf("\nDenied requests: error reading audit log: %v\n", err)
+		return
+	}
+	fmt.Printf("\nRecent denied requests (%d):\n", len(denied))
+	for _, d := range denied {
+		tool := d.Tool
+		if tool == "" {
+			tool = "unknown"
+		}
+		reason := d.Reason
+		if reason == "" {
+			reason = "whitelist"
+		}
+		fmt.Printf("  %s  %-7s %s%s  tool=%s reason=%s\n", d.Time.Local().Format("2006-01-02 15:04:05"), d.Method, d.Host, d.Path, tool, reason)
+	}
+}
+
+func proxyCmd() {
+	args := os.Args[2:]
+	if len(args) == 0 {
+		fmt.Println("Usage: localagent proxy <list|add|remove> [pattern]")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var body struct {
+		Patterns []string `json:"patterns"`
+	}
+
+	switch args[0] {
+	case "list":
+		if err := gatewayAdminRequest(cfg, http.MethodGet, "/proxy/whitelist", nil, &body); err != nil {
+			body.Patterns = cfg.AllowedDomains
+		}
+		for _, p := range body.Patterns {
+			fmt.Println(p)
+		}
+	case "add", "remove":
+		if len(args) < 2 {
+			fmt.Printf("Usage: localagent proxy %s <pattern>\n", args[0])
+			os.Exit(1)
+		}
+		pattern := args[1]
+
+		// Prefer the running gateway so the change applies immediately;
+		// it persists to config itself.
+		var apiErr error
+		if args[0] == "add" {
+			apiErr = gatewayAdminRequest(cfg, http.MethodPost, "/proxy/whitelist", map[string]string{"pattern": pattern}, &body)
+		} else {
+			apiErr = gatewayAdminRequest(cfg, http.MethodDelete, "/proxy/whitelist?pattern="+url.QueryEscape(pattern), nil, &body)
+		}
+		if apiErr == nil {
+			fmt.Printf("Whitelist updated: %s %s\n", args[0], pattern)
+			return
+		}
+
+		if args[0] == "add" {
+			cfg.AddAllowedDomain(pattern)
+		} else if !cfg.RemoveAllowedDomain(pattern) {
+			fmt.Printf("Pattern not found in allowed_domains: %s\n", pattern)
+			os.Exit(1)
+		}
+		if err := config.SaveConfig(getConfigPath(), cfg); err != nil {
+			fmt.Printf("Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Config updated: %s %s (applies on next start)\n", args[0], pattern)
+	default:
+		fmt.Printf("Unknown proxy command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func auditCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	args := os.Args[2:]
+	if len(args) > 0 && args[0] == "prune" {
+		if err := audit.NewLog(cfg.AuditPath(), cfg.Audit.Retention()).Prune(); err != nil {
+			fmt.Printf("Error pruning audit log: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Audit log pruned")
+		return
+	}
+
+	filter := audit.Filter{Limit: 50}
+	asJSON := false
+	for i := 0; i < len(args); i++ {
+		value := func() string {
+			if i+1 >= len(args) {
+				fmt.Printf("Missing value for %s\n", args[i])
+				os.Exit(1)
+			}
+			i++
+			return args[i]
+		}
+		switch args[i] {
+		case "--since":
+			d, err := parseSince(value())
+			if err != nil {
+				fmt.Printf("Invalid --since: %v\n", err)
+				os.Exit(1)
+			}
+			filter.Since = time.Now().Add(-d)
+		case "--tool":
+			filter.Tool = value()
+		case "--action":
+			filter.Action = value()
+		case "--session":
+			filter.Session = value()
+		case "-n":
+			n, err := strconv.Atoi(value())
+			if err != nil {
+				fmt.Println("Invalid -n: expected a number")
+				os.Exit(1)
+			}
+			filter.Limit = n
+		case "--json":
+			asJSON = true
+		default:
+			fmt.Printf("Unknown audit option: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	entries, err := audit.ReadFile(cfg.AuditPath(), filter)
+	if err != nil {
+		fmt.Printf("Error reading audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			enc.Encode(e)
+		}
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No audited actions")
+		return
+	}
+	for _, e := range entries {
+		status := e.Status
+		if e.Error != "" {
+			status += ": " + e.Error
+		}
+		session := e.Session
+		if session == "" {
+			session = "-"
+		}
+		fmt.Printf("%s  %-22s %-20s %s  [%s] args=%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, session, e.Target, status, e.ArgsHash)
+	}
+}
+
+// parseSince accepts Go durations plus a "d" suffix for days (e.g. "7d").
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// gatewayAdminRequest calls the running gateway's proxy admin API on loopback.
+func gatewayAdminRequest(cfg *config.Config, method, path string, payload, out any) error {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	endpoint := fmt.Sprintf("http://127.0.0.1:%d%s", cfg.Gateway.Port, path)
+	req, err := http.NewRequest(method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Timeout:   3 * time.Second,
+		Transport: &http.Transport{Proxy: nil},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("gateway returned %d: %s", resp.StatusCode, e.Error)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func proxyAuditPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir(), "proxy", "denied.jsonl")
 }
 
 func startProxy(cfg *config.Config) *proxy.Proxy {
@@ -402,6 +1055,15 @@ func startProxy(cfg *config.Config) *proxy.Proxy {
 	wl.Add(cfg.AllowedDomains...)
 	wl.Add("*.push.apple.com", "fcm.googleapis.com", "updates.push.services.mozilla.com")
 	p := proxy.New(wl)
+	p.SetAuditLog(proxy.NewAuditLog(proxyAuditPath(cfg)))
+	// User-configured services (e.g. Home Assistant or a Docker TCP host on
+	// the LAN) may resolve to private addresses; anything else doing so is
+	// treated as rebinding.
+	p.AllowPrivate(cfg.ServiceDomains()...)
+	p.AllowPrivate(cfg.AllowPrivateHosts...)
+	if cfg.ProxyRateLimit != 0 {
+		p.SetRateLimit(cfg.ProxyRateLimit)
+	}
 	if err := p.Start(); err != nil {
 		fmt.Printf("Error starting proxy: %v\n", err)
 		os.Exit(1)
@@ -411,36 +1073,1177 @@ func startProxy(cfg *config.Config) *proxy.Proxy {
 	os.Setenv("HTTPS_PROXY", addr)
 	os.Setenv("http_proxy", addr)
 	os.Setenv("https_proxy", addr)
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		proxy.AttributeTools(t)
+	}
+	httpclient.SetProxy(addr)
+	httpclient.SetLimits(
+		int64(cfg.Tools.Downloads.MaxResponseMB)<<20,
+		int64(cfg.Tools.Downloads.DailyQuotaMB)<<20,
+	)
 	return p
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, eventQueue *heartbeat.EventQueue) *cron.CronService {
-	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
+// setupRedaction registers the configured secrets, installs secret and PII
+// redaction for log output and returns the redactor, or nil when PII
+// redaction is disabled. A nil redactor still masks secrets.
+func setupRedaction(cfg *config.Config) *redact.Redactor {
+	r, err := redact.New(cfg.Redaction)
+	if err != nil {
+		fmt.Printf("Error in redaction config: %v\n", err)
+		os.Exit(1)
+	}
+	redact.AddSecrets(cfg.Secrets()...)
+	logger.SetFilter(r.String)
+	return r
+}
 
-	cronService := cron.NewCronService(cronStorePath, nil)
+// setupTelemetry registers the configured metric sinks and returns a func
+// that flushes and closes them. Invalid endpoints are skipped with a warning.
+func setupTelemetry(cfg *config.Config) func() {
+	tc := cfg.Telemetry
+	interval := time.Duration(tc.FlushInterval) * time.Second
+	var sinks []*telemetry.Sink
 
-	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus)
-	cronTool.SetSessionManager(agentLoop.GetSessionManager())
-	cronTool.SetEventEnqueuer(func(source, message, channel, chatID string, wake bool) {
-		e := heartbeat.Event{
-			Source:  source,
-			Message: message,
-			Channel: channel,
-			ChatID:  chatID,
+	if tc.File != "" {
+		path := tc.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.WorkspacePath(), path)
 		}
-		if wake {
-			eventQueue.EnqueueAndWake(e)
+		s, err := telemetry.NewFileSink(path, interval)
+		if err != nil {
+			logger.Warn("telemetry file %s: %v", path, err)
 		} else {
-			eventQueue.Enqueue(e)
+			sinks = append(sinks, s)
 		}
-	})
-	agentLoop.RegisterTool(cronTool)
+	}
+	for _, ep := range tc.Endpoints {
+		s, err := telemetry.NewLineProtocolSink(ep.URL, ep.ResolveToken(), interval)
+		if err != nil {
+			logger.Warn("telemetry endpoint skipped: %v", err)
+			continue
+		}
+		sinks = append(sinks, s)
+	}
 
-	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
-		result := cronTool.ExecuteJob(context.Background(), job)
-		return result, nil
-	})
+	for _, s := range sinks {
+		telemetry.AddHook(s)
+	}
+	if len(sinks) > 0 {
+		logger.Info("telemetry enabled: sinks=%d", len(sinks))
+	}
+	return func() {
+		for _, s := range sinks {
+			s.Close()
+		}
+	}
+}
 
-	return cronService
+// setupBridge returns the MQTT/NATS event bridge, or nil when bridge.url
+// is not set.
+func setupBridge(cfg *config.Config, r *redact.Redactor) *eventbridge.Bridge {
+	if cfg.Bridge.URL == "" {
+		return nil
+	}
+	b, err := eventbridge.New(cfg.Bridge, r)
+	if err != nil {
+		logger.Error("event bridge disabled: %v", err)
+		return nil
+	}
+	fmt.Printf("Event bridge: %s\n", redact.MaskSecrets(cfg.Bridge.URL))
+	return b
+}
+
+// encryptedDirs are the workspace directories (and the owner's USER.md)
+// sealed at rest.
+func encryptedDirs(cfg *config.Config) []string {
+	ws := cfg.WorkspacePath()
+	return []string{
+		filepath.Join(ws, "USER.md"),
+		filepath.Join(ws, "sessions"),
+		filepath.Join(ws, "memory"),
+		filepath.Join(ws, "members"),
+		filepath.Join(ws, "cron"),
+		filepath.Join(ws, "journal"),
+		filepath.Join(ws, "docs_index"),
+	}
+}
+
+func vaultParamsPath(cfg *config.Config) string {
+	return filepath.Join(cfg.DataDir(), "vault.json")
 }
 
+// setupEncryption unlocks the vault so sessions, memory and cron storage are
+// encrypted at rest. It must run before the agent loop loads sessions.
+func setupEncryption(cfg *config.Config) {
+	enc := cfg.Encryption
+	if !enc.Enabled {
+		if _, err := os.Stat(vaultParamsPath(cfg)); err == nil {
+			logger.Warn("encryption is disabled but %s exists; encrypted sessions and notes will not load", vaultParamsPath(cfg))
+		}
+		return
+	}
+
+	env := enc.PassphraseEnv
+	if env == "" {
+		env = "LOCALAGENT_PASSPHRASE"
+	}
+	passphrase := os.Getenv(env)
+	if passphrase == "" && enc.Keychain {
+		var err error
+		if passphrase, err = vault.KeychainPassphrase(); err != nil {
+			fmt.Printf("Error reading passphrase: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if passphrase == "" {
+		fmt.Printf("Encryption is enabled: set %s or enable encryption.keychain\n", env)
+		os.Exit(1)
+	}
+	redact.AddSecrets(passphrase)
+
+	key, err := vault.Unlock(vaultParamsPath(cfg), passphrase)
+	if err != nil {
+		fmt.Printf("Error unlocking encrypted storage: %v\n", err)
+		os.Exit(1)
+	}
+	vault.Enable(key, encryptedDirs(cfg)...)
+	// SQLite pages can't be sealed by the vault; the task database needs
+	// full-disk encryption to be covered.
+	logger.Warn("encryption: %s (tasks, blocks, links) is not encrypted", filepath.Join(cfg.WorkspacePath(), "localagent.db"))
+}
+
+func encryptCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if !cfg.Encryption.Enabled {
+		fmt.Println("Set encryption.enabled in the config first")
+		os.Exit(1)
+	}
+	setupEncryption(cfg)
+
+	sealed := 0
+	for _, dir := range encryptedDirs(cfg) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || strings.HasSuffix(path, ".tmp") || strings.HasSuffix(path, ".lock") {
+				return nil
+			}
+			changed, err := vault.SealFile(path)
+			if err != nil {
+				fmt.Printf("  %s: %v\n", path, err)
+				return nil
+			}
+			if changed {
+				sealed++
+			}
+			return nil
+		})
+	}
+	fmt.Printf("Encrypted %d files\n", sealed)
+	fmt.Printf("Not encrypted: %s (tasks, blocks, links); use full-disk encryption for it\n", filepath.Join(cfg.WorkspacePath(), "localagent.db"))
+}
+
+// migrationRunner covers every store with versioned on-disk data.
+func migrationRunner(cfg *config.Config) *migrate.Runner {
+	ws := cfg.WorkspacePath()
+	cronPath := filepath.Join(ws, "cron", "jobs.json")
+	tasksPath := filepath.Join(ws, "todo", "tasks.json")
+	return migrate.NewRunner(ws,
+		migrate.Store{
+			Name:       "sessions",
+			Paths:      []string{filepath.Join(ws, "sessions")},
+			Migrations: session.Migrations(filepath.Join(ws, "sessions")),
+		},
+		migrate.Store{
+			Name:       "cron",
+			Paths:      []string{cronPath},
+			Migrations: cron.Migrations(cronPath),
+		},
+		migrate.Store{
+			Name:       "todo",
+			Paths:      []string{tasksPath},
+			Migrations: db.Migrations(filepath.Join(ws, "localagent.db"), tasksPath),
+		},
+		migrate.Store{
+			Name:       "state",
+			Paths:      []string{filepath.Join(ws, "state", "state.json"), filepath.Join(ws, "calendar_reminders.json")},
+			Migrations: state.Migrations(ws),
+		},
+	)
+}
+
+// runMigrations brings the workspace up to date before anything opens it.
+// A failed migration is fatal: running on half-migrated data could lose it.
+func runMigrations(cfg *config.Config) {
+	res, err := migrationRunner(cfg).Run()
+	if err != nil {
+		fmt.Printf("Error migrating workspace: %v\n", err)
+		if len(res.Backups) > 0 {
+			fmt.Printf("Backups: %s\n", strings.Join(res.Backups, ", "))
+		}
+		os.Exit(1)
+	}
+}
+
+func migrateCmd() {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupEncryption(cfg)
+
+	r := migrationRunner(cfg)
+	r.DryRun = slices.Contains(os.Args[2:], "--dry-run")
+	res, err := r.Run()
+	for _, st := range res.Steps {
+		verb := "Migrated"
+		if r.DryRun {
+			verb = "Pending:"
+		}
+		fmt.Printf("%s %s v%d (%s)\n", verb, st.Store, st.Version, st.Name)
+	}
+	for _, b := range res.Backups {
+		fmt.Printf("Backup: %s\n", b)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(res.Steps) == 0 {
+		fmt.Println("Workspace is up to date")
+	}
+}
+
+func sessionsCmd() {
+	if len(os.Args) < 3 || os.Args[2] != "reindex" {
+		fmt.Println("Usage: localagent sessions reindex")
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupEncryption(cfg)
+
+	n, err := session.NewSessionManager(filepath.Join(cfg.WorkspacePath(), "sessions")).Reindex()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Indexed %d sessions\n", n)
+}
+
+func docsCmd() {
+	if len(os.Args) < 3 || (os.Args[2] != "refresh" && os.Args[2] != "status") {
+		fmt.Println("Usage: localagent docs refresh|status")
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	setupEncryption(cfg)
+
+	index := newDocIndex(cfg)
+	if index == nil {
+		fmt.Println("Document index is off: set tools.docs_qa.folders and tools.docs_qa.embedding_model")
+		os.Exit(1)
+	}
+	if os.Args[2] == "status" {
+		st, err := index.Status()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(tools.FormatDocsStatus(st))
+		return
+	}
+	stats, err := index.Refresh(context.Background())
+	fmt.Printf("Embedded %d files (%d chunks), %d unchanged, %d removed\n", stats.Indexed, stats.Chunks, stats.Unchanged, stats.Removed)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func exportCmd() {
+	key, format, output := "", "", ""
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format", "-f":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		default:
+			key = args[i]
+		}
+	}
+	format, err := transcript.ParseFormat(format)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	redactor := setupRedaction(cfg)
+	setupEncryption(cfg)
+
+	// The loop is only built for its session store and tool list, which
+	// says which tool arguments are sensitive.
+	agentLoop := agent.NewAgentLoop(cfg, bus.NewMessageBus(), newProvider(cfg, redactor))
+	sessions := agentLoop.GetSessionManager()
+	if key == "" {
+		fmt.Println("Usage: localagent export <session> [--format markdown|html] [-o file]")
+		fmt.Println()
+		for _, s := range sessions.ListSessions() {
+			fmt.Printf("  %-30s %4d messages  %s\n", s.Key, s.Messages, s.Updated.Format("2006-01-02 15:04"))
+		}
+		return
+	}
+
+	opts := transcript.Options{Sensitive: tools.SensitiveArgs(agentLoop.GetTools()), Redact: redactor.String}
+	var buf bytes.Buffer
+	if err := transcript.Export(&buf, sessions, key, format, opts); err != nil {
+		fmt.Printf("Error: %s: %v\n", key, err)
+		os.Exit(1)
+	}
+	if output == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0600); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %s to %s\n", key, output)
+}
+
+func doctorCmd() {
+	report := doctor.Run(context.Background(), getConfigPath())
+	section := ""
+	for _, r := range report.Results {
+		if r.Section != section {
+			section = r.Section
+			fmt.Printf("\n%s\n", section)
+		}
+		fmt.Printf("  [%-4s] %-16s %s\n", r.Status, r.Name, r.Detail)
+		if r.Fix != "" {
+			fmt.Printf("         fix: %s\n", r.Fix)
+		}
+	}
+	if report.Failed() {
+		fmt.Println("\nSome checks failed.")
+		os.Exit(1)
+	}
+	fmt.Println("\nAll checks passed.")
+}
+
+// remoteTaskRunner runs tasks delegated by federation peers, streaming the
+// tools the agent runs back as status.
+func remoteTaskRunner(agentLoop *agent.AgentLoop) federation.RunFunc {
+	return func(ctx context.Context, peer, task string, status func(string)) (string, error) {
+		return agentLoop.ProcessRemote(ctx, peer, task, func(e activity.Event) {
+			if e.Type == activity.ToolExec {
+				status(e.Message)
+			}
+		})
+	}
+}
+
+// setupRuntimeStats adds bus queue depths to the health server's
+// /debug/stats and reports the stats periodically, as telemetry events or
+// log lines, until ctx is done.
+func setupRuntimeStats(ctx context.Context, cfg *config.Config, healthServer *health.Server, msgBus *bus.MessageBus) {
+	healthServer.AddGauge("bus_inbound", func() float64 { in, _ := msgBus.Depths(); return float64(in) })
+	healthServer.AddGauge("bus_outbound", func() float64 { _, out := msgBus.Depths(); return float64(out) })
+
+	interval := cfg.Telemetry.RuntimeStatsInterval()
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			st := healthServer.Stats()
+			if telemetry.Enabled() {
+				telemetry.Emit("runtime", nil, st.Fields())
+				continue
+			}
+			logger.Info("runtime: goroutines=%d heap=%dMB objects=%d sys=%dMB gc=%d bus_in=%.0f bus_out=%.0f",
+				st.Goroutines, st.HeapAlloc>>20, st.HeapObjects, st.Sys>>20, st.NumGC, st.Gauges["bus_inbound"], st.Gauges["bus_outbound"])
+		}
+	}()
+}
+
+// setupOpenAIAPI mounts the OpenAI-compatible endpoint on the gateway when
+// enabled. A token is required since the agent has the owner's tools.
+func setupOpenAIAPI(cfg *config.Config, healthServer *health.Server, agentLoop *agent.AgentLoop) {
+	oc := cfg.Gateway.OpenAI
+	if !oc.Enabled {
+		return
+	}
+	token := oc.ResolveToken()
+	if token == "" {
+		logger.Warn("openai api disabled: gateway.openai.token_env is not set or empty")
+		return
+	}
+	healthServer.Handle("/v1/", openai.Handler(token, func(ctx context.Context, session, content string) (string, error) {
+		return agentLoop.ProcessDirectWithChannel(ctx, content, session, "openai", strings.TrimPrefix(session, "openai:"))
+	}))
+	fmt.Printf("OpenAI API: http://%s:%d/v1 (model %q)\n", cfg.Gateway.Host, cfg.Gateway.Port, openai.ModelID)
+}
+
+// setupSatelliteAPI mounts the voice endpoint for satellite devices on the
+// gateway when enabled. It needs a token and an STT service; without TTS
+// devices get text answers.
+func setupSatelliteAPI(cfg *config.Config, healthServer *health.Server, agentLoop *agent.AgentLoop) {
+	sc := cfg.Gateway.Satellite
+	if !sc.Enabled {
+		return
+	}
+	token := sc.ResolveToken()
+	if token == "" {
+		logger.Warn("satellite api disabled: gateway.satellite.token_env is not set or empty")
+		return
+	}
+	stt, tts := cfg.Tools.STT, cfg.Tools.TTS
+	if stt.URL == "" {
+		logger.Warn("satellite api disabled: tools.stt.url is not set")
+		return
+	}
+	speaker, language := cmp.Or(sc.Speaker, tts.Speaker), cmp.Or(sc.Language, tts.Language)
+	healthServer.Handle("/satellite/", satellite.Handler(satellite.Config{
+		Token:    token,
+		STTURL:   stt.URL,
+		STTKey:   stt.ResolveAPIKey(),
+		TTSURL:   tts.URL,
+		TTSKey:   tts.ResolveAPIKey(),
+		Speaker:  speaker,
+		Language: language,
+	}, agentLoop.ProcessSatellite))
+	fmt.Printf("Satellite API: http://%s:%d/satellite/turn\n", cfg.Gateway.Host, cfg.Gateway.Port)
+}
+
+// newProvider creates the LLM provider. Prompts are redacted when configured,
+// unless the provider is marked trusted (e.g. a local model).
+func newProvider(cfg *config.Config, r *redact.Redactor) providers.LLMProvider {
+	httpProvider := providers.NewHTTPProvider(
+		cfg.Provider.ResolveAPIKey(),
+		cfg.Provider.APIBase,
+		cfg.Provider.Proxy,
+	)
+	httpProvider.SetPromptCache(cfg.Provider.PromptCache)
+	return wrapProvider(cfg, r, httpProvider, cfg.Provider.Trusted)
+}
+
+// wrapProvider adds capture and prompt redaction (unless the endpoint is
+// trusted) to an HTTP provider.
+func wrapProvider(cfg *config.Config, r *redact.Redactor, httpProvider *providers.HTTPProvider, trusted bool) providers.LLMProvider {
+	if capture := llmCapture(cfg, r); capture != nil {
+		httpProvider.SetCapture(capture.Capture)
+	}
+	var provider providers.LLMProvider = httpProvider
+	if r != nil && cfg.Redaction.RedactPrompts && !trusted {
+		provider = redact.WrapProvider(provider, r)
+	}
+	return provider
+}
+
+// newModelRoutes builds agents.routes. A route with its own api_base gets
+// its own provider; the others use the main one with their model.
+func newModelRoutes(cfg *config.Config, r *redact.Redactor) map[string]agent.ModelRoute {
+	routes := make(map[string]agent.ModelRoute, len(cfg.Agents.Routes))
+	for name, route := range cfg.Agents.Routes {
+		mr := agent.ModelRoute{Model: route.Model}
+		if route.APIBase != "" {
+			httpProvider := providers.NewHTTPProvider(route.ResolveAPIKey(), route.APIBase, cfg.Provider.Proxy)
+			mr.Provider = wrapProvider(cfg, r, httpProvider, route.Trusted)
+		}
+		routes[name] = mr
+	}
+	return routes
+}
+
+var (
+	llmCaptureOnce  sync.Once
+	llmCaptureStore *llmcapture.Store
+)
+
+// llmCapture returns the store for raw provider exchanges, or nil when
+// provider.capture is off. Every provider and the web UI share one store,
+// so max_files holds across routes and the UI lists all exchanges.
+func llmCapture(cfg *config.Config, r *redact.Redactor) *llmcapture.Store {
+	llmCaptureOnce.Do(func() {
+		if cfg.Provider.Capture {
+			llmCaptureStore = llmcapture.New(filepath.Join(cfg.WorkspacePath(), "debug", "llm"), cfg.Provider.CaptureMaxFiles, r.String)
+		}
+	})
+	return llmCaptureStore
+}
+
+// setupCalendarReminders returns a watcher that wakes the heartbeat ahead of
+// calendar events, or nil when no calendar or lead time is configured.
+func setupCalendarReminders(cfg *config.Config, eventQueue *heartbeat.EventQueue) *heartbeat.CalendarWatcher {
+	cal := cfg.Tools.Calendar
+	if cal.URL == "" || cal.ReminderLeadMinutes <= 0 {
+		return nil
+	}
+	calendarTool := tools.NewCalendarTool(cfg.WorkspacePath(), cal.URL, cal.Username, cal.ResolvePassword())
+	upcoming := func(ctx context.Context, from, to time.Time) ([]heartbeat.UpcomingEvent, error) {
+		events, err := calendarTool.Upcoming(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]heartbeat.UpcomingEvent, len(events))
+		for i, e := range events {
+			out[i] = heartbeat.UpcomingEvent{UID: e.UID, Title: e.Title, Location: e.Location, Start: e.Start}
+		}
+		return out, nil
+	}
+	lead := time.Duration(cal.ReminderLeadMinutes) * time.Minute
+	return heartbeat.NewCalendarWatcher(cfg.WorkspacePath(), eventQueue, upcoming, lead)
+}
+
+// setupUpcoming gives every turn a short note of the next calendar events,
+// tasks due today and running timers.
+func setupUpcoming(cfg *config.Config, agentLoop *agent.AgentLoop, cronService *cron.CronService) {
+	note := upcoming.New(agentLoop.GetTodoService(), cronService)
+	if cal := cfg.Tools.Calendar; cal.URL != "" {
+		calendarTool := tools.NewCalendarTool(cfg.WorkspacePath(), cal.URL, cal.Username, cal.ResolvePassword())
+		note.SetCalendar(func(ctx context.Context, from, to time.Time) ([]upcoming.Event, error) {
+			events, err := calendarTool.Upcoming(ctx, from, to)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]upcoming.Event, len(events))
+			for i, e := range events {
+				out[i] = upcoming.Event{Title: e.Title, Location: e.Location, Start: e.Start}
+			}
+			return out, nil
+		})
+	}
+	agentLoop.SetUpcoming(note.String)
+}
+
+// setupDoNotDisturb returns the outbox that holds cron announcements
+// during focus and, with tools.calendar.do_not_disturb, busy calendar
+// events. It also serves as the heartbeat's busy check.
+func setupDoNotDisturb(cfg *config.Config, msgBus *bus.MessageBus, focus *dnd.Focus) *dnd.Outbox {
+	var checker *dnd.Checker
+	if cal := cfg.Tools.Calendar; cal.URL != "" && cal.DoNotDisturb {
+		calendarTool := tools.NewCalendarTool(cfg.WorkspacePath(), cal.URL, cal.Username, cal.ResolvePassword())
+		checker = dnd.NewChecker(calendarTool.BusyUntil)
+	}
+	outbox := dnd.NewOutbox(checker, msgBus)
+	outbox.SetFocus(focus)
+	return outbox
+}
+
+// scanStorage reports disk usage of the data directory and workspace by
+// category.
+func scanStorage(cfg *config.Config) storage.Usage {
+	workspace := cfg.WorkspacePath()
+	webchatDir := filepath.Join(cfg.DataDir(), "webchat")
+	return storage.Scan(storage.Layout{
+		Roots: []string{workspace, cfg.DataDir()},
+		Dirs: map[string]string{
+			filepath.Join(workspace, "sessions"): storage.CategorySessions,
+			filepath.Join(workspace, "memory"):   storage.CategoryMemory,
+			filepath.Join(workspace, "media"):    storage.CategoryMedia,
+			filepath.Join(webchatDir, "media"):   storage.CategoryMedia,
+			filepath.Join(webchatDir, "images"):  storage.CategoryImageJobs,
+			migrate.BackupDir(workspace):         storage.CategoryBackups,
+		},
+	})
+}
+
+// setupDiskWatcher returns a watcher that warns through the heartbeat when
+// the workspace disk is nearly full, or nil when the warning is disabled.
+func setupDiskWatcher(cfg *config.Config, eventQueue *heartbeat.EventQueue) *heartbeat.DiskWatcher {
+	threshold := cfg.Storage.LowDiskPercent()
+	if threshold <= 0 {
+		return nil
+	}
+	return heartbeat.NewDiskWatcher(cfg.WorkspacePath(), eventQueue, threshold, func() storage.Usage { return scanStorage(cfg) })
+}
+
+// setupWatchers creates the file watchers. A settled file either queues a
+// heartbeat event or runs the watcher's prompt as an agent turn in its own
+// session, one file at a time; invalid watchers are skipped.
+func setupWatchers(cfg *config.Config, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, eventQueue *heartbeat.EventQueue) []*watch.Watcher {
+	sessions := agentLoop.GetSessionManager()
+	stateManager := state.NewManager(cfg.WorkspacePath())
+	var watchers []*watch.Watcher
+	for _, wc := range cfg.Watchers {
+		handle := func(t watch.Trigger) {
+			eventQueue.EnqueueAndWake(heartbeat.Event{
+				Source:  "watch:" + t.Watcher,
+				Message: t.Expand(wc.Prompt),
+			})
+		}
+		if wc.Action == "agent" {
+			handle = func(t watch.Trigger) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+				defer cancel()
+				channel, chatID, ok := strings.Cut(stateManager.GetLastChannel(), ":")
+				if !ok || constants.IsInternalChannel(channel) {
+					channel, chatID = "cli", "direct"
+				}
+				response, err := agentLoop.ProcessDirectWithChannel(ctx, t.Expand(wc.Prompt), "watch-"+t.Watcher, channel, chatID)
+				if err != nil {
+					logger.Error("watch: %s: workflow for %s failed: %v", t.Watcher, t.Path, err)
+					return
+				}
+				if !wc.Announce || response == "" || agentLoop.WasMessageToolCalled() || channel == "cli" {
+					return
+				}
+				sessions.AddMessage(channel+":"+chatID, "assistant", response)
+				msgBus.PublishOutbound(bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: response})
+			}
+		}
+		w, err := watch.New(wc, cfg.WorkspacePath(), handle)
+		if err != nil {
+			logger.Error("watch: skipping watcher: %v", err)
+			continue
+		}
+		watchers = append(watchers, w)
+	}
+	return watchers
+}
+
+// setupFlashcards registers the flashcards tool and returns the watcher
+// that starts the daily quiz, or nil when no review time is configured.
+func setupFlashcards(cfg *config.Config, agentLoop *agent.AgentLoop, eventQueue *heartbeat.EventQueue) *heartbeat.FlashcardWatcher {
+	cards := flashcards.NewService(agentLoop.GetTodoService().DB())
+	agentLoop.RegisterTool(tools.NewFlashcardsTool(cards))
+	if cfg.Flashcards.ReviewTime == "" {
+		return nil
+	}
+	w, err := heartbeat.NewFlashcardWatcher(cfg.WorkspacePath(), eventQueue, func() int { return len(cards.Due("")) }, cfg.Flashcards.ReviewTime)
+	if err != nil {
+		logger.Error("flashcard quiz disabled: %v", err)
+		return nil
+	}
+	return w
+}
+
+// setupMedications registers the medications tool and the handler that
+// logs replies to dose reminders, and returns the reminder scheduler.
+// Reminders for medications added outside a chat go to the last active one.
+func setupMedications(cfg *config.Config, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus) *medications.Scheduler {
+	meds := medications.NewService(agentLoop.GetTodoService().DB())
+	meds.SetLowStockDays(cfg.Medications.LowStockDays)
+	agentLoop.RegisterTool(tools.NewMedicationsTool(meds))
+	agentLoop.AddReplyHandler(func(msg bus.InboundMessage) (string, bool) {
+		return meds.HandleReply(msg.Channel, msg.ChatID, msg.Content)
+	})
+
+	sessions := agentLoop.GetSessionManager()
+	send := func(channel, chatID, content string) {
+		sessions.AddMessage(channel+":"+chatID, "assistant", content)
+		msgBus.PublishOutbound(bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content})
+	}
+	stateManager := state.NewManager(cfg.WorkspacePath())
+	target := func() (string, string) {
+		channel, chatID, ok := strings.Cut(stateManager.GetLastChannel(), ":")
+		if !ok || constants.IsInternalChannel(channel) {
+			return "", ""
+		}
+		return channel, chatID
+	}
+	return medications.NewScheduler(meds, send, target)
+}
+
+// setupAllowlist registers the allowlist tool and the /approve and /deny
+// commands, and tells the owner, on the channel they last used, about
+// unknown senders asking for access.
+func setupAllowlist(cfg *config.Config, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, commands *channels.Commands) *channels.Allowlist {
+	allowlist := channels.NewAllowlist(cfg.WorkspacePath())
+	agentLoop.RegisterTool(tools.NewAllowlistTool(allowlist))
+
+	sessions := agentLoop.GetSessionManager()
+	stateManager := state.NewManager(cfg.WorkspacePath())
+	allowlist.SetNotifier(func(p channels.PendingSender) {
+		channel, chatID, ok := strings.Cut(stateManager.GetLastChannel(), ":")
+		if !ok || constants.IsInternalChannel(channel) {
+			logger.Warn("allowlist: no channel to notify about %s on %s", p.SenderID, p.Channel)
+			return
+		}
+		content := i18n.T("channels.access_notify", p.SenderID, p.Channel, p.Message, p.Channel, p.SenderID)
+		sessions.AddMessage(channel+":"+chatID, "assistant", content)
+		msgBus.PublishOutbound(bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content})
+	})
+
+	// The web chat is the owner's; other channels may have several users.
+	for _, approve := range []bool{true, false} {
+		name, decide, verb := "deny", allowlist.Deny, "Denied"
+		if approve {
+			name, decide, verb = "approve", allowlist.Approve, "Approved"
+		}
+		commands.Register(channels.Command{
+			Name:        name,
+			Usage:       "<channel> <sender>",
+			Description: name + " a pending access request",
+			Channels:    []string{"web"},
+			Handler: func(_ context.Context, req channels.CommandRequest) (string, error) {
+				channel, sender, _ := strings.Cut(req.Args, " ")
+				sender = strings.TrimSpace(sender)
+				if channel == "" || sender == "" {
+					return "", fmt.Errorf("usage: /%s <channel> <sender>", name)
+				}
+				if err := decide(channel, sender); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%s %s on %s.", verb, sender, channel), nil
+			},
+		})
+	}
+	return allowlist
+}
+
+// setupSleep registers the sleep tool and hands the sleep schedule to the
+// heartbeat. It returns the Home Assistant sensor watcher, or nil when no
+// sensor is configured.
+func setupSleep(cfg *config.Config, agentLoop *agent.AgentLoop, heartbeatService *heartbeat.HeartbeatService) *sleep.SensorWatcher {
+	tracker, err := sleep.NewTracker(cfg.WorkspacePath(), cfg.Sleep.Bedtime, cfg.Sleep.WakeTime)
+	if err != nil {
+		logger.Error("sleep schedule disabled: %v", err)
+		return nil
+	}
+	agentLoop.RegisterTool(tools.NewSleepTool(tracker))
+	heartbeatService.SetSleepSchedule(tracker)
+	if cfg.Sleep.Sensor == "" {
+		return nil
+	}
+	ha := cfg.Tools.HomeAssistant
+	if ha.URL == "" {
+		logger.Error("sleep sensor disabled: tools.home_assistant.url is not set")
+		return nil
+	}
+	return sleep.NewSensorWatcher(tracker, ha.URL, ha.ResolveAPIKey(), cfg.Sleep.Sensor, cfg.Sleep.AsleepStates)
+}
+
+// setupTravel registers the travel tool and returns travel mode, which
+// moves the agent's timezone to the destination of the trip under way.
+// Cron schedules follow the switch; the heartbeat gets trip briefings.
+func setupTravel(cfg *config.Config, agentLoop *agent.AgentLoop, heartbeatService *heartbeat.HeartbeatService, cronService *cron.CronService) *travel.Mode {
+	trips := travel.NewService(agentLoop.GetTodoService().DB())
+	mode := travel.NewMode(trips, when.Location(), cfg.WorkspacePath())
+	mode.OnChange(func(*time.Location) {
+		if err := cronService.Reschedule(); err != nil {
+			logger.Error("travel: failed to reschedule cron jobs: %v", err)
+		}
+	})
+	if cal := cfg.Tools.Calendar; cal.URL != "" && !cfg.Travel.DisableCalendar {
+		calendarTool := tools.NewCalendarTool(cfg.WorkspacePath(), cal.URL, cal.Username, cal.ResolvePassword())
+		mode.SetCalendar(func(ctx context.Context, from, to time.Time, minDur time.Duration) ([]travel.Event, error) {
+			events, err := calendarTool.LongEvents(ctx, from, to, minDur)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]travel.Event, len(events))
+			for i, e := range events {
+				out[i] = travel.Event{UID: e.UID, Title: e.Title, Location: e.Location, Start: e.Start, End: e.End}
+			}
+			return out, nil
+		})
+	}
+	agentLoop.RegisterTool(tools.NewTravelTool(mode, trips, cfg.WorkspacePath()))
+	agentLoop.SetTimeNote(mode.Note)
+	heartbeatService.SetTravelBriefing(mode)
+	return mode
+}
+
+// setupEmailTriage registers the email_triage tool when tools.email is
+// configured, and adds the day's triage to the heartbeat.
+func setupEmailTriage(cfg *config.Config, agentLoop *agent.AgentLoop, heartbeatService *heartbeat.HeartbeatService) {
+	ec := cfg.Tools.Email
+	if ec.Host == "" {
+		return
+	}
+	triager := email.NewTriager(email.Account{
+		Addr:     ec.Addr(),
+		Username: ec.Username,
+		Password: ec.ResolvePassword(),
+		Mailbox:  ec.Mailbox,
+		Plain:    ec.Plaintext,
+		Lookback: time.Duration(ec.LookbackDays) * 24 * time.Hour,
+		Max:      ec.MaxMessages,
+	}, cfg.WorkspacePath())
+	agentLoop.RegisterTool(tools.NewEmailTriageTool(triager))
+	if !ec.DisableBriefing {
+		heartbeatService.SetEmailBriefing(triager)
+	}
+}
+
+// setupParcels registers the track_package tool and returns the watcher
+// that turns status changes of watched parcels into heartbeat events, or
+// nil without tools.packages. Changes like "out for delivery" wake the
+// heartbeat; the rest wait for its next run.
+func setupParcels(cfg *config.Config, agentLoop *agent.AgentLoop, eventQueue *heartbeat.EventQueue) *parcels.Watcher {
+	pc := cfg.Tools.Packages
+	if pc.Provider == "" {
+		return nil
+	}
+	tracker, err := parcels.New(pc.Provider, pc.ResolveAPIKey())
+	if err != nil {
+		logger.Error("packages: %v", err)
+		return nil
+	}
+	store := parcels.NewStore(cfg.WorkspacePath())
+	agentLoop.RegisterTool(tools.NewTrackPackageTool(tracker, store))
+	return parcels.NewWatcher(store, tracker, time.Duration(pc.PollMinutes)*time.Minute, func(u parcels.Update) {
+		event := heartbeat.Event{
+			Source:    "parcels:" + u.Parcel.Number,
+			Message:   u.Message(),
+			Channel:   u.Parcel.Channel,
+			ChatID:    u.Parcel.ChatID,
+			ExpiresAt: time.Now().Add(24 * time.Hour),
+		}
+		if !u.Parcel.Status.Notable() {
+			event.Priority = heartbeat.PriorityLow
+			eventQueue.Enqueue(event)
+			return
+		}
+		eventQueue.EnqueueAndWake(event)
+	})
+}
+
+// setupSports registers the sports tool and, with notify_results and
+// followed teams, returns the watcher that turns their finished matches
+// into heartbeat events.
+func setupSports(cfg *config.Config, agentLoop *agent.AgentLoop, eventQueue *heartbeat.EventQueue) *sports.Watcher {
+	sc := cfg.Tools.Sports
+	client := sports.NewClient(sc.ResolveAPIKey())
+	agentLoop.RegisterTool(tools.NewSportsTool(client, sc.Teams))
+	if !sc.NotifyResults || len(sc.Teams) == 0 {
+		return nil
+	}
+	return sports.NewWatcher(client, sc.Teams, cfg.WorkspacePath(), time.Duration(sc.PollMinutes)*time.Minute, func(f sports.Final) {
+		eventQueue.EnqueueAndWake(heartbeat.Event{
+			Source:    "sports:" + f.Match.ID,
+			Message:   f.Message(),
+			ExpiresAt: time.Now().Add(12 * time.Hour),
+		})
+	})
+}
+
+// setupTransit registers the transit tool when tools.transit is configured,
+// and adds departures from the briefing stop to the morning heartbeat.
+func setupTransit(cfg *config.Config, agentLoop *agent.AgentLoop, heartbeatService *heartbeat.HeartbeatService) {
+	tc := cfg.Tools.Transit
+	if tc.Provider == "" {
+		return
+	}
+	if err := tc.Validate(); err != nil {
+		logger.Error("%v", err)
+		return
+	}
+	source, err := transit.New(tc)
+	if err != nil {
+		logger.Error("transit: %v", err)
+		return
+	}
+	var planner *transit.Planner
+	if tc.PlanURL != "" {
+		planner = transit.NewPlanner(tc.PlanURL)
+	}
+	stops := transit.Stops(tc.Stops)
+	agentLoop.RegisterTool(tools.NewTransitTool(source, planner, stops, transit.Domains(tc)))
+	if tc.DisableBriefing {
+		return
+	}
+	stop := stops[0]
+	if tc.BriefingStop != "" {
+		stop, _ = transit.Lookup(stops, tc.BriefingStop)
+	}
+	start, end, _ := tc.BriefingHours()
+	heartbeatService.SetTransitBriefing(transit.NewBriefing(source, stop, start, end, cfg.WorkspacePath()))
+}
+
+// setupAirQuality registers the air_quality tool and, with configured
+// locations, adds notable air quality and pollen to the day's first
+// heartbeat.
+func setupAirQuality(cfg *config.Config, agentLoop *agent.AgentLoop, heartbeatService *heartbeat.HeartbeatService) {
+	ac := cfg.Tools.AirQuality
+	agentLoop.RegisterTool(tools.NewAirQualityTool(ac))
+	if len(ac.Locations) > 0 && !ac.DisableBriefing {
+		heartbeatService.SetAirQualityBriefing(airquality.NewBriefing(ac, cfg.WorkspacePath()))
+	}
+}
+
+// setupDocsQA registers the docs_qa tool when tools.docs_qa is configured
+// and refreshes its index while the agent is idle.
+func setupDocsQA(cfg *config.Config, agentLoop *agent.AgentLoop) {
+	index := newDocIndex(cfg)
+	if index == nil {
+		return
+	}
+	agentLoop.RegisterTool(tools.NewDocsQATool(index))
+	if idleWork := agentLoop.IdleScheduler(); idleWork != nil {
+		idleWork.Every("document index refresh", func(ctx context.Context) {
+			if _, err := index.Refresh(ctx); err != nil {
+				logger.Warn("document index: %v", err)
+			}
+		})
+	}
+}
+
+// newDocIndex returns the document index, or nil without folders and an
+// embedding model. Embeddings use the provider's API unless
+// tools.docs_qa.api_base is set.
+func newDocIndex(cfg *config.Config) *docindex.Index {
+	dc := cfg.Tools.DocsQA
+	if len(dc.Folders) == 0 || dc.EmbeddingModel == "" {
+		return nil
+	}
+	apiBase, apiKey := dc.APIBase, dc.ResolveAPIKey()
+	if apiBase == "" {
+		apiBase, apiKey = cfg.Provider.APIBase, cfg.Provider.ResolveAPIKey()
+	}
+	embedder := providers.NewHTTPProvider(apiKey, apiBase, cfg.Provider.Proxy)
+	index := docindex.New(cfg.WorkspacePath(), dc.Folders, embedder, dc.EmbeddingModel, dc.ChunkTokens)
+	if pdf := cfg.Tools.PDF; pdf.URL != "" {
+		index.SetPDFConverter(func(ctx context.Context, path string) (string, error) {
+			return tools.ConvertPDF(ctx, path, pdf.URL, pdf.ResolveAPIKey())
+		})
+	}
+	return index
+}
+
+// setupJournal registers the journal tool and returns the scheduler that
+// writes entries, or nil when the journal is disabled.
+func setupJournal(cfg *config.Config, agentLoop *agent.AgentLoop, provider providers.LLMProvider) *journal.Scheduler {
+	if !cfg.Journal.Enabled {
+		return nil
+	}
+	gen := journal.NewGenerator(cfg.WorkspacePath(), provider, cfg.Agents.Defaults.Model, agentLoop.GetSessionManager())
+	gen.SetTodoService(agentLoop.GetTodoService())
+	if !cfg.Audit.Disabled {
+		gen.SetAuditPath(cfg.AuditPath())
+	}
+	if cal := cfg.Tools.Calendar; cal.URL != "" {
+		calendarTool := tools.NewCalendarTool(cfg.WorkspacePath(), cal.URL, cal.Username, cal.ResolvePassword())
+		gen.SetEvents(func(ctx context.Context, from, to time.Time) ([]journal.Event, error) {
+			events, err := calendarTool.Upcoming(ctx, from, to)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]journal.Event, len(events))
+			for i, e := range events {
+				out[i] = journal.Event{Title: e.Title, Location: e.Location, Start: e.Start}
+			}
+			return out, nil
+		})
+	}
+	agentLoop.RegisterTool(tools.NewJournalTool(gen))
+
+	scheduler, err := journal.NewScheduler(gen, cfg.WorkspacePath(), cfg.Journal.Time, cfg.Journal.WeeklyDay)
+	if err != nil {
+		logger.Error("journal disabled: %v", err)
+		return nil
+	}
+	return scheduler
+}
+
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, eventQueue *heartbeat.EventQueue, outbox *dnd.Outbox) *cron.CronService {
+	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
+
+	cronService := cron.NewCronService(cronStorePath, nil)
+
+	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus)
+	cronTool.SetSessionManager(agentLoop.GetSessionManager())
+	if outbox != nil {
+		cronTool.SetDeliver(outbox.Deliver)
+	}
+	cronTool.SetEventEnqueuer(func(source, message, channel, chatID string, wake bool) {
+		e := heartbeat.Event{
+			Source:  source,
+			Message: message,
+			Channel: channel,
+			ChatID:  chatID,
+		}
+		// "now" events are urgent: they wake immediately, even outside
+		// active hours. Others wait for the next heartbeat in the window.
+		if wake {
+			e.Priority = heartbeat.PriorityUrgent
+		}
+		eventQueue.Enqueue(e)
+	})
+	agentLoop.RegisterTool(cronTool)
+
+	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
+		result := cronTool.ExecuteJob(context.Background(), job)
+		return result, nil
+	})
+
+	return cronService
+}
+
+// setupCommands registers the slash commands channels answer without
+// running the agent.
+func setupCommands(agentLoop *agent.AgentLoop, cronService *cron.CronService, focus *dnd.Focus) *channels.Commands {
+	started := time.Now()
+	commands := channels.NewCommands()
+	commands.SetRoles(agentLoop.SenderRole)
+
+	commands.Register(channels.Command{
+		Name:        "status",
+		Description: "show model, uptime and scheduled jobs",
+		Handler: func(_ context.Context, _ channels.CommandRequest) (string, error) {
+			status := cronService.Status()
+			return fmt.Sprintf("Model: %s\nUptime: %s\nTools: %d\nScheduled jobs: %d",
+				agentLoop.Model(), time.Since(started).Round(time.Second), len(agentLoop.GetTools()), status.JobCount), nil
+		},
+	})
+
+	commands.Register(channels.Command{
+		Name:        "tasks",
+		Usage:       "[tag]",
+		Description: "list open tasks",
+		Handler: func(_ context.Context, req channels.CommandRequest) (string, error) {
+			todos := agentLoop.GetTodoService()
+			open := append(todos.ListTasks("doing", req.Args), todos.ListTasks("todo", req.Args)...)
+			if len(open) == 0 {
+				return "No open tasks.", nil
+			}
+			const maxTasks = 20
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "Open tasks (%d):", len(open))
+			for i, t := range open {
+				if i == maxTasks {
+					fmt.Fprintf(&sb, "\n... and %d more", len(open)-maxTasks)
+					break
+				}
+				sb.WriteString("\n- " + t.Title)
+				if t.Status == "doing" {
+					sb.WriteString(" [doing]")
+				}
+				if t.Due != "" {
+					sb.WriteString(" (due " + t.Due + ")")
+				}
+			}
+			return sb.String(), nil
+		},
+	})
+
+	commands.Register(channels.Command{
+		Name:        "focus",
+		Usage:       "[duration|off]",
+		Description: "hold non-urgent messages for a while, e.g. /focus 45m",
+		Handler: func(_ context.Context, req channels.CommandRequest) (string, error) {
+			switch req.Args {
+			case "":
+				if st := focus.Status(); st.Active {
+					return i18n.T("focus.status", st.Until.Format("15:04")), nil
+				}
+				return i18n.T("focus.none"), nil
+			case "off", "end", "stop":
+				if _, err := focus.End(); err != nil {
+					return "", err
+				}
+				return i18n.T("focus.off"), nil
+			}
+			d, err := time.ParseDuration(req.Args)
+			if err != nil {
+				return "", fmt.Errorf("invalid duration %q", req.Args)
+			}
+			st, err := focus.Start(d, "")
+			if err != nil {
+				return "", err
+			}
+			return i18n.T("focus.on", st.Until.Format("15:04")), nil
+		},
+	})
+
+	commands.Register(channels.Command{
+		Name:        "remindme",
+		Usage:       "<duration> <text>",
+		Description: "send a reminder after a delay, e.g. /remindme 20m take out laundry",
+		Public:      true,
+		Handler: func(_ context.Context, req channels.CommandRequest) (string, error) {
+			delay, text, _ := strings.Cut(req.Args, " ")
+			d, err := time.ParseDuration(delay)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid duration %q", delay)
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				return "", fmt.Errorf("reminder text is required")
+			}
+			return addReminder(cronService, req.Channel, req.ChatID, text, time.Now().Add(d))
+		},
+	})
+
+	commands.Register(channels.Command{
+		Name:        "model",
+		Usage:       "[name]",
+		Description: "show or switch the model until restart",
+		Handler: func(_ context.Context, req channels.CommandRequest) (string, error) {
+			if req.Args == "" {
+				return "Model: " + agentLoop.Model(), nil
+			}
+			agentLoop.SetModel(req.Args)
+			logger.Info("model switched to %s", req.Args)
+			return "Model switched to " + req.Args + ".", nil
+		},
+	})
+
+	return commands
+}
+
+// setupReminderShortcut answers "remind me in 20 minutes to ..." by
+// scheduling the reminder directly. Phrasing cron.ParseReminder can't
+// resolve to an exact time goes to the agent as usual.
+func setupReminderShortcut(agentLoop *agent.AgentLoop, cronService *cron.CronService) {
+	agentLoop.AddReplyHandler(func(msg bus.InboundMessage) (string, bool) {
+		at, what, ok := cron.ParseReminder(msg.Content, when.Now())
+		if !ok {
+			return "", false
+		}
+		reply, err := addReminder(cronService, msg.Channel, msg.ChatID, what, at)
+		if err != nil {
+			logger.Error("reminder shortcut: %v", err)
+			return "", false
+		}
+		return reply, true
+	})
+}
+
+// addReminder schedules a reminder for the chat and returns the
+// confirmation to send.
+func addReminder(cronService *cron.CronService, channel, chatID, text string, at time.Time) (string, error) {
+	if _, err := cronService.AddJob(cron.NewReminder(text, at, channel, chatID)); err != nil {
+		return "", err
+	}
+	now := when.Now()
+	at = at.In(now.Location())
+	layout := "15:04"
+	if at.YearDay() != now.YearDay() || at.Year() != now.Year() {
+		layout = "Mon Jan 2 15:04"
+	}
+	return fmt.Sprintf("OK, I'll remind you at %s: %s", at.Format(layout), text), nil
+}
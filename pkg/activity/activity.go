@@ -5,10 +5,12 @@ import "time"
 type EventType string
 
 const (
-	LLMTurn  EventType = "llm_turn"
-	LLMError EventType = "llm_error"
-	ToolExec EventType = "tool_exec"
-	Complete EventType = "complete"
+	LLMTurn   EventType = "llm_turn"
+	LLMError  EventType = "llm_error"
+	ToolExec  EventType = "tool_exec"
+	Complete  EventType = "complete"
+	Cancelled EventType = "cancelled"
+	Stalled   EventType = "stalled"
 )
 
 type Event struct {
@@ -25,3 +27,12 @@ type Emitter interface {
 type NopEmitter struct{}
 
 func (NopEmitter) Emit(Event) {}
+
+// Multi emits every event to each of its emitters in order.
+type Multi []Emitter
+
+func (m Multi) Emit(e Event) {
+	for _, em := range m {
+		em.Emit(e)
+	}
+}
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/activity"
+	"localagent/pkg/bus"
+	"localagent/pkg/logger"
+	"localagent/pkg/roles"
+	"localagent/pkg/tools"
+	"localagent/pkg/utils"
+)
+
+// approvalTTL is how long a held call waits for the user's answer.
+const approvalTTL = 30 * time.Minute
+
+var (
+	confirmReplies = []string{"yes", "y", "yep", "yeah", "ok", "okay", "sure", "go ahead", "do it", "confirm", "confirmed", "approve", "approved", "yes please", "please do"}
+	declineReplies = []string{"no", "n", "nope", "cancel", "don't", "dont", "stop", "never mind", "nevermind", "no thanks"}
+)
+
+// heldCall is a tool call waiting for the user's confirmation.
+type heldCall struct {
+	tool    string
+	args    map[string]any
+	summary string // "write notes/todo.md", shown back to the user
+}
+
+type heldCalls struct {
+	calls []heldCall
+	role  roles.Role
+	since time.Time
+}
+
+// approvals holds side-effecting calls made under the "suggest" autonomy
+// level, keyed by "channel:chatID" so a heartbeat or cron run can be
+// confirmed from the chat it reported to.
+type approvals struct {
+	mu   sync.Mutex
+	held map[string]*heldCalls
+}
+
+func newApprovals() *approvals {
+	return &approvals{held: make(map[string]*heldCalls)}
+}
+
+func (a *approvals) hold(chat string, role roles.Role, call heldCall) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := a.held[chat]
+	if h == nil || h.role != role || time.Since(h.since) > approvalTTL {
+		h = &heldCalls{role: role, since: time.Now()}
+		a.held[chat] = h
+	}
+	h.calls = append(h.calls, call)
+}
+
+// take removes and returns the calls held in chat for role, with the role
+// they were made under. The owner can answer for any role; other roles
+// only for their own calls.
+func (a *approvals) take(chat string, role roles.Role) ([]heldCall, roles.Role) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	h := a.held[chat]
+	if h == nil {
+		return nil, ""
+	}
+	if role == "" {
+		role = roles.Owner
+	}
+	if role != roles.Owner && role != h.role {
+		return nil, ""
+	}
+	delete(a.held, chat)
+	if time.Since(h.since) > approvalTTL {
+		return nil, ""
+	}
+	return h.calls, h.role
+}
+
+// needsApproval reports whether the call has to wait for the user under
+// the sender's autonomy level.
+func (al *AgentLoop) needsApproval(role roles.Role, name string, args map[string]any) bool {
+	return al.roles.NeedsConfirmation(role, name, al.tools.SideEffect(name, args))
+}
+
+// subagentGate holds subagents to the role and autonomy level of the run
+// that started them. A subagent can't ask the user, so calls that would
+// need confirmation are refused and left for the main agent to propose.
+func (al *AgentLoop) subagentGate(registry *tools.ToolRegistry) tools.ToolGate {
+	return func(ctx context.Context, name string, args map[string]any) *tools.ToolResult {
+		role := roles.Role(tools.RoleFromContext(ctx))
+		if !al.roles.Allows(role, name) {
+			return tools.ErrorResult(fmt.Sprintf("Tool %q is not available in this conversation.", name))
+		}
+		if al.roles.NeedsConfirmation(role, name, registry.SideEffect(name, args)) {
+			logger.Info("subagent call to %s refused: needs confirmation", name)
+			return tools.ErrorResult(fmt.Sprintf(
+				"Not run: %s needs the user's confirmation, which a subagent can't ask for. Describe the call in your result so the main agent can propose it.", name))
+		}
+		return nil
+	}
+}
+
+// holdForApproval parks a call until the user confirms it and tells the
+// model to describe it instead.
+func (al *AgentLoop) holdForApproval(opts processOptions, name string, args map[string]any) *tools.ToolResult {
+	action, target := al.tools.AuditAction(name, args)
+	summary := strings.TrimSpace(action + " " + utils.Truncate(target, 80))
+	if summary == "" {
+		summary = name
+	}
+	al.approvals.hold(opts.Channel+":"+opts.ChatID, opts.Role, heldCall{tool: name, args: args, summary: summary})
+	logger.Info("tool %s held for confirmation: %s session=%s", name, summary, opts.SessionKey)
+	return tools.NewToolResult(fmt.Sprintf(
+		"Not run yet: %s (%s) needs the user's confirmation. Tell the user exactly what it would do and ask them to reply \"yes\" to go ahead or \"no\" to cancel. Do not call it again before they answer.",
+		name, summary))
+}
+
+// resolveApproval answers a "yes" or "no" to calls held in the message's
+// chat: confirmed calls run without another LLM round trip. Any other
+// message drops them and goes to the agent, which can propose them again.
+func (al *AgentLoop) resolveApproval(ctx context.Context, msg bus.InboundMessage, role roles.Role, sessionKey string) (string, bool) {
+	calls, heldRole := al.approvals.take(msg.Channel+":"+msg.ChatID, role)
+	if len(calls) == 0 {
+		return "", false
+	}
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(msg.Content), ".!"))
+	switch {
+	case slices.Contains(declineReplies, answer):
+		return "OK, I won't do that.", true
+	case !slices.Contains(confirmReplies, answer):
+		logger.Info("dropping %d held tool call(s) in %s:%s: reply was not a confirmation", len(calls), msg.Channel, msg.ChatID)
+		return "", false
+	}
+
+	// Tools keep per-call state (SetContext); don't overlap with a run.
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	ctx = tools.WithSessionKey(ctx, sessionKey)
+	ctx = tools.WithRole(ctx, string(heldRole))
+	lines := make([]string, 0, len(calls))
+	for _, c := range calls {
+		result := al.tools.ExecuteWithContext(ctx, c.tool, c.args, msg.Channel, msg.ChatID, nil)
+		status := "success"
+		line := "Done: " + c.summary
+		if result.IsError {
+			status = "error"
+			line = fmt.Sprintf("Failed: %s: %s", c.summary, utils.Truncate(result.ForLLM, 200))
+		} else if !result.Silent && result.ForUser != "" {
+			line += "\n" + result.ForUser
+		}
+		lines = append(lines, line)
+		al.emitActivity(sessionKey, activity.Event{
+			Type:      activity.ToolExec,
+			Timestamp: time.Now(),
+			Message:   fmt.Sprintf("%s — %s (confirmed)", c.tool, status),
+			Detail:    map[string]any{"tool": c.tool, "status": status, "result": utils.Truncate(result.ForLLM, 500)},
+		})
+	}
+	return strings.Join(lines, "\n"), true
+}
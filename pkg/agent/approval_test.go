@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+	"localagent/pkg/providers"
+	"localagent/pkg/roles"
+	"localagent/pkg/tools"
+)
+
+// writeProvider asks to write note.txt, then answers with text.
+type writeProvider struct {
+	calls int
+}
+
+func (p *writeProvider) Chat(_ context.Context, messages []providers.Message, _ []providers.ToolDefinition, _ string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.calls++
+	if last := messages[len(messages)-1]; last.Role == "tool" {
+		return &providers.LLMResponse{Content: "Shall I write note.txt?", FinishReason: "stop"}, nil
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "1", Name: "write_file", Arguments: map[string]any{"path": "note.txt", "content": "milk"}}}}, nil
+}
+
+func (p *writeProvider) GetDefaultModel() string { return "" }
+
+func TestSuggestAutonomyHoldsSideEffects(t *testing.T) {
+	p := &writeProvider{}
+	al := newOverflowLoop(t, p)
+	al.roles.SetAutonomy(config.AutonomyConfig{Level: "suggest"})
+	note := filepath.Join(al.workspace, "note.txt")
+
+	answer, err := al.ProcessDirect(context.Background(), "note that we need milk", "cli:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Shall I write note.txt?" {
+		t.Errorf("answer = %q", answer)
+	}
+	if _, err := os.Stat(note); err == nil {
+		t.Fatal("write_file ran without confirmation")
+	}
+
+	calls := p.calls
+	answer, err = al.ProcessDirect(context.Background(), "Yes!", "cli:test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.calls != calls || !strings.HasPrefix(answer, "Done: file_write note.txt") {
+		t.Errorf("confirmation answer = %q, LLM calls %d -> %d", answer, calls, p.calls)
+	}
+	if data, _ := os.ReadFile(note); string(data) != "milk" {
+		t.Errorf("note.txt = %q", data)
+	}
+
+	// Unrelated replies drop held calls and go to the model.
+	al.ProcessDirect(context.Background(), "note that we need eggs", "cli:test")
+	os.Remove(note)
+	calls = p.calls
+	al.ProcessDirect(context.Background(), "actually, what's the weather?", "cli:test")
+	if p.calls == calls {
+		t.Error("a non-answer should reach the model")
+	}
+	if _, err := os.Stat(note); err == nil {
+		t.Error("held call ran without a yes")
+	}
+}
+
+func TestAutoAutonomyRunsDirectly(t *testing.T) {
+	al := newOverflowLoop(t, &writeProvider{})
+	al.roles.SetAutonomy(config.AutonomyConfig{Level: "suggest", Tools: map[string]string{"write_file": "auto"}})
+	if _, err := al.ProcessDirect(context.Background(), "note that we need milk", "cli:test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(al.workspace, "note.txt")); err != nil {
+		t.Errorf("per-tool auto override not applied: %v", err)
+	}
+}
+
+func TestSubagentGateFollowsAutonomy(t *testing.T) {
+	al := newOverflowLoop(t, &writeProvider{})
+	al.roles.SetAutonomy(config.AutonomyConfig{Level: "suggest"})
+	gate := al.subagentGate(al.tools)
+	ctx := tools.WithRole(context.Background(), string(roles.Owner))
+
+	if r := gate(ctx, "write_file", map[string]any{"path": "note.txt", "content": "milk"}); r == nil || !r.IsError {
+		t.Errorf("write_file from a subagent under suggest = %+v, want refusal", r)
+	}
+	if r := gate(ctx, "read_file", map[string]any{"path": "note.txt"}); r != nil {
+		t.Errorf("read_file refused: %s", r.ForLLM)
+	}
+	if r := gate(tools.WithRole(context.Background(), string(roles.Guest)), "exec", map[string]any{"command": "ls"}); r == nil || !r.IsError {
+		t.Error("guest subagent allowed exec")
+	}
+}
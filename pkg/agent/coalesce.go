@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"localagent/pkg/bus"
+)
+
+// maxDebounceFactor caps how long coalescing can keep extending: a user
+// who keeps typing is answered after at most this many debounce windows.
+const maxDebounceFactor = 4
+
+// nextInbound returns the next message to process: messages set aside
+// while coalescing another session come first, then the bus.
+func (al *AgentLoop) nextInbound(ctx context.Context) (bus.InboundMessage, bool) {
+	if len(al.pending) > 0 {
+		msg := al.pending[0]
+		al.pending = al.pending[1:]
+		return msg, true
+	}
+	return al.bus.ConsumeInbound(ctx)
+}
+
+// coalesce collects further messages from the same sender in msg's session
+// until none has arrived for the debounce window, and returns them with msg
+// first. Messages for other sessions are set aside in order. Only called
+// from Run, so al.pending needs no lock.
+func (al *AgentLoop) coalesce(ctx context.Context, msg bus.InboundMessage) []bus.InboundMessage {
+	batch := []bus.InboundMessage{msg}
+	if al.debounce <= 0 || msg.Channel == "system" {
+		return batch
+	}
+
+	// Messages already set aside for this conversation join first.
+	var others []bus.InboundMessage
+	for _, p := range al.pending {
+		if sameConversation(msg, p) {
+			batch = append(batch, p)
+		} else {
+			others = append(others, p)
+		}
+	}
+	al.pending = others
+
+	deadline := time.Now().Add(maxDebounceFactor * al.debounce)
+	quiet := time.Now().Add(al.debounce)
+	for {
+		until := quiet
+		if deadline.Before(until) {
+			until = deadline
+		}
+		wait := time.Until(until)
+		if wait <= 0 {
+			break
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, wait)
+		next, ok := al.bus.ConsumeInbound(waitCtx)
+		cancel()
+		if !ok {
+			break
+		}
+		if sameConversation(msg, next) {
+			batch = append(batch, next)
+			quiet = time.Now().Add(al.debounce)
+		} else {
+			al.pending = append(al.pending, next)
+		}
+	}
+	return batch
+}
+
+func sameConversation(a, b bus.InboundMessage) bool {
+	return b.Channel != "system" &&
+		a.Channel == b.Channel && a.ChatID == b.ChatID &&
+		a.SessionKey == b.SessionKey && a.SenderID == b.SenderID
+}
+
+// mergeInbound combines a batch into one prompt. The merged message keeps
+// the last message's ID and metadata so the reply threads under the newest
+// one.
+func mergeInbound(batch []bus.InboundMessage) bus.InboundMessage {
+	if len(batch) == 1 {
+		return batch[0]
+	}
+	merged := batch[len(batch)-1]
+	parts := make([]string, 0, len(batch))
+	merged.Media = nil
+	merged.Persisted = true
+	for _, m := range batch {
+		if m.Content != "" {
+			parts = append(parts, m.Content)
+		}
+		merged.Media = append(merged.Media, m.Media...)
+		merged.Persisted = merged.Persisted && m.Persisted
+	}
+	merged.Content = strings.Join(parts, "\n\n")
+	return merged
+}
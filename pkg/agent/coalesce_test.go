@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+)
+
+func TestCoalesceMergesSameConversation(t *testing.T) {
+	mb := bus.NewMessageBus()
+	al := &AgentLoop{bus: mb, debounce: 50 * time.Millisecond}
+	msg := func(id, chat, content string) bus.InboundMessage {
+		return bus.InboundMessage{ID: id, Channel: "telegram", ChatID: chat, SenderID: "u", SessionKey: "telegram:" + chat, Content: content}
+	}
+
+	mb.PublishInbound(msg("1", "a", "hey"))
+	mb.PublishInbound(msg("2", "b", "other chat"))
+	mb.PublishInbound(msg("3", "a", "are you there"))
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		mb.PublishInbound(msg("4", "a", "?"))
+	}()
+
+	first, _ := al.nextInbound(context.Background())
+	batch := al.coalesce(context.Background(), first)
+	if len(batch) != 3 {
+		t.Fatalf("expected 3 coalesced messages, got %d", len(batch))
+	}
+	merged := mergeInbound(batch)
+	if merged.ID != "4" || merged.Content != "hey\n\nare you there\n\n?" {
+		t.Errorf("unexpected merge: %+v", merged)
+	}
+
+	// The other chat's message was set aside, not lost.
+	next, _ := al.nextInbound(context.Background())
+	if next.ID != "2" {
+		t.Errorf("expected set-aside message 2 next, got %+v", next)
+	}
+}
+
+func TestCoalesceDisabled(t *testing.T) {
+	mb := bus.NewMessageBus()
+	al := &AgentLoop{bus: mb}
+	mb.PublishInbound(bus.InboundMessage{ID: "2", Channel: "cli", ChatID: "direct"})
+	if batch := al.coalesce(context.Background(), bus.InboundMessage{ID: "1", Channel: "cli", ChatID: "direct"}); len(batch) != 1 {
+		t.Errorf("expected no coalescing with debounce off, got %d", len(batch))
+	}
+}
@@ -7,16 +7,20 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"localagent/pkg/config"
 	"localagent/pkg/logger"
 	"localagent/pkg/prompts"
 	"localagent/pkg/providers"
 	"localagent/pkg/skills"
 	"localagent/pkg/tools"
 	"localagent/pkg/utils"
+	"localagent/pkg/vault"
+	"localagent/pkg/when"
 )
 
 type PDFService struct {
@@ -36,14 +40,13 @@ type ContextBuilder struct {
 	tools        *tools.ToolRegistry // Direct reference to tool registry
 	pdf          *PDFService
 	stt          *STTService
-}
-
-func getGlobalConfigDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-	return filepath.Join(home, ".localagent")
+	userDir      string // where USER.md is read from; the workspace for the owner
+	member       string // household member namespace, empty for the owner
+	timeNote     func() string
+	upcoming     func() string
+	worldClock   func() string
+	prompt       config.PromptConfig
+	cacheable    bool // keep the system prompt stable between turns
 }
 
 func NewContextBuilder(workspace string) *ContextBuilder {
@@ -51,15 +54,31 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	// Use the skills/ directory under the current working directory
 	wd, _ := os.Getwd()
 	builtinSkillsDir := filepath.Join(wd, "skills")
-	globalSkillsDir := filepath.Join(getGlobalConfigDir(), "skills")
+	globalSkillsDir := filepath.Join(config.BaseDir(), "skills") // shared by all profiles
 
 	return &ContextBuilder{
 		workspace:    workspace,
 		skillsLoader: skills.NewSkillsLoader(workspace, globalSkillsDir, builtinSkillsDir),
 		memory:       NewMemoryStore(workspace),
+		userDir:      workspace,
 	}
 }
 
+// ForMember returns a builder for a non-owner household member, whose
+// USER.md and memory live under workspace/members/<namespace>.
+func (cb *ContextBuilder) ForMember(namespace string) *ContextBuilder {
+	if namespace == "" {
+		return cb
+	}
+	dir := filepath.Join(cb.workspace, "members", namespace)
+	member := *cb
+	member.userDir = dir
+	member.memory = NewMemoryStore(dir)
+	member.member = namespace
+	member.upcoming = nil // the owner's calendar and tasks
+	return &member
+}
+
 // GetMemoryStore returns the memory store for direct access (e.g. memory flush).
 func (cb *ContextBuilder) GetMemoryStore() *MemoryStore {
 	return cb.memory
@@ -79,8 +98,47 @@ func (cb *ContextBuilder) SetSTTService(url, apiKey string) {
 	cb.stt = &STTService{URL: url, APIKey: apiKey}
 }
 
+// SetTimeNote adds fn's output, when not empty, below the current time
+// in the system prompt (e.g. travel mode's destination timezone).
+func (cb *ContextBuilder) SetTimeNote(fn func() string) {
+	cb.timeNote = fn
+}
+
+// SetUpcoming adds fn's output, when not empty, to every turn under
+// "Upcoming" (next events, tasks due today, running timers).
+func (cb *ContextBuilder) SetUpcoming(fn func() string) {
+	cb.upcoming = fn
+}
+
+// SetWorldClock adds fn's output, when not empty, to every turn under
+// "World Clock" (the local time at the user's places).
+func (cb *ContextBuilder) SetWorldClock(fn func() string) {
+	cb.worldClock = fn
+}
+
+// SetPromptLayout sets the system prompt section order, token budgets and
+// custom sections. The layout must be valid (config.PromptConfig.Validate).
+func (cb *ContextBuilder) SetPromptLayout(layout config.PromptConfig) {
+	cb.prompt = layout
+}
+
+// SetCacheable splits the system message into a stable first part, which
+// providers can cache, and a second part with what changes every turn: the
+// time of day, session, variables and summary.
+func (cb *ContextBuilder) SetCacheable(on bool) {
+	cb.cacheable = on
+}
+
 func (cb *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 (Monday)")
+	now := when.Now().Format("2006-01-02 15:04 MST (Monday)")
+	if cb.cacheable {
+		now = when.Now().Format("2006-01-02 (Monday)") + ", time of day under Current Time at the end"
+	}
+	if cb.timeNote != nil {
+		if note := cb.timeNote(); note != "" {
+			now += "\n" + note
+		}
+	}
 	workspacePath, _ := filepath.Abs(filepath.Join(cb.workspace))
 	rt := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
 
@@ -112,34 +170,95 @@ func (cb *ContextBuilder) buildToolsSection() string {
 }
 
 func (cb *ContextBuilder) BuildSystemPrompt() string {
-	parts := []string{}
-
-	// Core identity section
-	parts = append(parts, cb.getIdentity())
+	order := cb.prompt.Order
+	if len(order) == 0 {
+		order = slices.Clone(config.DefaultPromptOrder)
+		for _, sec := range cb.prompt.Sections {
+			order = append(order, sec.Name)
+		}
+	}
 
-	// Bootstrap files
-	bootstrapContent := cb.LoadBootstrapFiles()
-	if bootstrapContent != "" {
-		parts = append(parts, bootstrapContent)
+	parts := []string{}
+	var sizes []string
+	for _, name := range order {
+		content := cb.buildSection(name)
+		if content == "" {
+			continue
+		}
+		if budget := cb.prompt.Budgets[name]; budget > 0 {
+			content = truncateTokens(content, budget)
+		}
+		parts = append(parts, content)
+		sizes = append(sizes, fmt.Sprintf("%s=%d", name, utf8.RuneCountInString(content)/3))
 	}
+	logger.Debug("system prompt sections (est. tokens): %s", strings.Join(sizes, " "))
 
-	// Heartbeat behavior
-	parts = append(parts, prompts.HeartbeatSystem)
+	// Join with "---" separator
+	return strings.Join(parts, "\n\n---\n\n")
+}
 
-	// Skills - show summary, AI can read full content with read_file tool
-	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
-	if skillsSummary != "" {
-		parts = append(parts, fmt.Sprintf(prompts.SkillsSection, skillsSummary))
+// buildSection returns the content of a built-in or custom section, or ""
+// when it has nothing to say.
+func (cb *ContextBuilder) buildSection(name string) string {
+	switch name {
+	case "identity":
+		return cb.getIdentity()
+	case "bootstrap":
+		return cb.LoadBootstrapFiles()
+	case "heartbeat":
+		return prompts.HeartbeatSystem
+	case "skills":
+		// Summary only; the AI can read full content with read_file
+		if summary := cb.skillsLoader.BuildSkillsSummary(); summary != "" {
+			return fmt.Sprintf(prompts.SkillsSection, summary)
+		}
+	case "member":
+		if cb.member != "" {
+			return fmt.Sprintf(prompts.MemberSection, cb.member, cb.userDir)
+		}
+	case "memory":
+		if memoryContext := cb.memory.GetMemoryContext(); memoryContext != "" {
+			return "# Memory\n\n" + memoryContext
+		}
+	default:
+		for _, sec := range cb.prompt.Sections {
+			if sec.Name != name {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(cb.workspace, sec.File))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					logger.Warn("prompt section %s: %v", name, err)
+				}
+				return ""
+			}
+			body := strings.TrimSpace(string(data))
+			if body == "" {
+				return ""
+			}
+			title := sec.Title
+			if title == "" {
+				title = filepath.Base(sec.File)
+			}
+			return "# " + title + "\n\n" + body
+		}
 	}
+	return ""
+}
 
-	// Memory context
-	memoryContext := cb.memory.GetMemoryContext()
-	if memoryContext != "" {
-		parts = append(parts, "# Memory\n\n"+memoryContext)
+// truncateTokens cuts s to about budget tokens (3 characters each, as in
+// estimateTokens), preferring a line break, and says so.
+func truncateTokens(s string, budget int) string {
+	runes := []rune(s)
+	limit := budget * 3
+	if len(runes) <= limit {
+		return s
 	}
-
-	// Join with "---" separator
-	return strings.Join(parts, "\n\n---\n\n")
+	cut := string(runes[:limit])
+	if i := strings.LastIndexByte(cut, '\n'); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \n") + fmt.Sprintf("\n\n[... truncated to ~%d tokens]", budget)
 }
 
 func (cb *ContextBuilder) LoadBootstrapFiles() string {
@@ -153,7 +272,10 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	var result strings.Builder
 	for _, filename := range bootstrapFiles {
 		filePath := filepath.Join(cb.workspace, filename)
-		if data, err := os.ReadFile(filePath); err == nil {
+		if filename == "USER.md" {
+			filePath = filepath.Join(cb.userDir, filename)
+		}
+		if data, err := vault.ReadFile(filePath); err == nil {
 			fmt.Fprintf(&result, "## %s\n\n%s\n\n", filename, string(data))
 		}
 	}
@@ -161,31 +283,59 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	return result.String()
 }
 
-func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
+func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, vars map[string]string, currentMessage string, media []string, channel, chatID string) []providers.Message {
 	messages := []providers.Message{}
 
 	systemPrompt := cb.BuildSystemPrompt()
 
+	// Everything below changes between turns; with caching it goes after
+	// the stable prefix.
+	var volatile strings.Builder
+	if cb.cacheable {
+		volatile.WriteString("\n\n## Current Time\n" + when.Now().Format("15:04 MST"))
+	}
+
 	// Add Current Session info if provided
 	if channel != "" && chatID != "" {
-		systemPrompt += fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
+		fmt.Fprintf(&volatile, "\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
 	}
 
-	logger.Debug("system prompt built: %d chars, %d lines",
-		len(systemPrompt), strings.Count(systemPrompt, "\n")+1)
+	if cb.worldClock != nil {
+		if note := cb.worldClock(); note != "" {
+			volatile.WriteString("\n\n## World Clock\n" + note)
+		}
+	}
+
+	if cb.upcoming != nil {
+		if note := cb.upcoming(); note != "" {
+			volatile.WriteString("\n\n## Upcoming\n" + note)
+		}
+	}
+
+	if len(vars) > 0 {
+		volatile.WriteString("\n\n## Session Variables\n\nSet with the vars tool; keep them up to date.\n" + tools.FormatVars(vars, ""))
+	}
 
 	if summary != "" {
-		systemPrompt += "\n\n## Summary of Previous Conversation\n\n" + summary
+		volatile.WriteString("\n\n## Summary of Previous Conversation\n\n" + summary)
+	}
+
+	systemMsg := providers.Message{Role: "system", Content: systemPrompt + volatile.String()}
+	if cb.cacheable && volatile.Len() > 0 {
+		systemMsg.ContentParts = []providers.ContentPart{
+			{Type: "text", Text: systemPrompt},
+			{Type: "text", Text: strings.TrimPrefix(volatile.String(), "\n\n")},
+		}
 	}
 
+	logger.Debug("system prompt built: %d chars, %d lines",
+		len(systemMsg.Content), strings.Count(systemMsg.Content, "\n")+1)
+
 	for len(history) > 0 && history[0].Role == "tool" {
 		history = history[1:]
 	}
 
-	messages = append(messages, providers.Message{
-		Role:    "system",
-		Content: systemPrompt,
-	})
+	messages = append(messages, systemMsg)
 
 	messages = append(messages, history...)
 
@@ -259,6 +409,13 @@ func (cb *ContextBuilder) buildUserMessage(text string, media []string) provider
 					Text: fmt.Sprintf("\n--- Audio: %s ---\n%s\n--- End of %s ---", filename, audioText, filename),
 				})
 			}
+		} else if strings.EqualFold(filepath.Ext(mediaPath), ".ics") {
+			// Calendar invitations: show the path so the calendar tool can import it
+			filename := filepath.Base(mediaPath)
+			parts = append(parts, providers.ContentPart{
+				Type: "text",
+				Text: fmt.Sprintf("\n--- Calendar file: %s (path: %s) ---\n%s\n--- End of %s ---", filename, mediaPath, string(data), filename),
+			})
 		} else if utf8.Valid(data) {
 			// Include text-based files inline
 			filename := filepath.Base(mediaPath)
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestBuildSystemPromptLayout(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "SOUL.md"), []byte("Be kind."), 0644)
+	os.MkdirAll(filepath.Join(workspace, "notes"), 0755)
+	os.WriteFile(filepath.Join(workspace, "notes", "projects.md"), []byte(strings.Repeat("project line\n", 100)), 0644)
+
+	cb := NewContextBuilder(workspace)
+	cb.SetPromptLayout(config.PromptConfig{
+		Order:    []string{"projects", "bootstrap", "missing"},
+		Budgets:  map[string]int{"projects": 20},
+		Sections: []config.PromptSection{{Name: "projects", File: "notes/projects.md", Title: "Projects"}, {Name: "missing", File: "nope.md"}},
+	})
+	prompt := cb.BuildSystemPrompt()
+
+	parts := strings.Split(prompt, "\n\n---\n\n")
+	if len(parts) != 2 {
+		t.Fatalf("want projects and bootstrap sections, got %d:\n%s", len(parts), prompt)
+	}
+	if !strings.HasPrefix(parts[0], "# Projects\n\nproject line") || !strings.HasSuffix(parts[0], "[... truncated to ~20 tokens]") {
+		t.Errorf("projects section = %q", parts[0])
+	}
+	if len(parts[0]) > 20*3+40 {
+		t.Errorf("projects section over budget: %d chars", len(parts[0]))
+	}
+	if !strings.Contains(parts[1], "## SOUL.md\n\nBe kind.") {
+		t.Errorf("bootstrap section = %q", parts[1])
+	}
+	if strings.Contains(prompt, "# Memory") {
+		t.Error("sections left out of the order must not be included")
+	}
+}
+
+func TestBuildSystemPromptDefaultOrder(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "USER.md"), []byte("Name: Sam"), 0644)
+	os.WriteFile(filepath.Join(workspace, "CONTEXT.md"), []byte("Extra context"), 0644)
+
+	cb := NewContextBuilder(workspace)
+	cb.SetPromptLayout(config.PromptConfig{Sections: []config.PromptSection{{Name: "context", File: "CONTEXT.md"}}})
+	prompt := cb.BuildSystemPrompt()
+
+	user := strings.Index(prompt, "Name: Sam")
+	extra := strings.Index(prompt, "# CONTEXT.md\n\nExtra context")
+	if user < 0 || extra < user {
+		t.Errorf("custom sections should follow the built-in ones:\n%s", prompt)
+	}
+}
+
+func TestBuildMessagesCacheable(t *testing.T) {
+	cb := NewContextBuilder(t.TempDir())
+	cb.SetCacheable(true)
+	first := cb.BuildMessages(nil, "", nil, "hi", nil, "web", "chat-1")[0]
+	second := cb.BuildMessages(nil, "earlier we talked", map[string]string{"city": "Bern"}, "hi", nil, "web", "chat-2")[0]
+
+	if len(first.ContentParts) != 2 || len(second.ContentParts) != 2 {
+		t.Fatalf("want stable and volatile parts, got %d and %d", len(first.ContentParts), len(second.ContentParts))
+	}
+	if first.ContentParts[0].Text != second.ContentParts[0].Text {
+		t.Error("stable part changed between turns")
+	}
+	tail := second.ContentParts[1].Text
+	if !strings.HasPrefix(tail, "## Current Time\n") || !strings.Contains(tail, "Chat ID: chat-2") || !strings.Contains(tail, "earlier we talked") {
+		t.Errorf("volatile part = %q", tail)
+	}
+	if strings.Contains(second.ContentParts[0].Text, "chat-2") {
+		t.Error("session info leaked into the stable part")
+	}
+}
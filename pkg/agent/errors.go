@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"localagent/pkg/errs"
+	"localagent/pkg/i18n"
+	"localagent/pkg/providers"
+)
+
+// userError turns a processing failure into a short message for the chat.
+// The raw error is only appended when verbose is set; callers log it either
+// way. Webchat renders markdown, so details go in a code block there.
+func userError(err error, channel string, verbose bool) string {
+	msg := friendlyError(err)
+	switch {
+	case !verbose:
+		return msg
+	case channel == "web":
+		return msg + "\n\n```\n" + err.Error() + "\n```"
+	}
+	return msg + "\n\n" + i18n.T("error.details", err.Error())
+}
+
+func friendlyError(err error) string {
+	var apiErr *providers.APIError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, providers.ErrNoAPIBase):
+		return i18n.T("error.no_api_base")
+	case errors.Is(err, errs.ErrAuth):
+		return i18n.T("error.auth")
+	case errors.Is(err, errs.ErrRateLimited):
+		return i18n.T("error.rate_limited")
+	case errors.Is(err, errs.ErrNotFound):
+		return i18n.T("error.model_not_found")
+	case isContextOverflow(err):
+		return i18n.T("error.context_overflow")
+	case errs.Kind(err) == errs.ErrTimeout:
+		return i18n.T("error.timeout")
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500:
+		return i18n.T("error.server")
+	case errors.As(err, &apiErr):
+		return i18n.T("error.bad_request")
+	case errors.As(err, &opErr), errors.As(err, &netErr):
+		return i18n.T("error.unreachable")
+	}
+	return i18n.T("error.generic")
+}
+
+// rateLimitRetries is how many times a rate-limited LLM call is retried.
+const rateLimitRetries = 2
+
+// rateLimitDelay returns how long to wait before retrying a rate-limited
+// LLM call: the provider's Retry-After, else 2s doubling per attempt,
+// capped at 30s. ok is false for errors that aren't rate limits.
+func rateLimitDelay(err error, attempt int) (delay time.Duration, ok bool) {
+	if !errors.Is(err, errs.ErrRateLimited) {
+		return 0, false
+	}
+	delay = 2 * time.Second << attempt
+	var apiErr *providers.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		delay = apiErr.RetryAfter
+	}
+	return min(delay, 30*time.Second), true
+}
+
+// contextOverflowHints are phrases providers use when a request exceeds the
+// model's context window (OpenAI, Anthropic, llama.cpp, vLLM, Ollama).
+var contextOverflowHints = []string{
+	"context length", "context_length", "context window", "maximum context",
+	"too many tokens", "prompt is too long", "exceeds the available context",
+	"input is too long",
+}
+
+// isContextOverflow reports whether err is the provider rejecting a request
+// that doesn't fit the model's context window.
+func isContextOverflow(err error) bool {
+	var apiErr *providers.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	body := strings.ToLower(apiErr.Body)
+	for _, hint := range contextOverflowHints {
+		if strings.Contains(body, hint) {
+			return true
+		}
+	}
+	return false
+}
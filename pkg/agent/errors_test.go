@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/providers"
+)
+
+func TestUserErrorMapsCommonFailures(t *testing.T) {
+	wrap := func(err error) error { return fmt.Errorf("LLM call failed: %w", err) }
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{wrap(&providers.APIError{StatusCode: 401, Body: "bad key"}), "API key"},
+		{wrap(&providers.APIError{StatusCode: 429}), "rate limiting"},
+		{wrap(&providers.APIError{StatusCode: 404}), "model isn't available"},
+		{wrap(&providers.APIError{StatusCode: 400, Body: `{"error":"maximum context length exceeded"}`}), "too long"},
+		{wrap(&providers.APIError{StatusCode: 500, Body: "oops"}), "server had an error"},
+		{wrap(providers.ErrNoAPIBase), "provider.api_base"},
+		{wrap(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}), "can't reach"},
+		{wrap(context.DeadlineExceeded), "too long to answer"},
+		{wrap(&providers.APIError{StatusCode: 404, Body: "model 'llama9' not found"}), "model isn't available"},
+		{errors.New("boom"), "Something went wrong"},
+	}
+	for _, tt := range tests {
+		got := userError(tt.err, "telegram", false)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("userError(%v) = %q, want it to contain %q", tt.err, got, tt.want)
+		}
+		if strings.Contains(got, "LLM call failed") {
+			t.Errorf("userError(%v) leaked raw error: %q", tt.err, got)
+		}
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	if _, ok := rateLimitDelay(&providers.APIError{StatusCode: 500}, 0); ok {
+		t.Error("server errors should not be retried as rate limits")
+	}
+	if d, ok := rateLimitDelay(&providers.APIError{StatusCode: 429}, 1); !ok || d != 4*time.Second {
+		t.Errorf("backoff = %v, %v", d, ok)
+	}
+	if d, _ := rateLimitDelay(&providers.APIError{StatusCode: 429, RetryAfter: 7 * time.Second}, 0); d != 7*time.Second {
+		t.Errorf("Retry-After delay = %v", d)
+	}
+	if d, _ := rateLimitDelay(&providers.APIError{StatusCode: 429, RetryAfter: time.Hour}, 0); d != 30*time.Second {
+		t.Errorf("capped delay = %v", d)
+	}
+}
+
+func TestUserErrorVerbose(t *testing.T) {
+	err := &providers.APIError{StatusCode: 500, Body: "upstream exploded"}
+	if got := userError(err, "telegram", true); !strings.Contains(got, "Details: API request failed") {
+		t.Errorf("telegram verbose = %q", got)
+	}
+	if got := userError(err, "web", true); !strings.Contains(got, "```\nAPI request failed") {
+		t.Errorf("web verbose = %q", got)
+	}
+}
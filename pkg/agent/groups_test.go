@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"localagent/pkg/providers"
+)
+
+// groupProvider opens the finance group, then calls stock_price.
+type groupProvider struct {
+	offered [][]string
+}
+
+func (p *groupProvider) Chat(_ context.Context, _ []providers.Message, defs []providers.ToolDefinition, _ string, _ map[string]any) (*providers.LLMResponse, error) {
+	var names []string
+	for _, d := range defs {
+		names = append(names, d.Function.Name)
+	}
+	p.offered = append(p.offered, names)
+	switch len(p.offered) {
+	case 1:
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "1", Name: "open_tools", Arguments: map[string]any{"group": "finance"}}}}, nil
+	case 2:
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "2", Name: "convert_currency", Arguments: map[string]any{}}}}, nil
+	}
+	return &providers.LLMResponse{Content: "done", FinishReason: "stop"}, nil
+}
+
+func (p *groupProvider) GetDefaultModel() string { return "" }
+
+func TestToolGroupGateway(t *testing.T) {
+	p := &groupProvider{}
+	al := newOverflowLoop(t, p)
+	al.toolGroups = true
+
+	if _, err := al.ProcessDirect(context.Background(), "How is AAPL doing?", "cli:test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.offered) != 3 {
+		t.Fatalf("LLM calls = %d", len(p.offered))
+	}
+	first, second := p.offered[0], p.offered[1]
+	if !slices.Contains(first, "open_tools") || slices.Contains(first, "stock_price") || slices.Contains(first, "read_file") {
+		t.Errorf("first request tools = %v", first)
+	}
+	if !slices.Contains(first, "message") {
+		t.Errorf("ungrouped tools should always be offered: %v", first)
+	}
+	if !slices.Contains(second, "stock_price") || slices.Contains(second, "read_file") {
+		t.Errorf("second request tools = %v", second)
+	}
+	if len(second) <= len(first)-1 || len(first) >= len(al.toolDefsFor("", nil)) {
+		t.Errorf("collapsed %d, opened %d, full %d", len(first), len(second), len(al.toolDefsFor("", nil)))
+	}
+}
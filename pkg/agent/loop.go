@@ -3,9 +3,12 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -15,28 +18,42 @@ import (
 	"database/sql"
 
 	"localagent/pkg/activity"
+	"localagent/pkg/audit"
 	"localagent/pkg/bus"
 	"localagent/pkg/config"
 	"localagent/pkg/constants"
 	"localagent/pkg/db"
+	"localagent/pkg/federation"
 	"localagent/pkg/finance"
+	"localagent/pkg/hooks"
+	"localagent/pkg/i18n"
+	"localagent/pkg/idle"
 	"localagent/pkg/logger"
 	"localagent/pkg/prompts"
 	"localagent/pkg/providers"
+	"localagent/pkg/readstate"
+	"localagent/pkg/redact"
+	"localagent/pkg/roles"
 	"localagent/pkg/session"
 	"localagent/pkg/state"
+	"localagent/pkg/telemetry"
+	"localagent/pkg/templates"
 	"localagent/pkg/todo"
 	"localagent/pkg/tools"
 	"localagent/pkg/utils"
+	"localagent/pkg/when"
 )
 
 type AgentLoop struct {
 	bus            *bus.MessageBus
 	provider       providers.LLMProvider
+	routes         map[string]ModelRoute
 	workspace      string
 	model          string
+	modelMu        sync.RWMutex
 	contextWindow  int // Maximum context window size in tokens
 	maxIterations  int
+	maxDuration    time.Duration // wall-clock limit per message
 	sessions       *session.SessionManager
 	state          *state.Manager
 	contextBuilder *ContextBuilder
@@ -45,24 +62,79 @@ type AgentLoop struct {
 	running        atomic.Bool
 	mu             sync.Mutex // Serializes runAgentLoop to prevent races on shared tool state
 	summarizing    sync.Map   // Tracks which sessions are currently being summarized
+	inflight       sync.Map   // sessionKey -> *inflightRun for cancellation
 	stopCleanup    chan struct{}
+	media          *utils.MediaRetention
+	idle           *idle.Scheduler // nil unless agents.idle_work is enabled
 	database       *sql.DB
 	todoService    *todo.TodoService
+	audit          *audit.Log
+	roles          *roles.Resolver
+	redactor       *redact.Redactor
+	heartbeat      config.HeartbeatConfig
+	debounce       time.Duration        // inbound coalescing window, 0 = off
+	pending        []bus.InboundMessage // set aside while coalescing, owned by Run
+	readState      *readstate.Tracker
+	verboseErrors  bool // append raw errors to the friendly message sent to chats
+	toolGroups     bool // offer grouped tools behind open_tools until a group is opened
+	hooks          *hooks.Runner
+	watchers       sync.Map // session key -> *func(activity.Event), see ProcessRemote
+	replyHandlers  []ReplyHandler
+	approvals      *approvals // calls held under the "suggest" autonomy level
+	onboarding     config.OnboardingConfig
+	commandHelp    func(channel, role string) string // see SetCommandHelp
+	streamSink     func(sessionKey, delta string)
+}
+
+// ReplyHandler gets a chat message before the LLM does. When it handles
+// the message (e.g. a "taken" reply to a medication reminder) its reply is
+// sent instead of running the agent.
+type ReplyHandler func(msg bus.InboundMessage) (reply string, handled bool)
+
+// ErrCancelled is returned when processing was stopped before completion,
+// either through Cancel or because the caller's context was cancelled.
+// Any partial content is returned alongside it.
+var ErrCancelled = errors.New("cancelled")
+
+// errProcessingTimeout is the cancellation cause when a message exceeds maxDuration.
+var errProcessingTimeout = errors.New("processing time limit reached")
+
+// stoppedError records which step the iteration loop was on when its
+// context was cancelled.
+type stoppedError struct {
+	step string
+}
+
+func (e *stoppedError) Error() string { return "cancelled during " + e.step }
+func (e *stoppedError) Unwrap() error { return ErrCancelled }
+
+func maxProcessingDuration(cfg *config.Config) time.Duration {
+	if secs := cfg.Agents.Defaults.MaxProcessingSecs; secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 5 * time.Minute
 }
 
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string   // Session identifier for history/context
-	Channel         string   // Target channel for tool execution
-	ChatID          string   // Target chat ID for tool execution
-	SenderID        string   // Sender identifier (for activity events)
-	UserMessage     string   // User message content (may include prefix)
-	Media           []string // Media file paths attached to the message
-	DefaultResponse string   // Response when LLM returns empty
-	EnableSummary   bool     // Whether to trigger summarization
-	SendResponse    bool     // Whether to send response via bus
-	NoHistory       bool     // If true, don't load session history (for heartbeat)
-	Persisted       bool     // If true, user message was already saved to session by the channel
+	SessionKey      string                // Session identifier for history/context
+	Channel         string                // Target channel for tool execution
+	ChatID          string                // Target chat ID for tool execution
+	SenderID        string                // Sender identifier (for activity events)
+	UserMessage     string                // User message content (may include prefix)
+	Media           []string              // Media file paths attached to the message
+	DefaultResponse string                // Response when LLM returns empty
+	EnableSummary   bool                  // Whether to trigger summarization
+	SendResponse    bool                  // Whether to send response via bus
+	NoHistory       bool                  // If true, don't load session history (for heartbeat)
+	Persisted       bool                  // If true, user message was already saved to session by the channel
+	Role            roles.Role            // Sender's household role; empty means owner
+	Namespace       string                // Member namespace for non-owner senders, see roles.Namespace
+	Model           string                // Overrides al.model when set
+	Provider        providers.LLMProvider // Overrides al.provider when set
+	MaxIterations   int                   // Overrides al.maxIterations when > 0
+	Tools           []string              // If set, only these tools are offered and executable
+	TokenBudget     int                   // Stop iterating once this many tokens are used, 0 = unlimited
 }
 
 // createToolRegistry creates a tool registry with common tools.
@@ -84,6 +156,9 @@ func createToolRegistry(workspace string, cfg *config.Config, msgBus *bus.Messag
 	registry.Register(tools.NewNewsTool(30))
 	registry.Register(tools.NewAIPapersTool(30))
 
+	// Network diagnostics
+	registry.Register(tools.NewNetCheckTool(cfg.Tools.NetCheck.Hosts, cfg.Tools.NetCheck.SpeedTestURL))
+
 	// Yahoo Finance tools (shared client for auth)
 	yf := finance.NewYahooClient()
 	registry.Register(tools.NewStockTool(yf))
@@ -103,6 +178,10 @@ func createToolRegistry(workspace string, cfg *config.Config, msgBus *bus.Messag
 	registry.Register(tools.NewRemoveLinkTool(todoService))
 
 	registry.Register(tools.NewMessageTool(msgBus, sessions))
+	registry.Register(tools.NewVarsTool(sessions))
+	registry.Register(tools.NewForkSessionTool(sessions))
+	registry.Register(tools.NewRunTemplateTool(templates.NewStore(filepath.Join(workspace, "templates"))))
+	registry.SetRepairHints(!cfg.Agents.Defaults.DisableToolHints)
 
 	if cfg.Tools.PDF.URL != "" {
 		registry.Register(tools.NewPDFToTextTool(workspace, cfg.Tools.PDF.URL, cfg.Tools.PDF.ResolveAPIKey()))
@@ -117,7 +196,33 @@ func createToolRegistry(workspace string, cfg *config.Config, msgBus *bus.Messag
 	}
 
 	if cfg.Tools.Calendar.URL != "" {
-		registry.Register(tools.NewCalendarTool(cfg.Tools.Calendar.URL, cfg.Tools.Calendar.Username, cfg.Tools.Calendar.ResolvePassword()))
+		registry.Register(tools.NewCalendarTool(workspace, cfg.Tools.Calendar.URL, cfg.Tools.Calendar.Username, cfg.Tools.Calendar.ResolvePassword()))
+	}
+
+	if slices.ContainsFunc(cfg.Federation.Peers, func(p config.PeerConfig) bool { return p.URL != "" }) {
+		registry.Register(tools.NewRemoteAgentTool(msgBus, cfg.Federation.Peers))
+	}
+
+	if cfg.Tools.Docker.Host != "" {
+		dockerTool, err := tools.NewDockerTool(cfg.Tools.Docker.Host, cfg.Tools.Docker.CertPath, cfg.Tools.Docker.RestartAllowlist)
+		if err != nil {
+			logger.Warn("docker tool disabled: %v", err)
+		} else {
+			registry.Register(dockerTool)
+		}
+	}
+
+	for _, def := range cfg.Tools.Custom {
+		tool, err := tools.NewCustomTool(def, workspace)
+		if err != nil {
+			logger.Warn("custom tool disabled: %v", err)
+			continue
+		}
+		if _, exists := registry.Get(tool.Name()); exists {
+			logger.Warn("custom tool %s disabled: a built-in tool has that name", tool.Name())
+			continue
+		}
+		registry.Register(tool)
 	}
 
 	return registry
@@ -128,6 +233,14 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	os.MkdirAll(workspace, 0755)
 	os.MkdirAll(filepath.Join(workspace, "media"), 0755)
 
+	if tz := cfg.Agents.Defaults.Timezone; tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			when.SetLocation(loc)
+		} else {
+			logger.Warn("invalid timezone %q, using system local: %v", tz, err)
+		}
+	}
+
 	// Open SQLite database and migrate
 	dbPath := filepath.Join(workspace, "localagent.db")
 	database, err := db.Open(dbPath)
@@ -135,11 +248,6 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		logger.Error("failed to open database: %v", err)
 		os.Exit(1)
 	}
-	// One-shot migration from old JSON file
-	jsonPath := filepath.Join(workspace, "todo", "tasks.json")
-	if err := db.MigrateFromJSON(database, jsonPath); err != nil {
-		logger.Warn("JSON migration: %v", err)
-	}
 	todoService := todo.NewTodoService(database)
 
 	sessionsManager := session.NewSessionManager(filepath.Join(workspace, "sessions"))
@@ -153,6 +261,12 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
+	var auditLog *audit.Log
+	if !cfg.Audit.Disabled {
+		auditLog = audit.NewLog(cfg.AuditPath(), cfg.Audit.Retention())
+		toolsRegistry.SetAuditLog(auditLog)
+		subagentTools.SetAuditLog(auditLog)
+	}
 
 	// Create state manager for atomic state persistence
 	stateManager := state.NewManager(workspace)
@@ -166,30 +280,50 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	if cfg.Tools.STT.URL != "" {
 		contextBuilder.SetSTTService(cfg.Tools.STT.URL, cfg.Tools.STT.ResolveAPIKey())
 	}
+	contextBuilder.SetCacheable(cfg.Provider.PromptCache != "")
+	if layout, err := cfg.PromptLayout(); err != nil {
+		logger.Error("prompt layout ignored, using the default: %v", err)
+	} else {
+		contextBuilder.SetPromptLayout(layout)
+	}
 
 	stopCleanup := make(chan struct{})
-	mediaDir := filepath.Join(workspace, "media")
+	mediaRetention := newMediaRetention(cfg, filepath.Join(workspace, "media"))
+	mediaRetention.SetReferenced(sessionsManager.ReferencedMedia)
+	var idleWork *idle.Scheduler
+	maxIdle := cfg.Storage.SessionArchiveAfter()
+	if cfg.Agents.IdleWork.Enabled {
+		idleWork = idle.New(cfg.Agents.IdleWork.Quiet())
+		idleWork.Every("media pruning", func(context.Context) { mediaRetention.Prune() })
+		if maxIdle > 0 {
+			idleWork.Every("session archival", func(context.Context) { sessionsManager.Archive(maxIdle) })
+		}
+		go idleWork.Run(time.Minute, stopCleanup)
+	} else {
+		go mediaRetention.Run(5*time.Minute, stopCleanup)
+		if maxIdle > 0 {
+			go sessionsManager.RunArchival(maxIdle, time.Hour, stopCleanup)
+		}
+	}
 
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-stopCleanup:
-				return
-			case <-ticker.C:
-				utils.CleanOldMedia(mediaDir, 10*time.Minute)
-			}
+	roleResolver := roles.NewResolver(cfg.Roles)
+	roleResolver.SetAutonomy(cfg.Agents.Autonomy)
+	var ownerOnly []string
+	for _, def := range cfg.Tools.Custom {
+		if def.IsOwnerOnly() {
+			ownerOnly = append(ownerOnly, def.Name)
 		}
-	}()
+	}
+	roleResolver.SetOwnerOnly(ownerOnly)
 
-	return &AgentLoop{
+	al := &AgentLoop{
 		bus:            msgBus,
 		provider:       provider,
 		workspace:      workspace,
 		model:          cfg.Agents.Defaults.Model,
 		contextWindow:  cfg.Agents.Defaults.MaxTokens,
 		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
+		maxDuration:    maxProcessingDuration(cfg),
 		sessions:       sessionsManager,
 		state:          stateManager,
 		contextBuilder: contextBuilder,
@@ -197,9 +331,22 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		activity:       activity.NopEmitter{},
 		summarizing:    sync.Map{},
 		stopCleanup:    stopCleanup,
+		idle:           idleWork,
+		media:          mediaRetention,
 		database:       database,
 		todoService:    todoService,
+		audit:          auditLog,
+		roles:          roleResolver,
+		approvals:      newApprovals(),
+		heartbeat:      cfg.Heartbeat,
+		debounce:       time.Duration(cfg.Agents.Defaults.DebounceMS) * time.Millisecond,
+		verboseErrors:  cfg.Agents.Defaults.VerboseErrors,
+		toolGroups:     cfg.Agents.Defaults.ToolDefinitions == "groups",
+		onboarding:     cfg.Agents.Onboarding,
 	}
+	subagentManager.SetGate(al.subagentGate(subagentTools))
+	go al.watchdog(cfg.Agents.Watchdog, stopCleanup)
+	return al
 }
 
 func (al *AgentLoop) SetActivityEmitter(e activity.Emitter) {
@@ -210,12 +357,119 @@ func (al *AgentLoop) GetTodoService() *todo.TodoService {
 	return al.todoService
 }
 
+// IdleScheduler returns the idle work scheduler, nil when
+// agents.idle_work is disabled.
+func (al *AgentLoop) IdleScheduler() *idle.Scheduler {
+	return al.idle
+}
+
+// GetMediaRetention returns the shared media retention policy so other
+// components (e.g. webchat uploads) can register their media directories.
+func (al *AgentLoop) GetMediaRetention() *utils.MediaRetention {
+	return al.media
+}
+
+func newMediaRetention(cfg *config.Config, dirs ...string) *utils.MediaRetention {
+	maxAge := time.Duration(cfg.Media.MaxAgeMinutes) * time.Minute
+	if maxAge <= 0 {
+		maxAge = time.Hour
+	}
+	var maxTotal int64
+	switch {
+	case cfg.Media.MaxTotalMB == 0:
+		maxTotal = 1024 << 20
+	case cfg.Media.MaxTotalMB > 0:
+		maxTotal = int64(cfg.Media.MaxTotalMB) << 20
+	}
+	return utils.NewMediaRetention(maxAge, maxTotal, dirs...)
+}
+
+// AddReplyHandler registers h to run on every chat message, in order of
+// registration, until one handles it.
+func (al *AgentLoop) AddReplyHandler(h ReplyHandler) {
+	al.replyHandlers = append(al.replyHandlers, h)
+}
+
+// Model returns the model used for turns that don't override it. It can
+// change at runtime through SetModel.
+func (al *AgentLoop) Model() string {
+	al.modelMu.RLock()
+	defer al.modelMu.RUnlock()
+	return al.model
+}
+
+// SetModel switches the default model until restart.
+func (al *AgentLoop) SetModel(model string) {
+	al.modelMu.Lock()
+	al.model = model
+	al.modelMu.Unlock()
+}
+
+// SetTimeNote adds a line below the current time in the system prompt.
+func (al *AgentLoop) SetTimeNote(fn func() string) {
+	al.contextBuilder.SetTimeNote(fn)
+}
+
+// SetWorldClock adds fn's output to every turn as the "World Clock" note.
+func (al *AgentLoop) SetWorldClock(fn func() string) {
+	al.contextBuilder.SetWorldClock(fn)
+}
+
+// SetUpcoming adds fn's output to every turn as the "Upcoming" note.
+func (al *AgentLoop) SetUpcoming(fn func() string) {
+	al.contextBuilder.SetUpcoming(fn)
+}
+
+// SetStreamSink streams the assistant's text to fn as it is generated,
+// when the provider supports streaming. Deltas are not persisted; the
+// complete reply is still delivered as a normal message.
+func (al *AgentLoop) SetStreamSink(fn func(sessionKey, delta string)) {
+	al.streamSink = fn
+}
+
+// SetReadTracker marks a chat read whenever the user sends a message in it.
+func (al *AgentLoop) SetReadTracker(t *readstate.Tracker) {
+	al.readState = t
+}
+
+// SetRedactor masks personal data in activity events before they are
+// broadcast or persisted. Registered secrets are masked even without one.
+func (al *AgentLoop) SetRedactor(r *redact.Redactor) {
+	al.redactor = r
+}
+
+// SetHooks runs r's on_inbound_message hooks on chat messages and its
+// on_tool_result hooks on tool results.
+func (al *AgentLoop) SetHooks(r *hooks.Runner) {
+	al.hooks = r
+}
+
 // emitActivity broadcasts an activity event via SSE and persists it to the session.
 func (al *AgentLoop) emitActivity(sessionKey string, evt activity.Event) {
+	evt.Message = al.redactor.String(evt.Message)
+	evt.Detail = al.redactor.Map(evt.Detail)
 	al.activity.Emit(evt)
 	if sessionKey != "" {
 		al.sessions.AddActivity(sessionKey, evt)
+		if watch, ok := al.watchers.Load(sessionKey); ok {
+			(*watch.(*func(activity.Event)))(evt)
+		}
+	}
+}
+
+// chat calls the LLM, streaming its text to the stream sink when one is
+// set and the provider can stream.
+func (al *AgentLoop) chat(ctx context.Context, provider providers.LLMProvider, sessionKey string, messages []providers.Message, tools []providers.ToolDefinition, model string) (*providers.LLMResponse, error) {
+	options := map[string]any{
+		"max_tokens":  8192,
+		"temperature": 0.7,
+	}
+	if s, ok := provider.(providers.StreamingProvider); ok && al.streamSink != nil && sessionKey != "" {
+		return s.ChatStream(ctx, messages, tools, model, options, func(delta string) {
+			al.streamSink(sessionKey, delta)
+		})
 	}
+	return provider.Chat(ctx, messages, tools, model, options)
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
@@ -226,14 +480,38 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		default:
-			msg, ok := al.bus.ConsumeInbound(ctx)
+			msg, ok := al.nextInbound(ctx)
 			if !ok {
 				continue
 			}
+			batch := al.coalesce(ctx, msg)
+			if len(batch) > 1 {
+				logger.Info("coalesced %d messages from %s:%s session=%s", len(batch), msg.Channel, msg.SenderID, msg.SessionKey)
+				msg = mergeInbound(batch)
+			}
+
+			// A user writing in a chat has seen what was sent there before.
+			if al.readState != nil && msg.Channel != "system" && !federation.IsRemoteSender(msg.SenderID) {
+				al.readState.MarkRead(msg.Channel, msg.ChatID)
+			}
 
+			al.publishStatus(batch, bus.StatusProcessing, nil)
 			response, err := al.processMessage(ctx, msg)
+			if errors.Is(err, ErrCancelled) {
+				if response != "" {
+					al.bus.PublishOutbound(bus.OutboundMessage{
+						Channel: msg.Channel,
+						ChatID:  msg.ChatID,
+						Content: response,
+						ReplyTo: msg.ID,
+					})
+				}
+				al.publishStatus(batch, bus.StatusCancelled, nil)
+				continue
+			}
 			if err != nil {
-				response = fmt.Sprintf("Error processing message: %v", err)
+				logger.Error("processing message from %s:%s failed: %v", msg.Channel, msg.ChatID, err)
+				response = userError(err, msg.Channel, al.verboseErrors)
 				// Persist the error response so it survives page reload
 				if msg.SessionKey != "" {
 					al.sessions.AddMessage(msg.SessionKey, "assistant", response)
@@ -245,14 +523,50 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 					Channel: msg.Channel,
 					ChatID:  msg.ChatID,
 					Content: response,
+					ReplyTo: msg.ID,
 				})
 			}
+			if err != nil {
+				al.publishStatus(batch, bus.StatusFailed, err)
+			} else {
+				al.publishStatus(batch, bus.StatusAnswered, nil)
+			}
 		}
 	}
 
 	return nil
 }
 
+// Cancel stops in-flight processing for the given session. Pending tool
+// calls are skipped and whatever content was produced so far is kept.
+// Returns false if nothing is running for the session.
+func (al *AgentLoop) Cancel(sessionKey string) bool {
+	v, ok := al.inflight.Load(sessionKey)
+	if !ok {
+		return false
+	}
+	v.(*inflightRun).cancel(nil)
+	logger.Info("cancellation requested: session=%s", sessionKey)
+	return true
+}
+
+// publishStatus reports status for every message in a (possibly
+// coalesced) batch.
+func (al *AgentLoop) publishStatus(batch []bus.InboundMessage, status bus.MessageStatus, err error) {
+	for _, msg := range batch {
+		update := bus.StatusUpdate{
+			MessageID: msg.ID,
+			Channel:   msg.Channel,
+			ChatID:    msg.ChatID,
+			Status:    status,
+		}
+		if err != nil {
+			update.Error = err.Error()
+		}
+		al.bus.PublishStatus(update)
+	}
+}
+
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 	select {
@@ -282,6 +596,19 @@ func (al *AgentLoop) WasMessageToolCalled() bool {
 	return false
 }
 
+// GetTools returns the registered tools sorted by name.
+func (al *AgentLoop) GetTools() []tools.Tool {
+	names := al.tools.List()
+	sort.Strings(names)
+	out := make([]tools.Tool, 0, len(names))
+	for _, name := range names {
+		if t, ok := al.tools.Get(name); ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 // GetToolDomains returns all domains declared by registered tools.
 func (al *AgentLoop) GetToolDomains() []string {
 	return al.tools.DeclaredDomains()
@@ -303,7 +630,19 @@ func (al *AgentLoop) ProcessDirect(ctx context.Context, content, sessionKey stri
 	return al.ProcessDirectWithChannel(ctx, content, sessionKey, "cli", "direct")
 }
 
+// ProcessDirectWithChannel runs content as the owner, whatever roles are
+// configured for channel.
 func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	return al.ProcessDirectAs(ctx, content, sessionKey, channel, chatID, string(roles.Owner))
+}
+
+// ProcessDirectAs runs content with the given household role, e.g. for a
+// cron job created by a family member.
+func (al *AgentLoop) ProcessDirectAs(ctx context.Context, content, sessionKey, channel, chatID, role string) (string, error) {
+	r, err := roles.Parse(role)
+	if err != nil {
+		return "", err
+	}
 	msg := bus.InboundMessage{
 		Channel:    channel,
 		SenderID:   "cron",
@@ -312,7 +651,44 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 		SessionKey: sessionKey,
 	}
 
-	return al.processMessage(ctx, msg)
+	return al.processMessageAs(ctx, msg, r)
+}
+
+// SenderRole returns the household role of a sender on a channel.
+func (al *AgentLoop) SenderRole(channel, senderID string) string {
+	return string(al.roles.RoleFor(channel, senderID))
+}
+
+// ProcessSatellite runs what the user said to a voice satellite in the
+// device's own session. The sender is the device ID, so
+// roles.channels.satellite can limit what a shared speaker may do.
+func (al *AgentLoop) ProcessSatellite(ctx context.Context, device, text string) (string, error) {
+	return al.processMessage(ctx, bus.InboundMessage{
+		Channel:    "satellite",
+		SenderID:   device,
+		ChatID:     device,
+		Content:    text,
+		SessionKey: "satellite:" + device,
+	})
+}
+
+// ProcessRemote runs a task delegated by a federation peer in that peer's
+// own session. The sender is the peer name; peers are guests unless
+// roles.channels.federation gives them another role. Activity of the run is passed to onActivity.
+func (al *AgentLoop) ProcessRemote(ctx context.Context, peer, task string, onActivity func(activity.Event)) (string, error) {
+	sessionKey := "federation:" + peer
+	if onActivity != nil {
+		watch := &onActivity
+		al.watchers.Store(sessionKey, watch)
+		defer al.watchers.CompareAndDelete(sessionKey, watch)
+	}
+	return al.processMessage(ctx, bus.InboundMessage{
+		Channel:    "federation",
+		SenderID:   peer,
+		ChatID:     peer,
+		Content:    task,
+		SessionKey: sessionKey,
+	})
 }
 
 // ProcessHeartbeat processes a heartbeat with a rolling session history.
@@ -322,14 +698,23 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 	const sessionKey = "heartbeat"
 	const maxHistory = 10
 
+	provider, model := al.route(RouteHeartbeat)
+	if al.heartbeat.Model != "" {
+		model = al.heartbeat.Model
+	}
 	response, err := al.runAgentLoop(ctx, processOptions{
 		SessionKey:      sessionKey,
 		Channel:         channel,
 		ChatID:          chatID,
 		UserMessage:     content,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		DefaultResponse: i18n.T("agent.no_response"),
 		EnableSummary:   false,
 		SendResponse:    false,
+		Model:           model,
+		Provider:        provider,
+		MaxIterations:   al.heartbeat.MaxToolIterations,
+		Tools:           al.heartbeat.Tools,
+		TokenBudget:     al.heartbeat.MaxTokens,
 	})
 
 	// Trim heartbeat session to keep only recent turns
@@ -341,6 +726,15 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 }
 
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	role := al.roles.RoleFor(msg.Channel, msg.SenderID)
+	if msg.Metadata[bus.MetadataUnverified] != "" {
+		role = roles.Guest
+	}
+	return al.processMessageAs(ctx, msg, role)
+}
+
+// processMessageAs processes msg with the sender's household role.
+func (al *AgentLoop) processMessageAs(ctx context.Context, msg bus.InboundMessage, role roles.Role) (string, error) {
 	// Add message preview to log (show full content for error messages)
 	var logContent string
 	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
@@ -350,26 +744,79 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	}
 	logger.Info("processing message from %s:%s session=%s: %s", msg.Channel, msg.SenderID, msg.SessionKey, logContent)
 
+	// Idle work waits until a quiet period after the turn ends.
+	al.idle.Touch()
+	defer al.idle.Touch()
+
 	// Route system messages to processSystemMessage
 	if msg.Channel == "system" {
 		return al.processSystemMessage(ctx, msg)
 	}
 
+	if al.hooks.Has(hooks.InboundMessage) {
+		e := al.hooks.Run(ctx, hooks.Event{Event: hooks.InboundMessage, Channel: msg.Channel, ChatID: msg.ChatID, SenderID: msg.SenderID, Content: msg.Content})
+		if e.Drop {
+			return "", nil
+		}
+		msg.Content = e.Content
+	}
+
+	// Non-owner household members get their own session and memory so
+	// their conversations stay out of the owner's. Messages a channel has
+	// already persisted keep the channel's key.
+	namespace := roles.Namespace(role, msg.SenderID)
+	sessionKey := msg.SessionKey
+	if !msg.Persisted {
+		sessionKey = roles.SessionKey(sessionKey, namespace)
+	}
+	if role != roles.Owner {
+		logger.Info("sender %s has role %s, session=%s", msg.SenderID, role, sessionKey)
+	}
+	al.welcome(msg, role)
+
+	for _, h := range al.replyHandlers {
+		reply, ok := h(msg)
+		if !ok {
+			continue
+		}
+		return al.recordReply(msg, sessionKey, reply), nil
+	}
+
+	if reply, ok := al.resolveApproval(ctx, msg, role, sessionKey); ok {
+		return al.recordReply(msg, sessionKey, reply), nil
+	}
+
 	// Process as user message
+	provider, model := al.route(routeKey(msg.Channel, msg.SessionKey))
 	return al.runAgentLoop(ctx, processOptions{
-		SessionKey:      msg.SessionKey,
+		SessionKey:      sessionKey,
 		Channel:         msg.Channel,
 		ChatID:          msg.ChatID,
 		SenderID:        msg.SenderID,
 		UserMessage:     msg.Content,
 		Media:           msg.Media,
-		DefaultResponse: "I've completed processing but have no response to give.",
+		DefaultResponse: i18n.T("agent.no_response"),
 		EnableSummary:   true,
 		SendResponse:    false,
 		Persisted:       msg.Persisted,
+		Role:            role,
+		Namespace:       namespace,
+		Model:           model,
+		Provider:        provider,
 	})
 }
 
+// recordReply saves a message answered without the LLM, and its reply, to
+// the session.
+func (al *AgentLoop) recordReply(msg bus.InboundMessage, sessionKey, reply string) string {
+	if !msg.Persisted {
+		al.sessions.AddMessage(sessionKey, "user", msg.Content)
+	}
+	al.sessions.AddMessage(sessionKey, "assistant", reply)
+	al.sessions.Save(sessionKey)
+	return reply
+}
+
 func (al *AgentLoop) processSystemMessage(_ context.Context, msg bus.InboundMessage) (string, error) {
 	// Verify this is a system message
 	if msg.Channel != "system" {
@@ -424,6 +871,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		}
 	}
 
+	// Register a cancellable context for this run
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &inflightRun{cancel: cancel}
+	al.inflight.Store(opts.SessionKey, run)
+	defer func() {
+		al.inflight.CompareAndDelete(opts.SessionKey, run)
+		cancel(nil)
+	}()
+	ctx, cancelTimeout := context.WithTimeoutCause(ctx, al.maxDuration, errProcessingTimeout)
+	defer cancelTimeout()
+
 	// 1. Update tool contexts
 	al.updateToolContexts(opts.Channel, opts.ChatID)
 
@@ -445,9 +903,10 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 			}
 		}
 	}
-	messages := al.contextBuilder.BuildMessages(
+	messages := al.contextBuilder.ForMember(opts.Namespace).BuildMessages(
 		history,
 		summary,
+		al.sessions.GetVars(opts.SessionKey),
 		opts.UserMessage,
 		opts.Media,
 		opts.Channel,
@@ -462,8 +921,69 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	// 4. Signal processing started (for webchat processing indicator)
 	al.activity.Emit(activity.Event{Type: "processing_start"})
 
+	// If the provider rejects the prompt as too long, earlier history is
+	// summarized and the prompt rebuilt without it.
+	var compact func(context.Context) ([]providers.Message, bool)
+	if !opts.NoHistory {
+		prior := len(history)
+		compact = func(ctx context.Context) ([]providers.Message, bool) {
+			summary, ok := al.compactHistory(ctx, opts.SessionKey, prior)
+			if !ok {
+				return nil, false
+			}
+			return al.contextBuilder.ForMember(opts.Namespace).BuildMessages(
+				nil,
+				summary,
+				al.sessions.GetVars(opts.SessionKey),
+				opts.UserMessage,
+				opts.Media,
+				opts.Channel,
+				opts.ChatID,
+			), true
+		}
+	}
+
 	// 5. Run LLM iteration loop
-	finalContent, iteration, tokenCount, err := al.runLLMIteration(ctx, messages, opts)
+	start := time.Now()
+	finalContent, iteration, tokenCount, err := al.runLLMIteration(ctx, messages, opts, compact)
+	defer recordMessageMetrics(opts, start, iteration, tokenCount, &err)
+	var stopped *stoppedError
+	if errors.As(err, &stopped) && errors.Is(context.Cause(ctx), errProcessingTimeout) {
+		// Hitting the time limit is reported to the user as a normal answer
+		// so they learn where it stopped and can split the task.
+		logger.Warn("processing time limit (%s) reached: session=%s step=%s", al.maxDuration, opts.SessionKey, stopped.step)
+		note := i18n.T("agent.time_limit", al.maxDuration, stopped.step)
+		if finalContent != "" {
+			finalContent += "\n\n" + note
+		} else {
+			finalContent = note
+		}
+		err = nil
+	} else if errors.As(err, &stopped) && errors.Is(context.Cause(ctx), errStalled) {
+		note := i18n.T("agent.stalled", stopped.step)
+		if finalContent != "" {
+			finalContent += "\n\n" + note
+		} else {
+			finalContent = note
+		}
+		err = nil
+	}
+	if errors.Is(err, ErrCancelled) {
+		al.emitActivity(opts.SessionKey, activity.Event{
+			Type:      activity.Cancelled,
+			Timestamp: time.Now(),
+			Message:   fmt.Sprintf("Cancelled after %d iterations", iteration),
+			Detail: map[string]any{
+				"session":    opts.SessionKey,
+				"iterations": iteration,
+				"partial":    len(finalContent),
+			},
+		})
+		if finalContent != "" {
+			al.sessions.AddMessage(opts.SessionKey, "assistant", finalContent)
+		}
+		return finalContent, ErrCancelled
+	}
 	if err != nil {
 		// Emit completion activity so the processing state resets
 		al.emitActivity(opts.SessionKey, activity.Event{
@@ -524,31 +1044,106 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	return finalContent, nil
 }
 
-// runLLMIteration executes the LLM call loop with tool handling.
-// Returns the final content, iteration count, last known token count, and any error.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, int, error) {
+// runLLMIteration executes the LLM call loop with tool handling. When a call
+// fails with a context overflow and compact is set, compact's messages
+// (system prompt and user message) replace those before this turn and the
+// call is retried once. Returns the final content, iteration count, last
+// known token count, and any error.
+func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions, compact func(context.Context) ([]providers.Message, bool)) (string, int, int, error) {
+	turnStart := len(messages) - 1 // the user message; later ones belong to this turn
 	iteration := 0
 	var finalContent string
+	var partialContent string // latest assistant text, returned if cancelled
 	var lastTokenCount int
+	ctx = tools.WithSessionKey(ctx, opts.SessionKey)
+	ctx = tools.WithRole(ctx, string(opts.Role))
+
+	model := al.Model()
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	provider := al.provider
+	if opts.Provider != nil {
+		provider = opts.Provider
+	}
+	maxIterations := al.maxIterations
+	if opts.MaxIterations > 0 {
+		maxIterations = opts.MaxIterations
+	}
+	tokensUsed := 0
+
+	step := "startup"
+	var repeats repeatDetector
+	stuckOn := "" // set when a tool call repeats often enough to abort
+	var groups map[string]string
+	openGroups := make(map[string]bool) // tool groups whose definitions are offered
+	if al.toolGroups {
+		groups = al.tools.Groups()
+	}
 
-	for iteration < al.maxIterations {
+	for iteration < maxIterations {
+		if ctx.Err() != nil {
+			return partialContent, iteration, lastTokenCount, &stoppedError{step: step}
+		}
 		iteration++
+		step = fmt.Sprintf("LLM call #%d", iteration)
 
-		logger.Debug("LLM iteration %d/%d", iteration, al.maxIterations)
+		logger.Debug("LLM iteration %d/%d", iteration, maxIterations)
 
 		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
+		providerToolDefs := al.toolDefsFor(opts.Role, opts.Tools)
+		if al.toolGroups {
+			providerToolDefs = tools.CollapseGroups(providerToolDefs, groups, openGroups)
+		}
 
 		// Log LLM request details
-		logger.Debug("LLM request: iteration=%d model=%s messages=%d tools=%d", iteration, al.model, len(messages), len(providerToolDefs))
+		logger.Debug("LLM request: iteration=%d model=%s messages=%d tools=%d", iteration, model, len(messages), len(providerToolDefs))
 		logger.Debug("full LLM request: iteration=%d messages=%s tools=%s", iteration, formatMessagesForLog(messages), formatToolsForLog(providerToolDefs))
 
-		// Call LLM
-		response, err := al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]any{
-			"max_tokens":  8192,
-			"temperature": 0.7,
-		})
+		// Call LLM, backing off while the provider rate limits us
+		var response *providers.LLMResponse
+		var err error
+		for attempt := 0; ; attempt++ {
+			al.enterStage(opts.SessionKey, step)
+			llmStart := time.Now()
+			response, err = al.chat(ctx, provider, opts.SessionKey, messages, providerToolDefs, model)
+			recordLLMMetrics(model, llmStart, response, err)
+			delay, limited := rateLimitDelay(err, attempt)
+			if !limited || attempt >= rateLimitRetries || ctx.Err() != nil {
+				break
+			}
+			logger.Warn("LLM rate limited: iteration=%d session=%s; retrying in %v", iteration, opts.SessionKey, delay)
+			al.emitActivity(opts.SessionKey, activity.Event{
+				Type:      activity.LLMError,
+				Timestamp: time.Now(),
+				Message:   fmt.Sprintf("Rate limited on iteration #%d, retrying in %v", iteration, delay),
+				Detail:    map[string]any{"error": err.Error(), "retry": true},
+			})
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
 
+		if err != nil && ctx.Err() != nil {
+			return partialContent, iteration, lastTokenCount, &stoppedError{step: step}
+		}
+		if err != nil && compact != nil && isContextOverflow(err) {
+			logger.Warn("context overflow: iteration=%d session=%s; compacting history and retrying", iteration, opts.SessionKey)
+			prefix, ok := compact(ctx)
+			compact = nil // retry once
+			if ok {
+				al.emitActivity(opts.SessionKey, activity.Event{
+					Type:      activity.LLMError,
+					Timestamp: time.Now(),
+					Message:   fmt.Sprintf("Context overflow on iteration #%d, summarized earlier messages and retrying", iteration),
+					Detail:    map[string]any{"error": err.Error(), "retry": true},
+				})
+				messages = append(prefix, messages[turnStart+1:]...)
+				turnStart = len(prefix) - 1
+				continue
+			}
+		}
 		if err != nil {
 			logger.Error("LLM call failed: iteration=%d: %v", iteration, err)
 			al.emitActivity(opts.SessionKey, activity.Event{
@@ -562,6 +1157,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 
 		if response.Usage != nil {
 			lastTokenCount = response.Usage.PromptTokens + response.Usage.CompletionTokens
+			tokensUsed += lastTokenCount
 		}
 
 		// Check if no tool calls - we're done
@@ -570,20 +1166,28 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			logger.Info("LLM response (direct answer): iteration=%d chars=%d", iteration, len(finalContent))
 			turnDetail := map[string]any{
 				"iteration": iteration,
-				"model":     al.model,
+				"model":     model,
 				"chars":     len(finalContent),
 			}
-			if response.Usage != nil {
-				turnDetail["usage"] = map[string]any{
-					"prompt_tokens":     response.Usage.PromptTokens,
-					"completion_tokens": response.Usage.CompletionTokens,
-					"total_tokens":      response.Usage.TotalTokens,
+			message := fmt.Sprintf("LLM #%d — %d chars (%s)", iteration, len(finalContent), model)
+			if u := response.Usage; u != nil {
+				usage := map[string]any{
+					"prompt_tokens":     u.PromptTokens,
+					"completion_tokens": u.CompletionTokens,
+					"total_tokens":      u.TotalTokens,
+				}
+				if u.CachedTokens > 0 || u.CacheWriteTokens > 0 {
+					usage["cached_tokens"] = u.CachedTokens
+					usage["cache_write_tokens"] = u.CacheWriteTokens
+					usage["saved_tokens"] = u.SavedTokens
+					message += fmt.Sprintf(", %.0f%% of prompt cached", 100*u.CacheHitRate())
 				}
+				turnDetail["usage"] = usage
 			}
 			al.emitActivity(opts.SessionKey, activity.Event{
 				Type:      activity.LLMTurn,
 				Timestamp: time.Now(),
-				Message:   fmt.Sprintf("LLM #%d — %d chars (%s)", iteration, len(finalContent), al.model),
+				Message:   message,
 				Detail:    turnDetail,
 			})
 			break
@@ -600,14 +1204,18 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		al.emitActivity(opts.SessionKey, activity.Event{
 			Type:      activity.LLMTurn,
 			Timestamp: time.Now(),
-			Message:   fmt.Sprintf("LLM #%d — calling %s (%s)", iteration, strings.Join(toolNames, ", "), al.model),
+			Message:   fmt.Sprintf("LLM #%d — calling %s (%s)", iteration, strings.Join(toolNames, ", "), model),
 			Detail: map[string]any{
 				"iteration": iteration,
-				"model":     al.model,
+				"model":     model,
 				"tools":     toolNames,
 			},
 		})
 
+		if response.Content != "" {
+			partialContent = response.Content
+		}
+
 		// Build assistant message with tool calls
 		assistantMsg := tools.BuildAssistantToolCallMessage(response.Content, response.ReasoningContent, response.ToolCalls)
 		messages = append(messages, assistantMsg)
@@ -616,7 +1224,19 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
 
 		// Execute tool calls
+		var images []string
 		for _, tc := range response.ToolCalls {
+			// Every tool call needs a result in history; skip the remaining
+			// ones once cancelled.
+			if ctx.Err() != nil {
+				cancelledMsg := tools.BuildToolResultMessage(tc.ID, tc.Name, tools.ErrorResult("Skipped: processing was cancelled before this call ran"))
+				messages = append(messages, cancelledMsg)
+				al.sessions.AddFullMessage(opts.SessionKey, cancelledMsg)
+				continue
+			}
+
+			step = fmt.Sprintf("tool %s (iteration %d)", tc.Name, iteration)
+
 			// Log tool call with arguments preview
 			argsJSON, _ := json.Marshal(tc.Arguments)
 			argsPreview := utils.Truncate(string(argsJSON), 200)
@@ -629,22 +1249,51 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 			}
 
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+			var toolResult *tools.ToolResult
+			if n := repeats.observe(tc.Name, tc.Arguments); n >= repeatWarnAt {
+				logger.Warn("repeated tool call: %s x%d iteration=%d", tc.Name, n, iteration)
+				toolResult = tools.ErrorResult(repeatWarning(tc.Name, n))
+				if n >= repeatAbortAt {
+					stuckOn = tc.Name
+				}
+			} else if al.toolGroups && tc.Name == tools.OpenToolsName {
+				toolResult = al.openToolGroup(tc.Arguments, groups, openGroups, opts)
+			} else if !toolAllowed(opts.Tools, tc.Name) {
+				logger.Warn("tool %s not in the allowed set for session %s", tc.Name, opts.SessionKey)
+				toolResult = tools.ErrorResult(fmt.Sprintf("Tool %q is not available in this run.", tc.Name))
+			} else if !al.roles.Allows(opts.Role, tc.Name) {
+				logger.Warn("tool %s denied for role %s", tc.Name, opts.Role)
+				toolResult = tools.ErrorResult(fmt.Sprintf("Tool %q is not available in this conversation (role: %s).", tc.Name, opts.Role))
+			} else if al.needsApproval(opts.Role, tc.Name, tc.Arguments) {
+				toolResult = al.holdForApproval(opts, tc.Name, tc.Arguments)
+			} else {
+				if g := groups[tc.Name]; g != "" {
+					// Called without opening its group first (the name is
+					// in the system prompt); keep its definitions offered.
+					openGroups[g] = true
+				}
+				al.enterStage(opts.SessionKey, step)
+				toolResult = al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+				if al.hooks.Has(hooks.ToolResult) {
+					e := al.hooks.Run(ctx, hooks.Event{Event: hooks.ToolResult, Channel: opts.Channel, ChatID: opts.ChatID, Tool: tc.Name, Args: tc.Arguments, Content: toolResult.ForLLM, IsError: toolResult.IsError})
+					toolResult.ForLLM, toolResult.IsError = e.Content, e.IsError
+				}
+			}
 
 			status := "success"
 			if toolResult.IsError {
 				status = "error"
 			}
+			detail := toolResult.Detail()
+			detail["tool"] = tc.Name
+			detail["params"] = utils.Truncate(string(argsJSON), 500)
+			detail["status"] = status
+			detail["result"] = utils.Truncate(toolResult.ForLLM, 500)
 			al.emitActivity(opts.SessionKey, activity.Event{
 				Type:      activity.ToolExec,
 				Timestamp: time.Now(),
 				Message:   fmt.Sprintf("%s — %s", tc.Name, status),
-				Detail: map[string]any{
-					"tool":   tc.Name,
-					"params": utils.Truncate(string(argsJSON), 500),
-					"status": status,
-					"result": utils.Truncate(toolResult.ForLLM, 500),
-				},
+				Detail:    detail,
 			})
 
 			// Send ForUser content to user immediately if not Silent
@@ -659,15 +1308,112 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 
 			toolResultMsg := tools.BuildToolResultMessage(tc.ID, tc.Name, toolResult)
 			messages = append(messages, toolResultMsg)
+			images = append(images, toolResult.Images...)
 
 			// Save tool result message to session
 			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
 		}
+		if len(images) > 0 {
+			// Tool messages are text only; show the model the images in a
+			// message right after the results.
+			note := "Images returned by the tool calls above."
+			messages = append(messages, al.contextBuilder.buildUserMessage(note, images))
+			al.sessions.AddMessageWithMedia(opts.SessionKey, "user", note, images)
+		}
+		if ctx.Err() != nil {
+			return partialContent, iteration, lastTokenCount, &stoppedError{step: step}
+		}
+		if stuckOn != "" {
+			return repeatAbortMessage(stuckOn), iteration, lastTokenCount, nil
+		}
+		if opts.TokenBudget > 0 && tokensUsed >= opts.TokenBudget {
+			logger.Warn("token budget reached: session=%s used=%d budget=%d iteration=%d", opts.SessionKey, tokensUsed, opts.TokenBudget, iteration)
+			return partialContent, iteration, lastTokenCount, nil
+		}
 	}
 
 	return finalContent, iteration, lastTokenCount, nil
 }
 
+// recordMessageMetrics emits a telemetry event for a processed message.
+// errp is read when the deferred call runs, after err has its final value.
+func recordMessageMetrics(opts processOptions, start time.Time, iterations, tokens int, errp *error) {
+	if !telemetry.Enabled() {
+		return
+	}
+	status := "ok"
+	switch {
+	case errors.Is(*errp, ErrCancelled):
+		status = "cancelled"
+	case *errp != nil:
+		status = "error"
+	}
+	telemetry.Emit("message_processed", map[string]string{
+		"channel": opts.Channel,
+		"status":  status,
+	}, map[string]float64{
+		"duration_ms": float64(time.Since(start).Milliseconds()),
+		"iterations":  float64(iterations),
+		"tokens":      float64(tokens),
+	})
+}
+
+func recordLLMMetrics(model string, start time.Time, response *providers.LLMResponse, err error) {
+	if !telemetry.Enabled() {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	fields := map[string]float64{"duration_ms": float64(time.Since(start).Milliseconds())}
+	if response != nil && response.Usage != nil {
+		fields["prompt_tokens"] = float64(response.Usage.PromptTokens)
+		fields["completion_tokens"] = float64(response.Usage.CompletionTokens)
+		fields["total_tokens"] = float64(response.Usage.TotalTokens)
+		fields["cached_tokens"] = float64(response.Usage.CachedTokens)
+		fields["cache_write_tokens"] = float64(response.Usage.CacheWriteTokens)
+		fields["saved_tokens"] = float64(response.Usage.SavedTokens)
+	}
+	telemetry.Emit("llm_call", map[string]string{"model": model, "status": status}, fields)
+}
+
+// toolDefsFor returns the tool definitions offered to a sender with role,
+// limited to only when it is non-empty.
+func (al *AgentLoop) toolDefsFor(role roles.Role, only []string) []providers.ToolDefinition {
+	defs := al.tools.ToProviderDefs()
+	allowed := defs[:0]
+	for _, d := range defs {
+		if al.roles.Allows(role, d.Function.Name) && toolAllowed(only, d.Function.Name) {
+			allowed = append(allowed, d)
+		}
+	}
+	return allowed
+}
+
+// openToolGroup handles an open_tools call: it marks the requested group
+// open so its definitions are offered from the next iteration on.
+func (al *AgentLoop) openToolGroup(args map[string]any, groups map[string]string, open map[string]bool, opts processOptions) *tools.ToolResult {
+	group, _ := args["group"].(string)
+	var names []string
+	for _, name := range tools.GroupMembers(groups, group) {
+		if al.roles.Allows(opts.Role, name) && toolAllowed(opts.Tools, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return tools.ErrorResult(fmt.Sprintf("Unknown tool group %q. Pick one of the groups listed in the %s description.", group, tools.OpenToolsName))
+	}
+	open[group] = true
+	logger.Info("opened tool group %s for session %s", group, opts.SessionKey)
+	return tools.NewToolResult(fmt.Sprintf("Loaded the %s tools: %s. Call them directly now.", group, strings.Join(names, ", ")))
+}
+
+// toolAllowed reports whether name is in only, or true when only is empty.
+func toolAllowed(only []string, name string) bool {
+	return len(only) == 0 || slices.Contains(only, name)
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
@@ -689,22 +1435,43 @@ func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 }
 
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
+// With idle work enabled it waits for a quiet period, unless the history is
+// close enough to the context window that the next turn might not fit.
 func (al *AgentLoop) maybeSummarize(sessionKey string, tokenCount int) {
-	newHistory := al.sessions.GetHistory(sessionKey)
+	tokenCount, due := al.summaryDue(sessionKey, tokenCount)
+	if !due {
+		return
+	}
+	if al.idle != nil && tokenCount <= al.contextWindow*90/100 {
+		al.idle.Defer("summarize "+sessionKey, func(context.Context) {
+			if _, due := al.summaryDue(sessionKey, 0); due {
+				al.summarize(sessionKey)
+			}
+		})
+		return
+	}
+	go al.summarize(sessionKey)
+}
+
+// summaryDue reports whether the session history exceeds the summarization
+// thresholds, estimating tokenCount when it is 0.
+func (al *AgentLoop) summaryDue(sessionKey string, tokenCount int) (int, bool) {
+	history := al.sessions.GetHistory(sessionKey)
 	if tokenCount == 0 {
-		tokenCount = al.estimateTokens(newHistory)
+		tokenCount = al.estimateTokens(history)
 	}
-	threshold := al.contextWindow * 75 / 100
+	return tokenCount, len(history) > 50 || tokenCount > al.contextWindow*75/100
+}
 
-	if len(newHistory) > 50 || tokenCount > threshold {
-		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
-			go func() {
-				defer al.summarizing.Delete(sessionKey)
-				al.memoryFlush(sessionKey)
-				al.summarizeSession(sessionKey)
-			}()
-		}
+// summarize flushes memory and summarizes the session unless a
+// summarization is already running for it.
+func (al *AgentLoop) summarize(sessionKey string) {
+	if _, loading := al.summarizing.LoadOrStore(sessionKey, true); loading {
+		return
 	}
+	defer al.summarizing.Delete(sessionKey)
+	al.memoryFlush(sessionKey)
+	al.summarizeSession(sessionKey)
 }
 
 // memoryFlush runs a mini agent turn to persist important conversation context
@@ -718,12 +1485,17 @@ func (al *AgentLoop) memoryFlush(sessionKey string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
+	// Members' notes go to their own memory, sandboxed to their directory.
+	cb := al.contextBuilder.ForMember(roles.NamespaceFromSessionKey(sessionKey))
+	root := cb.userDir
+
 	registry := tools.NewToolRegistry()
-	registry.Register(tools.NewWriteFileTool(al.workspace))
-	registry.Register(tools.NewAppendFileTool(al.workspace))
-	registry.Register(tools.NewReadFileTool(al.workspace))
+	registry.Register(tools.NewWriteFileTool(root))
+	registry.Register(tools.NewAppendFileTool(root))
+	registry.Register(tools.NewReadFileTool(root))
+	registry.SetAuditLog(al.audit)
 
-	todayPath := al.contextBuilder.GetMemoryStore().GetTodayFile()
+	todayPath := cb.GetMemoryStore().GetTodayFile()
 
 	systemMsg := providers.Message{
 		Role:    "system",
@@ -739,9 +1511,11 @@ func (al *AgentLoop) memoryFlush(sessionKey string) {
 	messages = append(messages, history...)
 	messages = append(messages, userMsg)
 
+	// The flush runs just before summarization and uses the same route.
+	provider, model := al.modelFor(RouteSummary)
 	result, err := tools.RunToolLoop(ctx, tools.ToolLoopConfig{
-		Provider:      al.provider,
-		Model:         al.model,
+		Provider:      provider,
+		Model:         model,
 		Tools:         registry,
 		MaxIterations: 3,
 	}, messages, "", "")
@@ -835,8 +1609,46 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 		return
 	}
 
-	toSummarize := history[:len(history)-4]
+	finalSummary := al.summarizeMessages(ctx, history[:len(history)-4], summary)
+
+	if finalSummary != "" {
+		al.sessions.SetSummary(sessionKey, finalSummary)
+		al.sessions.TruncateHistory(sessionKey, 4)
+		al.sessions.Save(sessionKey)
+	}
+}
 
+// compactHistory replaces the first prior messages of a session with a
+// summary after the provider rejected a prompt as too long. When
+// summarizing fails too, they are dropped with a note. It returns the new
+// summary, or false when there is nothing to compact or a background
+// summarization is already rewriting the session.
+func (al *AgentLoop) compactHistory(ctx context.Context, sessionKey string, prior int) (string, bool) {
+	if _, busy := al.summarizing.LoadOrStore(sessionKey, true); busy {
+		return "", false
+	}
+	defer al.summarizing.Delete(sessionKey)
+
+	history := al.sessions.GetHistory(sessionKey)
+	if prior <= 0 || prior > len(history) {
+		return "", false
+	}
+	summary := al.sessions.GetSummary(sessionKey)
+	newSummary := strings.TrimSpace(al.summarizeMessages(ctx, history[:prior], summary))
+	if newSummary == "" {
+		logger.Warn("summarizing before retry failed: session=%s; dropping %d earlier messages", sessionKey, prior)
+		newSummary = strings.TrimSpace(summary + "\n[Note: Earlier messages were dropped because the conversation no longer fit the model's context.]")
+	}
+	al.sessions.SetSummary(sessionKey, newSummary)
+	al.sessions.TruncateHistory(sessionKey, len(history)-prior)
+	al.sessions.Save(sessionKey)
+	return newSummary, true
+}
+
+// summarizeMessages condenses the user and assistant messages of
+// toSummarize, together with the existing summary, into a new summary. It
+// returns "" when there is nothing to summarize or the LLM failed.
+func (al *AgentLoop) summarizeMessages(ctx context.Context, toSummarize []providers.Message, summary string) string {
 	// Oversized Message Guard
 	// Skip messages larger than 50% of context window to prevent summarizer overflow
 	maxMessageTokens := al.contextWindow / 2
@@ -857,7 +1669,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	}
 
 	if len(validMessages) == 0 {
-		return
+		return ""
 	}
 
 	// Multi-Part Summarization
@@ -873,7 +1685,8 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 		// Merge them
 		mergePrompt := fmt.Sprintf(prompts.SummarizeMerge, s1, s2)
-		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]any{
+		provider, model := al.modelFor(RouteSummary)
+		resp, err := provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, model, map[string]any{
 			"max_tokens":  1024,
 			"temperature": 0.3,
 		})
@@ -889,12 +1702,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	if omitted && finalSummary != "" {
 		finalSummary += "\n[Note: Some oversized messages were omitted from this summary for efficiency.]"
 	}
-
-	if finalSummary != "" {
-		al.sessions.SetSummary(sessionKey, finalSummary)
-		al.sessions.TruncateHistory(sessionKey, 4)
-		al.sessions.Save(sessionKey)
-	}
+	return finalSummary
 }
 
 // summarizeBatch summarizes a batch of messages.
@@ -909,7 +1717,8 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 		fmt.Fprintf(&prompt, "%s: %s\n", m.Role, m.Content)
 	}
 
-	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt.String()}}, nil, al.model, map[string]any{
+	provider, model := al.modelFor(RouteSummary)
+	response, err := provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt.String()}}, nil, model, map[string]any{
 		"max_tokens":  1024,
 		"temperature": 0.3,
 	})
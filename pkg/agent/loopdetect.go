@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// repeatWarnAt is how many identical consecutive tool calls trigger a
+	// corrective note instead of executing the call again.
+	repeatWarnAt = 3
+	// repeatAbortAt stops the iteration loop entirely.
+	repeatAbortAt = 5
+)
+
+// repeatDetector tracks consecutive identical (tool, args) calls within a
+// single message. Small models often get stuck re-issuing the same call.
+type repeatDetector struct {
+	last  string
+	count int
+}
+
+// observe records a call and returns how many times in a row it has been made.
+func (d *repeatDetector) observe(name string, args map[string]any) int {
+	argsJSON, _ := json.Marshal(args) // map keys are sorted, so this is canonical
+	sig := name + ":" + string(argsJSON)
+	if sig == d.last {
+		d.count++
+	} else {
+		d.last = sig
+		d.count = 1
+	}
+	return d.count
+}
+
+func repeatWarning(name string, count int) string {
+	return fmt.Sprintf("You have called %s with identical arguments %d times in a row; it was not executed again. "+
+		"The previous result is already above. Use it, change the arguments, or answer the user.", name, count)
+}
+
+func repeatAbortMessage(name string) string {
+	return fmt.Sprintf("I got stuck calling %s repeatedly with the same arguments, so I stopped. "+
+		"Could you rephrase the request or give me more detail on what you need?", name)
+}
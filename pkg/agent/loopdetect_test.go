@@ -0,0 +1,26 @@
+package agent
+
+import "testing"
+
+func TestRepeatDetector(t *testing.T) {
+	var d repeatDetector
+
+	args := map[string]any{"path": "a.txt", "lines": 10}
+	for i := 1; i <= 3; i++ {
+		if got := d.observe("read_file", args); got != i {
+			t.Fatalf("call %d: expected count %d, got %d", i, i, got)
+		}
+	}
+
+	// Same args built in a different order are still identical
+	if got := d.observe("read_file", map[string]any{"lines": 10, "path": "a.txt"}); got != 4 {
+		t.Errorf("expected count 4 for reordered args, got %d", got)
+	}
+
+	if got := d.observe("read_file", map[string]any{"path": "b.txt"}); got != 1 {
+		t.Errorf("expected count to reset on different args, got %d", got)
+	}
+	if got := d.observe("list_dir", map[string]any{"path": "b.txt"}); got != 1 {
+		t.Errorf("expected count to reset on different tool, got %d", got)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"localagent/pkg/vault"
 )
 
 // MemoryStore manages persistent memory for the agent.
@@ -43,7 +45,7 @@ func (ms *MemoryStore) GetTodayFile() string {
 // ReadLongTerm reads the long-term memory (MEMORY.md).
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadLongTerm() string {
-	if data, err := os.ReadFile(ms.memoryFile); err == nil {
+	if data, err := vault.ReadFile(ms.memoryFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -51,14 +53,14 @@ func (ms *MemoryStore) ReadLongTerm() string {
 
 // WriteLongTerm writes content to the long-term memory file (MEMORY.md).
 func (ms *MemoryStore) WriteLongTerm(content string) error {
-	return os.WriteFile(ms.memoryFile, []byte(content), 0644)
+	return vault.WriteFile(ms.memoryFile, []byte(content), 0644)
 }
 
 // ReadToday reads today's daily note.
 // Returns empty string if the file doesn't exist.
 func (ms *MemoryStore) ReadToday() string {
 	todayFile := ms.GetTodayFile()
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := vault.ReadFile(todayFile); err == nil {
 		return string(data)
 	}
 	return ""
@@ -74,7 +76,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 	os.MkdirAll(monthDir, 0755)
 
 	var existingContent string
-	if data, err := os.ReadFile(todayFile); err == nil {
+	if data, err := vault.ReadFile(todayFile); err == nil {
 		existingContent = string(data)
 	}
 
@@ -88,7 +90,7 @@ func (ms *MemoryStore) AppendToday(content string) error {
 		newContent = existingContent + "\n" + content
 	}
 
-	return os.WriteFile(todayFile, []byte(newContent), 0644)
+	return vault.WriteFile(todayFile, []byte(newContent), 0644)
 }
 
 // GetRecentDailyNotes returns daily notes from the last N days.
@@ -102,7 +104,7 @@ func (ms *MemoryStore) GetRecentDailyNotes(days int) string {
 		monthDir := dateStr[:6]            // YYYYMM
 		filePath := filepath.Join(ms.memoryDir, monthDir, dateStr+".md")
 
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, err := vault.ReadFile(filePath); err == nil {
 			notes = append(notes, string(data))
 		}
 	}
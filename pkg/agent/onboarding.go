@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/constants"
+	"localagent/pkg/i18n"
+	"localagent/pkg/logger"
+	"localagent/pkg/roles"
+)
+
+// onboardingNamespace records, per "channel:sender", when the sender was
+// welcomed.
+const onboardingNamespace = "onboarding"
+
+// capabilities groups tools into what a new user is told the agent can
+// do, by i18n key. Tools not listed here are named individually.
+var capabilities = []struct {
+	area  string
+	tools []string
+}{
+	{"welcome.tasks", []string{"query_tasks", "add_task", "modify_tasks", "add_block", "goals"}},
+	{"welcome.calendar", []string{"calendar", "cron"}},
+	{"welcome.files", []string{"read_file", "write_file", "edit_file", "list_dir", "pdf_to_text"}},
+	{"welcome.commands", []string{"exec", "docker"}},
+	{"welcome.voice", []string{"transcribe_audio"}},
+	{"welcome.journal", []string{"journal"}},
+	{"welcome.recipes", []string{"recipes", "meal_plan"}},
+	{"welcome.flashcards", []string{"flashcards"}},
+	{"welcome.medications", []string{"medications"}},
+	{"welcome.sleep_travel", []string{"sleep", "travel"}},
+	{"welcome.links", []string{"add_link"}},
+	{"welcome.email", []string{"email_triage"}},
+	{"welcome.news", []string{"tech_news", "ai_papers"}},
+	{"welcome.finance", []string{"convert_currency", "stock_price"}},
+	{"welcome.background", []string{"spawn", "subagent"}},
+}
+
+// SetCommandHelp sets the slash command list included in welcomes, e.g.
+// channels.Commands.Help.
+func (al *AgentLoop) SetCommandHelp(fn func(channel, role string) string) {
+	al.commandHelp = fn
+}
+
+// welcome sends msg's sender a welcome if this is their first message on
+// the channel.
+func (al *AgentLoop) welcome(msg bus.InboundMessage, role roles.Role) {
+	if constants.IsInternalChannel(msg.Channel) || !al.onboarding.Welcomes(msg.Channel) || msg.SenderID == "" {
+		return
+	}
+	key := msg.Channel + ":" + msg.SenderID
+	var at time.Time
+	if seen, _ := al.state.Get(onboardingNamespace, key, &at); seen {
+		return
+	}
+	if err := al.state.Set(onboardingNamespace, key, time.Now()); err != nil {
+		logger.Warn("onboarding: cannot record %s: %v", key, err)
+		return
+	}
+	logger.Info("onboarding: welcoming %s", key)
+	al.bus.PublishOutbound(bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: al.welcomeMessage(msg.Channel, role)})
+}
+
+// welcomeMessage describes what role can use: capabilities from the
+// registered tools the role may call, enabled skills and the channel's
+// commands.
+func (al *AgentLoop) welcomeMessage(channel string, role roles.Role) string {
+	var allowed []string
+	for _, name := range al.tools.List() {
+		if al.roles.Allows(role, name) {
+			allowed = append(allowed, name)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("welcome.intro"))
+	covered := make(map[string]bool)
+	for _, c := range capabilities {
+		has := false
+		for _, t := range c.tools {
+			if slices.Contains(allowed, t) {
+				has, covered[t] = true, true
+			}
+		}
+		if has {
+			sb.WriteString("\n- " + i18n.T(c.area))
+		}
+	}
+	var other []string
+	for _, name := range allowed {
+		if !covered[name] {
+			other = append(other, name)
+		}
+	}
+	if len(other) > 0 {
+		sb.WriteString("\n- " + i18n.T("welcome.other_tools", strings.Join(other, ", ")))
+	}
+	if skills, _ := al.contextBuilder.GetSkillsInfo()["names"].([]string); len(skills) > 0 {
+		sb.WriteString("\n\n" + i18n.T("welcome.skills", strings.Join(skills, ", ")))
+	}
+	if al.commandHelp != nil {
+		sb.WriteString("\n\n" + al.commandHelp(channel, string(role)))
+	}
+	sb.WriteString("\n\n" + i18n.T("welcome.outro"))
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+	"localagent/pkg/roles"
+	"localagent/pkg/state"
+	"localagent/pkg/tools"
+)
+
+func TestWelcomeOncePerSender(t *testing.T) {
+	ws := t.TempDir()
+	registry := tools.NewToolRegistry()
+	registry.Register(tools.NewExecTool(ws))
+	registry.Register(tools.NewReadFileTool(ws))
+	mb := bus.NewMessageBus()
+	al := &AgentLoop{
+		bus:            mb,
+		state:          state.NewManager(ws),
+		tools:          registry,
+		contextBuilder: NewContextBuilder(ws),
+		roles: roles.NewResolver(config.RolesConfig{Channels: map[string]map[string]string{
+			"mqtt": {"guest1": "guest"},
+		}}),
+		commandHelp: func(channel, role string) string { return "Commands:\n/help - list commands" },
+	}
+
+	msg := bus.InboundMessage{Channel: "mqtt", SenderID: "alice", ChatID: "alice", Content: "hi"}
+	al.welcome(msg, roles.Owner)
+	al.welcome(msg, roles.Owner)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	out, ok := mb.SubscribeOutbound(ctx)
+	if !ok || out.ChatID != "alice" {
+		t.Fatalf("no welcome sent: %+v", out)
+	}
+	for _, want := range []string{"running commands", "reading and writing files", "/help"} {
+		if !strings.Contains(out.Content, want) {
+			t.Errorf("welcome lacks %q:\n%s", want, out.Content)
+		}
+	}
+	if _, ok := mb.SubscribeOutbound(ctx); ok {
+		t.Error("welcomed twice")
+	}
+
+	// Guests are only told about tools they may use.
+	if got := al.welcomeMessage("mqtt", roles.Guest); strings.Contains(got, "running commands") || strings.Contains(got, "files") {
+		t.Errorf("guest welcome offers denied tools:\n%s", got)
+	}
+
+	// The owner's web chat is left alone by default.
+	if (config.OnboardingConfig{}).Welcomes("web") {
+		t.Error("web chat welcomed by default")
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+	"localagent/pkg/providers"
+)
+
+// overflowProvider rejects prompts with more than maxMessages messages as
+// too long and answers summarization requests.
+type overflowProvider struct {
+	maxMessages int
+	calls       int
+	summarized  bool
+}
+
+func (p *overflowProvider) Chat(_ context.Context, messages []providers.Message, _ []providers.ToolDefinition, _ string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.calls++
+	if len(messages) == 1 && strings.Contains(messages[0].Content, "CONVERSATION:") {
+		p.summarized = true
+		return &providers.LLMResponse{Content: "We planned a trip to Rome."}, nil
+	}
+	if len(messages) > p.maxMessages {
+		return nil, &providers.APIError{StatusCode: 400, Body: `{"error":{"code":"context_length_exceeded","message":"This model's maximum context length is 8192 tokens."}}`}
+	}
+	return &providers.LLMResponse{Content: "Rome it is.", FinishReason: "stop"}, nil
+}
+
+func (p *overflowProvider) GetDefaultModel() string { return "" }
+
+func newOverflowLoop(t *testing.T, p providers.LLMProvider) *AgentLoop {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), p)
+	t.Cleanup(al.Stop)
+	return al
+}
+
+func TestContextOverflowSummarizesAndRetries(t *testing.T) {
+	p := &overflowProvider{maxMessages: 3}
+	al := newOverflowLoop(t, p)
+	for i := range 6 {
+		al.sessions.AddMessage("cli:test", []string{"user", "assistant"}[i%2], fmt.Sprintf("message %d", i))
+	}
+
+	answer, err := al.ProcessDirect(context.Background(), "So, where are we going?", "cli:test")
+	if err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	if answer != "Rome it is." || !p.summarized {
+		t.Errorf("answer = %q, summarized = %v", answer, p.summarized)
+	}
+	if got := al.sessions.GetSummary("cli:test"); got != "We planned a trip to Rome." {
+		t.Errorf("summary = %q", got)
+	}
+	history := al.sessions.GetHistory("cli:test")
+	if len(history) != 2 || history[0].Content != "So, where are we going?" {
+		t.Errorf("history after compaction = %+v", history)
+	}
+}
+
+func TestContextOverflowRetriesOnce(t *testing.T) {
+	p := &overflowProvider{maxMessages: 1}
+	al := newOverflowLoop(t, p)
+	al.sessions.AddMessage("cli:test", "user", "earlier")
+
+	_, err := al.ProcessDirect(context.Background(), "hello", "cli:test")
+	if !isContextOverflow(err) {
+		t.Fatalf("want the overflow error after one retry, got %v", err)
+	}
+	if p.calls != 3 { // first attempt, summary, retry
+		t.Errorf("provider calls = %d", p.calls)
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"strings"
+
+	"localagent/pkg/providers"
+)
+
+// Route keys for runs that aren't tied to a chat channel.
+const (
+	RouteHeartbeat = "heartbeat"
+	RouteCron      = "cron"
+	RouteSummary   = "summary"
+)
+
+// ModelRoute is where a channel's turns go.
+type ModelRoute struct {
+	Provider providers.LLMProvider // nil = the loop's provider
+	Model    string                // "" = the default model, see Model
+}
+
+// SetModelRoutes sends turns to the route named by their channel, or by
+// RouteHeartbeat, RouteCron or RouteSummary. Must be called before Run.
+func (al *AgentLoop) SetModelRoutes(routes map[string]ModelRoute) {
+	al.routes = routes
+}
+
+// routeKey names the route of a message: cron jobs run in "cron-<id>"
+// sessions on their delivery channel but are routed as cron.
+func routeKey(channel, sessionKey string) string {
+	if strings.HasPrefix(sessionKey, "cron-") {
+		return RouteCron
+	}
+	return channel
+}
+
+// route returns the provider and model for key. The model is "" when the
+// route doesn't set one, so the default still follows SetModel.
+func (al *AgentLoop) route(key string) (providers.LLMProvider, string) {
+	r := al.routes[key]
+	if r.Provider == nil {
+		return al.provider, r.Model
+	}
+	return r.Provider, r.Model
+}
+
+// modelFor is route with the default model filled in.
+func (al *AgentLoop) modelFor(key string) (providers.LLMProvider, string) {
+	provider, model := al.route(key)
+	if model == "" {
+		model = al.Model()
+	}
+	return provider, model
+}
@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"localagent/pkg/providers"
+)
+
+// modelProvider records the model of each call.
+type modelProvider struct {
+	models []string
+}
+
+func (p *modelProvider) Chat(_ context.Context, _ []providers.Message, _ []providers.ToolDefinition, model string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.models = append(p.models, model)
+	return &providers.LLMResponse{Content: "ok", FinishReason: "stop"}, nil
+}
+
+func (p *modelProvider) GetDefaultModel() string { return "" }
+
+func (p *modelProvider) last() string {
+	if len(p.models) == 0 {
+		return ""
+	}
+	return p.models[len(p.models)-1]
+}
+
+func TestModelRoutes(t *testing.T) {
+	primary, local := &modelProvider{}, &modelProvider{}
+	al := newOverflowLoop(t, primary)
+	al.SetModelRoutes(map[string]ModelRoute{
+		"cli":          {Provider: local, Model: "small"},
+		RouteCron:      {Model: "medium"},
+		RouteHeartbeat: {Provider: local},
+		RouteSummary:   {Provider: local, Model: "summarizer"},
+	})
+	ctx := context.Background()
+	defaultModel := al.Model()
+
+	al.ProcessDirect(ctx, "hi", "cli:test")
+	if len(primary.models) != 0 || local.last() != "small" {
+		t.Errorf("cli turn went to main %v, local %v", primary.models, local.models)
+	}
+	al.ProcessDirectWithChannel(ctx, "hi", "web:1", "web", "1")
+	if primary.last() != defaultModel {
+		t.Errorf("unrouted turn used %v, want %s on the main provider", primary.models, defaultModel)
+	}
+	// Cron jobs run on their delivery channel but have their own route.
+	al.ProcessDirectWithChannel(ctx, "daily report", "cron-abc", "cli", "direct")
+	if primary.last() != "medium" {
+		t.Errorf("cron turn used %v", primary.models)
+	}
+	al.ProcessHeartbeat(ctx, "anything to do?", "cli", "direct")
+	if local.last() != defaultModel {
+		t.Errorf("heartbeat used %v, want the default model on its provider", local.models)
+	}
+	al.SetModel("switched")
+	al.ProcessHeartbeat(ctx, "anything to do?", "cli", "direct")
+	if local.last() != "switched" {
+		t.Errorf("heartbeat after SetModel used %q", local.last())
+	}
+
+	if _, err := al.summarizeBatch(ctx, []providers.Message{{Role: "user", Content: "hi"}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if local.last() != "summarizer" {
+		t.Errorf("summary used %q", local.last())
+	}
+	// The memory flush before summarizing follows the summary route too.
+	calls := len(local.models)
+	al.memoryFlush("cli:test")
+	if len(local.models) == calls || local.last() != "summarizer" {
+		t.Errorf("memory flush used main %v, local %v", primary.models, local.models)
+	}
+}
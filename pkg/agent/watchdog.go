@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"localagent/pkg/activity"
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+// watchdogInterval is how often in-flight runs are checked.
+const watchdogInterval = 5 * time.Second
+
+// errStalled is the cancellation cause when the watchdog stops a run whose
+// current step made no progress.
+var errStalled = errors.New("step stalled")
+
+type inflightRun struct {
+	cancel context.CancelCauseFunc
+
+	mu     sync.Mutex
+	stage  string    // "LLM call #2", "tool web_fetch (iteration 2)"
+	since  time.Time // when stage began
+	warned int       // warnings emitted for stage
+	dumped bool      // goroutine dump written for stage
+}
+
+// enter marks the start of a step the watchdog should time.
+func (r *inflightRun) enter(stage string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stage, r.since, r.warned, r.dumped = stage, time.Now(), 0, false
+}
+
+// enterStage records the step the session's run is on, if one is running.
+func (al *AgentLoop) enterStage(sessionKey, stage string) {
+	if v, ok := al.inflight.Load(sessionKey); ok {
+		v.(*inflightRun).enter(stage)
+	}
+}
+
+// watchdog checks in-flight runs until stop is closed, flagging a step
+// that runs past the configured thresholds: a warning activity at each
+// multiple of warn, a goroutine dump (and with cfg.Cancel, cancellation)
+// at dump.
+func (al *AgentLoop) watchdog(cfg config.WatchdogConfig, stop <-chan struct{}) {
+	warn, dump := cfg.Thresholds()
+	if warn <= 0 && dump <= 0 {
+		return
+	}
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			al.inflight.Range(func(k, v any) bool {
+				al.checkRun(k.(string), v.(*inflightRun), warn, dump, cfg.Cancel)
+				return true
+			})
+		}
+	}
+}
+
+func (al *AgentLoop) checkRun(sessionKey string, r *inflightRun, warn, dump time.Duration, cancel bool) {
+	r.mu.Lock()
+	if r.stage == "" {
+		r.mu.Unlock()
+		return
+	}
+	stage, elapsed := r.stage, time.Since(r.since)
+	warnNow := warn > 0 && elapsed >= time.Duration(r.warned+1)*warn
+	if warnNow {
+		r.warned = int(elapsed / warn)
+	}
+	dumpNow := dump > 0 && elapsed >= dump && !r.dumped
+	if dumpNow {
+		r.dumped = true
+	}
+	r.mu.Unlock()
+
+	elapsed = elapsed.Round(time.Second)
+	if warnNow && !dumpNow {
+		logger.Warn("watchdog: session=%s has been on %s for %v", sessionKey, stage, elapsed)
+		al.emitActivity(sessionKey, activity.Event{
+			Type:      activity.Stalled,
+			Timestamp: time.Now(),
+			Message:   fmt.Sprintf("Still on %s after %v", stage, elapsed),
+			Detail:    map[string]any{"stage": stage, "seconds": int(elapsed.Seconds())},
+		})
+	}
+	if !dumpNow {
+		return
+	}
+
+	path, err := al.dumpGoroutines()
+	if err != nil {
+		logger.Error("watchdog: goroutine dump failed: %v", err)
+	}
+	logger.Error("watchdog: session=%s stalled on %s for %v; goroutines dumped to %s", sessionKey, stage, elapsed, path)
+	msg := fmt.Sprintf("Stalled on %s for %v", stage, elapsed)
+	if cancel {
+		msg += "; cancelling"
+	}
+	al.emitActivity(sessionKey, activity.Event{
+		Type:      activity.Stalled,
+		Timestamp: time.Now(),
+		Message:   msg,
+		Detail:    map[string]any{"stage": stage, "seconds": int(elapsed.Seconds()), "dump": path, "cancelled": cancel},
+	})
+	if cancel {
+		r.cancel(errStalled)
+	}
+}
+
+// dumpGoroutines writes every goroutine's stack to workspace/debug and
+// returns the file's path.
+func (al *AgentLoop) dumpGoroutines() (string, error) {
+	dir := filepath.Join(al.workspace, "debug")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "stall-"+time.Now().Format("20060102-150405")+".txt")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return path, pprof.Lookup("goroutine").WriteTo(f, 2)
+}
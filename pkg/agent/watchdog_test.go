@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/activity"
+	"localagent/pkg/session"
+)
+
+func TestWatchdogCheckRun(t *testing.T) {
+	al := &AgentLoop{workspace: t.TempDir(), activity: activity.NopEmitter{}, sessions: session.NewSessionManager("")}
+	ctx, cancel := context.WithCancelCause(context.Background())
+	run := &inflightRun{cancel: cancel}
+	run.enter("tool web_fetch (iteration 1)")
+
+	al.checkRun("web:default", run, time.Minute, 3*time.Minute, true)
+	if len(al.sessions.GetActivity("web:default")) != 0 {
+		t.Fatal("warned before the threshold")
+	}
+
+	run.since = time.Now().Add(-90 * time.Second)
+	al.checkRun("web:default", run, time.Minute, 3*time.Minute, true)
+	al.checkRun("web:default", run, time.Minute, 3*time.Minute, true) // same threshold, no repeat
+	events := al.sessions.GetActivity("web:default")
+	if len(events) != 1 || events[0].Type != activity.Stalled || !strings.Contains(events[0].Message, "web_fetch") {
+		t.Fatalf("events = %+v", events)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("cancelled on a warning")
+	}
+
+	run.since = time.Now().Add(-4 * time.Minute)
+	al.checkRun("web:default", run, time.Minute, 3*time.Minute, true)
+	events = al.sessions.GetActivity("web:default")
+	last := events[len(events)-1]
+	dump, _ := last.Detail["dump"].(string)
+	if data, err := os.ReadFile(dump); err != nil || !strings.Contains(string(data), "goroutine") {
+		t.Errorf("dump %q: %v", dump, err)
+	}
+	if !errors.Is(context.Cause(ctx), errStalled) {
+		t.Errorf("cause = %v", context.Cause(ctx))
+	}
+
+	// A new step starts the clock again.
+	run.enter("LLM call #2")
+	if run.dumped || run.warned != 0 {
+		t.Error("enter should reset the step state")
+	}
+}
@@ -0,0 +1,264 @@
+// Package airquality reads air quality and pollen forecasts from the
+// Open-Meteo air quality API, which needs no API key. Pollen is only
+// forecast for Europe, during the season.
+package airquality
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+)
+
+var apiURL = "https://air-quality-api.open-meteo.com/v1/air-quality"
+
+// Domain is the host forecasts are fetched from.
+const Domain = "air-quality-api.open-meteo.com"
+
+// Pollens are the pollen types Open-Meteo forecasts.
+var Pollens = []string{"alder", "birch", "grass", "mugwort", "olive", "ragweed"}
+
+// Level is a pollen load.
+type Level int
+
+const (
+	None Level = iota
+	Low
+	Moderate
+	High
+	VeryHigh
+)
+
+func (l Level) String() string {
+	return [...]string{"none", "low", "moderate", "high", "very high"}[l]
+}
+
+// pollenBands are rough upper bounds (grains/m³) of the low, moderate and
+// high loads as used by European pollen services; grass and weeds trouble
+// people at lower counts than tree pollen.
+var pollenBands = map[string][3]float64{
+	"alder":   {10, 50, 200},
+	"birch":   {10, 50, 200},
+	"olive":   {10, 50, 200},
+	"grass":   {5, 30, 100},
+	"mugwort": {5, 20, 50},
+	"ragweed": {5, 20, 50},
+}
+
+// PollenLevel classifies a count of the given pollen type.
+func PollenLevel(kind string, grains float64) Level {
+	if grains < 1 {
+		return None
+	}
+	bands, ok := pollenBands[kind]
+	if !ok {
+		bands = pollenBands["birch"]
+	}
+	for i, upper := range bands {
+		if grains < upper {
+			return Level(i + 1)
+		}
+	}
+	return VeryHigh
+}
+
+// AQIBand describes a European AQI value, e.g. "fair".
+func AQIBand(aqi float64) string {
+	switch {
+	case aqi < 20:
+		return "good"
+	case aqi < 40:
+		return "fair"
+	case aqi < 60:
+		return "moderate"
+	case aqi < 80:
+		return "poor"
+	case aqi < 100:
+		return "very poor"
+	}
+	return "extremely poor"
+}
+
+// poorAQI is the European AQI from which air quality is worth a briefing.
+const poorAQI = 60
+
+// Report is the current air quality at a place and today's peaks.
+type Report struct {
+	Place       string
+	EuropeanAQI float64
+	USAQI       float64
+	PM25        float64 // µg/m³
+	PM10        float64 // µg/m³
+	Ozone       float64 // µg/m³
+	PeakAQI     float64 // highest European AQI later today
+	// Pollen is today's peak count per type in grains/m³; types without a
+	// forecast (outside Europe or out of season) are missing.
+	Pollen map[string]float64
+}
+
+// Fetch returns the report at lat/lon.
+func Fetch(ctx context.Context, client *http.Client, lat, lon float64, now time.Time) (Report, error) {
+	hourly := []string{"european_aqi"}
+	for _, p := range Pollens {
+		hourly = append(hourly, p+"_pollen")
+	}
+	q := url.Values{
+		"latitude":      {fmt.Sprintf("%.4f", lat)},
+		"longitude":     {fmt.Sprintf("%.4f", lon)},
+		"current":       {"european_aqi,us_aqi,pm2_5,pm10,ozone"},
+		"hourly":        {strings.Join(hourly, ",")},
+		"timezone":      {"auto"},
+		"forecast_days": {"1"},
+	}
+	var data struct {
+		Current struct {
+			EuropeanAQI *float64 `json:"european_aqi"`
+			USAQI       *float64 `json:"us_aqi"`
+			PM25        *float64 `json:"pm2_5"`
+			PM10        *float64 `json:"pm10"`
+			Ozone       *float64 `json:"ozone"`
+		} `json:"current"`
+		Hourly    map[string]json.RawMessage `json:"hourly"`
+		UTCOffset int                        `json:"utc_offset_seconds"`
+		Reason    string                     `json:"reason"`
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return Report{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Report{}, err
+	}
+	defer resp.Body.Close()
+	body, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := json.Unmarshal(body, &data); err != nil && resp.StatusCode == http.StatusOK {
+		return Report{}, fmt.Errorf("decode air quality: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if data.Reason != "" {
+			return Report{}, fmt.Errorf("open-meteo: %s", data.Reason)
+		}
+		return Report{}, fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	c := data.Current
+	val := func(p *float64) float64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	r := Report{
+		EuropeanAQI: val(c.EuropeanAQI),
+		USAQI:       val(c.USAQI),
+		PM25:        val(c.PM25),
+		PM10:        val(c.PM10),
+		Ozone:       val(c.Ozone),
+		Pollen:      make(map[string]float64),
+	}
+	var times []string
+	json.Unmarshal(data.Hourly["time"], &times)
+	peak := func(key string) (float64, bool) {
+		var values []*float64
+		json.Unmarshal(data.Hourly[key], &values)
+		best, found := 0.0, false
+		for i, v := range values {
+			if v == nil || (i < len(times) && hourPassed(times[i], data.UTCOffset, now)) {
+				continue
+			}
+			best, found = max(best, *v), true
+		}
+		return best, found
+	}
+	r.PeakAQI, _ = peak("european_aqi")
+	for _, p := range Pollens {
+		if v, ok := peak(p + "_pollen"); ok {
+			r.Pollen[p] = v
+		}
+	}
+	return r, nil
+}
+
+// hourPassed reports whether the hour starting at s, a local time at the
+// place utcOffset seconds ahead of UTC, is over by now.
+func hourPassed(s string, utcOffset int, now time.Time) bool {
+	t, err := time.Parse("2006-01-02T15:04", s)
+	if err != nil {
+		return false
+	}
+	return !t.Add(time.Hour - time.Duration(utcOffset)*time.Second).After(now)
+}
+
+// allergens returns the pollen types of interest with a forecast, in
+// order; all types when interest is empty.
+func (r Report) allergens(interest []string) []string {
+	var kinds []string
+	for _, p := range Pollens {
+		if _, ok := r.Pollen[p]; ok && (len(interest) == 0 || slices.Contains(interest, p)) {
+			kinds = append(kinds, p)
+		}
+	}
+	return kinds
+}
+
+// Notable reports whether the report is worth bringing up unasked: poor
+// air or at least a moderate load of one of the allergens.
+func (r Report) Notable(allergens []string) bool {
+	if max(r.EuropeanAQI, r.PeakAQI) >= poorAQI {
+		return true
+	}
+	for _, p := range r.allergens(allergens) {
+		if PollenLevel(p, r.Pollen[p]) >= Moderate {
+			return true
+		}
+	}
+	return false
+}
+
+// Format describes the report in a few lines; only the allergens are
+// listed under pollen when set.
+func (r Report) Format(allergens []string) string {
+	var sb strings.Builder
+	if r.Place != "" {
+		fmt.Fprintf(&sb, "%s\n", r.Place)
+	}
+	fmt.Fprintf(&sb, "Air: %s (European AQI %.0f, US AQI %.0f), PM2.5 %.0f µg/m³, PM10 %.0f µg/m³, ozone %.0f µg/m³",
+		AQIBand(r.EuropeanAQI), r.EuropeanAQI, r.USAQI, r.PM25, r.PM10, r.Ozone)
+	if AQIBand(r.PeakAQI) != AQIBand(r.EuropeanAQI) && r.PeakAQI > r.EuropeanAQI {
+		fmt.Fprintf(&sb, "\nLater today: %s (AQI %.0f)", AQIBand(r.PeakAQI), r.PeakAQI)
+	}
+	kinds := r.allergens(allergens)
+	if len(kinds) == 0 {
+		sb.WriteString("\nPollen: no forecast here (Europe only, in season)")
+		return sb.String()
+	}
+	parts := make([]string, 0, len(kinds))
+	for _, p := range kinds {
+		level := PollenLevel(p, r.Pollen[p])
+		if level == None {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%.0f grains/m³)", p, level, r.Pollen[p]))
+	}
+	if len(parts) == 0 {
+		sb.WriteString("\nPollen today: none")
+	} else {
+		sb.WriteString("\nPollen today (peak): " + strings.Join(parts, ", "))
+	}
+	return sb.String()
+}
+
+// NewClient returns the client for Open-Meteo requests.
+func NewClient() *http.Client {
+	return httpclient.New("air_quality", httpclient.WithTimeout(15*time.Second))
+}
@@ -0,0 +1,113 @@
+package airquality
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/when"
+)
+
+// fakeAPI serves a day of forecasts at UTC+2 and counts requests.
+func fakeAPI(t *testing.T, grass string) *int {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("latitude") != "47.3700" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"utc_offset_seconds":7200,
+			"current":{"european_aqi":32,"us_aqi":45,"pm2_5":9.4,"pm10":15.2,"ozone":60},
+			"hourly":{"time":["2026-05-04T08:00","2026-05-04T09:00","2026-05-04T10:00"],
+				"european_aqi":[70,35,38],
+				"birch_pollen":[80,4,2],
+				"grass_pollen":` + grass + `,
+				"ragweed_pollen":[null,null,null]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	old := apiURL
+	apiURL = srv.URL
+	t.Cleanup(func() { apiURL = old })
+	return &calls
+}
+
+// now is 09:30 at UTC+2, so the 08:00 hour is over.
+var now = time.Date(2026, 5, 4, 7, 30, 0, 0, time.UTC)
+
+func TestFetch(t *testing.T) {
+	fakeAPI(t, `[10,40,20]`)
+	r, err := Fetch(context.Background(), NewClient(), 47.37, 8.54, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.EuropeanAQI != 32 || r.PeakAQI != 38 {
+		t.Errorf("aqi = %v, peak %v", r.EuropeanAQI, r.PeakAQI)
+	}
+	if r.Pollen["birch"] != 4 || r.Pollen["grass"] != 40 {
+		t.Errorf("pollen = %v", r.Pollen)
+	}
+	if _, ok := r.Pollen["ragweed"]; ok {
+		t.Error("ragweed has no forecast and should be missing")
+	}
+
+	got := r.Format(nil)
+	for _, want := range []string{"Air: fair (European AQI 32, US AQI 45), PM2.5 9 µg/m³", "birch low (4 grains/m³)", "grass high (40 grains/m³)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("format missing %q:\n%s", want, got)
+		}
+	}
+	if !r.Notable(nil) || !r.Notable([]string{"grass"}) || r.Notable([]string{"birch"}) {
+		t.Error("only a grass allergy should make this notable")
+	}
+}
+
+func TestPollenLevel(t *testing.T) {
+	tests := []struct {
+		kind   string
+		grains float64
+		want   Level
+	}{
+		{"grass", 0.4, None},
+		{"grass", 3, Low},
+		{"grass", 29, Moderate},
+		{"grass", 150, VeryHigh},
+		{"birch", 29, Moderate},
+		{"birch", 120, High},
+	}
+	for _, tt := range tests {
+		if got := PollenLevel(tt.kind, tt.grains); got != tt.want {
+			t.Errorf("PollenLevel(%s, %v) = %v, want %v", tt.kind, tt.grains, got, tt.want)
+		}
+	}
+}
+
+func TestBriefingOncePerDay(t *testing.T) {
+	when.SetLocation(time.UTC)
+	calls := fakeAPI(t, `[10,40,20]`)
+	cfg := config.AirQualityConfig{
+		Locations: []config.AirQualityLocation{{Name: "Zurich", Lat: 47.37, Lon: 8.54}},
+		Allergens: []string{"grass"},
+	}
+	b := NewBriefing(cfg, t.TempDir())
+	got := b.Briefing(now)
+	if !strings.Contains(got, "Zurich") || !strings.Contains(got, "grass high") || strings.Contains(got, "birch") {
+		t.Errorf("briefing = %q", got)
+	}
+	if got := b.Briefing(now.Add(time.Hour)); got != "" || *calls != 1 {
+		t.Errorf("second briefing = %q after %d calls", got, *calls)
+	}
+}
+
+func TestBriefingQuietDay(t *testing.T) {
+	when.SetLocation(time.UTC)
+	fakeAPI(t, `[1,2,1]`)
+	cfg := config.AirQualityConfig{Locations: []config.AirQualityLocation{{Name: "Zurich", Lat: 47.37, Lon: 8.54}}}
+	if got := NewBriefing(cfg, t.TempDir()).Briefing(now); got != "" {
+		t.Errorf("briefing = %q, want nothing on a quiet day", got)
+	}
+}
@@ -0,0 +1,101 @@
+package airquality
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/travel"
+	"localagent/pkg/when"
+)
+
+const (
+	stateNamespace = "air_quality"
+	fetchTimeout   = 20 * time.Second
+)
+
+// Location is a place with coordinates.
+type Location struct {
+	Name string
+	Lat  float64
+	Lon  float64
+}
+
+// Resolve returns the location of cfg, geocoding its name when it has no
+// coordinates.
+func Resolve(ctx context.Context, client *http.Client, cfg config.AirQualityLocation) (Location, error) {
+	if cfg.Lat != 0 || cfg.Lon != 0 {
+		return Location{Name: cfg.Name, Lat: cfg.Lat, Lon: cfg.Lon}, nil
+	}
+	p, err := travel.Geocode(ctx, client, cfg.Name)
+	if err != nil {
+		return Location{}, err
+	}
+	return Location{Name: p.Label(), Lat: p.Latitude, Lon: p.Longitude}, nil
+}
+
+// Report fetches the report at loc.
+func (loc Location) Report(ctx context.Context, client *http.Client, now time.Time) (Report, error) {
+	r, err := Fetch(ctx, client, loc.Lat, loc.Lon, now)
+	r.Place = loc.Name
+	return r, err
+}
+
+// Briefing adds notable air quality and pollen at the configured locations
+// to the first heartbeat of the day.
+type Briefing struct {
+	locations []config.AirQualityLocation
+	allergens []string
+	client    *http.Client
+	state     *state.Manager
+}
+
+func NewBriefing(cfg config.AirQualityConfig, workspace string) *Briefing {
+	return &Briefing{locations: cfg.Locations, allergens: cfg.Allergens, client: NewClient(), state: state.NewManager(workspace)}
+}
+
+// Briefing returns the reports worth mentioning once a day, or "" when
+// given already today or nothing is notable. When every lookup fails it
+// is retried on the next heartbeat.
+func (b *Briefing) Briefing(now time.Time) string {
+	today := now.In(when.Location()).Format("2006-01-02")
+	var briefed string
+	b.state.Get(stateNamespace, "briefed", &briefed)
+	if briefed == today {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	var notable []string
+	fetched := false
+	for _, cfg := range b.locations {
+		loc, err := Resolve(ctx, b.client, cfg)
+		if err != nil {
+			logger.Warn("air quality: %s: %v", cfg.Name, err)
+			continue
+		}
+		r, err := loc.Report(ctx, b.client, now)
+		if err != nil {
+			logger.Warn("air quality: %s: %v", cfg.Name, err)
+			continue
+		}
+		fetched = true
+		if r.Notable(b.allergens) {
+			notable = append(notable, r.Format(b.allergens))
+		}
+	}
+	if !fetched {
+		return ""
+	}
+	if err := b.state.Set(stateNamespace, "briefed", today); err != nil {
+		logger.Warn("air quality: failed to save briefing state: %v", err)
+	}
+	if len(notable) == 0 {
+		return ""
+	}
+	return "Air quality and pollen today (worth a mention for allergies):\n" + strings.Join(notable, "\n\n")
+}
@@ -0,0 +1,188 @@
+// Package audit keeps an append-only record of side-effecting actions the
+// agent took (commands run, files written, messages sent, calendar and cron
+// changes) so they can be reviewed later with `localagent audit`.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/storage"
+)
+
+// DefaultRetention is used when no retention period is configured.
+const DefaultRetention = 90 * 24 * time.Hour
+
+// pruneInterval is how often Record checks for entries past retention.
+const pruneInterval = 24 * time.Hour
+
+// Entry is a single audited action.
+type Entry struct {
+	Time     time.Time `json:"ts"`
+	Action   string    `json:"action"` // e.g. "exec", "file_write", "calendar.create_event"
+	Tool     string    `json:"tool"`
+	Target   string    `json:"target,omitempty"` // command, path, recipient or event the action touched
+	Session  string    `json:"session,omitempty"`
+	Channel  string    `json:"channel,omitempty"`
+	ChatID   string    `json:"chat_id,omitempty"`
+	ArgsHash string    `json:"args_hash"`
+	Status   string    `json:"status"` // "ok", "error" or "async"
+	Error    string    `json:"error,omitempty"`
+}
+
+// Log appends entries to a JSONL file and drops entries older than the
+// retention period.
+type Log struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	lastPrune time.Time
+}
+
+// NewLog opens the audit log at path. A retention of 0 uses DefaultRetention;
+// a negative retention keeps entries forever.
+func NewLog(path string, retention time.Duration) *Log {
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	return &Log{path: path, retention: retention}
+}
+
+// Record appends an entry, filling in the timestamp if unset.
+func (l *Log) Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.retention > 0 && time.Since(l.lastPrune) > pruneInterval {
+		if err := l.pruneLocked(time.Now().Add(-l.retention)); err != nil {
+			logger.Warn("audit: prune failed: %v", err)
+		}
+		l.lastPrune = time.Now()
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Warn("audit: failed to open %s: %v", l.path, err)
+		return
+	}
+	defer f.Close()
+	data, _ := json.Marshal(e)
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		logger.Warn("audit: failed to write %s: %v", l.path, err)
+	}
+}
+
+// Prune removes entries older than the retention period.
+func (l *Log) Prune() error {
+	if l.retention <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastPrune = time.Now()
+	return l.pruneLocked(time.Now().Add(-l.retention))
+}
+
+func (l *Log) pruneLocked(cutoff time.Time) error {
+	entries, err := ReadFile(l.path, Filter{})
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	keep := entries[:0]
+	for _, e := range entries {
+		if !e.Time.Before(cutoff) {
+			keep = append(keep, e)
+		}
+	}
+	if len(keep) == len(entries) {
+		return nil
+	}
+
+	var sb strings.Builder
+	for _, e := range keep {
+		data, _ := json.Marshal(e)
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	return storage.WriteFile(l.path, []byte(sb.String()), 0600)
+}
+
+// Filter selects entries when reading the log. Zero fields match everything.
+type Filter struct {
+	Since   time.Time
+	Tool    string
+	Action  string // matches exactly or as a prefix before "." (e.g. "calendar")
+	Session string
+	Limit   int // keep only the last Limit matches
+}
+
+func (f Filter) match(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Tool != "" && e.Tool != f.Tool {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action && !strings.HasPrefix(e.Action, f.Action+".") {
+		return false
+	}
+	if f.Session != "" && e.Session != f.Session {
+		return false
+	}
+	return true
+}
+
+// ReadFile returns the entries in an audit file that match the filter,
+// oldest first. A missing file yields no entries.
+func ReadFile(path string, f Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !f.match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if f.Limit > 0 && len(entries) > f.Limit {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// HashArgs returns a short, stable hash of tool arguments so identical calls
+// can be correlated without storing their contents.
+func HashArgs(args map[string]any) string {
+	data, err := json.Marshal(args) // map keys are sorted, so this is stable
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path, -1)
+
+	log.Record(Entry{Action: "exec", Tool: "exec", Target: "ls", Session: "web:default", Status: "ok"})
+	log.Record(Entry{Action: "calendar.create_event", Tool: "calendar", Target: "Dentist", Session: "telegram:1", Status: "ok"})
+	log.Record(Entry{Action: "calendar.delete_event", Tool: "calendar", Session: "web:default", Status: "error"})
+
+	all, err := ReadFile(path, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Time.IsZero() {
+		t.Fatalf("expected 3 timestamped entries, got %+v", all)
+	}
+
+	cal, _ := ReadFile(path, Filter{Action: "calendar"})
+	if len(cal) != 2 {
+		t.Errorf("action prefix filter: got %d entries", len(cal))
+	}
+	web, _ := ReadFile(path, Filter{Session: "web:default", Limit: 1})
+	if len(web) != 1 || web[0].Action != "calendar.delete_event" {
+		t.Errorf("session filter with limit: got %+v", web)
+	}
+}
+
+func TestPruneDropsExpiredEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(path, 24*time.Hour)
+	log.lastPrune = time.Now() // keep Record from pruning
+
+	log.Record(Entry{Time: time.Now().Add(-48 * time.Hour), Action: "exec", Status: "ok"})
+	log.Record(Entry{Action: "file_write", Status: "ok"})
+
+	if err := log.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := ReadFile(path, Filter{})
+	if len(entries) != 1 || entries[0].Action != "file_write" {
+		t.Errorf("expected only the recent entry, got %+v", entries)
+	}
+}
+
+func TestHashArgsStable(t *testing.T) {
+	a := HashArgs(map[string]any{"command": "ls", "working_dir": "/tmp"})
+	b := HashArgs(map[string]any{"working_dir": "/tmp", "command": "ls"})
+	if a == "" || a != b {
+		t.Errorf("hash should be stable regardless of key order: %q vs %q", a, b)
+	}
+	if a == HashArgs(map[string]any{"command": "rm"}) {
+		t.Error("different args should hash differently")
+	}
+}
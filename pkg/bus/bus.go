@@ -9,6 +9,9 @@ type MessageBus struct {
 	inbound  chan InboundMessage
 	outbound chan OutboundMessage
 	handlers map[string]MessageHandler
+	statuses map[string]StatusHandler
+	dedup    *dedupCache
+	watchers []Observer
 	closed   bool
 	mu       sync.RWMutex
 }
@@ -18,18 +21,49 @@ func NewMessageBus() *MessageBus {
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
 		handlers: make(map[string]MessageHandler),
+		statuses: make(map[string]StatusHandler),
+		dedup:    newDedupCache(),
 	}
 }
 
+// Observer sees every message published on the bus, e.g. to mirror it
+// elsewhere. Its methods run on the publisher's goroutine and must not block.
+type Observer interface {
+	ObserveInbound(InboundMessage)
+	ObserveOutbound(OutboundMessage)
+}
+
+// AddObserver registers o for all messages published from now on.
+func (mb *MessageBus) AddObserver(o Observer) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.watchers = append(mb.watchers, o)
+}
+
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 	mb.mu.RLock()
 	defer mb.mu.RUnlock()
 	if mb.closed {
 		return
 	}
+	for _, o := range mb.watchers {
+		o.ObserveInbound(msg)
+	}
 	mb.inbound <- msg
 }
 
+// Claim records msg's idempotency key and reports whether msg is new.
+// Channels call it before acting on a message (persisting, publishing) and
+// drop it if it is a redelivery. For a duplicate, firstID is the ID of the
+// message first seen with the key. Messages without a key are always new.
+func (mb *MessageBus) Claim(msg InboundMessage) (firstID string, fresh bool) {
+	key := msg.DedupKey()
+	if key == "" {
+		return msg.ID, true
+	}
+	return mb.dedup.claim(key, msg.ID)
+}
+
 func (mb *MessageBus) ConsumeInbound(ctx context.Context) (InboundMessage, bool) {
 	select {
 	case msg := <-mb.inbound:
@@ -45,6 +79,9 @@ func (mb *MessageBus) PublishOutbound(msg OutboundMessage) {
 	if mb.closed {
 		return
 	}
+	for _, o := range mb.watchers {
+		o.ObserveOutbound(msg)
+	}
 	mb.outbound <- msg
 }
 
@@ -57,6 +94,12 @@ func (mb *MessageBus) SubscribeOutbound(ctx context.Context) (OutboundMessage, b
 	}
 }
 
+// Depths returns how many messages wait in the inbound and outbound
+// queues.
+func (mb *MessageBus) Depths() (inbound, outbound int) {
+	return len(mb.inbound), len(mb.outbound)
+}
+
 func (mb *MessageBus) RegisterHandler(channel string, handler MessageHandler) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
@@ -70,6 +113,28 @@ func (mb *MessageBus) GetHandler(channel string) (MessageHandler, bool) {
 	return handler, ok
 }
 
+// SubscribeStatus registers a handler for delivery status updates of
+// messages that originated on the given channel.
+func (mb *MessageBus) SubscribeStatus(channel string, handler StatusHandler) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.statuses[channel] = handler
+}
+
+// PublishStatus delivers a status update to the originating channel's
+// handler, if any. Updates without a message ID are ignored.
+func (mb *MessageBus) PublishStatus(update StatusUpdate) {
+	if update.MessageID == "" {
+		return
+	}
+	mb.mu.RLock()
+	handler, ok := mb.statuses[update.Channel]
+	mb.mu.RUnlock()
+	if ok {
+		handler(update)
+	}
+}
+
 func (mb *MessageBus) Close() {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
@@ -0,0 +1,65 @@
+package bus
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// dedupTTL is how long an idempotency key is remembered. Webhook and
+	// client retries arrive within seconds to minutes.
+	dedupTTL = 10 * time.Minute
+	// dedupMaxKeys bounds the cache; the oldest keys go first.
+	dedupMaxKeys = 4096
+)
+
+// dedupCache remembers recently seen idempotency keys and the ID of the
+// message that first used each one.
+type dedupCache struct {
+	mu    sync.Mutex
+	seen  map[string]dedupEntry
+	order []string // insertion order, for eviction
+	now   func() time.Time
+}
+
+type dedupEntry struct {
+	id      string
+	expires time.Time
+}
+
+func newDedupCache() *dedupCache {
+	return &dedupCache{seen: make(map[string]dedupEntry), now: time.Now}
+}
+
+// claim records key for id and reports whether it is new. For a duplicate it
+// returns the ID recorded first.
+func (c *dedupCache) claim(key, id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	c.expire(now)
+	if e, ok := c.seen[key]; ok {
+		return e.id, false
+	}
+	c.seen[key] = dedupEntry{id: id, expires: now.Add(dedupTTL)}
+	c.order = append(c.order, key)
+	for len(c.order) > dedupMaxKeys {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	return id, true
+}
+
+// expire drops keys past their TTL. Keys expire in insertion order, so it
+// stops at the first live one. Must be called with c.mu held.
+func (c *dedupCache) expire(now time.Time) {
+	n := 0
+	for _, key := range c.order {
+		if e, ok := c.seen[key]; ok && now.Before(e.expires) {
+			break
+		}
+		delete(c.seen, key)
+		n++
+	}
+	c.order = c.order[n:]
+}
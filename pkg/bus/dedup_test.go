@@ -0,0 +1,32 @@
+package bus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimDropsRedeliveries(t *testing.T) {
+	mb := NewMessageBus()
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	mb.dedup.now = func() time.Time { return now }
+
+	first := InboundMessage{ID: "a", Channel: "telegram", IdempotencyKey: "42"}
+	if id, fresh := mb.Claim(first); !fresh || id != "a" {
+		t.Fatalf("first delivery: id=%q fresh=%v", id, fresh)
+	}
+	retry := InboundMessage{ID: "b", Channel: "telegram", IdempotencyKey: "42"}
+	if id, fresh := mb.Claim(retry); fresh || id != "a" {
+		t.Fatalf("redelivery: id=%q fresh=%v, want original id and not fresh", id, fresh)
+	}
+	if _, fresh := mb.Claim(InboundMessage{Channel: "web", IdempotencyKey: "42"}); !fresh {
+		t.Error("keys must be scoped per channel")
+	}
+	if _, fresh := mb.Claim(InboundMessage{Channel: "telegram"}); !fresh {
+		t.Error("messages without a key are always new")
+	}
+
+	now = now.Add(dedupTTL)
+	if _, fresh := mb.Claim(retry); !fresh {
+		t.Error("key should expire after the TTL")
+	}
+}
@@ -1,6 +1,7 @@
 package bus
 
 type InboundMessage struct {
+	ID         string            `json:"id,omitempty"` // set by channels that track delivery status
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
 	ChatID     string            `json:"chat_id"`
@@ -9,12 +10,55 @@ type InboundMessage struct {
 	SessionKey string            `json:"session_key"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
 	Persisted  bool              `json:"persisted,omitempty"` // true if user message was already saved to session
+	// IdempotencyKey identifies the message upstream (e.g. the platform's
+	// message ID or a client-generated key). Redeliveries carry the same key.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// MetadataMessageID is the metadata key under which channels pass the
+// upstream message ID; it becomes the IdempotencyKey.
+const MetadataMessageID = "message_id"
+
+// MetadataUnverified marks a message whose sender the channel could not
+// authenticate, e.g. a bare email From header. The agent handles it with
+// the guest role whatever role the sender ID has.
+const MetadataUnverified = "unverified"
+
+// DedupKey scopes the idempotency key to the channel, or returns "" if the
+// message has none.
+func (m InboundMessage) DedupKey() string {
+	if m.IdempotencyKey == "" {
+		return ""
+	}
+	return m.Channel + ":" + m.IdempotencyKey
 }
 
 type OutboundMessage struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
 	Content string `json:"content"`
+	ReplyTo string `json:"reply_to,omitempty"` // ID of the inbound message being answered
 }
 
+// MessageStatus is a step in an inbound message's delivery lifecycle.
+type MessageStatus string
+
+const (
+	StatusQueued     MessageStatus = "queued"
+	StatusProcessing MessageStatus = "processing"
+	StatusAnswered   MessageStatus = "answered"
+	StatusFailed     MessageStatus = "failed"
+	StatusCancelled  MessageStatus = "cancelled"
+)
+
+type StatusUpdate struct {
+	MessageID string        `json:"message_id"`
+	Channel   string        `json:"channel"`
+	ChatID    string        `json:"chat_id"`
+	Status    MessageStatus `json:"status"`
+	Error     string        `json:"error,omitempty"`
+}
+
+type StatusHandler func(StatusUpdate)
+
 type MessageHandler func(InboundMessage) error
@@ -0,0 +1,147 @@
+package channels
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AllowlistHandler serves runtime allowlist management:
+//
+//	GET    /allowlist/                         entries per channel and pending requests
+//	POST   /allowlist/entries                  add {"channel": "...", "entry": "..."}
+//	DELETE /allowlist/entries?channel=&entry=  remove an entry
+//	POST   /allowlist/approve                  allow {"channel": "...", "sender_id": "..."}
+//	POST   /allowlist/deny                     reject {"channel": "...", "sender_id": "..."}
+//
+// Only loopback clients are accepted. Since a web page the owner has open
+// can also reach loopback, requests from browsers on another site are
+// refused and POST bodies must be sent as application/json, which a page
+// can't do cross-origin without a CORS preflight.
+func AllowlistHandler(a *Allowlist) http.Handler {
+	list := func(w http.ResponseWriter) {
+		writeJSON(w, http.StatusOK, map[string]any{"channels": a.Channels(), "pending": a.Pending("")})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/allowlist/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/allowlist/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		list(w)
+	})
+	mux.HandleFunc("/allowlist/entries", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var body struct {
+				Channel string `json:"channel"`
+				Entry   string `json:"entry"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Channel == "" || strings.TrimSpace(body.Entry) == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "channel and entry are required"})
+				return
+			}
+			if err := a.Add(body.Channel, body.Entry); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			list(w)
+		case http.MethodDelete:
+			channel, entry := r.URL.Query().Get("channel"), r.URL.Query().Get("entry")
+			if channel == "" || entry == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "channel and entry are required"})
+				return
+			}
+			if err := a.Remove(channel, entry); err != nil {
+				writeJSON(w, errorStatus(err), map[string]string{"error": err.Error()})
+				return
+			}
+			list(w)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	decide := func(approve bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			var body struct {
+				Channel  string `json:"channel"`
+				SenderID string `json:"sender_id"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Channel == "" || body.SenderID == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "channel and sender_id are required"})
+				return
+			}
+			decision := a.Deny
+			if approve {
+				decision = a.Approve
+			}
+			if err := decision(body.Channel, body.SenderID); err != nil {
+				writeJSON(w, errorStatus(err), map[string]string{"error": err.Error()})
+				return
+			}
+			list(w)
+		}
+	}
+	mux.HandleFunc("/allowlist/approve", decide(true))
+	mux.HandleFunc("/allowlist/deny", decide(false))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin API is only available from localhost"})
+			return
+		}
+		if crossSite(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-site requests are not allowed"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// crossSite reports whether a browser sent r from a page on another
+// origin. Clients that aren't browsers send neither header.
+func crossSite(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return true
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err != nil || u.Host != r.Host
+	}
+	return false
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrEntryNotFound), errors.Is(err, ErrNotPending):
+		return http.StatusNotFound
+	case errors.Is(err, ErrLastEntry):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,249 @@
+package channels
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+// allowlistNamespace is the state store namespace holding runtime
+// allowlists, keyed by channel.
+const allowlistNamespace = "allowlist"
+
+// maxPending bounds the access requests kept per channel.
+const maxPending = 20
+
+var (
+	ErrEntryNotFound = errors.New("not on the allowlist")
+	ErrNotPending    = errors.New("no pending request from that sender")
+	// ErrLastEntry keeps an allowlist from being emptied, which would
+	// let anyone in.
+	ErrLastEntry = errors.New("cannot remove the last entry: an empty allowlist allows any sender")
+)
+
+// PendingSender is an unknown sender who messaged a channel with an
+// allowlist.
+type PendingSender struct {
+	Channel  string    `json:"channel"`
+	SenderID string    `json:"sender_id"`
+	ChatID   string    `json:"chat_id"`
+	Message  string    `json:"message,omitempty"` // first message, truncated
+	At       time.Time `json:"at"`
+}
+
+type channelAllowlist struct {
+	Allow   []string        `json:"allow"`
+	Pending []PendingSender `json:"pending,omitempty"`
+	Denied  []string        `json:"denied,omitempty"` // senders not asked about again
+	Edited  bool            `json:"edited,omitempty"` // Allow replaces the config entries
+}
+
+// Allowlist holds the senders each channel accepts. It starts from the
+// config entries; once a channel's list is edited at runtime it is kept in
+// the state store and takes precedence over config.
+type Allowlist struct {
+	state  *state.Manager
+	notify func(PendingSender)
+
+	mu    sync.Mutex
+	lists map[string]*channelAllowlist
+}
+
+func NewAllowlist(workspace string) *Allowlist {
+	a := &Allowlist{state: state.NewManager(workspace), lists: make(map[string]*channelAllowlist)}
+	for _, channel := range a.state.Keys(allowlistNamespace) {
+		var l channelAllowlist
+		if _, err := a.state.Get(allowlistNamespace, channel, &l); err != nil {
+			logger.Warn("allowlist: %v", err)
+			continue
+		}
+		a.lists[channel] = &l
+	}
+	return a
+}
+
+// SetNotifier sets the function told about each new access request, e.g.
+// to message the owner.
+func (a *Allowlist) SetNotifier(fn func(PendingSender)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.notify = fn
+}
+
+// seed registers channel with its config entries. A list edited at runtime
+// keeps its stored entries.
+func (a *Allowlist) seed(channel string, entries []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l := a.list(channel)
+	if !l.Edited {
+		l.Allow = slices.Clone(entries)
+	}
+}
+
+// list returns channel's allowlist, creating it. The caller holds a.mu.
+func (a *Allowlist) list(channel string) *channelAllowlist {
+	l, ok := a.lists[channel]
+	if !ok {
+		l = &channelAllowlist{}
+		a.lists[channel] = l
+	}
+	return l
+}
+
+// save persists channel's allowlist. The caller holds a.mu.
+func (a *Allowlist) save(channel string) error {
+	return a.state.Set(allowlistNamespace, channel, a.lists[channel])
+}
+
+// Allowed reports whether senderID may use channel. A channel without
+// entries allows any sender.
+func (a *Allowlist) Allowed(channel, senderID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l := a.lists[channel]
+	if l == nil || len(l.Allow) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(l.Allow, func(entry string) bool { return MatchSender(entry, senderID) })
+}
+
+// Channels returns the entries of every known channel.
+func (a *Allowlist) Channels() map[string][]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string][]string, len(a.lists))
+	for channel, l := range a.lists {
+		out[channel] = slices.Clone(l.Allow)
+	}
+	return out
+}
+
+// Add allows entry on channel. Adding to an empty list restricts the
+// channel to the listed senders.
+func (a *Allowlist) Add(channel, entry string) error {
+	entry = strings.TrimSpace(entry)
+	if channel == "" || entry == "" {
+		return fmt.Errorf("channel and entry are required")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l := a.list(channel)
+	if !slices.Contains(l.Allow, entry) {
+		l.Allow = append(l.Allow, entry)
+	}
+	l.Edited = true
+	l.Pending = slices.DeleteFunc(l.Pending, func(p PendingSender) bool { return MatchSender(entry, p.SenderID) })
+	l.Denied = slices.DeleteFunc(l.Denied, func(s string) bool { return MatchSender(entry, s) })
+	return a.save(channel)
+}
+
+// Remove takes entry off channel's list.
+func (a *Allowlist) Remove(channel, entry string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l := a.lists[channel]
+	if l == nil || !slices.Contains(l.Allow, entry) {
+		return ErrEntryNotFound
+	}
+	if len(l.Allow) == 1 {
+		return ErrLastEntry
+	}
+	l.Allow = slices.DeleteFunc(l.Allow, func(s string) bool { return s == entry })
+	l.Edited = true
+	return a.save(channel)
+}
+
+// Pending returns the open access requests for channel, or for every
+// channel when it is empty, oldest first.
+func (a *Allowlist) Pending(channel string) []PendingSender {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []PendingSender
+	for _, name := range slices.Sorted(maps.Keys(a.lists)) {
+		if channel == "" || name == channel {
+			out = append(out, a.lists[name].Pending...)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].At.Before(out[j].At) })
+	return out
+}
+
+// Request records a message from a sender channel doesn't allow. It
+// reports whether this is a new request, in which case the notifier is
+// told; repeat messages and denied senders are ignored.
+func (a *Allowlist) Request(channel, senderID, chatID, content string) bool {
+	a.mu.Lock()
+	l := a.list(channel)
+	known := slices.Contains(l.Denied, senderID) ||
+		slices.ContainsFunc(l.Pending, func(p PendingSender) bool { return p.SenderID == senderID })
+	if known || len(l.Pending) >= maxPending {
+		a.mu.Unlock()
+		return false
+	}
+	if r := []rune(content); len(r) > 200 {
+		content = string(r[:200]) + "..."
+	}
+	p := PendingSender{Channel: channel, SenderID: senderID, ChatID: chatID, Message: content, At: time.Now()}
+	l.Pending = append(l.Pending, p)
+	if err := a.save(channel); err != nil {
+		logger.Warn("allowlist: cannot save request from %s: %v", senderID, err)
+	}
+	notify := a.notify
+	a.mu.Unlock()
+
+	logger.Info("allowlist: access request from %s on %s", senderID, channel)
+	if notify != nil {
+		notify(p)
+	}
+	return true
+}
+
+// Approve allows a pending sender and drops the request. senderID may be
+// any form MatchSender accepts, e.g. just the ID.
+func (a *Allowlist) Approve(channel, senderID string) error {
+	a.mu.Lock()
+	p, ok := a.pending(channel, senderID)
+	a.mu.Unlock()
+	if !ok {
+		return ErrNotPending
+	}
+	id, _, _ := strings.Cut(p.SenderID, "|")
+	return a.Add(channel, id)
+}
+
+// Deny drops a pending request. Later messages from the sender are
+// ignored without asking again.
+func (a *Allowlist) Deny(channel, senderID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p, ok := a.pending(channel, senderID)
+	if !ok {
+		return ErrNotPending
+	}
+	l := a.lists[channel]
+	l.Pending = slices.DeleteFunc(l.Pending, func(q PendingSender) bool { return q.SenderID == p.SenderID })
+	l.Denied = append(l.Denied, p.SenderID)
+	return a.save(channel)
+}
+
+// pending finds the request from senderID on channel. The caller holds
+// a.mu.
+func (a *Allowlist) pending(channel, senderID string) (PendingSender, bool) {
+	if l := a.lists[channel]; l != nil && senderID != "" {
+		for _, p := range l.Pending {
+			if MatchSender(senderID, p.SenderID) {
+				return p, true
+			}
+		}
+	}
+	return PendingSender{}, false
+}
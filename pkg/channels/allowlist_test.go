@@ -0,0 +1,137 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/i18n"
+)
+
+func TestAllowlistRequestFlow(t *testing.T) {
+	ws := t.TempDir()
+	mb := bus.NewMessageBus()
+	a := NewAllowlist(ws)
+	var notified []PendingSender
+	a.SetNotifier(func(p PendingSender) { notified = append(notified, p) })
+
+	c := NewBaseChannel("mqtt", nil, mb, []string{"alice"})
+	c.SetAllowlist(a)
+
+	c.HandleMessage("bob", "bob", "hi, can I use this?", nil, nil)
+	c.HandleMessage("bob", "bob", "hello??", nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := mb.SubscribeOutbound(ctx)
+	if !ok || out.ChatID != "bob" || out.Content != i18n.T("channels.access_requested") {
+		t.Fatalf("rejection = %+v, %v", out, ok)
+	}
+	if len(notified) != 1 || notified[0].SenderID != "bob" || notified[0].Message != "hi, can I use this?" {
+		t.Fatalf("notified = %+v, want one request from bob", notified)
+	}
+
+	if err := a.Approve("mqtt", "carol"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Approve(carol) = %v, want ErrNotPending", err)
+	}
+	if err := a.Approve("mqtt", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsAllowed("bob") || len(a.Pending("")) != 0 {
+		t.Errorf("bob not allowed after approval, pending = %+v", a.Pending(""))
+	}
+
+	// Runtime edits survive a restart and win over the config entries.
+	b := NewAllowlist(ws)
+	c2 := NewBaseChannel("mqtt", nil, mb, []string{"alice"})
+	c2.SetAllowlist(b)
+	if got := b.Channels()["mqtt"]; strings.Join(got, ",") != "alice,bob" {
+		t.Errorf("reloaded entries = %v", got)
+	}
+	if err := b.Remove("mqtt", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Remove("mqtt", "bob"); !errors.Is(err, ErrLastEntry) {
+		t.Errorf("removing the last entry = %v, want ErrLastEntry", err)
+	}
+	if c2.IsAllowed("alice") {
+		t.Error("alice still allowed after removal")
+	}
+}
+
+func TestAllowlistDeny(t *testing.T) {
+	a := NewAllowlist(t.TempDir())
+	a.seed("mqtt", []string{"alice"})
+	if !a.Request("mqtt", "eve|evil", "eve", "let me in") {
+		t.Fatal("first request not recorded")
+	}
+	if err := a.Deny("mqtt", "eve"); err != nil {
+		t.Fatal(err)
+	}
+	if a.Request("mqtt", "eve|evil", "eve", "please") {
+		t.Error("denied sender asked about again")
+	}
+	if a.Allowed("mqtt", "eve|evil") {
+		t.Error("denied sender allowed")
+	}
+	if !a.Allowed("web", "anyone") {
+		t.Error("channel without entries should allow anyone")
+	}
+}
+
+func TestAllowlistHandler(t *testing.T) {
+	a := NewAllowlist(t.TempDir())
+	a.seed("mqtt", []string{"alice"})
+	h := AllowlistHandler(a)
+
+	send := func(method, target, body, remote string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = remote
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(method, target, body, remote string) *httptest.ResponseRecorder {
+		return send(method, target, body, remote, nil)
+	}
+	if rec := do(http.MethodGet, "/allowlist/", "", "192.0.2.1:1234"); rec.Code != http.StatusForbidden {
+		t.Errorf("remote GET = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/allowlist/entries", `{"channel":"mqtt","entry":"bob"}`, "127.0.0.1:1234"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"bob"`) {
+		t.Errorf("add = %d %s", rec.Code, rec.Body)
+	}
+	csrf := []map[string]string{
+		{"Content-Type": "text/plain"},
+		{"Origin": "https://evil.example"},
+		{"Sec-Fetch-Site": "cross-site"},
+	}
+	for _, header := range csrf {
+		if rec := send(http.MethodPost, "/allowlist/entries", `{"channel":"mqtt","entry":"mallory"}`, "127.0.0.1:1234", header); rec.Code == http.StatusOK {
+			t.Errorf("add with %v accepted", header)
+		}
+	}
+	if a.Allowed("mqtt", "mallory") {
+		t.Error("cross-site request added an entry")
+	}
+	if rec := do(http.MethodDelete, "/allowlist/entries?channel=mqtt&entry=carol", "", "127.0.0.1:1234"); rec.Code != http.StatusNotFound {
+		t.Errorf("remove unknown = %d, want 404", rec.Code)
+	}
+	a.Request("mqtt", "dave", "dave", "hi")
+	if rec := do(http.MethodPost, "/allowlist/approve", `{"channel":"mqtt","sender_id":"dave"}`, "127.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("approve = %d %s", rec.Code, rec.Body)
+	}
+	if !a.Allowed("mqtt", "dave") {
+		t.Error("dave not allowed after approval")
+	}
+}
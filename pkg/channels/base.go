@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"localagent/pkg/bus"
+	"localagent/pkg/i18n"
+	"localagent/pkg/logger"
 )
 
 type Channel interface {
@@ -23,6 +25,8 @@ type BaseChannel struct {
 	running   bool
 	name      string
 	allowList []string
+	allowlist *Allowlist
+	commands  *Commands
 }
 
 func NewBaseChannel(name string, config any, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -44,10 +48,25 @@ func (c *BaseChannel) IsRunning() bool {
 }
 
 func (c *BaseChannel) IsAllowed(senderID string) bool {
+	if c.allowlist != nil {
+		return c.allowlist.Allowed(c.name, senderID)
+	}
 	if len(c.allowList) == 0 {
 		return true
 	}
 
+	for _, allowed := range c.allowList {
+		if MatchSender(allowed, senderID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchSender reports whether senderID matches an allowlist entry. Both may
+// be "id|username"; entries may be prefixed with "@".
+func MatchSender(allowed, senderID string) bool {
 	idPart := senderID
 	userPart := ""
 	if idx := strings.Index(senderID, "|"); idx > 0 {
@@ -55,49 +74,79 @@ func (c *BaseChannel) IsAllowed(senderID string) bool {
 		userPart = senderID[idx+1:]
 	}
 
-	for _, allowed := range c.allowList {
-		trimmed := strings.TrimPrefix(allowed, "@")
-		allowedID := trimmed
-		allowedUser := ""
-		if idx := strings.Index(trimmed, "|"); idx > 0 {
-			allowedID = trimmed[:idx]
-			allowedUser = trimmed[idx+1:]
-		}
-
-		if senderID == allowed ||
-			idPart == allowed ||
-			senderID == trimmed ||
-			idPart == trimmed ||
-			idPart == allowedID ||
-			(allowedUser != "" && senderID == allowedUser) ||
-			(userPart != "" && (userPart == allowed || userPart == trimmed || userPart == allowedUser)) {
-			return true
-		}
+	trimmed := strings.TrimPrefix(allowed, "@")
+	allowedID := trimmed
+	allowedUser := ""
+	if idx := strings.Index(trimmed, "|"); idx > 0 {
+		allowedID = trimmed[:idx]
+		allowedUser = trimmed[idx+1:]
 	}
 
-	return false
+	return senderID == allowed ||
+		idPart == allowed ||
+		senderID == trimmed ||
+		idPart == trimmed ||
+		idPart == allowedID ||
+		(allowedUser != "" && senderID == allowedUser) ||
+		(userPart != "" && (userPart == allowed || userPart == trimmed || userPart == allowedUser))
 }
 
 func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []string, metadata map[string]string) {
 	if !c.IsAllowed(senderID) {
+		if c.allowlist != nil && c.allowlist.Request(c.name, senderID, chatID, content) {
+			c.bus.PublishOutbound(bus.OutboundMessage{Channel: c.name, ChatID: chatID, Content: i18n.T("channels.access_requested")})
+		}
 		return
 	}
 
 	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
 
 	msg := bus.InboundMessage{
-		Channel:    c.name,
-		SenderID:   senderID,
-		ChatID:     chatID,
-		Content:    content,
-		Media:      media,
-		SessionKey: sessionKey,
-		Metadata:   metadata,
+		Channel:        c.name,
+		SenderID:       senderID,
+		ChatID:         chatID,
+		Content:        content,
+		Media:          media,
+		SessionKey:     sessionKey,
+		Metadata:       metadata,
+		IdempotencyKey: metadata[bus.MetadataMessageID],
+	}
+	if _, fresh := c.bus.Claim(msg); !fresh {
+		logger.Info("%s: dropping duplicate message %s", c.name, msg.IdempotencyKey)
+		return
+	}
+
+	role := c.commands.Role(c.name, senderID)
+	if metadata[bus.MetadataUnverified] != "" {
+		role = "guest"
+	}
+	if reply, ok := c.commands.HandleAs(c.name, chatID, senderID, role, content); ok {
+		c.bus.PublishOutbound(bus.OutboundMessage{Channel: c.name, ChatID: chatID, Content: reply})
+		return
 	}
 
 	c.bus.PublishInbound(msg)
 }
 
+// SetAllowlist moves the channel's allowlist into a, which can change it at
+// runtime and records access requests from unknown senders.
+func (c *BaseChannel) SetAllowlist(a *Allowlist) {
+	a.seed(c.name, c.allowList)
+	c.allowlist = a
+}
+
+// SetCommands enables slash commands on the channel.
+func (c *BaseChannel) SetCommands(commands *Commands) {
+	c.commands = commands
+}
+
+// HandleCommand answers content if it is a slash command registered for
+// this channel. Channels that bypass HandleMessage call it before
+// publishing.
+func (c *BaseChannel) HandleCommand(chatID, senderID, content string) (reply string, handled bool) {
+	return c.commands.Handle(c.name, chatID, senderID, content)
+}
+
 func (c *BaseChannel) Bus() *bus.MessageBus {
 	return c.bus
 }
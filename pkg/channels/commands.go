@@ -0,0 +1,200 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"localagent/pkg/i18n"
+	"localagent/pkg/logger"
+)
+
+// commandTimeout bounds a single command handler.
+const commandTimeout = 30 * time.Second
+
+// CommandRequest is a slash command received on a channel.
+type CommandRequest struct {
+	Channel  string
+	ChatID   string
+	SenderID string
+	Role     string // sender's household role, "owner" unless roles say otherwise
+	Args     string // text after the command name, trimmed
+}
+
+// Command is a slash command answered by the channel layer without an LLM
+// round trip. Name has no leading "/". Channels limits the command to those
+// channels; empty means every channel. Only the owner may use a command
+// unless Public is set.
+type Command struct {
+	Name        string
+	Usage       string // argument synopsis shown by /help, e.g. "<duration> <text>"
+	Description string
+	Channels    []string
+	Public      bool // usable by family and guests too
+	Handler     func(ctx context.Context, req CommandRequest) (string, error)
+}
+
+func (c Command) availableOn(channel string) bool {
+	return len(c.Channels) == 0 || slices.Contains(c.Channels, channel)
+}
+
+func (c Command) allows(role string) bool {
+	return c.Public || role == RoleOwner
+}
+
+// RoleOwner is the role of senders when no role resolver is set.
+const RoleOwner = "owner"
+
+// Commands is a registry of slash commands shared by channels. A nil
+// *Commands handles nothing. Messages naming an unknown command are not
+// handled and reach the agent as usual.
+type Commands struct {
+	mu       sync.RWMutex
+	commands map[string]Command
+	roleOf   func(channel, senderID string) string
+}
+
+func NewCommands() *Commands {
+	return &Commands{commands: make(map[string]Command)}
+}
+
+// Register adds or replaces a command. "help" is built in and cannot be
+// replaced.
+func (c *Commands) Register(cmd Command) {
+	name := strings.ToLower(strings.TrimPrefix(cmd.Name, "/"))
+	if name == "" || name == "help" || cmd.Handler == nil {
+		return
+	}
+	cmd.Name = name
+	c.mu.Lock()
+	c.commands[name] = cmd
+	c.mu.Unlock()
+}
+
+// SetRoles sets how a sender's household role is found, e.g.
+// agent.AgentLoop.SenderRole. Without it every sender is the owner.
+func (c *Commands) SetRoles(fn func(channel, senderID string) string) {
+	c.mu.Lock()
+	c.roleOf = fn
+	c.mu.Unlock()
+}
+
+// Role returns the role of a sender on channel.
+func (c *Commands) Role(channel, senderID string) string {
+	if c == nil {
+		return RoleOwner
+	}
+	c.mu.RLock()
+	fn := c.roleOf
+	c.mu.RUnlock()
+	if fn == nil {
+		return RoleOwner
+	}
+	return fn(channel, senderID)
+}
+
+// List returns the commands available to role on channel, sorted by name.
+func (c *Commands) List(channel, role string) []Command {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []Command
+	for _, cmd := range c.commands {
+		if cmd.availableOn(channel) && cmd.allows(role) {
+			out = append(out, cmd)
+		}
+	}
+	slices.SortFunc(out, func(a, b Command) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Help renders the command list for role on channel.
+func (c *Commands) Help(channel, role string) string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("commands.header"))
+	for _, cmd := range c.List(channel, role) {
+		sb.WriteString("\n/" + cmd.Name)
+		if cmd.Usage != "" {
+			sb.WriteString(" " + cmd.Usage)
+		}
+		if cmd.Description != "" {
+			sb.WriteString(" - " + cmd.Description)
+		}
+	}
+	return sb.String()
+}
+
+// Handle answers content if it is a command available on channel. handled
+// is false for ordinary messages and unknown commands.
+func (c *Commands) Handle(channel, chatID, senderID, content string) (reply string, handled bool) {
+	if c == nil {
+		return "", false
+	}
+	return c.HandleAs(channel, chatID, senderID, c.Role(channel, senderID), content)
+}
+
+// HandleAs is Handle with the sender's role already known, e.g. "guest"
+// for a sender the channel could not authenticate.
+func (c *Commands) HandleAs(channel, chatID, senderID, role, content string) (reply string, handled bool) {
+	if c == nil {
+		return "", false
+	}
+	name, args, ok := ParseCommand(content)
+	if !ok {
+		return "", false
+	}
+	if name == "help" {
+		return c.Help(channel, role), true
+	}
+
+	c.mu.RLock()
+	cmd, ok := c.commands[name]
+	c.mu.RUnlock()
+	if !ok || !cmd.availableOn(channel) {
+		return "", false
+	}
+	if !cmd.allows(role) {
+		logger.Info("%s: /%s denied for %s (role %s)", channel, name, senderID, role)
+		return i18n.T("commands.owner_only", name), true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	reply, err := cmd.Handler(ctx, CommandRequest{Channel: channel, ChatID: chatID, SenderID: senderID, Role: role, Args: args})
+	if err != nil {
+		logger.Info("%s: /%s failed: %v", channel, name, err)
+		reply = fmt.Sprintf("/%s: %v", name, err)
+		if cmd.Usage != "" {
+			reply += "\n" + i18n.T("commands.usage", name, cmd.Usage)
+		}
+	}
+	return reply, true
+}
+
+// ParseCommand splits "/name args" into its lowercased name and trimmed
+// arguments. A "@bot" suffix on the name, as some chat apps add, is dropped.
+// Names are letters, digits, "_" and "-", so paths such as "/etc/hosts" are
+// not commands.
+func ParseCommand(content string) (name, args string, ok bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") {
+		return "", "", false
+	}
+	head, args := content[1:], ""
+	if i := strings.IndexFunc(head, unicode.IsSpace); i >= 0 {
+		head, args = head[:i], head[i:]
+	}
+	head, _, _ = strings.Cut(head, "@")
+	if head == "" {
+		return "", "", false
+	}
+	for _, r := range head {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(head), strings.TrimSpace(args), true
+}
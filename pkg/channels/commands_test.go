@@ -0,0 +1,132 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		in, name, args string
+		ok             bool
+	}{
+		{"/status", "status", "", true},
+		{"  /RemindMe 20m take out\tlaundry ", "remindme", "20m take out\tlaundry", true},
+		{"/tasks@localagent_bot work", "tasks", "work", true},
+		{"/model\ngpt-4o", "model", "gpt-4o", true},
+		{"/etc/hosts looks odd", "", "", false},
+		{"/ nothing", "", "", false},
+		{"hello /status", "", "", false},
+	}
+	for _, c := range cases {
+		name, args, ok := ParseCommand(c.in)
+		if name != c.name || args != c.args || ok != c.ok {
+			t.Errorf("ParseCommand(%q) = %q, %q, %v", c.in, name, args, ok)
+		}
+	}
+}
+
+func TestCommandsHandle(t *testing.T) {
+	cmds := NewCommands()
+	cmds.Register(Command{Name: "/echo", Usage: "<text>", Description: "repeat text", Handler: func(_ context.Context, req CommandRequest) (string, error) {
+		if req.Args == "" {
+			return "", errors.New("nothing to echo")
+		}
+		return req.Channel + ":" + req.ChatID + " " + req.Args, nil
+	}})
+	cmds.Register(Command{Name: "ring", Channels: []string{"web"}, Handler: func(context.Context, CommandRequest) (string, error) {
+		return "ding", nil
+	}})
+
+	if reply, ok := cmds.Handle("mqtt", "kitchen", "u", "/echo hi"); !ok || reply != "mqtt:kitchen hi" {
+		t.Errorf("echo = %q, %v", reply, ok)
+	}
+	if reply, ok := cmds.Handle("mqtt", "kitchen", "u", "/echo"); !ok || !strings.Contains(reply, "nothing to echo") || !strings.Contains(reply, "Usage: /echo <text>") {
+		t.Errorf("failed echo = %q, %v", reply, ok)
+	}
+	if _, ok := cmds.Handle("mqtt", "kitchen", "u", "/ring"); ok {
+		t.Error("web-only command handled on mqtt")
+	}
+	if _, ok := cmds.Handle("web", "default", "u", "/unknown thing"); ok {
+		t.Error("unknown commands should reach the agent")
+	}
+
+	help, _ := cmds.Handle("mqtt", "kitchen", "u", "/help")
+	if !strings.Contains(help, "/echo <text> - repeat text") || strings.Contains(help, "/ring") {
+		t.Errorf("mqtt help = %q", help)
+	}
+	if help, _ := cmds.Handle("web", "default", "u", "/help"); !strings.Contains(help, "/ring") {
+		t.Errorf("web help = %q", help)
+	}
+
+	var none *Commands
+	if _, ok := none.Handle("web", "default", "u", "/help"); ok {
+		t.Error("nil registry should handle nothing")
+	}
+}
+
+func TestBaseChannelAnswersCommands(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch := NewBaseChannel("mqtt", nil, msgBus, nil)
+	cmds := NewCommands()
+	cmds.Register(Command{Name: "ping", Handler: func(context.Context, CommandRequest) (string, error) { return "pong", nil }})
+	ch.SetCommands(cmds)
+
+	ch.HandleMessage("u", "kitchen", "/ping", nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || out.Content != "pong" || out.ChatID != "kitchen" || out.Channel != "mqtt" {
+		t.Fatalf("outbound = %+v, %v", out, ok)
+	}
+
+	ch.HandleMessage("u", "kitchen", "turn on the lights", nil, nil)
+	in, ok := msgBus.ConsumeInbound(ctx)
+	if !ok || in.Content != "turn on the lights" {
+		t.Errorf("inbound = %+v, %v", in, ok)
+	}
+}
+
+func TestCommandsRespectRoles(t *testing.T) {
+	cmds := NewCommands()
+	ran := 0
+	handler := func(context.Context, CommandRequest) (string, error) { ran++; return "ok", nil }
+	cmds.Register(Command{Name: "tasks", Handler: handler})
+	cmds.Register(Command{Name: "remindme", Public: true, Handler: handler})
+	cmds.SetRoles(func(channel, senderID string) string {
+		if senderID == "me" {
+			return "owner"
+		}
+		return "guest"
+	})
+
+	if reply, ok := cmds.Handle("discord", "c1", "stranger", "/tasks"); !ok || reply != "/tasks is only available to the owner." {
+		t.Errorf("guest /tasks = %q, %v", reply, ok)
+	}
+	if ran != 0 {
+		t.Fatal("owner-only command ran for a guest")
+	}
+	if reply, _ := cmds.Handle("discord", "c1", "stranger", "/remindme"); reply != "ok" {
+		t.Errorf("guest /remindme = %q", reply)
+	}
+	if help, _ := cmds.Handle("discord", "c1", "stranger", "/help"); strings.Contains(help, "/tasks") || !strings.Contains(help, "/remindme") {
+		t.Errorf("guest help = %q", help)
+	}
+	if reply, _ := cmds.Handle("discord", "c1", "me", "/tasks"); reply != "ok" {
+		t.Errorf("owner /tasks = %q", reply)
+	}
+
+	msgBus := bus.NewMessageBus()
+	ch := NewBaseChannel("email", nil, msgBus, nil)
+	ch.SetCommands(cmds)
+	ran = 0
+	ch.HandleMessage("me", "me", "/tasks", nil, map[string]string{bus.MetadataUnverified: "true"})
+	if ran != 0 {
+		t.Error("owner-only command ran for an unverified sender")
+	}
+}
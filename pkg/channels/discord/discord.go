@@ -0,0 +1,257 @@
+// Package discord connects the agent to Discord as a bot over the gateway
+// WebSocket and replies through the REST API.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/logger"
+	"localagent/pkg/utils"
+)
+
+const (
+	defaultAPIBase    = "https://discord.com/api/v10"
+	defaultGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+
+	// maxMessageLen is Discord's limit on message content, in characters.
+	maxMessageLen = 2000
+	// maxAttachmentBytes caps a downloaded attachment; larger ones are
+	// skipped.
+	maxAttachmentBytes = 25 << 20
+)
+
+// MediaDir is where attachments of Discord messages are saved.
+func MediaDir(dataDir string) string {
+	return filepath.Join(dataDir, "discord", "media")
+}
+
+// Channel answers Discord direct messages, mentions of the bot, and every
+// message in the configured listen channels. Each Discord channel (a DM or
+// a guild text channel) is its own chat, so sessions are keyed
+// "discord:<channel id>"; senders are "<user id>|<username>".
+type Channel struct {
+	*channels.BaseChannel
+	token    string
+	listen   []string
+	mediaDir string
+	media    *utils.MediaRetention
+	client   *http.Client
+
+	// apiBase and gatewayURL are overridden in tests.
+	apiBase    string
+	gatewayURL string
+
+	mu      sync.Mutex
+	botID   string
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func NewChannel(cfg config.DiscordConfig, msgBus *bus.MessageBus, mediaDir string) (*Channel, error) {
+	token := cfg.ResolveToken()
+	if token == "" {
+		return nil, fmt.Errorf("bot token env %s is not set", cfg.TokenEnv)
+	}
+	return &Channel{
+		BaseChannel: channels.NewBaseChannel("discord", cfg, msgBus, cfg.AllowFrom),
+		token:       token,
+		listen:      cfg.ListenChannels,
+		mediaDir:    mediaDir,
+		client:      httpclient.New("discord", httpclient.WithTimeout(60*time.Second)),
+		apiBase:     defaultAPIBase,
+		gatewayURL:  defaultGatewayURL,
+	}, nil
+}
+
+// SetMediaRetention prunes saved attachments under the agent's media
+// retention policy. Must be called before Start.
+func (c *Channel) SetMediaRetention(r *utils.MediaRetention) {
+	c.media = r
+	r.AddDir(c.mediaDir)
+}
+
+func (c *Channel) Start(ctx context.Context) error {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.stopped = make(chan struct{})
+	go c.run(ctx)
+	c.SetRunning(true)
+	return nil
+}
+
+func (c *Channel) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.stopped
+	}
+	c.SetRunning(false)
+	return nil
+}
+
+// Send posts msg to its Discord channel, split into several messages when
+// it is over Discord's length limit.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	for _, part := range channels.SplitMessage(msg.Content, maxMessageLen) {
+		body := map[string]any{"content": part}
+		if msg.ReplyTo != "" {
+			body["message_reference"] = map[string]any{"message_id": msg.ReplyTo, "fail_if_not_exists": false}
+			msg.ReplyTo = ""
+		}
+		if err := c.post(ctx, "/channels/"+msg.ChatID+"/messages", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Channel) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiBase+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		respBody, _ := httpclient.ReadBody(resp)
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("discord: %s (status %d)", apiErr.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("discord: %s returned status %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// message is the part of a MESSAGE_CREATE event the channel uses.
+type message struct {
+	ID          string       `json:"id"`
+	ChannelID   string       `json:"channel_id"`
+	GuildID     string       `json:"guild_id"`
+	Content     string       `json:"content"`
+	Author      user         `json:"author"`
+	Mentions    []user       `json:"mentions"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type user struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+type attachment struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+func (c *Channel) receive(ctx context.Context, m message) {
+	c.mu.Lock()
+	botID := c.botID
+	c.mu.Unlock()
+	if m.Author.Bot || m.Author.ID == botID {
+		return
+	}
+	content := m.Content
+	if m.GuildID != "" {
+		mentioned := slices.ContainsFunc(m.Mentions, func(u user) bool { return u.ID == botID })
+		if !mentioned && !slices.Contains(c.listen, m.ChannelID) {
+			return
+		}
+		if botID != "" {
+			content = strings.NewReplacer("<@"+botID+">", "", "<@!"+botID+">", "").Replace(content)
+		}
+	}
+	content = strings.TrimSpace(content)
+
+	senderID := m.Author.ID + "|" + m.Author.Username
+	if !c.IsAllowed(senderID) {
+		// HandleMessage rejects the sender too; this only avoids
+		// downloading their attachments first.
+		c.HandleMessage(senderID, m.ChannelID, content, nil, nil)
+		return
+	}
+	var media []string
+	for _, a := range m.Attachments {
+		path, err := c.download(ctx, a)
+		if err != nil {
+			logger.Warn("discord channel: attachment %s: %v", a.Filename, err)
+			continue
+		}
+		media = append(media, path)
+	}
+	if content == "" && len(media) == 0 {
+		return
+	}
+	metadata := map[string]string{bus.MetadataMessageID: m.ID}
+	if m.GuildID != "" {
+		metadata["guild_id"] = m.GuildID
+	}
+	c.HandleMessage(senderID, m.ChannelID, content, media, metadata)
+}
+
+// download saves an attachment to the media directory and returns its path.
+func (c *Channel) download(ctx context.Context, a attachment) (string, error) {
+	if a.Size > maxAttachmentBytes {
+		return "", fmt.Errorf("%d bytes is over the %d MB limit", a.Size, maxAttachmentBytes>>20)
+	}
+	if err := os.MkdirAll(c.mediaDir, 0700); err != nil {
+		return "", err
+	}
+	if c.media != nil {
+		go c.media.Prune()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	f, err := os.CreateTemp(c.mediaDir, "*_"+strings.ReplaceAll(utils.SanitizeFilename(a.Filename), "*", "_"))
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxAttachmentBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxAttachmentBytes {
+		err = fmt.Errorf("over the %d MB limit", maxAttachmentBytes>>20)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,157 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeDiscord serves a gateway that sends READY and then each of events
+// (with FILES replaced by the server URL) as a MESSAGE_CREATE, an
+// attachment at /files/notes.txt, and the messages endpoint, which reports
+// what is posted.
+func fakeDiscord(t *testing.T, events ...string) (*httptest.Server, <-chan string, <-chan map[string]any) {
+	t.Helper()
+	identified := make(chan string, 1)
+	posted := make(chan map[string]any, 10)
+	upgrader := websocket.Upgrader{}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/gateway":
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.WriteJSON(map[string]any{"op": opHello, "d": map[string]any{"heartbeat_interval": 45000}})
+			var identify struct {
+				Op int `json:"op"`
+				D  struct {
+					Token   string `json:"token"`
+					Intents int    `json:"intents"`
+				} `json:"d"`
+			}
+			if err := conn.ReadJSON(&identify); err != nil || identify.Op != opIdentify {
+				return
+			}
+			identified <- identify.D.Token
+			conn.WriteJSON(map[string]any{"op": opDispatch, "s": 1, "t": "READY", "d": map[string]any{
+				"session_id": "sess", "resume_gateway_url": "ws" + strings.TrimPrefix(srv.URL, "http"),
+				"user": map[string]any{"id": "99", "username": "agent", "bot": true},
+			}})
+			for i, e := range events {
+				conn.WriteJSON(map[string]any{"op": opDispatch, "s": i + 2, "t": "MESSAGE_CREATE", "d": json.RawMessage(strings.ReplaceAll(e, "FILES", srv.URL))})
+			}
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		case r.URL.Path == "/files/notes.txt":
+			w.Write([]byte("milk, eggs"))
+		case strings.HasPrefix(r.URL.Path, "/api/channels/"):
+			if r.Header.Get("Authorization") != "Bot secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"message":"401: Unauthorized"}`))
+				return
+			}
+			var body map[string]any
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			body["path"] = r.URL.Path
+			posted <- body
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, identified, posted
+}
+
+func newTestChannel(t *testing.T, srv *httptest.Server, cfg config.DiscordConfig) (*Channel, *bus.MessageBus) {
+	t.Helper()
+	t.Setenv("TEST_DISCORD_TOKEN", "secret")
+	cfg.TokenEnv = "TEST_DISCORD_TOKEN"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewChannel(cfg, msgBus, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.apiBase = srv.URL + "/api"
+	ch.gatewayURL = "ws" + strings.TrimPrefix(srv.URL, "http") + "/gateway"
+	ch.client = srv.Client()
+	return ch, msgBus
+}
+
+func TestChannelReceivesMessages(t *testing.T) {
+	srv, identified, _ := fakeDiscord(t,
+		`{"id":"m1","channel_id":"c1","guild_id":"g","content":"no mention, ignored","author":{"id":"1","username":"ann"}}`,
+		`{"id":"m2","channel_id":"c1","guild_id":"g","content":"<@99> shopping list?","author":{"id":"1","username":"ann"},
+		  "mentions":[{"id":"99"}],"attachments":[{"url":"FILES/files/notes.txt","filename":"notes.txt","size":9}]}`,
+		`{"id":"m3","channel_id":"d1","content":"from a stranger","author":{"id":"2","username":"bob"}}`,
+		`{"id":"m4","channel_id":"c2","guild_id":"g","content":"echo","author":{"id":"3","username":"otherbot","bot":true}}`,
+		`{"id":"m5","channel_id":"c2","guild_id":"g","content":"listening here","author":{"id":"1","username":"ann"}}`,
+		`{"id":"m6","channel_id":"d2","content":"a DM","author":{"id":"1","username":"ann"}}`,
+	)
+	ch, msgBus := newTestChannel(t, srv, config.DiscordConfig{AllowFrom: []string{"1"}, ListenChannels: []string{"c2"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch.Start(ctx)
+	defer ch.Stop(ctx)
+
+	if token := <-identified; token != "secret" {
+		t.Errorf("identified with %q", token)
+	}
+	got := map[string]bus.InboundMessage{}
+	for range 3 {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("inbound messages so far: %v", got)
+		}
+		got[msg.Metadata[bus.MetadataMessageID]] = msg
+	}
+	m2 := got["m2"]
+	if m2.Content != "shopping list?" || m2.SessionKey != "discord:c1" || m2.SenderID != "1|ann" || len(m2.Media) != 1 {
+		t.Fatalf("mention = %+v", m2)
+	}
+	if data, _ := os.ReadFile(m2.Media[0]); string(data) != "milk, eggs" {
+		t.Errorf("attachment = %q", data)
+	}
+	if got["m5"].SessionKey != "discord:c2" || got["m6"].SessionKey != "discord:d2" {
+		t.Errorf("inbound = %+v", got)
+	}
+}
+
+func TestChannelSend(t *testing.T) {
+	srv, _, posted := fakeDiscord(t)
+	ch, _ := newTestChannel(t, srv, config.DiscordConfig{})
+	long := strings.Repeat("word ", 500) // 2500 characters
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "c1", Content: long, ReplyTo: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	first, second := <-posted, <-posted
+	if first["path"] != "/api/channels/c1/messages" || first["message_reference"] == nil || second["message_reference"] != nil {
+		t.Errorf("posted %v then %v", first, second)
+	}
+	if n := len(first["content"].(string)); n > maxMessageLen || n+len(second["content"].(string)) < 2495 {
+		t.Errorf("split into %d and %d characters", n, len(second["content"].(string)))
+	}
+
+	ch.token = "wrong"
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "c1", Content: "hi"}); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes.
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+// intents are GUILD_MESSAGES, DIRECT_MESSAGES and MESSAGE_CONTENT; the last
+// must be enabled for the bot in the developer portal.
+const intents = 1<<9 | 1<<12 | 1<<15
+
+// payload is a gateway frame.
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// session is what a dropped connection needs to resume without missing
+// events.
+type session struct {
+	id        string
+	resumeURL string
+	seq       int64
+}
+
+// errFatal wraps errors that retrying will not fix, such as a bad token or
+// disallowed intents.
+var errFatal = errors.New("fatal")
+
+// run keeps a gateway connection open until ctx ends.
+func (c *Channel) run(ctx context.Context) {
+	defer close(c.stopped)
+	var sess session
+	backoff := time.Second
+	for ctx.Err() == nil {
+		connected, err := c.connect(ctx, &sess)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errFatal) {
+			logger.Error("discord channel: %v; giving up", err)
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		logger.Warn("discord channel: gateway connection lost: %v (retrying in %v)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+// connect runs one gateway connection, resuming sess when it has one, and
+// reports whether the connection got as far as receiving events.
+func (c *Channel) connect(ctx context.Context, sess *session) (bool, error) {
+	url := c.gatewayURL
+	if sess.id != "" && sess.resumeURL != "" {
+		url = sess.resumeURL + "/?v=10&encoding=json"
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	var writeMu sync.Mutex
+	send := func(op int, d any) error {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(payload{Op: op, D: data})
+	}
+
+	var hello payload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return false, err
+	}
+	if hello.Op != opHello {
+		return false, fmt.Errorf("expected hello, got op %d", hello.Op)
+	}
+	var h struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	json.Unmarshal(hello.D, &h)
+	if h.HeartbeatInterval <= 0 {
+		return false, fmt.Errorf("hello without heartbeat interval")
+	}
+
+	if sess.id != "" {
+		err = send(opResume, map[string]any{"token": c.token, "session_id": sess.id, "seq": sess.seq})
+	} else {
+		err = send(opIdentify, map[string]any{
+			"token":   c.token,
+			"intents": intents,
+			"properties": map[string]string{
+				"os":      runtime.GOOS,
+				"browser": "localagent",
+				"device":  "localagent",
+			},
+		})
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// seq is shared with the heartbeat goroutine; acked is cleared on each
+	// beat and set by its ack, so a missing ack means a zombie connection.
+	var mu sync.Mutex
+	acked := true
+	heartbeat := func() error {
+		mu.Lock()
+		seq := sess.seq
+		mu.Unlock()
+		if seq == 0 {
+			return send(opHeartbeat, nil)
+		}
+		return send(opHeartbeat, seq)
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(h.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				missed := !acked
+				acked = false
+				mu.Unlock()
+				if missed {
+					logger.Warn("discord channel: no heartbeat ack, reconnecting")
+					conn.Close()
+					return
+				}
+				if heartbeat() != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	connected := false
+	for {
+		var p payload
+		if err := conn.ReadJSON(&p); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				switch closeErr.Code {
+				case 4004, 4010, 4011, 4012, 4013, 4014:
+					return connected, fmt.Errorf("%w: gateway closed: %s (%d)", errFatal, closeErr.Text, closeErr.Code)
+				case 4007, 4009:
+					sess.id = "" // the session cannot be resumed
+				}
+			}
+			return connected, err
+		}
+		if p.S != nil {
+			mu.Lock()
+			sess.seq = *p.S
+			mu.Unlock()
+		}
+		switch p.Op {
+		case opDispatch:
+			connected = true
+			c.dispatch(ctx, sess, p)
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return connected, err
+			}
+		case opHeartbeatAck:
+			mu.Lock()
+			acked = true
+			mu.Unlock()
+		case opReconnect:
+			return connected, fmt.Errorf("gateway asked to reconnect")
+		case opInvalidSession:
+			var resumable bool
+			json.Unmarshal(p.D, &resumable)
+			if !resumable {
+				sess.id = ""
+			}
+			return connected, fmt.Errorf("invalid session")
+		}
+	}
+}
+
+func (c *Channel) dispatch(ctx context.Context, sess *session, p payload) {
+	switch p.T {
+	case "READY":
+		var ready struct {
+			SessionID        string `json:"session_id"`
+			ResumeGatewayURL string `json:"resume_gateway_url"`
+			User             user   `json:"user"`
+		}
+		if err := json.Unmarshal(p.D, &ready); err != nil {
+			logger.Warn("discord channel: bad READY event: %v", err)
+			return
+		}
+		sess.id, sess.resumeURL = ready.SessionID, ready.ResumeGatewayURL
+		c.mu.Lock()
+		c.botID = ready.User.ID
+		c.mu.Unlock()
+		logger.Info("discord channel: connected as %s", ready.User.Username)
+	case "RESUMED":
+		logger.Info("discord channel: resumed session")
+	case "MESSAGE_CREATE":
+		var m message
+		if err := json.Unmarshal(p.D, &m); err != nil {
+			logger.Warn("discord channel: bad MESSAGE_CREATE event: %v", err)
+			return
+		}
+		go c.receive(ctx, m)
+	}
+}
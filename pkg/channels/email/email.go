@@ -0,0 +1,565 @@
+// Package email makes the agent reachable by email: an IMAP mailbox is
+// polled for mail from allowed senders and replies go out over SMTP.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	imap "localagent/pkg/email"
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/utils"
+)
+
+const (
+	channelNamespace = "email_channel"
+	defaultPollEvery = time.Minute
+	// maxMessageBytes bounds a fetched message, attachments included.
+	maxMessageBytes = 25 << 20
+)
+
+// Channel makes the agent reachable by email. Each correspondent is one
+// chat keyed by their address, so a back-and-forth keeps its context; only
+// mail from allowed senders is read, and automatic mail (out-of-office
+// replies, bounces) is skipped so two bots can't talk in circles. The
+// mailbox is opened read-only and new mail is found by UID. Since anyone
+// can write any From header, senders are only trusted when the receiving
+// server's Authentication-Results vouch for them (see AuthServID); other
+// mail is marked unverified and handled with the guest role.
+type Channel struct {
+	*channels.BaseChannel
+	cfg      config.MailChannelConfig
+	password string
+	from     *mail.Address
+	mailbox  string
+	poll     time.Duration
+	mediaDir string
+	media    *utils.MediaRetention
+	state    *state.Manager
+
+	// dial connects to the IMAP server; replaced in tests.
+	dial func(ctx context.Context) (*imap.Client, error)
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// cursor is the last UID handled, valid while the mailbox's UIDVALIDITY
+// stays the same.
+type cursor struct {
+	UIDValidity uint32 `json:"uid_validity"`
+	UID         uint32 `json:"uid"`
+}
+
+// thread is the latest message from a correspondent, which replies answer.
+type thread struct {
+	Subject    string `json:"subject"`
+	MessageID  string `json:"message_id,omitempty"`
+	References string `json:"references,omitempty"`
+}
+
+func NewChannel(cfg config.MailChannelConfig, msgBus *bus.MessageBus, workspace string) (*Channel, error) {
+	if cfg.IMAPHost == "" || cfg.SMTPHost == "" || cfg.Username == "" {
+		return nil, fmt.Errorf("imap_host, smtp_host and username are required")
+	}
+	if len(cfg.AllowFrom) == 0 {
+		return nil, fmt.Errorf("allow_from is required: anyone can send email")
+	}
+	password := cfg.ResolvePassword()
+	if password == "" {
+		return nil, fmt.Errorf("password env %q is not set", cfg.PasswordEnv)
+	}
+	address := cfg.Address
+	if address == "" {
+		address = cfg.Username
+	}
+	from, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("address %q: %v", address, err)
+	}
+	allow := make([]string, len(cfg.AllowFrom))
+	for i, a := range cfg.AllowFrom {
+		allow[i] = strings.ToLower(strings.TrimSpace(a))
+	}
+	c := &Channel{
+		BaseChannel: channels.NewBaseChannel("email", cfg, msgBus, allow),
+		cfg:         cfg,
+		password:    password,
+		from:        from,
+		mailbox:     cfg.Mailbox,
+		poll:        time.Duration(cfg.PollSeconds) * time.Second,
+		mediaDir:    filepath.Join(workspace, "media", "email"),
+		state:       state.NewManager(workspace),
+	}
+	if c.mailbox == "" {
+		c.mailbox = "INBOX"
+	}
+	if c.poll <= 0 {
+		c.poll = defaultPollEvery
+	}
+	c.dial = func(ctx context.Context) (*imap.Client, error) {
+		return imap.Dial(ctx, cfg.IMAPAddr(), cfg.Plaintext)
+	}
+	return c, nil
+}
+
+// SetMediaRetention prunes saved attachments under the agent's media
+// retention policy. Must be called before Start.
+func (c *Channel) SetMediaRetention(r *utils.MediaRetention) {
+	c.media = r
+	r.AddDir(c.mediaDir)
+}
+
+func (c *Channel) Start(ctx context.Context) error {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.stopped = make(chan struct{})
+	go c.run(ctx)
+	c.SetRunning(true)
+	return nil
+}
+
+func (c *Channel) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.stopped
+	}
+	c.SetRunning(false)
+	return nil
+}
+
+// run checks the mailbox every poll until ctx ends.
+func (c *Channel) run(ctx context.Context) {
+	defer close(c.stopped)
+	ticker := time.NewTicker(c.poll)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		err := c.Check(checkCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("email channel: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reads the mail that arrived since the last check. The first check
+// (and one after the mailbox's UIDs were reset) only notes where new mail
+// starts.
+func (c *Channel) Check(ctx context.Context) error {
+	cl, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", c.cfg.IMAPAddr(), err)
+	}
+	defer cl.Close()
+	cl.SetMaxLiteral(maxMessageBytes)
+	if err := cl.Login(c.cfg.Username, c.password); err != nil {
+		return err
+	}
+	mb, err := cl.ExamineStatus(c.mailbox)
+	if err != nil {
+		return err
+	}
+	var cur cursor
+	known, _ := c.state.Get(channelNamespace, "cursor", &cur)
+	if !known || cur.UIDValidity != mb.UIDValidity {
+		cur = cursor{UIDValidity: mb.UIDValidity}
+		if mb.UIDNext > 0 {
+			cur.UID = mb.UIDNext - 1
+		} else if uids, err := cl.SearchAfter(0); err != nil {
+			return err
+		} else if len(uids) > 0 {
+			cur.UID = uids[len(uids)-1]
+		}
+		cl.Logout()
+		return c.state.Set(channelNamespace, "cursor", cur)
+	}
+
+	uids, err := cl.SearchAfter(cur.UID)
+	if err != nil || len(uids) == 0 {
+		cl.Logout()
+		return err
+	}
+	headers, err := cl.FetchItem(uids, "BODY.PEEK[HEADER.FIELDS (FROM)]")
+	if err != nil {
+		return err
+	}
+	var wanted []uint32
+	for _, uid := range uids {
+		addr := fromAddress(headers[uid])
+		if addr != "" && c.IsAllowed(addr) {
+			wanted = append(wanted, uid)
+		} else {
+			logger.Info("email channel: ignoring mail from %q", addr)
+		}
+	}
+	raw, err := cl.FetchItem(wanted, "BODY.PEEK[]")
+	if err != nil {
+		return err
+	}
+	cl.Logout()
+	for _, uid := range wanted {
+		if data, ok := raw[uid]; ok {
+			c.receive(data)
+		}
+	}
+	cur.UID = uids[len(uids)-1]
+	return c.state.Set(channelNamespace, "cursor", cur)
+}
+
+// fromAddress returns the lower-cased sender address of a header block.
+func fromAddress(header []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(append(bytes.TrimRight(header, "\r\n"), "\r\n\r\n"...)))
+	if err != nil {
+		return ""
+	}
+	a, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(a.Address)
+}
+
+// receive turns a whole message into an inbound message from its sender.
+func (c *Channel) receive(raw []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		logger.Warn("email channel: unreadable message: %v", err)
+		return
+	}
+	h := msg.Header
+	addr := fromAddress([]byte("From: " + h.Get("From")))
+	if auto := h.Get("Auto-Submitted"); auto != "" && !strings.EqualFold(auto, "no") {
+		logger.Info("email channel: skipping automatic mail from %s", addr)
+		return
+	}
+	verified := c.cfg.AuthServID != ""
+	if verified && !authenticated(h, c.cfg.AuthServID, addr) {
+		logger.Warn("email channel: ignoring mail from %s: no passing Authentication-Results from %s", addr, c.cfg.AuthServID)
+		return
+	}
+	subject, err := wordDecoder.DecodeHeader(h.Get("Subject"))
+	if err != nil {
+		subject = h.Get("Subject")
+	}
+	body, _ := io.ReadAll(msg.Body)
+	plain, html, files := mimeParts(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), "", body, 0)
+	if plain == "" {
+		plain = imap.HTMLText(html)
+	}
+	text := stripQuoted(plain)
+	content := text
+	if subject != "" {
+		content = strings.TrimSpace("Subject: " + subject + "\n\n" + text)
+	}
+	if !c.IsAllowed(addr) {
+		// HandleMessage rejects the sender too; this only keeps their
+		// attachments and thread off disk.
+		c.HandleMessage(addr, addr, content, nil, nil)
+		return
+	}
+
+	var media []string
+	for _, f := range files {
+		path, err := c.save(f)
+		if err != nil {
+			logger.Warn("email channel: attachment %s: %v", f.name, err)
+			continue
+		}
+		media = append(media, path)
+	}
+	if text == "" && subject == "" && len(media) == 0 {
+		return
+	}
+
+	messageID := strings.TrimSpace(h.Get("Message-Id"))
+	refs := strings.TrimSpace(h.Get("References"))
+	if err := c.state.Set(channelNamespace, "thread:"+addr, thread{Subject: subject, MessageID: messageID, References: refs}); err != nil {
+		logger.Warn("email channel: save thread: %v", err)
+	}
+	metadata := make(map[string]string)
+	if messageID != "" {
+		metadata[bus.MetadataMessageID] = messageID
+	}
+	if !verified {
+		metadata[bus.MetadataUnverified] = "true"
+	}
+	c.HandleMessage(addr, addr, content, media, metadata)
+}
+
+// authenticated reports whether the topmost Authentication-Results header
+// from authServID shows DMARC passing for the domain of addr, or DKIM or
+// SPF passing for that domain. The receiving server removes headers that
+// claim its authserv-id and adds its own on top, so later ones don't count.
+func authenticated(h mail.Header, authServID, addr string) bool {
+	domain := addr[strings.LastIndexByte(addr, '@')+1:]
+	for _, v := range h["Authentication-Results"] {
+		id, results, _ := strings.Cut(v, ";")
+		if f := strings.Fields(id); len(f) == 0 || !strings.EqualFold(f[0], authServID) {
+			continue
+		}
+		for _, res := range strings.Split(results, ";") {
+			f := strings.Fields(strings.ToLower(res))
+			if len(f) == 0 {
+				continue
+			}
+			method, result, _ := strings.Cut(f[0], "=")
+			if result != "pass" {
+				continue
+			}
+			props := make(map[string]string)
+			for _, p := range f[1:] {
+				if k, v, ok := strings.Cut(p, "="); ok {
+					props[k] = strings.Trim(v, `"`)
+				}
+			}
+			switch method {
+			case "dmarc":
+				if props["header.from"] == domain {
+					return true
+				}
+			case "dkim":
+				if props["header.d"] == domain || strings.HasSuffix(props["header.i"], "@"+domain) {
+					return true
+				}
+			case "spf":
+				if from := props["smtp.mailfrom"]; from == domain || strings.HasSuffix(from, "@"+domain) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+var wordDecoder = mime.WordDecoder{}
+
+// attachment is a file part of a message.
+type attachment struct {
+	name string
+	data []byte
+}
+
+// mimeParts returns the first plain and HTML text of a message body and
+// its attachments.
+func mimeParts(contentType, encoding, disposition string, body []byte, depth int) (plain, html string, files []attachment) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && depth < 5 {
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(p)
+			pt, ph, pf := mimeParts(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p.Header.Get("Content-Disposition"), data, depth+1)
+			if plain == "" {
+				plain = pt
+			}
+			if html == "" {
+				html = ph
+			}
+			files = append(files, pf...)
+		}
+		return plain, html, files
+	}
+	disp, dparams, _ := mime.ParseMediaType(disposition)
+	name := dparams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	data := imap.Decode(encoding, body)
+	if name != "" || disp == "attachment" {
+		if name == "" {
+			name = "attachment"
+		}
+		return "", "", []attachment{{name: name, data: data}}
+	}
+	switch mediaType {
+	case "text/plain":
+		return string(data), "", nil
+	case "text/html":
+		return "", string(data), nil
+	}
+	return "", "", nil
+}
+
+// quoteIntro matches the line mail clients put before a quoted reply,
+// e.g. "On Mon, 12 Oct 2026 at 09:00, Ann <ann@example.com> wrote:".
+var quoteIntro = regexp.MustCompile(`(?i)^(on .+ wrote:|-+ ?original message ?-+)$`)
+
+// stripQuoted drops the quoted earlier messages below a reply.
+func stripQuoted(text string) string {
+	var out []string
+	for _, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		t := strings.TrimSpace(l)
+		if quoteIntro.MatchString(t) {
+			break
+		}
+		if strings.HasPrefix(t, ">") {
+			continue
+		}
+		out = append(out, strings.TrimRight(l, " "))
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// save writes an attachment to the media directory and returns its path.
+func (c *Channel) save(f attachment) (string, error) {
+	if err := os.MkdirAll(c.mediaDir, 0700); err != nil {
+		return "", err
+	}
+	if c.media != nil {
+		go c.media.Prune()
+	}
+	out, err := os.CreateTemp(c.mediaDir, "*_"+strings.ReplaceAll(utils.SanitizeFilename(f.name), "*", "_"))
+	if err != nil {
+		return "", err
+	}
+	_, err = out.Write(f.data)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// Send mails msg to its correspondent, as a reply to their latest message
+// when there is one.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	var th thread
+	c.state.Get(channelNamespace, "thread:"+msg.ChatID, &th)
+	subject := th.Subject
+	switch {
+	case subject == "":
+		subject, _, _ = strings.Cut(strings.TrimSpace(msg.Content), "\n")
+		if r := []rune(subject); len(r) > 60 {
+			subject = string(r[:60]) + "…"
+		}
+	case !strings.HasPrefix(strings.ToLower(subject), "re:"):
+		subject = "Re: " + subject
+	}
+	data, err := c.compose(msg.ChatID, subject, msg.Content, th)
+	if err != nil {
+		return err
+	}
+	return c.sendMail(ctx, msg.ChatID, data)
+}
+
+// compose builds a plain-text message.
+func (c *Channel) compose(to, subject, body string, th thread) ([]byte, error) {
+	domain := c.from.Address[strings.LastIndexByte(c.from.Address, '@')+1:]
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", c.from.String())
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+utils.RandHex(16)+"@"+domain+">")
+	if th.MessageID != "" {
+		header("In-Reply-To", th.MessageID)
+		header("References", strings.TrimSpace(th.References+" "+th.MessageID))
+	}
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sendMail delivers data to the SMTP server: implicit TLS on port 465,
+// otherwise STARTTLS unless plaintext is set.
+func (c *Channel) sendMail(ctx context.Context, to string, data []byte) error {
+	addr := c.cfg.SMTPAddr()
+	host, port, _ := net.SplitHostPort(addr)
+	d := net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	implicitTLS := port == "465" && !c.cfg.Plaintext
+	if implicitTLS {
+		td := tls.Dialer{NetDialer: &d, Config: &tls.Config{ServerName: host}}
+		conn, err = td.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("smtp: connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	cl, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer cl.Close()
+	if !implicitTLS && !c.cfg.Plaintext {
+		if ok, _ := cl.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp: %s does not offer STARTTLS (set plaintext for a local relay)", addr)
+		}
+		if err := cl.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("smtp: %w", err)
+		}
+	}
+	if ok, _ := cl.Extension("AUTH"); ok {
+		if err := cl.Auth(smtp.PlainAuth("", c.cfg.Username, c.password, host)); err != nil {
+			return fmt.Errorf("smtp: %w", err)
+		}
+	}
+	if err := cl.Mail(c.from.Address); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := cl.Rcpt(to); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	w, err := cl.Data()
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return cl.Quit()
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"localagent/pkg/bus"
 	"localagent/pkg/config"
 	"localagent/pkg/constants"
+	"localagent/pkg/errs"
+	"localagent/pkg/hooks"
 	"localagent/pkg/logger"
+	"localagent/pkg/readstate"
 )
 
 type Manager struct {
@@ -16,6 +20,9 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
+	limiter      *outboundLimiter
+	readState    *readstate.Tracker
+	hooks        *hooks.Runner
 	mu           sync.RWMutex
 }
 
@@ -28,6 +35,7 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 		channels: make(map[string]Channel),
 		bus:      messageBus,
 		config:   cfg,
+		limiter:  newOutboundLimiter(cfg.Outbound),
 	}
 
 	m.initChannels()
@@ -54,6 +62,7 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	m.dispatchTask = &asyncTask{cancel: cancel}
 
 	go m.dispatchOutbound(dispatchCtx)
+	go m.sendDigests(dispatchCtx)
 
 	for name, channel := range m.channels {
 		logger.Info("starting channel: %s", name)
@@ -77,6 +86,15 @@ func (m *Manager) StopAll(ctx context.Context) error {
 		m.dispatchTask = nil
 	}
 
+	// Deliver anything still held by the rate limiter before the channels go.
+	for _, msg := range m.limiter.Flush() {
+		if channel, ok := m.channels[msg.Channel]; ok {
+			if err := channel.Send(ctx, msg); err != nil {
+				logger.Error("error sending held messages to channel %s: %v", msg.Channel, err)
+			}
+		}
+	}
+
 	for name, channel := range m.channels {
 		logger.Info("stopping channel: %s", name)
 		if err := channel.Stop(ctx); err != nil {
@@ -102,12 +120,20 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			if m.hooks.Has(hooks.OutboundMessage) {
+				e := m.hooks.Run(ctx, hooks.Event{Event: hooks.OutboundMessage, Channel: msg.Channel, ChatID: msg.ChatID, Content: msg.Content})
+				if e.Drop {
+					continue
+				}
+				msg.Channel, msg.ChatID, msg.Content = e.Channel, e.ChatID, e.Content
+			}
+
 			if constants.IsInternalChannel(msg.Channel) {
 				continue
 			}
 
 			m.mu.RLock()
-			channel, exists := m.channels[msg.Channel]
+			_, exists := m.channels[msg.Channel]
 			m.mu.RUnlock()
 
 			if !exists {
@@ -115,13 +141,84 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
-			if err := channel.Send(ctx, msg); err != nil {
-				logger.Error("error sending message to channel %s: %v", msg.Channel, err)
+			if !m.limiter.Admit(msg) {
+				logger.Warn("outbound rate limit for %s reached, holding message for %s", msg.Channel, msg.ChatID)
+				continue
 			}
+			m.send(ctx, msg)
+		}
+	}
+}
+
+// sendDigests periodically delivers messages held by the rate limiter,
+// coalesced into one message per chat.
+func (m *Manager) sendDigests(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, msg := range m.limiter.Digests() {
+				m.send(ctx, msg)
+			}
+		}
+	}
+}
+
+// sendRetryDelay is how long send waits before retrying a rate-limited or
+// timed-out delivery.
+var sendRetryDelay = 5 * time.Second
+
+func (m *Manager) send(ctx context.Context, msg bus.OutboundMessage) {
+	m.mu.RLock()
+	channel, exists := m.channels[msg.Channel]
+	readState := m.readState
+	m.mu.RUnlock()
+	if !exists {
+		return
+	}
+	// Stamp the delivery before sending so a channel that marks the chat
+	// read during Send (e.g. an open webchat tab) counts it as read.
+	at := time.Now()
+	err := channel.Send(ctx, msg)
+	switch errs.Kind(err) {
+	case errs.ErrRateLimited, errs.ErrTimeout:
+		// Transient: try once more after a short pause.
+		logger.Warn("sending to channel %s failed, retrying: %v", msg.Channel, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(sendRetryDelay):
 		}
+		err = channel.Send(ctx, msg)
+	case errs.ErrAuth:
+		logger.Error("channel %s rejected its credentials; check its token in the config: %v", msg.Channel, err)
+		return
+	}
+	if err != nil {
+		logger.Error("error sending message to channel %s: %v", msg.Channel, err)
+		return
+	}
+	if readState != nil {
+		readState.Delivered(msg.Channel, msg.ChatID, msg.Content, at)
 	}
 }
 
+// SetReadTracker records delivered messages for read-state tracking.
+func (m *Manager) SetReadTracker(t *readstate.Tracker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.readState = t
+}
+
+// SetHooks runs r's on_outbound_message hooks before each delivery.
+// Call it before StartAll.
+func (m *Manager) SetHooks(r *hooks.Runner) {
+	m.hooks = r
+}
+
 func (m *Manager) GetStatus() map[string]any {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
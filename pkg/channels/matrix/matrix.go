@@ -0,0 +1,169 @@
+// Package matrix connects the agent to a Matrix homeserver through the
+// client-server API, as a user logged in with an access token.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/utils"
+)
+
+// MediaDir is where media of Matrix messages are saved, inside the
+// workspace so file tools can read them.
+func MediaDir(workspace string) string {
+	return filepath.Join(workspace, "media", "matrix")
+}
+
+// Channel answers messages in the Matrix rooms it has joined. Each room is
+// its own chat, so sessions are keyed "matrix:<room id>"; senders are
+// Matrix user IDs ("@ann:example.org").
+type Channel struct {
+	*channels.BaseChannel
+	homeserver string
+	token      string
+	rooms      []string
+	mediaDir   string
+	media      *utils.MediaRetention
+	client     *http.Client
+
+	userID string // set by whoami before syncing
+	txn    atomic.Int64
+
+	mu        sync.Mutex
+	encrypted map[string]bool // rooms already warned about
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+}
+
+func NewChannel(cfg config.MatrixConfig, msgBus *bus.MessageBus, mediaDir string) (*Channel, error) {
+	u, err := url.Parse(cfg.Homeserver)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("homeserver %q: want http(s)://host", cfg.Homeserver)
+	}
+	token := cfg.ResolveAccessToken()
+	if token == "" {
+		return nil, fmt.Errorf("access token env %q is not set", cfg.AccessTokenEnv)
+	}
+	return &Channel{
+		BaseChannel: channels.NewBaseChannel("matrix", cfg, msgBus, cfg.AllowFrom),
+		homeserver:  strings.TrimSuffix(cfg.Homeserver, "/"),
+		token:       token,
+		rooms:       cfg.Rooms,
+		mediaDir:    mediaDir,
+		// Long enough for a /sync long poll.
+		client:    httpclient.New("matrix", httpclient.WithTimeout(time.Duration(syncTimeout)*time.Millisecond+30*time.Second)),
+		encrypted: make(map[string]bool),
+	}, nil
+}
+
+// SetMediaRetention prunes saved media under the agent's media retention
+// policy. Must be called before Start.
+func (c *Channel) SetMediaRetention(r *utils.MediaRetention) {
+	c.media = r
+	r.AddDir(c.mediaDir)
+}
+
+func (c *Channel) Start(ctx context.Context) error {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.stopped = make(chan struct{})
+	go c.run(ctx)
+	c.SetRunning(true)
+	return nil
+}
+
+func (c *Channel) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.stopped
+	}
+	c.SetRunning(false)
+	return nil
+}
+
+// Send posts msg as a text message to its room, as a reply when ReplyTo
+// holds an event ID.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	content := map[string]any{"msgtype": "m.text", "body": msg.Content}
+	if msg.ReplyTo != "" {
+		content["m.relates_to"] = map[string]any{"m.in_reply_to": map[string]string{"event_id": msg.ReplyTo}}
+	}
+	txnID := fmt.Sprintf("localagent.%d.%d", time.Now().UnixNano(), c.txn.Add(1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(msg.ChatID) + "/send/m.room.message/" + txnID
+	return c.do(ctx, http.MethodPut, path, content, nil)
+}
+
+// roomAllowed reports whether the channel answers in room.
+func (c *Channel) roomAllowed(room string) bool {
+	return len(c.rooms) == 0 || slices.Contains(c.rooms, room)
+}
+
+// do sends a client-server API request with body as JSON and decodes the
+// response into out when it is not nil.
+func (c *Channel) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.homeserver+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("matrix: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apiError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("matrix: decode response: %w", err)
+	}
+	return nil
+}
+
+// apiError turns a Matrix error response ({"errcode", "error"}) into an
+// error of the status's kind.
+func apiError(status int, body []byte) error {
+	var e struct {
+		Code    string `json:"errcode"`
+		Message string `json:"error"`
+	}
+	err := fmt.Errorf("matrix: status %d", status)
+	if json.Unmarshal(body, &e) == nil && e.Code != "" {
+		err = fmt.Errorf("matrix: %s: %s (status %d)", e.Code, e.Message, status)
+	}
+	return errs.Wrap(errs.FromStatus(status), err)
+}
@@ -0,0 +1,179 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+)
+
+const (
+	initialSync = `{"next_batch":"s1",
+		"rooms":{"join":{"!home:example.org":{"timeline":{"events":[
+			{"type":"m.room.message","sender":"@ann:example.org","event_id":"$old","content":{"msgtype":"m.text","body":"sent while offline"}}]}}},
+		"invite":{"!new:example.org":{"invite_state":{"events":[
+			{"type":"m.room.member","sender":"@ann:example.org","state_key":"@agent:example.org","content":{"membership":"invite"}}]}},
+			"!spam:example.org":{"invite_state":{"events":[
+			{"type":"m.room.member","sender":"@bob:example.org","state_key":"@agent:example.org","content":{"membership":"invite"}}]}}}}}`
+	nextSync = `{"next_batch":"s2","rooms":{"join":{
+		"!home:example.org":{"timeline":{"events":[
+			{"type":"m.room.message","sender":"@agent:example.org","event_id":"$own","content":{"msgtype":"m.text","body":"my own reply"}},
+			{"type":"m.room.message","sender":"@ann:example.org","event_id":"$1","content":{"msgtype":"m.text","body":"> <@bob:example.org> earlier\n\nWhat's on today?","m.relates_to":{"m.in_reply_to":{"event_id":"$0"}}}},
+			{"type":"m.room.message","sender":"@ann:example.org","event_id":"$2","content":{"msgtype":"m.image","body":"is this ripe?","filename":"avocado.jpg","url":"mxc://example.org/abc","info":{"size":4}}},
+			{"type":"m.room.encrypted","sender":"@ann:example.org","event_id":"$3","content":{"algorithm":"m.megolm.v1.aes-sha2"}},
+			{"type":"m.room.message","sender":"@ann:example.org","event_id":"$4","content":{"msgtype":"m.text","body":"* edited","m.relates_to":{"rel_type":"m.replace","event_id":"$1"}}}]}},
+		"!other:example.org":{"timeline":{"events":[
+			{"type":"m.room.message","sender":"@ann:example.org","event_id":"$5","content":{"msgtype":"m.text","body":"not a configured room"}}]}}}}}`
+)
+
+// fakeHomeserver serves whoami, two syncs, joins, media and sends; it
+// reports joined rooms and sent message bodies.
+func fakeHomeserver(t *testing.T) (*httptest.Server, <-chan string, <-chan map[string]any) {
+	t.Helper()
+	joined := make(chan string, 10)
+	sent := make(chan map[string]any, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errcode":"M_UNKNOWN_TOKEN","error":"Invalid access token"}`))
+			return
+		}
+		path := r.URL.EscapedPath()
+		switch {
+		case path == "/_matrix/client/v3/account/whoami":
+			w.Write([]byte(`{"user_id":"@agent:example.org"}`))
+		case path == "/_matrix/client/v3/sync":
+			switch r.URL.Query().Get("since") {
+			case "":
+				w.Write([]byte(initialSync))
+			case "s1":
+				w.Write([]byte(nextSync))
+			default:
+				<-r.Context().Done()
+			}
+		case strings.HasPrefix(path, "/_matrix/client/v3/join/"):
+			joined <- strings.TrimPrefix(r.URL.Path, "/_matrix/client/v3/join/")
+			w.Write([]byte(`{}`))
+		case path == "/_matrix/client/v1/media/download/example.org/abc":
+			// An older homeserver without authenticated media.
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errcode":"M_UNRECOGNIZED","error":"Unrecognized request"}`))
+		case path == "/_matrix/media/v3/download/example.org/abc":
+			w.Write([]byte("JPEG"))
+		case strings.HasPrefix(path, "/_matrix/client/v3/rooms/") && r.Method == http.MethodPut:
+			var body map[string]any
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			body["path"] = path
+			sent <- body
+			w.Write([]byte(`{"event_id":"$sent"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, joined, sent
+}
+
+func newTestChannel(t *testing.T, srv *httptest.Server, cfg config.MatrixConfig) (*Channel, *bus.MessageBus) {
+	t.Helper()
+	t.Setenv("TEST_MATRIX_TOKEN", "secret")
+	cfg.Homeserver = srv.URL + "/"
+	cfg.AccessTokenEnv = "TEST_MATRIX_TOKEN"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewChannel(cfg, msgBus, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+func TestChannelSync(t *testing.T) {
+	srv, joined, _ := fakeHomeserver(t)
+	ch, msgBus := newTestChannel(t, srv, config.MatrixConfig{
+		AllowFrom: []string{"@ann:example.org"},
+		Rooms:     []string{"!home:example.org", "!new:example.org"},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch.Start(ctx)
+	defer ch.Stop(ctx)
+
+	if room := <-joined; room != "!new:example.org" {
+		t.Errorf("joined %s", room)
+	}
+	var got []bus.InboundMessage
+	for range 2 {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatalf("inbound messages so far: %+v", got)
+		}
+		got = append(got, msg)
+	}
+	if got[0].Content != "What's on today?" || got[0].SessionKey != "matrix:!home:example.org" || got[0].SenderID != "@ann:example.org" || got[0].Metadata[bus.MetadataMessageID] != "$1" {
+		t.Errorf("text = %+v", got[0])
+	}
+	if got[1].Content != "is this ripe?" || len(got[1].Media) != 1 || !strings.HasSuffix(got[1].Media[0], "_avocado.jpg") {
+		t.Fatalf("image = %+v", got[1])
+	}
+	if data, _ := os.ReadFile(got[1].Media[0]); string(data) != "JPEG" {
+		t.Errorf("media = %q", data)
+	}
+	select {
+	case msg := <-inbound(ctx, msgBus):
+		t.Errorf("unexpected inbound %+v", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if len(joined) != 0 {
+		t.Errorf("joined %s without being allowed", <-joined)
+	}
+}
+
+func inbound(ctx context.Context, msgBus *bus.MessageBus) <-chan bus.InboundMessage {
+	ch := make(chan bus.InboundMessage, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if msg, ok := msgBus.ConsumeInbound(ctx); ok {
+			ch <- msg
+		}
+	}()
+	return ch
+}
+
+func TestChannelSend(t *testing.T) {
+	srv, _, sent := fakeHomeserver(t)
+	ch, _ := newTestChannel(t, srv, config.MatrixConfig{})
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "!home:example.org", Content: "Dentist at 3", ReplyTo: "$1"}); err != nil {
+		t.Fatal(err)
+	}
+	body := <-sent
+	if body["body"] != "Dentist at 3" || body["msgtype"] != "m.text" || body["m.relates_to"] == nil ||
+		!strings.HasPrefix(body["path"].(string), "/_matrix/client/v3/rooms/%21home:example.org/send/m.room.message/") {
+		t.Errorf("sent %v", body)
+	}
+
+	ch.token = "wrong"
+	err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "!home:example.org", Content: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "M_UNKNOWN_TOKEN") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestNewChannelValidates(t *testing.T) {
+	t.Setenv("TEST_MATRIX_TOKEN", "secret")
+	if _, err := NewChannel(config.MatrixConfig{Homeserver: "matrix.example.org", AccessTokenEnv: "TEST_MATRIX_TOKEN"}, nil, ""); err == nil {
+		t.Error("expected an error for a homeserver without scheme")
+	}
+	if _, err := NewChannel(config.MatrixConfig{Homeserver: "https://matrix.example.org", AccessTokenEnv: "UNSET_MATRIX_TOKEN"}, nil, ""); err == nil {
+		t.Error("expected an error for a missing token")
+	}
+}
@@ -0,0 +1,304 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/logger"
+	"localagent/pkg/utils"
+)
+
+const (
+	// syncTimeout is how long, in milliseconds, a /sync long poll waits
+	// for events.
+	syncTimeout = 30000
+	// maxMediaBytes caps a downloaded attachment; larger ones are skipped.
+	maxMediaBytes = 50 << 20
+	// initialFilter keeps the first sync small: its timeline is skipped, so
+	// messages sent while the agent was offline are not answered.
+	initialFilter = `{"room":{"state":{"lazy_load_members":true},"timeline":{"limit":1}}}`
+)
+
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]struct {
+			InviteState struct {
+				Events []event `json:"events"`
+			} `json:"invite_state"`
+		} `json:"invite"`
+	} `json:"rooms"`
+}
+
+type event struct {
+	Type     string          `json:"type"`
+	Sender   string          `json:"sender"`
+	EventID  string          `json:"event_id"`
+	StateKey *string         `json:"state_key"`
+	Content  json.RawMessage `json:"content"`
+}
+
+// messageContent is the content of an m.room.message event.
+type messageContent struct {
+	MsgType  string `json:"msgtype"`
+	Body     string `json:"body"`
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	Info     struct {
+		Size int64 `json:"size"`
+	} `json:"info"`
+	RelatesTo struct {
+		RelType   string `json:"rel_type"`
+		InReplyTo struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+}
+
+// run syncs until ctx ends.
+func (c *Channel) run(ctx context.Context) {
+	defer close(c.stopped)
+	backoff := time.Second
+	since := ""
+	for ctx.Err() == nil {
+		var err error
+		if c.userID == "" {
+			err = c.whoami(ctx)
+		}
+		if err == nil {
+			since, err = c.sync(ctx, since)
+		}
+		if err == nil {
+			backoff = time.Second
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errs.ErrAuth) {
+			logger.Error("matrix channel: %v; giving up", err)
+			return
+		}
+		logger.Warn("matrix channel: %v (retrying in %v)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func (c *Channel) whoami(ctx context.Context) error {
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &resp); err != nil {
+		return err
+	}
+	c.userID = resp.UserID
+	logger.Info("matrix channel: logged in as %s on %s", c.userID, c.homeserver)
+	return nil
+}
+
+// sync runs one /sync request from since and returns the token for the
+// next one. The first sync only accepts invites.
+func (c *Channel) sync(ctx context.Context, since string) (string, error) {
+	q := url.Values{}
+	if since == "" {
+		q.Set("filter", initialFilter)
+	} else {
+		q.Set("since", since)
+		q.Set("timeout", strconv.Itoa(syncTimeout))
+	}
+	var resp syncResponse
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+q.Encode(), nil, &resp); err != nil {
+		return since, err
+	}
+	for room, invite := range resp.Rooms.Invite {
+		c.invited(ctx, room, invite.InviteState.Events)
+	}
+	if since != "" {
+		for room, joined := range resp.Rooms.Join {
+			for _, ev := range joined.Timeline.Events {
+				c.handle(ctx, room, ev)
+			}
+		}
+	}
+	return resp.NextBatch, nil
+}
+
+// invited joins room when it is in the configured rooms, or, without
+// configured rooms, when the inviter is an allowed sender.
+func (c *Channel) invited(ctx context.Context, room string, state []event) {
+	inviter := ""
+	for _, ev := range state {
+		if ev.Type == "m.room.member" && ev.StateKey != nil && *ev.StateKey == c.userID {
+			inviter = ev.Sender
+		}
+	}
+	if len(c.rooms) > 0 && !c.roomAllowed(room) || len(c.rooms) == 0 && (inviter == "" || !c.IsAllowed(inviter)) {
+		logger.Info("matrix channel: ignoring invite to %s from %s", room, inviter)
+		return
+	}
+	if err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(room), map[string]any{}, nil); err != nil {
+		logger.Warn("matrix channel: joining %s: %v", room, err)
+		return
+	}
+	logger.Info("matrix channel: joined %s (invited by %s)", room, inviter)
+}
+
+func (c *Channel) handle(ctx context.Context, room string, ev event) {
+	if ev.Sender == c.userID || !c.roomAllowed(room) {
+		return
+	}
+	if ev.Type == "m.room.encrypted" {
+		c.mu.Lock()
+		warned := c.encrypted[room]
+		c.encrypted[room] = true
+		c.mu.Unlock()
+		if !warned {
+			logger.Warn("matrix channel: %s is encrypted; point matrix.homeserver at an E2EE proxy such as Pantalaimon to read it", room)
+		}
+		return
+	}
+	if ev.Type != "m.room.message" {
+		return
+	}
+	var m messageContent
+	if err := json.Unmarshal(ev.Content, &m); err != nil || m.RelatesTo.RelType == "m.replace" {
+		return // undecodable, or an edit of an earlier message
+	}
+
+	var text, mediaURL, name string
+	switch m.MsgType {
+	case "m.text", "m.notice", "m.emote":
+		text = m.Body
+		if m.RelatesTo.InReplyTo.EventID != "" {
+			text = stripReplyFallback(text)
+		}
+	case "m.image", "m.file", "m.audio", "m.video":
+		mediaURL, name = m.URL, m.Body
+		// With a filename, the body is a caption.
+		if m.Filename != "" {
+			name = m.Filename
+			if m.Body != m.Filename {
+				text = m.Body
+			}
+		}
+	default:
+		return
+	}
+	text = strings.TrimSpace(text)
+
+	if !c.IsAllowed(ev.Sender) {
+		// HandleMessage rejects the sender too; this only avoids
+		// downloading their media first.
+		c.HandleMessage(ev.Sender, room, text, nil, nil)
+		return
+	}
+	var media []string
+	if mediaURL != "" {
+		if m.Info.Size > maxMediaBytes {
+			logger.Warn("matrix channel: skipping %s: %d bytes is over the %d MB limit", name, m.Info.Size, maxMediaBytes>>20)
+		} else if path, err := c.download(ctx, mediaURL, name); err != nil {
+			logger.Warn("matrix channel: media %s: %v", name, err)
+		} else {
+			media = append(media, path)
+		}
+	}
+	if text == "" && len(media) == 0 {
+		return
+	}
+	c.HandleMessage(ev.Sender, room, text, media, map[string]string{bus.MetadataMessageID: ev.EventID})
+}
+
+// stripReplyFallback removes the quote of the replied-to message that
+// clients put before the text of a reply.
+func stripReplyFallback(body string) string {
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	if i == 0 {
+		return body
+	}
+	return strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+}
+
+// download saves the media at an mxc:// URI to the media directory and
+// returns its path.
+func (c *Channel) download(ctx context.Context, mxc, name string) (string, error) {
+	server, mediaID, ok := strings.Cut(strings.TrimPrefix(mxc, "mxc://"), "/")
+	if !strings.HasPrefix(mxc, "mxc://") || !ok || server == "" || mediaID == "" {
+		return "", fmt.Errorf("bad media URI %q", mxc)
+	}
+	ref := url.PathEscape(server) + "/" + url.PathEscape(mediaID)
+	resp, err := c.fetch(ctx, "/_matrix/client/v1/media/download/"+ref)
+	if err == nil && resp.StatusCode == http.StatusNotFound {
+		// Homeservers before authenticated media (Matrix 1.11).
+		resp.Body.Close()
+		resp, err = c.fetch(ctx, "/_matrix/media/v3/download/"+ref)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := httpclient.ReadBody(resp)
+		return "", apiError(resp.StatusCode, body)
+	}
+
+	if err := os.MkdirAll(c.mediaDir, 0700); err != nil {
+		return "", err
+	}
+	if c.media != nil {
+		go c.media.Prune()
+	}
+	if name == "" {
+		name = mediaID
+	}
+	f, err := os.CreateTemp(c.mediaDir, "*_"+strings.ReplaceAll(utils.SanitizeFilename(name), "*", "_"))
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxMediaBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxMediaBytes {
+		err = fmt.Errorf("over the %d MB limit", maxMediaBytes>>20)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (c *Channel) fetch(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.homeserver+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return c.client.Do(req)
+}
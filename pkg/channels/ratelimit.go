@@ -0,0 +1,135 @@
+package channels
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+)
+
+// digestSeparator goes between messages coalesced into one digest.
+const digestSeparator = "\n\n---\n\n"
+
+// outboundLimiter is a per-channel token bucket for outbound messages.
+// Messages over the limit are not dropped: they are held per chat and sent
+// as a single digest once the channel has a token again.
+type outboundLimiter struct {
+	mu      sync.Mutex
+	cfg     config.OutboundConfig
+	buckets map[string]*bucket
+	held    map[chatKey][]bus.OutboundMessage
+	order   []chatKey // chats with held messages, oldest first
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type chatKey struct {
+	channel, chatID string
+}
+
+func newOutboundLimiter(cfg config.OutboundConfig) *outboundLimiter {
+	return &outboundLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*bucket),
+		held:    make(map[chatKey][]bus.OutboundMessage),
+		now:     time.Now,
+	}
+}
+
+// Admit reports whether msg may be sent now. If not, it is held for the
+// next digest. A chat that already has held messages queues behind them so
+// ordering is kept.
+func (l *outboundLimiter) Admit(msg bus.OutboundMessage) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := chatKey{msg.Channel, msg.ChatID}
+	if _, waiting := l.held[key]; waiting {
+		l.held[key] = append(l.held[key], msg)
+		return false
+	}
+	if l.take(msg.Channel) {
+		return true
+	}
+	l.order = append(l.order, key)
+	l.held[key] = []bus.OutboundMessage{msg}
+	return false
+}
+
+// Digests returns one coalesced message for each chat with held messages
+// whose channel has a token available, consuming a token for each.
+func (l *outboundLimiter) Digests() []bus.OutboundMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []bus.OutboundMessage
+	keep := l.order[:0]
+	for _, key := range l.order {
+		if !l.take(key.channel) {
+			keep = append(keep, key)
+			continue
+		}
+		out = append(out, digest(l.held[key]))
+		delete(l.held, key)
+	}
+	l.order = keep
+	return out
+}
+
+// Flush returns a digest for every chat with held messages, ignoring the
+// limit. Used on shutdown so nothing is lost.
+func (l *outboundLimiter) Flush() []bus.OutboundMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []bus.OutboundMessage
+	for _, key := range l.order {
+		out = append(out, digest(l.held[key]))
+	}
+	l.held = make(map[chatKey][]bus.OutboundMessage)
+	l.order = nil
+	return out
+}
+
+// take consumes a token for channel if one is available.
+// Must be called with l.mu held.
+func (l *outboundLimiter) take(channel string) bool {
+	limit := l.cfg.Limit(channel)
+	if limit.PerMinute < 0 {
+		return true
+	}
+	now := l.now()
+	b, ok := l.buckets[channel]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[channel] = b
+	}
+	rate := float64(limit.PerMinute) / 60
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// digest combines held messages for one chat. A single message is sent
+// unchanged.
+func digest(msgs []bus.OutboundMessage) bus.OutboundMessage {
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	parts := make([]string, len(msgs))
+	for i, m := range msgs {
+		parts[i] = m.Content
+	}
+	out := msgs[len(msgs)-1]
+	out.Content = fmt.Sprintf("(%d messages combined to avoid flooding this chat)\n\n", len(msgs)) +
+		strings.Join(parts, digestSeparator)
+	return out
+}
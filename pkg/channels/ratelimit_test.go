@@ -0,0 +1,53 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+)
+
+func TestOutboundLimiterCoalesces(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	l := newOutboundLimiter(config.OutboundConfig{
+		Default:  config.RateLimitConfig{PerMinute: 6, Burst: 2},
+		Channels: map[string]config.RateLimitConfig{"web": {PerMinute: -1}},
+	})
+	l.now = func() time.Time { return now }
+
+	msg := func(ch, content string) bus.OutboundMessage {
+		return bus.OutboundMessage{Channel: ch, ChatID: "1", Content: content}
+	}
+
+	if !l.Admit(msg("telegram", "a")) || !l.Admit(msg("telegram", "b")) {
+		t.Fatal("burst should be admitted")
+	}
+	for _, c := range []string{"c", "d", "e"} {
+		if l.Admit(msg("telegram", c)) {
+			t.Fatalf("%s admitted over the limit", c)
+		}
+	}
+	for range 10 {
+		if !l.Admit(msg("web", "x")) {
+			t.Fatal("unlimited channel was limited")
+		}
+	}
+
+	if got := l.Digests(); len(got) != 0 {
+		t.Fatalf("digest sent without a token: %+v", got)
+	}
+
+	now = now.Add(10 * time.Second) // one token at 6/min
+	got := l.Digests()
+	if len(got) != 1 {
+		t.Fatalf("expected one digest, got %d", len(got))
+	}
+	if !strings.HasPrefix(got[0].Content, "(3 messages combined") || !strings.Contains(got[0].Content, "c"+digestSeparator+"d"+digestSeparator+"e") {
+		t.Errorf("unexpected digest: %q", got[0].Content)
+	}
+	if got := l.Flush(); len(got) != 0 {
+		t.Errorf("nothing should be left, got %d", len(got))
+	}
+}
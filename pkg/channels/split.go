@@ -0,0 +1,25 @@
+package channels
+
+import "strings"
+
+// SplitMessage splits s into parts of at most limit characters, breaking
+// at the last newline (or else space) of each part when there is one.
+func SplitMessage(s string, limit int) []string {
+	var parts []string
+	runes := []rune(s)
+	for len(runes) > limit {
+		cut := limit
+		chunk := string(runes[:limit])
+		if i := strings.LastIndex(chunk, "\n"); i > 0 {
+			cut = len([]rune(chunk[:i]))
+		} else if i := strings.LastIndex(chunk, " "); i > 0 {
+			cut = len([]rune(chunk[:i]))
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), "\n "))
+	}
+	if len(runes) > 0 || len(parts) == 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
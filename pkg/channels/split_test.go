@@ -0,0 +1,13 @@
+package channels
+
+import "testing"
+
+func TestSplitMessage(t *testing.T) {
+	if got := SplitMessage("short", 10); len(got) != 1 || got[0] != "short" {
+		t.Errorf("split = %q", got)
+	}
+	got := SplitMessage("line one\nline two", 12)
+	if len(got) != 2 || got[0] != "line one" || got[1] != "line two" {
+		t.Errorf("split = %q", got)
+	}
+}
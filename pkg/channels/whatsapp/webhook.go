@@ -0,0 +1,247 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/logger"
+)
+
+// maxWebhookBytes bounds a webhook request body.
+const maxWebhookBytes = 1 << 20
+
+// payload is the part of a webhook notification the channel uses.
+type payload struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Changes []struct {
+			Field string `json:"field"`
+			Value value  `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+type value struct {
+	Metadata struct {
+		PhoneNumberID string `json:"phone_number_id"`
+	} `json:"metadata"`
+	Contacts []struct {
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+		WaID string `json:"wa_id"`
+	} `json:"contacts"`
+	Messages []message `json:"messages"`
+	Statuses []struct {
+		ID          string `json:"id"`
+		Status      string `json:"status"`
+		RecipientID string `json:"recipient_id"`
+		Errors      []struct {
+			Code  int    `json:"code"`
+			Title string `json:"title"`
+		} `json:"errors"`
+	} `json:"statuses"`
+}
+
+type message struct {
+	From     string                 `json:"from"`
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Text     *struct{ Body string } `json:"text"`
+	Image    *mediaRef              `json:"image"`
+	Audio    *mediaRef              `json:"audio"`
+	Video    *mediaRef              `json:"video"`
+	Document *mediaRef              `json:"document"`
+	Sticker  *mediaRef              `json:"sticker"`
+	Location *struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Name      string  `json:"name"`
+		Address   string  `json:"address"`
+	} `json:"location"`
+	Button *struct {
+		Text string `json:"text"`
+	} `json:"button"`
+	Interactive *struct {
+		ButtonReply *struct {
+			Title string `json:"title"`
+		} `json:"button_reply"`
+		ListReply *struct {
+			Title string `json:"title"`
+		} `json:"list_reply"`
+	} `json:"interactive"`
+}
+
+type mediaRef struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption"`
+	Filename string `json:"filename"`
+}
+
+// ServeHTTP answers Meta's webhook verification (GET) and notifications
+// (POST), which must be signed with the app secret. Notifications are
+// acknowledged at once and handled in the background; Meta retries
+// unacknowledged ones, and repeats are dropped by message ID.
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != WebhookPath {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if q.Get("hub.mode") != "subscribe" || subtle.ConstantTimeCompare([]byte(q.Get("hub.verify_token")), []byte(c.verifyToken)) != 1 {
+			http.Error(w, "verification failed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, q.Get("hub.challenge"))
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if !c.validSignature(body, r.Header.Get("X-Hub-Signature-256")) {
+			logger.Warn("whatsapp channel: webhook call with a bad signature from %s", r.RemoteAddr)
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var p payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		ctx := c.ctx
+		if ctx != nil {
+			c.wg.Add(1)
+		}
+		c.mu.Unlock()
+		if ctx == nil {
+			http.Error(w, "channel not running", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		go func() {
+			defer c.wg.Done()
+			c.handle(ctx, p)
+		}()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// validSignature checks the "sha256=<hex>" HMAC of body with the app
+// secret.
+func (c *Channel) validSignature(body []byte, header string) bool {
+	sig, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil || !strings.HasPrefix(header, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(c.appSecret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func (c *Channel) handle(ctx context.Context, p payload) {
+	if p.Object != "whatsapp_business_account" {
+		return
+	}
+	for _, entry := range p.Entry {
+		for _, change := range entry.Changes {
+			v := change.Value
+			// One app can serve several numbers.
+			if change.Field != "messages" || v.Metadata.PhoneNumberID != c.phoneID {
+				continue
+			}
+			for _, s := range v.Statuses {
+				for _, e := range s.Errors {
+					logger.Warn("whatsapp channel: message %s to %s %s: %s (code %d)", s.ID, s.RecipientID, s.Status, e.Title, e.Code)
+				}
+			}
+			names := make(map[string]string)
+			for _, contact := range v.Contacts {
+				names[contact.WaID] = contact.Profile.Name
+			}
+			for _, m := range v.Messages {
+				c.receive(ctx, m, names[m.From])
+			}
+		}
+	}
+}
+
+func (c *Channel) receive(ctx context.Context, m message, name string) {
+	var content, kind string
+	var ref *mediaRef
+	switch {
+	case m.Text != nil:
+		content = m.Text.Body
+	case m.Button != nil:
+		content = m.Button.Text
+	case m.Interactive != nil && m.Interactive.ButtonReply != nil:
+		content = m.Interactive.ButtonReply.Title
+	case m.Interactive != nil && m.Interactive.ListReply != nil:
+		content = m.Interactive.ListReply.Title
+	case m.Location != nil:
+		l := m.Location
+		content = fmt.Sprintf("Location: %.5f, %.5f", l.Latitude, l.Longitude)
+		if place := strings.Trim(l.Name+", "+l.Address, ", "); place != "" {
+			content += " (" + place + ")"
+		}
+	case m.Image != nil:
+		kind, ref = "image", m.Image
+	case m.Audio != nil:
+		kind, ref = "audio", m.Audio
+	case m.Video != nil:
+		kind, ref = "video", m.Video
+	case m.Document != nil:
+		kind, ref = "document", m.Document
+	case m.Sticker != nil:
+		kind, ref = "sticker", m.Sticker
+	default:
+		logger.Debug("whatsapp channel: ignoring %s message from %s", m.Type, m.From)
+		return
+	}
+	if ref != nil {
+		content = ref.Caption
+	}
+	content = strings.TrimSpace(content)
+	c.touch(m.From)
+
+	// The profile name is whatever the sender typed in, so it never takes
+	// part in allowlist or role matching.
+	if !c.IsAllowed(m.From) {
+		// HandleMessage rejects the sender too; this only avoids
+		// downloading their media first.
+		c.HandleMessage(m.From, m.From, content, nil, nil)
+		return
+	}
+	var media []string
+	if ref != nil {
+		path, err := c.download(ctx, kind, *ref)
+		if err != nil {
+			logger.Warn("whatsapp channel: %s %s: %v", kind, ref.ID, err)
+		} else {
+			media = append(media, path)
+		}
+	}
+	if content == "" && len(media) == 0 {
+		return
+	}
+	metadata := map[string]string{bus.MetadataMessageID: m.ID}
+	if name != "" {
+		metadata[MetadataProfileName] = name
+	}
+	c.HandleMessage(m.From, m.From, content, media, metadata)
+}
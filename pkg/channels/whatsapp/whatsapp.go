@@ -0,0 +1,365 @@
+// Package whatsapp connects the agent to a WhatsApp Business number through
+// the Meta Cloud API: Meta posts inbound messages to a webhook on the
+// gateway, media is fetched and replies are sent through the Graph API.
+package whatsapp
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/utils"
+)
+
+const (
+	defaultAPIBase = "https://graph.facebook.com/v21.0"
+
+	// WebhookPath is where the gateway receives Meta's webhook calls.
+	WebhookPath = "/whatsapp/webhook"
+	// MetadataProfileName carries the sender's WhatsApp profile name. It is
+	// chosen by the sender and is never used to identify them.
+	MetadataProfileName = "profile_name"
+
+	// maxMessageLen is WhatsApp's limit on a text message, in characters.
+	maxMessageLen = 4096
+	// maxMediaBytes caps a downloaded attachment (WhatsApp's document limit).
+	maxMediaBytes = 100 << 20
+	// maxTemplateParam bounds the text put in a template's body parameter.
+	maxTemplateParam = 1024
+
+	// window is how long after a user's last message free-form replies
+	// are allowed; later ones need an approved template.
+	window = 24 * time.Hour
+	// codeReengagement is the Graph error for a free-form message sent
+	// outside the window.
+	codeReengagement = 131047
+
+	stateNamespace = "whatsapp"
+)
+
+// MediaDir is where media of WhatsApp messages is saved.
+func MediaDir(workspace string) string {
+	return filepath.Join(workspace, "media", "whatsapp")
+}
+
+// Channel answers WhatsApp messages sent to the business number. Each
+// user is their own chat, so sessions are keyed "whatsapp:<number>";
+// senders are "<number>|<profile name>".
+type Channel struct {
+	*channels.BaseChannel
+	phoneID      string
+	token        string
+	appSecret    string
+	verifyToken  string
+	template     string
+	templateLang string
+	mediaDir     string
+	media        *utils.MediaRetention
+	state        *state.Manager
+	client       *http.Client
+
+	// apiBase is overridden in tests.
+	apiBase string
+
+	mu     sync.Mutex
+	ctx    context.Context // set while running; webhook deliveries are handled under it
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewChannel(cfg config.WhatsAppConfig, msgBus *bus.MessageBus, workspace string) (*Channel, error) {
+	if cfg.PhoneNumberID == "" {
+		return nil, errors.New("phone_number_id is required")
+	}
+	token, appSecret, verifyToken := cfg.ResolveToken(), cfg.ResolveAppSecret(), cfg.ResolveVerifyToken()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("access token env %q is not set", cfg.TokenEnv)
+	case appSecret == "":
+		return nil, fmt.Errorf("app secret env %q is not set", cfg.AppSecretEnv)
+	case verifyToken == "":
+		return nil, fmt.Errorf("verify token env %q is not set", cfg.VerifyTokenEnv)
+	}
+	var allow []string
+	for _, number := range cfg.AllowFrom {
+		if n := NormalizeNumber(number); n != "" {
+			allow = append(allow, n)
+		}
+	}
+	if len(allow) == 0 {
+		return nil, errors.New("allow_from is required: anyone can message a business number")
+	}
+	lang := cfg.TemplateLang
+	if lang == "" {
+		lang = "en_US"
+	}
+	return &Channel{
+		BaseChannel:  channels.NewBaseChannel("whatsapp", cfg, msgBus, allow),
+		phoneID:      cfg.PhoneNumberID,
+		token:        token,
+		appSecret:    appSecret,
+		verifyToken:  verifyToken,
+		template:     cfg.Template,
+		templateLang: lang,
+		mediaDir:     MediaDir(workspace),
+		state:        state.NewManager(workspace),
+		client:       httpclient.New("whatsapp", httpclient.WithTimeout(60*time.Second)),
+		apiBase:      defaultAPIBase,
+	}, nil
+}
+
+// NormalizeNumber reduces a phone number to the digits WhatsApp uses as
+// its ID, e.g. "+49 176 1234-5678" to "4917612345678".
+func NormalizeNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+// SetMediaRetention prunes saved media under the agent's media retention
+// policy. Must be called before Start.
+func (c *Channel) SetMediaRetention(r *utils.MediaRetention) {
+	c.media = r
+	r.AddDir(c.mediaDir)
+}
+
+func (c *Channel) Start(ctx context.Context) error {
+	c.mu.Lock()
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.mu.Unlock()
+	c.SetRunning(true)
+	return nil
+}
+
+func (c *Channel) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.ctx, c.cancel = nil, nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	c.wg.Wait()
+	c.SetRunning(false)
+	return nil
+}
+
+// Send delivers msg as text, split at WhatsApp's length limit. When the
+// 24-hour window since the user's last message has closed, the configured
+// template carries the message instead.
+func (c *Channel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if c.template != "" && !c.inWindow(msg.ChatID) {
+		return c.sendTemplate(ctx, msg.ChatID, msg.Content)
+	}
+	for i, part := range channels.SplitMessage(msg.Content, maxMessageLen) {
+		body := map[string]any{
+			"messaging_product": "whatsapp",
+			"recipient_type":    "individual",
+			"to":                msg.ChatID,
+			"type":              "text",
+			"text":              map[string]any{"body": part, "preview_url": false},
+		}
+		if msg.ReplyTo != "" {
+			body["context"] = map[string]any{"message_id": msg.ReplyTo}
+			msg.ReplyTo = ""
+		}
+		err := c.do(ctx, http.MethodPost, "/"+c.phoneID+"/messages", body, nil)
+		var gerr *graphError
+		if i == 0 && c.template != "" && errors.As(err, &gerr) && gerr.Code == codeReengagement {
+			return c.sendTemplate(ctx, msg.ChatID, msg.Content)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendTemplate sends content as the body parameter of the configured
+// template. Parameters can't hold newlines, so whitespace is collapsed.
+func (c *Channel) sendTemplate(ctx context.Context, to, content string) error {
+	param := utils.Truncate(strings.Join(strings.Fields(content), " "), maxTemplateParam)
+	return c.do(ctx, http.MethodPost, "/"+c.phoneID+"/messages", map[string]any{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "template",
+		"template": map[string]any{
+			"name":     c.template,
+			"language": map[string]any{"code": c.templateLang},
+			"components": []any{map[string]any{
+				"type":       "body",
+				"parameters": []any{map[string]any{"type": "text", "text": param}},
+			}},
+		},
+	}, nil)
+}
+
+// touch records a message from number, opening its reply window.
+func (c *Channel) touch(number string) {
+	if err := c.state.Set(stateNamespace, "last:"+number, time.Now()); err != nil {
+		logger.Warn("whatsapp channel: %v", err)
+	}
+}
+
+func (c *Channel) inWindow(number string) bool {
+	var last time.Time
+	ok, _ := c.state.Get(stateNamespace, "last:"+number, &last)
+	return ok && time.Since(last) < window
+}
+
+// graphError is an error response of the Graph API.
+type graphError struct {
+	Status  int
+	Code    int
+	Message string
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("whatsapp: %s (code %d, status %d)", e.Message, e.Code, e.Status)
+}
+
+func (c *Channel) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.apiBase+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("whatsapp: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("whatsapp: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+				Code    int    `json:"code"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error.Message == "" {
+			e.Error.Message = path + " failed"
+		}
+		return errs.Wrap(errs.FromStatus(resp.StatusCode), &graphError{Status: resp.StatusCode, Code: e.Error.Code, Message: e.Error.Message})
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// mediaExts names saved media by type; WhatsApp only sends filenames for
+// documents.
+var mediaExts = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/aac":       ".aac",
+	"audio/amr":       ".amr",
+	"video/mp4":       ".mp4",
+	"video/3gpp":      ".3gp",
+	"application/pdf": ".pdf",
+}
+
+// download fetches a media object's URL from the Graph API, saves the
+// file to the media directory and returns its path.
+func (c *Channel) download(ctx context.Context, kind string, ref mediaRef) (string, error) {
+	var info struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+		FileSize int64  `json:"file_size"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/"+ref.ID, nil, &info); err != nil {
+		return "", err
+	}
+	if info.FileSize > maxMediaBytes {
+		return "", fmt.Errorf("%d bytes is over the %d MB limit", info.FileSize, maxMediaBytes>>20)
+	}
+	if err := os.MkdirAll(c.mediaDir, 0700); err != nil {
+		return "", err
+	}
+	if c.media != nil {
+		go c.media.Prune()
+	}
+
+	name := ref.Filename
+	if name == "" {
+		mediaType, _, _ := mime.ParseMediaType(cmp.Or(info.MimeType, ref.MimeType))
+		ext, ok := mediaExts[mediaType]
+		if !ok {
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+				ext = exts[0]
+			}
+		}
+		name = kind + ext
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+	f, err := os.CreateTemp(c.mediaDir, "*_"+strings.ReplaceAll(utils.SanitizeFilename(name), "*", "_"))
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxMediaBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxMediaBytes {
+		err = fmt.Errorf("over the %d MB limit", maxMediaBytes>>20)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,224 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+)
+
+// fakeGraph serves the Graph API calls the channel makes and records the
+// messages sent. While reengage is set, free-form messages fail with 131047.
+type fakeGraph struct {
+	mu       sync.Mutex
+	sent     []map[string]any
+	reengage bool
+}
+
+func (g *fakeGraph) server(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("%s without the access token", r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/media-1":
+			json.NewEncoder(w).Encode(map[string]any{"url": srv.URL + "/files/media-1", "mime_type": "image/jpeg", "file_size": 4})
+		case r.Method == http.MethodGet && r.URL.Path == "/files/media-1":
+			w.Write([]byte("jpeg"))
+		case r.Method == http.MethodPost && r.URL.Path == "/555/messages":
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.sent = append(g.sent, body)
+			if g.reengage && body["type"] == "text" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":{"message":"Re-engagement message","code":131047}}`)
+				return
+			}
+			fmt.Fprint(w, `{"messages":[{"id":"wamid.out"}]}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (g *fakeGraph) last() map[string]any {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.sent) == 0 {
+		return nil
+	}
+	return g.sent[len(g.sent)-1]
+}
+
+func newTestChannel(t *testing.T, msgBus *bus.MessageBus, template string) (*Channel, *fakeGraph, string) {
+	t.Helper()
+	t.Setenv("TEST_WA_TOKEN", "tok")
+	t.Setenv("TEST_WA_SECRET", "secret")
+	t.Setenv("TEST_WA_VERIFY", "verify-me")
+	ws := t.TempDir()
+	ch, err := NewChannel(config.WhatsAppConfig{
+		PhoneNumberID: "555", TokenEnv: "TEST_WA_TOKEN", AppSecretEnv: "TEST_WA_SECRET",
+		VerifyTokenEnv: "TEST_WA_VERIFY", AllowFrom: []string{"+49 176 1234-5678"}, Template: template,
+	}, msgBus, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &fakeGraph{}
+	ch.apiBase = g.server(t).URL
+	return ch, g, ws
+}
+
+func post(t *testing.T, ch *Channel, body, secret string) int {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func notification(messages string) string {
+	return `{"object":"whatsapp_business_account","entry":[{"changes":[{"field":"messages","value":{` +
+		`"metadata":{"phone_number_id":"555"},"contacts":[{"profile":{"name":"Alice"},"wa_id":"4917612345678"}],` +
+		`"messages":[` + messages + `]}}]}]}`
+}
+
+func TestWebhookVerifyAndReceive(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, _, ws := newTestChannel(t, msgBus, "")
+
+	rec := httptest.NewRecorder()
+	ch.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, WebhookPath+"?hub.mode=subscribe&hub.verify_token=verify-me&hub.challenge=1158201444", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "1158201444" {
+		t.Errorf("verify = %d %q", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	ch.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, WebhookPath+"?hub.mode=subscribe&hub.verify_token=wrong&hub.challenge=1", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("verify with a wrong token = %d", rec.Code)
+	}
+
+	text := notification(`{"from":"4917612345678","id":"wamid.1","type":"text","text":{"body":"Hello there"}}`)
+	if code := post(t, ch, text, "secret"); code != http.StatusServiceUnavailable {
+		t.Errorf("post before start = %d", code)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch.Start(ctx)
+	defer ch.Stop(ctx)
+	if code := post(t, ch, text, "other"); code != http.StatusUnauthorized {
+		t.Errorf("post with a bad signature = %d", code)
+	}
+	if code := post(t, ch, text, "secret"); code != http.StatusOK {
+		t.Fatalf("post = %d", code)
+	}
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.SessionKey != "whatsapp:4917612345678" || msg.SenderID != "4917612345678" || msg.Content != "Hello there" ||
+		msg.Metadata[MetadataProfileName] != "Alice" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	// Meta retries deliveries; the repeat is dropped.
+	post(t, ch, text, "secret")
+	post(t, ch, notification(`{"from":"4917612345678","id":"wamid.2","type":"image","image":{"id":"media-1","mime_type":"image/jpeg","caption":"Receipt"}}`), "secret")
+	msg, ok = msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound image")
+	}
+	if msg.Content != "Receipt" || len(msg.Media) != 1 || !strings.HasPrefix(msg.Media[0], MediaDir(ws)) || !strings.HasSuffix(msg.Media[0], "_image.jpg") {
+		t.Fatalf("image message = %+v", msg)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "jpeg" {
+		t.Errorf("saved media = %q", data)
+	}
+}
+
+func TestSpoofedProfileNameRejected(t *testing.T) {
+	msgBus := bus.NewMessageBus()
+	ch, _, _ := newTestChannel(t, msgBus, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch.Start(ctx)
+	defer ch.Stop(ctx)
+
+	// A stranger names their profile after the allowed number. Messages in
+	// one call are handled in order, so the owner's message that follows is
+	// the first inbound one unless the stranger got through.
+	body := `{"object":"whatsapp_business_account","entry":[{"changes":[{"field":"messages","value":{` +
+		`"metadata":{"phone_number_id":"555"},"contacts":[{"profile":{"name":"4917612345678"},"wa_id":"15550001111"},` +
+		`{"profile":{"name":"Alice"},"wa_id":"4917612345678"}],"messages":[` +
+		`{"from":"15550001111","id":"wamid.9","type":"text","text":{"body":"let me in"}},` +
+		`{"from":"4917612345678","id":"wamid.10","type":"text","text":{"body":"it's me"}}]}}]}]}`
+	if code := post(t, ch, body, "secret"); code != http.StatusOK {
+		t.Fatalf("post = %d", code)
+	}
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	if msg.SenderID != "4917612345678" || msg.Content != "it's me" {
+		t.Errorf("spoofed sender got through: %+v", msg)
+	}
+}
+
+func TestSendTextAndTemplate(t *testing.T) {
+	ch, g, _ := newTestChannel(t, bus.NewMessageBus(), "agent_update")
+	ctx := context.Background()
+
+	// Nobody has written yet, so the window is closed.
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "4917612345678", Content: "Your\ntrain leaves at 8."}); err != nil {
+		t.Fatal(err)
+	}
+	sent := g.last()
+	tmpl, _ := sent["template"].(map[string]any)
+	if sent["type"] != "template" || tmpl["name"] != "agent_update" {
+		t.Fatalf("sent %v, want the template", sent)
+	}
+	if data, _ := json.Marshal(tmpl["components"]); !strings.Contains(string(data), `"text":"Your train leaves at 8."`) {
+		t.Errorf("template components = %s", data)
+	}
+
+	ch.touch("4917612345678")
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "4917612345678", Content: "Done.", ReplyTo: "wamid.1"}); err != nil {
+		t.Fatal(err)
+	}
+	sent = g.last()
+	text, _ := sent["text"].(map[string]any)
+	reply, _ := sent["context"].(map[string]any)
+	if sent["type"] != "text" || text["body"] != "Done." || reply["message_id"] != "wamid.1" {
+		t.Errorf("sent %v, want a text reply", sent)
+	}
+
+	// Meta's view of the window wins over ours.
+	g.reengage = true
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "4917612345678", Content: "Later."}); err != nil {
+		t.Fatal(err)
+	}
+	if sent := g.last(); sent["type"] != "template" {
+		t.Errorf("sent %v after 131047, want the template", sent)
+	}
+}
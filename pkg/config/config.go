@@ -3,30 +3,170 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 )
 
 type WebChatConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	MaxUploadMB int    `json:"max_upload_mb,omitempty"` // default 10
 }
 
 type Config struct {
-	Agents         AgentsConfig    `json:"agents"`
-	Provider       ProviderConfig  `json:"provider"`
-	Gateway        GatewayConfig   `json:"gateway"`
-	Tools          ToolsConfig     `json:"tools"`
-	Heartbeat      HeartbeatConfig `json:"heartbeat"`
-	WebChat        WebChatConfig   `json:"webchat"`
-	AllowedDomains []string        `json:"allowed_domains"`
+	Agents         AgentsConfig      `json:"agents"`
+	Media          MediaConfig       `json:"media"`
+	Provider       ProviderConfig    `json:"provider"`
+	Gateway        GatewayConfig     `json:"gateway"`
+	Tools          ToolsConfig       `json:"tools"`
+	Heartbeat      HeartbeatConfig   `json:"heartbeat"`
+	WebChat        WebChatConfig     `json:"webchat"`
+	Audit          AuditConfig       `json:"audit"`
+	Roles          RolesConfig       `json:"roles"`
+	Redaction      RedactionConfig   `json:"redaction"`
+	Encryption     EncryptionConfig  `json:"encryption"`
+	Telemetry      TelemetryConfig   `json:"telemetry"`
+	Storage        StorageConfig     `json:"storage"`
+	Outbound       OutboundConfig    `json:"outbound"`
+	Federation     FederationConfig  `json:"federation"`
+	Journal        JournalConfig     `json:"journal"`
+	Flashcards     FlashcardsConfig  `json:"flashcards"`
+	Medications    MedicationsConfig `json:"medications"`
+	Sleep          SleepConfig       `json:"sleep"`
+	Travel         TravelConfig      `json:"travel"`
+	Hooks          []HookConfig      `json:"hooks,omitempty"`
+	Watchers       []WatcherConfig   `json:"watchers,omitempty"`
+	Places         []PlaceConfig     `json:"places,omitempty"`
+	Bridge         BridgeConfig      `json:"bridge"`
+	MQTT           MQTTConfig        `json:"mqtt"`
+	Discord        DiscordConfig     `json:"discord"`
+	Matrix         MatrixConfig      `json:"matrix"`
+	EmailChannel   MailChannelConfig `json:"email_channel"`
+	WhatsApp       WhatsAppConfig    `json:"whatsapp"`
+	AllowedDomains []string          `json:"allowed_domains"`
+	// AllowPrivateHosts lists hostnames that may resolve to private
+	// addresses through the proxy. Configured service URLs are always allowed.
+	AllowPrivateHosts []string `json:"allow_private_hosts,omitempty"`
+	// ProxyRateLimit is the per-host request limit per minute
+	// (0 = default, negative = unlimited).
+	ProxyRateLimit int `json:"proxy_rate_limit,omitempty"`
 	mu             sync.RWMutex
+	dataDir        string // directory the config was loaded from
 }
 
 type AgentsConfig struct {
-	Defaults AgentDefaults `json:"defaults"`
+	Defaults   AgentDefaults    `json:"defaults"`
+	Prompt     PromptConfig     `json:"prompt"`
+	Autonomy   AutonomyConfig   `json:"autonomy"`
+	IdleWork   IdleWorkConfig   `json:"idle_work"`
+	Watchdog   WatchdogConfig   `json:"watchdog"`
+	Onboarding OnboardingConfig `json:"onboarding"`
+	// Routes picks the model per channel name ("web", "discord", "cli",
+	// ...) or for "heartbeat", "cron" and "summary" (session summaries)
+	// runs. Unrouted turns use defaults.model on the main provider.
+	Routes map[string]ModelRoute `json:"routes,omitempty"`
+}
+
+// ModelRoute sends a channel's turns to another model, optionally on its
+// own OpenAI-compatible endpoint, e.g. a small local model for heartbeats.
+type ModelRoute struct {
+	Model     string `json:"model,omitempty"`       // empty = defaults.model
+	APIBase   string `json:"api_base,omitempty"`    // empty = the main provider
+	APIKeyEnv string `json:"api_key_env,omitempty"` // only with api_base
+	Trusted   bool   `json:"trusted,omitempty"`     // local model: prompts are never redacted, as provider.trusted
+}
+
+func (r ModelRoute) ResolveAPIKey() string {
+	if r.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(r.APIKeyEnv)
+}
+
+// OnboardingConfig controls the welcome sent to a sender the first time
+// they message the agent on a channel.
+type OnboardingConfig struct {
+	Disabled bool     `json:"disabled"`
+	Channels []string `json:"channels,omitempty"` // channels that welcome new senders; empty = all but web
+}
+
+// Welcomes reports whether new senders on channel get a welcome.
+func (c OnboardingConfig) Welcomes(channel string) bool {
+	if c.Disabled {
+		return false
+	}
+	if len(c.Channels) == 0 {
+		return channel != "web"
+	}
+	return slices.Contains(c.Channels, channel)
+}
+
+// WatchdogConfig flags a message whose current step (one LLM call or tool
+// call) runs for too long. Negative seconds disable that stage.
+type WatchdogConfig struct {
+	WarnSecs int  `json:"warn_secs"` // emit a warning activity, repeated at each multiple; 0 = default (60)
+	DumpSecs int  `json:"dump_secs"` // write a goroutine dump to workspace/debug; 0 = default (180)
+	Cancel   bool `json:"cancel"`    // also cancel the message's processing at dump_secs
+}
+
+// Thresholds returns the warn and dump durations, 0 when disabled.
+func (c WatchdogConfig) Thresholds() (warn, dump time.Duration) {
+	secs := func(v, def int) time.Duration {
+		switch {
+		case v == 0:
+			return time.Duration(def) * time.Second
+		case v > 0:
+			return time.Duration(v) * time.Second
+		}
+		return 0
+	}
+	return secs(c.WarnSecs, 60), secs(c.DumpSecs, 180)
+}
+
+// IdleWorkConfig defers maintenance (summarization, media pruning, session
+// archival) until the agent has been quiet for a while within the
+// heartbeat's active hours, instead of running it right after a turn.
+type IdleWorkConfig struct {
+	Enabled      bool `json:"enabled"`
+	QuietMinutes int  `json:"quiet_minutes"` // 0 = default (10)
+}
+
+// Quiet returns how long the agent must be idle before work runs.
+func (c IdleWorkConfig) Quiet() time.Duration {
+	if c.QuietMinutes <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.QuietMinutes) * time.Minute
+}
+
+// AutonomyConfig decides whether side-effecting tool calls (file writes,
+// calendar changes, messages, commands) run directly or wait for the user.
+type AutonomyConfig struct {
+	Level string            `json:"level,omitempty"` // "auto" (default) runs them; "suggest" describes them and runs them once the user replies "yes"
+	Tools map[string]string `json:"tools,omitempty"` // tool name -> level, overriding level and role policies for that tool
+}
+
+// Validate checks the levels.
+func (a AutonomyConfig) Validate() error {
+	if !ValidAutonomy(a.Level) {
+		return fmt.Errorf("unknown autonomy level %q", a.Level)
+	}
+	for tool, level := range a.Tools {
+		if level == "" || !ValidAutonomy(level) {
+			return fmt.Errorf("tool %s: unknown autonomy level %q", tool, level)
+		}
+	}
+	return nil
+}
+
+// ValidAutonomy reports whether level is "auto", "suggest" or empty.
+func ValidAutonomy(level string) bool {
+	return level == "" || level == "auto" || level == "suggest"
 }
 
 type AgentDefaults struct {
@@ -35,12 +175,29 @@ type AgentDefaults struct {
 	MaxTokens         int     `json:"max_tokens"`
 	Temperature       float64 `json:"temperature"`
 	MaxToolIterations int     `json:"max_tool_iterations"`
+	MaxProcessingSecs int     `json:"max_processing_secs"` // wall-clock limit per message, 0 = default (300)
+	DisableToolHints  bool    `json:"disable_tool_hints"`  // don't append repair hints to failed tool results
+	Timezone          string  `json:"timezone"`            // IANA name for resolving dates like "tomorrow 3pm", empty = system local
+	Locale            string  `json:"locale"`              // language of chat replies and CLI messages: "en" (default), "de" or "fr"
+	DebounceMS        int     `json:"debounce_ms"`         // wait this long for more messages in a session and answer them together, 0 = off
+	VerboseErrors     bool    `json:"verbose_errors"`      // include raw error details in messages sent to chats
+	ToolDefinitions   string  `json:"tool_definitions"`    // "groups" offers grouped tools behind one open_tools definition, "full" or empty = every tool in full
 }
 
 type ProviderConfig struct {
 	APIKeyEnv string `json:"api_key_env"`
 	APIBase   string `json:"api_base"`
 	Proxy     string `json:"proxy,omitempty"`
+	Trusted   bool   `json:"trusted,omitempty"` // local model: prompts are never redacted
+	// PromptCache adds caching hints: "anthropic" marks the system prompt
+	// and tools with cache_control, "openai" sends a prompt_cache_key.
+	// Either keeps the system prompt stable between turns. Empty = off.
+	PromptCache string `json:"prompt_cache,omitempty"`
+	// Capture writes every request/response pair, redacted, to
+	// workspace/debug/llm for debugging. Keeps the newest CaptureMaxFiles
+	// (default 200).
+	Capture         bool `json:"capture,omitempty"`
+	CaptureMaxFiles int  `json:"capture_max_files,omitempty"`
 }
 
 func (p ProviderConfig) ResolveAPIKey() string {
@@ -55,6 +212,281 @@ type HeartbeatConfig struct {
 	Interval         int                `json:"interval"`           // minutes, min 5
 	MaxDailyMessages int                `json:"max_daily_messages"` // 0 = use default (3)
 	ActiveHours      *ActiveHoursConfig `json:"active_hours,omitempty"`
+	MaxQueuedEvents  int                `json:"max_queued_events,omitempty"` // wake event queue size, 0 = default (256)
+
+	// Overrides for heartbeat runs; zero values use the agent defaults.
+	Model             string   `json:"model,omitempty"`
+	MaxToolIterations int      `json:"max_tool_iterations,omitempty"`
+	Tools             []string `json:"tools,omitempty"`      // if set, only these tools are offered
+	MaxTokens         int      `json:"max_tokens,omitempty"` // total prompt+completion tokens per heartbeat
+}
+
+// AuditConfig controls the side-effect audit log (workspace/audit.jsonl).
+type AuditConfig struct {
+	Disabled      bool `json:"disabled"`
+	RetentionDays int  `json:"retention_days"` // 0 = default (90), negative = keep forever
+}
+
+// Retention returns the configured retention as a duration.
+func (a AuditConfig) Retention() time.Duration {
+	return time.Duration(a.RetentionDays) * 24 * time.Hour
+}
+
+// JournalConfig schedules the daily journal (workspace/journal).
+type JournalConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Time      string `json:"time,omitempty"`       // "HH:MM" to write the day's entry, default "22:00"
+	WeeklyDay string `json:"weekly_day,omitempty"` // day of the weekly rollup, default "sunday"
+}
+
+// FlashcardsConfig schedules the daily flashcard quiz.
+type FlashcardsConfig struct {
+	ReviewTime string `json:"review_time,omitempty"` // "HH:MM" to quiz due cards over chat; empty disables the quiz
+}
+
+// MedicationsConfig tunes medication reminders.
+type MedicationsConfig struct {
+	LowStockDays int `json:"low_stock_days,omitempty"` // warn when stock lasts this many days or less, default 7
+}
+
+// SleepConfig sets the user's sleep schedule and where sleep is detected.
+// The heartbeat holds non-urgent messages while the user sleeps.
+type SleepConfig struct {
+	Bedtime      string   `json:"bedtime,omitempty"`       // "HH:MM"; with wake_time, used until the user sets a schedule
+	WakeTime     string   `json:"wake_time,omitempty"`     // "HH:MM"
+	Sensor       string   `json:"sensor,omitempty"`        // Home Assistant entity, e.g. "binary_sensor.bed_occupancy"; needs tools.home_assistant
+	AsleepStates []string `json:"asleep_states,omitempty"` // sensor states meaning asleep, default on/asleep/sleeping/sleep/in_bed
+}
+
+// TravelConfig tunes travel mode.
+type TravelConfig struct {
+	DisableCalendar bool `json:"disable_calendar,omitempty"` // don't create trips from multi-day calendar events with a location
+}
+
+// RolesConfig assigns household roles (owner, family, guest) to senders.
+// On a channel listed here, and on the federation channel, senders without
+// an entry are guests; on other channels they are the owner.
+type RolesConfig struct {
+	// Channels maps a channel name to allowlist entries ("id", "id|username"
+	// or "@username") and the role each one gets.
+	Channels map[string]map[string]string `json:"channels,omitempty"`
+	// Policies overrides the built-in tool policy of a role.
+	Policies map[string]RolePolicy `json:"policies,omitempty"`
+}
+
+type RolePolicy struct {
+	AllowTools []string `json:"allow_tools,omitempty"` // if set, only these tools are offered
+	DenyTools  []string `json:"deny_tools,omitempty"`
+	Autonomy   string   `json:"autonomy,omitempty"` // "auto" or "suggest" for this role, empty = agents.autonomy.level
+}
+
+// RedactionConfig masks personal data in logs and activity payloads, and in
+// prompts sent to an untrusted provider when RedactPrompts is set.
+type RedactionConfig struct {
+	Enabled       bool              `json:"enabled"`
+	Patterns      []string          `json:"patterns,omitempty"` // built-ins: email, phone, credit_card (default all)
+	Custom        map[string]string `json:"custom,omitempty"`   // label -> regular expression
+	Mode          string            `json:"mode,omitempty"`     // "mask" (default) or "strip"
+	RedactPrompts bool              `json:"redact_prompts"`
+}
+
+// EncryptionConfig seals sessions, memory notes, USER.md and the cron store
+// at rest. The SQLite task database (localagent.db) is not covered, and
+// startup says so; it needs full-disk encryption.
+type EncryptionConfig struct {
+	Enabled       bool   `json:"enabled"`
+	PassphraseEnv string `json:"passphrase_env,omitempty"` // default LOCALAGENT_PASSPHRASE
+	Keychain      bool   `json:"keychain,omitempty"`       // read the passphrase from the OS keychain (service "localagent")
+}
+
+// TelemetryConfig pushes self-metrics (messages, tokens, tool runs) to local
+// collectors. Endpoints must resolve to loopback or private addresses.
+type TelemetryConfig struct {
+	File          string              `json:"file,omitempty"` // JSON lines, relative to the workspace
+	Endpoints     []TelemetryEndpoint `json:"endpoints,omitempty"`
+	FlushInterval int                 `json:"flush_interval,omitempty"` // seconds, 0 = default (10)
+	// RuntimeStats is how often, in seconds, the gateway reports goroutines,
+	// heap, GC and bus depths: as "runtime" events when sinks are set, in
+	// the log otherwise. 0 = default (60 with sinks, off without), negative = off.
+	RuntimeStats int `json:"runtime_stats,omitempty"`
+}
+
+// RuntimeStatsInterval returns how often to report runtime stats, 0 when
+// disabled.
+func (t TelemetryConfig) RuntimeStatsInterval() time.Duration {
+	switch {
+	case t.RuntimeStats > 0:
+		return time.Duration(t.RuntimeStats) * time.Second
+	case t.RuntimeStats == 0 && (t.File != "" || len(t.Endpoints) > 0):
+		return time.Minute
+	}
+	return 0
+}
+
+// TelemetryEndpoint is an InfluxDB line protocol write URL, e.g.
+// http://localhost:8428/write for VictoriaMetrics.
+type TelemetryEndpoint struct {
+	URL      string `json:"url"`
+	TokenEnv string `json:"token_env,omitempty"`
+}
+
+func (t TelemetryEndpoint) ResolveToken() string {
+	if t.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(t.TokenEnv)
+}
+
+// BridgeConfig mirrors activity events and inbound/outbound messages to an
+// MQTT or NATS broker so other home automation can react to the agent.
+// Topics may use {type}, {channel} and {chat_id}; "-" turns a stream off.
+type BridgeConfig struct {
+	URL         string   `json:"url,omitempty"` // mqtt://, mqtts://, nats:// or nats+tls://host:port; empty = off
+	Username    string   `json:"username,omitempty"`
+	PasswordEnv string   `json:"password_env,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`      // default "localagent"
+	Events      []string `json:"events,omitempty"`         // activity types to mirror, default all
+	Activity    string   `json:"activity_topic,omitempty"` // default localagent/activity/{type}
+	Inbound     string   `json:"inbound_topic,omitempty"`  // default localagent/inbound/{channel}
+	Outbound    string   `json:"outbound_topic,omitempty"` // default localagent/outbound/{channel}
+}
+
+func (b BridgeConfig) ResolvePassword() string {
+	if b.PasswordEnv == "" {
+		return ""
+	}
+	return os.Getenv(b.PasswordEnv)
+}
+
+// MQTTConfig is the MQTT command channel. A message on
+// CommandTopic/<id> is a chat message in session "mqtt:<id>"; the reply
+// is published to ResponseTopic/<id>.
+type MQTTConfig struct {
+	URL           string   `json:"url,omitempty"` // mqtt:// or mqtts://host:port; empty = off
+	Username      string   `json:"username,omitempty"`
+	PasswordEnv   string   `json:"password_env,omitempty"`
+	ClientID      string   `json:"client_id,omitempty"`      // default "localagent-commands"
+	CommandTopic  string   `json:"command_topic,omitempty"`  // default "localagent/command"
+	ResponseTopic string   `json:"response_topic,omitempty"` // default "localagent/response"
+	AllowFrom     []string `json:"allow_from,omitempty"`     // allowed <id>s, empty = any
+}
+
+func (m MQTTConfig) ResolvePassword() string {
+	if m.PasswordEnv == "" {
+		return ""
+	}
+	return os.Getenv(m.PasswordEnv)
+}
+
+// DiscordConfig connects the agent to Discord as a bot. It answers direct
+// messages, mentions, and every message in listen_channels.
+type DiscordConfig struct {
+	TokenEnv       string   `json:"token_env,omitempty"`       // env var holding the bot token; empty = off
+	AllowFrom      []string `json:"allow_from,omitempty"`      // allowed user IDs, empty = any
+	ListenChannels []string `json:"listen_channels,omitempty"` // guild channel IDs answered without a mention
+}
+
+func (d DiscordConfig) ResolveToken() string {
+	if d.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(d.TokenEnv)
+}
+
+// MatrixConfig connects the agent to a Matrix homeserver as a user with an
+// access token. Encrypted rooms need homeserver to point at an E2EE-aware
+// proxy such as Pantalaimon; without one only unencrypted rooms work.
+type MatrixConfig struct {
+	Homeserver     string   `json:"homeserver,omitempty"`       // e.g. https://matrix.example.org; empty = off
+	AccessTokenEnv string   `json:"access_token_env,omitempty"` // env var holding the access token
+	AllowFrom      []string `json:"allow_from,omitempty"`       // allowed user IDs, empty = any
+	Rooms          []string `json:"rooms,omitempty"`            // room IDs answered and joined on invite, empty = any
+}
+
+func (m MatrixConfig) ResolveAccessToken() string {
+	if m.AccessTokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(m.AccessTokenEnv)
+}
+
+// WhatsAppConfig connects the agent to a WhatsApp Business number through
+// the Meta Cloud API. Meta delivers messages to the gateway's
+// /whatsapp/webhook, which must be reachable over HTTPS (e.g. behind a
+// reverse proxy or tunnel).
+type WhatsAppConfig struct {
+	PhoneNumberID  string   `json:"phone_number_id,omitempty"`   // the business number's ID from the app dashboard; empty = off
+	TokenEnv       string   `json:"token_env,omitempty"`         // env var holding the access token
+	AppSecretEnv   string   `json:"app_secret_env,omitempty"`    // env var holding the app secret, to check webhook signatures
+	VerifyTokenEnv string   `json:"verify_token_env,omitempty"`  // env var holding the verify token entered in the webhook setup
+	AllowFrom      []string `json:"allow_from,omitempty"`        // phone numbers in international format; required
+	Template       string   `json:"template,omitempty"`          // approved template with one body parameter, for replies after the 24-hour window
+	TemplateLang   string   `json:"template_language,omitempty"` // default "en_US"
+}
+
+func (w WhatsAppConfig) ResolveToken() string {
+	if w.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(w.TokenEnv)
+}
+
+func (w WhatsAppConfig) ResolveAppSecret() string {
+	if w.AppSecretEnv == "" {
+		return ""
+	}
+	return os.Getenv(w.AppSecretEnv)
+}
+
+func (w WhatsAppConfig) ResolveVerifyToken() string {
+	if w.VerifyTokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(w.VerifyTokenEnv)
+}
+
+// MailChannelConfig makes the agent reachable by email: the IMAP mailbox
+// is polled for mail from allow_from and replies go out over SMTP. Give it
+// a mailbox of its own; messages are never marked read or moved.
+type MailChannelConfig struct {
+	IMAPHost    string   `json:"imap_host,omitempty"` // host:port, port 993 (143 with plaintext) when omitted; empty = off
+	SMTPHost    string   `json:"smtp_host,omitempty"` // host:port, port 587 (STARTTLS) when omitted; 465 uses implicit TLS
+	Username    string   `json:"username,omitempty"`
+	PasswordEnv string   `json:"password_env,omitempty"`
+	Address     string   `json:"address,omitempty"`      // From address of replies, default username
+	Mailbox     string   `json:"mailbox,omitempty"`      // default INBOX
+	Plaintext   bool     `json:"plaintext,omitempty"`    // no TLS, e.g. a bridge on localhost
+	PollSeconds int      `json:"poll_seconds,omitempty"` // default 60
+	AllowFrom   []string `json:"allow_from,omitempty"`   // sender addresses; required
+	// AuthServID is the authserv-id the receiving mail server writes in
+	// Authentication-Results headers (e.g. "mx.example.org"). When set,
+	// mail is only read if that header shows DMARC, DKIM or SPF passing for
+	// the From domain. Without it the From header can't be trusted and
+	// senders get the guest role.
+	AuthServID string `json:"auth_serv_id,omitempty"`
+}
+
+func (e MailChannelConfig) ResolvePassword() string {
+	if e.PasswordEnv == "" {
+		return ""
+	}
+	return os.Getenv(e.PasswordEnv)
+}
+
+// IMAPAddr returns IMAPHost with the default port added when it has none.
+func (e MailChannelConfig) IMAPAddr() string {
+	return EmailConfig{Host: e.IMAPHost, Plaintext: e.Plaintext}.Addr()
+}
+
+// SMTPAddr returns SMTPHost with the default port added when it has none.
+func (e MailChannelConfig) SMTPAddr() string {
+	if _, _, err := net.SplitHostPort(e.SMTPHost); err == nil {
+		return e.SMTPHost
+	}
+	if e.Plaintext {
+		return net.JoinHostPort(e.SMTPHost, "25")
+	}
+	return net.JoinHostPort(e.SMTPHost, "587")
 }
 
 type ActiveHoursConfig struct {
@@ -63,9 +495,149 @@ type ActiveHoursConfig struct {
 	Timezone string `json:"timezone"` // e.g. "America/New_York"
 }
 
+// MediaConfig is the retention policy for uploaded and generated media.
+// Files referenced by a session are kept past MaxAgeMinutes; MaxTotalMB
+// evicts least recently used files regardless.
+type MediaConfig struct {
+	MaxAgeMinutes int `json:"max_age_minutes"` // unreferenced files, 0 = default (60)
+	MaxTotalMB    int `json:"max_total_mb"`    // 0 = default (1024), negative = unlimited
+}
+
+// StorageConfig sets disk quotas beyond media (see MediaConfig) and when the
+// heartbeat warns about a nearly full disk.
+type StorageConfig struct {
+	ImageJobsMaxMB int `json:"image_jobs_max_mb"` // oldest jobs pruned first, 0 = default (2048), negative = unlimited
+	MinFreePercent int `json:"min_free_percent"`  // warn below this, 0 = default (5), negative = never
+	// Sessions idle this many days are compressed into sessions/archive
+	// and restored when used again. 0 = default (90), negative = never.
+	SessionArchiveDays int `json:"session_archive_days"`
+}
+
+// ImageJobsMaxBytes returns the image job quota in bytes, 0 for unlimited.
+func (s StorageConfig) ImageJobsMaxBytes() int64 {
+	switch {
+	case s.ImageJobsMaxMB == 0:
+		return 2048 << 20
+	case s.ImageJobsMaxMB > 0:
+		return int64(s.ImageJobsMaxMB) << 20
+	}
+	return 0
+}
+
+// SessionArchiveAfter returns how long a session may be idle before it is
+// archived, 0 when archival is disabled.
+func (s StorageConfig) SessionArchiveAfter() time.Duration {
+	switch {
+	case s.SessionArchiveDays == 0:
+		return 90 * 24 * time.Hour
+	case s.SessionArchiveDays > 0:
+		return time.Duration(s.SessionArchiveDays) * 24 * time.Hour
+	}
+	return 0
+}
+
+// LowDiskPercent returns the free space threshold for the low disk
+// warning, 0 when disabled.
+func (s StorageConfig) LowDiskPercent() float64 {
+	switch {
+	case s.MinFreePercent == 0:
+		return 5
+	case s.MinFreePercent > 0:
+		return float64(s.MinFreePercent)
+	}
+	return 0
+}
+
+// OutboundConfig rate limits messages sent to each channel. Messages over
+// the limit are held and sent together as one digest once it allows.
+type OutboundConfig struct {
+	Default  RateLimitConfig            `json:"default"`
+	Channels map[string]RateLimitConfig `json:"channels,omitempty"` // per channel name, unset fields inherit Default
+}
+
+type RateLimitConfig struct {
+	PerMinute int `json:"per_minute"` // 0 = default (20), negative = unlimited
+	Burst     int `json:"burst"`      // messages sent back to back, 0 = default (5)
+}
+
+// Limit returns the resolved limit for channel; PerMinute <= 0 means
+// unlimited.
+func (o OutboundConfig) Limit(channel string) RateLimitConfig {
+	l := o.Channels[channel]
+	if l.PerMinute == 0 {
+		l.PerMinute = o.Default.PerMinute
+	}
+	if l.Burst <= 0 {
+		l.Burst = o.Default.Burst
+	}
+	if l.PerMinute == 0 {
+		l.PerMinute = 20
+	}
+	if l.Burst <= 0 {
+		l.Burst = 5
+	}
+	return l
+}
+
+// FederationConfig lists other localagent instances this one may delegate
+// tasks to (remote_agent tool) or accept tasks from (gateway /federation/).
+// Each peer shares a bearer token with this instance. Tasks from accepted
+// peers run with the guest role unless roles.channels.federation gives the
+// peer name another one.
+type FederationConfig struct {
+	Peers []PeerConfig `json:"peers,omitempty"`
+}
+
+type PeerConfig struct {
+	Name     string `json:"name"`
+	URL      string `json:"url,omitempty"`    // peer gateway, e.g. "http://laptop:18790"; empty = we never call it
+	TokenEnv string `json:"token_env"`        // env var holding the shared token
+	Accept   bool   `json:"accept,omitempty"` // let this peer delegate tasks to us
+}
+
+func (p PeerConfig) ResolveToken() string {
+	if p.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(p.TokenEnv)
+}
+
 type GatewayConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host      string             `json:"host"`
+	Port      int                `json:"port"`
+	OpenAI    OpenAIAPIConfig    `json:"openai"`
+	Satellite SatelliteAPIConfig `json:"satellite"`
+}
+
+// OpenAIAPIConfig exposes the agent as an OpenAI-compatible
+// /v1/chat/completions endpoint on the gateway.
+type OpenAIAPIConfig struct {
+	Enabled  bool   `json:"enabled"`
+	TokenEnv string `json:"token_env"` // env var holding the bearer token clients use as API key; required
+}
+
+func (o OpenAIAPIConfig) ResolveToken() string {
+	if o.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(o.TokenEnv)
+}
+
+// SatelliteAPIConfig exposes a voice endpoint on the gateway for satellite
+// devices (ESP32 speakers, Wyoming satellites). Speech goes through
+// tools.stt and tools.tts.
+type SatelliteAPIConfig struct {
+	Enabled  bool   `json:"enabled"`
+	TokenEnv string `json:"token_env"`          // env var holding the bearer token devices send; required
+	Speaker  string `json:"speaker,omitempty"`  // TTS voice, default tools.tts.speaker
+	Language string `json:"language,omitempty"` // TTS language, default tools.tts.language
+}
+
+func (s SatelliteAPIConfig) ResolveToken() string {
+	if s.TokenEnv == "" {
+		return ""
+	}
+	return os.Getenv(s.TokenEnv)
 }
 
 type PDFConfig struct {
@@ -80,6 +652,28 @@ func (p PDFConfig) ResolveAPIKey() string {
 	return os.Getenv(p.APIKeyEnv)
 }
 
+// SummarizeConfig tunes the summarize_document tool.
+type SummarizeConfig struct {
+	Model       string `json:"model,omitempty"`        // e.g. a cheaper model for the per-chunk summaries, default the agent model
+	ChunkTokens int    `json:"chunk_tokens,omitempty"` // approximate chunk size, default 3000
+}
+
+// DocsQAConfig indexes workspace folders for the docs_qa tool.
+type DocsQAConfig struct {
+	Folders        []string `json:"folders,omitempty"`         // relative to the workspace or absolute, e.g. ["notes", "exports"]
+	EmbeddingModel string   `json:"embedding_model,omitempty"` // required, e.g. "text-embedding-3-small"
+	APIBase        string   `json:"api_base,omitempty"`        // OpenAI-compatible embeddings API, default provider.api_base
+	APIKeyEnv      string   `json:"api_key_env,omitempty"`     // key for api_base
+	ChunkTokens    int      `json:"chunk_tokens,omitempty"`    // default 400
+}
+
+func (d DocsQAConfig) ResolveAPIKey() string {
+	if d.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(d.APIKeyEnv)
+}
+
 type STTConfig struct {
 	URL       string `json:"url"`
 	APIKeyEnv string `json:"api_key_env"`
@@ -122,9 +716,11 @@ type CronToolsConfig struct {
 }
 
 type CalendarConfig struct {
-	URL         string `json:"url"`
-	Username    string `json:"username"`
-	PasswordEnv string `json:"password_env"`
+	URL                 string `json:"url"`
+	Username            string `json:"username"`
+	PasswordEnv         string `json:"password_env"`
+	ReminderLeadMinutes int    `json:"reminder_lead_minutes"` // heartbeat reminder before timed events, 0 = disabled
+	DoNotDisturb        bool   `json:"do_not_disturb"`        // hold non-urgent heartbeat and cron messages during busy events
 }
 
 func (c CalendarConfig) ResolvePassword() string {
@@ -134,6 +730,109 @@ func (c CalendarConfig) ResolvePassword() string {
 	return os.Getenv(c.PasswordEnv)
 }
 
+// EmailConfig is the IMAP mailbox the email_triage tool reads. It is only
+// ever opened read-only.
+type EmailConfig struct {
+	Host            string `json:"host"` // host:port, port 993 (143 with plaintext) when omitted
+	Username        string `json:"username"`
+	PasswordEnv     string `json:"password_env"`
+	Mailbox         string `json:"mailbox,omitempty"`          // default INBOX
+	Plaintext       bool   `json:"plaintext,omitempty"`        // no TLS, e.g. a bridge on localhost
+	LookbackDays    int    `json:"lookback_days,omitempty"`    // unread mail considered, default 3
+	MaxMessages     int    `json:"max_messages,omitempty"`     // newest unread messages triaged, default 30
+	DisableBriefing bool   `json:"disable_briefing,omitempty"` // don't add the triage to the first heartbeat of the day
+}
+
+func (e EmailConfig) ResolvePassword() string {
+	if e.PasswordEnv == "" {
+		return ""
+	}
+	return os.Getenv(e.PasswordEnv)
+}
+
+// Addr returns Host with the default IMAP port added when it has none.
+func (e EmailConfig) Addr() string {
+	if _, _, err := net.SplitHostPort(e.Host); err == nil {
+		return e.Host
+	}
+	if e.Plaintext {
+		return net.JoinHostPort(e.Host, "143")
+	}
+	return net.JoinHostPort(e.Host, "993")
+}
+
+// PackagesConfig enables the track_package tool through a tracking
+// aggregator; watched parcels are polled for status changes.
+type PackagesConfig struct {
+	Provider    string `json:"provider"` // "aftership" or "17track"
+	APIKeyEnv   string `json:"api_key_env"`
+	PollMinutes int    `json:"poll_minutes,omitempty"` // how often watched parcels are checked, default 60
+}
+
+func (p PackagesConfig) ResolveAPIKey() string {
+	if p.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(p.APIKeyEnv)
+}
+
+// AirQualityConfig sets the default locations of the air_quality tool
+// (Open-Meteo, no key). Their air quality and pollen join the first
+// heartbeat of the day when levels are notable.
+type AirQualityConfig struct {
+	Locations       []AirQualityLocation `json:"locations,omitempty"`
+	Allergens       []string             `json:"allergens,omitempty"` // pollen the user reacts to, e.g. ["grass", "birch"]; default all
+	DisableBriefing bool                 `json:"disable_briefing,omitempty"`
+}
+
+// AirQualityLocation is a place to report on; without coordinates the
+// name is geocoded.
+type AirQualityLocation struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat,omitempty"`
+	Lon  float64 `json:"lon,omitempty"`
+}
+
+// SportsConfig sets the teams the sports tool follows, through TheSportsDB
+// (its free key is used without api_key_env). With notify_results, each
+// finished match of a followed team becomes a heartbeat event.
+type SportsConfig struct {
+	APIKeyEnv     string             `json:"api_key_env,omitempty"`
+	Teams         []SportsTeamConfig `json:"teams,omitempty"`
+	NotifyResults bool               `json:"notify_results,omitempty"`
+	PollMinutes   int                `json:"poll_minutes,omitempty"` // result checks, default 15
+}
+
+// SportsTeamConfig is a followed team; without an ID it is looked up by
+// name.
+type SportsTeamConfig struct {
+	Name string `json:"name"`
+	ID   string `json:"id,omitempty"` // TheSportsDB idTeam, e.g. "133604"
+}
+
+func (s SportsConfig) ResolveAPIKey() string {
+	if s.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(s.APIKeyEnv)
+}
+
+// ScreenshotConfig enables the screenshot tool. Commands run with sh -c
+// (PowerShell on Windows) and must write an image to {file}; empty ones
+// use the OS default (screencapture, grim/gnome-screenshot/scrot/import).
+type ScreenshotConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Command       string `json:"command,omitempty"`        // whole screen
+	WindowCommand string `json:"window_command,omitempty"` // focused window
+}
+
+// ClipboardConfig enables the clipboard tool (wl-clipboard, xclip or xsel
+// on Linux, pbcopy/pbpaste on macOS).
+type ClipboardConfig struct {
+	Enabled   bool `json:"enabled"`
+	WriteOnly bool `json:"write_only,omitempty"` // never read the clipboard, it may hold passwords
+}
+
 type TTSConfig struct {
 	URL       string `json:"url"`
 	APIKeyEnv string `json:"api_key_env"`
@@ -148,14 +847,52 @@ func (t TTSConfig) ResolveAPIKey() string {
 	return os.Getenv(t.APIKeyEnv)
 }
 
+type NetCheckConfig struct {
+	Hosts        []string `json:"hosts,omitempty"`         // host or host:port, default: public resolvers + google.com
+	SpeedTestURL string   `json:"speedtest_url,omitempty"` // download URL used for bandwidth measurement
+}
+
+// ReceiptConfig sets the model parse_receipt reads receipt photos with; it
+// must accept images.
+type ReceiptConfig struct {
+	Model string `json:"model,omitempty"` // default the agent model
+}
+
+// DownloadsConfig limits how much tools can pull over HTTP.
+// Zero uses the default; negative disables the limit.
+type DownloadsConfig struct {
+	MaxResponseMB int `json:"max_response_mb,omitempty"` // default 10
+	DailyQuotaMB  int `json:"daily_quota_mb,omitempty"`  // per tool, default 500
+}
+
+type DockerConfig struct {
+	Host             string   `json:"host"`                        // unix:///var/run/docker.sock or tcp://host:2376
+	CertPath         string   `json:"cert_path,omitempty"`         // dir with ca.pem, cert.pem, key.pem for TLS
+	RestartAllowlist []string `json:"restart_allowlist,omitempty"` // container names the agent may restart
+}
+
 type ToolsConfig struct {
 	PDF           PDFConfig           `json:"pdf"`
+	Summarize     SummarizeConfig     `json:"summarize"`
+	DocsQA        DocsQAConfig        `json:"docs_qa"`
 	STT           STTConfig           `json:"stt"`
 	TTS           TTSConfig           `json:"tts"`
 	Image         ImageConfig         `json:"image"`
 	Cron          CronToolsConfig     `json:"cron"`
 	HomeAssistant HomeAssistantConfig `json:"home_assistant"`
 	Calendar      CalendarConfig      `json:"calendar"`
+	Email         EmailConfig         `json:"email"`
+	Packages      PackagesConfig      `json:"packages"`
+	Transit       TransitConfig       `json:"transit"`
+	AirQuality    AirQualityConfig    `json:"air_quality"`
+	Sports        SportsConfig        `json:"sports"`
+	Screenshot    ScreenshotConfig    `json:"screenshot"`
+	Clipboard     ClipboardConfig     `json:"clipboard"`
+	NetCheck      NetCheckConfig      `json:"net_check"`
+	Docker        DockerConfig        `json:"docker"`
+	Downloads     DownloadsConfig     `json:"downloads"`
+	Receipt       ReceiptConfig       `json:"receipt"`
+	Custom        []CustomToolConfig  `json:"custom,omitempty"`
 }
 
 func DefaultConfig() *Config {
@@ -167,6 +904,8 @@ func DefaultConfig() *Config {
 				MaxTokens:         8192,
 				Temperature:       0.7,
 				MaxToolIterations: 20,
+				MaxProcessingSecs: 300,
+				DebounceMS:        1500,
 			},
 		},
 		Provider: ProviderConfig{
@@ -180,6 +919,10 @@ func DefaultConfig() *Config {
 			Enabled:  true,
 			Interval: 30,
 		},
+		Media: MediaConfig{
+			MaxAgeMinutes: 60,
+			MaxTotalMB:    1024,
+		},
 		WebChat: WebChatConfig{
 			Host: "0.0.0.0",
 			Port: 18791,
@@ -199,6 +942,7 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	applyEnvOverrides(cfg)
+	cfg.dataDir = filepath.Dir(path)
 
 	return cfg, nil
 }
@@ -226,24 +970,121 @@ func (c *Config) WorkspacePath() string {
 	return expandHome(c.Agents.Defaults.Workspace)
 }
 
+// AuditPath is where side-effecting tool calls are recorded.
+func (c *Config) AuditPath() string {
+	return filepath.Join(c.WorkspacePath(), "audit.jsonl")
+}
+
+// DataDir holds per-instance data outside the workspace (webchat, vault,
+// proxy logs): the directory of the loaded config, so each profile keeps
+// its own.
 func (c *Config) DataDir() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".localagent")
+	if c.dataDir != "" {
+		return c.dataDir
+	}
+	return BaseDir()
+}
+
+// AddAllowedDomain appends a pattern to AllowedDomains if not already present.
+func (c *Config) AddAllowedDomain(pattern string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.AllowedDomains {
+		if d == pattern {
+			return
+		}
+	}
+	c.AllowedDomains = append(c.AllowedDomains, pattern)
+}
+
+// RemoveAllowedDomain removes a pattern from AllowedDomains.
+// Returns true if it was present.
+func (c *Config) RemoveAllowedDomain(pattern string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, d := range c.AllowedDomains {
+		if d == pattern {
+			c.AllowedDomains = append(c.AllowedDomains[:i], c.AllowedDomains[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Secrets returns the non-empty API keys, tokens and passwords resolved
+// from the environment, for masking in logs.
+func (c *Config) Secrets() []string {
+	values := []string{
+		c.Provider.ResolveAPIKey(),
+		c.Gateway.OpenAI.ResolveToken(),
+		c.Gateway.Satellite.ResolveToken(),
+		c.Tools.PDF.ResolveAPIKey(),
+		c.Tools.DocsQA.ResolveAPIKey(),
+		c.Tools.STT.ResolveAPIKey(),
+		c.Tools.TTS.ResolveAPIKey(),
+		c.Tools.Image.ResolveAPIKey(),
+		c.Tools.HomeAssistant.ResolveAPIKey(),
+		c.Tools.Calendar.ResolvePassword(),
+		c.Bridge.ResolvePassword(),
+		c.MQTT.ResolvePassword(),
+		c.Discord.ResolveToken(),
+		c.Matrix.ResolveAccessToken(),
+		c.EmailChannel.ResolvePassword(),
+		c.WhatsApp.ResolveToken(),
+		c.WhatsApp.ResolveAppSecret(),
+		c.WhatsApp.ResolveVerifyToken(),
+	}
+	for _, e := range c.Telemetry.Endpoints {
+		values = append(values, e.ResolveToken())
+	}
+	for _, p := range c.Federation.Peers {
+		values = append(values, p.ResolveToken())
+	}
+	for _, t := range c.Tools.Custom {
+		values = append(values, t.ResolveAPIKey())
+	}
+	for _, r := range c.Agents.Routes {
+		values = append(values, r.ResolveAPIKey())
+	}
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
 
 // ServiceDomains extracts host from configured service URLs
-// (provider API base, PDF, STT, Image).
+// (provider API base, PDF, STT, Image, Docker TCP host, federation peers,
+// Matrix homeserver).
 func (c *Config) ServiceDomains() []string {
 	var domains []string
-	for _, rawURL := range []string{
+	urls := []string{
 		c.Provider.APIBase,
 		c.Tools.PDF.URL,
+		c.Tools.DocsQA.APIBase,
 		c.Tools.STT.URL,
 		c.Tools.TTS.URL,
 		c.Tools.Image.URL,
 		c.Tools.HomeAssistant.URL,
 		c.Tools.Calendar.URL,
-	} {
+		c.Tools.Docker.Host, // unix:// sockets have no host and are skipped
+		c.Matrix.Homeserver,
+	}
+	for _, p := range c.Federation.Peers {
+		urls = append(urls, p.URL)
+	}
+	for _, r := range c.Agents.Routes {
+		urls = append(urls, r.APIBase)
+	}
+	if c.Discord.TokenEnv != "" {
+		domains = append(domains, "discord.com", "cdn.discordapp.com", "media.discordapp.net")
+	}
+	if c.WhatsApp.PhoneNumberID != "" {
+		domains = append(domains, "graph.facebook.com", "lookaside.fbsbx.com")
+	}
+	for _, rawURL := range urls {
 		if rawURL == "" {
 			continue
 		}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CustomToolConfig defines a tool without Go code: either a shell Command
+// or an HTTP request. {{param}} placeholders in the command, URL, headers
+// and body are filled from the call's arguments.
+type CustomToolConfig struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"` // JSON schema of the arguments, default: none
+	Group       string         `json:"group,omitempty"`      // tool group for agents.defaults.tool_definitions "groups"
+	Command     string         `json:"command,omitempty"`    // run with sh -c in the workspace; values are shell-quoted
+	HTTP        *CustomHTTP    `json:"http,omitempty"`
+	Output      CustomOutput   `json:"output"`
+	TimeoutSecs int            `json:"timeout_secs,omitempty"` // default 30
+	OwnerOnly   *bool          `json:"owner_only,omitempty"`   // default true for commands and non-GET requests
+}
+
+type CustomHTTP struct {
+	Method    string            `json:"method,omitempty"` // default GET
+	URL       string            `json:"url"`              // values are query-escaped
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      string            `json:"body,omitempty"`        // values are JSON-escaped
+	APIKeyEnv string            `json:"api_key_env,omitempty"` // sent as a bearer token
+}
+
+// CustomOutput post-processes what the command printed or the request
+// returned, in field order.
+type CustomOutput struct {
+	JSONPath string `json:"json_path,omitempty"` // dotted path into a JSON result, e.g. "current.temp" or "items.0.title"
+	Match    string `json:"match,omitempty"`     // regexp; keeps the first submatch (or whole match) of each matching line
+	MaxChars int    `json:"max_chars,omitempty"` // default 4000
+}
+
+func (c CustomToolConfig) ResolveAPIKey() string {
+	if c.HTTP == nil || c.HTTP.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(c.HTTP.APIKeyEnv)
+}
+
+// IsOwnerOnly reports whether family members and guests are kept from the
+// tool. Commands and requests that can change something are the owner's
+// unless owner_only is set to false.
+func (c CustomToolConfig) IsOwnerOnly() bool {
+	if c.OwnerOnly != nil {
+		return *c.OwnerOnly
+	}
+	return c.Command != "" || (c.HTTP != nil && c.HTTP.Method != "" && !strings.EqualFold(c.HTTP.Method, "GET"))
+}
+
+var customToolName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// Validate reports the first problem with the definition.
+func (c CustomToolConfig) Validate() error {
+	if !customToolName.MatchString(c.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and underscores", c.Name)
+	}
+	if c.Description == "" {
+		return fmt.Errorf("%s: description is required", c.Name)
+	}
+	if (c.Command == "") == (c.HTTP == nil) {
+		return fmt.Errorf("%s: set exactly one of command and http", c.Name)
+	}
+	if c.HTTP != nil {
+		u, err := url.Parse(c.HTTP.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Contains(u.Host, "{{") {
+			return fmt.Errorf("%s: http.url must be an http(s) URL with a fixed host", c.Name)
+		}
+		if m := strings.ToUpper(c.HTTP.Method); m != "" && !slices.Contains([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}, m) {
+			return fmt.Errorf("%s: unsupported http.method %q", c.Name, c.HTTP.Method)
+		}
+	}
+	if c.Parameters != nil {
+		if t, _ := c.Parameters["type"].(string); t != "object" {
+			return fmt.Errorf(`%s: parameters must be a JSON schema with "type": "object"`, c.Name)
+		}
+	}
+	if c.Output.Match != "" {
+		if _, err := regexp.Compile(c.Output.Match); err != nil {
+			return fmt.Errorf("%s: output.match: %v", c.Name, err)
+		}
+	}
+	if c.TimeoutSecs < 0 || c.Output.MaxChars < 0 {
+		return fmt.Errorf("%s: timeout_secs and output.max_chars must not be negative", c.Name)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HookConfig runs the Starlark Script (relative to the workspace) at an
+// extension point. The script defines hook(event); it changes the event
+// dict in place or returns a new one. Scripts are sandboxed: they have no
+// file, network or process access.
+type HookConfig struct {
+	Event     string   `json:"event"` // on_inbound_message, on_outbound_message or on_tool_result
+	Script    string   `json:"script"`
+	Tools     []string `json:"tools,omitempty"`      // on_tool_result: only these tools, default all
+	TimeoutMS int      `json:"timeout_ms,omitempty"` // default 5000
+}
+
+// HookEvents lists the extension points hooks can attach to.
+var HookEvents = []string{"on_inbound_message", "on_outbound_message", "on_tool_result"}
+
+func (h HookConfig) Validate() error {
+	if !slices.Contains(HookEvents, h.Event) {
+		return fmt.Errorf("unknown hook event %q", h.Event)
+	}
+	if strings.TrimSpace(h.Script) == "" {
+		return fmt.Errorf("%s hook: script is required", h.Event)
+	}
+	if h.TimeoutMS < 0 {
+		return fmt.Errorf("%s hook: timeout_ms must not be negative", h.Event)
+	}
+	return nil
+}
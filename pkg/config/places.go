@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PlaceConfig is a place the user cares about in another timezone, e.g.
+// family abroad or a remote team. The agent sees its local time with every
+// message and the world_time tool answers for it.
+type PlaceConfig struct {
+	Name     string `json:"name"`     // who or what is there, e.g. "Mom" or "Bangalore team"
+	Timezone string `json:"timezone"` // IANA name, e.g. "America/Toronto"
+	// Start and End ("HH:MM") are the local hours it's fine to call or
+	// message; default 08:00-21:00.
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+func (p PlaceConfig) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("place with timezone %q: name is required", p.Timezone)
+	}
+	if p.Timezone == "" {
+		return fmt.Errorf("place %q: timezone is required", p.Name)
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("place %q: unknown timezone %q", p.Name, p.Timezone)
+	}
+	if _, _, err := p.Hours(); err != nil {
+		return fmt.Errorf("place %q: %v", p.Name, err)
+	}
+	return nil
+}
+
+// Hours returns the reachable hours as offsets from local midnight.
+func (p PlaceConfig) Hours() (start, end time.Duration, err error) {
+	if start, err = clockOffset(p.Start, "08:00"); err != nil {
+		return 0, 0, err
+	}
+	if end, err = clockOffset(p.End, "21:00"); err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
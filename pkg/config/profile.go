@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// ProfileEnv selects a profile when no --profile flag is given.
+const ProfileEnv = "LOCALAGENT_PROFILE"
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// BaseDir is ~/.localagent, home of the default profile.
+func BaseDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".localagent")
+}
+
+// ProfileDir returns the directory holding a profile's config.json, data
+// and default workspace. The empty profile is the default one in BaseDir.
+func ProfileDir(profile string) string {
+	if profile == "" {
+		return BaseDir()
+	}
+	return filepath.Join(BaseDir(), "profiles", profile)
+}
+
+// ConfigPath returns the config file of a profile.
+func ConfigPath(profile string) string {
+	return filepath.Join(ProfileDir(profile), "config.json")
+}
+
+// ValidateProfileName rejects names that would escape the profiles
+// directory or be awkward on the command line.
+func ValidateProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// DefaultProfileConfig is DefaultConfig with the workspace inside the
+// profile directory, so profiles never share memory or sessions.
+func DefaultProfileConfig(profile string) *Config {
+	cfg := DefaultConfig()
+	if profile != "" {
+		cfg.Agents.Defaults.Workspace = filepath.Join("~/.localagent/profiles", profile, "workspace")
+	}
+	return cfg
+}
+
+// Profiles lists the named profiles that have a config file.
+func Profiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(BaseDir(), "profiles"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() || ValidateProfileName(e.Name()) != nil {
+			continue
+		}
+		if _, err := os.Stat(ConfigPath(e.Name())); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
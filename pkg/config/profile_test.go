@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProfileDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if got, want := ConfigPath(""), filepath.Join(home, ".localagent", "config.json"); got != want {
+		t.Errorf("default config = %s, want %s", got, want)
+	}
+	work := ProfileDir("work")
+	if want := filepath.Join(home, ".localagent", "profiles", "work"); work != want {
+		t.Errorf("work dir = %s, want %s", work, want)
+	}
+
+	if err := SaveConfig(ConfigPath("work"), DefaultProfileConfig("work")); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(ProfileDir("empty"), 0755) // no config.json, not listed
+	cfg, err := LoadConfig(ConfigPath("work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir() != work {
+		t.Errorf("DataDir = %s, want %s", cfg.DataDir(), work)
+	}
+	if want := filepath.Join(work, "workspace"); cfg.WorkspacePath() != want {
+		t.Errorf("WorkspacePath = %s, want %s", cfg.WorkspacePath(), want)
+	}
+
+	names, err := Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"work"}) {
+		t.Errorf("Profiles = %v", names)
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	for _, name := range []string{"work", "home-2", "a_b"} {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "../x", "a/b", "-x", "with space"} {
+		if ValidateProfileName(name) == nil {
+			t.Errorf("%q accepted", name)
+		}
+	}
+}
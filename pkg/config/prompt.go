@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// PromptConfig controls how the system prompt is assembled. Fields set in a
+// profile's config override the layout in SharedPromptPath.
+type PromptConfig struct {
+	Order    []string        `json:"order,omitempty"`    // section names; default DefaultPromptOrder then custom sections; unlisted sections are left out
+	Budgets  map[string]int  `json:"budgets,omitempty"`  // section name -> max tokens (~3 characters each); longer sections are truncated
+	Sections []PromptSection `json:"sections,omitempty"` // custom sections read from workspace files
+}
+
+// PromptSection adds a workspace file to the system prompt.
+type PromptSection struct {
+	Name  string `json:"name"`
+	File  string `json:"file"`            // relative to the workspace
+	Title string `json:"title,omitempty"` // heading, default the file name
+}
+
+// DefaultPromptOrder lists the built-in system prompt sections.
+var DefaultPromptOrder = []string{"identity", "bootstrap", "heartbeat", "skills", "member", "memory"}
+
+// SharedPromptPath holds a prompt layout (same fields as agents.prompt)
+// shared by all profiles.
+func SharedPromptPath() string {
+	return filepath.Join(BaseDir(), "prompt.json")
+}
+
+// PromptLayout returns agents.prompt over the shared layout: a non-empty
+// order replaces the shared one, budgets and sections (by name) are merged.
+func (c *Config) PromptLayout() (PromptConfig, error) {
+	own := c.Agents.Prompt
+	var layout PromptConfig
+	data, err := os.ReadFile(SharedPromptPath())
+	if err != nil && !os.IsNotExist(err) {
+		return own, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &layout); err != nil {
+			return own, fmt.Errorf("%s: %w", SharedPromptPath(), err)
+		}
+	}
+
+	if len(own.Order) > 0 {
+		layout.Order = own.Order
+	}
+	if len(own.Budgets) > 0 {
+		budgets := make(map[string]int, len(layout.Budgets)+len(own.Budgets))
+		maps.Copy(budgets, layout.Budgets)
+		maps.Copy(budgets, own.Budgets)
+		layout.Budgets = budgets
+	}
+	for _, sec := range own.Sections {
+		i := slices.IndexFunc(layout.Sections, func(s PromptSection) bool { return s.Name == sec.Name })
+		if i >= 0 {
+			layout.Sections[i] = sec
+		} else {
+			layout.Sections = append(layout.Sections, sec)
+		}
+	}
+	return layout, layout.Validate()
+}
+
+// Validate checks that section names are known and unique and that
+// custom section files stay inside the workspace.
+func (p PromptConfig) Validate() error {
+	known := map[string]bool{}
+	for _, name := range DefaultPromptOrder {
+		known[name] = true
+	}
+	for _, sec := range p.Sections {
+		if sec.Name == "" || sec.File == "" {
+			return fmt.Errorf("prompt section needs a name and a file")
+		}
+		if known[sec.Name] {
+			return fmt.Errorf("prompt section %q is defined twice or shadows a built-in section", sec.Name)
+		}
+		if !filepath.IsLocal(sec.File) {
+			return fmt.Errorf("prompt section %q: file %q must be relative to the workspace", sec.Name, sec.File)
+		}
+		known[sec.Name] = true
+	}
+	seen := map[string]bool{}
+	for _, name := range p.Order {
+		if !known[name] {
+			return fmt.Errorf("unknown prompt section %q in order", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("prompt section %q is listed twice in order", name)
+		}
+		seen[name] = true
+	}
+	for name, budget := range p.Budgets {
+		if !known[name] {
+			return fmt.Errorf("unknown prompt section %q in budgets", name)
+		}
+		if budget < 0 {
+			return fmt.Errorf("prompt budget for %q is negative", name)
+		}
+	}
+	return nil
+}
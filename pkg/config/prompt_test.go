@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestPromptLayoutOverridesShared(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	os.MkdirAll(BaseDir(), 0755)
+	shared := `{"order":["identity","memory","notes"],"budgets":{"memory":500,"notes":200},
+		"sections":[{"name":"notes","file":"notes/context.md"}]}`
+	if err := os.WriteFile(SharedPromptPath(), []byte(shared), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	layout, err := cfg.PromptLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(layout.Order, []string{"identity", "memory", "notes"}) || layout.Budgets["memory"] != 500 {
+		t.Errorf("shared layout = %+v", layout)
+	}
+
+	cfg.Agents.Prompt = PromptConfig{
+		Budgets:  map[string]int{"memory": 1000},
+		Sections: []PromptSection{{Name: "notes", File: "work/notes.md", Title: "Work notes"}, {Name: "projects", File: "PROJECTS.md"}},
+	}
+	layout, err = cfg.PromptLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Budgets["memory"] != 1000 || layout.Budgets["notes"] != 200 {
+		t.Errorf("budgets = %v", layout.Budgets)
+	}
+	if len(layout.Sections) != 2 || layout.Sections[0].File != "work/notes.md" || layout.Sections[1].Name != "projects" {
+		t.Errorf("sections = %+v", layout.Sections)
+	}
+	if len(layout.Order) != 3 {
+		t.Errorf("profile without an order should keep the shared one: %v", layout.Order)
+	}
+}
+
+func TestPromptConfigValidate(t *testing.T) {
+	bad := []PromptConfig{
+		{Order: []string{"identity", "soul"}},
+		{Order: []string{"memory", "memory"}},
+		{Budgets: map[string]int{"skills": -1}},
+		{Sections: []PromptSection{{Name: "memory", File: "x.md"}}},
+		{Sections: []PromptSection{{Name: "etc", File: "../../etc/passwd"}}},
+		{Sections: []PromptSection{{Name: "nofile"}}},
+	}
+	for _, p := range bad {
+		if p.Validate() == nil {
+			t.Errorf("%+v should be invalid", p)
+		}
+	}
+	ok := PromptConfig{Order: []string{"notes", "identity"}, Sections: []PromptSection{{Name: "notes", File: "notes.md"}}}
+	if err := ok.Validate(); err != nil {
+		t.Error(err)
+	}
+}
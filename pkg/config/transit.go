@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TransitConfig enables the transit tool: next departures from the user's
+// stops through Transitland or a GTFS-RT feed, and journey planning through
+// an OpenTripPlanner server. Departures from the briefing stop are added to
+// the first heartbeat of the morning.
+type TransitConfig struct {
+	Provider  string              `json:"provider"` // "transitland" or "gtfs_rt"
+	APIKeyEnv string              `json:"api_key_env,omitempty"`
+	FeedURL   string              `json:"feed_url,omitempty"` // GTFS-RT TripUpdates feed, for gtfs_rt
+	PlanURL   string              `json:"plan_url,omitempty"` // OpenTripPlanner plan endpoint, e.g. http://localhost:8080/otp/routers/default/plan
+	Stops     []TransitStopConfig `json:"stops"`
+	// BriefingStop names the stop whose departures go into the morning
+	// heartbeat, default the first; BriefingStart and BriefingEnd ("HH:MM")
+	// bound the morning, default 06:00-09:30.
+	BriefingStop    string `json:"briefing_stop,omitempty"`
+	BriefingStart   string `json:"briefing_start,omitempty"`
+	BriefingEnd     string `json:"briefing_end,omitempty"`
+	DisableBriefing bool   `json:"disable_briefing,omitempty"`
+}
+
+// TransitStopConfig is a stop the user departs from, e.g. "home" or "work".
+type TransitStopConfig struct {
+	Name   string   `json:"name"`
+	StopID string   `json:"stop_id"`          // Transitland stop key (onestop id) or GTFS stop_id
+	Routes []string `json:"routes,omitempty"` // only these routes (short names; GTFS route_id for gtfs_rt)
+	// Lat and Lon locate the stop for journey planning.
+	Lat float64 `json:"lat,omitempty"`
+	Lon float64 `json:"lon,omitempty"`
+}
+
+func (t TransitConfig) ResolveAPIKey() string {
+	if t.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(t.APIKeyEnv)
+}
+
+func (t TransitConfig) Validate() error {
+	switch t.Provider {
+	case "":
+		return nil
+	case "transitland":
+		if t.APIKeyEnv == "" {
+			return fmt.Errorf("transit: transitland needs api_key_env")
+		}
+	case "gtfs_rt":
+		if t.FeedURL == "" {
+			return fmt.Errorf("transit: gtfs_rt needs feed_url")
+		}
+	default:
+		return fmt.Errorf("transit: unknown provider %q (use transitland or gtfs_rt)", t.Provider)
+	}
+	if len(t.Stops) == 0 {
+		return fmt.Errorf("transit: at least one stop is required")
+	}
+	var names []string
+	for _, s := range t.Stops {
+		name := strings.ToLower(strings.TrimSpace(s.Name))
+		if name == "" {
+			return fmt.Errorf("transit: stop %q: name is required", s.StopID)
+		}
+		if s.StopID == "" {
+			return fmt.Errorf("transit: stop %q: stop_id is required", s.Name)
+		}
+		if slices.Contains(names, name) {
+			return fmt.Errorf("transit: duplicate stop name %q", s.Name)
+		}
+		names = append(names, name)
+	}
+	if t.BriefingStop != "" && !slices.Contains(names, strings.ToLower(t.BriefingStop)) {
+		return fmt.Errorf("transit: briefing_stop %q is not one of the stops", t.BriefingStop)
+	}
+	if _, _, err := t.BriefingHours(); err != nil {
+		return fmt.Errorf("transit: %v", err)
+	}
+	return nil
+}
+
+// BriefingHours returns the morning window as offsets from local midnight.
+func (t TransitConfig) BriefingHours() (start, end time.Duration, err error) {
+	if start, err = clockOffset(t.BriefingStart, "06:00"); err != nil {
+		return 0, 0, err
+	}
+	if end, err = clockOffset(t.BriefingEnd, "09:30"); err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// clockOffset parses "HH:MM" (def when s is empty) as an offset from midnight.
+func clockOffset(s, def string) (time.Duration, error) {
+	if s == "" {
+		s = def
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
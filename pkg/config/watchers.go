@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WatcherConfig watches a directory for new or changed files. A file
+// triggers once it has been unchanged for the debounce time, so downloads
+// and copies in progress are not picked up half written.
+type WatcherConfig struct {
+	Name      string   `json:"name,omitempty"`      // for logs and the agent session, default the directory name
+	Path      string   `json:"path"`                // directory; relative paths are inside the workspace
+	Patterns  []string `json:"patterns,omitempty"`  // file name globs, e.g. "*.pdf"; default all files
+	Ignore    []string `json:"ignore,omitempty"`    // file name globs to skip, on top of dotfiles and partial downloads
+	Recursive bool     `json:"recursive,omitempty"` // include subdirectories
+	// DebounceSeconds is how long a file must stay unchanged (default 5).
+	DebounceSeconds int `json:"debounce_seconds,omitempty"`
+	// Action is "event" (default: queue a heartbeat event) or "agent" (run
+	// Prompt as an agent turn right away).
+	Action string `json:"action,omitempty"`
+	// Prompt is the event text or agent instruction. {path}, {name} and
+	// {change} ("created" or "modified") are replaced. Default: a note
+	// that the file appeared or changed.
+	Prompt string `json:"prompt,omitempty"`
+	// Announce sends the agent's reply to the channel last used (agent
+	// action only); otherwise the workflow is expected to file its results.
+	Announce bool `json:"announce,omitempty"`
+}
+
+// WatcherActions lists the actions a watcher can take.
+var WatcherActions = []string{"event", "agent"}
+
+func (w WatcherConfig) Validate() error {
+	if strings.TrimSpace(w.Path) == "" {
+		return fmt.Errorf("watcher %q: path is required", w.Name)
+	}
+	if w.Action != "" && !slices.Contains(WatcherActions, w.Action) {
+		return fmt.Errorf("watcher %q: unknown action %q", w.Label(), w.Action)
+	}
+	if w.Action == "agent" && strings.TrimSpace(w.Prompt) == "" {
+		return fmt.Errorf("watcher %q: the agent action needs a prompt", w.Label())
+	}
+	if w.DebounceSeconds < 0 {
+		return fmt.Errorf("watcher %q: debounce_seconds must not be negative", w.Label())
+	}
+	for _, p := range slices.Concat(w.Patterns, w.Ignore) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("watcher %q: bad pattern %q", w.Label(), p)
+		}
+	}
+	return nil
+}
+
+// Label names the watcher in logs: Name, or the last element of Path.
+func (w WatcherConfig) Label() string {
+	if w.Name != "" {
+		return w.Name
+	}
+	return filepath.Base(filepath.Clean(w.Path))
+}
+
+// Dir resolves Path: "~" is the home directory and relative paths are
+// inside workspace.
+func (w WatcherConfig) Dir(workspace string) string {
+	p := expandHome(w.Path)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workspace, p)
+	}
+	return filepath.Clean(p)
+}
@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"encoding/json"
+	"os"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/migrate"
+	"localagent/pkg/vault"
+)
+
+// Migrations returns the on-disk format migrations for the job store at
+// storePath. The store's own Version field is kept in step with the
+// migrate schema version.
+func Migrations(storePath string) []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "stamp store version", Up: func() error {
+			return rewriteStore(storePath, func(s *CronStore) { s.Version = 1 })
+		}},
+	}
+}
+
+// rewriteStore applies fn to the store at path under the file lock and
+// writes it back. A missing store is left alone.
+func rewriteStore(path string, fn func(*CronStore)) error {
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	store, err := readStore(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fn(store)
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return vault.WriteFileAtomic(path, data, 0644)
+}
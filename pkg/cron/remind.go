@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/utils"
+	"localagent/pkg/when"
+)
+
+// reminderPrefixes open a reminder request; matching is case-insensitive.
+var reminderPrefixes = []string{"please remind me ", "remind me ", "can you remind me ", "could you remind me "}
+
+// timeStarts are words a trailing time expression may begin with, as in
+// "remind me to call Sam at 5pm".
+var timeStarts = []string{"in", "at", "on", "by", "tomorrow", "tonight", "today", "next", "this",
+	"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// ParseReminder recognizes "remind me <when> to <what>" and "remind me to
+// <what> <when>", e.g. "remind me in 20 minutes to check the oven". It only
+// succeeds when the time resolves to an exact instant after ref, so
+// requests without a time of day or with unusual phrasing are left to the
+// agent.
+func ParseReminder(text string, ref time.Time) (at time.Time, what string, ok bool) {
+	s := strings.TrimRight(strings.TrimSpace(text), ".!?")
+	lower := strings.ToLower(s)
+	rest := ""
+	for _, p := range reminderPrefixes {
+		if strings.HasPrefix(lower, p) {
+			rest = s[len(p):]
+			break
+		}
+	}
+	if rest == "" || strings.Contains(rest, "\n") {
+		return time.Time{}, "", false
+	}
+	words := strings.Fields(rest)
+
+	valid := func(expr, task string) bool {
+		task = strings.TrimSpace(task)
+		if task == "" {
+			return false
+		}
+		r, err := when.Parse(expr, ref)
+		if err != nil || r.DateOnly || !r.Time.After(ref) {
+			return false
+		}
+		at, what = r.Time, task
+		return true
+	}
+
+	if strings.EqualFold(words[0], "to") {
+		// "to <what> <when>": the earliest split whose tail is a time.
+		for i := 2; i < len(words); i++ {
+			if isTimeStart(words[i]) && valid(strings.Join(words[i:], " "), strings.Join(words[1:i], " ")) {
+				return at, what, true
+			}
+		}
+		return time.Time{}, "", false
+	}
+	// "<when> to <what>": the earliest "to" preceded by a time.
+	for i := 1; i < len(words)-1; i++ {
+		if strings.EqualFold(words[i], "to") && valid(strings.Join(words[:i], " "), strings.Join(words[i+1:], " ")) {
+			return at, what, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+func isTimeStart(word string) bool {
+	return slices.Contains(timeStarts, strings.ToLower(word))
+}
+
+// NewReminder returns a one-shot job that sends "Reminder: <text>" to the
+// chat at at, without running the agent.
+func NewReminder(text string, at time.Time, channel, chatID string) CronJob {
+	return CronJob{
+		Name:     "Reminder: " + utils.Truncate(text, 40),
+		Schedule: CronSchedule{Kind: "at", At: at.Format(time.RFC3339)},
+		Payload:  CronPayload{Kind: "message", Text: "Reminder: " + text},
+		Delivery: &CronDelivery{Mode: "announce", Channel: channel, To: chatID},
+	}
+}
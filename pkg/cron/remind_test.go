@@ -0,0 +1,55 @@
+package cron
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseReminder(t *testing.T) {
+	ref := time.Date(2026, 3, 10, 14, 0, 0, 0, time.UTC) // a Tuesday
+	cases := []struct {
+		in   string
+		at   time.Time
+		what string
+	}{
+		{"remind me in 20 minutes to check the oven", ref.Add(20 * time.Minute), "check the oven"},
+		{"Remind me to check in with Sam at 5pm.", time.Date(2026, 3, 10, 17, 0, 0, 0, time.UTC), "check in with Sam"},
+		{"please remind me tomorrow at 9am to call the dentist", time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), "call the dentist"},
+		{"remind me at 9am to stretch", time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), "stretch"}, // past today
+		{"can you remind me to go to the store in an hour?", ref.Add(time.Hour), "go to the store"},
+	}
+	for _, c := range cases {
+		at, what, ok := ParseReminder(c.in, ref)
+		if !ok || !at.Equal(c.at) || what != c.what {
+			t.Errorf("ParseReminder(%q) = %v, %q, %v", c.in, at, what, ok)
+		}
+	}
+
+	for _, in := range []string{
+		"remind me tomorrow to call the dentist", // no time of day
+		"remind me to water the plants",          // no time
+		"remind me in 20 minutes",                // nothing to remind
+		"remind me to water the plants in the morning",
+		"what did you remind me about?",
+	} {
+		if at, what, ok := ParseReminder(in, ref); ok {
+			t.Errorf("ParseReminder(%q) = %v, %q; want fallback to the agent", in, at, what)
+		}
+	}
+}
+
+func TestNewReminderIsValidJob(t *testing.T) {
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	job, err := cs.AddJob(NewReminder("take out laundry", at, "web", "default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !job.DeleteAfterRun || job.State.NextRunAtMS == nil || *job.State.NextRunAtMS != at.UnixMilli() {
+		t.Errorf("job = %+v", job)
+	}
+	if job.Payload.Text != "Reminder: take out laundry" || job.Delivery.To != "default" {
+		t.Errorf("payload = %+v, delivery = %+v", job.Payload, job.Delivery)
+	}
+}
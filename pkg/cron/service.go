@@ -10,8 +10,12 @@ import (
 
 	"github.com/adhocore/gronx"
 
+	"localagent/pkg/filelock"
 	"localagent/pkg/logger"
+	"localagent/pkg/storage"
 	"localagent/pkg/utils"
+	"localagent/pkg/vault"
+	"localagent/pkg/when"
 )
 
 var errorBackoffMS = []int64{30_000, 60_000, 300_000, 900_000, 3_600_000}
@@ -78,6 +82,12 @@ type CronJob struct {
 	CreatedAtMS    int64         `json:"createdAtMs"`
 	UpdatedAtMS    int64         `json:"updatedAtMs"`
 	DeleteAfterRun bool          `json:"deleteAfterRun"`
+	// Precise jobs fire on their own timer, to the millisecond and without
+	// waiting behind other due jobs; used for kitchen timers.
+	Precise bool `json:"precise,omitempty"`
+	// Role is the household role of the member who created the job; its
+	// agent turns run with that role. Empty means the owner.
+	Role string `json:"role,omitempty"`
 }
 
 type CronStore struct {
@@ -101,6 +111,8 @@ type CronService struct {
 	running   bool
 	stopChan  chan struct{}
 	gronx     *gronx.Gronx
+	storeMod  time.Time              // mtime of the store file when last read or written
+	armed     map[string]*time.Timer // precise jobs by ID
 }
 
 func NewCronService(storePath string, onJob JobHandler) *CronService {
@@ -108,6 +120,7 @@ func NewCronService(storePath string, onJob JobHandler) *CronService {
 		storePath: storePath,
 		onJob:     onJob,
 		gronx:     gronx.New(),
+		armed:     make(map[string]*time.Timer),
 	}
 	cs.loadStore()
 	return cs
@@ -121,6 +134,8 @@ func (cs *CronService) Start() error {
 		return nil
 	}
 
+	unlock := cs.lockStore()
+	defer unlock()
 	if err := cs.loadStore(); err != nil {
 		return fmt.Errorf("failed to load store: %w", err)
 	}
@@ -132,6 +147,9 @@ func (cs *CronService) Start() error {
 
 	cs.stopChan = make(chan struct{})
 	cs.running = true
+	for i := range cs.store.Jobs {
+		cs.arm(&cs.store.Jobs[i])
+	}
 	go cs.runLoop(cs.stopChan)
 
 	return nil
@@ -146,6 +164,10 @@ func (cs *CronService) Stop() {
 	}
 
 	cs.running = false
+	for id, t := range cs.armed {
+		t.Stop()
+		delete(cs.armed, id)
+	}
 	if cs.stopChan != nil {
 		close(cs.stopChan)
 		cs.stopChan = nil
@@ -174,16 +196,18 @@ func (cs *CronService) checkJobs() {
 		return
 	}
 
+	// Pick up jobs written by another process, then only take the file
+	// lock (and re-read under it) when something is actually due.
+	cs.refreshIfChanged()
 	now := time.Now().UnixMilli()
-	var dueJobIDs []string
-
-	for i := range cs.store.Jobs {
-		job := &cs.store.Jobs[i]
-		if job.Enabled && job.State.RunningAtMS == nil && job.State.NextRunAtMS != nil && *job.State.NextRunAtMS <= now {
-			dueJobIDs = append(dueJobIDs, job.ID)
-		}
+	if len(cs.dueJobIDs(now)) == 0 {
+		cs.mu.Unlock()
+		return
 	}
 
+	unlock := cs.lockStore()
+	dueJobIDs := cs.dueJobIDs(now)
+
 	dueMap := make(map[string]bool, len(dueJobIDs))
 	for _, jobID := range dueJobIDs {
 		dueMap[jobID] = true
@@ -196,10 +220,13 @@ func (cs *CronService) checkJobs() {
 		}
 	}
 
-	if err := cs.saveStoreUnsafe(); err != nil {
-		logger.Error("cron: failed to save store: %v", err)
+	if len(dueJobIDs) > 0 {
+		if err := cs.saveStoreUnsafe(); err != nil {
+			logger.Error("cron: failed to save store: %v", err)
+		}
 	}
 
+	unlock()
 	cs.mu.Unlock()
 
 	for _, jobID := range dueJobIDs {
@@ -207,6 +234,22 @@ func (cs *CronService) checkJobs() {
 	}
 }
 
+// dueJobIDs lists the jobs for the loop to run. Armed precise jobs are
+// left to their timers; ones another process added are caught here.
+func (cs *CronService) dueJobIDs(now int64) []string {
+	var ids []string
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if cs.armed[job.ID] != nil {
+			continue
+		}
+		if job.Enabled && job.State.RunningAtMS == nil && job.State.NextRunAtMS != nil && *job.State.NextRunAtMS <= now {
+			ids = append(ids, job.ID)
+		}
+	}
+	return ids
+}
+
 func (cs *CronService) executeJobByID(jobID string) {
 	startTime := time.Now().UnixMilli()
 
@@ -233,6 +276,8 @@ func (cs *CronService) executeJobByID(jobID string) {
 
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	unlock := cs.lockStore()
+	defer unlock()
 
 	var job *CronJob
 	for i := range cs.store.Jobs {
@@ -291,6 +336,7 @@ func (cs *CronService) executeJobByID(jobID string) {
 				logger.Warn("cron: job %s auto-disabled after %d schedule errors", job.ID, maxScheduleErrors)
 			}
 		}
+		cs.arm(job)
 	}
 
 	if err := cs.saveStoreUnsafe(); err != nil {
@@ -298,6 +344,65 @@ func (cs *CronService) executeJobByID(jobID string) {
 	}
 }
 
+// arm starts the timer of a precise job for its next run, replacing any
+// earlier one. Must be called with cs.mu held.
+func (cs *CronService) arm(job *CronJob) {
+	cs.disarm(job.ID)
+	if !cs.running || !job.Precise || !job.Enabled || job.State.NextRunAtMS == nil {
+		return
+	}
+	id := job.ID
+	d := time.Until(time.UnixMilli(*job.State.NextRunAtMS))
+	cs.armed[id] = time.AfterFunc(max(d, 0), func() { cs.fire(id) })
+}
+
+// disarm stops the timer of a precise job. Must be called with cs.mu held.
+func (cs *CronService) disarm(jobID string) {
+	if t := cs.armed[jobID]; t != nil {
+		t.Stop()
+		delete(cs.armed, jobID)
+	}
+}
+
+// fire runs a precise job when its timer goes off.
+func (cs *CronService) fire(jobID string) {
+	cs.mu.Lock()
+	delete(cs.armed, jobID)
+	if !cs.running {
+		cs.mu.Unlock()
+		return
+	}
+	unlock := cs.lockStore()
+	now := time.Now().UnixMilli()
+	due := false
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if job.ID != jobID {
+			continue
+		}
+		if job.Enabled && job.State.RunningAtMS == nil && job.State.NextRunAtMS != nil {
+			if *job.State.NextRunAtMS > now {
+				// Moved by another process; wait for the new time.
+				cs.arm(job)
+				break
+			}
+			job.State.NextRunAtMS = nil
+			job.State.RunningAtMS = &now
+			due = true
+			if err := cs.saveStoreUnsafe(); err != nil {
+				logger.Error("cron: failed to save store: %v", err)
+			}
+		}
+		break
+	}
+	unlock()
+	cs.mu.Unlock()
+
+	if due {
+		cs.executeJobByID(jobID)
+	}
+}
+
 func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int64 {
 	if schedule.Kind == "at" {
 		if schedule.At != "" {
@@ -340,7 +445,9 @@ func (cs *CronService) computeNextRun(schedule *CronSchedule, nowMS int64) *int6
 			return nil
 		}
 
-		now := time.UnixMilli(nowMS)
+		// Without a TZ, expressions follow the agent's timezone, which
+		// moves with the user in travel mode.
+		now := time.UnixMilli(nowMS).In(when.Location())
 		if schedule.TZ != "" {
 			loc, err := time.LoadLocation(schedule.TZ)
 			if err == nil {
@@ -375,6 +482,30 @@ func (cs *CronService) recomputeNextRuns() {
 	}
 }
 
+// Reschedule recomputes the next run of cron expressions without their own
+// TZ, e.g. after the agent's timezone changed.
+func (cs *CronService) Reschedule() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	unlock := cs.lockStore()
+	defer unlock()
+
+	now := time.Now().UnixMilli()
+	changed := false
+	for i := range cs.store.Jobs {
+		job := &cs.store.Jobs[i]
+		if !job.Enabled || job.State.RunningAtMS != nil || job.Schedule.Kind != "cron" || job.Schedule.TZ != "" {
+			continue
+		}
+		job.State.NextRunAtMS = cs.computeNextRun(&job.Schedule, now)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return cs.saveStoreUnsafe()
+}
+
 func (cs *CronService) Load() error {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
@@ -387,21 +518,73 @@ func (cs *CronService) SetOnJob(handler JobHandler) {
 	cs.onJob = handler
 }
 
+// loadStore reads the store from disk, falling back to the backup kept by
+// saveStoreUnsafe if the file is unreadable. On failure the previously
+// loaded jobs are kept, so a bad read never gets saved back as an empty
+// store.
 func (cs *CronService) loadStore() error {
-	cs.store = &CronStore{
-		Version: 1,
-		Jobs:    []CronJob{},
+	if cs.store == nil {
+		cs.store = &CronStore{Version: 1, Jobs: []CronJob{}}
 	}
 
-	data, err := os.ReadFile(cs.storePath)
+	if info, err := os.Stat(cs.storePath); err == nil {
+		cs.storeMod = info.ModTime()
+	}
+	store, err := readStore(cs.storePath)
+	if os.IsNotExist(err) {
+		cs.store = &CronStore{Version: 1, Jobs: []CronJob{}}
+		return nil
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
+		bak, bakErr := readStore(storage.BackupPath(cs.storePath))
+		if bakErr != nil {
+			return err
 		}
-		return err
+		logger.Warn("cron: %s unreadable (%v), recovered %d jobs from backup", cs.storePath, err, len(bak.Jobs))
+		store = bak
 	}
+	cs.store = store
+	return nil
+}
+
+func readStore(path string) (*CronStore, error) {
+	data, err := vault.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	store := &CronStore{Version: 1, Jobs: []CronJob{}}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// lockStore takes the cross-process lock on the store file and re-reads
+// the store, so the caller's changes are applied on top of whatever another
+// process (e.g. `localagent agent` next to the gateway) last wrote.
+// Must be called with cs.mu held.
+func (cs *CronService) lockStore() (unlock func()) {
+	unlock, err := filelock.Lock(cs.storePath)
+	if err != nil {
+		logger.Warn("cron: %v; writing without lock", err)
+		unlock = func() {}
+	}
+	if err := cs.loadStore(); err != nil {
+		logger.Error("cron: failed to reload store: %v", err)
+	}
+	return unlock
+}
 
-	return json.Unmarshal(data, cs.store)
+// refreshIfChanged re-reads the store when its mtime moved since we last
+// read or wrote it. Must be called with cs.mu held.
+func (cs *CronService) refreshIfChanged() {
+	info, err := os.Stat(cs.storePath)
+	if err != nil || info.ModTime().Equal(cs.storeMod) {
+		return
+	}
+	if err := cs.loadStore(); err != nil {
+		logger.Error("cron: failed to reload store: %v", err)
+	}
 }
 
 func (cs *CronService) saveStoreUnsafe() error {
@@ -415,12 +598,20 @@ func (cs *CronService) saveStoreUnsafe() error {
 		return err
 	}
 
-	return os.WriteFile(cs.storePath, data, 0644)
+	if err := vault.WriteFileAtomic(cs.storePath, data, 0644); err != nil {
+		return err
+	}
+	if info, err := os.Stat(cs.storePath); err == nil {
+		cs.storeMod = info.ModTime()
+	}
+	return nil
 }
 
 func (cs *CronService) AddJob(job CronJob) (*CronJob, error) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	unlock := cs.lockStore()
+	defer unlock()
 
 	now := time.Now().UnixMilli()
 
@@ -443,12 +634,16 @@ func (cs *CronService) AddJob(job CronJob) (*CronJob, error) {
 		return nil, err
 	}
 
-	return &cs.store.Jobs[len(cs.store.Jobs)-1], nil
+	added := &cs.store.Jobs[len(cs.store.Jobs)-1]
+	cs.arm(added)
+	return added, nil
 }
 
 func (cs *CronService) PatchJob(jobID string, patch map[string]any) (*CronJob, error) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	unlock := cs.lockStore()
+	defer unlock()
 
 	var job *CronJob
 	for i := range cs.store.Jobs {
@@ -522,12 +717,15 @@ func (cs *CronService) PatchJob(jobID string, patch map[string]any) (*CronJob, e
 		return nil, err
 	}
 
+	cs.arm(job)
 	return job, nil
 }
 
 func (cs *CronService) RemoveJob(jobID string) bool {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	unlock := cs.lockStore()
+	defer unlock()
 	return cs.removeJobUnsafe(jobID)
 }
 
@@ -541,6 +739,7 @@ func (cs *CronService) removeJobUnsafe(jobID string) bool {
 	}
 	cs.store.Jobs = jobs
 	removed := len(cs.store.Jobs) < before
+	cs.disarm(jobID)
 
 	if removed {
 		if err := cs.saveStoreUnsafe(); err != nil {
@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func everyMinute() CronJob {
+	every := int64(60_000)
+	return CronJob{
+		Name:     "ping",
+		Schedule: CronSchedule{Kind: "every", EveryMS: &every},
+		Payload:  CronPayload{Kind: "agentTurn", Message: "ping"},
+	}
+}
+
+func TestConcurrentServicesShareStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron", "jobs.json")
+	gateway := NewCronService(path, nil)
+	cli := NewCronService(path, nil)
+
+	a, err := gateway.AddJob(everyMinute())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cli.AddJob(everyMinute())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The CLI's write must not drop the gateway's job, and vice versa.
+	if _, err := gateway.PatchJob(b.ID, map[string]any{"name": "renamed"}); err != nil {
+		t.Fatalf("gateway should see the job added by the other process: %v", err)
+	}
+	jobs := NewCronService(path, nil).ListJobs(true)
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs on disk, got %d", len(jobs))
+	}
+	for _, j := range jobs {
+		if j.ID == b.ID && j.Name != "renamed" {
+			t.Errorf("patch lost: %+v", j)
+		}
+	}
+
+	if !cli.RemoveJob(a.ID) {
+		t.Fatal("cli should see the job added by the gateway")
+	}
+	if got := NewCronService(path, nil).ListJobs(true); len(got) != 1 || got[0].ID != b.ID {
+		t.Fatalf("unexpected jobs after remove: %+v", got)
+	}
+}
+
+func TestLoadRecoversFromBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	cs := NewCronService(path, nil)
+	job, err := cs.AddJob(everyMinute())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.PatchJob(job.ID, map[string]any{"name": "second save"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a torn write of the main file.
+	os.WriteFile(path, []byte(`{"version":1,"jobs":[{"id"`), 0644)
+
+	jobs := NewCronService(path, nil).ListJobs(true)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("expected job recovered from backup, got %+v", jobs)
+	}
+}
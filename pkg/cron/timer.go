@@ -0,0 +1,53 @@
+package cron
+
+import (
+	"strings"
+	"time"
+)
+
+// timerPrefix starts the name of timer jobs, which tells them apart from
+// other jobs.
+const timerPrefix = "Timer: "
+
+// NewTimer returns a precise job that tells the chat when d has passed,
+// once or, with repeat, every d. Its "immediate" delivery skips the
+// do-not-disturb outbox: the user asked for the ring.
+func NewTimer(label string, d time.Duration, repeat bool, channel, chatID string, now time.Time) CronJob {
+	job := CronJob{
+		Name:     timerPrefix + label,
+		Payload:  CronPayload{Kind: "message", Text: "Timer done: " + label + " (" + ShortDuration(d) + ")"},
+		Delivery: &CronDelivery{Mode: "immediate", Channel: channel, To: chatID},
+		Precise:  true,
+	}
+	if repeat {
+		every, anchor := d.Milliseconds(), now.Add(d).UnixMilli()
+		job.Schedule = CronSchedule{Kind: "every", EveryMS: &every, AnchorMS: &anchor}
+		job.Payload.Text = "Timer: " + label + " (every " + ShortDuration(d) + ")"
+	} else {
+		job.Schedule = CronSchedule{Kind: "at", At: now.Add(d).Format(time.RFC3339Nano)}
+	}
+	return job
+}
+
+// IsTimer reports whether job was made by NewTimer.
+func IsTimer(job *CronJob) bool {
+	return job.Precise && strings.HasPrefix(job.Name, timerPrefix)
+}
+
+// TimerLabel is the label a timer job was started with.
+func TimerLabel(job *CronJob) string {
+	return strings.TrimPrefix(job.Name, timerPrefix)
+}
+
+// ShortDuration formats d to the second without trailing zero units,
+// e.g. "5m" or "1h30m" rather than "5m0s" or "1h30m0s".
+func ShortDuration(d time.Duration) string {
+	s := d.Round(time.Second).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
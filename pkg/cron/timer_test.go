@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimerFiresToTheSecond(t *testing.T) {
+	fired := make(chan *CronJob, 1)
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		fired <- job
+		return "ok", nil
+	})
+	if err := cs.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Stop()
+
+	start := time.Now()
+	if _, err := cs.AddJob(NewTimer("tea", 300*time.Millisecond, false, "web", "default", start)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case job := <-fired:
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 900*time.Millisecond {
+			t.Errorf("fired after %v", elapsed)
+		}
+		if !IsTimer(job) || TimerLabel(job) != "tea" || job.Payload.Text != "Timer done: tea (0s)" {
+			t.Errorf("job = %+v", job)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timer did not fire")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(cs.ListJobs(true)) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if jobs := cs.ListJobs(true); len(jobs) != 0 {
+		t.Errorf("one-shot timer left behind: %+v", jobs)
+	}
+}
+
+func TestRemovedTimerDoesNotFire(t *testing.T) {
+	fired := make(chan struct{}, 1)
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(*CronJob) (string, error) {
+		fired <- struct{}{}
+		return "ok", nil
+	})
+	if err := cs.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Stop()
+
+	job, err := cs.AddJob(NewTimer("eggs", 200*time.Millisecond, true, "web", "default", time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.RemoveJob(job.ID)
+	select {
+	case <-fired:
+		t.Fatal("removed timer fired")
+	case <-time.After(1500 * time.Millisecond):
+	}
+}
+
+func TestShortDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		5 * time.Minute:           "5m",
+		90 * time.Minute:          "1h30m",
+		2 * time.Hour:             "2h",
+		90 * time.Second:          "1m30s",
+		1500 * time.Millisecond:   "2s",
+		time.Hour + 5*time.Second: "1h0m5s",
+	} {
+		if got := ShortDuration(d); got != want {
+			t.Errorf("ShortDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
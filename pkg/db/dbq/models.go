@@ -17,6 +17,33 @@ type Block struct {
 	CreatedAtMs int64  `json:"createdAtMs"`
 }
 
+type Flashcard struct {
+	ID           string  `json:"id"`
+	Deck         string  `json:"deck"`
+	Front        string  `json:"front"`
+	Back         string  `json:"back"`
+	Ease         float64 `json:"ease"`
+	IntervalDays int64   `json:"intervalDays"`
+	Reps         int64   `json:"reps"`
+	Lapses       int64   `json:"lapses"`
+	DueAtMs      int64   `json:"dueAtMs"`
+	ReviewedAtMs int64   `json:"reviewedAtMs"`
+	CreatedAtMs  int64   `json:"createdAtMs"`
+}
+
+type Goal struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	Status       string `json:"status"`
+	TargetDate   string `json:"targetDate"`
+	KeyResults   string `json:"keyResults"`
+	Tag          string `json:"tag"`
+	ProgressAtMs int64  `json:"progressAtMs"`
+	CreatedAtMs  int64  `json:"createdAtMs"`
+	UpdatedAtMs  int64  `json:"updatedAtMs"`
+}
+
 type Link struct {
 	ID          string `json:"id"`
 	Url         string `json:"url"`
@@ -27,6 +54,52 @@ type Link struct {
 	UpdatedAtMs int64  `json:"updatedAtMs"`
 }
 
+type MealPlan struct {
+	Week        string `json:"week"`
+	Meals       string `json:"meals"`
+	UpdatedAtMs int64  `json:"updatedAtMs"`
+}
+
+type Medication struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Dosage             string `json:"dosage"`
+	Times              string `json:"times"`
+	Days               string `json:"days"`
+	Stock              int64  `json:"stock"`
+	DoseUnits          int64  `json:"doseUnits"`
+	Notes              string `json:"notes"`
+	Channel            string `json:"channel"`
+	ChatID             string `json:"chatId"`
+	Active             int64  `json:"active"`
+	LowStockWarnedAtMs int64  `json:"lowStockWarnedAtMs"`
+	CreatedAtMs        int64  `json:"createdAtMs"`
+	UpdatedAtMs        int64  `json:"updatedAtMs"`
+}
+
+type MedicationDose struct {
+	ID            string `json:"id"`
+	MedicationID  string `json:"medicationId"`
+	ScheduledAtMs int64  `json:"scheduledAtMs"`
+	Status        string `json:"status"`
+	Channel       string `json:"channel"`
+	ChatID        string `json:"chatId"`
+	RespondedAtMs int64  `json:"respondedAtMs"`
+}
+
+type Recipe struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	SourceUrl   string `json:"sourceUrl"`
+	Servings    int64  `json:"servings"`
+	Ingredients string `json:"ingredients"`
+	Steps       string `json:"steps"`
+	Tags        string `json:"tags"`
+	Notes       string `json:"notes"`
+	CreatedAtMs int64  `json:"createdAtMs"`
+	UpdatedAtMs int64  `json:"updatedAtMs"`
+}
+
 type Task struct {
 	ID          string        `json:"id"`
 	Title       string        `json:"title"`
@@ -43,3 +116,19 @@ type Task struct {
 	UpdatedAtMs int64         `json:"updatedAtMs"`
 	DoneAtMs    sql.NullInt64 `json:"doneAtMs"`
 }
+
+type Trip struct {
+	ID          string  `json:"id"`
+	Destination string  `json:"destination"`
+	Timezone    string  `json:"timezone"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	StartAtMs   int64   `json:"startAtMs"`
+	EndAtMs     int64   `json:"endAtMs"`
+	Source      string  `json:"source"`
+	CalendarUid string  `json:"calendarUid"`
+	Itinerary   string  `json:"itinerary"`
+	Notes       string  `json:"notes"`
+	CreatedAtMs int64   `json:"createdAtMs"`
+	UpdatedAtMs int64   `json:"updatedAtMs"`
+}
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"os"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/migrate"
 )
 
 type jsonTaskStore struct {
@@ -28,8 +31,19 @@ type jsonTask struct {
 }
 
 // MigrateFromJSON reads the old JSON task file, inserts rows into SQLite,
-// and renames the JSON file to .bak. Safe to call if the file doesn't exist.
+// and renames the JSON file to .bak. Safe to call if the file doesn't exist,
+// and from two processes at once: the file lock lets only one migrate.
+// The database itself needs no extra locking (WAL with a busy timeout).
 func MigrateFromJSON(database *sql.DB, jsonPath string) error {
+	if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
+		return nil
+	}
+	unlock, err := filelock.Lock(jsonPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	data, err := os.ReadFile(jsonPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -92,3 +106,21 @@ func convertLegacyRecurrence(r string) string {
 		return r
 	}
 }
+
+// Migrations returns the file migrations for the todo store. Schema changes
+// inside the database go in migrations (see Migrate) instead.
+func Migrations(dbPath, jsonPath string) []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "import tasks.json", Up: func() error {
+			if _, err := os.Stat(jsonPath); os.IsNotExist(err) {
+				return nil
+			}
+			database, err := Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			return MigrateFromJSON(database, jsonPath)
+		}},
+	}
+}
@@ -16,6 +16,11 @@ var migrations = []migration{
 	{3, migrateCreateLinks},
 	{4, migrateBackfillTaskOrder},
 	{5, migrateAddReminders},
+	{6, migrateCreateGoals},
+	{7, migrateCreateFlashcards},
+	{8, migrateCreateRecipes},
+	{9, migrateCreateMedications},
+	{10, migrateCreateTrips},
 }
 
 func Migrate(db *sql.DB) error {
@@ -132,3 +137,124 @@ func migrateCreateBlocks(tx *sql.Tx) error {
 	_, err = tx.Exec(`CREATE INDEX idx_blocks_range ON blocks(start_at_ms, end_at_ms)`)
 	return err
 }
+
+func migrateCreateGoals(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE goals (
+		id             TEXT PRIMARY KEY,
+		title          TEXT NOT NULL,
+		description    TEXT NOT NULL DEFAULT '',
+		status         TEXT NOT NULL DEFAULT 'active',
+		target_date    TEXT NOT NULL DEFAULT '',
+		key_results    TEXT NOT NULL DEFAULT '[]',
+		tag            TEXT NOT NULL DEFAULT '',
+		progress_at_ms INTEGER NOT NULL,
+		created_at_ms  INTEGER NOT NULL,
+		updated_at_ms  INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX idx_goals_status ON goals(status)`)
+	return err
+}
+
+func migrateCreateFlashcards(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE flashcards (
+		id             TEXT PRIMARY KEY,
+		deck           TEXT NOT NULL DEFAULT '',
+		front          TEXT NOT NULL,
+		back           TEXT NOT NULL,
+		ease           REAL NOT NULL DEFAULT 2.5,
+		interval_days  INTEGER NOT NULL DEFAULT 0,
+		reps           INTEGER NOT NULL DEFAULT 0,
+		lapses         INTEGER NOT NULL DEFAULT 0,
+		due_at_ms      INTEGER NOT NULL,
+		reviewed_at_ms INTEGER NOT NULL DEFAULT 0,
+		created_at_ms  INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX idx_flashcards_due ON flashcards(due_at_ms)`)
+	return err
+}
+
+func migrateCreateRecipes(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE recipes (
+		id            TEXT PRIMARY KEY,
+		title         TEXT NOT NULL,
+		source_url    TEXT NOT NULL DEFAULT '',
+		servings      INTEGER NOT NULL DEFAULT 0,
+		ingredients   TEXT NOT NULL DEFAULT '[]',
+		steps         TEXT NOT NULL DEFAULT '[]',
+		tags          TEXT NOT NULL DEFAULT '[]',
+		notes         TEXT NOT NULL DEFAULT '',
+		created_at_ms INTEGER NOT NULL,
+		updated_at_ms INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE TABLE meal_plans (
+		week          TEXT PRIMARY KEY,
+		meals         TEXT NOT NULL DEFAULT '[]',
+		updated_at_ms INTEGER NOT NULL
+	)`)
+	return err
+}
+
+func migrateCreateMedications(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE medications (
+		id                     TEXT PRIMARY KEY,
+		name                   TEXT NOT NULL,
+		dosage                 TEXT NOT NULL DEFAULT '',
+		times                  TEXT NOT NULL DEFAULT '[]',
+		days                   TEXT NOT NULL DEFAULT '[]',
+		stock                  INTEGER NOT NULL DEFAULT -1,
+		dose_units             INTEGER NOT NULL DEFAULT 1,
+		notes                  TEXT NOT NULL DEFAULT '',
+		channel                TEXT NOT NULL DEFAULT '',
+		chat_id                TEXT NOT NULL DEFAULT '',
+		active                 INTEGER NOT NULL DEFAULT 1,
+		low_stock_warned_at_ms INTEGER NOT NULL DEFAULT 0,
+		created_at_ms          INTEGER NOT NULL,
+		updated_at_ms          INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE TABLE medication_doses (
+		id              TEXT PRIMARY KEY,
+		medication_id   TEXT NOT NULL REFERENCES medications(id) ON DELETE CASCADE,
+		scheduled_at_ms INTEGER NOT NULL,
+		status          TEXT NOT NULL DEFAULT 'pending',
+		channel         TEXT NOT NULL DEFAULT '',
+		chat_id         TEXT NOT NULL DEFAULT '',
+		responded_at_ms INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (medication_id, scheduled_at_ms)
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE INDEX idx_medication_doses_status ON medication_doses(status)`)
+	return err
+}
+
+func migrateCreateTrips(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE trips (
+		id            TEXT PRIMARY KEY,
+		destination   TEXT NOT NULL,
+		timezone      TEXT NOT NULL DEFAULT '',
+		latitude      REAL NOT NULL DEFAULT 0,
+		longitude     REAL NOT NULL DEFAULT 0,
+		start_at_ms   INTEGER NOT NULL,
+		end_at_ms     INTEGER NOT NULL DEFAULT 0,
+		source        TEXT NOT NULL DEFAULT 'manual',
+		calendar_uid  TEXT NOT NULL DEFAULT '',
+		itinerary     TEXT NOT NULL DEFAULT '[]',
+		notes         TEXT NOT NULL DEFAULT '',
+		created_at_ms INTEGER NOT NULL,
+		updated_at_ms INTEGER NOT NULL
+	)`)
+	return err
+}
@@ -0,0 +1,18 @@
+-- name: ListFlashcards :many
+SELECT * FROM flashcards ORDER BY deck, created_at_ms;
+
+-- name: ListDueFlashcards :many
+SELECT * FROM flashcards WHERE due_at_ms <= ? ORDER BY due_at_ms, created_at_ms;
+
+-- name: GetFlashcard :one
+SELECT * FROM flashcards WHERE id = ?;
+
+-- name: InsertFlashcard :exec
+INSERT INTO flashcards (id, deck, front, back, ease, interval_days, reps, lapses, due_at_ms, reviewed_at_ms, created_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateFlashcardSchedule :exec
+UPDATE flashcards SET ease=?, interval_days=?, reps=?, lapses=?, due_at_ms=?, reviewed_at_ms=? WHERE id=?;
+
+-- name: DeleteFlashcard :execresult
+DELETE FROM flashcards WHERE id = ?;
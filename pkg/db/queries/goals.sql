@@ -0,0 +1,15 @@
+-- name: ListGoals :many
+SELECT * FROM goals ORDER BY (status != 'active'), CASE target_date WHEN '' THEN 1 ELSE 0 END, target_date, created_at_ms;
+
+-- name: GetGoal :one
+SELECT * FROM goals WHERE id = ?;
+
+-- name: InsertGoal :exec
+INSERT INTO goals (id, title, description, status, target_date, key_results, tag, progress_at_ms, created_at_ms, updated_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateGoal :exec
+UPDATE goals SET title=?, description=?, status=?, target_date=?, key_results=?, tag=?, progress_at_ms=?, updated_at_ms=? WHERE id=?;
+
+-- name: DeleteGoal :execresult
+DELETE FROM goals WHERE id = ?;
@@ -0,0 +1,28 @@
+-- name: ListMedications :many
+SELECT * FROM medications ORDER BY name COLLATE NOCASE;
+
+-- name: GetMedication :one
+SELECT * FROM medications WHERE id = ?;
+
+-- name: InsertMedication :exec
+INSERT INTO medications (id, name, dosage, times, days, stock, dose_units, notes, channel, chat_id, active, low_stock_warned_at_ms, created_at_ms, updated_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateMedication :exec
+UPDATE medications SET name=?, dosage=?, times=?, days=?, stock=?, dose_units=?, notes=?, channel=?, chat_id=?, active=?, low_stock_warned_at_ms=?, updated_at_ms=? WHERE id=?;
+
+-- name: DeleteMedication :execresult
+DELETE FROM medications WHERE id = ?;
+
+-- name: InsertMedicationDose :execresult
+INSERT OR IGNORE INTO medication_doses (id, medication_id, scheduled_at_ms, status, channel, chat_id, responded_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListPendingMedicationDoses :many
+SELECT * FROM medication_doses WHERE status = 'pending' ORDER BY scheduled_at_ms;
+
+-- name: ListMedicationDosesSince :many
+SELECT * FROM medication_doses WHERE scheduled_at_ms >= ? ORDER BY scheduled_at_ms;
+
+-- name: UpdateMedicationDoseStatus :exec
+UPDATE medication_doses SET status=?, responded_at_ms=? WHERE id=?;
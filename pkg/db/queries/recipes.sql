@@ -0,0 +1,25 @@
+-- name: ListRecipes :many
+SELECT * FROM recipes ORDER BY title COLLATE NOCASE;
+
+-- name: GetRecipe :one
+SELECT * FROM recipes WHERE id = ?;
+
+-- name: InsertRecipe :exec
+INSERT INTO recipes (id, title, source_url, servings, ingredients, steps, tags, notes, created_at_ms, updated_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateRecipe :exec
+UPDATE recipes SET title=?, source_url=?, servings=?, ingredients=?, steps=?, tags=?, notes=?, updated_at_ms=? WHERE id=?;
+
+-- name: DeleteRecipe :execresult
+DELETE FROM recipes WHERE id = ?;
+
+-- name: GetMealPlan :one
+SELECT * FROM meal_plans WHERE week = ?;
+
+-- name: UpsertMealPlan :exec
+INSERT INTO meal_plans (week, meals, updated_at_ms) VALUES (?, ?, ?)
+ON CONFLICT (week) DO UPDATE SET meals = excluded.meals, updated_at_ms = excluded.updated_at_ms;
+
+-- name: DeleteMealPlan :execresult
+DELETE FROM meal_plans WHERE week = ?;
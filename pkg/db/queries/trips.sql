@@ -0,0 +1,18 @@
+-- name: ListTrips :many
+SELECT * FROM trips ORDER BY start_at_ms;
+
+-- name: GetTrip :one
+SELECT * FROM trips WHERE id = ?;
+
+-- name: GetTripByCalendarUID :one
+SELECT * FROM trips WHERE calendar_uid = ? AND calendar_uid != '';
+
+-- name: InsertTrip :exec
+INSERT INTO trips (id, destination, timezone, latitude, longitude, start_at_ms, end_at_ms, source, calendar_uid, itinerary, notes, created_at_ms, updated_at_ms)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: UpdateTrip :exec
+UPDATE trips SET destination=?, timezone=?, latitude=?, longitude=?, start_at_ms=?, end_at_ms=?, itinerary=?, notes=?, updated_at_ms=? WHERE id=?;
+
+-- name: DeleteTrip :execresult
+DELETE FROM trips WHERE id = ?;
@@ -0,0 +1,213 @@
+// Package dnd is do-not-disturb: while a busy calendar event is in
+// progress or the user has turned on focus, non-urgent proactive messages
+// are held and delivered when it ends.
+package dnd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/i18n"
+	"localagent/pkg/logger"
+)
+
+// checkTTL is how long a busy lookup is reused. Meetings are coarse, and
+// the heartbeat and outbox may ask often.
+const checkTTL = time.Minute
+
+// BusyFunc reports whether the user is busy at now and until when.
+type BusyFunc func(ctx context.Context, now time.Time) (until time.Time, busy bool, err error)
+
+// Checker caches a BusyFunc. Lookup errors count as not busy, so a broken
+// calendar never holds messages back.
+type Checker struct {
+	fn BusyFunc
+
+	mu      sync.Mutex
+	checked time.Time
+	until   time.Time
+	busy    bool
+}
+
+func NewChecker(fn BusyFunc) *Checker {
+	return &Checker{fn: fn}
+}
+
+// Busy reports whether the user is busy at now and when that ends.
+func (c *Checker) Busy(now time.Time) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.busy && !now.Before(c.until) {
+		c.checked = time.Time{} // the meeting ended; look again
+	}
+	if now.Sub(c.checked) < checkTTL && !now.Before(c.checked) {
+		return c.until, c.busy
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	until, busy, err := c.fn(ctx, now)
+	if err != nil {
+		logger.Warn("do not disturb: calendar lookup failed: %v", err)
+		until, busy = time.Time{}, false
+	}
+	c.checked, c.until, c.busy = now, until, busy
+	return until, busy
+}
+
+// Outbox publishes messages, holding them while the user is busy. Held
+// messages are kept in memory and delivered in order when the busy period
+// ends, or as one digest per chat if any were held during focus.
+type Outbox struct {
+	checker *Checker // nil without a calendar
+	focus   *Focus
+	bus     *bus.MessageBus
+	now     func() time.Time
+
+	mu     sync.Mutex
+	held   []bus.OutboundMessage
+	digest bool // something was held during focus
+	timer  *time.Timer
+}
+
+func NewOutbox(checker *Checker, msgBus *bus.MessageBus) *Outbox {
+	return &Outbox{checker: checker, bus: msgBus, now: time.Now}
+}
+
+// SetFocus also holds messages while f is on, and delivers them as soon
+// as it ends.
+func (o *Outbox) SetFocus(f *Focus) {
+	o.mu.Lock()
+	o.focus = f
+	o.mu.Unlock()
+	f.OnChange(func(st FocusStatus) {
+		if !st.Active {
+			o.resume()
+		}
+	})
+}
+
+// busy reports whether messages are held at now, until when, and whether
+// focus is the reason. The caller holds mu.
+func (o *Outbox) busy(now time.Time) (until time.Time, busy, focus bool) {
+	if o.focus != nil {
+		until, focus = o.focus.Busy(now)
+	}
+	if o.checker != nil {
+		if end, meeting := o.checker.Busy(now); meeting {
+			busy = true
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	return until, busy || focus, focus
+}
+
+// Busy reports whether messages are being held at now, for a calendar
+// event or focus, and until when.
+func (o *Outbox) Busy(now time.Time) (time.Time, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	until, busy, _ := o.busy(now)
+	return until, busy
+}
+
+// Deliver publishes msg now, or holds it until the current busy event ends.
+func (o *Outbox) Deliver(msg bus.OutboundMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	until, busy, focus := o.busy(o.now())
+	if !busy && len(o.held) == 0 {
+		o.bus.PublishOutbound(msg)
+		return
+	}
+	o.held = append(o.held, msg)
+	o.digest = o.digest || focus
+	if busy {
+		logger.Info("do not disturb: holding message for %s:%s until %s", msg.Channel, msg.ChatID, until.Format("15:04"))
+	}
+	o.schedule(until)
+}
+
+// flush delivers held messages unless the user is still busy.
+func (o *Outbox) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.timer = nil
+	if until, busy, _ := o.busy(o.now()); busy {
+		o.schedule(until)
+		return
+	}
+	held := o.held
+	if o.digest {
+		held = digests(held)
+	}
+	for _, msg := range held {
+		o.bus.PublishOutbound(msg)
+	}
+	if len(o.held) > 0 {
+		logger.Info("do not disturb: delivered %d held messages", len(o.held))
+	}
+	o.held, o.digest = nil, false
+}
+
+// resume flushes right away instead of waiting for the scheduled time,
+// e.g. when focus is ended early.
+func (o *Outbox) resume() {
+	o.mu.Lock()
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.mu.Unlock()
+	o.flush()
+}
+
+// digests combines held messages into one per chat, in order of each
+// chat's first message.
+func digests(held []bus.OutboundMessage) []bus.OutboundMessage {
+	var out []bus.OutboundMessage
+	byChat := make(map[string][]string)
+	for _, msg := range held {
+		key := msg.Channel + ":" + msg.ChatID
+		if _, ok := byChat[key]; !ok {
+			out = append(out, bus.OutboundMessage{Channel: msg.Channel, ChatID: msg.ChatID})
+		}
+		byChat[key] = append(byChat[key], msg.Content)
+	}
+	for i, msg := range out {
+		contents := byChat[msg.Channel+":"+msg.ChatID]
+		out[i].Content = i18n.T("focus.digest", len(contents)) + "\n\n" + strings.Join(contents, "\n\n---\n\n")
+	}
+	return out
+}
+
+// schedule arranges a flush at until (or right away if it has passed).
+// Callers hold mu.
+func (o *Outbox) schedule(until time.Time) {
+	if o.timer != nil {
+		return
+	}
+	o.timer = time.AfterFunc(max(until.Sub(o.now()), 0), o.flush)
+}
+
+// Stop cancels a pending flush and delivers held messages right away.
+func (o *Outbox) Stop() {
+	o.mu.Lock()
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	held := o.held
+	if o.digest {
+		held = digests(held)
+	}
+	o.held, o.digest = nil, false
+	o.mu.Unlock()
+	for _, msg := range held {
+		o.bus.PublishOutbound(msg)
+	}
+}
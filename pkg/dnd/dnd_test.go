@@ -0,0 +1,72 @@
+package dnd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+)
+
+func TestCheckerCachesAndFailsOpen(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 15, 0, 0, time.UTC)
+	end := now.Add(30 * time.Minute)
+	calls := 0
+	var fail bool
+	c := NewChecker(func(_ context.Context, at time.Time) (time.Time, bool, error) {
+		calls++
+		if fail {
+			return time.Time{}, false, errors.New("caldav down")
+		}
+		return end, at.Before(end), nil
+	})
+
+	if until, busy := c.Busy(now); !busy || !until.Equal(end) {
+		t.Fatalf("busy = %v until %v", busy, until)
+	}
+	c.Busy(now.Add(30 * time.Second))
+	if calls != 1 {
+		t.Errorf("lookups within a minute should be cached, got %d calls", calls)
+	}
+	if _, busy := c.Busy(end); busy || calls != 2 {
+		t.Errorf("meeting end should trigger a fresh lookup: busy=%v calls=%d", busy, calls)
+	}
+
+	fail = true
+	if _, busy := c.Busy(end.Add(2 * time.Minute)); busy {
+		t.Error("lookup errors must not hold messages")
+	}
+}
+
+func TestOutboxHoldsUntilMeetingEnds(t *testing.T) {
+	end := time.Now().Add(100 * time.Millisecond)
+	c := NewChecker(func(_ context.Context, at time.Time) (time.Time, bool, error) {
+		return end, at.Before(end), nil
+	})
+	msgBus := bus.NewMessageBus()
+	o := NewOutbox(c, msgBus)
+
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "first"})
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "second"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	if _, ok := msgBus.SubscribeOutbound(ctx); ok {
+		t.Fatal("message delivered during the meeting")
+	}
+	cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, want := range []string{"first", "second"} {
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		if !ok || msg.Content != want {
+			t.Fatalf("got %q (ok=%v), want %q", msg.Content, ok, want)
+		}
+	}
+
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "after"})
+	if msg, ok := msgBus.SubscribeOutbound(ctx); !ok || msg.Content != "after" {
+		t.Errorf("after the meeting messages go out directly, got %q", msg.Content)
+	}
+}
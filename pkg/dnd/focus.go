@@ -0,0 +1,144 @@
+package dnd
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+// focusNamespace holds the current focus window in the state store, so it
+// survives a restart.
+const focusNamespace = "focus"
+
+// maxFocus bounds a single focus window.
+const maxFocus = 12 * time.Hour
+
+// FocusStatus is a focus window the user started. The zero value means no
+// focus.
+type FocusStatus struct {
+	Active  bool      `json:"active"`
+	Started time.Time `json:"started,omitzero"`
+	Until   time.Time `json:"until,omitzero"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// Focus is do-not-disturb for a time the user picks ("focus for 45
+// minutes"). While it is on, non-urgent proactive messages are held like
+// during a busy calendar event, and delivered as a digest when it ends.
+type Focus struct {
+	state *state.Manager
+	now   func() time.Time
+
+	mu        sync.Mutex
+	status    FocusStatus
+	timer     *time.Timer
+	listeners []func(FocusStatus)
+}
+
+func NewFocus(workspace string) *Focus {
+	f := &Focus{state: state.NewManager(workspace), now: time.Now}
+	if _, err := f.state.Get(focusNamespace, "current", &f.status); err != nil {
+		logger.Warn("focus: %v", err)
+	}
+	if f.status.Active {
+		f.arm()
+	}
+	return f
+}
+
+// OnChange registers fn to be told when focus starts or ends, including
+// when a window runs out.
+func (f *Focus) OnChange(fn func(FocusStatus)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listeners = append(f.listeners, fn)
+}
+
+// Start turns focus on for d, replacing any current window.
+func (f *Focus) Start(d time.Duration, reason string) (FocusStatus, error) {
+	if d <= 0 || d > maxFocus {
+		return FocusStatus{}, fmt.Errorf("focus duration must be between 1 minute and %v", maxFocus)
+	}
+	now := f.now()
+	f.mu.Lock()
+	f.status = FocusStatus{Active: true, Started: now, Until: now.Add(d).Truncate(time.Second), Reason: reason}
+	st := f.status
+	err := f.save()
+	f.arm()
+	f.mu.Unlock()
+
+	logger.Info("focus: on until %s", st.Until.Format("15:04"))
+	f.notify(st)
+	return st, err
+}
+
+// End turns focus off. It reports whether focus was on.
+func (f *Focus) End() (bool, error) {
+	f.mu.Lock()
+	if !f.status.Active {
+		f.mu.Unlock()
+		return false, nil
+	}
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.status = FocusStatus{}
+	err := f.save()
+	f.mu.Unlock()
+
+	logger.Info("focus: off")
+	f.notify(FocusStatus{})
+	return true, err
+}
+
+// Status returns the current focus window, ending it if it ran out.
+func (f *Focus) Status() FocusStatus {
+	f.mu.Lock()
+	st := f.status
+	f.mu.Unlock()
+	if st.Active && !f.now().Before(st.Until) {
+		f.End()
+		return FocusStatus{}
+	}
+	return st
+}
+
+// Busy reports whether focus is on at now and until when, so Focus can
+// stand in for a calendar Checker.
+func (f *Focus) Busy(now time.Time) (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.status.Active || !now.Before(f.status.Until) {
+		return time.Time{}, false
+	}
+	return f.status.Until, true
+}
+
+// arm schedules the end of the current window. The caller holds f.mu.
+func (f *Focus) arm() {
+	if f.timer != nil {
+		f.timer.Stop()
+	}
+	f.timer = time.AfterFunc(max(f.status.Until.Sub(f.now()), 0), func() { f.Status() })
+}
+
+// save persists the status. The caller holds f.mu.
+func (f *Focus) save() error {
+	if !f.status.Active {
+		return f.state.Delete(focusNamespace, "current")
+	}
+	return f.state.Set(focusNamespace, "current", f.status)
+}
+
+func (f *Focus) notify(st FocusStatus) {
+	f.mu.Lock()
+	listeners := append([]func(FocusStatus){}, f.listeners...)
+	f.mu.Unlock()
+	for _, fn := range listeners {
+		fn(st)
+	}
+}
@@ -0,0 +1,84 @@
+package dnd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+)
+
+func TestFocusHoldsAndDeliversDigest(t *testing.T) {
+	ws := t.TempDir()
+	f := NewFocus(ws)
+	msgBus := bus.NewMessageBus()
+	o := NewOutbox(nil, msgBus)
+	o.SetFocus(f)
+
+	if _, err := f.Start(0, ""); err == nil {
+		t.Error("zero duration accepted")
+	}
+	st, err := f.Start(30*time.Minute, "writing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until, busy := o.Busy(time.Now()); !busy || !until.Equal(st.Until) {
+		t.Fatalf("busy = %v until %v, want until %v", busy, until, st.Until)
+	}
+	if got := NewFocus(ws).Status(); !got.Active || got.Reason != "writing" {
+		t.Errorf("focus not restored after restart: %+v", got)
+	}
+
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "standup moved"})
+	o.Deliver(bus.OutboundMessage{Channel: "mqtt", ChatID: "k", Content: "laundry done"})
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "package arrived"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	if _, ok := msgBus.SubscribeOutbound(ctx); ok {
+		t.Fatal("message delivered during focus")
+	}
+	cancel()
+
+	if was, err := f.End(); !was || err != nil {
+		t.Fatalf("End = %v, %v", was, err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	web, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok || web.ChatID != "1" || !strings.Contains(web.Content, "2 message") ||
+		!strings.Contains(web.Content, "standup moved") || !strings.Contains(web.Content, "package arrived") {
+		t.Fatalf("web digest = %+v", web)
+	}
+	if mq, ok := msgBus.SubscribeOutbound(ctx); !ok || mq.Channel != "mqtt" || !strings.Contains(mq.Content, "laundry done") {
+		t.Fatalf("mqtt digest = %+v", mq)
+	}
+
+	o.Deliver(bus.OutboundMessage{Channel: "web", ChatID: "1", Content: "after"})
+	if msg, ok := msgBus.SubscribeOutbound(ctx); !ok || msg.Content != "after" {
+		t.Errorf("after focus messages go out directly, got %q", msg.Content)
+	}
+	if NewFocus(ws).Status().Active {
+		t.Error("ended focus restored after restart")
+	}
+}
+
+func TestFocusRunsOut(t *testing.T) {
+	f := NewFocus(t.TempDir())
+	ended := make(chan FocusStatus, 1)
+	f.OnChange(func(st FocusStatus) {
+		if !st.Active {
+			ended <- st
+		}
+	})
+	f.Start(time.Minute, "")
+	f.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if f.Status().Active {
+		t.Error("focus still on after its window")
+	}
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Error("no end notification")
+	}
+}
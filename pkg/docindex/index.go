@@ -0,0 +1,437 @@
+// Package docindex is a small vector store over files in workspace
+// folders (notes, exported documents). Files are split into chunks with
+// their line ranges, embedded, and kept in one index file; searches rank
+// chunks by cosine similarity to the embedded question. Each file's size
+// and modification time are recorded so changes since the last refresh
+// can be reported and re-embedded.
+package docindex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/docsum"
+	"localagent/pkg/filelock"
+	"localagent/pkg/logger"
+	"localagent/pkg/providers"
+	"localagent/pkg/vault"
+)
+
+// ErrNotIndexed is returned by Search before the first refresh.
+var ErrNotIndexed = errors.New("no documents indexed yet")
+
+const (
+	defaultChunkTokens = 400
+	charsPerToken      = 4
+	// embedBatch is how many chunks are sent per embeddings request.
+	embedBatch = 32
+)
+
+// textExts are indexed as plain text; PDFs need a converter.
+var textExts = []string{".md", ".markdown", ".txt", ".org", ".rst"}
+
+// Chunk is an embedded part of a file.
+type Chunk struct {
+	Text      string `json:"text"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Vector    vector `json:"vector"`
+}
+
+// Source is an indexed file.
+type Source struct {
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`
+	Chunks  []Chunk   `json:"chunks"`
+}
+
+type data struct {
+	Model   string             `json:"model"`
+	Updated time.Time          `json:"updated"`
+	Sources map[string]*Source `json:"sources"` // by display path
+}
+
+// Hit is a chunk found by Search.
+type Hit struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Score     float64
+	Text      string
+}
+
+// Cite formats the hit's source, e.g. "notes/trip.md:10-24".
+func (h Hit) Cite() string {
+	if h.StartLine == h.EndLine {
+		return fmt.Sprintf("%s:%d", h.Path, h.StartLine)
+	}
+	return fmt.Sprintf("%s:%d-%d", h.Path, h.StartLine, h.EndLine)
+}
+
+// Stats describes a refresh.
+type Stats struct {
+	Indexed   int // files embedded
+	Unchanged int
+	Removed   int
+	Chunks    int // chunks embedded
+}
+
+// Status compares the index with the folders.
+type Status struct {
+	Updated time.Time // zero before the first refresh
+	Files   int
+	Chunks  int
+	Changed []string // modified since the refresh
+	Added   []string
+	Removed []string
+}
+
+// Stale reports whether the index is missing files or content.
+func (s Status) Stale() bool {
+	return s.Updated.IsZero() || len(s.Changed)+len(s.Added)+len(s.Removed) > 0
+}
+
+type Index struct {
+	workspace  string
+	folders    []string
+	embedder   providers.Embedder
+	model      string
+	chunkChars int
+	path       string
+	convertPDF func(ctx context.Context, path string) (string, error)
+}
+
+// New indexes folders (relative to workspace or absolute) with the
+// embedding model. chunkTokens <= 0 uses 400.
+func New(workspace string, folders []string, embedder providers.Embedder, model string, chunkTokens int) *Index {
+	if chunkTokens <= 0 {
+		chunkTokens = defaultChunkTokens
+	}
+	return &Index{
+		workspace:  workspace,
+		folders:    folders,
+		embedder:   embedder,
+		model:      model,
+		chunkChars: chunkTokens * charsPerToken,
+		path:       filepath.Join(workspace, "docs_index", "index.json"),
+	}
+}
+
+// SetPDFConverter enables indexing PDFs, converted to text by fn.
+func (ix *Index) SetPDFConverter(fn func(ctx context.Context, path string) (string, error)) {
+	ix.convertPDF = fn
+}
+
+// Folders returns the configured folders.
+func (ix *Index) Folders() []string {
+	return ix.folders
+}
+
+// files lists the indexable files by display path: relative to the
+// workspace when inside it, else absolute. Hidden files and directories
+// are skipped.
+func (ix *Index) files() (map[string]string, map[string]fs.FileInfo) {
+	paths := map[string]string{}
+	infos := map[string]fs.FileInfo{}
+	for _, folder := range ix.folders {
+		root := folder
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(ix.workspace, root)
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(d.Name(), ".") && path != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !ix.indexable(path) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			name := path
+			if rel, err := filepath.Rel(ix.workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+			paths[name] = path
+			infos[name] = info
+			return nil
+		})
+		if err != nil {
+			logger.Warn("docindex: %s: %v", folder, err)
+		}
+	}
+	return paths, infos
+}
+
+func (ix *Index) indexable(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return slices.Contains(textExts, ext) || (ext == ".pdf" && ix.convertPDF != nil)
+}
+
+func (ix *Index) load() (*data, error) {
+	raw, err := vault.ReadFile(ix.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &data{Sources: map[string]*Source{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d data
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("read %s: %w", ix.path, err)
+	}
+	if d.Sources == nil {
+		d.Sources = map[string]*Source{}
+	}
+	return &d, nil
+}
+
+func (ix *Index) save(d *data) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := vault.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ix.path)
+}
+
+// Status reports what changed in the folders since the last refresh.
+func (ix *Index) Status() (Status, error) {
+	unlock, err := filelock.RLock(ix.path)
+	if err != nil {
+		return Status{}, err
+	}
+	d, err := ix.load()
+	unlock()
+	if err != nil {
+		return Status{}, err
+	}
+	_, infos := ix.files()
+	st := Status{Updated: d.Updated, Files: len(d.Sources)}
+	for name, src := range d.Sources {
+		st.Chunks += len(src.Chunks)
+		info, ok := infos[name]
+		switch {
+		case !ok:
+			st.Removed = append(st.Removed, name)
+		case info.Size() != src.Size || !info.ModTime().Equal(src.ModTime):
+			st.Changed = append(st.Changed, name)
+		}
+	}
+	for name := range infos {
+		if _, ok := d.Sources[name]; !ok {
+			st.Added = append(st.Added, name)
+		}
+	}
+	slices.Sort(st.Changed)
+	slices.Sort(st.Added)
+	slices.Sort(st.Removed)
+	return st, nil
+}
+
+// Refresh embeds new and changed files and drops removed ones. Progress
+// is saved even when an embedding request fails, so the next refresh
+// resumes.
+func (ix *Index) Refresh(ctx context.Context) (Stats, error) {
+	var stats Stats
+	unlock, err := filelock.Lock(ix.path)
+	if err != nil {
+		return stats, err
+	}
+	defer unlock()
+	d, err := ix.load()
+	if err != nil {
+		return stats, err
+	}
+	if d.Model != ix.model {
+		d.Model, d.Sources = ix.model, map[string]*Source{}
+	}
+
+	paths, infos := ix.files()
+	for name := range d.Sources {
+		if _, ok := infos[name]; !ok {
+			delete(d.Sources, name)
+			stats.Removed++
+		}
+	}
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var refreshErr error
+	for _, name := range names {
+		info, old := infos[name], d.Sources[name]
+		if old != nil && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			stats.Unchanged++
+			continue
+		}
+		text, err := ix.read(ctx, paths[name])
+		if err != nil {
+			logger.Warn("docindex: %s: %v", name, err)
+			continue
+		}
+		sum := sha256.Sum256([]byte(text))
+		hash := hex.EncodeToString(sum[:])
+		if old != nil && old.Hash == hash {
+			old.ModTime, old.Size = info.ModTime(), info.Size()
+			stats.Unchanged++
+			continue
+		}
+		chunks := split(text, ix.chunkChars)
+		if err := ix.embed(ctx, chunks); err != nil {
+			refreshErr = fmt.Errorf("embed %s: %w", name, err)
+			break
+		}
+		d.Sources[name] = &Source{ModTime: info.ModTime(), Size: info.Size(), Hash: hash, Chunks: chunks}
+		stats.Indexed++
+		stats.Chunks += len(chunks)
+	}
+
+	if refreshErr == nil {
+		d.Updated = time.Now()
+	}
+	if err := ix.save(d); err != nil {
+		return stats, err
+	}
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		logger.Info("docindex: indexed %d files (%d chunks), removed %d", stats.Indexed, stats.Chunks, stats.Removed)
+	}
+	return stats, refreshErr
+}
+
+func (ix *Index) read(ctx context.Context, path string) (string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return ix.convertPDF(ctx, path)
+	}
+	raw, err := vault.ReadFile(path)
+	return string(raw), err
+}
+
+func (ix *Index) embed(ctx context.Context, chunks []Chunk) error {
+	for start := 0; start < len(chunks); start += embedBatch {
+		batch := chunks[start:min(start+embedBatch, len(chunks))]
+		inputs := make([]string, len(batch))
+		for i, c := range batch {
+			inputs[i] = c.Text
+		}
+		vectors, err := ix.embedder.Embed(ctx, ix.model, inputs)
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			batch[i].Vector = normalize(vectors[i])
+		}
+	}
+	return nil
+}
+
+// Search returns the limit chunks most similar to query.
+func (ix *Index) Search(ctx context.Context, query string, limit int) ([]Hit, error) {
+	unlock, err := filelock.RLock(ix.path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ix.load()
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Sources) == 0 {
+		return nil, ErrNotIndexed
+	}
+	if d.Model != ix.model {
+		return nil, fmt.Errorf("the index was built with %s; refresh it for %s", d.Model, ix.model)
+	}
+	vectors, err := ix.embedder.Embed(ctx, ix.model, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	q := normalize(vectors[0])
+
+	var hits []Hit
+	for name, src := range d.Sources {
+		for _, c := range src.Chunks {
+			hits = append(hits, Hit{Path: name, StartLine: c.StartLine, EndLine: c.EndLine, Score: dot(q, c.Vector), Text: c.Text})
+		}
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Cite(), b.Cite())
+	})
+	return hits[:min(limit, len(hits))], nil
+}
+
+// split chunks text and records each chunk's line range.
+func split(text string, maxChars int) []Chunk {
+	var chunks []Chunk
+	offset, line := 0, 1
+	for _, part := range docsum.Split(text, maxChars) {
+		i := strings.Index(text[offset:], part)
+		if i < 0 {
+			continue
+		}
+		line += strings.Count(text[offset:offset+i], "\n")
+		end := line + strings.Count(part, "\n")
+		chunks = append(chunks, Chunk{Text: part, StartLine: line, EndLine: end})
+		offset += i + len(part)
+		line = end
+	}
+	return chunks
+}
+
+func normalize(v []float32) vector {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make(vector, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+// dot is the cosine similarity of normalized vectors.
+func dot(a, b vector) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -0,0 +1,124 @@
+package docindex
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/providers"
+)
+
+var keywords = []string{"paris", "rome", "budget", "train"}
+
+// fakeEmbeddings serves /embeddings with keyword-count vectors and counts
+// the inputs embedded.
+func fakeEmbeddings(t *testing.T) (providers.Embedder, *int) {
+	t.Helper()
+	embedded := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "embed-small" {
+			t.Errorf("model = %q", req.Model)
+		}
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		// Answer out of order; Embed must sort by index.
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			v := []float32{0.01, 0.01, 0.01, 0.01}
+			for k, word := range keywords {
+				v[k] += float32(strings.Count(strings.ToLower(req.Input[i]), word))
+			}
+			data = append(data, item{i, v})
+		}
+		embedded += len(req.Input)
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return providers.NewHTTPProvider("", srv.URL, ""), &embedded
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexRefreshSearchAndStaleness(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, "notes", "trip.md"), "# Trip\n\nDay one in Paris, the Louvre.\n\nThen the night train to Rome,\nbooked for the 14th.\n")
+	writeFile(t, filepath.Join(ws, "notes", "budget.txt"), "Budget: 1200 euros.\n")
+	writeFile(t, filepath.Join(ws, "notes", ".drafts", "secret.md"), "Rome rome rome\n")
+	writeFile(t, filepath.Join(ws, "notes", "photo.jpg"), "rome")
+	embedder, embedded := fakeEmbeddings(t)
+	ix := New(ws, []string{"notes"}, embedder, "embed-small", 15) // 60 chars: the trip splits in two
+
+	st, err := ix.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Stale() || !slices.Equal(st.Added, []string{"notes/budget.txt", "notes/trip.md"}) {
+		t.Fatalf("status before refresh = %+v", st)
+	}
+	if _, err := ix.Search(context.Background(), "rome", 3); err != ErrNotIndexed {
+		t.Errorf("search before refresh err = %v", err)
+	}
+
+	stats, err := ix.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 2 || stats.Chunks != 3 {
+		t.Errorf("stats = %+v, want 2 files in 3 chunks", stats)
+	}
+	hits, err := ix.Search(context.Background(), "When is the train to Rome?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Cite() != "notes/trip.md:5-6" || !strings.HasPrefix(hits[0].Text, "Then the night train") {
+		t.Errorf("hits = %+v", hits)
+	}
+	if st, _ := ix.Status(); st.Stale() || st.Files != 2 {
+		t.Errorf("status after refresh = %+v", st)
+	}
+
+	// A rewritten file is reported and only it is embedded again.
+	writeFile(t, filepath.Join(ws, "notes", "budget.txt"), "Budget: 1500 euros, Paris hotels.\n")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(ws, "notes", "budget.txt"), later, later)
+	os.Remove(filepath.Join(ws, "notes", "trip.md"))
+	st, _ = ix.Status()
+	if !slices.Equal(st.Changed, []string{"notes/budget.txt"}) || !slices.Equal(st.Removed, []string{"notes/trip.md"}) {
+		t.Errorf("status after edits = %+v", st)
+	}
+	before := *embedded
+	stats, err = New(ws, []string{"notes"}, embedder, "embed-small", 15).Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 1 || stats.Removed != 1 || *embedded-before != 1 {
+		t.Errorf("stats = %+v after embedding %d chunks", stats, *embedded-before)
+	}
+	hits, _ = ix.Search(context.Background(), "paris", 5)
+	if len(hits) != 1 || hits[0].Path != "notes/budget.txt" {
+		t.Errorf("hits after refresh = %+v", hits)
+	}
+}
@@ -0,0 +1,36 @@
+package docindex
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// vector is stored as base64 little-endian float32s, about a third of the
+// size of a JSON number array.
+type vector []float32
+
+func (v vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return json.Marshal(buf)
+}
+
+func (v *vector) UnmarshalJSON(data []byte) error {
+	var buf []byte
+	if err := json.Unmarshal(data, &buf); err != nil {
+		return err
+	}
+	if len(buf)%4 != 0 {
+		return fmt.Errorf("vector of %d bytes", len(buf))
+	}
+	out := make(vector, len(buf)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = out
+	return nil
+}
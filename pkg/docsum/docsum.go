@@ -0,0 +1,281 @@
+// Package docsum summarizes documents too long for the context window:
+// the text is split into chunks, each chunk is summarized on its own and
+// the chunk summaries are merged (map-reduce). Results are cached per
+// document under workspace/documents.
+package docsum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"localagent/pkg/prompts"
+	"localagent/pkg/providers"
+)
+
+// ErrEmpty is returned for a document without text.
+var ErrEmpty = errors.New("document has no text")
+
+const (
+	defaultChunkTokens = 3000
+	charsPerToken      = 4
+	// parallel bounds concurrent LLM calls per document.
+	parallel = 4
+)
+
+// Chunk is one part of a document, saved as a text file so follow-up
+// questions can read it.
+type Chunk struct {
+	Index   int    `json:"index"` // from 1
+	Path    string `json:"path"`  // relative to the workspace
+	Summary string `json:"summary"`
+}
+
+// Document is a summarized document.
+type Document struct {
+	ID      string    `json:"id"` // prefix of the text's SHA-256
+	Name    string    `json:"name"`
+	Model   string    `json:"model"`
+	Summary string    `json:"summary"`
+	Chunks  []Chunk   `json:"chunks"`
+	Created time.Time `json:"created"`
+}
+
+type Summarizer struct {
+	workspace  string
+	provider   providers.LLMProvider
+	model      string
+	chunkChars int
+}
+
+// New summarizes with model; chunkTokens <= 0 uses 3000.
+func New(workspace string, provider providers.LLMProvider, model string, chunkTokens int) *Summarizer {
+	if chunkTokens <= 0 {
+		chunkTokens = defaultChunkTokens
+	}
+	return &Summarizer{workspace: workspace, provider: provider, model: model, chunkChars: chunkTokens * charsPerToken}
+}
+
+// Summarize returns the summary of text, named name in prompts. cached
+// reports whether it was summarized before; the same text is never
+// summarized twice.
+func (s *Summarizer) Summarize(ctx context.Context, name, text string) (doc *Document, cached bool, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, false, ErrEmpty
+	}
+	sum := sha256.Sum256([]byte(text))
+	id := hex.EncodeToString(sum[:8])
+	rel := filepath.Join("documents", id)
+	dir := filepath.Join(s.workspace, rel)
+	if doc, err := load(dir); err == nil {
+		return doc, true, nil
+	}
+
+	parts := Split(text, s.chunkChars)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, false, err
+	}
+	doc = &Document{ID: id, Name: name, Model: s.model, Chunks: make([]Chunk, len(parts)), Created: time.Now()}
+	for i, part := range parts {
+		path := filepath.Join(rel, fmt.Sprintf("chunk-%03d.txt", i+1))
+		if err := os.WriteFile(filepath.Join(s.workspace, path), []byte(part), 0644); err != nil {
+			return nil, false, err
+		}
+		doc.Chunks[i] = Chunk{Index: i + 1, Path: path}
+	}
+
+	err = s.each(ctx, len(parts), func(ctx context.Context, i int) error {
+		summary, err := s.complete(ctx, fmt.Sprintf(prompts.DocsumMap, i+1, len(parts), name), parts[i])
+		doc.Chunks[i].Summary = summary
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("summarize chunks: %w", err)
+	}
+	summaries := make([]string, len(parts))
+	for i, c := range doc.Chunks {
+		summaries[i] = c.Summary
+	}
+	if doc.Summary, err = s.reduce(ctx, name, summaries); err != nil {
+		return nil, false, fmt.Errorf("merge summaries: %w", err)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.json"), data, 0644); err != nil {
+		return nil, false, err
+	}
+	return doc, false, nil
+}
+
+func load(dir string) (*Document, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// reduce merges summaries in rounds until one is left, so the input to
+// each merge stays around the chunk size.
+func (s *Summarizer) reduce(ctx context.Context, name string, summaries []string) (string, error) {
+	for len(summaries) > 1 {
+		groups := group(summaries, s.chunkChars)
+		merged := make([]string, len(groups))
+		err := s.each(ctx, len(groups), func(ctx context.Context, i int) error {
+			var err error
+			merged[i], err = s.complete(ctx, fmt.Sprintf(prompts.DocsumReduce, name), groups[i])
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		summaries = merged
+	}
+	return summaries[0], nil
+}
+
+// group joins consecutive summaries into inputs of about maxChars. Each
+// group takes at least two, so every round halves the count or better.
+func group(summaries []string, maxChars int) []string {
+	var groups []string
+	var cur strings.Builder
+	n := 0
+	for _, summary := range summaries {
+		if n >= 2 && cur.Len()+len(summary) > maxChars {
+			groups = append(groups, cur.String())
+			cur.Reset()
+			n = 0
+		}
+		if n > 0 {
+			cur.WriteString("\n\n---\n\n")
+		}
+		cur.WriteString(summary)
+		n++
+	}
+	if n == 1 && len(groups) > 0 {
+		groups[len(groups)-1] += "\n\n---\n\n" + cur.String()
+	} else {
+		groups = append(groups, cur.String())
+	}
+	return groups
+}
+
+// each runs fn for 0..n-1, at most parallel at a time, and returns the
+// first error.
+func (s *Summarizer) each(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, parallel)
+	)
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := fn(ctx, i); err != nil {
+				once.Do(func() { firstErr = err; cancel() })
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+func (s *Summarizer) complete(ctx context.Context, system, text string) (string, error) {
+	resp, err := s.provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: strings.TrimSpace(system)},
+		{Role: "user", Content: text},
+	}, nil, s.model, map[string]any{
+		"max_tokens":  1024,
+		"temperature": 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", errors.New("model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// Split cuts text into chunks of at most maxChars bytes, preferring
+// paragraph breaks, then line breaks, then spaces.
+func Split(text string, maxChars int) []string {
+	var chunks []string
+	var cur strings.Builder
+	flush := func() {
+		if t := strings.TrimSpace(cur.String()); t != "" {
+			chunks = append(chunks, t)
+		}
+		cur.Reset()
+	}
+	for _, para := range strings.SplitAfter(text, "\n\n") {
+		for _, piece := range cut(para, maxChars, "\n", " ") {
+			if cur.Len() > 0 && cur.Len()+len(piece) > maxChars {
+				flush()
+			}
+			cur.WriteString(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// cut splits s after seps[0] when it is longer than maxChars, recursing
+// with the remaining separators, and finally at rune boundaries.
+func cut(s string, maxChars int, seps ...string) []string {
+	if len(s) <= maxChars {
+		return []string{s}
+	}
+	if len(seps) == 0 {
+		var out []string
+		for len(s) > maxChars {
+			i := maxChars
+			for i > 0 && !utf8.RuneStart(s[i]) {
+				i--
+			}
+			if i == 0 {
+				i = maxChars
+			}
+			out = append(out, s[:i])
+			s = s[i:]
+		}
+		return append(out, s)
+	}
+	var out []string
+	for _, p := range strings.SplitAfter(s, seps[0]) {
+		out = append(out, cut(p, maxChars, seps[1:]...)...)
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package docsum
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"localagent/pkg/providers"
+)
+
+// fakeProvider "summarizes" a chunk as its first word plus padding and a
+// merge as the first words of its parts, so merges can be checked for
+// order.
+type fakeProvider struct {
+	mu     sync.Mutex
+	merges int
+	calls  int
+}
+
+func (p *fakeProvider) Chat(_ context.Context, msgs []providers.Message, _ []providers.ToolDefinition, _ string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if strings.HasPrefix(msgs[0].Content, "Below are summaries") {
+		p.merges++
+		var firsts []string
+		for _, part := range strings.Split(msgs[1].Content, "\n\n---\n\n") {
+			first, _, _ := strings.Cut(strings.Fields(part)[0], "+")
+			firsts = append(firsts, first)
+		}
+		return &providers.LLMResponse{Content: strings.Join(firsts, "+")}, nil
+	}
+	return &providers.LLMResponse{Content: strings.Fields(msgs[1].Content)[0] + " " + strings.Repeat("s", 15)}, nil
+}
+
+func (p *fakeProvider) GetDefaultModel() string { return "test" }
+
+func TestSplit(t *testing.T) {
+	text := "alpha beta\n\ngamma\n\n" + strings.Repeat("word ", 10) + "\n\nä" + strings.Repeat("é", 20)
+	chunks := Split(text, 20)
+	for _, c := range chunks {
+		if len(c) > 20 {
+			t.Errorf("chunk %q is longer than 20 bytes", c)
+		}
+	}
+	if chunks[0] != "alpha beta\n\ngamma" {
+		t.Errorf("first chunk = %q, want the two short paragraphs together", chunks[0])
+	}
+	if got := strings.Join(chunks, ""); strings.Join(strings.Fields(got), "") != strings.Join(strings.Fields(text), "") {
+		t.Errorf("chunks lost text: %q", chunks)
+	}
+}
+
+func TestSummarizeMapReduceAndCache(t *testing.T) {
+	var paras []string
+	for _, w := range []string{"one", "two", "three", "four", "five"} {
+		paras = append(paras, w+" "+strings.Repeat("x", 30))
+	}
+	text := strings.Join(paras, "\n\n")
+	workspace := t.TempDir()
+	p := &fakeProvider{}
+	s := New(workspace, p, "cheap", 10) // 40 chars: one paragraph per chunk
+
+	doc, cached, err := s.Summarize(context.Background(), "report.pdf", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached || len(doc.Chunks) != 5 {
+		t.Fatalf("cached=%v chunks=%d, want 5 fresh chunks", cached, len(doc.Chunks))
+	}
+	if doc.Summary != "one+three" || p.merges != 3 {
+		t.Errorf("summary = %q after %d merges, want a multi-round merge in order", doc.Summary, p.merges)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, doc.Chunks[2].Path))
+	if err != nil || !strings.HasPrefix(string(data), "three ") || !strings.HasPrefix(doc.Chunks[2].Summary, "three ") {
+		t.Errorf("chunk 3 = %q %+v (%v)", data, doc.Chunks[2], err)
+	}
+
+	calls := p.calls
+	again, cached, err := New(workspace, p, "cheap", 10).Summarize(context.Background(), "report.pdf", text+"\n")
+	if err != nil || !cached || again.Summary != doc.Summary || p.calls != calls {
+		t.Errorf("second summarize: cached=%v summary=%q calls=%d (%v), want the cached result", cached, again.Summary, p.calls-calls, err)
+	}
+	if _, _, err := s.Summarize(context.Background(), "empty.txt", " \n"); err != ErrEmpty {
+		t.Errorf("empty err = %v", err)
+	}
+}
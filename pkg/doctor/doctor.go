@@ -0,0 +1,554 @@
+// Package doctor runs the self-diagnostics behind `localagent doctor`:
+// config validity, provider and model availability, tool services,
+// workspace permissions, port conflicts and clock sanity. Each finding
+// carries a fix the user can act on.
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"localagent/pkg/airquality"
+	"localagent/pkg/channels/email"
+	"localagent/pkg/channels/matrix"
+	"localagent/pkg/channels/whatsapp"
+	"localagent/pkg/config"
+	"localagent/pkg/eventbridge"
+	"localagent/pkg/mqtt"
+	"localagent/pkg/providers"
+)
+
+type Status int
+
+const (
+	OK Status = iota
+	Skip
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case Skip:
+		return "skip"
+	case Warn:
+		return "warn"
+	}
+	return "FAIL"
+}
+
+// Result is one finding. Fix is empty when there is nothing to do.
+type Result struct {
+	Section string
+	Name    string
+	Status  Status
+	Detail  string
+	Fix     string
+}
+
+// Report is the outcome of a doctor run, in check order.
+type Report struct {
+	Results []Result
+}
+
+// Failed reports whether any check failed.
+func (r *Report) Failed() bool {
+	return slices.ContainsFunc(r.Results, func(res Result) bool { return res.Status == Fail })
+}
+
+// maxClockSkew is how far the local clock may drift from the provider's.
+const maxClockSkew = 2 * time.Minute
+
+type doctor struct {
+	cfg        *config.Config
+	configPath string
+	timeout    time.Duration
+	now        func() time.Time
+	report     Report
+	serverTime time.Time // Date header of the provider response, if any
+}
+
+// Run loads the config at configPath and checks everything it points at.
+// When the config cannot be loaded only that failure is reported.
+func Run(ctx context.Context, configPath string) *Report {
+	d := &doctor{configPath: configPath, timeout: 5 * time.Second, now: time.Now}
+	if !d.checkConfig() {
+		return &d.report
+	}
+	d.checkProvider(ctx)
+	d.checkServices(ctx)
+	d.checkWorkspace()
+	d.checkPorts(ctx)
+	d.checkClock()
+	return &d.report
+}
+
+func (d *doctor) add(section, name string, status Status, detail, fix string) {
+	d.report.Results = append(d.report.Results, Result{Section: section, Name: name, Status: status, Detail: detail, Fix: fix})
+}
+
+func (d *doctor) checkConfig() bool {
+	const section = "config"
+	data, err := os.ReadFile(d.configPath)
+	if err != nil {
+		d.add(section, "file", Fail, err.Error(), "Run `localagent onboard` to create a config")
+		return false
+	}
+	strict := json.NewDecoder(bytes.NewReader(data))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(&config.Config{}); err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			d.add(section, "file", Fail, fmt.Sprintf("invalid JSON at line %d: %v", lineOf(data, syntaxErr.Offset), err),
+				"Fix the JSON syntax in "+d.configPath)
+			return false
+		case errors.As(err, &typeErr):
+			d.add(section, "file", Fail, fmt.Sprintf("line %d: %v", lineOf(data, typeErr.Offset), err),
+				fmt.Sprintf("Set %s to a %s", typeErr.Field, typeErr.Type))
+			return false
+		case strings.HasPrefix(err.Error(), "json: unknown field"):
+			d.add(section, "file", Warn, strings.TrimPrefix(err.Error(), "json: "),
+				"Check the key for typos; unknown keys are ignored")
+		default:
+			d.add(section, "file", Fail, err.Error(), "Fix "+d.configPath)
+			return false
+		}
+	} else {
+		d.add(section, "file", OK, d.configPath, "")
+	}
+
+	cfg, err := config.LoadConfig(d.configPath)
+	if err != nil {
+		d.add(section, "load", Fail, err.Error(), "Fix "+d.configPath)
+		return false
+	}
+	d.cfg = cfg
+
+	if cfg.Agents.Defaults.Model == "" {
+		d.add(section, "model", Fail, "agents.defaults.model is empty", "Set agents.defaults.model to a model your provider serves")
+	}
+	if u, err := url.Parse(cfg.Provider.APIBase); cfg.Provider.APIBase == "" || err != nil || u.Host == "" {
+		d.add(section, "provider", Fail, fmt.Sprintf("provider.api_base %q is not a URL", cfg.Provider.APIBase),
+			`Set provider.api_base, e.g. "http://localhost:11434/v1"`)
+	}
+	envs := []struct{ key, name string }{
+		{"provider.api_key_env", cfg.Provider.APIKeyEnv},
+		{"tools.pdf.api_key_env", cfg.Tools.PDF.APIKeyEnv},
+		{"tools.stt.api_key_env", cfg.Tools.STT.APIKeyEnv},
+		{"tools.tts.api_key_env", cfg.Tools.TTS.APIKeyEnv},
+		{"tools.image.api_key_env", cfg.Tools.Image.APIKeyEnv},
+		{"tools.home_assistant.api_key_env", cfg.Tools.HomeAssistant.APIKeyEnv},
+		{"tools.calendar.password_env", cfg.Tools.Calendar.PasswordEnv},
+	}
+	for _, p := range cfg.Federation.Peers {
+		envs = append(envs, struct{ key, name string }{"federation.peers." + p.Name + ".token_env", p.TokenEnv})
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Agents.Routes)) {
+		route := cfg.Agents.Routes[name]
+		if route.APIBase == "" {
+			continue
+		}
+		if u, err := url.Parse(route.APIBase); err != nil || u.Host == "" {
+			d.add(section, "agents.routes."+name, Fail, fmt.Sprintf("api_base %q is not a URL", route.APIBase),
+				"Set it to an OpenAI-compatible endpoint or remove it to use the main provider")
+		}
+		envs = append(envs, struct{ key, name string }{"agents.routes." + name + ".api_key_env", route.APIKeyEnv})
+	}
+	for _, env := range envs {
+		if env.name != "" && os.Getenv(env.name) == "" {
+			d.add(section, env.key, Fail, fmt.Sprintf("$%s is not set", env.name),
+				fmt.Sprintf("export %s=... before starting localagent", env.name))
+		}
+	}
+	for _, tz := range []struct{ key, name string }{
+		{"agents.defaults.timezone", cfg.Agents.Defaults.Timezone},
+		{"heartbeat.active_hours.timezone", activeHoursTZ(cfg)},
+	} {
+		if tz.name == "" {
+			continue
+		}
+		if _, err := time.LoadLocation(tz.name); err != nil {
+			d.add(section, tz.key, Fail, fmt.Sprintf("unknown timezone %q", tz.name),
+				`Use an IANA name such as "Europe/Zurich" or "America/New_York"`)
+		}
+	}
+	switch cfg.Provider.PromptCache {
+	case "", providers.PromptCacheAnthropic, providers.PromptCacheOpenAI:
+	default:
+		d.add(section, "provider.prompt_cache", Fail, fmt.Sprintf("unknown prompt cache mode %q", cfg.Provider.PromptCache),
+			`Use "anthropic", "openai" or leave it empty`)
+	}
+	switch cfg.Agents.Defaults.ToolDefinitions {
+	case "", "full", "groups":
+	default:
+		d.add(section, "agents.defaults.tool_definitions", Fail, fmt.Sprintf("unknown tool definition mode %q", cfg.Agents.Defaults.ToolDefinitions),
+			`Use "groups" or leave it empty`)
+	}
+	if err := cfg.Agents.Autonomy.Validate(); err != nil {
+		d.add(section, "agents.autonomy", Fail, err.Error(), `Use "auto" or "suggest"`)
+	}
+	for role, p := range cfg.Roles.Policies {
+		if !config.ValidAutonomy(p.Autonomy) {
+			d.add(section, "roles.policies."+role+".autonomy", Fail, fmt.Sprintf("unknown autonomy level %q", p.Autonomy), `Use "auto" or "suggest"`)
+		}
+	}
+	for _, t := range cfg.Tools.Custom {
+		if err := t.Validate(); err != nil {
+			d.add(section, "tools.custom", Fail, err.Error(), "Fix or remove the definition; the tool is skipped at startup")
+		}
+	}
+	if cfg.Bridge.URL != "" {
+		if _, err := eventbridge.New(cfg.Bridge, nil); err != nil {
+			d.add(section, "bridge.url", Fail, err.Error(), "Use e.g. mqtt://homeassistant.local:1883 or nats://localhost:4222")
+		}
+	}
+	if cfg.MQTT.URL != "" {
+		if _, err := mqtt.NewChannel(cfg.MQTT, nil); err != nil {
+			d.add(section, "mqtt", Fail, err.Error(), "Use e.g. mqtt://homeassistant.local:1883 and topics without + or #")
+		}
+	}
+	if cfg.Discord.TokenEnv != "" && cfg.Discord.ResolveToken() == "" {
+		d.add(section, "discord", Fail, cfg.Discord.TokenEnv+" is not set", "Export the bot token or clear discord.token_env; the channel is skipped at startup")
+	}
+	if cfg.Matrix.Homeserver != "" {
+		if _, err := matrix.NewChannel(cfg.Matrix, nil, ""); err != nil {
+			d.add(section, "matrix", Fail, err.Error(), "Use e.g. https://matrix.example.org and export the access token; the channel is skipped at startup")
+		}
+	}
+	if cfg.EmailChannel.IMAPHost != "" {
+		if _, err := email.NewChannel(cfg.EmailChannel, nil, cfg.WorkspacePath()); err != nil {
+			d.add(section, "email_channel", Fail, err.Error(), "Set smtp_host, username, allow_from and export the password; the channel is skipped at startup")
+		} else if cfg.EmailChannel.AuthServID == "" {
+			d.add(section, "email_channel", Warn, "senders are not authenticated, so their mail is handled with the guest role",
+				"Set auth_serv_id to the ID your mail server puts in Authentication-Results headers")
+		}
+	}
+	if cfg.WhatsApp.PhoneNumberID != "" {
+		if _, err := whatsapp.NewChannel(cfg.WhatsApp, nil, cfg.WorkspacePath()); err != nil {
+			d.add(section, "whatsapp", Fail, err.Error(), "Set allow_from and export the access token, app secret and verify token; the channel is skipped at startup")
+		}
+	}
+	for _, h := range cfg.Hooks {
+		if err := h.Validate(); err != nil {
+			d.add(section, "hooks", Fail, err.Error(), "Events: "+strings.Join(config.HookEvents, ", ")+"; the hook is skipped at startup")
+		}
+	}
+	for _, w := range cfg.Watchers {
+		if err := w.Validate(); err != nil {
+			d.add(section, "watchers", Fail, err.Error(), "Actions: "+strings.Join(config.WatcherActions, ", ")+"; the watcher is skipped at startup")
+		}
+	}
+	for _, p := range cfg.Places {
+		if err := p.Validate(); err != nil {
+			d.add(section, "places", Fail, err.Error(), `Use an IANA timezone, e.g. "America/Toronto"; the place is skipped at startup`)
+		}
+	}
+	if err := cfg.Tools.Transit.Validate(); err != nil {
+		d.add(section, "tools.transit", Fail, err.Error(), "Give each stop a name and stop_id; the transit tool is disabled until fixed")
+	}
+	for _, a := range cfg.Tools.AirQuality.Allergens {
+		if !slices.Contains(airquality.Pollens, a) {
+			d.add(section, "tools.air_quality", Warn, fmt.Sprintf("unknown allergen %q", a), "Pollen types: "+strings.Join(airquality.Pollens, ", "))
+		}
+	}
+	if _, err := cfg.PromptLayout(); err != nil {
+		d.add(section, "agents.prompt", Fail, err.Error(),
+			"Fix agents.prompt or "+config.SharedPromptPath()+`; sections: `+strings.Join(config.DefaultPromptOrder, ", ")+" and custom section names")
+	}
+	for _, p := range []struct {
+		key  string
+		port int
+	}{{"gateway.port", cfg.Gateway.Port}, {"webchat.port", cfg.WebChat.Port}} {
+		if p.port <= 0 || p.port > 65535 {
+			d.add(section, p.key, Fail, fmt.Sprintf("port %d is out of range", p.port), "Use a port between 1 and 65535")
+		}
+	}
+	return true
+}
+
+func activeHoursTZ(cfg *config.Config) string {
+	if ah := cfg.Heartbeat.ActiveHours; ah != nil {
+		return ah.Timezone
+	}
+	return ""
+}
+
+// lineOf converts a byte offset into a 1-based line number.
+func lineOf(data []byte, offset int64) int {
+	offset = min(max(offset, 0), int64(len(data)))
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func (d *doctor) client(proxy string) *http.Client {
+	c := &http.Client{Timeout: d.timeout}
+	if proxy != "" {
+		if u, err := url.Parse(proxy); err == nil {
+			c.Transport = &http.Transport{Proxy: http.ProxyURL(u)}
+		}
+	}
+	return c
+}
+
+// checkProvider lists the provider's models and looks for the configured one.
+func (d *doctor) checkProvider(ctx context.Context) {
+	const section = "provider"
+	base := strings.TrimRight(d.cfg.Provider.APIBase, "/")
+	if base == "" {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/models", nil)
+	if err != nil {
+		d.add(section, "reachable", Fail, err.Error(), "Fix provider.api_base")
+		return
+	}
+	if key := d.cfg.Provider.ResolveAPIKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := d.client(d.cfg.Provider.Proxy).Do(req)
+	if err != nil {
+		d.add(section, "reachable", Fail, err.Error(),
+			fmt.Sprintf("Start the model server or point provider.api_base at it (now %s)", base))
+		return
+	}
+	defer resp.Body.Close()
+	if t, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		d.serverTime = t
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		d.add(section, "auth", Fail, "API key rejected ("+resp.Status+")",
+			"Check that provider.api_key_env names a variable holding a valid key")
+		return
+	case resp.StatusCode != http.StatusOK:
+		d.add(section, "reachable", Warn, base+"/models returned "+resp.Status,
+			"The server is up but does not list models; model availability was not checked")
+		return
+	}
+	d.add(section, "reachable", OK, base, "")
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		d.add(section, "model", Warn, "could not parse model list: "+err.Error(), "")
+		return
+	}
+	model := d.cfg.Agents.Defaults.Model
+	ids := make([]string, len(list.Data))
+	for i, m := range list.Data {
+		ids[i] = m.ID
+	}
+	if slices.Contains(ids, model) {
+		d.add(section, "model", OK, model, "")
+		return
+	}
+	fix := fmt.Sprintf("Pull the model on the server (e.g. `ollama pull %s`) or set agents.defaults.model to an available one", model)
+	if len(ids) > 0 {
+		shown := ids[:min(len(ids), 8)]
+		fix += ": " + strings.Join(shown, ", ")
+		if len(ids) > len(shown) {
+			fix += ", ..."
+		}
+	}
+	d.add(section, "model", Fail, fmt.Sprintf("%q is not served by %s", model, base), fix)
+}
+
+type service struct {
+	name, key string
+	url       string
+	path      string // appended to url for the probe
+	auth      func(*http.Request)
+}
+
+// checkServices probes each configured tool service. Any HTTP response
+// counts as reachable; 401/403 means the credentials are wrong.
+func (d *doctor) checkServices(ctx context.Context) {
+	const section = "services"
+	t := d.cfg.Tools
+	bearer := func(key string) func(*http.Request) {
+		return func(r *http.Request) {
+			if key != "" {
+				r.Header.Set("Authorization", "Bearer "+key)
+			}
+		}
+	}
+	services := []service{
+		{name: "pdf", key: "tools.pdf", url: t.PDF.URL, auth: bearer(t.PDF.ResolveAPIKey())},
+		{name: "stt", key: "tools.stt", url: t.STT.URL, auth: bearer(t.STT.ResolveAPIKey())},
+		{name: "tts", key: "tools.tts", url: t.TTS.URL, auth: bearer(t.TTS.ResolveAPIKey())},
+		{name: "image", key: "tools.image", url: t.Image.URL, auth: bearer(t.Image.ResolveAPIKey())},
+		{name: "home_assistant", key: "tools.home_assistant", url: t.HomeAssistant.URL, path: "/api/",
+			auth: bearer(t.HomeAssistant.ResolveAPIKey())},
+		{name: "calendar", key: "tools.calendar", url: t.Calendar.URL, auth: func(r *http.Request) {
+			if t.Calendar.Username != "" {
+				r.SetBasicAuth(t.Calendar.Username, t.Calendar.ResolvePassword())
+			}
+		}},
+	}
+	client := d.client("")
+	for _, s := range services {
+		if s.url == "" {
+			d.add(section, s.name, Skip, "not configured", "")
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(s.url, "/")+s.path, nil)
+		if err != nil {
+			d.add(section, s.name, Fail, err.Error(), "Fix "+s.key+".url")
+			continue
+		}
+		s.auth(req)
+		resp, err := client.Do(req)
+		if err != nil {
+			d.add(section, s.name, Fail, err.Error(),
+				fmt.Sprintf("Start the service or fix %s.url (now %s)", s.key, s.url))
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			d.add(section, s.name, Fail, "credentials rejected ("+resp.Status+")",
+				"Check the credentials configured under "+s.key)
+		case resp.StatusCode >= 500:
+			d.add(section, s.name, Warn, s.url+" returned "+resp.Status, "Check the service's logs")
+		default:
+			d.add(section, s.name, OK, s.url, "")
+		}
+	}
+}
+
+// checkWorkspace makes sure the workspace and data directory exist, are
+// writable and are not writable by other users.
+func (d *doctor) checkWorkspace() {
+	const section = "workspace"
+	for _, dir := range []struct{ name, path, missingFix string }{
+		{"workspace", d.cfg.WorkspacePath(), "Run `localagent onboard` or fix agents.defaults.workspace"},
+		{"data dir", d.cfg.DataDir(), "Run `localagent onboard`"},
+	} {
+		info, err := os.Stat(dir.path)
+		if err != nil {
+			d.add(section, dir.name, Fail, err.Error(), dir.missingFix)
+			continue
+		}
+		if !info.IsDir() {
+			d.add(section, dir.name, Fail, dir.path+" is not a directory", dir.missingFix)
+			continue
+		}
+		f, err := os.CreateTemp(dir.path, ".doctor-*")
+		if err != nil {
+			d.add(section, dir.name, Fail, "not writable: "+err.Error(),
+				fmt.Sprintf("chown -R $(id -un) %s && chmod u+rwx %s", dir.path, dir.path))
+			continue
+		}
+		f.Close()
+		os.Remove(f.Name())
+		if info.Mode().Perm()&0o002 != 0 {
+			d.add(section, dir.name, Warn, fmt.Sprintf("%s is world-writable (%v)", dir.path, info.Mode().Perm()),
+				"chmod o-w "+dir.path)
+			continue
+		}
+		d.add(section, dir.name, OK, dir.path, "")
+	}
+}
+
+// checkPorts tries to bind the gateway and webchat ports. A port held by a
+// running localagent gateway is fine.
+func (d *doctor) checkPorts(ctx context.Context) {
+	const section = "ports"
+	gatewayUp := d.gatewayRunning(ctx)
+	for _, p := range []struct {
+		name, key string
+		host      string
+		port      int
+	}{
+		{"gateway", "gateway.port", d.cfg.Gateway.Host, d.cfg.Gateway.Port},
+		{"webchat", "webchat.port", d.cfg.WebChat.Host, d.cfg.WebChat.Port},
+	} {
+		if p.port <= 0 || p.port > 65535 {
+			continue
+		}
+		addr := net.JoinHostPort(p.host, strconv.Itoa(p.port))
+		ln, err := net.Listen("tcp", addr)
+		if err == nil {
+			ln.Close()
+			d.add(section, p.name, OK, addr+" is free", "")
+			continue
+		}
+		if gatewayUp {
+			d.add(section, p.name, OK, addr+" is in use by the running gateway", "")
+			continue
+		}
+		d.add(section, p.name, Fail, fmt.Sprintf("cannot listen on %s: %v", addr, err),
+			fmt.Sprintf("Stop the process using the port (`lsof -i :%d`) or change %s", p.port, p.key))
+	}
+}
+
+func (d *doctor) gatewayRunning(ctx context.Context) bool {
+	u := fmt.Sprintf("http://%s/health", net.JoinHostPort("127.0.0.1", strconv.Itoa(d.cfg.Gateway.Port)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := d.client("").Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+	}
+	return resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&body) == nil && body.Status == "ok"
+}
+
+// checkClock compares the local clock with the provider's Date header and
+// reports the timezone used for dates.
+func (d *doctor) checkClock() {
+	const section = "clock"
+	now := d.now()
+	if !d.serverTime.IsZero() {
+		skew := now.Sub(d.serverTime)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > maxClockSkew {
+			d.add(section, "time", Fail, fmt.Sprintf("local clock is off by %s from the provider", skew.Round(time.Second)),
+				"Enable time sync (e.g. `timedatectl set-ntp true`); reminders and cron depend on it")
+		} else {
+			d.add(section, "time", OK, "in sync with the provider", "")
+		}
+	}
+	loc := time.Local
+	if tz := d.cfg.Agents.Defaults.Timezone; tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return // reported by checkConfig
+		}
+		loc = l
+	}
+	name, offset := now.In(loc).Zone()
+	detail := fmt.Sprintf("%s (%s, now %s)", loc, name, now.In(loc).Format("2006-01-02 15:04"))
+	if name == "UTC" && offset == 0 && d.cfg.Agents.Defaults.Timezone == "" {
+		d.add(section, "timezone", Warn, detail,
+			`The system runs in UTC; set agents.defaults.timezone (e.g. "Europe/Zurich") so "tomorrow 9am" means your 9am`)
+		return
+	}
+	d.add(section, "timezone", OK, detail, "")
+}
@@ -0,0 +1,114 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".localagent", "workspace"), 0755)
+	path := filepath.Join(home, ".localagent", "config.json")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func find(r *Report, section, name string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.Section == section && res.Name == name {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestConfigSyntaxErrorReportsLine(t *testing.T) {
+	path := writeConfig(t, "{\n  \"agents\": {\n    \"defaults\": {,}\n  }\n}")
+	r := Run(context.Background(), path)
+	res, ok := find(r, "config", "file")
+	if !ok || res.Status != Fail || !strings.Contains(res.Detail, "line 3") {
+		t.Fatalf("got %+v", r.Results)
+	}
+	if len(r.Results) != 1 {
+		t.Errorf("expected only the config failure, got %d results", len(r.Results))
+	}
+}
+
+func TestProviderModelMissing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"qwen3:8b"},{"id":"gemma3:4b"}]}`)
+	}))
+	defer srv.Close()
+
+	path := writeConfig(t, fmt.Sprintf(`{
+		"agents": {"defaults": {"workspace": %q, "model": "llama3.2:latest", "timezone": "Europe/Zurich"}},
+		"provider": {"api_base": %q},
+		"gateway": {"host": "127.0.0.1", "port": %d},
+		"webchat": {"host": "127.0.0.1", "port": %d}
+	}`, t.TempDir(), srv.URL+"/v1", freePort(t), freePort(t)))
+	r := Run(context.Background(), path)
+
+	if res, _ := find(r, "provider", "reachable"); res.Status != OK {
+		t.Errorf("reachable = %+v", res)
+	}
+	res, _ := find(r, "provider", "model")
+	if res.Status != Fail || !strings.Contains(res.Fix, "qwen3:8b") {
+		t.Errorf("model = %+v", res)
+	}
+	if res, _ := find(r, "clock", "time"); res.Status != OK {
+		t.Errorf("clock = %+v", res)
+	}
+	if !r.Failed() {
+		t.Error("expected report to fail")
+	}
+}
+
+func TestPortConflict(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	busy := ln.Addr().(*net.TCPAddr).Port
+
+	path := writeConfig(t, fmt.Sprintf(`{
+		"agents": {"defaults": {"model": "m"}},
+		"provider": {"api_base": "http://127.0.0.1:1/v1"},
+		"gateway": {"host": "127.0.0.1", "port": %d},
+		"webchat": {"host": "127.0.0.1", "port": %d}
+	}`, freePort(t), busy))
+	r := Run(context.Background(), path)
+
+	if res, _ := find(r, "ports", "gateway"); res.Status != OK {
+		t.Errorf("gateway = %+v", res)
+	}
+	res, _ := find(r, "ports", "webchat")
+	if res.Status != Fail || !strings.Contains(res.Fix, "webchat.port") {
+		t.Errorf("webchat = %+v", res)
+	}
+}
@@ -0,0 +1,129 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeIMAP answers the commands fetchIMAP sends and records them.
+func fakeIMAP(t *testing.T, conn net.Conn, commands *[]string) {
+	t.Helper()
+	header1 := "From: Alice Smith <alice@example.com>\r\nSubject: Can you review the contract?\r\nDate: Wed, 14 Oct 2026 09:00:00 +0000\r\nContent-Type: text/plain\r\n\r\n"
+	text1 := "Hi, please look at section 3 before Friday.\r\n"
+	header2 := "From: Weekly Digest <digest@news.example.org>\r\nSubject: =?utf-8?q?This_week_in_Go?=\r\nList-Unsubscribe: <mailto:unsub@news.example.org>\r\nDate: Wed, 14 Oct 2026 08:00:00 +0000\r\nContent-Type: multipart/alternative; boundary=b1\r\n\r\n"
+	text2 := "--b1\r\nContent-Type: text/html\r\n\r\n<p>Top <b>stories</b></p>\r\n--b1--\r\n"
+	header3 := "From: no-reply@shop.example\r\nSubject: Your order shipped\r\nDate: Tue, 13 Oct 2026 18:00:00 +0000\r\n\r\n"
+
+	go func() {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+		for {
+			l, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			tag, cmd, _ := strings.Cut(strings.TrimSpace(l), " ")
+			*commands = append(*commands, cmd)
+			switch verb := strings.Fields(cmd)[0]; verb {
+			case "UID":
+				if strings.HasPrefix(cmd, "UID SEARCH") {
+					fmt.Fprint(conn, "* SEARCH 4 7 9\r\n")
+					break
+				}
+				fmt.Fprintf(conn, "* 1 FETCH (UID 4 BODY[HEADER.FIELDS (FROM SUBJECT)] {%d}\r\n%s BODY[TEXT]<0> {%d}\r\n%s)\r\n", len(header1), header1, len(text1), text1)
+				fmt.Fprintf(conn, "* 2 FETCH (BODY[TEXT]<0> {%d}\r\n%s UID 7 BODY[HEADER.FIELDS (FROM)] {%d}\r\n%s)\r\n", len(text2), text2, len(header2), header2)
+				fmt.Fprintf(conn, "* 3 FETCH (UID 9 BODY[HEADER.FIELDS (FROM)] {%d}\r\n%s BODY[TEXT]<0> NIL)\r\n", len(header3), header3)
+			case "LOGOUT":
+				fmt.Fprint(conn, "* BYE\r\n")
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		}
+	}()
+}
+
+func TestTriageOverIMAP(t *testing.T) {
+	var commands []string
+	tr := NewTriager(Account{Username: "me", Password: `p"w`}, t.TempDir())
+	tr.dial = func(ctx context.Context) (*Client, error) {
+		client, server := net.Pipe()
+		fakeIMAP(t, server, &commands)
+		return NewClient(client)
+	}
+
+	s, err := tr.Triage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range commands {
+		if strings.HasPrefix(c, "SELECT") || strings.Contains(c, "STORE") || (strings.Contains(c, "FETCH") && !strings.Contains(c, "BODY.PEEK")) {
+			t.Errorf("command could change the mailbox: %s", c)
+		}
+	}
+	if strings.Join(commands[:2], "; ") != `LOGIN "me" "p\"w"; EXAMINE "INBOX"` {
+		t.Errorf("commands = %q", commands)
+	}
+	if len(s.Messages) != 3 {
+		t.Fatalf("got %d messages: %+v", len(s.Messages), s.Messages)
+	}
+	alice, digest, shop := s.Messages[0], s.Messages[1], s.Messages[2]
+	if alice.UID != 4 || alice.Category != ActionNeeded || alice.Snippet != "Hi, please look at section 3 before Friday." {
+		t.Errorf("alice = %+v", alice)
+	}
+	if digest.UID != 7 || digest.Category != Newsletter || digest.Subject != "This week in Go" || digest.Snippet != "Top stories" {
+		t.Errorf("digest = %+v", digest)
+	}
+	if shop.Category != FYI || shop.Snippet != "" {
+		t.Errorf("shop = %+v", shop)
+	}
+	out := s.Format()
+	for _, want := range []string{"3): 1 need action, 1 FYI, 1 newsletters", "Alice Smith: Can you review the contract?", "Newsletters: Weekly Digest"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A rule for the domain wins over the heuristics, subdomains included.
+	if key, err := tr.SetRule("example.org", ActionNeeded); err != nil || key != "@example.org" {
+		t.Fatalf("SetRule = %q, %v", key, err)
+	}
+	commands = nil
+	s, _ = tr.Triage(context.Background())
+	if d := s.Messages[1]; d.Category != ActionNeeded || d.Rule != "@example.org" {
+		t.Errorf("digest with rule = %+v", d)
+	}
+	if err := tr.RemoveRule("@example.org"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.RemoveRule("@example.org"); !errors.Is(err, ErrNoRule) {
+		t.Errorf("removing a missing rule = %v", err)
+	}
+}
+
+func TestBriefingOncePerDay(t *testing.T) {
+	tr := NewTriager(Account{}, t.TempDir())
+	calls := 0
+	tr.fetch = func(ctx context.Context) ([]Message, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return []Message{{Address: "bob@example.com", Subject: "Lunch?"}}, nil
+	}
+	now := time.Now()
+	if got := tr.Briefing(now); got != "" {
+		t.Errorf("briefing after a failed fetch = %q", got)
+	}
+	if got := tr.Briefing(now); !strings.Contains(got, "bob@example.com: Lunch?") {
+		t.Errorf("briefing = %q", got)
+	}
+	if got := tr.Briefing(now); got != "" || calls != 2 {
+		t.Errorf("second briefing the same day = %q after %d fetches", got, calls)
+	}
+}
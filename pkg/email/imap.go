@@ -0,0 +1,342 @@
+// Package email triages the user's inbox: a minimal read-only IMAP client
+// fetches unread headers and snippets, and each message is sorted into
+// action needed, FYI or newsletter by per-sender rules and heuristics.
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxLiteral bounds a single literal the server may send, so a
+// broken or hostile server can't make us allocate without limit.
+const defaultMaxLiteral = 1 << 20
+
+// Client is an IMAP4rev1 connection that only ever reads: mailboxes are
+// opened with EXAMINE and bodies fetched with BODY.PEEK, so flags such as
+// \Seen are never changed.
+type Client struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	tag        int
+	maxLiteral int
+}
+
+// literal is a {n} literal in a response line, with the text between it
+// and the previous literal (e.g. "* 3 FETCH (UID 7 BODY[TEXT]<0> ") so
+// callers can tell which item it belongs to.
+type literal struct {
+	prefix string
+	data   []byte
+}
+
+// line is one untagged response with its literals.
+type line struct {
+	text string // the line with literals left out
+	lits []literal
+}
+
+// Dial connects to addr (host:port), over TLS unless plain is set, and
+// reads the server greeting.
+func Dial(ctx context.Context, addr string, plain bool) (*Client, error) {
+	d := net.Dialer{Timeout: 10 * time.Second}
+	var nc net.Conn
+	var err error
+	if plain {
+		nc, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		td := tls.Dialer{NetDialer: &d}
+		nc, err = td.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(dl)
+	}
+	c, err := NewClient(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient reads the greeting on an established connection.
+func NewClient(conn net.Conn) (*Client, error) {
+	c := &Client{conn: conn, r: bufio.NewReader(conn), maxLiteral: defaultMaxLiteral}
+	l, err := c.readLine()
+	if err != nil {
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(l.text, "* OK") && !strings.HasPrefix(l.text, "* PREAUTH") {
+		return nil, fmt.Errorf("imap greeting: %s", l.text)
+	}
+	return c, nil
+}
+
+// Close closes the connection without logging out.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Login authenticates with a username and password.
+func (c *Client) Login(username, password string) error {
+	_, err := c.cmd("LOGIN " + quote(username) + " " + quote(password))
+	return err
+}
+
+// SetMaxLiteral raises (or lowers) the largest literal accepted, e.g. for
+// fetching whole messages with attachments.
+func (c *Client) SetMaxLiteral(n int) {
+	c.maxLiteral = n
+}
+
+// Examine opens mailbox read-only.
+func (c *Client) Examine(mailbox string) error {
+	_, err := c.ExamineStatus(mailbox)
+	return err
+}
+
+// Mailbox is the state of an opened mailbox. UIDs are only comparable
+// while UIDValidity stays the same.
+type Mailbox struct {
+	UIDValidity uint32
+	UIDNext     uint32 // 0 when the server doesn't say
+}
+
+// ExamineStatus opens mailbox read-only and returns its UID state.
+func (c *Client) ExamineStatus(mailbox string) (Mailbox, error) {
+	lines, err := c.cmd("EXAMINE " + quote(mailbox))
+	if err != nil {
+		return Mailbox{}, err
+	}
+	var mb Mailbox
+	for _, l := range lines {
+		if n, ok := responseCode(l.text, "UIDVALIDITY"); ok {
+			mb.UIDValidity = n
+		}
+		if n, ok := responseCode(l.text, "UIDNEXT"); ok {
+			mb.UIDNext = n
+		}
+	}
+	return mb, nil
+}
+
+// responseCode returns the number of a "[NAME n]" response code in text.
+func responseCode(text, name string) (uint32, bool) {
+	i := strings.Index(strings.ToUpper(text), "["+name+" ")
+	if i < 0 {
+		return 0, false
+	}
+	rest := text[i+len(name)+2:]
+	end := strings.IndexByte(rest, ']')
+	if end < 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(rest[:end], 10, 32)
+	return uint32(n), err == nil
+}
+
+// SearchUnseen returns the UIDs of unread messages received since the
+// given day, oldest first.
+func (c *Client) SearchUnseen(since time.Time) ([]uint32, error) {
+	return c.search("UNSEEN SINCE " + since.Format("2-Jan-2006"))
+}
+
+// SearchAfter returns the UIDs of messages after uid, oldest first.
+func (c *Client) SearchAfter(uid uint32) ([]uint32, error) {
+	uids, err := c.search(fmt.Sprintf("UID %d:*", uid+1))
+	// "n:*" matches the last message even when its UID is below n.
+	return slices.DeleteFunc(uids, func(u uint32) bool { return u <= uid }), err
+}
+
+func (c *Client) search(criteria string) ([]uint32, error) {
+	lines, err := c.cmd("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, l := range lines {
+		rest, ok := strings.CutPrefix(l.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if n, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetched is the raw data of one message from fetchPreview.
+type fetched struct {
+	uid    uint32
+	header []byte
+	text   []byte
+}
+
+// fetchPreview returns the triage headers of each message and the first
+// snippetBytes of its body, without marking anything read.
+func (c *Client) fetchPreview(uids []uint32, snippetBytes int) ([]fetched, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	set := make([]string, len(uids))
+	for i, u := range uids {
+		set[i] = strconv.FormatUint(uint64(u), 10)
+	}
+	lines, err := c.cmd(fmt.Sprintf("UID FETCH %s (UID BODY.PEEK[HEADER.FIELDS (%s)] BODY.PEEK[TEXT]<0.%d>)",
+		strings.Join(set, ","), strings.Join(headerFields, " "), snippetBytes))
+	if err != nil {
+		return nil, err
+	}
+	var out []fetched
+	for _, l := range lines {
+		if !strings.Contains(l.text, " FETCH ") {
+			continue
+		}
+		f := fetched{uid: fetchUID(l.text)}
+		for _, lit := range l.lits {
+			switch p := strings.ToUpper(lit.prefix); {
+			case strings.Contains(p, "BODY[HEADER"):
+				f.header = lit.data
+			case strings.Contains(p, "BODY[TEXT]"):
+				f.text = lit.data
+			}
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// FetchItem returns the literal of item (e.g. "BODY.PEEK[]" for whole
+// messages) for each of uids that has one, without marking anything read.
+func (c *Client) FetchItem(uids []uint32, item string) (map[uint32][]byte, error) {
+	out := make(map[uint32][]byte)
+	if len(uids) == 0 {
+		return out, nil
+	}
+	set := make([]string, len(uids))
+	for i, u := range uids {
+		set[i] = strconv.FormatUint(uint64(u), 10)
+	}
+	lines, err := c.cmd(fmt.Sprintf("UID FETCH %s (UID %s)", strings.Join(set, ","), item))
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		if !strings.Contains(l.text, " FETCH ") || len(l.lits) == 0 {
+			continue
+		}
+		out[fetchUID(l.text)] = l.lits[0].data
+	}
+	return out, nil
+}
+
+// Logout ends the session and closes the connection.
+func (c *Client) Logout() error {
+	_, err := c.cmd("LOGOUT")
+	c.conn.Close()
+	return err
+}
+
+// cmd sends a tagged command and returns the untagged lines that came
+// back, or an error for a NO or BAD completion.
+func (c *Client) cmd(command string) ([]line, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := io.WriteString(c.conn, tag+" "+command+"\r\n"); err != nil {
+		return nil, err
+	}
+	var lines []line
+	for {
+		l, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		status, ok := strings.CutPrefix(l.text, tag+" ")
+		if !ok {
+			lines = append(lines, l)
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+			verb, _, _ := strings.Cut(command, " ")
+			return nil, fmt.Errorf("imap %s: %s", verb, status)
+		}
+		return lines, nil
+	}
+}
+
+// readLine reads one response line, following {n} literals onto the
+// lines that continue after them.
+func (c *Client) readLine() (line, error) {
+	var l line
+	var sb strings.Builder
+	for {
+		s, err := c.r.ReadString('\n')
+		if err != nil {
+			return l, err
+		}
+		s = strings.TrimRight(s, "\r\n")
+		n, ok := literalSize(s)
+		if !ok {
+			sb.WriteString(s)
+			l.text = sb.String()
+			return l, nil
+		}
+		if n > c.maxLiteral {
+			return l, fmt.Errorf("imap literal of %d bytes too large", n)
+		}
+		head := s[:strings.LastIndexByte(s, '{')]
+		sb.WriteString(head)
+		data := make([]byte, n)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return l, err
+		}
+		l.lits = append(l.lits, literal{prefix: head, data: data})
+	}
+}
+
+// literalSize reports the size of the literal announced at the end of s.
+func literalSize(s string) (int, bool) {
+	if !strings.HasSuffix(s, "}") {
+		return 0, false
+	}
+	i := strings.LastIndexByte(s, '{')
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(s[i+1:len(s)-1], "+"))
+	return n, err == nil && n >= 0
+}
+
+// fetchUID returns the UID item of a FETCH response.
+func fetchUID(text string) uint32 {
+	i := strings.Index(strings.ToUpper(text), "UID ")
+	if i < 0 {
+		return 0
+	}
+	rest := text[i+4:]
+	end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	n, _ := strconv.ParseUint(rest, 10, 32)
+	return uint32(n)
+}
+
+// quote returns s as an IMAP quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
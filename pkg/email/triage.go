@@ -0,0 +1,475 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+// Category is where triage puts a message.
+type Category string
+
+const (
+	ActionNeeded Category = "action"
+	FYI          Category = "fyi"
+	Newsletter   Category = "newsletter"
+)
+
+// Categories lists the categories in the order they are reported.
+var Categories = []Category{ActionNeeded, FYI, Newsletter}
+
+// ParseCategory accepts a category name or a loose spelling of it
+// ("action needed", "news").
+func ParseCategory(s string) (Category, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "action", "action needed", "action_needed", "todo":
+		return ActionNeeded, true
+	case "fyi", "info":
+		return FYI, true
+	case "newsletter", "newsletters", "news", "bulk":
+		return Newsletter, true
+	}
+	return "", false
+}
+
+const (
+	rulesNamespace = "email_rules"  // sender pattern -> Category
+	stateNamespace = "email_triage" // briefing bookkeeping
+
+	defaultLookback = 3 * 24 * time.Hour
+	defaultMax      = 30
+	snippetBytes    = 4096
+	snippetRunes    = 160
+	fetchTimeout    = 30 * time.Second
+)
+
+// headerFields are the headers fetched for triage.
+var headerFields = []string{"FROM", "SUBJECT", "DATE", "LIST-ID", "LIST-UNSUBSCRIBE", "PRECEDENCE", "CONTENT-TYPE", "CONTENT-TRANSFER-ENCODING"}
+
+// ErrNoRule is returned when removing a rule that doesn't exist.
+var ErrNoRule = errors.New("no rule for that sender")
+
+// Account is the IMAP mailbox to triage.
+type Account struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	Mailbox  string // default INBOX
+	Plain    bool   // no TLS, e.g. a local bridge
+	Lookback time.Duration
+	Max      int // newest unread messages considered
+}
+
+// Message is an unread message as triage sees it.
+type Message struct {
+	UID      uint32    `json:"uid"`
+	From     string    `json:"from"`
+	Address  string    `json:"address"`
+	Subject  string    `json:"subject"`
+	Date     time.Time `json:"date,omitzero"`
+	Snippet  string    `json:"snippet,omitempty"`
+	Category Category  `json:"category"`
+	Rule     string    `json:"rule,omitempty"` // the sender rule that decided the category
+	bulk     bool
+}
+
+// Summary is the outcome of one triage run.
+type Summary struct {
+	Messages []Message
+}
+
+// Count returns how many messages are in c.
+func (s Summary) Count(c Category) int {
+	n := 0
+	for _, m := range s.Messages {
+		if m.Category == c {
+			n++
+		}
+	}
+	return n
+}
+
+// Format renders the summary: counts, then the messages needing action
+// and FYI with snippets, then newsletters by sender only.
+func (s Summary) Format() string {
+	if len(s.Messages) == 0 {
+		return "Inbox zero: no unread email."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Unread email (%d): %d need action, %d FYI, %d newsletters.",
+		len(s.Messages), s.Count(ActionNeeded), s.Count(FYI), s.Count(Newsletter))
+	for _, c := range []Category{ActionNeeded, FYI} {
+		if s.Count(c) == 0 {
+			continue
+		}
+		title := "Action needed:"
+		if c == FYI {
+			title = "FYI:"
+		}
+		sb.WriteString("\n\n" + title)
+		for _, m := range s.Messages {
+			if m.Category != c {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n- %s: %s", m.sender(), m.Subject)
+			if c == ActionNeeded && m.Snippet != "" {
+				sb.WriteString(" — " + m.Snippet)
+			}
+		}
+	}
+	if s.Count(Newsletter) > 0 {
+		var senders []string
+		for _, m := range s.Messages {
+			if m.Category == Newsletter && !slices.Contains(senders, m.sender()) {
+				senders = append(senders, m.sender())
+			}
+		}
+		sb.WriteString("\n\nNewsletters: " + strings.Join(senders, ", "))
+	}
+	return sb.String()
+}
+
+func (m Message) sender() string {
+	if m.From != "" {
+		return m.From
+	}
+	return m.Address
+}
+
+// Triager fetches and classifies unread email.
+type Triager struct {
+	account Account
+	state   *state.Manager
+	dial    func(ctx context.Context) (*Client, error)
+	fetch   func(ctx context.Context) ([]Message, error)
+}
+
+func NewTriager(account Account, workspace string) *Triager {
+	if account.Mailbox == "" {
+		account.Mailbox = "INBOX"
+	}
+	if account.Lookback <= 0 {
+		account.Lookback = defaultLookback
+	}
+	if account.Max <= 0 {
+		account.Max = defaultMax
+	}
+	t := &Triager{account: account, state: state.NewManager(workspace)}
+	t.dial = func(ctx context.Context) (*Client, error) { return Dial(ctx, account.Addr, account.Plain) }
+	t.fetch = t.fetchIMAP
+	return t
+}
+
+// Triage fetches unread messages and classifies them, newest first.
+func (t *Triager) Triage(ctx context.Context) (Summary, error) {
+	msgs, err := t.fetch(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	rules := t.Rules()
+	for i := range msgs {
+		msgs[i].Category, msgs[i].Rule = classify(msgs[i], rules)
+	}
+	slices.SortStableFunc(msgs, func(a, b Message) int { return b.Date.Compare(a.Date) })
+	return Summary{Messages: msgs}, nil
+}
+
+// Briefing returns the triage summary for the heartbeat once a day, or ""
+// when it was given already today or there is no unread email. A failed
+// fetch is retried on the next heartbeat.
+func (t *Triager) Briefing(now time.Time) string {
+	today := now.In(when.Location()).Format("2006-01-02")
+	var briefed string
+	t.state.Get(stateNamespace, "briefed", &briefed)
+	if briefed == today {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	s, err := t.Triage(ctx)
+	if err != nil {
+		logger.Warn("email: triage for briefing failed: %v", err)
+		return ""
+	}
+	if err := t.state.Set(stateNamespace, "briefed", today); err != nil {
+		logger.Warn("email: failed to save briefing state: %v", err)
+	}
+	if len(s.Messages) == 0 {
+		return ""
+	}
+	return "Email triage (read-only, nothing was marked read):\n" + s.Format()
+}
+
+// Rules returns the sender rules: an address or "@domain" mapped to the
+// category its mail always gets.
+func (t *Triager) Rules() map[string]Category {
+	rules := make(map[string]Category)
+	for _, k := range t.state.Keys(rulesNamespace) {
+		var c Category
+		if ok, _ := t.state.Get(rulesNamespace, k, &c); ok {
+			rules[k] = c
+		}
+	}
+	return rules
+}
+
+// SetRule files all mail from sender (an address, "@domain" or
+// "domain") under c.
+func (t *Triager) SetRule(sender string, c Category) (string, error) {
+	key := ruleKey(sender)
+	if key == "" {
+		return "", fmt.Errorf("invalid sender %q: use an address or @domain", sender)
+	}
+	if !slices.Contains(Categories, c) {
+		return "", fmt.Errorf("unknown category %q", c)
+	}
+	return key, t.state.Set(rulesNamespace, key, c)
+}
+
+// RemoveRule deletes the rule for sender.
+func (t *Triager) RemoveRule(sender string) error {
+	key := ruleKey(sender)
+	if _, ok := t.Rules()[key]; !ok {
+		return ErrNoRule
+	}
+	return t.state.Delete(rulesNamespace, key)
+}
+
+// RuleList returns the rules sorted by sender, one "sender: category" per
+// line.
+func (t *Triager) RuleList() []string {
+	rules := t.Rules()
+	var out []string
+	for _, k := range slices.Sorted(maps.Keys(rules)) {
+		out = append(out, fmt.Sprintf("%s: %s", k, rules[k]))
+	}
+	return out
+}
+
+// ruleKey normalizes a sender to an address or "@domain".
+func ruleKey(sender string) string {
+	s := strings.ToLower(strings.TrimSpace(sender))
+	if a, err := mail.ParseAddress(s); err == nil {
+		s = a.Address
+	}
+	switch {
+	case s == "" || strings.ContainsAny(s, " \t,<>"):
+		return ""
+	case strings.HasPrefix(s, "@"):
+		if len(s) == 1 {
+			return ""
+		}
+		return s
+	case !strings.Contains(s, "@"):
+		return "@" + s
+	}
+	return s
+}
+
+var (
+	automatedSender = regexp.MustCompile(`^(no-?reply|do-?not-?reply|notifications?|alerts?|mailer-daemon|bounces?)[+@.-]`)
+	actionPhrases   = []string{
+		"action required", "action needed", "please", "can you", "could you", "would you", "urgent", "asap",
+		"deadline", "reminder:", "reply", "respond", "confirm", "approve", "approval", "sign", "rsvp",
+		"invoice", "payment due", "overdue", "expires", "question",
+	}
+)
+
+// classify picks m's category: a rule for its address or domain wins,
+// then list headers mark newsletters, automated senders are FYI, and
+// requests or questions to the user need action.
+func classify(m Message, rules map[string]Category) (Category, string) {
+	addr := strings.ToLower(m.Address)
+	if c, ok := rules[addr]; ok {
+		return c, addr
+	}
+	if _, domain, ok := strings.Cut(addr, "@"); ok {
+		// Subdomains fall under a rule for the parent domain.
+		for d := domain; d != ""; {
+			if c, ok := rules["@"+d]; ok {
+				return c, "@" + d
+			}
+			_, d, _ = strings.Cut(d, ".")
+			if !strings.Contains(d, ".") {
+				break
+			}
+		}
+	}
+	if m.bulk {
+		return Newsletter, ""
+	}
+	if automatedSender.MatchString(addr) {
+		return FYI, ""
+	}
+	text := strings.ToLower(m.Subject + " " + m.Snippet)
+	if strings.Contains(m.Subject, "?") {
+		return ActionNeeded, ""
+	}
+	for _, p := range actionPhrases {
+		if strings.Contains(text, p) {
+			return ActionNeeded, ""
+		}
+	}
+	return FYI, ""
+}
+
+// fetchIMAP reads the newest unread messages of the account.
+func (t *Triager) fetchIMAP(ctx context.Context) ([]Message, error) {
+	a := t.account
+	c, err := t.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", a.Addr, err)
+	}
+	defer c.Close()
+	if err := c.Login(a.Username, a.Password); err != nil {
+		return nil, err
+	}
+	if err := c.Examine(a.Mailbox); err != nil {
+		return nil, err
+	}
+	uids, err := c.SearchUnseen(time.Now().Add(-a.Lookback))
+	if err != nil {
+		return nil, err
+	}
+	if len(uids) > a.Max {
+		uids = uids[len(uids)-a.Max:]
+	}
+	raw, err := c.fetchPreview(uids, snippetBytes)
+	if err != nil {
+		return nil, err
+	}
+	c.Logout()
+	msgs := make([]Message, 0, len(raw))
+	for _, f := range raw {
+		msgs = append(msgs, parseMessage(f))
+	}
+	return msgs, nil
+}
+
+var wordDecoder = mime.WordDecoder{}
+
+// parseMessage builds a Message from fetched headers and body text.
+func parseMessage(f fetched) Message {
+	m := Message{UID: f.uid}
+	hdr, err := mail.ReadMessage(bytes.NewReader(append(bytes.TrimRight(f.header, "\r\n"), "\r\n\r\n"...)))
+	if err != nil {
+		return m
+	}
+	h := hdr.Header
+	if a, err := mail.ParseAddress(h.Get("From")); err == nil {
+		m.From, m.Address = a.Name, strings.ToLower(a.Address)
+	} else {
+		m.From = h.Get("From")
+	}
+	m.Subject = h.Get("Subject")
+	if s, err := wordDecoder.DecodeHeader(m.Subject); err == nil {
+		m.Subject = s
+	}
+	if m.Subject == "" {
+		m.Subject = "(no subject)"
+	}
+	m.Date, _ = h.Date()
+	prec := strings.ToLower(h.Get("Precedence"))
+	m.bulk = h.Get("List-Id") != "" || h.Get("List-Unsubscribe") != "" || prec == "bulk" || prec == "list"
+	m.Snippet = snippet(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), f.text)
+	return m
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`(?s)<(style|script)\b.*?</(style|script)>|<[^>]*>`)
+	htmlBreak  = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// snippet returns the start of the readable text of a (possibly
+// truncated) body, preferring text/plain parts.
+func snippet(contentType, encoding string, body []byte) string {
+	text, html := bodyText(contentType, encoding, body, 0)
+	if text == "" {
+		text = HTMLText(html)
+	}
+	text = strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+	if r := []rune(text); len(r) > snippetRunes {
+		text = strings.TrimSpace(string(r[:snippetRunes])) + "…"
+	}
+	return text
+}
+
+// HTMLText is the text of an HTML body, with a line break for each
+// paragraph, line break and list item.
+func HTMLText(html string) string {
+	text := htmlBreak.ReplaceAllString(html, "\n")
+	text = htmlTag.ReplaceAllString(text, " ")
+	return strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'").Replace(text)
+}
+
+// bodyText returns the first plain and HTML text found in body.
+func bodyText(contentType, encoding string, body []byte, depth int) (plain, html string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && depth < 3 {
+		mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for plain == "" {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				break
+			}
+			// The body is cut off, so the last part read may end early.
+			data, _ := io.ReadAll(p)
+			pt, ph := bodyText(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), data, depth+1)
+			plain = pt
+			if html == "" {
+				html = ph
+			}
+		}
+		return plain, html
+	}
+	data := Decode(encoding, body)
+	switch mediaType {
+	case "text/plain":
+		return string(data), ""
+	case "text/html":
+		return "", string(data)
+	}
+	return "", ""
+}
+
+// Decode undoes a transfer encoding, keeping what decodes of a truncated
+// body.
+func Decode(encoding string, body []byte) []byte {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		data, _ := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		return data
+	case "base64":
+		clean := bytes.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' {
+				return -1
+			}
+			return r
+		}, body)
+		clean = clean[:len(clean)/4*4]
+		data := make([]byte, base64.StdEncoding.DecodedLen(len(clean)))
+		n, _ := base64.StdEncoding.Decode(data, clean)
+		return data[:n]
+	}
+	return body
+}
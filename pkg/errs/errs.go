@@ -0,0 +1,73 @@
+// Package errs defines the kinds of failure providers and tools wrap their
+// errors in, so the agent loop, retries and channels can react with
+// errors.Is (back off, ask for reconfiguration, apologize) instead of
+// matching error text.
+package errs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+var (
+	ErrRateLimited = errors.New("rate limited")
+	ErrAuth        = errors.New("authentication failed")
+	ErrNotFound    = errors.New("not found")
+	ErrTimeout     = errors.New("timed out")
+)
+
+var kinds = []error{ErrRateLimited, ErrAuth, ErrNotFound, ErrTimeout}
+
+// kindError tags err with a kind without changing its message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// Wrap tags err with kind, one of the Err* values, keeping its message.
+// A nil kind or err returns err unchanged.
+func Wrap(kind, err error) error {
+	if kind == nil || err == nil {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// FromStatus returns the kind of an HTTP status code, or nil for codes
+// without one.
+func FromStatus(code int) error {
+	switch code {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrTimeout
+	}
+	return nil
+}
+
+// Kind returns the kind of err: a wrapped Err* value, or ErrTimeout for
+// deadlines and network timeouts. It returns nil when the kind is unknown.
+func Kind(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, k := range kinds {
+		if errors.Is(err, k) {
+			return k
+		}
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestWrapKeepsMessageAndChain(t *testing.T) {
+	base := errors.New("HTTP 429: slow down")
+	err := fmt.Errorf("search failed: %w", Wrap(ErrRateLimited, base))
+	if err.Error() != "search failed: HTTP 429: slow down" {
+		t.Errorf("message = %q", err)
+	}
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, base) {
+		t.Error("wrapped error lost its kind or cause")
+	}
+	if Wrap(nil, base) != base || Wrap(ErrAuth, nil) != nil {
+		t.Error("Wrap with a nil kind or error should pass err through")
+	}
+}
+
+func TestKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{Wrap(FromStatus(http.StatusTooManyRequests), errors.New("x")), ErrRateLimited},
+		{Wrap(FromStatus(http.StatusForbidden), errors.New("x")), ErrAuth},
+		{Wrap(FromStatus(http.StatusGone), errors.New("x")), ErrNotFound},
+		{fmt.Errorf("fetch: %w", context.DeadlineExceeded), ErrTimeout},
+		{Wrap(FromStatus(http.StatusInternalServerError), errors.New("x")), nil},
+		{errors.New("boom"), nil},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := Kind(tt.err); got != tt.want {
+			t.Errorf("Kind(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
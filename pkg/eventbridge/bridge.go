@@ -0,0 +1,233 @@
+// Package eventbridge mirrors activity events and bus messages to an MQTT
+// or NATS broker, so other home automation components can react to the
+// agent. Publishing is best effort: events are queued, and dropped while
+// the broker is unreachable and the queue is full.
+package eventbridge
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"localagent/pkg/activity"
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+	"localagent/pkg/mqtt"
+	"localagent/pkg/redact"
+)
+
+const queueSize = 256
+
+// conn is a broker connection that can publish.
+type conn interface {
+	Publish(topic string, payload []byte) error
+	Ping() error
+	Done() <-chan struct{} // closed when the connection drops
+	Close() error
+}
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// Bridge is an activity.Emitter and bus.Observer that forwards to a broker.
+type Bridge struct {
+	cfg      config.BridgeConfig
+	scheme   string
+	host     string
+	password string
+	redactor *redact.Redactor
+	queue    chan message
+	dropped  atomic.Int64
+	cancel   context.CancelFunc
+	stopped  chan struct{}
+}
+
+// New validates cfg. Call Start to connect.
+func New(cfg config.BridgeConfig, r *redact.Redactor) (*Bridge, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	port := map[string]string{"mqtt": "1883", "mqtts": "8883", "nats": "4222", "nats+tls": "4222"}[u.Scheme]
+	if port == "" || u.Hostname() == "" {
+		return nil, fmt.Errorf("url %q: want mqtt://, mqtts://, nats:// or nats+tls://host[:port]", cfg.URL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "localagent"
+	}
+	sep := "/"
+	if strings.HasPrefix(u.Scheme, "nats") {
+		sep = "." // NATS subjects are dot-separated
+	}
+	for _, t := range []struct {
+		topic *string
+		def   string
+	}{{&cfg.Activity, "activity/{type}"}, {&cfg.Inbound, "inbound/{channel}"}, {&cfg.Outbound, "outbound/{channel}"}} {
+		if *t.topic == "" {
+			*t.topic = strings.ReplaceAll("localagent/"+t.def, "/", sep)
+		}
+	}
+	return &Bridge{
+		cfg:      cfg,
+		scheme:   u.Scheme,
+		host:     host,
+		password: cfg.ResolvePassword(),
+		redactor: r,
+		queue:    make(chan message, queueSize),
+	}, nil
+}
+
+// Start connects in the background and keeps reconnecting until Stop.
+func (b *Bridge) Start(ctx context.Context) {
+	ctx, b.cancel = context.WithCancel(ctx)
+	b.stopped = make(chan struct{})
+	go b.run(ctx)
+}
+
+// Stop disconnects; queued events that were not sent are dropped.
+func (b *Bridge) Stop() {
+	if b.cancel == nil {
+		return
+	}
+	b.cancel()
+	<-b.stopped
+}
+
+func (b *Bridge) Emit(e activity.Event) {
+	if len(b.cfg.Events) > 0 && !slices.Contains(b.cfg.Events, string(e.Type)) {
+		return
+	}
+	e.Message = b.redactor.String(e.Message)
+	e.Detail = b.redactor.Map(e.Detail)
+	b.enqueue(b.cfg.Activity, map[string]string{"type": string(e.Type)}, e)
+}
+
+func (b *Bridge) ObserveInbound(msg bus.InboundMessage) {
+	msg.Content = b.redactor.String(msg.Content)
+	b.enqueue(b.cfg.Inbound, map[string]string{"channel": msg.Channel, "chat_id": msg.ChatID}, msg)
+}
+
+func (b *Bridge) ObserveOutbound(msg bus.OutboundMessage) {
+	msg.Content = b.redactor.String(msg.Content)
+	b.enqueue(b.cfg.Outbound, map[string]string{"channel": msg.Channel, "chat_id": msg.ChatID}, msg)
+}
+
+func (b *Bridge) enqueue(topic string, vars map[string]string, v any) {
+	if topic == "-" {
+		return
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	select {
+	case b.queue <- message{topic: expandTopic(topic, vars), payload: payload}:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// expandTopic fills {name} placeholders, replacing characters that are
+// special in MQTT topics or NATS subjects.
+func expandTopic(topic string, vars map[string]string) string {
+	clean := strings.NewReplacer("/", "_", ".", "_", "+", "_", "#", "_", "*", "_", ">", "_", " ", "_")
+	for k, v := range vars {
+		if v == "" {
+			v = "none"
+		}
+		topic = strings.ReplaceAll(topic, "{"+k+"}", clean.Replace(v))
+	}
+	return topic
+}
+
+func (b *Bridge) run(ctx context.Context) {
+	defer close(b.stopped)
+	backoff := time.Second
+	for ctx.Err() == nil {
+		c, err := b.dial(ctx)
+		if err != nil {
+			logger.Warn("event bridge: connecting to %s: %v (retrying in %v)", b.host, err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, time.Minute)
+			continue
+		}
+		logger.Info("event bridge connected to %s://%s", b.scheme, b.host)
+		backoff = time.Second
+		err = b.publish(ctx, c)
+		c.Close()
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("event bridge: connection to %s lost: %v", b.host, err)
+		}
+	}
+}
+
+// publish sends queued messages on c until it fails or ctx ends.
+func (b *Bridge) publish(ctx context.Context, c conn) error {
+	ping := time.NewTicker(mqtt.KeepAlive / 2)
+	defer ping.Stop()
+	for {
+		if n := b.dropped.Swap(0); n > 0 {
+			logger.Warn("event bridge: dropped %d events while the queue was full", n)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.Done():
+			return fmt.Errorf("connection closed")
+		case <-ping.C:
+			if err := c.Ping(); err != nil {
+				return err
+			}
+		case m := <-b.queue:
+			if err := c.Publish(m.topic, m.payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *Bridge) dial(ctx context.Context) (conn, error) {
+	if !strings.HasPrefix(b.scheme, "nats") {
+		c, err := mqtt.Dial(ctx, b.host, b.scheme == "mqtts", mqtt.Options{ClientID: b.cfg.ClientID, Username: b.cfg.Username, Password: b.password})
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	d := net.Dialer{Timeout: 10 * time.Second}
+	var nc net.Conn
+	var err error
+	if b.scheme == "nats+tls" {
+		td := tls.Dialer{NetDialer: &d}
+		nc, err = td.DialContext(ctx, "tcp", b.host)
+	} else {
+		nc, err = d.DialContext(ctx, "tcp", b.host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialNATS(nc, b.cfg.ClientID, b.cfg.Username, b.password)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,146 @@
+package eventbridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/activity"
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+	"localagent/pkg/mqtt"
+)
+
+// fakeMQTT accepts one client, acknowledges CONNECT and sends every
+// PUBLISH as "topic payload" on the returned channel.
+func fakeMQTT(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan string, 10)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			header, body, err := mqtt.ReadPacket(r)
+			if err != nil {
+				return
+			}
+			switch header & 0xF0 {
+			case 0x10:
+				got <- "CONNECT " + string(body[12:22]) // client id after the 2-byte length
+				c.Write([]byte{0x20, 2, 0, 0})
+			case 0x30:
+				n := int(body[0])<<8 | int(body[1])
+				got <- string(body[2:2+n]) + " " + string(body[2+n:])
+			}
+		}
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestMQTTBridge(t *testing.T) {
+	addr, got := fakeMQTT(t)
+	b, err := New(config.BridgeConfig{URL: "mqtt://" + addr, Events: []string{"complete"}, Outbound: "home/agent/{channel}/{chat_id}"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Start(context.Background())
+	defer b.Stop()
+
+	if first := recv(t, got); first != "CONNECT localagent" {
+		t.Fatalf("connect = %q", first)
+	}
+	b.Emit(activity.Event{Type: activity.ToolExec, Message: "ignored"})
+	b.Emit(activity.Event{Type: activity.Complete, Message: "done"})
+	b.ObserveOutbound(bus.OutboundMessage{Channel: "telegram", ChatID: "a/b", Content: "Heartbeat alert"})
+
+	if m := recv(t, got); !strings.HasPrefix(m, `localagent/activity/complete {"type":"complete"`) {
+		t.Errorf("activity = %q", m)
+	}
+	if m := recv(t, got); m != `home/agent/telegram/a_b {"channel":"telegram","chat_id":"a/b","content":"Heartbeat alert"}` {
+		t.Errorf("outbound = %q", m)
+	}
+}
+
+func TestNATSBridge(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string, 10)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		fmt.Fprint(c, "INFO {}\r\n")
+		r := bufio.NewReader(c)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				var opts map[string]any
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts)
+				got <- fmt.Sprintf("CONNECT %v", opts["user"])
+			case line == "PING\r\n":
+				fmt.Fprint(c, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				payload, _ := r.ReadString('\n')
+				got <- strings.TrimSpace(line) + " " + strings.TrimSpace(payload)
+			}
+		}
+	}()
+
+	t.Setenv("BRIDGE_PASS", "hunter2-secret")
+	b, err := New(config.BridgeConfig{URL: "nats://" + ln.Addr().String(), Username: "agent", PasswordEnv: "BRIDGE_PASS"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Start(context.Background())
+	defer b.Stop()
+
+	if m := recv(t, got); m != "CONNECT agent" {
+		t.Fatalf("connect = %q", m)
+	}
+	b.ObserveInbound(bus.InboundMessage{Channel: "web", ChatID: "1", Content: "hi"})
+	if m := recv(t, got); !strings.HasPrefix(m, "PUB localagent.inbound.web ") || !strings.Contains(m, `"content":"hi"`) {
+		t.Errorf("inbound = %q", m)
+	}
+}
+
+func TestNewRejectsBadURL(t *testing.T) {
+	for _, u := range []string{"http://broker", "mqtt://", "broker:1883"} {
+		if _, err := New(config.BridgeConfig{URL: u}, nil); err == nil {
+			t.Errorf("%q should be rejected", u)
+		}
+	}
+}
+
+func recv(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the broker")
+		return ""
+	}
+}
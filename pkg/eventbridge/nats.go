@@ -0,0 +1,97 @@
+package eventbridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsConn is a minimal NATS client that only publishes.
+type natsConn struct {
+	conn net.Conn
+	mu   sync.Mutex // serializes writes
+	done chan struct{}
+	err  error // set before done is closed
+}
+
+func dialNATS(conn net.Conn, name, username, password string) (*natsConn, error) {
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading INFO: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return nil, fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "name": name, "lang": "go", "version": "localagent"}
+	if username != "" {
+		opts["user"], opts["pass"] = username, password
+	}
+	connect, _ := json.Marshal(opts)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return nil, err
+	}
+	// The server answers the PING with PONG once CONNECT was accepted.
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading PONG: %w", err)
+	}
+	if line = strings.TrimSpace(line); line != "PONG" {
+		return nil, fmt.Errorf("server refused connection: %s", line)
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{conn: conn, done: make(chan struct{})}
+	go c.readLoop(r)
+	return c, nil
+}
+
+// readLoop answers server PINGs and ends on errors or a dropped connection.
+func (c *natsConn) readLoop(r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err == nil {
+			switch line = strings.TrimSpace(line); {
+			case line == "PING":
+				err = c.write([]byte("PONG\r\n"))
+			case strings.HasPrefix(line, "-ERR"):
+				err = fmt.Errorf("server error: %s", line)
+			}
+		}
+		if err != nil {
+			c.err = err
+			close(c.done)
+			return
+		}
+	}
+}
+
+func (c *natsConn) Publish(subject string, payload []byte) error {
+	b := fmt.Appendf(nil, "PUB %s %d\r\n", subject, len(payload))
+	b = append(b, payload...)
+	return c.write(append(b, '\r', '\n'))
+}
+
+func (c *natsConn) Ping() error {
+	return c.write([]byte("PING\r\n"))
+}
+
+func (c *natsConn) Done() <-chan struct{} { return c.done }
+
+func (c *natsConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *natsConn) write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(b)
+	return err
+}
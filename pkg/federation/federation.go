@@ -0,0 +1,177 @@
+// Package federation lets localagent instances delegate tasks to each
+// other. A peer POSTs a task to /federation/task on the gateway with its
+// shared bearer token; the answer streams back as newline-delimited JSON
+// events while the receiving agent works on it.
+package federation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+// Event types streamed back to the caller.
+const (
+	EventStatus = "status" // progress, e.g. a tool the remote agent ran
+	EventResult = "result" // final answer, ends the stream
+	EventError  = "error"  // failure, ends the stream
+)
+
+// Event is one line of the response stream.
+type Event struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type taskRequest struct {
+	Task string `json:"task"`
+}
+
+// maxTaskBytes caps the request body.
+const maxTaskBytes = 64 << 10
+
+// senderPrefix marks inbound messages that carry a peer's answer.
+const senderPrefix = "remote:"
+
+// SenderID is the sender of the message that delivers peer's answer.
+func SenderID(peer string) string {
+	return senderPrefix + peer
+}
+
+// IsRemoteSender reports whether an inbound message carries a peer's answer
+// rather than something the user wrote.
+func IsRemoteSender(senderID string) bool {
+	return strings.HasPrefix(senderID, senderPrefix)
+}
+
+// RunFunc processes a task for peer, reporting progress through status.
+type RunFunc func(ctx context.Context, peer, task string, status func(string)) (string, error)
+
+// Handler serves /federation/task for the peers with Accept set. A request
+// is attributed to the peer whose token it carries.
+func Handler(peers []config.PeerConfig, run RunFunc) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/federation/task", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		peer, ok := authenticate(peers, r.Header.Get("Authorization"))
+		if !ok {
+			logger.Warn("federation: rejected task from %s", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req taskRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxTaskBytes)).Decode(&req); err != nil || strings.TrimSpace(req.Task) == "" {
+			http.Error(w, "task is required", http.StatusBadRequest)
+			return
+		}
+
+		logger.Info("federation: task from %s (%d chars)", peer, len(req.Task))
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		enc := json.NewEncoder(w)
+		send := func(e Event) {
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		result, err := run(r.Context(), peer, req.Task, func(text string) {
+			send(Event{Type: EventStatus, Text: text})
+		})
+		if err != nil {
+			logger.Error("federation: task from %s failed: %v", peer, err)
+			send(Event{Type: EventError, Text: err.Error()})
+			return
+		}
+		send(Event{Type: EventResult, Text: result})
+	})
+	return mux
+}
+
+// authenticate returns the accepting peer whose token matches the bearer
+// token in header.
+func authenticate(peers []config.PeerConfig, header string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	for _, p := range peers {
+		want := p.ResolveToken()
+		if !p.Accept || want == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// Delegate sends task to peer and returns its answer, passing status
+// events to onStatus as they arrive.
+func Delegate(ctx context.Context, client *http.Client, peer config.PeerConfig, task string, onStatus func(string)) (string, error) {
+	if peer.URL == "" {
+		return "", fmt.Errorf("peer %q has no url", peer.Name)
+	}
+	token := peer.ResolveToken()
+	if token == "" {
+		return "", fmt.Errorf("peer %q: token env %q is not set", peer.Name, peer.TokenEnv)
+	}
+	body, _ := json.Marshal(taskRequest{Task: task})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(peer.URL, "/")+"/federation/task", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("peer %q: %s: %s", peer.Name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return "", fmt.Errorf("peer %q: bad event: %w", peer.Name, err)
+		}
+		switch e.Type {
+		case EventStatus:
+			if onStatus != nil {
+				onStatus(e.Text)
+			}
+		case EventResult:
+			return e.Text, nil
+		case EventError:
+			return "", fmt.Errorf("peer %q: %s", peer.Name, e.Text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("peer " + peer.Name + " closed the stream without an answer")
+}
@@ -0,0 +1,76 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestDelegateStreamsStatusAndResult(t *testing.T) {
+	t.Setenv("FED_LAPTOP", "s3cret")
+	var gotPeer, gotTask string
+	srv := httptest.NewServer(Handler(
+		[]config.PeerConfig{{Name: "home", TokenEnv: "FED_LAPTOP", Accept: true}},
+		func(_ context.Context, peer, task string, status func(string)) (string, error) {
+			gotPeer, gotTask = peer, task
+			status("read_file — success")
+			return "3 files", nil
+		},
+	))
+	defer srv.Close()
+
+	var statuses []string
+	answer, err := Delegate(context.Background(), srv.Client(),
+		config.PeerConfig{Name: "laptop", URL: srv.URL, TokenEnv: "FED_LAPTOP"},
+		"count files in ~/notes", func(s string) { statuses = append(statuses, s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "3 files" || gotPeer != "home" || gotTask != "count files in ~/notes" {
+		t.Errorf("answer=%q peer=%q task=%q", answer, gotPeer, gotTask)
+	}
+	if len(statuses) != 1 || statuses[0] != "read_file — success" {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
+
+func TestHandlerRejectsUnknownOrNonAcceptingPeers(t *testing.T) {
+	t.Setenv("FED_A", "token-a")
+	t.Setenv("FED_B", "token-b")
+	srv := httptest.NewServer(Handler(
+		[]config.PeerConfig{
+			{Name: "a", TokenEnv: "FED_A", Accept: true},
+			{Name: "b", TokenEnv: "FED_B"}, // we call b, b may not call us
+		},
+		func(context.Context, string, string, func(string)) (string, error) { return "ok", nil },
+	))
+	defer srv.Close()
+
+	for _, env := range []string{"FED_B", "FED_NONE"} {
+		t.Setenv("FED_NONE", "wrong")
+		_, err := Delegate(context.Background(), srv.Client(), config.PeerConfig{Name: "x", URL: srv.URL, TokenEnv: env}, "hi", nil)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("%s: err = %v, want 401", env, err)
+		}
+	}
+}
+
+func TestDelegateReportsRemoteError(t *testing.T) {
+	t.Setenv("FED_A", "token-a")
+	srv := httptest.NewServer(Handler(
+		[]config.PeerConfig{{Name: "a", TokenEnv: "FED_A", Accept: true}},
+		func(context.Context, string, string, func(string)) (string, error) {
+			return "", errors.New("model offline")
+		},
+	))
+	defer srv.Close()
+
+	_, err := Delegate(context.Background(), srv.Client(), config.PeerConfig{Name: "a", URL: srv.URL, TokenEnv: "FED_A"}, "hi", nil)
+	if err == nil || !strings.Contains(err.Error(), "model offline") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Package filelock provides advisory locks on files shared between
+// localagent processes, such as the gateway and a concurrent
+// `localagent agent` run. Locks are taken on a sibling "<path>.lock" file so
+// the data file itself can be replaced by an atomic rename while held.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Timeout bounds how long Lock and RLock retry before giving up, so a hung
+// process holding a lock can't block writers forever.
+var Timeout = 10 * time.Second
+
+const retryInterval = 20 * time.Millisecond
+
+// ErrTimeout is returned when the lock could not be acquired within Timeout.
+var ErrTimeout = errors.New("timed out waiting for file lock")
+
+// Lock waits for an exclusive lock on path and returns a func that
+// releases it.
+func Lock(path string) (unlock func(), err error) {
+	return lock(path, true)
+}
+
+// RLock waits for a shared lock on path.
+func RLock(path string) (unlock func(), err error) {
+	return lock(path, false)
+}
+
+func lock(path string, exclusive bool) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(Timeout)
+	for {
+		ok, err := tryLock(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrTimeout)
+		}
+		time.Sleep(retryInterval)
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
+
+// Remove deletes path's lock file once path itself is gone, so archived or
+// deleted data leaves no lock file behind. It takes the lock first; a
+// process that opened the lock file before the removal ends up locking the
+// unlinked file, which is harmless since nothing writes path any more.
+func Remove(path string) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+	return os.Remove(path + ".lock")
+}
@@ -0,0 +1,56 @@
+package filelock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockExcludes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		u, err := Lock(path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		close(acquired)
+		u()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock acquired while the first was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second Lock not acquired after unlock")
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	old := Timeout
+	Timeout = 50 * time.Millisecond
+	defer func() { Timeout = old }()
+
+	path := filepath.Join(t.TempDir(), "store.json")
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	if _, err := RLock(path); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout while exclusively locked, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// Advisory locking is unix-only; elsewhere Lock always succeeds and writers
+// rely on atomic renames alone.
+func tryLock(*os.File, bool) (bool, error) { return true, nil }
+
+func unlock(*os.File) {}
@@ -4,12 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"strings"
 	"sync"
 	"time"
+
+	"localagent/pkg/httpclient"
 )
 
 // Value represents a Yahoo Finance formatted value with raw number and display string.
@@ -28,10 +29,7 @@ type YahooClient struct {
 func NewYahooClient() *YahooClient {
 	jar, _ := cookiejar.New(nil)
 	return &YahooClient{
-		client: &http.Client{
-			Timeout: 15 * time.Second,
-			Jar:     jar,
-		},
+		client: httpclient.New("finance", httpclient.WithTimeout(15*time.Second), httpclient.WithJar(jar)),
 	}
 }
 
@@ -65,7 +63,7 @@ func (yc *YahooClient) getCrumb(ctx context.Context) (string, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp)
 	if err != nil {
 		return "", err
 	}
@@ -102,7 +100,7 @@ func (yc *YahooClient) get(ctx context.Context, url string) ([]byte, error) {
 		return nil, fmt.Errorf("Yahoo Finance returned status %d", resp.StatusCode)
 	}
 
-	return io.ReadAll(resp.Body)
+	return httpclient.ReadBody(resp)
 }
 
 // FetchQuoteSummary fetches a quoteSummary module for a symbol, with automatic crumb retry.
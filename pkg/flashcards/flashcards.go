@@ -0,0 +1,164 @@
+// Package flashcards stores spaced-repetition cards and schedules their
+// reviews with SM-2. Cards live in the same SQLite database as tasks.
+package flashcards
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/utils"
+)
+
+type Card struct {
+	ID           string  `json:"id"`
+	Deck         string  `json:"deck,omitempty"`
+	Front        string  `json:"front"` // the prompt
+	Back         string  `json:"back"`  // the answer
+	Ease         float64 `json:"ease"`
+	IntervalDays int     `json:"intervalDays"`
+	Reps         int     `json:"reps"` // successful reviews in a row
+	Lapses       int     `json:"lapses"`
+	DueAtMS      int64   `json:"dueAtMs"`
+	ReviewedAtMS int64   `json:"reviewedAtMs,omitempty"`
+	CreatedAtMS  int64   `json:"createdAtMs"`
+}
+
+// Due reports whether the card should be reviewed at now.
+func (c Card) Due(now time.Time) bool {
+	return c.DueAtMS <= now.UnixMilli()
+}
+
+type Service struct {
+	q   *dbq.Queries
+	now func() time.Time
+}
+
+func NewService(database *sql.DB) *Service {
+	return &Service{q: dbq.New(database), now: time.Now}
+}
+
+// Add creates a card that is due at once.
+func (s *Service) Add(deck, front, back string) (*Card, error) {
+	front, back = strings.TrimSpace(front), strings.TrimSpace(back)
+	if front == "" || back == "" {
+		return nil, fmt.Errorf("front and back are required")
+	}
+	now := s.now().UnixMilli()
+	c := Card{
+		ID:          utils.RandHex(8),
+		Deck:        strings.TrimSpace(deck),
+		Front:       front,
+		Back:        back,
+		Ease:        initialEase,
+		DueAtMS:     now,
+		CreatedAtMS: now,
+	}
+	err := s.q.InsertFlashcard(context.Background(), dbq.InsertFlashcardParams{
+		ID:           c.ID,
+		Deck:         c.Deck,
+		Front:        c.Front,
+		Back:         c.Back,
+		Ease:         c.Ease,
+		IntervalDays: int64(c.IntervalDays),
+		Reps:         int64(c.Reps),
+		Lapses:       int64(c.Lapses),
+		DueAtMs:      c.DueAtMS,
+		ReviewedAtMs: c.ReviewedAtMS,
+		CreatedAtMs:  c.CreatedAtMS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Service) Get(id string) *Card {
+	row, err := s.q.GetFlashcard(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	c := dbCardToCard(row)
+	return &c
+}
+
+// List returns the cards of deck, or all cards when deck is empty.
+func (s *Service) List(deck string) []Card {
+	rows, err := s.q.ListFlashcards(context.Background())
+	if err != nil {
+		return nil
+	}
+	return filterDeck(rows, deck)
+}
+
+// Due returns the cards of deck (all decks when empty) due now, most
+// overdue first.
+func (s *Service) Due(deck string) []Card {
+	rows, err := s.q.ListDueFlashcards(context.Background(), s.now().UnixMilli())
+	if err != nil {
+		return nil
+	}
+	return filterDeck(rows, deck)
+}
+
+// Answer records a review of card id with SM-2 quality q and returns the
+// rescheduled card.
+func (s *Service) Answer(id string, q int) (*Card, error) {
+	c := s.Get(id)
+	if c == nil {
+		return nil, fmt.Errorf("card not found: %s", id)
+	}
+	next := Schedule(*c, q, s.now())
+	err := s.q.UpdateFlashcardSchedule(context.Background(), dbq.UpdateFlashcardScheduleParams{
+		Ease:         next.Ease,
+		IntervalDays: int64(next.IntervalDays),
+		Reps:         int64(next.Reps),
+		Lapses:       int64(next.Lapses),
+		DueAtMs:      next.DueAtMS,
+		ReviewedAtMs: next.ReviewedAtMS,
+		ID:           id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+func (s *Service) Remove(id string) bool {
+	res, err := s.q.DeleteFlashcard(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func filterDeck(rows []dbq.Flashcard, deck string) []Card {
+	var cards []Card
+	for _, r := range rows {
+		if deck != "" && !strings.EqualFold(r.Deck, deck) {
+			continue
+		}
+		cards = append(cards, dbCardToCard(r))
+	}
+	return cards
+}
+
+func dbCardToCard(r dbq.Flashcard) Card {
+	return Card{
+		ID:           r.ID,
+		Deck:         r.Deck,
+		Front:        r.Front,
+		Back:         r.Back,
+		Ease:         r.Ease,
+		IntervalDays: int(r.IntervalDays),
+		Reps:         int(r.Reps),
+		Lapses:       int(r.Lapses),
+		DueAtMS:      r.DueAtMs,
+		ReviewedAtMS: r.ReviewedAtMs,
+		CreatedAtMS:  r.CreatedAtMs,
+	}
+}
@@ -0,0 +1,110 @@
+package flashcards
+
+import (
+	"testing"
+	"time"
+
+	"localagent/pkg/db"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewService(database)
+}
+
+func TestScheduleSM2(t *testing.T) {
+	now := time.Date(2026, 5, 4, 19, 30, 0, 0, time.UTC)
+	c := Card{Ease: initialEase}
+
+	var intervals []int
+	for range 4 {
+		c = Schedule(c, 4, now)
+		intervals = append(intervals, c.IntervalDays)
+	}
+	// 1, 6, then interval * ease; ease stays 2.5 with quality 4.
+	want := []int{1, 6, 15, 38}
+	for i := range want {
+		if intervals[i] != want[i] {
+			t.Fatalf("intervals = %v, want %v", intervals, want)
+		}
+	}
+	if due := time.UnixMilli(c.DueAtMS).UTC(); !due.Equal(time.Date(2026, 6, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("due = %s, want midnight 38 days later", due)
+	}
+
+	c = Schedule(c, 1, now)
+	if c.Reps != 0 || c.IntervalDays != 1 || c.Lapses != 1 {
+		t.Errorf("lapse: reps=%d interval=%d lapses=%d", c.Reps, c.IntervalDays, c.Lapses)
+	}
+	for range 10 {
+		c = Schedule(c, 0, now)
+	}
+	if c.Ease != minEase {
+		t.Errorf("ease = %v, want floor %v", c.Ease, minEase)
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	for in, want := range map[string]int{"again": 1, "Good": 4, "easy": 5, "0": 0, "3": 3} {
+		if got, err := ParseGrade(in); err != nil || got != want {
+			t.Errorf("ParseGrade(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"6", "meh", ""} {
+		if _, err := ParseGrade(in); err == nil {
+			t.Errorf("ParseGrade(%q) should fail", in)
+		}
+	}
+}
+
+func TestDueAndAnswer(t *testing.T) {
+	s := newTestService(t)
+	now := time.Date(2026, 5, 4, 19, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	word, err := s.Add("german", "der Schmetterling", "butterfly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add("capitals", "Australia", "Canberra")
+	if _, err := s.Add("german", "  ", "x"); err == nil {
+		t.Error("expected empty front to be rejected")
+	}
+
+	if n := len(s.Due("")); n != 2 {
+		t.Fatalf("got %d due cards, want 2", n)
+	}
+	if due := s.Due("German"); len(due) != 1 || due[0].ID != word.ID {
+		t.Fatalf("deck filter: %+v", due)
+	}
+
+	got, err := s.Answer(word.ID, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Reps != 1 || got.IntervalDays != 1 {
+		t.Errorf("after answer: reps=%d interval=%d", got.Reps, got.IntervalDays)
+	}
+	if n := len(s.Due("german")); n != 0 {
+		t.Errorf("answered card still due today")
+	}
+	s.now = func() time.Time { return now.Add(24 * time.Hour) }
+	if n := len(s.Due("german")); n != 1 {
+		t.Errorf("card not due the next day")
+	}
+	if stored := s.Get(word.ID); stored.Reps != 1 || stored.ReviewedAtMS != now.UnixMilli() {
+		t.Errorf("schedule not persisted: %+v", stored)
+	}
+
+	if _, err := s.Answer("missing", 4); err == nil {
+		t.Error("expected unknown card to fail")
+	}
+	if !s.Remove(word.ID) || s.Get(word.ID) != nil {
+		t.Error("card not removed")
+	}
+}
@@ -0,0 +1,63 @@
+package flashcards
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+)
+
+// Grades map the answer buttons people know from flashcard apps onto SM-2
+// quality (0-5).
+var grades = map[string]int{
+	"again": 1,
+	"hard":  3,
+	"good":  4,
+	"easy":  5,
+}
+
+// ParseGrade accepts again, hard, good, easy or an SM-2 quality 0-5.
+func ParseGrade(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if q, ok := grades[s]; ok {
+		return q, nil
+	}
+	if q, err := strconv.Atoi(s); err == nil && q >= 0 && q <= 5 {
+		return q, nil
+	}
+	return 0, fmt.Errorf("invalid grade %q (use again, hard, good, easy or 0-5)", s)
+}
+
+// Schedule applies an SM-2 review with quality q (0-5) at now. A failed
+// recall (q < 3) restarts the repetitions; the card is due again tomorrow.
+// Due dates are midnight in now's location so cards are due all day.
+func Schedule(c Card, q int, now time.Time) Card {
+	if q < 3 {
+		c.Reps = 0
+		c.IntervalDays = 1
+		c.Lapses++
+	} else {
+		c.Reps++
+		switch c.Reps {
+		case 1:
+			c.IntervalDays = 1
+		case 2:
+			c.IntervalDays = 6
+		default:
+			c.IntervalDays = int(math.Round(float64(c.IntervalDays) * c.Ease))
+		}
+	}
+	d := float64(5 - q)
+	c.Ease = max(c.Ease+0.1-d*(0.08+d*0.02), minEase)
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	c.DueAtMS = day.AddDate(0, 0, c.IntervalDays).UnixMilli()
+	c.ReviewedAtMS = now.UnixMilli()
+	return c
+}
@@ -0,0 +1,301 @@
+// Package goals stores the user's goals with their key results and judges
+// progress for periodic reviews. Goals live in the same SQLite database as
+// tasks; tasks tagged with a goal's tag count toward it.
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/todo"
+	"localagent/pkg/utils"
+)
+
+const (
+	StatusActive    = "active"
+	StatusAchieved  = "achieved"
+	StatusAbandoned = "abandoned"
+)
+
+// KeyResult is a measurable outcome of a goal: a number to reach
+// (Target > 0) or something to get done.
+type KeyResult struct {
+	Title   string  `json:"title"`
+	Target  float64 `json:"target,omitempty"`
+	Current float64 `json:"current,omitempty"`
+	Unit    string  `json:"unit,omitempty"`
+	Done    bool    `json:"done,omitempty"`
+}
+
+// Progress is the completed fraction, 0 to 1.
+func (kr KeyResult) Progress() float64 {
+	if kr.Target <= 0 {
+		if kr.Done {
+			return 1
+		}
+		return 0
+	}
+	return min(max(kr.Current/kr.Target, 0), 1)
+}
+
+type Goal struct {
+	ID           string      `json:"id"`
+	Title        string      `json:"title"`
+	Description  string      `json:"description,omitempty"`
+	Status       string      `json:"status"`
+	TargetDate   string      `json:"targetDate,omitempty"` // YYYY-MM-DD
+	KeyResults   []KeyResult `json:"keyResults,omitempty"`
+	Tag          string      `json:"tag,omitempty"` // tasks with this tag count toward the goal
+	ProgressAtMS int64       `json:"progressAtMs"`  // last recorded progress
+	CreatedAtMS  int64       `json:"createdAtMs"`
+	UpdatedAtMS  int64       `json:"updatedAtMs"`
+}
+
+type Service struct {
+	q     *dbq.Queries
+	tasks *todo.TodoService
+	now   func() time.Time
+}
+
+func NewService(database *sql.DB) *Service {
+	return &Service{q: dbq.New(database), now: time.Now}
+}
+
+// SetTodoService lets tasks tagged with a goal's tag count toward it.
+func (s *Service) SetTodoService(t *todo.TodoService) {
+	s.tasks = t
+}
+
+// List returns goals with status, or all goals when status is empty.
+// Active goals come first, soonest target date first.
+func (s *Service) List(status string) []Goal {
+	rows, err := s.q.ListGoals(context.Background())
+	if err != nil {
+		return nil
+	}
+	var goals []Goal
+	for _, r := range rows {
+		if status != "" && r.Status != status {
+			continue
+		}
+		goals = append(goals, dbGoalToGoal(r))
+	}
+	return goals
+}
+
+func (s *Service) Get(id string) *Goal {
+	row, err := s.q.GetGoal(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	g := dbGoalToGoal(row)
+	return &g
+}
+
+func (s *Service) Add(g Goal) (*Goal, error) {
+	g.Title = strings.TrimSpace(g.Title)
+	if g.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if g.Status == "" {
+		g.Status = StatusActive
+	}
+	if err := validate(g); err != nil {
+		return nil, err
+	}
+	now := s.now().UnixMilli()
+	if g.ID == "" {
+		g.ID = utils.RandHex(8)
+	}
+	g.ProgressAtMS, g.CreatedAtMS, g.UpdatedAtMS = now, now, now
+
+	err := s.q.InsertGoal(context.Background(), dbq.InsertGoalParams{
+		ID:           g.ID,
+		Title:        g.Title,
+		Description:  g.Description,
+		Status:       g.Status,
+		TargetDate:   g.TargetDate,
+		KeyResults:   marshalKeyResults(g.KeyResults),
+		Tag:          g.Tag,
+		ProgressAtMs: g.ProgressAtMS,
+		CreatedAtMs:  g.CreatedAtMS,
+		UpdatedAtMs:  g.UpdatedAtMS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Update applies patch (title, description, status, target_date, tag,
+// key_results). Changing key results or status counts as progress.
+func (s *Service) Update(id string, patch map[string]any) (*Goal, error) {
+	g := s.Get(id)
+	if g == nil {
+		return nil, fmt.Errorf("goal not found: %s", id)
+	}
+	progressed := false
+	if v, ok := patch["title"].(string); ok && strings.TrimSpace(v) != "" {
+		g.Title = strings.TrimSpace(v)
+	}
+	if v, ok := patch["description"].(string); ok {
+		g.Description = v
+	}
+	if v, ok := patch["status"].(string); ok && v != g.Status {
+		g.Status = v
+		progressed = true
+	}
+	if v, ok := patch["target_date"].(string); ok {
+		g.TargetDate = v
+	}
+	if v, ok := patch["tag"].(string); ok {
+		g.Tag = v
+	}
+	if v, ok := patch["key_results"]; ok {
+		krs, err := ParseKeyResults(v)
+		if err != nil {
+			return nil, err
+		}
+		g.KeyResults = krs
+		progressed = true
+	}
+	if err := validate(*g); err != nil {
+		return nil, err
+	}
+	return g, s.save(g, progressed)
+}
+
+// SetProgress updates the key result named by ref, a 1-based index or its
+// title. A nil value or done leaves that field unchanged.
+func (s *Service) SetProgress(id, ref string, value *float64, done *bool) (*Goal, error) {
+	g := s.Get(id)
+	if g == nil {
+		return nil, fmt.Errorf("goal not found: %s", id)
+	}
+	i := findKeyResult(g.KeyResults, ref)
+	if i < 0 {
+		return nil, fmt.Errorf("goal %s has no key result %q", id, ref)
+	}
+	if value != nil {
+		g.KeyResults[i].Current = *value
+	}
+	if done != nil {
+		g.KeyResults[i].Done = *done
+	}
+	return g, s.save(g, true)
+}
+
+func (s *Service) Remove(id string) bool {
+	res, err := s.q.DeleteGoal(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+func (s *Service) save(g *Goal, progressed bool) error {
+	now := s.now().UnixMilli()
+	g.UpdatedAtMS = now
+	if progressed {
+		g.ProgressAtMS = now
+	}
+	return s.q.UpdateGoal(context.Background(), dbq.UpdateGoalParams{
+		Title:        g.Title,
+		Description:  g.Description,
+		Status:       g.Status,
+		TargetDate:   g.TargetDate,
+		KeyResults:   marshalKeyResults(g.KeyResults),
+		Tag:          g.Tag,
+		ProgressAtMs: g.ProgressAtMS,
+		UpdatedAtMs:  g.UpdatedAtMS,
+		ID:           g.ID,
+	})
+}
+
+func validate(g Goal) error {
+	switch g.Status {
+	case StatusActive, StatusAchieved, StatusAbandoned:
+	default:
+		return fmt.Errorf("invalid status %q (use active, achieved or abandoned)", g.Status)
+	}
+	if g.TargetDate != "" {
+		if _, err := time.Parse("2006-01-02", g.TargetDate); err != nil {
+			return fmt.Errorf("invalid target_date %q (want YYYY-MM-DD)", g.TargetDate)
+		}
+	}
+	for _, kr := range g.KeyResults {
+		if strings.TrimSpace(kr.Title) == "" {
+			return fmt.Errorf("every key result needs a title")
+		}
+	}
+	return nil
+}
+
+func findKeyResult(krs []KeyResult, ref string) int {
+	if n, err := strconv.Atoi(ref); err == nil && n >= 1 && n <= len(krs) {
+		return n - 1
+	}
+	for i, kr := range krs {
+		if strings.EqualFold(kr.Title, ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseKeyResults accepts key results as decoded from tool arguments: a
+// list of objects or of plain titles.
+func ParseKeyResults(v any) ([]KeyResult, error) {
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("key_results must be a list")
+	}
+	krs := make([]KeyResult, 0, len(list))
+	for _, item := range list {
+		if title, ok := item.(string); ok {
+			krs = append(krs, KeyResult{Title: title})
+			continue
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var kr KeyResult
+		if err := json.Unmarshal(data, &kr); err != nil {
+			return nil, fmt.Errorf("invalid key result: %v", err)
+		}
+		krs = append(krs, kr)
+	}
+	return krs, nil
+}
+
+func marshalKeyResults(krs []KeyResult) string {
+	if len(krs) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(krs)
+	return string(data)
+}
+
+func dbGoalToGoal(r dbq.Goal) Goal {
+	g := Goal{
+		ID:           r.ID,
+		Title:        r.Title,
+		Description:  r.Description,
+		Status:       r.Status,
+		TargetDate:   r.TargetDate,
+		Tag:          r.Tag,
+		ProgressAtMS: r.ProgressAtMs,
+		CreatedAtMS:  r.CreatedAtMs,
+		UpdatedAtMS:  r.UpdatedAtMs,
+	}
+	json.Unmarshal([]byte(r.KeyResults), &g.KeyResults)
+	return g
+}
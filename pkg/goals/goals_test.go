@@ -0,0 +1,120 @@
+package goals
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/db"
+	"localagent/pkg/journal"
+	"localagent/pkg/todo"
+)
+
+func newTestService(t *testing.T) (*Service, *todo.TodoService) {
+	t.Helper()
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	tasks := todo.NewTodoService(database)
+	s := NewService(database)
+	s.SetTodoService(tasks)
+	return s, tasks
+}
+
+func TestAddUpdateProgress(t *testing.T) {
+	s, _ := newTestService(t)
+	g, err := s.Add(Goal{Title: "Run a half marathon", TargetDate: "2026-09-01", KeyResults: []KeyResult{
+		{Title: "Weekly distance", Target: 40, Unit: "km"},
+		{Title: "Sign up"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(Goal{Title: "x", TargetDate: "soon"}); err == nil {
+		t.Error("expected invalid target date to be rejected")
+	}
+
+	value, done := 20.0, true
+	if _, err := s.SetProgress(g.ID, "1", &value, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.SetProgress(g.ID, "sign up", nil, &done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.KeyResults[0].Current != 20 || !got.KeyResults[1].Done {
+		t.Fatalf("progress not saved: %+v", got.KeyResults)
+	}
+	if _, err := s.SetProgress(g.ID, "3", &value, nil); err == nil {
+		t.Error("expected unknown key result to fail")
+	}
+
+	if _, err := s.Update(g.ID, map[string]any{"status": "done"}); err == nil {
+		t.Error("expected invalid status to be rejected")
+	}
+	if _, err := s.Update(g.ID, map[string]any{"status": StatusAchieved}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.List(StatusActive)); n != 0 {
+		t.Errorf("got %d active goals, want 0", n)
+	}
+	if !s.Remove(g.ID) || s.Get(g.ID) != nil {
+		t.Error("goal not removed")
+	}
+}
+
+func TestAssess(t *testing.T) {
+	s, tasks := newTestService(t)
+	created := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return created }
+
+	behind, _ := s.Add(Goal{Title: "Read 12 books", TargetDate: "2026-12-31", KeyResults: []KeyResult{{Title: "Books", Target: 12, Current: 1}}})
+	tagged, _ := s.Add(Goal{Title: "Launch site", Tag: "site"})
+	a, _ := tasks.AddTask(todo.Task{Title: "Design", Tags: []string{"site"}})
+	tasks.AddTask(todo.Task{Title: "Deploy", Tags: []string{"site"}})
+	tasks.CompleteTask(a.ID)
+
+	now := time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)
+	got := s.Assess(*behind, now)
+	if !got.Stalled || len(got.Reasons) != 2 {
+		t.Errorf("want stalled for idle and behind schedule, got %+v", got.Reasons)
+	}
+
+	// The completed task counts as recent progress.
+	got = s.Assess(*tagged, time.Now().Add(24*time.Hour))
+	if got.Progress != 0.5 || got.TasksDone != 1 || got.TasksOpen != 1 {
+		t.Errorf("progress=%v done=%d open=%d", got.Progress, got.TasksDone, got.TasksOpen)
+	}
+	if got.Stalled {
+		t.Errorf("goal with a task done today should not stall: %v", got.Reasons)
+	}
+}
+
+func TestReviewFormatsStallingFirst(t *testing.T) {
+	s, _ := newTestService(t)
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return created }
+	s.Add(Goal{Title: "Learn German", KeyResults: []KeyResult{{Title: "Lessons", Target: 50, Current: 10}}})
+	now := created.AddDate(0, 0, 20)
+	s.now = func() time.Time { return now }
+	fresh, _ := s.Add(Goal{Title: "Fix the bike"})
+	s.Update(fresh.ID, map[string]any{"key_results": []any{"Buy tube"}})
+
+	ws := t.TempDir()
+	store := journal.NewStore(ws)
+	store.Write(journal.DayName(now.AddDate(0, 0, -2)), "# Day\n\n- Practiced learn german vocabulary")
+
+	out := FormatReview(s.Review(now, store), now)
+	stalling := strings.Index(out, "Stalling:")
+	onTrack := strings.Index(out, "On track:")
+	if stalling < 0 || onTrack < stalling {
+		t.Fatalf("stalling goals should come first:\n%s", out)
+	}
+	for _, want := range []string{"STALLING: no progress in 20 days", "progress: 20%", "journal 2026-03-19: Practiced learn german vocabulary", "1. Buy tube"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("review missing %q:\n%s", want, out)
+		}
+	}
+}
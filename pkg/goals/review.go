@@ -0,0 +1,204 @@
+package goals
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/journal"
+)
+
+const (
+	// StallDays without progress marks an active goal as stalling.
+	StallDays = 14
+	// behindMargin is how far progress may trail the elapsed time before a
+	// goal counts as behind schedule.
+	behindMargin = 0.3
+	// journalDays is how far back reviews look for journal mentions.
+	journalDays = 14
+	maxMentions = 3
+)
+
+// Assessment is a goal's state at review time.
+type Assessment struct {
+	Goal         Goal
+	Progress     float64 // 0 to 1, or -1 when nothing is measurable
+	Elapsed      float64 // share of the time to the target date used, or -1
+	TasksDone    int
+	TasksOpen    int
+	LastProgress time.Time
+	Stalled      bool
+	Reasons      []string
+	Mentions     []string // journal lines mentioning the goal, "YYYY-MM-DD: ..."
+}
+
+// Assess judges g at now. Completing a task tagged with the goal's tag
+// counts as progress.
+func (s *Service) Assess(g Goal, now time.Time) Assessment {
+	a := Assessment{Goal: g, Progress: -1, Elapsed: -1, LastProgress: time.UnixMilli(g.ProgressAtMS)}
+
+	if s.tasks != nil && g.Tag != "" {
+		for _, t := range s.tasks.ListTasks("", g.Tag) {
+			if t.Status != "done" {
+				a.TasksOpen++
+				continue
+			}
+			a.TasksDone++
+			if t.DoneAtMS != nil && time.UnixMilli(*t.DoneAtMS).After(a.LastProgress) {
+				a.LastProgress = time.UnixMilli(*t.DoneAtMS)
+			}
+		}
+	}
+
+	switch {
+	case len(g.KeyResults) > 0:
+		sum := 0.0
+		for _, kr := range g.KeyResults {
+			sum += kr.Progress()
+		}
+		a.Progress = sum / float64(len(g.KeyResults))
+	case a.TasksDone+a.TasksOpen > 0:
+		a.Progress = float64(a.TasksDone) / float64(a.TasksDone+a.TasksOpen)
+	}
+
+	if target, err := time.ParseInLocation("2006-01-02", g.TargetDate, now.Location()); err == nil {
+		// The goal is due at the end of its target day.
+		end := target.AddDate(0, 0, 1)
+		if total := end.Sub(time.UnixMilli(g.CreatedAtMS)); total > 0 {
+			a.Elapsed = min(float64(now.Sub(time.UnixMilli(g.CreatedAtMS)))/float64(total), 1)
+		}
+		if g.Status == StatusActive && now.After(end) {
+			a.Reasons = append(a.Reasons, fmt.Sprintf("target date %s has passed", g.TargetDate))
+		}
+	}
+
+	if g.Status != StatusActive {
+		return a
+	}
+	if idle := now.Sub(a.LastProgress); idle >= StallDays*24*time.Hour {
+		a.Reasons = append(a.Reasons, fmt.Sprintf("no progress in %d days", int(idle.Hours()/24)))
+	}
+	if a.Elapsed >= 0 && a.Progress >= 0 && a.Elapsed-a.Progress > behindMargin {
+		a.Reasons = append(a.Reasons, fmt.Sprintf("%.0f%% of the time used but %.0f%% done", a.Elapsed*100, a.Progress*100))
+	}
+	a.Stalled = len(a.Reasons) > 0
+	return a
+}
+
+// Review assesses all active goals, adding mentions from the journal in
+// store when it is not nil.
+func (s *Service) Review(now time.Time, store *journal.Store) []Assessment {
+	var out []Assessment
+	for _, g := range s.List(StatusActive) {
+		a := s.Assess(g, now)
+		if store != nil {
+			a.Mentions = mentions(store, g, now)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// mentions finds journal lines from the last journalDays days that name
+// the goal's title or tag.
+func mentions(store *journal.Store, g Goal, now time.Time) []string {
+	needles := []string{strings.ToLower(g.Title)}
+	if g.Tag != "" {
+		needles = append(needles, strings.ToLower(g.Tag))
+	}
+	var found []string
+	for i := range journalDays {
+		day := journal.DayName(now.AddDate(0, 0, -i))
+		entry, err := store.Read(day)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(entry, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(line, "#-* "))
+			lower := strings.ToLower(line)
+			for _, n := range needles {
+				if strings.Contains(lower, n) {
+					found = append(found, day+": "+line)
+					break
+				}
+			}
+			if len(found) >= maxMentions {
+				return found
+			}
+		}
+	}
+	return found
+}
+
+// FormatGoal renders a goal with its key results on a few lines.
+func FormatGoal(g Goal) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s (%s", g.ID, g.Title, g.Status)
+	if g.TargetDate != "" {
+		fmt.Fprintf(&b, ", target %s", g.TargetDate)
+	}
+	if g.Tag != "" {
+		fmt.Fprintf(&b, ", tasks tagged %q", g.Tag)
+	}
+	b.WriteString(")")
+	if g.Description != "" {
+		b.WriteString("\n  " + g.Description)
+	}
+	for i, kr := range g.KeyResults {
+		fmt.Fprintf(&b, "\n  %d. %s", i+1, kr.Title)
+		switch {
+		case kr.Target > 0:
+			fmt.Fprintf(&b, ": %g/%g", kr.Current, kr.Target)
+			if kr.Unit != "" {
+				b.WriteString(" " + kr.Unit)
+			}
+		case kr.Done:
+			b.WriteString(": done")
+		}
+	}
+	return b.String()
+}
+
+// FormatReview renders assessments for the agent to summarize, stalling
+// goals first.
+func FormatReview(as []Assessment, now time.Time) string {
+	if len(as) == 0 {
+		return "No active goals."
+	}
+	var stalled, onTrack []string
+	for _, a := range as {
+		var b strings.Builder
+		b.WriteString(FormatGoal(a.Goal))
+		if a.Progress >= 0 {
+			fmt.Fprintf(&b, "\n  progress: %.0f%%", a.Progress*100)
+		} else {
+			b.WriteString("\n  progress: not measurable (no key results or tagged tasks)")
+		}
+		if a.Elapsed >= 0 {
+			fmt.Fprintf(&b, ", time used: %.0f%%", a.Elapsed*100)
+		}
+		if a.TasksDone+a.TasksOpen > 0 {
+			fmt.Fprintf(&b, "\n  tasks: %d done, %d open", a.TasksDone, a.TasksOpen)
+		}
+		fmt.Fprintf(&b, "\n  last progress: %d days ago", int(now.Sub(a.LastProgress).Hours()/24))
+		if a.Stalled {
+			fmt.Fprintf(&b, "\n  STALLING: %s", strings.Join(a.Reasons, "; "))
+		}
+		for _, m := range a.Mentions {
+			b.WriteString("\n  journal " + m)
+		}
+		if a.Stalled {
+			stalled = append(stalled, b.String())
+		} else {
+			onTrack = append(onTrack, b.String())
+		}
+	}
+	var out []string
+	if len(stalled) > 0 {
+		out = append(out, "Stalling:\n"+strings.Join(stalled, "\n\n"))
+	}
+	if len(onTrack) > 0 {
+		out = append(out, "On track:\n"+strings.Join(onTrack, "\n\n"))
+	}
+	return strings.Join(out, "\n\n")
+}
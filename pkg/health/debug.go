@@ -0,0 +1,112 @@
+package health
+
+import (
+	"encoding/json"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// RuntimeStats is a snapshot of the process for spotting leaks in a
+// long-running gateway.
+type RuntimeStats struct {
+	Uptime      float64            `json:"uptime_seconds"`
+	Goroutines  int                `json:"goroutines"`
+	HeapAlloc   uint64             `json:"heap_alloc_bytes"`
+	HeapObjects uint64             `json:"heap_objects"`
+	Sys         uint64             `json:"sys_bytes"`
+	NumGC       uint32             `json:"num_gc"`
+	GCPauseNs   uint64             `json:"gc_pause_total_ns"`
+	Gauges      map[string]float64 `json:"gauges,omitempty"` // registered with AddGauge, e.g. bus depths
+}
+
+// Fields flattens the stats for telemetry.Emit.
+func (st RuntimeStats) Fields() map[string]float64 {
+	fields := map[string]float64{
+		"uptime_seconds":    st.Uptime,
+		"goroutines":        float64(st.Goroutines),
+		"heap_alloc_bytes":  float64(st.HeapAlloc),
+		"heap_objects":      float64(st.HeapObjects),
+		"sys_bytes":         float64(st.Sys),
+		"num_gc":            float64(st.NumGC),
+		"gc_pause_total_ns": float64(st.GCPauseNs),
+	}
+	maps.Copy(fields, st.Gauges)
+	return fields
+}
+
+// AddGauge adds a value reported with the runtime stats, such as a queue
+// depth.
+func (s *Server) AddGauge(name string, fn func() float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gauges[name] = fn
+}
+
+// Stats reads the current runtime stats and gauges.
+func (s *Server) Stats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	st := RuntimeStats{
+		Uptime:      time.Since(s.startTime).Seconds(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   m.HeapAlloc,
+		HeapObjects: m.HeapObjects,
+		Sys:         m.Sys,
+		NumGC:       m.NumGC,
+		GCPauseNs:   m.PauseTotalNs,
+	}
+
+	s.mu.RLock()
+	fns := maps.Clone(s.gauges)
+	s.mu.RUnlock()
+	if len(fns) > 0 {
+		st.Gauges = make(map[string]float64, len(fns))
+		for name, fn := range fns {
+			st.Gauges[name] = fn()
+		}
+	}
+	return st
+}
+
+// debugHandler serves runtime stats and pprof to loopback clients only:
+//
+//	GET /debug/stats           RuntimeStats as JSON
+//	GET /debug/pprof/...       net/http/pprof (profile, heap, goroutine, trace)
+func (s *Server) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Stats())
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "debug endpoints are only available from localhost"})
+			return
+		}
+		// CPU profiles and traces run for ?seconds= (default 30), past
+		// the server's write timeout.
+		secs, _ := strconv.Atoi(r.URL.Query().Get("seconds"))
+		if secs <= 0 {
+			secs = 30
+		}
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Duration(secs)*time.Second + 10*time.Second))
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,41 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	s := NewServer("127.0.0.1", 0)
+	s.AddGauge("bus_inbound", func() float64 { return 3 })
+
+	get := func(path, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/debug/stats", "127.0.0.1:5000")
+	var st RuntimeStats
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("stats: %d %v", rec.Code, err)
+	}
+	if st.Goroutines == 0 || st.HeapAlloc == 0 || st.Gauges["bus_inbound"] != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+	if f := st.Fields(); f["bus_inbound"] != 3 || f["goroutines"] == 0 {
+		t.Errorf("fields = %v", f)
+	}
+
+	if rec := get("/debug/pprof/goroutine?debug=1", "[::1]:5000"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("pprof: %d", rec.Code)
+	}
+	if rec := get("/debug/stats", "192.168.1.20:5000"); rec.Code != http.StatusForbidden {
+		t.Errorf("remote client got %d", rec.Code)
+	}
+}
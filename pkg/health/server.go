@@ -12,9 +12,11 @@ import (
 
 type Server struct {
 	server    *http.Server
+	mux       *http.ServeMux
 	mu        sync.RWMutex
 	ready     bool
 	checkFns  map[string]func() (bool, string)
+	gauges    map[string]func() float64
 	startTime time.Time
 }
 
@@ -34,13 +36,16 @@ type StatusResponse struct {
 func NewServer(host string, port int) *Server {
 	mux := http.NewServeMux()
 	s := &Server{
+		mux:       mux,
 		ready:     false,
 		checkFns:  make(map[string]func() (bool, string)),
+		gauges:    make(map[string]func() float64),
 		startTime: time.Now(),
 	}
 
 	mux.HandleFunc("/health", s.healthHandler)
 	mux.HandleFunc("/ready", s.readyHandler)
+	mux.Handle("/debug/", s.debugHandler())
 
 	addr := fmt.Sprintf("%s:%d", host, port)
 	s.server = &http.Server{
@@ -80,6 +85,12 @@ func (s *Server) SetReady(ready bool) {
 	s.mu.Unlock()
 }
 
+// Handle registers an additional handler on the health server's mux.
+// Must be called before the server is started.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 func (s *Server) RegisterCheck(name string, checkFn func() (bool, string)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,145 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+const calendarCheckInterval = time.Minute
+
+// UpcomingEvent is a calendar event starting soon.
+type UpcomingEvent struct {
+	UID      string
+	Title    string
+	Location string
+	Start    time.Time
+}
+
+// UpcomingFunc returns the events starting in [from, to).
+type UpcomingFunc func(ctx context.Context, from, to time.Time) ([]UpcomingEvent, error)
+
+// CalendarWatcher enqueues a heartbeat wake event for each calendar event
+// that starts within the lead time. Each event instance is announced once,
+// including across restarts.
+type CalendarWatcher struct {
+	queue    *EventQueue
+	upcoming UpcomingFunc
+	lead     time.Duration
+	state    *state.Manager
+	now      func() time.Time
+
+	mu       sync.Mutex
+	notified map[string]time.Time // event key -> start time
+	stop     chan struct{}
+}
+
+// NewCalendarWatcher creates a watcher whose dedup state lives in workspace.
+func NewCalendarWatcher(workspace string, queue *EventQueue, upcoming UpcomingFunc, lead time.Duration) *CalendarWatcher {
+	w := &CalendarWatcher{
+		queue:    queue,
+		upcoming: upcoming,
+		lead:     lead,
+		state:    state.NewManager(workspace),
+		now:      time.Now,
+		notified: make(map[string]time.Time),
+		stop:     make(chan struct{}),
+	}
+	w.load()
+	return w
+}
+
+func (w *CalendarWatcher) Start() {
+	ticker := time.NewTicker(calendarCheckInterval)
+	go func() {
+		w.Check(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				w.Check(context.Background())
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("calendar reminders started (lead %s)", w.lead)
+}
+
+func (w *CalendarWatcher) Stop() {
+	close(w.stop)
+}
+
+// Check looks for events starting within the lead time and enqueues a
+// wake event for the ones not announced yet.
+func (w *CalendarWatcher) Check(ctx context.Context) {
+	now := w.now()
+	events, err := w.upcoming(ctx, now, now.Add(w.lead))
+	if err != nil {
+		logger.Warn("calendar reminders: %v", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := false
+	for key, start := range w.notified {
+		if now.Sub(start) > dedupWindow {
+			delete(w.notified, key)
+			changed = true
+		}
+	}
+
+	for _, e := range events {
+		if e.Start.Before(now) {
+			continue
+		}
+		key := e.UID + "@" + e.Start.UTC().Format(time.RFC3339)
+		if _, ok := w.notified[key]; ok {
+			continue
+		}
+		w.notified[key] = e.Start
+		changed = true
+		w.queue.EnqueueAndWake(Event{
+			Source:    "calendar",
+			Message:   formatUpcoming(e, now),
+			Priority:  PriorityUrgent,
+			ExpiresAt: e.Start,
+		})
+		logger.Info("calendar reminder: %s at %s", e.Title, e.Start.Format(time.RFC3339))
+	}
+
+	if changed {
+		w.save()
+	}
+}
+
+func formatUpcoming(e UpcomingEvent, now time.Time) string {
+	mins := int(e.Start.Sub(now).Round(time.Minute) / time.Minute)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Upcoming calendar event in %d minutes: %q at %s", mins, e.Title, e.Start.In(now.Location()).Format("15:04"))
+	if e.Location != "" {
+		fmt.Fprintf(&b, " (location: %s)", e.Location)
+	}
+	b.WriteString(". Remind the user, mentioning when they should leave if a location is given.")
+	return b.String()
+}
+
+func (w *CalendarWatcher) load() {
+	if _, err := w.state.Get("calendar", "notified", &w.notified); err != nil {
+		logger.Warn("calendar reminders: ignoring corrupt state: %v", err)
+		w.notified = make(map[string]time.Time)
+	}
+}
+
+func (w *CalendarWatcher) save() {
+	if err := w.state.Set("calendar", "notified", w.notified); err != nil {
+		logger.Warn("calendar reminders: save state: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package heartbeat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarWatcherDedup(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	events := []UpcomingEvent{
+		{UID: "dentist", Title: "Dentist", Location: "Main St", Start: now.Add(25 * time.Minute)},
+	}
+	upcoming := func(_ context.Context, from, to time.Time) ([]UpcomingEvent, error) {
+		return events, nil
+	}
+
+	q := NewEventQueue()
+	q.now = func() time.Time { return now }
+	w := NewCalendarWatcher(dir, q, upcoming, 30*time.Minute)
+	w.now = func() time.Time { return now }
+
+	w.Check(context.Background())
+	got := q.Drain()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got))
+	}
+	if got[0].Source != "calendar" || !strings.Contains(got[0].Message, "Dentist") || !strings.Contains(got[0].Message, "Main St") {
+		t.Errorf("unexpected event: %+v", got[0])
+	}
+
+	w.Check(context.Background())
+	if got := q.Drain(); len(got) != 0 {
+		t.Errorf("expected no duplicate, got %d", len(got))
+	}
+
+	// Dedup state survives a restart.
+	w2 := NewCalendarWatcher(dir, q, upcoming, 30*time.Minute)
+	w2.now = func() time.Time { return now }
+	w2.Check(context.Background())
+	if got := q.Drain(); len(got) != 0 {
+		t.Errorf("expected no duplicate after reload, got %d", len(got))
+	}
+
+	// Another instance of a recurring event is announced separately.
+	events = append(events, UpcomingEvent{UID: "dentist", Title: "Dentist", Start: now.Add(29 * time.Minute)})
+	w2.Check(context.Background())
+	if got := q.Drain(); len(got) != 1 {
+		t.Errorf("expected new instance to be announced, got %d", len(got))
+	}
+}
+
+func TestFormatUpcomingUsesLocalTime(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, berlin)
+	e := UpcomingEvent{Title: "Dentist", Start: time.Date(2025, 1, 15, 8, 30, 0, 0, time.UTC)}
+	if msg := formatUpcoming(e, now); !strings.Contains(msg, "at 09:30") {
+		t.Errorf("event time not in the local zone: %s", msg)
+	}
+}
@@ -0,0 +1,238 @@
+package heartbeat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+// A checklist lives in workspace/HEARTBEAT.md. Lines of the form
+//
+//	- [hourly] Check for overdue tasks
+//	- [daily 08:00] Send a short tech news digest
+//	- [every 15m] Check the front door sensor
+//	- [on-event] Look at today's calendar
+//
+// are scheduled items; only the ones that are due go into a heartbeat
+// prompt. Untagged list items run on every heartbeat and any other text is
+// passed through as standing notes.
+
+// schedule tolerance absorbs ticker jitter so an hourly item evaluated at
+// 10:00:01 is due again on the 11:00:00 tick.
+const scheduleSlack = time.Minute
+
+type ChecklistItem struct {
+	Text     string
+	Schedule string // raw tag, empty = every heartbeat
+
+	every   time.Duration
+	at      int // minutes since midnight for "daily HH:MM", -1 otherwise
+	daily   bool
+	onEvent bool
+}
+
+// Checklist is the parsed HEARTBEAT.md.
+type Checklist struct {
+	Items []ChecklistItem
+	Notes string
+}
+
+// ParseChecklist parses HEARTBEAT.md content. Items with an unknown
+// schedule tag are kept as notes so nothing the user wrote is lost.
+func ParseChecklist(content string) *Checklist {
+	c := &Checklist{}
+	var notes []string
+	for _, line := range strings.Split(content, "\n") {
+		item, ok := parseChecklistLine(line)
+		if ok {
+			c.Items = append(c.Items, item)
+			continue
+		}
+		notes = append(notes, line)
+	}
+	c.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return c
+}
+
+func parseChecklistLine(line string) (ChecklistItem, bool) {
+	trimmed := strings.TrimSpace(line)
+	rest, ok := strings.CutPrefix(trimmed, "- ")
+	if !ok {
+		rest, ok = strings.CutPrefix(trimmed, "* ")
+	}
+	if !ok {
+		return ChecklistItem{}, false
+	}
+	rest = strings.TrimSpace(rest)
+	item := ChecklistItem{at: -1}
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return ChecklistItem{}, false
+		}
+		item.Schedule = strings.TrimSpace(rest[1:end])
+		rest = strings.TrimSpace(rest[end+1:])
+		if err := item.parseSchedule(); err != nil {
+			return ChecklistItem{}, false
+		}
+	}
+	if rest == "" {
+		return ChecklistItem{}, false
+	}
+	item.Text = rest
+	return item, true
+}
+
+func (it *ChecklistItem) parseSchedule() error {
+	fields := strings.Fields(strings.ToLower(it.Schedule))
+	if len(fields) == 0 {
+		return nil // "[ ]" checkbox: every heartbeat
+	}
+	switch fields[0] {
+	case "always":
+	case "hourly":
+		it.every = time.Hour
+	case "daily":
+		it.daily = true
+		if len(fields) > 1 {
+			if it.at = parseTimeMinutes(fields[1]); it.at < 0 {
+				return fmt.Errorf("invalid time %q", fields[1])
+			}
+		}
+	case "every":
+		if len(fields) < 2 {
+			return fmt.Errorf("every needs a duration")
+		}
+		d, err := time.ParseDuration(fields[1])
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q", fields[1])
+		}
+		it.every = d
+	case "on-event", "event":
+		it.onEvent = true
+	default:
+		return fmt.Errorf("unknown schedule %q", fields[0])
+	}
+	return nil
+}
+
+// Due reports whether the item should be part of a periodic heartbeat at
+// now, given when it was last evaluated. On-event items are never due on
+// their own.
+func (it ChecklistItem) Due(last, now time.Time) bool {
+	switch {
+	case it.onEvent:
+		return false
+	case it.daily && it.at >= 0:
+		y, m, d := now.Date()
+		slot := time.Date(y, m, d, it.at/60, it.at%60, 0, 0, now.Location())
+		return !now.Before(slot) && last.Before(slot)
+	case it.daily:
+		return last.IsZero() || !sameDay(last.In(now.Location()), now)
+	case it.every > 0:
+		return last.IsZero() || now.Sub(last) >= it.every-scheduleSlack
+	}
+	return true
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// checklistTracker remembers when each item was last evaluated, keyed by
+// item text, in the workspace state under heartbeat/checklist.
+type checklistTracker struct {
+	state *state.Manager
+	mu    sync.Mutex
+	last  map[string]time.Time
+}
+
+func newChecklistTracker(sm *state.Manager) *checklistTracker {
+	t := &checklistTracker{state: sm, last: make(map[string]time.Time)}
+	if _, err := sm.Get(stateNamespace, "checklist", &t.last); err != nil {
+		logger.Warn("heartbeat checklist: ignoring corrupt state: %v", err)
+		t.last = make(map[string]time.Time)
+	}
+	return t
+}
+
+func (t *checklistTracker) get(text string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last[text]
+}
+
+// mark records items as evaluated at now and forgets items that are no
+// longer in the checklist.
+func (t *checklistTracker) mark(evaluated []string, current *Checklist, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, text := range evaluated {
+		t.last[text] = now
+	}
+	if current != nil {
+		keep := make(map[string]bool, len(current.Items))
+		for _, it := range current.Items {
+			keep[it.Text] = true
+		}
+		for text := range t.last {
+			if !keep[text] {
+				delete(t.last, text)
+			}
+		}
+	}
+	if err := t.state.Set(stateNamespace, "checklist", t.last); err != nil {
+		logger.Warn("heartbeat checklist: save state: %v", err)
+	}
+}
+
+// loadChecklist reads HEARTBEAT.md from the workspace, or returns nil when
+// there is none.
+func (hs *HeartbeatService) loadChecklist() *Checklist {
+	data, err := os.ReadFile(filepath.Join(hs.workspace, "HEARTBEAT.md"))
+	if err != nil {
+		return nil
+	}
+	return ParseChecklist(string(data))
+}
+
+// dueItems splits the checklist into items due for a periodic heartbeat and
+// on-event items.
+func (hs *HeartbeatService) dueItems(c *Checklist, now time.Time) (due, onEvent []ChecklistItem) {
+	for _, it := range c.Items {
+		if it.onEvent {
+			onEvent = append(onEvent, it)
+			continue
+		}
+		if it.Due(hs.checklist.get(it.Text), now) {
+			due = append(due, it)
+		}
+	}
+	return due, onEvent
+}
+
+func formatChecklist(header string, items []ChecklistItem) string {
+	var b strings.Builder
+	b.WriteString(header)
+	for _, it := range items {
+		b.WriteString("\n- ")
+		b.WriteString(it.Text)
+	}
+	return b.String()
+}
+
+func itemTexts(items []ChecklistItem) []string {
+	texts := make([]string, len(items))
+	for i, it := range items {
+		texts[i] = it.Text
+	}
+	return texts
+}
@@ -0,0 +1,103 @@
+package heartbeat
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChecklist(t *testing.T) {
+	c := ParseChecklist(`# Heartbeat
+
+Be terse.
+
+- [hourly] Check overdue tasks
+- [daily 08:00] Tech news digest
+- [every 15m] Front door sensor
+- [on-event] Look at today's calendar
+- Always check the weather
+- [x] already done
+- [weekly] unsupported
+`)
+	if len(c.Items) != 5 {
+		t.Fatalf("expected 5 items, got %d: %+v", len(c.Items), c.Items)
+	}
+	if c.Items[1].Text != "Tech news digest" || c.Items[1].at != 8*60 || !c.Items[1].daily {
+		t.Errorf("daily item parsed wrong: %+v", c.Items[1])
+	}
+	if c.Items[2].every != 15*time.Minute {
+		t.Errorf("every item parsed wrong: %+v", c.Items[2])
+	}
+	if !c.Items[3].onEvent || c.Items[4].Schedule != "" {
+		t.Errorf("unexpected items: %+v", c.Items[3:])
+	}
+	for _, want := range []string{"Be terse.", "[x] already done", "[weekly] unsupported"} {
+		if !strings.Contains(c.Notes, want) {
+			t.Errorf("notes missing %q: %q", want, c.Notes)
+		}
+	}
+}
+
+func TestChecklistItemDue(t *testing.T) {
+	loc := time.UTC
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 10, h, m, 0, 0, loc) }
+
+	hourly := ChecklistItem{every: time.Hour, at: -1}
+	if !hourly.Due(time.Time{}, at(9, 0)) {
+		t.Error("never evaluated item should be due")
+	}
+	if hourly.Due(at(9, 0), at(9, 30)) {
+		t.Error("hourly item due after 30 minutes")
+	}
+	if !hourly.Due(at(9, 0).Add(time.Second), at(10, 0)) {
+		t.Error("hourly item should tolerate tick jitter")
+	}
+
+	digest := ChecklistItem{daily: true, at: 8 * 60}
+	if digest.Due(time.Time{}, at(7, 30)) {
+		t.Error("daily 08:00 item due before 08:00")
+	}
+	if !digest.Due(at(7, 30).AddDate(0, 0, -1), at(8, 5)) {
+		t.Error("daily 08:00 item should be due after 08:00")
+	}
+	if digest.Due(at(8, 5), at(14, 0)) {
+		t.Error("daily item due twice in one day")
+	}
+
+	daily := ChecklistItem{daily: true, at: -1}
+	if daily.Due(at(6, 0), at(23, 0)) || !daily.Due(at(6, 0), at(6, 0).AddDate(0, 0, 1)) {
+		t.Error("daily item should be due once per calendar day")
+	}
+
+	if (ChecklistItem{onEvent: true}).Due(time.Time{}, at(9, 0)) {
+		t.Error("on-event item should never be due on a periodic heartbeat")
+	}
+}
+
+func TestBuildPromptChecklist(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "HEARTBEAT.md"), []byte("- [hourly] Check tasks\n- [on-event] Check calendar\n"), 0644)
+	hs := NewHeartbeatService(ws, 30, 3, true)
+	hs.SetEventQueue(NewEventQueue())
+
+	hp := hs.buildPrompt(true)
+	if !strings.Contains(hp.text, "- Check tasks") || strings.Contains(hp.text, "Check calendar") {
+		t.Fatalf("unexpected periodic prompt: %q", hp.text)
+	}
+	hs.checklist.mark(hp.items, hp.checklist, time.Now())
+
+	// Persisted across restarts, so the hourly item is not due again.
+	hs = NewHeartbeatService(ws, 30, 3, true)
+	hs.SetEventQueue(NewEventQueue())
+	if hp := hs.buildPrompt(true); !hp.skip {
+		t.Fatalf("expected skip with nothing due, got %q", hp.text)
+	}
+
+	hs.eventQueue.Enqueue(Event{Source: "cron", Message: "water the plants"})
+	hp = hs.buildPrompt(true)
+	if !hp.isCronEvent || !strings.Contains(hp.text, "- Check calendar") {
+		t.Fatalf("on-event item missing from event prompt: %q", hp.text)
+	}
+}
@@ -0,0 +1,125 @@
+package heartbeat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/storage"
+)
+
+const (
+	diskCheckInterval = 10 * time.Minute
+	diskWarnEvery     = 24 * time.Hour
+)
+
+// DiskWatcher queues a heartbeat event when the workspace disk has less
+// free space than a threshold. It warns at most once a day, including
+// across restarts.
+type DiskWatcher struct {
+	queue   *EventQueue
+	minFree float64 // percent
+	space   func() (storage.DiskSpace, error)
+	usage   func() storage.Usage // optional breakdown for the message
+	state   *state.Manager
+	now     func() time.Time
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// NewDiskWatcher creates a watcher for the disk holding workspace. usage may
+// be nil; when set, the largest categories are named in the warning.
+func NewDiskWatcher(workspace string, queue *EventQueue, minFreePercent float64, usage func() storage.Usage) *DiskWatcher {
+	return &DiskWatcher{
+		queue:   queue,
+		minFree: minFreePercent,
+		space:   func() (storage.DiskSpace, error) { return storage.Space(workspace) },
+		usage:   usage,
+		state:   state.NewManager(workspace),
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+}
+
+func (w *DiskWatcher) Start() {
+	ticker := time.NewTicker(diskCheckInterval)
+	go func() {
+		w.Check()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("disk watcher started (warn below %.0f%% free)", w.minFree)
+}
+
+func (w *DiskWatcher) Stop() {
+	close(w.stop)
+}
+
+// Check enqueues a warning if free space is below the threshold and no
+// warning was sent in the last day.
+func (w *DiskWatcher) Check() {
+	d, err := w.space()
+	if err != nil {
+		logger.Warn("disk watcher: %v", err)
+		return
+	}
+	free := d.FreePercent()
+	if free < 0 || free >= w.minFree {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	var last time.Time
+	if _, err := w.state.Get(stateNamespace, "disk_warned", &last); err != nil {
+		logger.Warn("disk watcher: ignoring corrupt state: %v", err)
+	}
+	if !last.IsZero() && now.Sub(last) < diskWarnEvery {
+		return
+	}
+	if err := w.state.Set(stateNamespace, "disk_warned", now); err != nil {
+		logger.Warn("disk watcher: save state: %v", err)
+	}
+
+	var top []storage.CategoryUsage
+	if w.usage != nil {
+		top = w.usage().Categories
+	}
+	w.queue.Enqueue(Event{
+		Source:   "disk",
+		Message:  formatDiskWarning(d, free, top),
+		Priority: PriorityNormal,
+	})
+	logger.Warn("disk nearly full: %.1f%% free", free)
+}
+
+func formatDiskWarning(d storage.DiskSpace, free float64, top []storage.CategoryUsage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The workspace disk is nearly full: %s free of %s (%.1f%%).",
+		storage.FormatBytes(int64(d.Free)), storage.FormatBytes(int64(d.Total)), free)
+	if len(top) > 3 {
+		top = top[:3]
+	}
+	if len(top) > 0 {
+		parts := make([]string, len(top))
+		for i, c := range top {
+			parts[i] = fmt.Sprintf("%s %s", c.Name, storage.FormatBytes(c.Bytes))
+		}
+		fmt.Fprintf(&b, " Largest workspace data: %s.", strings.Join(parts, ", "))
+	}
+	b.WriteString(" Tell the user and suggest what they could clean up.")
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package heartbeat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/storage"
+)
+
+func TestDiskWatcherWarnsOncePerDay(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	q := NewEventQueue()
+	usage := func() storage.Usage {
+		return storage.Usage{Categories: []storage.CategoryUsage{{Name: "image_jobs", Bytes: 3 << 30}}}
+	}
+	newWatcher := func(free uint64) *DiskWatcher {
+		w := NewDiskWatcher(dir, q, 5, usage)
+		w.space = func() (storage.DiskSpace, error) { return storage.DiskSpace{Total: 100 << 30, Free: free << 30}, nil }
+		w.now = func() time.Time { return now }
+		return w
+	}
+
+	newWatcher(50).Check()
+	if got := q.Drain(); len(got) != 0 {
+		t.Fatalf("expected no warning with plenty of space, got %d", len(got))
+	}
+
+	w := newWatcher(2)
+	w.Check()
+	got := q.Drain()
+	if len(got) != 1 || got[0].Source != "disk" {
+		t.Fatalf("expected one disk warning, got %+v", got)
+	}
+	if !strings.Contains(got[0].Message, "2.0 GB free") || !strings.Contains(got[0].Message, "image_jobs 3.0 GB") {
+		t.Errorf("unexpected message: %q", got[0].Message)
+	}
+
+	// Throttled, also across restarts.
+	newWatcher(2).Check()
+	if got := q.Drain(); len(got) != 0 {
+		t.Errorf("expected no repeat warning, got %d", len(got))
+	}
+
+	now = now.Add(25 * time.Hour)
+	newWatcher(2).Check()
+	if got := q.Drain(); len(got) != 1 {
+		t.Errorf("expected warning again after a day, got %d", len(got))
+	}
+}
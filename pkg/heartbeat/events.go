@@ -1,56 +1,154 @@
 package heartbeat
 
 import (
+	"slices"
 	"sync"
 	"time"
+
+	"localagent/pkg/logger"
+)
+
+// Priority orders queued events. Urgent events wake the heartbeat as soon
+// as they are enqueued and are delivered even outside active hours.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityUrgent Priority = 1
 )
 
+func (p Priority) String() string {
+	switch {
+	case p >= PriorityUrgent:
+		return "urgent"
+	case p <= PriorityLow:
+		return "low"
+	}
+	return "normal"
+}
+
+const defaultMaxEvents = 256
+
 type Event struct {
 	Source     string
 	Message    string
 	Channel    string
 	ChatID     string
+	Priority   Priority
 	EnqueuedAt time.Time
+	ExpiresAt  time.Time // zero = never; expired events are dropped unseen
+}
+
+func (e Event) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
 }
 
 type EventQueue struct {
-	events []Event
-	mu     sync.Mutex
-	notify chan struct{}
+	events  []Event
+	maxSize int
+	now     func() time.Time
+	mu      sync.Mutex
+	notify  chan struct{}
 }
 
 func NewEventQueue() *EventQueue {
 	return &EventQueue{
-		notify: make(chan struct{}, 1),
+		maxSize: defaultMaxEvents,
+		now:     time.Now,
+		notify:  make(chan struct{}, 1),
 	}
 }
 
-func (q *EventQueue) Enqueue(e Event) {
+// SetMaxSize limits how many events are held; n <= 0 restores the default.
+func (q *EventQueue) SetMaxSize(n int) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	if n <= 0 {
+		n = defaultMaxEvents
+	}
+	q.maxSize = n
+}
+
+// Enqueue adds an event. When the queue is full, expired events are pruned
+// first, then the oldest event of the lowest priority is dropped (which may
+// be e itself).
+func (q *EventQueue) Enqueue(e Event) {
+	q.mu.Lock()
+	now := q.now()
 	if e.EnqueuedAt.IsZero() {
-		e.EnqueuedAt = time.Now()
+		e.EnqueuedAt = now
 	}
 	q.events = append(q.events, e)
+	if len(q.events) > q.maxSize {
+		q.pruneExpired(now)
+	}
+	for len(q.events) > q.maxSize {
+		victim := 0
+		for i, ev := range q.events {
+			if ev.Priority < q.events[victim].Priority {
+				victim = i
+			}
+		}
+		dropped := q.events[victim]
+		q.events = slices.Delete(q.events, victim, victim+1)
+		logger.Warn("heartbeat: event queue full (%d), dropped %s event from %s: %.80s", q.maxSize, dropped.Priority, dropped.Source, dropped.Message)
+	}
+	q.mu.Unlock()
+
+	if e.Priority >= PriorityUrgent {
+		q.wake()
+	}
 }
 
 func (q *EventQueue) EnqueueAndWake(e Event) {
 	q.Enqueue(e)
+	q.wake()
+}
+
+func (q *EventQueue) wake() {
 	select {
 	case q.notify <- struct{}{}:
 	default:
 	}
 }
 
+// Drain removes and returns all unexpired events, highest priority first.
 func (q *EventQueue) Drain() []Event {
+	return q.DrainPriority(PriorityLow)
+}
+
+// DrainPriority removes and returns unexpired events with at least priority
+// min, highest priority first. Lower-priority events stay queued.
+func (q *EventQueue) DrainPriority(min Priority) []Event {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.pruneExpired(q.now())
 	if len(q.events) == 0 {
 		return nil
 	}
-	events := q.events
-	q.events = nil
-	return events
+	var out, keep []Event
+	for _, e := range q.events {
+		if e.Priority >= min {
+			out = append(out, e)
+		} else {
+			keep = append(keep, e)
+		}
+	}
+	q.events = keep
+	slices.SortStableFunc(out, func(a, b Event) int { return int(b.Priority - a.Priority) })
+	return out
+}
+
+// pruneExpired must be called with q.mu held.
+func (q *EventQueue) pruneExpired(now time.Time) {
+	q.events = slices.DeleteFunc(q.events, func(e Event) bool {
+		if e.expired(now) {
+			logger.Info("heartbeat: dropped expired event from %s (expired %s): %.80s", e.Source, e.ExpiresAt.Format(time.RFC3339), e.Message)
+			return true
+		}
+		return false
+	})
 }
 
 func (q *EventQueue) WakeChan() <-chan struct{} {
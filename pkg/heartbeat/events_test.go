@@ -86,3 +86,52 @@ func TestEnqueueSetsTimestamp(t *testing.T) {
 		t.Fatal("EnqueuedAt not set correctly")
 	}
 }
+
+func TestDrainPriorityOrderAndExpiry(t *testing.T) {
+	q := NewEventQueue()
+	q.Enqueue(Event{Source: "news", Message: "low", Priority: PriorityLow})
+	q.Enqueue(Event{Source: "cron", Message: "stale", ExpiresAt: time.Now().Add(-time.Minute)})
+	q.Enqueue(Event{Source: "cron", Message: "normal"})
+	q.Enqueue(Event{Source: "calendar", Message: "urgent", Priority: PriorityUrgent})
+
+	urgent := q.DrainPriority(PriorityUrgent)
+	if len(urgent) != 1 || urgent[0].Message != "urgent" {
+		t.Fatalf("expected only the urgent event, got %+v", urgent)
+	}
+
+	events := q.Drain()
+	if len(events) != 2 || events[0].Message != "normal" || events[1].Message != "low" {
+		t.Fatalf("expected normal then low with the expired event dropped, got %+v", events)
+	}
+}
+
+func TestUrgentEnqueueWakes(t *testing.T) {
+	q := NewEventQueue()
+	q.Enqueue(Event{Source: "cron", Message: "later"})
+	select {
+	case <-q.WakeChan():
+		t.Fatal("normal Enqueue should not wake")
+	default:
+	}
+
+	q.Enqueue(Event{Source: "cron", Message: "now", Priority: PriorityUrgent})
+	select {
+	case <-q.WakeChan():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("urgent Enqueue did not wake")
+	}
+}
+
+func TestQueueOverflowDropsLowestPriority(t *testing.T) {
+	q := NewEventQueue()
+	q.SetMaxSize(2)
+	q.Enqueue(Event{Message: "normal 1"})
+	q.Enqueue(Event{Message: "low", Priority: PriorityLow})
+	q.Enqueue(Event{Message: "normal 2"})
+	q.Enqueue(Event{Message: "urgent", Priority: PriorityUrgent})
+
+	events := q.Drain()
+	if len(events) != 2 || events[0].Message != "urgent" || events[1].Message != "normal 2" {
+		t.Fatalf("expected urgent and newest normal to survive, got %+v", events)
+	}
+}
@@ -0,0 +1,110 @@
+package heartbeat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+const flashcardCheckInterval = 5 * time.Minute
+
+// FlashcardWatcher wakes the heartbeat once a day, at or after the review
+// time, when flashcards are due so the agent quizzes the user over chat.
+type FlashcardWatcher struct {
+	queue *EventQueue
+	due   func() int
+	at    time.Duration // offset from midnight
+	state *state.Manager
+	now   func() time.Time
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// NewFlashcardWatcher creates a watcher that quizzes at "HH:MM" in the
+// agent's timezone. due returns the number of cards due now.
+func NewFlashcardWatcher(workspace string, queue *EventQueue, due func() int, at string) (*FlashcardWatcher, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flashcard review time %q (want HH:MM)", at)
+	}
+	return &FlashcardWatcher{
+		queue: queue,
+		due:   due,
+		at:    time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute,
+		state: state.NewManager(workspace),
+		now:   when.Now,
+		stop:  make(chan struct{}),
+	}, nil
+}
+
+func (w *FlashcardWatcher) Start() {
+	ticker := time.NewTicker(flashcardCheckInterval)
+	go func() {
+		w.Check()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("flashcard reviews started (daily at %02d:%02d)", int(w.at.Hours()), int(w.at.Minutes())%60)
+}
+
+func (w *FlashcardWatcher) Stop() {
+	close(w.stop)
+}
+
+// Check enqueues a quiz event if the review time has passed today, cards
+// are due and no quiz was started today.
+func (w *FlashcardWatcher) Check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if now.Before(midnight.Add(w.at)) {
+		return
+	}
+	today := now.Format("2006-01-02")
+	var last string
+	if _, err := w.state.Get(stateNamespace, "flashcards_quizzed", &last); err != nil {
+		logger.Warn("flashcard watcher: ignoring corrupt state: %v", err)
+	}
+	if last == today {
+		return
+	}
+	n := w.due()
+	if n == 0 {
+		return
+	}
+	if err := w.state.Set(stateNamespace, "flashcards_quizzed", today); err != nil {
+		logger.Warn("flashcard watcher: save state: %v", err)
+	}
+
+	w.queue.EnqueueAndWake(Event{
+		Source:    "flashcards",
+		Message:   formatQuiz(n),
+		Priority:  PriorityNormal,
+		ExpiresAt: midnight.AddDate(0, 0, 1),
+	})
+	logger.Info("flashcard watcher: %d cards due", n)
+}
+
+func formatQuiz(n int) string {
+	cards := "flashcards are"
+	if n == 1 {
+		cards = "flashcard is"
+	}
+	return fmt.Sprintf("%d %s due for review. Quiz the user one card at a time: "+
+		"use the flashcards tool (action quiz) to get the next card, ask only its front, "+
+		"and once they answer, grade it with action answer and tell them the correct answer.", n, cards)
+}
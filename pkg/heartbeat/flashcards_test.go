@@ -0,0 +1,53 @@
+package heartbeat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlashcardWatcherQuizzesOncePerDay(t *testing.T) {
+	dir := t.TempDir()
+	q := NewEventQueue()
+	// Drain drops expired events by the wall clock, so stay in the future.
+	y, m, d := time.Now().AddDate(0, 0, 7).Date()
+	now := time.Date(y, m, d, 18, 0, 0, 0, time.Local)
+	due := 3
+	newWatcher := func() *FlashcardWatcher {
+		w, err := NewFlashcardWatcher(dir, q, func() int { return due }, "19:00")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.now = func() time.Time { return now }
+		return w
+	}
+
+	newWatcher().Check()
+	if got := q.Drain(); len(got) != 0 {
+		t.Fatalf("quiz before review time: %+v", got)
+	}
+
+	now = now.Add(90 * time.Minute)
+	newWatcher().Check()
+	got := q.Drain()
+	if len(got) != 1 || got[0].Source != "flashcards" || !strings.Contains(got[0].Message, "3 flashcards are due") {
+		t.Fatalf("expected one quiz event, got %+v", got)
+	}
+
+	// Once a day, also across restarts.
+	newWatcher().Check()
+	if got := q.Drain(); len(got) != 0 {
+		t.Errorf("repeat quiz the same day: %d", len(got))
+	}
+
+	now = now.Add(24 * time.Hour)
+	due = 0
+	newWatcher().Check()
+	if got := q.Drain(); len(got) != 0 {
+		t.Errorf("quiz with no due cards: %d", len(got))
+	}
+
+	if _, err := NewFlashcardWatcher(dir, q, func() int { return 0 }, "7pm"); err == nil {
+		t.Error("expected invalid review time to fail")
+	}
+}
@@ -12,9 +12,11 @@ import (
 	"localagent/pkg/constants"
 	"localagent/pkg/logger"
 	"localagent/pkg/prompts"
+	"localagent/pkg/readstate"
 	"localagent/pkg/session"
 	"localagent/pkg/state"
 	"localagent/pkg/tools"
+	"localagent/pkg/when"
 )
 
 const (
@@ -22,6 +24,9 @@ const (
 	defaultIntervalMinutes = 30
 	defaultMaxDaily        = 3
 	dedupWindow            = 24 * time.Hour
+
+	// stateNamespace holds heartbeat keys in the workspace state.
+	stateNamespace = "heartbeat"
 )
 
 // ActiveHours defines a time window during which heartbeats are allowed.
@@ -31,6 +36,45 @@ type ActiveHours struct {
 	Timezone string // IANA timezone, e.g. "America/New_York"
 }
 
+// SleepSchedule tells the heartbeat when the user sleeps.
+type SleepSchedule interface {
+	// Asleep reports whether the user is asleep at now; known is false
+	// when there is nothing to go on.
+	Asleep(now time.Time) (asleep, known bool)
+	// MorningNote describes last night's sleep shortly after waking.
+	MorningNote(now time.Time) string
+}
+
+// BusyCheck tells the heartbeat when the user is in a meeting.
+type BusyCheck interface {
+	// Busy reports whether the user is busy at now and until when.
+	Busy(now time.Time) (until time.Time, busy bool)
+}
+
+// TravelBriefing tells the heartbeat about the user's trip.
+type TravelBriefing interface {
+	// Briefing returns trip details not mentioned yet, or "".
+	Briefing(now time.Time) string
+}
+
+// EmailBriefing tells the heartbeat about unread email.
+type EmailBriefing interface {
+	// Briefing returns the day's email triage once a day, or "".
+	Briefing(now time.Time) string
+}
+
+// TransitBriefing tells the heartbeat about the morning's departures.
+type TransitBriefing interface {
+	// Briefing returns the next departures once each morning, or "".
+	Briefing(now time.Time) string
+}
+
+// AirQualityBriefing tells the heartbeat about bad air and high pollen.
+type AirQualityBriefing interface {
+	// Briefing returns notable air quality once a day, or "".
+	Briefing(now time.Time) string
+}
+
 // HeartbeatHandler is the function type for handling heartbeat.
 // It returns a ToolResult that can indicate async operations.
 // channel and chatID are derived from the last active user channel.
@@ -43,8 +87,10 @@ type HeartbeatService struct {
 	bus        *bus.MessageBus
 	sessions   *session.SessionManager
 	state      *state.Manager
+	checklist  *checklistTracker
 	handler    HeartbeatHandler
 	eventQueue *EventQueue
+	readState  *readstate.Tracker
 	interval   time.Duration
 	enabled    bool
 	mu         sync.RWMutex
@@ -52,6 +98,13 @@ type HeartbeatService struct {
 
 	// Active hours gating
 	activeHours *ActiveHours
+	sleep       SleepSchedule
+	travel      TravelBriefing
+	email       EmailBriefing
+	transit     TransitBriefing
+	airQuality  AirQualityBriefing
+	busy        BusyCheck
+	busyRecheck *time.Timer // runs a heartbeat when the current meeting ends
 
 	// Daily message budget
 	maxDailyMessages int
@@ -78,12 +131,48 @@ func NewHeartbeatService(workspace string, intervalMinutes, maxDailyMessages int
 		maxDailyMessages = defaultMaxDaily
 	}
 
-	return &HeartbeatService{
+	sm := state.NewManager(workspace)
+	hs := &HeartbeatService{
 		workspace:        workspace,
 		interval:         time.Duration(intervalMinutes) * time.Minute,
 		maxDailyMessages: maxDailyMessages,
 		enabled:          enabled,
-		state:            state.NewManager(workspace),
+		state:            sm,
+		checklist:        newChecklistTracker(sm),
+	}
+	hs.loadDeliveryState()
+	return hs
+}
+
+// deliveryState is the daily budget and dedup state kept across restarts.
+type deliveryState struct {
+	Date          string    `json:"date"`
+	Sent          int       `json:"sent"`
+	LastAlert     string    `json:"last_alert,omitempty"`
+	LastAlertSent time.Time `json:"last_alert_sent,omitempty"`
+}
+
+func (hs *HeartbeatService) loadDeliveryState() {
+	var ds deliveryState
+	if _, err := hs.state.Get(stateNamespace, "delivery", &ds); err != nil {
+		logger.Warn("heartbeat: ignoring corrupt delivery state: %v", err)
+		return
+	}
+	hs.dailyResetDate = ds.Date
+	hs.dailySentCount = ds.Sent
+	hs.lastAlertText = ds.LastAlert
+	hs.lastAlertSentAt = ds.LastAlertSent
+}
+
+func (hs *HeartbeatService) saveDeliveryState() {
+	err := hs.state.Set(stateNamespace, "delivery", deliveryState{
+		Date:          hs.dailyResetDate,
+		Sent:          hs.dailySentCount,
+		LastAlert:     hs.lastAlertText,
+		LastAlertSent: hs.lastAlertSentAt,
+	})
+	if err != nil {
+		hs.logError("Failed to save delivery state: %v", err)
 	}
 }
 
@@ -116,6 +205,25 @@ func (hs *HeartbeatService) SetEventQueue(eq *EventQueue) {
 	hs.eventQueue = eq
 }
 
+// SetReadTracker lets heartbeat prompts say which earlier messages the
+// user has not seen yet.
+func (hs *HeartbeatService) SetReadTracker(t *readstate.Tracker) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.readState = t
+}
+
+// readNote describes the delivery chat's read state for the prompt.
+func (hs *HeartbeatService) readNote(channel, chatID string) string {
+	hs.mu.RLock()
+	t := hs.readState
+	hs.mu.RUnlock()
+	if t == nil || channel == "" || chatID == "" {
+		return ""
+	}
+	return t.Get(channel, chatID).Describe(time.Now())
+}
+
 // SetActiveHours configures the active hours window.
 // Heartbeats outside this window are skipped (cron events still go through).
 func (hs *HeartbeatService) SetActiveHours(ah *ActiveHours) {
@@ -124,6 +232,56 @@ func (hs *HeartbeatService) SetActiveHours(ah *ActiveHours) {
 	hs.activeHours = ah
 }
 
+// SetSleepSchedule makes the user's sleep the active hours: while they
+// are asleep only urgent events are delivered. The configured active hours
+// apply when the schedule knows nothing. The first heartbeat after waking
+// gets a note about last night's sleep.
+func (hs *HeartbeatService) SetSleepSchedule(s SleepSchedule) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.sleep = s
+}
+
+// SetBusyCheck defers non-urgent heartbeats and events while the user is
+// busy (e.g. in a meeting); a heartbeat runs when the busy period ends.
+func (hs *HeartbeatService) SetBusyCheck(b BusyCheck) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.busy = b
+}
+
+// SetTravelBriefing adds destination weather and upcoming bookings to
+// heartbeat prompts while a trip is under way or coming up.
+func (hs *HeartbeatService) SetTravelBriefing(b TravelBriefing) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.travel = b
+}
+
+// SetEmailBriefing adds the day's email triage to the first heartbeat
+// prompt of the day.
+func (hs *HeartbeatService) SetEmailBriefing(b EmailBriefing) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.email = b
+}
+
+// SetTransitBriefing adds the next departures from the user's stop to the
+// first heartbeat prompt of the morning.
+func (hs *HeartbeatService) SetTransitBriefing(b TransitBriefing) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.transit = b
+}
+
+// SetAirQualityBriefing adds poor air quality and high pollen at the
+// user's locations to the first heartbeat prompt of the day.
+func (hs *HeartbeatService) SetAirQualityBriefing(b AirQualityBriefing) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.airQuality = b
+}
+
 // Start begins the heartbeat service
 func (hs *HeartbeatService) Start() error {
 	hs.mu.Lock()
@@ -159,6 +317,10 @@ func (hs *HeartbeatService) Stop() {
 	logger.Info("heartbeat: stopping service")
 	close(hs.stopChan)
 	hs.stopChan = nil
+	if hs.busyRecheck != nil {
+		hs.busyRecheck.Stop()
+		hs.busyRecheck = nil
+	}
 }
 
 // runLoop runs the heartbeat ticker
@@ -207,14 +369,23 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	logger.Debug("heartbeat: executing")
 
-	hp := hs.buildPrompt()
-
-	// Active hours gate: skip periodic heartbeats outside the window.
-	// Cron events always go through regardless of active hours.
-	if !hp.isCronEvent && !hs.isWithinActiveHours() {
+	// Active hours gate: skip periodic heartbeats outside the window. Only
+	// urgent events are delivered then; the rest wait in the queue.
+	active := hs.isWithinActiveHours()
+	busyUntil, busy := hs.busyUntil()
+	hp := hs.buildPrompt(active && !busy)
+	if !hp.isCronEvent && !active {
 		hs.logInfo("Skipped: outside active hours")
 		return
 	}
+	if !hp.isCronEvent && busy {
+		hs.logInfo("Skipped: busy until %s", busyUntil.Format("15:04"))
+		return
+	}
+	if hp.skip {
+		hs.logInfo("Skipped: no checklist items due")
+		return
+	}
 
 	// Daily budget hard cap: skip periodic heartbeats when budget is exhausted.
 	// Cron events always go through.
@@ -239,7 +410,27 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		hs.logInfo("Using event channel: %s, chatID: %s", channel, chatID)
 	}
 
-	result := handler(hp.text, channel, chatID, hp.isCronEvent)
+	text := hp.text
+	if note := hs.readNote(channel, chatID); note != "" {
+		text += "\n\n" + note
+	}
+	if note := hs.sleepNote(); note != "" {
+		text += "\n\n" + note
+	}
+	if note := hs.travelNote(); note != "" {
+		text += "\n\n" + note
+	}
+	if note := hs.emailNote(); note != "" {
+		text += "\n\n" + note
+	}
+	if note := hs.transitNote(); note != "" {
+		text += "\n\n" + note
+	}
+	if note := hs.airQualityNote(); note != "" {
+		text += "\n\n" + note
+	}
+
+	result := handler(text, channel, chatID, hp.isCronEvent)
 
 	if result == nil {
 		hs.logInfo("Heartbeat handler returned nil result")
@@ -251,6 +442,10 @@ func (hs *HeartbeatService) executeHeartbeat() {
 		return
 	}
 
+	if len(hp.items) > 0 {
+		hs.checklist.mark(hp.items, hp.checklist, time.Now())
+	}
+
 	if result.Async {
 		hs.logInfo("Async task started: %s", result.ForLLM)
 		logger.Info("heartbeat: async task started: %s", result.ForLLM)
@@ -297,6 +492,7 @@ func (hs *HeartbeatService) executeHeartbeat() {
 
 	hs.recordAlert(response)
 	hs.recordDailySend()
+	hs.saveDeliveryState()
 	hs.sendResponse(response)
 	sent, max := hs.dailySent()
 	hs.logInfo("Heartbeat completed (%d/%d daily): %s", sent, max, result.ForLLM)
@@ -335,8 +531,9 @@ func (hs *HeartbeatService) RequestWakeNow(text string) {
 	}
 
 	eq.EnqueueAndWake(Event{
-		Source:  "wake",
-		Message: text,
+		Source:   "wake",
+		Message:  text,
+		Priority: PriorityUrgent,
 	})
 }
 
@@ -345,37 +542,79 @@ type heartbeatPrompt struct {
 	isCronEvent bool
 	channel     string
 	chatID      string
+
+	// Checklist items included in text, marked evaluated once the
+	// heartbeat has run.
+	items     []string
+	checklist *Checklist
+	skip      bool // a checklist exists but nothing is due
 }
 
-// buildPrompt builds the heartbeat prompt from pending events or the static heartbeat prompt.
-func (hs *HeartbeatService) buildPrompt() heartbeatPrompt {
+// buildPrompt builds the heartbeat prompt from pending events or the static
+// heartbeat prompt, adding the HEARTBEAT.md checklist items that are due.
+// Outside active hours only urgent events are taken from the queue.
+func (hs *HeartbeatService) buildPrompt(active bool) heartbeatPrompt {
 	hs.mu.RLock()
 	eq := hs.eventQueue
 	hs.mu.RUnlock()
 
 	var events []Event
 	if eq != nil {
-		events = eq.Drain()
+		min := PriorityLow
+		if !active {
+			min = PriorityUrgent
+		}
+		events = eq.DrainPriority(min)
+	}
+
+	now := time.Now()
+	checklist := hs.loadChecklist()
+	var due, onEvent []ChecklistItem
+	if checklist != nil {
+		due, onEvent = hs.dueItems(checklist, now)
 	}
 
 	if len(events) > 0 {
 		// Use channel/chatID from the first event (all events in a batch
 		// typically share the same origin).
-		return heartbeatPrompt{
+		hp := heartbeatPrompt{
 			text:        hs.buildCronEventPrompt(events),
 			isCronEvent: true,
 			channel:     events[0].Channel,
 			chatID:      events[0].ChatID,
 		}
+		if len(onEvent) > 0 {
+			hp.text += "\n\n" + formatChecklist("Also run these checks and mention anything that needs the user's attention:", onEvent)
+			hp.items = itemTexts(onEvent)
+			hp.checklist = checklist
+		}
+		return hp
+	}
+
+	if checklist != nil && len(checklist.Items) > 0 && len(due) == 0 && checklist.Notes == "" {
+		return heartbeatPrompt{skip: true}
+	}
+
+	var b strings.Builder
+	b.WriteString(prompts.Heartbeat)
+	if len(due) > 0 {
+		b.WriteString("\n\n")
+		b.WriteString(formatChecklist("Checklist items due now:", due))
+	}
+	if checklist != nil && checklist.Notes != "" {
+		b.WriteString("\n\nNotes from HEARTBEAT.md:\n")
+		b.WriteString(checklist.Notes)
 	}
 
-	now := time.Now()
 	tz, _ := now.Zone()
 	sent, max := hs.dailySent()
 	remaining := max - sent
-	budgetLine := fmt.Sprintf("Messages sent today: %d/%d. You have %d remaining — make them count.", sent, max, remaining)
+	fmt.Fprintf(&b, "\n\nMessages sent today: %d/%d. You have %d remaining — make them count.", sent, max, remaining)
+	fmt.Fprintf(&b, "\n\nCurrent time: %s (%s)", now.Format("2006-01-02 15:04:05"), tz)
 	return heartbeatPrompt{
-		text: fmt.Sprintf("%s\n\n%s\n\nCurrent time: %s (%s)", prompts.Heartbeat, budgetLine, now.Format("2006-01-02 15:04:05"), tz),
+		text:      b.String(),
+		items:     itemTexts(due),
+		checklist: checklist,
 	}
 }
 
@@ -397,13 +636,26 @@ func (hs *HeartbeatService) buildCronEventPrompt(events []Event) string {
 
 // --- Active hours ---
 
+// Active reports whether now is within active hours (and the user isn't
+// asleep), for other background work that follows the same schedule.
+func (hs *HeartbeatService) Active() bool {
+	return hs.isWithinActiveHours()
+}
+
 // isWithinActiveHours checks whether the current time falls inside the
 // configured active hours window. Returns true if no window is configured.
 func (hs *HeartbeatService) isWithinActiveHours() bool {
 	hs.mu.RLock()
 	ah := hs.activeHours
+	sleep := hs.sleep
 	hs.mu.RUnlock()
 
+	if sleep != nil {
+		if asleep, known := sleep.Asleep(when.Now()); known {
+			return !asleep
+		}
+	}
+
 	if ah == nil || ah.Start == "" || ah.End == "" {
 		return true
 	}
@@ -435,6 +687,102 @@ func (hs *HeartbeatService) isWithinActiveHours() bool {
 	return cur >= start || cur < end
 }
 
+// busyUntil asks the busy check and, while busy, schedules a heartbeat
+// for when the busy period ends so held events go out promptly.
+func (hs *HeartbeatService) busyUntil() (time.Time, bool) {
+	hs.mu.RLock()
+	check := hs.busy
+	hs.mu.RUnlock()
+	if check == nil {
+		return time.Time{}, false
+	}
+	now := when.Now()
+	until, busy := check.Busy(now)
+	if !busy {
+		return until, false
+	}
+	hs.mu.Lock()
+	if hs.busyRecheck == nil {
+		hs.busyRecheck = time.AfterFunc(until.Sub(now)+time.Second, func() {
+			hs.mu.Lock()
+			hs.busyRecheck = nil
+			hs.mu.Unlock()
+			hs.executeHeartbeat()
+		})
+	}
+	hs.mu.Unlock()
+	return until, true
+}
+
+// sleepNote returns the morning note about last night's sleep, once a day.
+func (hs *HeartbeatService) sleepNote() string {
+	hs.mu.RLock()
+	sleep := hs.sleep
+	hs.mu.RUnlock()
+	if sleep == nil {
+		return ""
+	}
+	now := when.Now()
+	note := sleep.MorningNote(now)
+	if note == "" {
+		return ""
+	}
+	today := now.Format("2006-01-02")
+	var noted string
+	hs.state.Get(stateNamespace, "sleep_noted", &noted)
+	if noted == today {
+		return ""
+	}
+	if err := hs.state.Set(stateNamespace, "sleep_noted", today); err != nil {
+		hs.logError("Failed to save sleep note state: %v", err)
+	}
+	return note
+}
+
+// travelNote returns the travel briefing, if any.
+func (hs *HeartbeatService) travelNote() string {
+	hs.mu.RLock()
+	travel := hs.travel
+	hs.mu.RUnlock()
+	if travel == nil {
+		return ""
+	}
+	return travel.Briefing(when.Now())
+}
+
+// emailNote returns the email triage, if any.
+func (hs *HeartbeatService) emailNote() string {
+	hs.mu.RLock()
+	email := hs.email
+	hs.mu.RUnlock()
+	if email == nil {
+		return ""
+	}
+	return email.Briefing(when.Now())
+}
+
+// transitNote returns the morning departures, if any.
+func (hs *HeartbeatService) transitNote() string {
+	hs.mu.RLock()
+	transit := hs.transit
+	hs.mu.RUnlock()
+	if transit == nil {
+		return ""
+	}
+	return transit.Briefing(when.Now())
+}
+
+// airQualityNote returns the air quality briefing, if any.
+func (hs *HeartbeatService) airQualityNote() string {
+	hs.mu.RLock()
+	airQuality := hs.airQuality
+	hs.mu.RUnlock()
+	if airQuality == nil {
+		return ""
+	}
+	return airQuality.Briefing(when.Now())
+}
+
 // parseTimeMinutes parses "HH:MM" into minutes since midnight. Returns -1 on error.
 func parseTimeMinutes(t string) int {
 	parts := strings.SplitN(t, ":", 2)
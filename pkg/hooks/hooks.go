@@ -0,0 +1,215 @@
+// Package hooks runs user scripts at defined extension points so messages
+// and tool results can be filtered, rerouted or enriched without changing
+// the Go code.
+//
+// A hook is a Starlark script that defines hook(event). The event is a dict
+// the function may change in place or replace by returning a new one.
+// Starlark has no access to files, the network, the environment or other
+// processes, so a hook can only compute on the events it is given; a step
+// limit and a timeout stop runaway scripts.
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	starjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+// Extension points.
+const (
+	InboundMessage  = "on_inbound_message"  // before the agent sees a chat message
+	OutboundMessage = "on_outbound_message" // before a message is delivered to a channel
+	ToolResult      = "on_tool_result"      // before a tool result goes back to the LLM
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	// maxSteps bounds the work one script call may do, independent of the
+	// timeout, so a tight loop can't hold a CPU for seconds.
+	maxSteps = 10_000_000
+)
+
+// Event is the document passed through a hook. Inbound hooks may change
+// Content; outbound hooks Content, Channel and ChatID (routing); tool result
+// hooks Content and IsError. Drop discards a message.
+type Event struct {
+	Event    string         `json:"event"`
+	Channel  string         `json:"channel,omitempty"`
+	ChatID   string         `json:"chat_id,omitempty"`
+	SenderID string         `json:"sender_id,omitempty"`
+	Content  string         `json:"content"`
+	Tool     string         `json:"tool,omitempty"`
+	Args     map[string]any `json:"args,omitempty"`
+	IsError  bool           `json:"is_error,omitempty"`
+	Drop     bool           `json:"drop,omitempty"`
+}
+
+// hook is a loaded script. Its globals are frozen after loading, so calls
+// from several goroutines can share it.
+type hook struct {
+	config.HookConfig
+	fn starlark.Callable
+}
+
+// Runner runs the configured hooks. A nil Runner runs none.
+type Runner struct {
+	hooks []hook
+}
+
+// New loads the valid hooks in defs, with relative script paths resolved
+// against dir, and returns a Runner for them, or nil when there are none.
+// Invalid hooks and scripts that fail to load are skipped with a warning.
+func New(defs []config.HookConfig, dir string) *Runner {
+	var loaded []hook
+	for _, h := range defs {
+		if err := h.Validate(); err != nil {
+			logger.Warn("hook disabled: %v", err)
+			continue
+		}
+		fn, err := load(h.Script, dir)
+		if err != nil {
+			logger.Warn("%s hook %s disabled: %v", h.Event, h.Script, err)
+			continue
+		}
+		loaded = append(loaded, hook{HookConfig: h, fn: fn})
+	}
+	if len(loaded) == 0 {
+		return nil
+	}
+	logger.Info("hooks: %d enabled", len(loaded))
+	return &Runner{hooks: loaded}
+}
+
+// predeclared is what scripts can use beyond the Starlark builtins.
+var predeclared = starlark.StringDict{"json": starjson.Module}
+
+// load runs a script's top level and returns its hook function.
+func load(script, dir string) (starlark.Callable, error) {
+	path := script
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	thread := newThread(script)
+	globals, err := starlark.ExecFile(thread, path, nil, predeclared)
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	fn, ok := globals["hook"].(starlark.Callable)
+	if !ok {
+		return nil, errors.New("script does not define hook(event)")
+	}
+	return fn, nil
+}
+
+// newThread returns a thread without load() whose print goes to the log.
+func newThread(script string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: script,
+		Print: func(_ *starlark.Thread, msg string) {
+			logger.Info("hook %s: %s", script, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxSteps)
+	return thread
+}
+
+// Has reports whether any hook is registered for event.
+func (r *Runner) Has(event string) bool {
+	if r == nil {
+		return false
+	}
+	return slices.ContainsFunc(r.hooks, func(h hook) bool { return h.Event == event })
+}
+
+// Run passes e through every hook for e.Event in config order, stopping
+// once one drops it. A hook that fails or times out is logged and skipped,
+// so a broken script never blocks messages.
+func (r *Runner) Run(ctx context.Context, e Event) Event {
+	if r == nil {
+		return e
+	}
+	for _, h := range r.hooks {
+		if h.Event != e.Event || (e.Tool != "" && len(h.Tools) > 0 && !slices.Contains(h.Tools, e.Tool)) {
+			continue
+		}
+		out, err := h.run(ctx, e)
+		if err != nil {
+			logger.Warn("%s hook %s failed: %v", h.Event, h.Script, err)
+			continue
+		}
+		e = out
+		if e.Drop {
+			logger.Info("%s hook %s dropped the message", h.Event, h.Script)
+			break
+		}
+	}
+	return e
+}
+
+func (h hook) run(ctx context.Context, e Event) (Event, error) {
+	timeout := defaultTimeout
+	if h.TimeoutMS > 0 {
+		timeout = time.Duration(h.TimeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	thread := newThread(h.Script)
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(ctx.Err().Error()) })
+	defer stop()
+
+	// The event crosses into Starlark as JSON so args keep their shape.
+	in, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	arg, err := starlark.Call(thread, starjson.Module.Members["decode"], starlark.Tuple{starlark.String(in)}, nil)
+	if err != nil {
+		return e, err
+	}
+	res, err := starlark.Call(thread, h.fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return e, fmt.Errorf("timed out after %v", timeout)
+		}
+		return e, scriptError(err)
+	}
+	switch res.(type) {
+	case starlark.NoneType:
+		res = arg // changed in place, or not at all
+	case *starlark.Dict:
+	default:
+		return e, fmt.Errorf("hook returned %s, want dict or None", res.Type())
+	}
+	enc, err := starlark.Call(thread, starjson.Module.Members["encode"], starlark.Tuple{res}, nil)
+	if err != nil {
+		return e, scriptError(err)
+	}
+	// Decode over a copy so fields the hook leaves out keep their values;
+	// the event name and tool are not the hook's to change.
+	out := e
+	if err := json.Unmarshal([]byte(enc.(starlark.String).GoString()), &out); err != nil {
+		return e, fmt.Errorf("invalid event: %v", err)
+	}
+	out.Event, out.Tool, out.Args = e.Event, e.Tool, e.Args
+	return out, nil
+}
+
+// scriptError adds the Starlark backtrace to script failures.
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
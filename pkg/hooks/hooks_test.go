@@ -0,0 +1,99 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+// writeScripts writes name -> source into a temporary workspace.
+func writeScripts(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunChainsHooks(t *testing.T) {
+	dir := writeScripts(t, map[string]string{
+		"translate.star": "def hook(e):\n    e[\"content\"] = e[\"content\"].replace(\"hola\", \"hello\")\n",
+		"fail.star":      "def hook(e):\n    fail(\"broken\")\n",
+		"tag.star":       "def hook(e):\n    return dict(e, content = \"[es] \" + e[\"content\"])\n",
+		"drop.star":      "def hook(e):\n    return {\"drop\": True}\n",
+		"startup.star":   "def hook(e):\n    pass\n",
+		"nohook.star":    "x = 1\n",
+		"syntax.star":    "def hook(e)\n",
+	})
+	r := New([]config.HookConfig{
+		{Event: InboundMessage, Script: "translate.star"},
+		{Event: InboundMessage, Script: "fail.star"},
+		{Event: InboundMessage, Script: "tag.star"},
+		{Event: OutboundMessage, Script: "drop.star"},
+		{Event: OutboundMessage, Script: "nohook.star"},
+		{Event: OutboundMessage, Script: "syntax.star"},
+		{Event: "on_startup", Script: "startup.star"},
+	}, dir)
+
+	e := r.Run(context.Background(), Event{Event: InboundMessage, Channel: "telegram", ChatID: "42", Content: "hola amigo"})
+	if e.Content != "[es] hello amigo" || e.Channel != "telegram" || e.ChatID != "42" {
+		t.Errorf("inbound = %+v", e)
+	}
+	if out := r.Run(context.Background(), Event{Event: OutboundMessage, Channel: "web", Content: "hi"}); !out.Drop || out.Channel != "web" {
+		t.Errorf("outbound = %+v", out)
+	}
+	if r.Has(ToolResult) || len(r.hooks) != 4 {
+		t.Errorf("hooks = %+v", r.hooks)
+	}
+}
+
+func TestToolResultFilter(t *testing.T) {
+	dir := writeScripts(t, map[string]string{
+		"redact.star": "def hook(e):\n    if e[\"args\"][\"command\"].startswith(\"cat\"):\n        return {\"content\": \"redacted\", \"tool\": \"other\"}\n",
+	})
+	r := New([]config.HookConfig{{Event: ToolResult, Script: "redact.star", Tools: []string{"exec"}}}, dir)
+
+	e := r.Run(context.Background(), Event{Event: ToolResult, Tool: "exec", Args: map[string]any{"command": "cat .env"}, Content: "secret output"})
+	if e.Content != "redacted" || e.Tool != "exec" {
+		t.Errorf("exec result = %+v", e)
+	}
+	if e := r.Run(context.Background(), Event{Event: ToolResult, Tool: "read_file", Content: "file"}); e.Content != "file" {
+		t.Errorf("read_file result = %+v", e)
+	}
+}
+
+func TestTimeoutKeepsEvent(t *testing.T) {
+	dir := writeScripts(t, map[string]string{
+		"spin.star": "def hook(e):\n    for i in range(1 << 30):\n        pass\n",
+	})
+	r := New([]config.HookConfig{{Event: InboundMessage, Script: "spin.star", TimeoutMS: 50}}, dir)
+	if e := r.Run(context.Background(), Event{Event: InboundMessage, Content: "hi"}); e.Content != "hi" {
+		t.Errorf("event = %+v", e)
+	}
+	var nilRunner *Runner
+	if nilRunner.Has(InboundMessage) || nilRunner.Run(context.Background(), Event{Content: "x"}).Content != "x" {
+		t.Error("nil runner should pass events through")
+	}
+}
+
+func TestScriptsAreSandboxed(t *testing.T) {
+	// Neither load() nor anything touching files exists, so both scripts
+	// fail to load and no hook is enabled.
+	dir := writeScripts(t, map[string]string{
+		"load.star": "load(\"os.star\", \"system\")\ndef hook(e):\n    pass\n",
+		"open.star": "def hook(e):\n    e[\"content\"] = open(\"/etc/passwd\").read()\n",
+	})
+	r := New([]config.HookConfig{
+		{Event: InboundMessage, Script: "load.star"},
+		{Event: InboundMessage, Script: "open.star"},
+	}, dir)
+	if r != nil {
+		t.Errorf("hooks = %+v", r.hooks)
+	}
+}
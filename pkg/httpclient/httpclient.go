@@ -0,0 +1,174 @@
+// Package httpclient provides the HTTP clients tools use for outbound
+// requests. Every client routes through the egress proxy, so the whitelist
+// built from DeclaredDomains is enforced, and attributes its requests to the
+// owning tool for the proxy's audit log.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"localagent/pkg/proxy"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	defaultRetries = 2
+	retryBaseDelay = 500 * time.Millisecond
+)
+
+var (
+	proxyMu  sync.RWMutex
+	proxyURL *url.URL
+
+	baseOnce      sync.Once
+	baseTransport *http.Transport
+)
+
+// SetProxy sets the egress proxy all clients route through. It is called once
+// the proxy is listening; before that, clients fall back to the environment.
+func SetProxy(addr string) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	proxyMu.Lock()
+	proxyURL = u
+	proxyMu.Unlock()
+	return nil
+}
+
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	proxyMu.RLock()
+	u := proxyURL
+	proxyMu.RUnlock()
+	if u != nil {
+		return u, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+func transport() *http.Transport {
+	baseOnce.Do(func() {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = proxyFunc
+		proxy.AttributeTools(t)
+		baseTransport = t
+	})
+	return baseTransport
+}
+
+type Option func(*toolTransport, *http.Client)
+
+// WithTimeout overrides the overall request timeout (default 30s).
+func WithTimeout(d time.Duration) Option {
+	return func(_ *toolTransport, c *http.Client) { c.Timeout = d }
+}
+
+// WithRetries sets how many times idempotent requests are retried on
+// network errors, 429 and 5xx responses (default 2). Use 0 to disable.
+func WithRetries(n int) Option {
+	return func(t *toolTransport, _ *http.Client) { t.retries = n }
+}
+
+// WithUserAgent overrides the default "localagent/<tool>" User-Agent.
+func WithUserAgent(ua string) Option {
+	return func(t *toolTransport, _ *http.Client) { t.userAgent = ua }
+}
+
+// WithJar attaches a cookie jar to the client.
+func WithJar(jar http.CookieJar) Option {
+	return func(_ *toolTransport, c *http.Client) { c.Jar = jar }
+}
+
+// New returns a client for the named tool.
+func New(tool string, opts ...Option) *http.Client {
+	t := &toolTransport{
+		base:      transport(),
+		tool:      tool,
+		userAgent: "localagent/" + tool,
+		retries:   defaultRetries,
+	}
+	c := &http.Client{Timeout: defaultTimeout, Transport: t}
+	for _, opt := range opts {
+		opt(t, c)
+	}
+	return c
+}
+
+type toolTransport struct {
+	base      http.RoundTripper
+	tool      string
+	userAgent string
+	retries   int
+}
+
+func (t *toolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(proxy.WithTool(req.Context(), t.tool))
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	// Plain HTTP requests carry the header to the proxy directly; HTTPS
+	// gets it on the CONNECT via the context.
+	if req.URL.Scheme == "http" {
+		req.Header.Set(proxy.ToolHeader, t.tool)
+	}
+
+	if _, quota := currentLimits(); quota > 0 && t.quotaRemaining(quota) <= 0 {
+		return nil, &QuotaExceededError{Tool: t.tool, Quota: quota}
+	}
+
+	retries := t.retries
+	if !isIdempotent(req) {
+		retries = 0
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				return nil, berr
+			}
+			req.Body = body
+		}
+
+		resp, err = t.base.RoundTrip(req)
+		if attempt >= retries || !shouldRetry(resp, err) {
+			if resp != nil {
+				t.limitBody(resp)
+			}
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := retryBaseDelay << attempt
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.GetBody != nil
+	}
+	return false
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew_SetsUserAgent(t *testing.T) {
+	var ua string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	resp, err := New("tech_news").Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if ua != "localagent/tech_news" {
+		t.Errorf("expected tool user agent, got %q", ua)
+	}
+}
+
+func TestNew_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	resp, err := New("test", WithTimeout(10*time.Second)).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 after retries, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestNew_NoRetryForPost(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	resp, err := New("test").Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected POST to be attempted once, got %d", calls.Load())
+	}
+}
+
+func TestReadBody_TruncatesAtLimit(t *testing.T) {
+	SetLimits(16, 0)
+	defer SetLimits(DefaultMaxResponseSize, 0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	resp, err := New("limit_test").Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ReadBody(resp)
+	var tooLarge *TooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected TooLargeError, got %v", err)
+	}
+	if len(data) != 16 {
+		t.Errorf("expected 16 bytes, got %d", len(data))
+	}
+}
+
+func TestReadBody_ExactlyAtLimit(t *testing.T) {
+	SetLimits(16, 0)
+	defer SetLimits(DefaultMaxResponseSize, 0)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 16)))
+	}))
+	defer srv.Close()
+
+	resp, err := New("limit_test").Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := ReadBody(resp); err != nil {
+		t.Errorf("expected no error at exactly the limit, got %v", err)
+	}
+}
+
+func TestNew_DailyQuota(t *testing.T) {
+	SetLimits(0, 10)
+	defer SetLimits(0, DefaultDailyQuota)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 10)))
+	}))
+	defer srv.Close()
+
+	client := New("quota_test")
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ReadBody(resp)
+	resp.Body.Close()
+
+	_, err = client.Get(srv.URL)
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected QuotaExceededError, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxResponseSize = 10 << 20  // 10 MB per response
+	DefaultDailyQuota      = 500 << 20 // 500 MB per tool per day
+)
+
+var (
+	limitsMu        sync.RWMutex
+	maxResponseSize int64 = DefaultMaxResponseSize
+	dailyQuota      int64 = DefaultDailyQuota
+
+	usage = &quotaTracker{used: make(map[string]int64)}
+)
+
+// SetLimits configures the per-response size cap and the per-tool daily
+// download quota. Zero keeps the default; a negative value disables the limit.
+func SetLimits(maxResponse, quota int64) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	if maxResponse != 0 {
+		maxResponseSize = maxResponse
+	}
+	if quota != 0 {
+		dailyQuota = quota
+	}
+}
+
+func currentLimits() (int64, int64) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	return maxResponseSize, dailyQuota
+}
+
+// QuotaExceededError is returned when a tool has used up its daily download quota.
+type QuotaExceededError struct {
+	Tool  string
+	Quota int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily download quota of %s for %s exhausted, try again tomorrow", formatBytes(e.Quota), e.Tool)
+}
+
+// TooLargeError is returned from reads of a response body once it passes the size limit.
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("response exceeded the %s size limit and was truncated", formatBytes(e.Limit))
+}
+
+type quotaTracker struct {
+	mu   sync.Mutex
+	day  string
+	used map[string]int64
+}
+
+func (q *quotaTracker) remaining(tool string, quota int64) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	return quota - q.used[tool]
+}
+
+func (q *quotaTracker) add(tool string, n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	q.used[tool] += n
+}
+
+func (q *quotaTracker) rollover() {
+	today := time.Now().Format("2006-01-02")
+	if q.day != today {
+		q.day = today
+		clear(q.used)
+	}
+}
+
+// limitedBody stops reading once the size limit is reached and counts the
+// bytes read against the tool's daily quota.
+type limitedBody struct {
+	rc        io.ReadCloser
+	tool      string
+	remaining int64 // negative = unlimited
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining == 0 {
+		// Probe one byte to tell "exactly at the limit" from "cut off".
+		var probe [1]byte
+		if n, _ := b.rc.Read(probe[:]); n > 0 {
+			return 0, &TooLargeError{Limit: b.limit}
+		}
+		return 0, io.EOF
+	}
+	if b.remaining > 0 && int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.rc.Read(p)
+	if b.remaining > 0 {
+		b.remaining -= int64(n)
+	}
+	usage.add(b.tool, int64(n))
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+func (t *toolTransport) limitBody(resp *http.Response) {
+	maxSize, quota := currentLimits()
+	remaining := maxSize
+	limit := maxSize
+	if quota > 0 {
+		left := max(t.quotaRemaining(quota), 0)
+		if remaining < 0 || left < remaining {
+			remaining = left
+			limit = left
+		}
+	}
+	resp.Body = &limitedBody{rc: resp.Body, tool: t.tool, remaining: remaining, limit: limit}
+}
+
+func (t *toolTransport) quotaRemaining(quota int64) int64 {
+	return usage.remaining(t.tool, quota)
+}
+
+// ReadBody reads a response body obtained from a client created by New.
+// If the body was cut off at the size limit, the data read so far is
+// returned together with a *TooLargeError.
+func ReadBody(resp *http.Response) ([]byte, error) {
+	return io.ReadAll(resp.Body)
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,56 @@
+package i18n
+
+var de = map[string]string{
+	"agent.no_response": "Ich bin fertig, habe aber keine Antwort.",
+	"agent.time_limit":  "Ich habe nach %s bei %s aufgehört, das ist das Zeitlimit für eine Nachricht. Teile die Aufgabe in kleinere Schritte auf und schicke sie einzeln.",
+	"agent.stalled":     "Ich habe aufgehört, weil %s nicht mehr reagiert hat. Bitte versuche es noch einmal.",
+
+	"error.details":          "Details: %s",
+	"error.no_api_base":      "Es ist kein Sprachmodell konfiguriert. Setze provider.api_base in der Konfiguration.",
+	"error.auth":             "Das Sprachmodell hat den API-Schlüssel abgelehnt. Prüfe provider.api_key_env in der Konfiguration.",
+	"error.rate_limited":     "Das Sprachmodell begrenzt gerade die Anfragen. Bitte versuche es in einer Minute noch einmal.",
+	"error.model_not_found":  "Das konfigurierte Modell ist beim Anbieter nicht verfügbar. Prüfe agents.defaults.model in der Konfiguration.",
+	"error.context_overflow": "Diese Unterhaltung ist zu lang für das Modell. Starte eine neue Sitzung oder bitte mich um eine Zusammenfassung.",
+	"error.timeout":          "Das Sprachmodell hat zu lange für die Antwort gebraucht. Bitte versuche es noch einmal.",
+	"error.server":           "Der Server des Sprachmodells hatte einen Fehler. Bitte versuche es gleich noch einmal.",
+	"error.bad_request":      "Das Sprachmodell konnte die Anfrage nicht verarbeiten. Bitte versuche es noch einmal.",
+	"error.unreachable":      "Ich erreiche den Server des Sprachmodells gerade nicht. Prüfe, ob er läuft, und versuche es dann noch einmal.",
+	"error.generic":          "Beim Verarbeiten deiner Nachricht ist etwas schiefgegangen. Bitte versuche es noch einmal.",
+
+	"channels.access_requested": "Entschuldigung, ich spreche nur mit Leuten, die ich kenne. Ich habe Bescheid gegeben, dass du Zugang möchtest.",
+	"channels.access_notify":    "%s möchte mit mir auf %s sprechen: %q\nAntworte, um zuzustimmen oder abzulehnen, oder nutze /approve %s %s im Web-Chat.",
+	"commands.header":           "Befehle:\n/help - Befehle auflisten",
+	"commands.usage":            "Verwendung: /%s %s",
+	"commands.owner_only":       "/%s steht nur dem Besitzer zur Verfügung.",
+
+	"welcome.intro":        "Hallo! Ich bin ein Assistent, mit dem du hier schreiben kannst. Ich helfe bei:",
+	"welcome.other_tools":  "weitere Werkzeuge: %s",
+	"welcome.skills":       "Fähigkeiten: %s",
+	"welcome.outro":        "Schreib einfach in eigenen Worten, was du brauchst.",
+	"welcome.tasks":        "Aufgaben, Zeitblöcken und Zielen",
+	"welcome.calendar":     "deinem Kalender, Erinnerungen und geplanten Aufträgen",
+	"welcome.files":        "dem Lesen und Schreiben von Dateien und PDFs",
+	"welcome.commands":     "dem Ausführen von Befehlen und Containern",
+	"welcome.voice":        "Sprachnachrichten",
+	"welcome.journal":      "einem Tagebuch",
+	"welcome.recipes":      "Rezepten und Essensplänen",
+	"welcome.flashcards":   "Karteikarten",
+	"welcome.medications":  "Medikamentenerinnerungen",
+	"welcome.sleep_travel": "Schlaf- und Reiseverfolgung",
+	"welcome.links":        "einer Linksammlung",
+	"welcome.email":        "dem Sortieren ungelesener E-Mails",
+	"welcome.news":         "Tech-News und KI-Papern",
+	"welcome.finance":      "Währungsumrechnung und Aktienkursen",
+	"welcome.background":   "Hintergrundaufgaben",
+
+	"focus.digest": "Während deiner Fokuszeit habe ich %d Nachricht(en) zurückgehalten:",
+	"focus.on":     "Fokus ist an bis %s. Bis dahin halte ich nicht dringende Nachrichten zurück.",
+	"focus.off":    "Fokus ist aus.",
+	"focus.status": "Fokus ist an bis %s.",
+	"focus.none":   "Fokus ist nicht an.",
+
+	"cli.interactive": "localagent interaktiver Modus ('exit' zum Beenden)",
+	"cli.goodbye":     "Tschüss!",
+	"cli.error":       "Fehler: %v",
+	"cli.cancelled":   "(abgebrochen)",
+}
@@ -0,0 +1,62 @@
+package i18n
+
+var en = map[string]string{
+	// agent replies
+	"agent.no_response": "I've completed processing but have no response to give.",
+	"agent.time_limit":  "I stopped after %s while on %s, which is the time limit for a single message. Try splitting the task into smaller steps and sending them one at a time.",
+	"agent.stalled":     "I stopped because %s stopped responding. Please try again.",
+
+	// processing errors shown in chats
+	"error.details":          "Details: %s",
+	"error.no_api_base":      "No language model is configured. Set provider.api_base in the config.",
+	"error.auth":             "The language model rejected the API key. Check provider.api_key_env in the config.",
+	"error.rate_limited":     "The language model is rate limiting requests. Please try again in a minute.",
+	"error.model_not_found":  "The configured model isn't available on the provider. Check agents.defaults.model in the config.",
+	"error.context_overflow": "This conversation is too long for the model. Start a new session or ask me to summarize.",
+	"error.timeout":          "The language model took too long to answer. Please try again.",
+	"error.server":           "The language model server had an error. Please try again in a moment.",
+	"error.bad_request":      "The language model couldn't handle that request. Please try again.",
+	"error.unreachable":      "I can't reach the language model server right now. Check that it is running, then try again.",
+	"error.generic":          "Something went wrong while processing your message. Please try again.",
+
+	// channels
+	"channels.access_requested": "Sorry, I only talk to people I know. I've let my owner know you'd like access.",
+	"channels.access_notify":    "%s wants to talk to me on %s: %q\nReply to approve or deny, or use /approve %s %s in the web chat.",
+	"commands.header":           "Commands:\n/help - list commands",
+	"commands.usage":            "Usage: /%s %s",
+	"commands.owner_only":       "/%s is only available to the owner.",
+
+	// first-contact welcome
+	"welcome.intro":        "Hi! I'm an assistant you can talk to here. I can help with:",
+	"welcome.other_tools":  "other tools: %s",
+	"welcome.skills":       "Skills: %s",
+	"welcome.outro":        "Just write what you need in plain language.",
+	"welcome.tasks":        "tasks, time blocks and goals",
+	"welcome.calendar":     "your calendar, reminders and scheduled jobs",
+	"welcome.files":        "reading and writing files and PDFs",
+	"welcome.commands":     "running commands and containers",
+	"welcome.voice":        "voice messages",
+	"welcome.journal":      "a journal",
+	"welcome.recipes":      "recipes and meal plans",
+	"welcome.flashcards":   "flashcards",
+	"welcome.medications":  "medication reminders",
+	"welcome.sleep_travel": "sleep and travel tracking",
+	"welcome.links":        "a link library",
+	"welcome.email":        "triaging your unread email",
+	"welcome.news":         "tech news and AI papers",
+	"welcome.finance":      "currency conversion and stock prices",
+	"welcome.background":   "background tasks",
+
+	// focus mode
+	"focus.digest": "While you were focusing I held back %d message(s):",
+	"focus.on":     "Focus is on until %s. I'll hold non-urgent messages until then.",
+	"focus.off":    "Focus is off.",
+	"focus.status": "Focus is on until %s.",
+	"focus.none":   "Focus isn't on.",
+
+	// interactive CLI
+	"cli.interactive": "localagent interactive mode (type 'exit' to quit)",
+	"cli.goodbye":     "Goodbye!",
+	"cli.error":       "Error: %v",
+	"cli.cancelled":   "(cancelled)",
+}
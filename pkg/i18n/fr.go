@@ -0,0 +1,56 @@
+package i18n
+
+var fr = map[string]string{
+	"agent.no_response": "J'ai terminé, mais je n'ai pas de réponse à donner.",
+	"agent.time_limit":  "Je me suis arrêté après %s pendant %s, c'est la limite de temps pour un message. Essaie de découper la tâche en étapes plus petites et de les envoyer une par une.",
+	"agent.stalled":     "Je me suis arrêté car %s ne répondait plus. Merci de réessayer.",
+
+	"error.details":          "Détails : %s",
+	"error.no_api_base":      "Aucun modèle de langage n'est configuré. Renseigne provider.api_base dans la configuration.",
+	"error.auth":             "Le modèle de langage a refusé la clé d'API. Vérifie provider.api_key_env dans la configuration.",
+	"error.rate_limited":     "Le modèle de langage limite les requêtes. Merci de réessayer dans une minute.",
+	"error.model_not_found":  "Le modèle configuré n'est pas disponible chez le fournisseur. Vérifie agents.defaults.model dans la configuration.",
+	"error.context_overflow": "Cette conversation est trop longue pour le modèle. Démarre une nouvelle session ou demande-moi de la résumer.",
+	"error.timeout":          "Le modèle de langage a mis trop de temps à répondre. Merci de réessayer.",
+	"error.server":           "Le serveur du modèle de langage a rencontré une erreur. Merci de réessayer dans un instant.",
+	"error.bad_request":      "Le modèle de langage n'a pas pu traiter cette requête. Merci de réessayer.",
+	"error.unreachable":      "Je n'arrive pas à joindre le serveur du modèle de langage. Vérifie qu'il tourne, puis réessaie.",
+	"error.generic":          "Un problème est survenu pendant le traitement de ton message. Merci de réessayer.",
+
+	"channels.access_requested": "Désolé, je ne parle qu'aux personnes que je connais. J'ai prévenu mon propriétaire que tu aimerais y avoir accès.",
+	"channels.access_notify":    "%s veut me parler sur %s : %q\nRéponds pour accepter ou refuser, ou utilise /approve %s %s dans le chat web.",
+	"commands.header":           "Commandes :\n/help - lister les commandes",
+	"commands.usage":            "Utilisation : /%s %s",
+	"commands.owner_only":       "/%s est réservé au propriétaire.",
+
+	"welcome.intro":        "Bonjour ! Je suis un assistant avec qui tu peux discuter ici. Je peux t'aider avec :",
+	"welcome.other_tools":  "autres outils : %s",
+	"welcome.skills":       "Compétences : %s",
+	"welcome.outro":        "Écris simplement ce dont tu as besoin.",
+	"welcome.tasks":        "les tâches, créneaux et objectifs",
+	"welcome.calendar":     "ton agenda, les rappels et les tâches planifiées",
+	"welcome.files":        "la lecture et l'écriture de fichiers et de PDF",
+	"welcome.commands":     "l'exécution de commandes et de conteneurs",
+	"welcome.voice":        "les messages vocaux",
+	"welcome.journal":      "un journal",
+	"welcome.recipes":      "les recettes et menus",
+	"welcome.flashcards":   "les cartes mémoire",
+	"welcome.medications":  "les rappels de médicaments",
+	"welcome.sleep_travel": "le suivi du sommeil et des voyages",
+	"welcome.links":        "une bibliothèque de liens",
+	"welcome.email":        "le tri de vos e-mails non lus",
+	"welcome.news":         "l'actualité tech et les articles d'IA",
+	"welcome.finance":      "la conversion de devises et le cours des actions",
+	"welcome.background":   "les tâches de fond",
+
+	"focus.digest": "Pendant ta séance de concentration, j'ai retenu %d message(s) :",
+	"focus.on":     "Mode concentration activé jusqu'à %s. Je retiens les messages non urgents d'ici là.",
+	"focus.off":    "Mode concentration désactivé.",
+	"focus.status": "Mode concentration activé jusqu'à %s.",
+	"focus.none":   "Le mode concentration n'est pas activé.",
+
+	"cli.interactive": "localagent mode interactif (tape 'exit' pour quitter)",
+	"cli.goodbye":     "Au revoir !",
+	"cli.error":       "Erreur : %v",
+	"cli.cancelled":   "(annulé)",
+}
@@ -0,0 +1,73 @@
+// Package i18n translates the user-facing strings produced by the Go code:
+// chat replies and errors, channel notices and interactive CLI output.
+// Prompts, logs and tool results stay in English for the model.
+package i18n
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultLocale is used when none is set and for keys a catalog lacks.
+const DefaultLocale = "en"
+
+// catalogs maps a locale to its messages by key. Messages are fmt formats
+// taking the same verbs in the same order in every locale.
+var catalogs = map[string]map[string]string{
+	"en": en,
+	"de": de,
+	"fr": fr,
+}
+
+var current atomic.Value // string
+
+// SetLocale selects the catalog for T. It accepts a language ("de") or a
+// POSIX or BCP 47 tag ("de_DE.UTF-8", "fr-CH"); empty selects the default.
+// Unknown locales leave the current one unchanged.
+func SetLocale(tag string) error {
+	if tag == "" {
+		current.Store(DefaultLocale)
+		return nil
+	}
+	lang := strings.ToLower(tag)
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported locale %q (available: %s)", tag, strings.Join(Locales(), ", "))
+	}
+	current.Store(lang)
+	return nil
+}
+
+// Locale returns the selected locale.
+func Locale() string {
+	if l, ok := current.Load().(string); ok {
+		return l
+	}
+	return DefaultLocale
+}
+
+// Locales lists the available locales, sorted.
+func Locales() []string {
+	return slices.Sorted(maps.Keys(catalogs))
+}
+
+// T returns the message for key in the selected locale, formatted with
+// args. Keys missing from the locale fall back to English, then to the key
+// itself.
+func T(key string, args ...any) string {
+	msg, ok := catalogs[Locale()][key]
+	if !ok {
+		if msg, ok = en[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,53 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key, msg := range en {
+			got, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s: missing %s", locale, key)
+				continue
+			}
+			if want, have := verbRe.FindAllString(msg, -1), verbRe.FindAllString(got, -1); !slices.Equal(want, have) {
+				t.Errorf("%s: %s has verbs %v, want %v", locale, key, have, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s: %s is not in the English catalog", locale, key)
+			}
+		}
+	}
+}
+
+func TestSetLocale(t *testing.T) {
+	defer SetLocale("")
+
+	for _, tag := range []string{"de", "de_DE.UTF-8", "DE-at"} {
+		SetLocale("")
+		if err := SetLocale(tag); err != nil || Locale() != "de" {
+			t.Errorf("SetLocale(%q) = %v, locale %s", tag, err, Locale())
+		}
+	}
+	if err := SetLocale("xx"); err == nil || Locale() != "de" {
+		t.Errorf("SetLocale(xx) = %v, locale %s; want an error and no change", err, Locale())
+	}
+	if got := T("cli.error", "boom"); got != "Fehler: boom" {
+		t.Errorf("T(cli.error) = %q", got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key = %q", got)
+	}
+	SetLocale("")
+	if got := T("cli.goodbye"); got != "Goodbye!" {
+		t.Errorf("default locale T(cli.goodbye) = %q", got)
+	}
+}
@@ -0,0 +1,152 @@
+// Package idle runs deferred maintenance (summarization, media pruning,
+// session archival) once the agent has been quiet for a while, so the work
+// doesn't compete with interactive turns for the LLM, disk or CPU.
+package idle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+type task struct {
+	name string
+	fn   func(ctx context.Context)
+}
+
+// Scheduler tracks activity and runs work once nothing has happened for
+// its quiet period. Deferred tasks run once; recurring tasks run once per
+// quiet period. A nil Scheduler runs deferred tasks immediately.
+type Scheduler struct {
+	quiet time.Duration
+
+	mu        sync.Mutex
+	last      time.Time // last activity
+	ranAfter  time.Time // recurring tasks ran in the quiet period after this activity
+	pending   []task
+	queued    map[string]bool
+	recurring []task
+	active    func() bool
+	cancel    context.CancelFunc // stops the current run when activity resumes
+}
+
+// New returns a scheduler that considers the agent idle after quiet
+// without activity.
+func New(quiet time.Duration) *Scheduler {
+	return &Scheduler{quiet: quiet, last: time.Now(), queued: make(map[string]bool)}
+}
+
+// SetActive limits work to times when active reports true, e.g. the
+// heartbeat's active hours. Nil allows any time.
+func (s *Scheduler) SetActive(active func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = active
+}
+
+// Touch records activity, postponing idle work and stopping a run in
+// progress between tasks.
+func (s *Scheduler) Touch() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = time.Now()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// Defer queues fn to run once at the next idle period. A task already
+// queued under name is not queued again.
+func (s *Scheduler) Defer(name string, fn func(ctx context.Context)) {
+	if s == nil {
+		go fn(context.Background())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queued[name] {
+		return
+	}
+	s.queued[name] = true
+	s.pending = append(s.pending, task{name, fn})
+}
+
+// Every runs fn once in every idle period.
+func (s *Scheduler) Every(name string, fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recurring = append(s.recurring, task{name, fn})
+}
+
+// Run checks for idleness every interval until stop is closed.
+func (s *Scheduler) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.RunIdle()
+		}
+	}
+}
+
+// RunIdle runs queued tasks, then recurring ones not yet run in this quiet
+// period, if the agent is idle. It stops early when activity resumes;
+// unfinished deferred tasks stay queued. It returns how many tasks ran.
+func (s *Scheduler) RunIdle() int {
+	s.mu.Lock()
+	if time.Since(s.last) < s.quiet || s.active != nil && !s.active() {
+		s.mu.Unlock()
+		return 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	since := s.last
+	s.mu.Unlock()
+	defer cancel()
+
+	ran := 0
+	for ctx.Err() == nil {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.mu.Unlock()
+			break
+		}
+		t := s.pending[0]
+		s.pending = s.pending[1:]
+		delete(s.queued, t.name)
+		s.mu.Unlock()
+		s.run(ctx, t)
+		ran++
+	}
+
+	s.mu.Lock()
+	recurring := s.recurring
+	if !s.ranAfter.Equal(since) {
+		s.ranAfter = since
+	} else {
+		recurring = nil
+	}
+	s.mu.Unlock()
+	for _, t := range recurring {
+		if ctx.Err() != nil {
+			break
+		}
+		s.run(ctx, t)
+		ran++
+	}
+	return ran
+}
+
+func (s *Scheduler) run(ctx context.Context, t task) {
+	start := time.Now()
+	t.fn(ctx)
+	logger.Debug("idle: ran %s in %v", t.name, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,54 @@
+package idle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunIdle(t *testing.T) {
+	s := New(time.Hour)
+	var deferred, recurring int
+	s.Defer("summarize a", func(context.Context) { deferred++ })
+	s.Defer("summarize a", func(context.Context) { deferred++ }) // already queued
+	s.Every("prune", func(context.Context) { recurring++ })
+
+	if s.RunIdle() != 0 {
+		t.Fatal("ran work before the quiet period")
+	}
+
+	s.quiet = 0
+	active := false
+	s.SetActive(func() bool { return active })
+	if s.RunIdle() != 0 {
+		t.Fatal("ran work outside active hours")
+	}
+
+	active = true
+	if n := s.RunIdle(); n != 2 || deferred != 1 || recurring != 1 {
+		t.Fatalf("ran %d: deferred=%d recurring=%d", n, deferred, recurring)
+	}
+	if s.RunIdle() != 0 {
+		t.Error("recurring work should run once per quiet period")
+	}
+	s.Touch()
+	if s.RunIdle() != 1 || recurring != 2 {
+		t.Errorf("recurring = %d after new activity", recurring)
+	}
+}
+
+func TestActivityStopsRun(t *testing.T) {
+	s := New(0)
+	var ran []string
+	s.Defer("a", func(context.Context) { ran = append(ran, "a"); s.Touch() })
+	s.Defer("b", func(context.Context) { ran = append(ran, "b") })
+
+	s.RunIdle()
+	if len(ran) != 1 {
+		t.Fatalf("ran %v; activity should stop the run", ran)
+	}
+	s.RunIdle()
+	if len(ran) != 2 || ran[1] != "b" {
+		t.Errorf("ran %v; b should stay queued", ran)
+	}
+}
@@ -0,0 +1,302 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"localagent/pkg/audit"
+	"localagent/pkg/logger"
+	"localagent/pkg/prompts"
+	"localagent/pkg/providers"
+	"localagent/pkg/session"
+	"localagent/pkg/todo"
+	"localagent/pkg/utils"
+	"localagent/pkg/vault"
+)
+
+// ErrEmpty is returned when there is nothing to write about.
+var ErrEmpty = errors.New("nothing to write about")
+
+// Limits on the material sent to the model.
+const (
+	maxMessageChars = 500
+	maxSessionChars = 6000
+	maxActions      = 100
+)
+
+// skippedSessions hold no conversation with the user.
+var skippedSessions = []string{"heartbeat", "federation:"}
+
+// Event is a calendar event on the day of an entry.
+type Event struct {
+	Title    string
+	Location string
+	Start    time.Time
+}
+
+// EventsFunc lists calendar events starting in [from, to).
+type EventsFunc func(ctx context.Context, from, to time.Time) ([]Event, error)
+
+// Conversation is the part of a session that happened on one day.
+type Conversation struct {
+	Session string
+	Lines   []string // "user: ..." / "assistant: ..."
+}
+
+// Material is what a daily entry is written from.
+type Material struct {
+	Conversations []Conversation
+	Notes         string // the day's memory notes
+	Tasks         []string
+	Events        []Event
+	Actions       []audit.Entry
+}
+
+// Empty reports whether nothing happened that day.
+func (m Material) Empty() bool {
+	return len(m.Conversations) == 0 && m.Notes == "" && len(m.Tasks) == 0 && len(m.Events) == 0 && len(m.Actions) == 0
+}
+
+// Generator writes daily entries and weekly rollups.
+type Generator struct {
+	store     *Store
+	provider  providers.LLMProvider
+	model     string
+	sessions  *session.SessionManager
+	memoryDir string
+	tasks     *todo.TodoService
+	auditPath string
+	events    EventsFunc
+}
+
+func NewGenerator(workspace string, provider providers.LLMProvider, model string, sessions *session.SessionManager) *Generator {
+	return &Generator{
+		store:     NewStore(workspace),
+		provider:  provider,
+		model:     model,
+		sessions:  sessions,
+		memoryDir: filepath.Join(workspace, "memory"),
+	}
+}
+
+// Store returns the store entries are written to.
+func (g *Generator) Store() *Store {
+	return g.store
+}
+
+// SetTodoService includes tasks completed on the day.
+func (g *Generator) SetTodoService(s *todo.TodoService) {
+	g.tasks = s
+}
+
+// SetAuditPath includes side-effecting tool actions from the audit log.
+func (g *Generator) SetAuditPath(path string) {
+	g.auditPath = path
+}
+
+// SetEvents includes calendar events.
+func (g *Generator) SetEvents(fn EventsFunc) {
+	g.events = fn
+}
+
+// Collect gathers the material for the day containing day, in its location.
+func (g *Generator) Collect(ctx context.Context, day time.Time) Material {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+	var m Material
+
+	if g.sessions != nil {
+		for _, info := range g.sessions.ListSessions() {
+			if info.Updated.Before(from) || skipSession(info.Key) {
+				continue
+			}
+			if c := conversation(info.Key, g.sessions.GetTimeline(info.Key), from, to); len(c.Lines) > 0 {
+				m.Conversations = append(m.Conversations, c)
+			}
+		}
+	}
+
+	notes := filepath.Join(g.memoryDir, from.Format("200601"), from.Format("20060102")+".md")
+	if data, err := vault.ReadFile(notes); err == nil {
+		m.Notes = strings.TrimSpace(string(data))
+	}
+
+	if g.tasks != nil {
+		for _, t := range g.tasks.QueryTasks(todo.TaskQuery{Status: "done"}) {
+			if t.DoneAtMS == nil {
+				continue
+			}
+			if done := time.UnixMilli(*t.DoneAtMS); !done.Before(from) && done.Before(to) {
+				m.Tasks = append(m.Tasks, t.Title)
+			}
+		}
+	}
+
+	if g.events != nil {
+		events, err := g.events(ctx, from, to)
+		if err != nil {
+			logger.Warn("journal: calendar: %v", err)
+		}
+		m.Events = events
+	}
+
+	if g.auditPath != "" {
+		entries, err := audit.ReadFile(g.auditPath, audit.Filter{Since: from})
+		if err != nil {
+			logger.Warn("journal: audit log: %v", err)
+		}
+		for _, e := range entries {
+			if e.Time.Before(to) && e.Status != "error" {
+				m.Actions = append(m.Actions, e)
+			}
+		}
+		if len(m.Actions) > maxActions {
+			m.Actions = m.Actions[len(m.Actions)-maxActions:]
+		}
+	}
+	return m
+}
+
+func skipSession(key string) bool {
+	for _, s := range skippedSessions {
+		if key == s || (strings.HasSuffix(s, ":") && strings.HasPrefix(key, s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// conversation keeps the user and assistant text of timeline in [from, to).
+func conversation(key string, timeline []session.TimelineEntry, from, to time.Time) Conversation {
+	c := Conversation{Session: key}
+	size := 0
+	for _, e := range timeline {
+		if e.Kind != "message" || e.Message == nil || e.Timestamp.Before(from) || !e.Timestamp.Before(to) {
+			continue
+		}
+		msg := e.Message
+		text := strings.TrimSpace(msg.Content)
+		role := msg.Role
+		if role == "tool" && msg.ToolName == "message" {
+			role = "assistant" // sent through the message tool
+		} else if role != "user" && role != "assistant" {
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		line := role + ": " + utils.Truncate(strings.Join(strings.Fields(text), " "), maxMessageChars)
+		if size += len(line); size > maxSessionChars {
+			c.Lines = append(c.Lines, "[...]")
+			break
+		}
+		c.Lines = append(c.Lines, line)
+	}
+	return c
+}
+
+// Text renders the material as the model's input.
+func (m Material) Text(loc *time.Location) string {
+	var sb strings.Builder
+	for _, c := range m.Conversations {
+		fmt.Fprintf(&sb, "## Conversation (%s)\n%s\n\n", c.Session, strings.Join(c.Lines, "\n"))
+	}
+	if m.Notes != "" {
+		fmt.Fprintf(&sb, "## Memory notes\n%s\n\n", m.Notes)
+	}
+	if len(m.Tasks) > 0 {
+		fmt.Fprintf(&sb, "## Completed tasks\n- %s\n\n", strings.Join(m.Tasks, "\n- "))
+	}
+	if len(m.Events) > 0 {
+		sb.WriteString("## Calendar events\n")
+		for _, e := range m.Events {
+			fmt.Fprintf(&sb, "- %s %s", e.Start.In(loc).Format("15:04"), e.Title)
+			if e.Location != "" {
+				fmt.Fprintf(&sb, " (%s)", e.Location)
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	if len(m.Actions) > 0 {
+		sb.WriteString("## Tool actions\n")
+		for _, a := range m.Actions {
+			fmt.Fprintf(&sb, "- %s %s %s\n", a.Time.In(loc).Format("15:04"), a.Action, utils.Truncate(a.Target, 120))
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Daily writes the entry for the day containing day and returns it. It
+// returns ErrEmpty without writing when nothing happened.
+func (g *Generator) Daily(ctx context.Context, day time.Time) (string, error) {
+	m := g.Collect(ctx, day)
+	if m.Empty() {
+		return "", ErrEmpty
+	}
+	title := day.Format("Monday, January 2, 2006")
+	body, err := g.complete(ctx, fmt.Sprintf(prompts.JournalDaily, title), m.Text(day.Location()))
+	if err != nil {
+		return "", err
+	}
+	entry := fmt.Sprintf("# %s\n\n%s\n", title, body)
+	if err := g.store.Write(DayName(day), entry); err != nil {
+		return "", err
+	}
+	logger.Info("journal: wrote %s", DayName(day))
+	return entry, nil
+}
+
+// Weekly writes the rollup of the ISO week containing day from its daily
+// entries and returns it. It returns ErrEmpty when the week has none.
+func (g *Generator) Weekly(ctx context.Context, day time.Time) (string, error) {
+	name := WeekName(day)
+	start, err := WeekStart(name, day.Location())
+	if err != nil {
+		return "", err
+	}
+	var days []string
+	for i := range 7 {
+		d := start.AddDate(0, 0, i)
+		if entry, err := g.store.Read(DayName(d)); err == nil {
+			days = append(days, strings.TrimSpace(entry))
+		}
+	}
+	if len(days) == 0 {
+		return "", ErrEmpty
+	}
+	end := start.AddDate(0, 0, 6)
+	title := fmt.Sprintf("Week %s (%s – %s)", name, start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+	body, err := g.complete(ctx, fmt.Sprintf(prompts.JournalWeekly, title), strings.Join(days, "\n\n---\n\n"))
+	if err != nil {
+		return "", err
+	}
+	entry := fmt.Sprintf("# %s\n\n%s\n", title, body)
+	if err := g.store.Write(name, entry); err != nil {
+		return "", err
+	}
+	logger.Info("journal: wrote %s", name)
+	return entry, nil
+}
+
+func (g *Generator) complete(ctx context.Context, system, material string) (string, error) {
+	resp, err := g.provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: strings.TrimSpace(system)},
+		{Role: "user", Content: material},
+	}, nil, g.model, map[string]any{
+		"max_tokens":  1500,
+		"temperature": 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+	body := strings.TrimSpace(resp.Content)
+	if body == "" {
+		return "", errors.New("model returned an empty entry")
+	}
+	return body, nil
+}
@@ -0,0 +1,150 @@
+// Package journal compiles a daily journal entry from the day's
+// conversations, completed tasks, calendar events and notable tool actions,
+// and rolls the week's entries up into a weekly summary.
+//
+// Entries are markdown files in workspace/journal: YYYY-MM-DD.md for days
+// and YYYY-Www.md (ISO week) for weekly rollups.
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/vault"
+)
+
+const (
+	dayLayout = "2006-01-02"
+	ext       = ".md"
+)
+
+// ErrNotFound is returned when no entry exists for a date or week.
+var ErrNotFound = errors.New("no journal entry")
+
+var (
+	dayRe  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	weekRe = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+)
+
+// Store reads and writes journal files.
+type Store struct {
+	dir string
+}
+
+func NewStore(workspace string) *Store {
+	return &Store{dir: filepath.Join(workspace, "journal")}
+}
+
+// Dir is the journal directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// DayName is the entry name for the day containing t.
+func DayName(t time.Time) string {
+	return t.Format(dayLayout)
+}
+
+// WeekName is the ISO week name ("2026-W07") for the week containing t.
+func WeekName(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// WeekStart returns midnight on the Monday of the ISO week named name, in loc.
+func WeekStart(name string, loc *time.Location) (time.Time, error) {
+	m := weekRe.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid week %q (want YYYY-Www)", name)
+	}
+	var year, week int
+	fmt.Sscan(m[1], &year)
+	fmt.Sscan(m[2], &week)
+	// January 4th is always in ISO week 1.
+	jan4 := time.Date(year, 1, 4, 0, 0, 0, 0, loc)
+	offset := (int(jan4.Weekday()) + 6) % 7 // days since Monday
+	start := jan4.AddDate(0, 0, -offset+(week-1)*7)
+	if y, w := start.ISOWeek(); y != year || w != week {
+		return time.Time{}, fmt.Errorf("invalid week %q", name)
+	}
+	return start, nil
+}
+
+// ValidName reports whether name is a day or week entry name.
+func ValidName(name string) bool {
+	return dayRe.MatchString(name) || weekRe.MatchString(name)
+}
+
+// IsWeek reports whether name is a weekly rollup.
+func IsWeek(name string) bool {
+	return weekRe.MatchString(name)
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+ext)
+}
+
+// Read returns the entry called name, a day or week name.
+func (s *Store) Read(name string) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("invalid entry name %q (want YYYY-MM-DD or YYYY-Www)", name)
+	}
+	data, err := vault.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Exists reports whether the entry called name has been written.
+func (s *Store) Exists(name string) bool {
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+// Write replaces the entry called name.
+func (s *Store) Write(name, content string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid entry name %q", name)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return vault.WriteFileAtomic(s.path(name), []byte(content), 0644)
+}
+
+// List returns the names of daily entries and of weekly rollups, each
+// newest first.
+func (s *Store) List() (days, weeks []string, err error) {
+	files, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ext)
+		switch {
+		case !ok || f.IsDir():
+		case dayRe.MatchString(name):
+			days = append(days, name)
+		case weekRe.MatchString(name):
+			weeks = append(weeks, name)
+		}
+	}
+	slices.Sort(days)
+	slices.Reverse(days)
+	slices.Sort(weeks)
+	slices.Reverse(weeks)
+	return days, weeks, nil
+}
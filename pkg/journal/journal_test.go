@@ -0,0 +1,152 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/providers"
+	"localagent/pkg/session"
+)
+
+type fakeProvider struct {
+	calls []string // user message of each call
+}
+
+func (p *fakeProvider) Chat(_ context.Context, msgs []providers.Message, _ []providers.ToolDefinition, _ string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.calls = append(p.calls, msgs[len(msgs)-1].Content)
+	return &providers.LLMResponse{Content: "## Highlights\n- entry"}, nil
+}
+
+func (p *fakeProvider) GetDefaultModel() string { return "test" }
+
+func TestWeekStartRoundTrip(t *testing.T) {
+	for _, day := range []string{"2026-01-01", "2026-03-15", "2026-12-31", "2027-01-03"} {
+		d, _ := time.Parse(dayLayout, day)
+		start, err := WeekStart(WeekName(d), time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start.Weekday() != time.Monday || d.Before(start) || !d.Before(start.AddDate(0, 0, 7)) {
+			t.Errorf("%s: week %s starts %s", day, WeekName(d), start.Format(dayLayout))
+		}
+	}
+	if _, err := WeekStart("2026-W54", time.UTC); err == nil {
+		t.Error("expected error for week 54")
+	}
+}
+
+func TestStoreListAndRead(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, name := range []string{"2026-03-02", "2026-03-03", "2026-W10"} {
+		if err := s.Write(name, "# "+name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Write("../escape", "x"); err == nil {
+		t.Error("expected invalid name to be rejected")
+	}
+	days, weeks, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(days, ",") != "2026-03-03,2026-03-02" || strings.Join(weeks, ",") != "2026-W10" {
+		t.Errorf("days=%v weeks=%v", days, weeks)
+	}
+	if _, err := s.Read("2026-03-04"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestDailyCollectsTheDay(t *testing.T) {
+	ws := t.TempDir()
+	sm := session.NewSessionManager(filepath.Join(ws, "sessions"))
+	sm.AddMessage("web:default", "user", "Plan the trip to Lisbon")
+	sm.AddMessage("web:default", "assistant", "Booked flights for May 3")
+	sm.AddMessage("heartbeat", "assistant", "HEARTBEAT_OK")
+
+	now := time.Now()
+	notes := filepath.Join(ws, "memory", now.Format("200601"), now.Format("20060102")+".md")
+	os.MkdirAll(filepath.Dir(notes), 0755)
+	os.WriteFile(notes, []byte("Prefers window seats"), 0644)
+
+	p := &fakeProvider{}
+	g := NewGenerator(ws, p, "test", sm)
+	g.SetEvents(func(_ context.Context, from, to time.Time) ([]Event, error) {
+		return []Event{{Title: "Dentist", Start: from.Add(10 * time.Hour)}}, nil
+	})
+
+	entry, err := g.Daily(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(entry, "# "+now.Format("Monday")) {
+		t.Errorf("entry missing title: %q", entry)
+	}
+	input := p.calls[0]
+	for _, want := range []string{"Lisbon", "Booked flights", "Prefers window seats", "10:00 Dentist"} {
+		if !strings.Contains(input, want) {
+			t.Errorf("material missing %q:\n%s", want, input)
+		}
+	}
+	if strings.Contains(input, "HEARTBEAT_OK") {
+		t.Error("heartbeat session should be skipped")
+	}
+	if !g.Store().Exists(DayName(now)) {
+		t.Error("entry not written")
+	}
+
+	// Nothing happened the day before.
+	if _, err := NewGenerator(ws, p, "test", sm).Daily(context.Background(), now.AddDate(0, 0, -1)); !errors.Is(err, ErrEmpty) {
+		t.Errorf("got %v, want ErrEmpty", err)
+	}
+}
+
+func TestSchedulerWritesOncePerDayAndWeekly(t *testing.T) {
+	ws := t.TempDir()
+	p := &fakeProvider{}
+	g := NewGenerator(ws, p, "test", nil)
+	g.SetEvents(func(_ context.Context, from, _ time.Time) ([]Event, error) {
+		return []Event{{Title: "Standup", Start: from.Add(9 * time.Hour)}}, nil
+	})
+	s, err := NewScheduler(g, ws, "21:00", "sunday")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sunday 2026-03-08, before the journal time: yesterday is due.
+	now := time.Date(2026, 3, 8, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	s.Check()
+	s.Check()
+	if len(p.calls) != 1 || !g.Store().Exists("2026-03-07") {
+		t.Fatalf("calls=%d, want Saturday's entry once", len(p.calls))
+	}
+
+	// After the journal time: Sunday's entry, then the week's rollup.
+	now = time.Date(2026, 3, 8, 21, 30, 0, 0, time.UTC)
+	s.Check()
+	if len(p.calls) != 3 || !g.Store().Exists("2026-03-08") || !g.Store().Exists("2026-W10") {
+		t.Fatalf("calls=%d, want Sunday's entry and the weekly rollup", len(p.calls))
+	}
+	if !strings.Contains(p.calls[2], "2026-03-07") && !strings.Contains(p.calls[2], "Saturday") {
+		t.Errorf("rollup input should include the daily entries: %q", p.calls[2])
+	}
+	s.Check()
+	if len(p.calls) != 3 {
+		t.Errorf("calls=%d after a repeated check, want 3", len(p.calls))
+	}
+}
+
+func TestNewSchedulerValidates(t *testing.T) {
+	if _, err := NewScheduler(nil, t.TempDir(), "25:00", ""); err == nil {
+		t.Error("expected error for invalid time")
+	}
+	if _, err := NewScheduler(nil, t.TempDir(), "", "caturday"); err == nil {
+		t.Error("expected error for invalid weekday")
+	}
+}
@@ -0,0 +1,131 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+const (
+	checkInterval   = 10 * time.Minute
+	generateTimeout = 5 * time.Minute
+
+	stateNamespace = "journal"
+	stateDaily     = "last_daily"
+	stateWeekly    = "last_weekly"
+)
+
+// Scheduler writes the day's entry once a day at a set time, and the
+// weekly rollup after the entry on the weekly day. If the gateway was down
+// at that time, the most recent missed day is written on the next check.
+type Scheduler struct {
+	gen    *Generator
+	at     time.Duration // after midnight
+	weekly time.Weekday
+	state  *state.Manager
+	now    func() time.Time
+	stop   chan struct{}
+}
+
+// NewScheduler runs gen at "HH:MM" (default "22:00") with the rollup on
+// weekday (default "sunday"). Times are in the agent's timezone.
+func NewScheduler(gen *Generator, workspace, at, weekday string) (*Scheduler, error) {
+	if at == "" {
+		at = "22:00"
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid journal time %q (want HH:MM)", at)
+	}
+	weekly, err := ParseWeekday(weekday)
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{
+		gen:    gen,
+		at:     time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute,
+		weekly: weekly,
+		state:  state.NewManager(workspace),
+		now:    when.Now,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+// ParseWeekday accepts an English weekday name; empty means Sunday.
+func ParseWeekday(s string) (time.Weekday, error) {
+	if s == "" {
+		return time.Sunday, nil
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(checkInterval)
+	go func() {
+		s.Check()
+		for {
+			select {
+			case <-ticker.C:
+				s.Check()
+			case <-s.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("journal scheduler started (daily at %02d:%02d, weekly on %s)", int(s.at.Hours()), int(s.at.Minutes())%60, s.weekly)
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// Check writes the entry for the most recent day whose time has passed,
+// unless that was already done. Failures are retried on the next check.
+func (s *Scheduler) Check() {
+	now := s.now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if now.Sub(day) < s.at {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generateTimeout)
+	defer cancel()
+
+	name := DayName(day)
+	var last string
+	s.state.Get(stateNamespace, stateDaily, &last)
+	if last < name {
+		if _, err := s.gen.Daily(ctx, day); err != nil && !errors.Is(err, ErrEmpty) {
+			logger.Warn("journal: %s: %v", name, err)
+			return
+		}
+		s.state.Set(stateNamespace, stateDaily, name)
+	}
+
+	if day.Weekday() != s.weekly {
+		return
+	}
+	week := WeekName(day)
+	var lastWeek string
+	s.state.Get(stateNamespace, stateWeekly, &lastWeek)
+	if lastWeek >= week {
+		return
+	}
+	if _, err := s.gen.Weekly(ctx, day); err != nil && !errors.Is(err, ErrEmpty) {
+		logger.Warn("journal: %s: %v", week, err)
+		return
+	}
+	s.state.Set(stateNamespace, stateWeekly, week)
+}
@@ -0,0 +1,261 @@
+// Package llmcapture writes raw LLM request/response pairs to disk for
+// debugging, e.g. to see exactly which tools and messages the model was
+// given when it ignored a tool.
+package llmcapture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+// DefaultMaxFiles is how many captures are kept when no limit is configured.
+const DefaultMaxFiles = 200
+
+// ErrNotFound is returned by Get for unknown or invalid capture names.
+var ErrNotFound = errors.New("capture not found")
+
+var namePattern = regexp.MustCompile(`^\d{8}-\d{6}-\d{3}-\d{4}\.json$`)
+
+// Summary describes one capture without its payloads.
+type Summary struct {
+	Name         string    `json:"name"`
+	Time         time.Time `json:"time"`
+	Model        string    `json:"model"`
+	Status       int       `json:"status"`
+	DurationMS   int64     `json:"duration_ms"`
+	Error        string    `json:"error,omitempty"`
+	Messages     int       `json:"messages"`
+	Tools        int       `json:"tools"`
+	ToolCalls    []string  `json:"tool_calls,omitempty"`
+	FinishReason string    `json:"finish_reason,omitempty"`
+	Size         int64     `json:"size,omitempty"`
+}
+
+type record struct {
+	Summary
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Store keeps the newest captures as JSON files in a directory.
+type Store struct {
+	dir      string
+	maxFiles int
+	redact   func(string) string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// New returns a store writing to dir and keeping at most maxFiles captures
+// (DefaultMaxFiles when <= 0). redact, when non-nil, is applied to every
+// string in the payloads.
+func New(dir string, maxFiles int, redact func(string) string) *Store {
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	return &Store{dir: dir, maxFiles: maxFiles, redact: redact}
+}
+
+// Dir returns the capture directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Capture writes one exchange. It matches providers.CaptureFunc; failures
+// are logged, never returned, so capturing can't break a chat.
+func (s *Store) Capture(model string, request, response []byte, status int, elapsed time.Duration, err error) {
+	now := time.Now()
+	rec := record{
+		Summary: Summary{
+			Time:       now,
+			Model:      model,
+			Status:     status,
+			DurationMS: elapsed.Milliseconds(),
+		},
+		Request:  s.payload(request),
+		Response: s.payload(response),
+	}
+	if err != nil {
+		rec.Error = s.redactString(err.Error())
+	}
+	summarize(&rec)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq = (s.seq + 1) % 10000
+	rec.Name = fmt.Sprintf("%s-%03d-%04d.json", now.UTC().Format("20060102-150405"), now.Nanosecond()/int(time.Millisecond), s.seq)
+
+	data, err := marshal(rec, "  ")
+	if err != nil {
+		logger.Warn("llm capture: %v", err)
+		return
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		logger.Warn("llm capture: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, rec.Name), data, 0o600); err != nil {
+		logger.Warn("llm capture: %v", err)
+		return
+	}
+	s.rotate()
+}
+
+// rotate removes the oldest captures beyond maxFiles. Callers hold mu.
+func (s *Store) rotate() {
+	names := s.names()
+	for len(names) > s.maxFiles {
+		os.Remove(filepath.Join(s.dir, names[0]))
+		names = names[1:]
+	}
+}
+
+// names returns capture file names, oldest first.
+func (s *Store) names() []string {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && namePattern.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns summaries of all captures, newest first.
+func (s *Store) List() []Summary {
+	s.mu.Lock()
+	names := s.names()
+	s.mu.Unlock()
+
+	out := make([]Summary, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(filepath.Join(s.dir, names[i]))
+		if err != nil {
+			continue // rotated away meanwhile
+		}
+		var sum Summary
+		if err := json.Unmarshal(data, &sum); err != nil {
+			continue
+		}
+		sum.Name = names[i]
+		sum.Size = int64(len(data))
+		out = append(out, sum)
+	}
+	return out
+}
+
+// Get returns the full JSON of one capture.
+func (s *Store) Get(name string) ([]byte, error) {
+	if !namePattern.MatchString(name) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// payload redacts a JSON body. Bodies that aren't JSON (error pages) are
+// stored as a JSON string.
+func (s *Store) payload(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		v = string(data)
+	}
+	out, err := marshal(s.value(v), "")
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// marshal encodes v without escaping HTML, so markup in prompts and error
+// pages stays readable.
+func marshal(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (s *Store) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return s.redactString(v)
+	case map[string]any:
+		for k, item := range v {
+			v[k] = s.value(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = s.value(item)
+		}
+	}
+	return v
+}
+
+func (s *Store) redactString(str string) string {
+	if s.redact == nil {
+		return str
+	}
+	return s.redact(str)
+}
+
+// summarize fills the message and tool counts, the tools the model called
+// and its finish reason from the payloads.
+func summarize(rec *record) {
+	var req struct {
+		Messages []json.RawMessage `json:"messages"`
+		Tools    []json.RawMessage `json:"tools"`
+	}
+	if json.Unmarshal(rec.Request, &req) == nil {
+		rec.Messages = len(req.Messages)
+		rec.Tools = len(req.Tools)
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				ToolCalls []struct {
+					Function struct {
+						Name string `json:"name"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if len(rec.Response) == 0 || json.Unmarshal(rec.Response, &resp) != nil || len(resp.Choices) == 0 {
+		return
+	}
+	choice := resp.Choices[0]
+	rec.FinishReason = choice.FinishReason
+	for _, tc := range choice.Message.ToolCalls {
+		rec.ToolCalls = append(rec.ToolCalls, strings.TrimSpace(tc.Function.Name))
+	}
+}
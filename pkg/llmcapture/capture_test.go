@@ -0,0 +1,65 @@
+package llmcapture
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testRequest  = `{"model":"m","messages":[{"role":"user","content":"mail bob@example.com"}],"tools":[{"type":"function","function":{"name":"email"}}]}`
+	testResponse = `{"choices":[{"message":{"content":"","tool_calls":[{"id":"1","function":{"name":"email","arguments":"{\"to\":\"bob@example.com\"}"}}]},"finish_reason":"tool_calls"}]}`
+)
+
+func TestCaptureRedactsAndSummarizes(t *testing.T) {
+	s := New(t.TempDir(), 0, func(str string) string {
+		return strings.ReplaceAll(str, "bob@example.com", "[REDACTED:email]")
+	})
+	s.Capture("m", []byte(testRequest), []byte(testResponse), 200, 1500*time.Millisecond, nil)
+
+	list := s.List()
+	if len(list) != 1 {
+		t.Fatalf("captures = %+v", list)
+	}
+	sum := list[0]
+	if sum.Model != "m" || sum.Status != 200 || sum.DurationMS != 1500 || sum.Messages != 1 || sum.Tools != 1 ||
+		len(sum.ToolCalls) != 1 || sum.ToolCalls[0] != "email" || sum.FinishReason != "tool_calls" || sum.Size == 0 {
+		t.Errorf("summary = %+v", sum)
+	}
+
+	data, err := s.Get(sum.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "bob@example.com") || !strings.Contains(string(data), "[REDACTED:email]") {
+		t.Errorf("capture not redacted:\n%s", data)
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil || len(rec.Request) == 0 || len(rec.Response) == 0 {
+		t.Errorf("capture = %s (%v)", data, err)
+	}
+}
+
+func TestCaptureErrorsAndRotation(t *testing.T) {
+	s := New(t.TempDir(), 3, nil)
+	for range 5 {
+		s.Capture("m", []byte(testRequest), []byte("<html>Bad Gateway</html>"), 502, time.Second, errors.New("API request failed"))
+	}
+	list := s.List()
+	if len(list) != 3 {
+		t.Fatalf("kept %d captures, want 3", len(list))
+	}
+	if list[0].Name <= list[1].Name || list[0].Error == "" || list[0].Status != 502 {
+		t.Errorf("list = %+v", list)
+	}
+	data, _ := s.Get(list[0].Name)
+	if !strings.Contains(string(data), `"response": "<html>Bad Gateway`) {
+		t.Errorf("non-JSON body not kept as a string:\n%s", data)
+	}
+
+	if _, err := s.Get("../config.json"); err != ErrNotFound {
+		t.Errorf("Get outside the store: %v", err)
+	}
+}
@@ -44,6 +44,19 @@ type Logger struct {
 
 var globalLoggerPtr atomic.Pointer[Logger]
 
+// filter, when set, rewrites every formatted message (e.g. to redact PII).
+var filter atomic.Pointer[func(string) string]
+
+// SetFilter installs fn to rewrite log messages before they are written.
+// Passing nil removes it.
+func SetFilter(fn func(string) string) {
+	if fn == nil {
+		filter.Store(nil)
+		return
+	}
+	filter.Store(&fn)
+}
+
 func Init(level Level) {
 	l := &Logger{level: level}
 	globalLoggerPtr.Store(l)
@@ -57,10 +70,14 @@ func (l *Logger) logWithLevel(level Level, format string, v ...any) {
 	if !l.shouldLog(level) {
 		return
 	}
+	text := fmt.Sprintf(format, v...)
+	if fn := filter.Load(); fn != nil {
+		text = (*fn)(text)
+	}
 	msg := fmt.Sprintf("%s [%s] %s\n",
 		time.Now().Format("2006/01/02 15:04:05"),
 		level.String(),
-		fmt.Sprintf(format, v...))
+		text)
 
 	if level >= LevelWarn {
 		os.Stderr.WriteString(msg)
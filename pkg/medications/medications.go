@@ -0,0 +1,515 @@
+// Package medications stores medications with their dose schedule and
+// stock, and keeps an adherence log of every scheduled dose. The
+// Scheduler sends dose reminders to a chat; short replies there ("taken",
+// "skip") are logged through HandleReply.
+package medications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/utils"
+)
+
+const (
+	StatusPending = "pending"
+	StatusTaken   = "taken"
+	StatusSkipped = "skipped"
+	StatusMissed  = "missed"
+)
+
+// DefaultLowStockDays is how many days of supply left trigger a warning.
+const DefaultLowStockDays = 7
+
+type Medication struct {
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Dosage             string   `json:"dosage,omitempty"` // e.g. "100 mg", "1 tablet"
+	Times              []string `json:"times"`            // "HH:MM" in the agent's timezone
+	Days               []string `json:"days,omitempty"`   // weekdays ("mon"); empty = every day
+	Stock              int      `json:"stock"`            // units left, -1 = not tracked
+	DoseUnits          int      `json:"doseUnits"`        // units taken per dose
+	Notes              string   `json:"notes,omitempty"`
+	Channel            string   `json:"channel,omitempty"` // where reminders go
+	ChatID             string   `json:"chatId,omitempty"`
+	Active             bool     `json:"active"`
+	LowStockWarnedAtMS int64    `json:"lowStockWarnedAtMs,omitempty"`
+	CreatedAtMS        int64    `json:"createdAtMs"`
+	UpdatedAtMS        int64    `json:"updatedAtMs"`
+}
+
+// Label is the name with the dosage, e.g. "Aspirin 100 mg".
+func (m Medication) Label() string {
+	if m.Dosage == "" {
+		return m.Name
+	}
+	return m.Name + " " + m.Dosage
+}
+
+// Tracked reports whether stock is counted.
+func (m Medication) Tracked() bool {
+	return m.Stock >= 0
+}
+
+// ScheduledOn reports whether doses are due on t's weekday.
+func (m Medication) ScheduledOn(t time.Time) bool {
+	return len(m.Days) == 0 || slices.Contains(m.Days, dayName(t.Weekday()))
+}
+
+// DaysLeft estimates how many days the stock lasts, or -1 when stock
+// isn't tracked or nothing is scheduled.
+func (m Medication) DaysLeft() float64 {
+	days := len(m.Days)
+	if days == 0 {
+		days = 7
+	}
+	perDay := float64(len(m.Times)*m.DoseUnits*days) / 7
+	if !m.Tracked() || perDay == 0 {
+		return -1
+	}
+	return float64(m.Stock) / perDay
+}
+
+// Dose is one scheduled intake and what became of it.
+type Dose struct {
+	ID            string `json:"id"`
+	MedicationID  string `json:"medicationId"`
+	ScheduledAtMS int64  `json:"scheduledAtMs"`
+	Status        string `json:"status"`
+	Channel       string `json:"channel,omitempty"`
+	ChatID        string `json:"chatId,omitempty"`
+	RespondedAtMS int64  `json:"respondedAtMs,omitempty"`
+}
+
+type Service struct {
+	q            *dbq.Queries
+	now          func() time.Time
+	lowStockDays int
+}
+
+func NewService(database *sql.DB) *Service {
+	return &Service{q: dbq.New(database), now: time.Now, lowStockDays: DefaultLowStockDays}
+}
+
+// SetLowStockDays sets the days of supply left that trigger a low-stock
+// warning; n <= 0 keeps the default.
+func (s *Service) SetLowStockDays(n int) {
+	if n > 0 {
+		s.lowStockDays = n
+	}
+}
+
+func (s *Service) List() []Medication {
+	rows, err := s.q.ListMedications(context.Background())
+	if err != nil {
+		return nil
+	}
+	meds := make([]Medication, len(rows))
+	for i, r := range rows {
+		meds[i] = dbMedicationToMedication(r)
+	}
+	return meds
+}
+
+func (s *Service) Get(id string) *Medication {
+	row, err := s.q.GetMedication(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	m := dbMedicationToMedication(row)
+	return &m
+}
+
+// Find returns the medication with id or, failing that, with name
+// (ignoring case).
+func (s *Service) Find(ref string) *Medication {
+	if m := s.Get(ref); m != nil {
+		return m
+	}
+	for _, m := range s.List() {
+		if strings.EqualFold(m.Name, strings.TrimSpace(ref)) {
+			return &m
+		}
+	}
+	return nil
+}
+
+func (s *Service) Add(m Medication) (*Medication, error) {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if m.DoseUnits <= 0 {
+		m.DoseUnits = 1
+	}
+	var err error
+	if m.Times, err = normalizeTimes(m.Times); err != nil {
+		return nil, err
+	}
+	if m.Days, err = normalizeDays(m.Days); err != nil {
+		return nil, err
+	}
+	now := s.now().UnixMilli()
+	if m.ID == "" {
+		m.ID = utils.RandHex(8)
+	}
+	m.Active = true
+	m.CreatedAtMS, m.UpdatedAtMS = now, now
+
+	err = s.q.InsertMedication(context.Background(), dbq.InsertMedicationParams{
+		ID:                 m.ID,
+		Name:               m.Name,
+		Dosage:             m.Dosage,
+		Times:              marshalList(m.Times),
+		Days:               marshalList(m.Days),
+		Stock:              int64(m.Stock),
+		DoseUnits:          int64(m.DoseUnits),
+		Notes:              m.Notes,
+		Channel:            m.Channel,
+		ChatID:             m.ChatID,
+		Active:             1,
+		LowStockWarnedAtMs: 0,
+		CreatedAtMs:        m.CreatedAtMS,
+		UpdatedAtMs:        m.UpdatedAtMS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Update applies patch (name, dosage, times, days, stock, dose_units,
+// notes, active).
+func (s *Service) Update(id string, patch map[string]any) (*Medication, error) {
+	m := s.Get(id)
+	if m == nil {
+		return nil, fmt.Errorf("medication not found: %s", id)
+	}
+	if v, ok := patch["name"].(string); ok && strings.TrimSpace(v) != "" {
+		m.Name = strings.TrimSpace(v)
+	}
+	if v, ok := patch["dosage"].(string); ok {
+		m.Dosage = v
+	}
+	if v, ok := patch["times"]; ok {
+		times, err := normalizeTimes(toStringList(v))
+		if err != nil {
+			return nil, err
+		}
+		m.Times = times
+	}
+	if v, ok := patch["days"]; ok {
+		days, err := normalizeDays(toStringList(v))
+		if err != nil {
+			return nil, err
+		}
+		m.Days = days
+	}
+	if v, ok := patch["stock"].(float64); ok {
+		s.setStock(m, int(v))
+	}
+	if v, ok := patch["dose_units"].(float64); ok && v > 0 {
+		m.DoseUnits = int(v)
+	}
+	if v, ok := patch["notes"].(string); ok {
+		m.Notes = v
+	}
+	if v, ok := patch["active"].(bool); ok {
+		m.Active = v
+	}
+	return m, s.save(m)
+}
+
+// Refill adds units to the stock, or sets it when stock isn't tracked yet.
+func (s *Service) Refill(id string, units int) (*Medication, error) {
+	m := s.Get(id)
+	if m == nil {
+		return nil, fmt.Errorf("medication not found: %s", id)
+	}
+	if units <= 0 {
+		return nil, fmt.Errorf("units must be positive")
+	}
+	s.setStock(m, max(m.Stock, 0)+units)
+	return m, s.save(m)
+}
+
+func (s *Service) Remove(id string) bool {
+	res, err := s.q.DeleteMedication(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// Record logs a dose of medication id as taken or skipped now. A pending
+// reminder for it is resolved; otherwise a dose is added to the log.
+func (s *Service) Record(id, status string) (*Medication, error) {
+	if status != StatusTaken && status != StatusSkipped {
+		return nil, fmt.Errorf("invalid status %q (use taken or skipped)", status)
+	}
+	m := s.Get(id)
+	if m == nil {
+		return nil, fmt.Errorf("medication not found: %s", id)
+	}
+	for _, d := range s.pending() {
+		if d.MedicationID == m.ID {
+			return s.resolve(d, m, status)
+		}
+	}
+	now := s.now().UnixMilli()
+	_, err := s.q.InsertMedicationDose(context.Background(), dbq.InsertMedicationDoseParams{
+		ID:            utils.RandHex(8),
+		MedicationID:  m.ID,
+		ScheduledAtMs: now,
+		Status:        status,
+		RespondedAtMs: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status == StatusTaken {
+		return m, s.consume(m)
+	}
+	return m, nil
+}
+
+// Doses returns the log since t, oldest first.
+func (s *Service) Doses(since time.Time) []Dose {
+	rows, err := s.q.ListMedicationDosesSince(context.Background(), since.UnixMilli())
+	if err != nil {
+		return nil
+	}
+	doses := make([]Dose, len(rows))
+	for i, r := range rows {
+		doses[i] = dbDoseToDose(r)
+	}
+	return doses
+}
+
+func (s *Service) pending() []Dose {
+	rows, err := s.q.ListPendingMedicationDoses(context.Background())
+	if err != nil {
+		return nil
+	}
+	doses := make([]Dose, len(rows))
+	for i, r := range rows {
+		doses[i] = dbDoseToDose(r)
+	}
+	return doses
+}
+
+func (s *Service) resolve(d Dose, m *Medication, status string) (*Medication, error) {
+	err := s.q.UpdateMedicationDoseStatus(context.Background(), dbq.UpdateMedicationDoseStatusParams{
+		Status:        status,
+		RespondedAtMs: s.now().UnixMilli(),
+		ID:            d.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status == StatusTaken {
+		return m, s.consume(m)
+	}
+	return m, nil
+}
+
+// consume takes a dose off the stock.
+func (s *Service) consume(m *Medication) error {
+	if !m.Tracked() {
+		return nil
+	}
+	m.Stock = max(m.Stock-m.DoseUnits, 0)
+	return s.save(m)
+}
+
+// setStock changes the stock; going up counts as a refill and re-arms the
+// low-stock warning.
+func (s *Service) setStock(m *Medication, stock int) {
+	if stock > m.Stock {
+		m.LowStockWarnedAtMS = 0
+	}
+	m.Stock = stock
+}
+
+// LowStock reports whether m has fewer days of supply left than the
+// warning threshold.
+func (s *Service) LowStock(m Medication) bool {
+	left := m.DaysLeft()
+	return left >= 0 && left <= float64(s.lowStockDays)
+}
+
+func (s *Service) save(m *Medication) error {
+	m.UpdatedAtMS = s.now().UnixMilli()
+	active := int64(0)
+	if m.Active {
+		active = 1
+	}
+	return s.q.UpdateMedication(context.Background(), dbq.UpdateMedicationParams{
+		Name:               m.Name,
+		Dosage:             m.Dosage,
+		Times:              marshalList(m.Times),
+		Days:               marshalList(m.Days),
+		Stock:              int64(m.Stock),
+		DoseUnits:          int64(m.DoseUnits),
+		Notes:              m.Notes,
+		Channel:            m.Channel,
+		ChatID:             m.ChatID,
+		Active:             active,
+		LowStockWarnedAtMs: m.LowStockWarnedAtMS,
+		UpdatedAtMs:        m.UpdatedAtMS,
+		ID:                 m.ID,
+	})
+}
+
+// FormatMedication renders one medication for chat.
+func FormatMedication(m Medication) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", m.ID, m.Label())
+	schedule := strings.Join(m.Times, ", ")
+	if schedule == "" {
+		schedule = "as needed"
+	}
+	if len(m.Days) > 0 {
+		schedule += " on " + strings.Join(m.Days, ", ")
+	}
+	fmt.Fprintf(&b, " - %s", schedule)
+	if m.DoseUnits > 1 {
+		fmt.Fprintf(&b, ", %d units per dose", m.DoseUnits)
+	}
+	if m.Tracked() {
+		fmt.Fprintf(&b, ", stock %s", formatStock(m))
+	}
+	if !m.Active {
+		b.WriteString(" (paused)")
+	}
+	if m.Notes != "" {
+		fmt.Fprintf(&b, "\n  %s", m.Notes)
+	}
+	return b.String()
+}
+
+func formatStock(m Medication) string {
+	if left := m.DaysLeft(); left >= 0 {
+		return fmt.Sprintf("%d (%d days)", m.Stock, int(math.Floor(left)))
+	}
+	return fmt.Sprint(m.Stock)
+}
+
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func dayName(d time.Weekday) string {
+	return dayNames[d]
+}
+
+// normalizeDays accepts weekday names or abbreviations and returns them
+// as three-letter names in week order.
+func normalizeDays(days []string) ([]string, error) {
+	var out []string
+	for _, d := range days {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" {
+			continue
+		}
+		found := false
+		for _, name := range dayNames {
+			if strings.HasPrefix(d, name) {
+				if !slices.Contains(out, name) {
+					out = append(out, name)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid day %q", d)
+		}
+	}
+	slices.SortFunc(out, func(a, b string) int {
+		return slices.Index(dayNames, a) - slices.Index(dayNames, b)
+	})
+	if len(out) == 7 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func normalizeTimes(times []string) ([]string, error) {
+	var out []string
+	for _, t := range times {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(t))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q (want HH:MM)", t)
+		}
+		if hm := parsed.Format("15:04"); !slices.Contains(out, hm) {
+			out = append(out, hm)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func toStringList(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		return strings.Split(v, ",")
+	}
+	return nil
+}
+
+func marshalList(list []string) string {
+	if list == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func dbMedicationToMedication(r dbq.Medication) Medication {
+	m := Medication{
+		ID:                 r.ID,
+		Name:               r.Name,
+		Dosage:             r.Dosage,
+		Stock:              int(r.Stock),
+		DoseUnits:          int(r.DoseUnits),
+		Notes:              r.Notes,
+		Channel:            r.Channel,
+		ChatID:             r.ChatID,
+		Active:             r.Active != 0,
+		LowStockWarnedAtMS: r.LowStockWarnedAtMs,
+		CreatedAtMS:        r.CreatedAtMs,
+		UpdatedAtMS:        r.UpdatedAtMs,
+	}
+	json.Unmarshal([]byte(r.Times), &m.Times)
+	json.Unmarshal([]byte(r.Days), &m.Days)
+	return m
+}
+
+func dbDoseToDose(r dbq.MedicationDose) Dose {
+	return Dose{
+		ID:            r.ID,
+		MedicationID:  r.MedicationID,
+		ScheduledAtMS: r.ScheduledAtMs,
+		Status:        r.Status,
+		Channel:       r.Channel,
+		ChatID:        r.ChatID,
+		RespondedAtMS: r.RespondedAtMs,
+	}
+}
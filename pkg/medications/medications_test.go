@@ -0,0 +1,155 @@
+package medications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/db"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewService(database)
+}
+
+type sent struct{ channel, chatID, content string }
+
+func newTestScheduler(s *Service, now *time.Time) (*Scheduler, *[]sent) {
+	var out []sent
+	sc := NewScheduler(s, func(channel, chatID, content string) {
+		out = append(out, sent{channel, chatID, content})
+	}, func() (string, string) { return "web", "default" })
+	sc.now = func() time.Time { return *now }
+	s.now = sc.now
+	return sc, &out
+}
+
+func TestParseReply(t *testing.T) {
+	for text, want := range map[string][2]string{
+		"taken":              {StatusTaken, ""},
+		"Took it!":           {StatusTaken, ""},
+		"✅":                  {StatusTaken, ""},
+		"skip vitamin d":     {StatusSkipped, "vitamin d"},
+		"skipped my aspirin": {StatusSkipped, "aspirin"},
+	} {
+		status, rest, ok := ParseReply(text)
+		if !ok || status != want[0] || rest != want[1] {
+			t.Errorf("ParseReply(%q) = %q, %q, %v", text, status, rest, ok)
+		}
+	}
+	for _, text := range []string{"", "what's the weather", "I took the dog out for a long walk this morning"} {
+		if _, _, ok := ParseReply(text); ok {
+			t.Errorf("ParseReply(%q) should not be a reply", text)
+		}
+	}
+}
+
+func TestAddNormalizes(t *testing.T) {
+	s := newTestService(t)
+	m, err := s.Add(Medication{Name: " Aspirin ", Dosage: "100 mg", Times: []string{"20:00", "8:00"}, Days: []string{"Friday", "mon"}, Stock: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "Aspirin" || strings.Join(m.Times, ",") != "08:00,20:00" || strings.Join(m.Days, ",") != "mon,fri" || m.DoseUnits != 1 {
+		t.Errorf("added %+v", m)
+	}
+	if _, err := s.Add(Medication{Name: "X", Times: []string{"noon"}}); err == nil {
+		t.Error("invalid time should fail")
+	}
+	if got := s.Find("aspirin"); got == nil || got.ID != m.ID {
+		t.Errorf("Find by name = %+v", got)
+	}
+}
+
+func TestRemindersAndReplies(t *testing.T) {
+	s := newTestService(t)
+	now := time.Date(2026, 10, 14, 7, 0, 0, 0, time.UTC) // Wednesday
+	sc, out := newTestScheduler(s, &now)
+
+	aspirin, _ := s.Add(Medication{Name: "Aspirin", Dosage: "100 mg", Times: []string{"08:00"}, Stock: 30, Channel: "telegram", ChatID: "42"})
+	vitd, _ := s.Add(Medication{Name: "Vitamin D", Times: []string{"08:00"}, Stock: -1, Channel: "telegram", ChatID: "42"})
+	s.Add(Medication{Name: "Weekly", Times: []string{"08:00"}, Days: []string{"sun"}, Stock: -1})
+
+	sc.Check()
+	if len(*out) != 0 {
+		t.Fatalf("nothing is due yet, sent %v", *out)
+	}
+
+	now = now.Add(75 * time.Minute)
+	sc.Check()
+	sc.Check()
+	if len(*out) != 1 || (*out)[0].chatID != "42" || !strings.Contains((*out)[0].content, "Aspirin 100 mg, Vitamin D") {
+		t.Fatalf("reminders = %v", *out)
+	}
+
+	if _, ok := s.HandleReply("web", "default", "taken"); ok {
+		t.Error("reply in a chat without reminders should not be handled")
+	}
+	if _, ok := s.HandleReply("telegram", "42", "what should I eat today?"); ok {
+		t.Error("unrelated message should not be handled")
+	}
+	reply, ok := s.HandleReply("telegram", "42", "took aspirin")
+	if !ok || !strings.Contains(reply, "Aspirin 100 mg: taken, stock 29 (29 days)") {
+		t.Fatalf("reply = %q, %v", reply, ok)
+	}
+	if got := s.Get(aspirin.ID); got.Stock != 29 {
+		t.Errorf("stock = %d, want 29", got.Stock)
+	}
+
+	// The unanswered vitamin D dose is missed after a few hours.
+	now = now.Add(3 * time.Hour)
+	sc.Check()
+	doses := s.Doses(now.AddDate(0, 0, -1))
+	status := make(map[string]string)
+	for _, d := range doses {
+		status[d.MedicationID] = d.Status
+	}
+	if status[aspirin.ID] != StatusTaken || status[vitd.ID] != StatusMissed {
+		t.Errorf("statuses = %v", status)
+	}
+	if _, ok := s.HandleReply("telegram", "42", "taken"); ok {
+		t.Error("missed dose should no longer take replies")
+	}
+
+	summary := s.Summary(7, now)
+	for _, want := range []string{"Aspirin 100 mg (08:00): 1/1 taken (100%); stock 29 (29 days)", "Vitamin D (08:00): 0/1 taken (0%), 1 missed", "Weekly (08:00): nothing logged"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestLowStockWarning(t *testing.T) {
+	s := newTestService(t)
+	now := time.Date(2026, 10, 14, 7, 0, 0, 0, time.UTC)
+	sc, out := newTestScheduler(s, &now)
+
+	m, _ := s.Add(Medication{Name: "Iron", Times: []string{"08:00", "20:00"}, Stock: 12})
+	sc.Check()
+	sc.Check()
+	if len(*out) != 1 || (*out)[0].channel != "web" || !strings.Contains((*out)[0].content, "Iron is running low: 12 (6 days)") {
+		t.Fatalf("warnings = %v", *out)
+	}
+
+	if _, err := s.Refill(m.ID, 60); err != nil {
+		t.Fatal(err)
+	}
+	got := s.Get(m.ID)
+	if got.Stock != 72 || got.LowStockWarnedAtMS != 0 {
+		t.Errorf("after refill: %+v", got)
+	}
+
+	// Logging a dose without a reminder still counts against stock.
+	if _, err := s.Record(m.ID, StatusTaken); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get(m.ID); got.Stock != 71 {
+		t.Errorf("stock = %d, want 71", got.Stock)
+	}
+}
@@ -0,0 +1,320 @@
+package medications
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/logger"
+	"localagent/pkg/utils"
+	"localagent/pkg/when"
+)
+
+const (
+	reminderCheckInterval = time.Minute
+
+	// catchUp is how late a dose reminder may still go out, e.g. after a
+	// restart. Older doses are not reminded.
+	catchUp = time.Hour
+
+	// missAfter is how long a reminded dose waits for a reply before it
+	// is logged as missed.
+	missAfter = 3 * time.Hour
+)
+
+// SendFunc delivers a message to a chat.
+type SendFunc func(channel, chatID, content string)
+
+// TargetFunc returns the chat for medications added without one, usually
+// the last active chat.
+type TargetFunc func() (channel, chatID string)
+
+// Scheduler sends dose reminders and low-stock warnings, and logs doses
+// that got no reply as missed.
+type Scheduler struct {
+	svc    *Service
+	send   SendFunc
+	target TargetFunc
+	now    func() time.Time
+
+	mu   sync.Mutex
+	stop chan struct{}
+}
+
+// NewScheduler creates a scheduler; target may be nil.
+func NewScheduler(svc *Service, send SendFunc, target TargetFunc) *Scheduler {
+	return &Scheduler{svc: svc, send: send, target: target, now: when.Now, stop: make(chan struct{})}
+}
+
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(reminderCheckInterval)
+	go func() {
+		s.Check()
+		for {
+			select {
+			case <-ticker.C:
+				s.Check()
+			case <-s.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("medication reminders started")
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// Check reminds doses that came due, marks unanswered ones missed and
+// warns about medications running low.
+func (s *Scheduler) Check() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ctx := context.Background()
+
+	due := make(map[chat][]Medication)
+	var order []chat
+	for _, m := range s.svc.List() {
+		if !m.Active || !m.ScheduledOn(now) {
+			continue
+		}
+		c := s.chatFor(m)
+		if c.channel == "" {
+			continue
+		}
+		for _, hm := range m.Times {
+			at, err := time.ParseInLocation("15:04", hm, now.Location())
+			if err != nil {
+				continue
+			}
+			at = time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+			if at.After(now) || now.Sub(at) > catchUp {
+				continue
+			}
+			res, err := s.svc.q.InsertMedicationDose(ctx, dbq.InsertMedicationDoseParams{
+				ID:            utils.RandHex(8),
+				MedicationID:  m.ID,
+				ScheduledAtMs: at.UnixMilli(),
+				Status:        StatusPending,
+				Channel:       c.channel,
+				ChatID:        c.chatID,
+			})
+			if err != nil {
+				logger.Warn("medications: record dose: %v", err)
+				continue
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue // already reminded
+			}
+			if _, ok := due[c]; !ok {
+				order = append(order, c)
+			}
+			due[c] = append(due[c], m)
+		}
+	}
+	for _, c := range order {
+		s.send(c.channel, c.chatID, formatReminder(due[c]))
+		logger.Info("medications: reminded %d doses to %s:%s", len(due[c]), c.channel, c.chatID)
+	}
+
+	for _, d := range s.svc.pending() {
+		if now.Sub(time.UnixMilli(d.ScheduledAtMS)) < missAfter {
+			continue
+		}
+		err := s.svc.q.UpdateMedicationDoseStatus(ctx, dbq.UpdateMedicationDoseStatusParams{Status: StatusMissed, ID: d.ID})
+		if err != nil {
+			logger.Warn("medications: mark missed: %v", err)
+		}
+	}
+
+	for _, m := range s.svc.List() {
+		if !m.Active || m.LowStockWarnedAtMS != 0 || !s.svc.LowStock(m) {
+			continue
+		}
+		c := s.chatFor(m)
+		if c.channel == "" {
+			continue
+		}
+		s.send(c.channel, c.chatID, fmt.Sprintf("%s is running low: %s left. Time to refill.", m.Label(), formatStock(m)))
+		m.LowStockWarnedAtMS = now.UnixMilli()
+		if err := s.svc.save(&m); err != nil {
+			logger.Warn("medications: save low-stock warning: %v", err)
+		}
+	}
+}
+
+type chat struct{ channel, chatID string }
+
+// chatFor is where m's reminders go: the chat it was added from, or the
+// target chat.
+func (s *Scheduler) chatFor(m Medication) chat {
+	if m.Channel != "" && m.ChatID != "" {
+		return chat{m.Channel, m.ChatID}
+	}
+	if s.target != nil {
+		channel, chatID := s.target()
+		return chat{channel, chatID}
+	}
+	return chat{}
+}
+
+func formatReminder(meds []Medication) string {
+	labels := make([]string, len(meds))
+	for i, m := range meds {
+		labels[i] = m.Label()
+		if m.DoseUnits > 1 {
+			labels[i] += fmt.Sprintf(" (%d units)", m.DoseUnits)
+		}
+	}
+	return fmt.Sprintf("Time for your medication: %s.\nReply \"taken\" or \"skip\".", strings.Join(labels, ", "))
+}
+
+var (
+	takenWords = []string{"taken", "took", "done", "✅"}
+	skipWords  = []string{"skip", "skipped", "skipping", "❌"}
+	fillers    = []string{"it", "them", "all", "both", "my", "i", "have", "just", "the"}
+)
+
+// ParseReply reads a reply to a dose reminder: "taken", "took it",
+// "skip", "skip vitamin d"... It returns the status and any remaining
+// words, which may name a medication. Long messages are not replies.
+func ParseReply(text string) (status, rest string, ok bool) {
+	words := strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(text), ".!")))
+	if len(words) == 0 || len(words) > 6 {
+		return "", "", false
+	}
+	var kept []string
+	for _, w := range words {
+		w = strings.Trim(w, ".,!")
+		switch {
+		case status == "" && slices.Contains(takenWords, w):
+			status = StatusTaken
+		case status == "" && slices.Contains(skipWords, w):
+			status = StatusSkipped
+		case !slices.Contains(fillers, w):
+			kept = append(kept, w)
+		}
+	}
+	if status == "" {
+		return "", "", false
+	}
+	return status, strings.Join(kept, " "), true
+}
+
+// HandleReply logs a reply in a chat with pending dose reminders. It
+// returns the confirmation to send back, or false when text isn't a reply
+// to a reminder and should go to the agent.
+func (s *Service) HandleReply(channel, chatID, text string) (string, bool) {
+	var pending []Dose
+	for _, d := range s.pending() {
+		if d.Channel == channel && d.ChatID == chatID {
+			pending = append(pending, d)
+		}
+	}
+	if len(pending) == 0 {
+		return "", false
+	}
+	status, rest, ok := ParseReply(text)
+	if !ok {
+		return "", false
+	}
+
+	var lines []string
+	seen := make(map[string]bool)
+	for _, d := range pending {
+		if seen[d.MedicationID] {
+			continue // a later reminder of the same medication is still pending
+		}
+		m := s.Get(d.MedicationID)
+		if m == nil {
+			continue
+		}
+		if rest != "" && !strings.Contains(strings.ToLower(m.Name), rest) && !strings.Contains(rest, strings.ToLower(m.Name)) {
+			continue
+		}
+		seen[d.MedicationID] = true
+		m, err := s.resolve(d, m, status)
+		if err != nil {
+			logger.Warn("medications: log reply: %v", err)
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", m.Label(), status)
+		if status == StatusTaken && m.Tracked() {
+			line += ", stock " + formatStock(*m)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+	return "Logged " + strings.Join(lines, "; ") + ".", true
+}
+
+// Summary reports adherence per medication over the last days, pending
+// reminders and low stock.
+func (s *Service) Summary(days int, now time.Time) string {
+	if days <= 0 {
+		days = 7
+	}
+	meds := s.List()
+	if len(meds) == 0 {
+		return "No medications."
+	}
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+	counts := make(map[string]map[string]int)
+	for _, d := range s.Doses(since) {
+		if counts[d.MedicationID] == nil {
+			counts[d.MedicationID] = make(map[string]int)
+		}
+		counts[d.MedicationID][d.Status]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Medication adherence, last %d days:", days)
+	var pending, low []string
+	for _, m := range meds {
+		c := counts[m.ID]
+		logged := c[StatusTaken] + c[StatusSkipped] + c[StatusMissed]
+		fmt.Fprintf(&b, "\n- %s (%s): ", m.Label(), strings.Join(m.Times, ", "))
+		if logged == 0 {
+			b.WriteString("nothing logged")
+		} else {
+			pct := int(math.Round(100 * float64(c[StatusTaken]) / float64(logged)))
+			fmt.Fprintf(&b, "%d/%d taken (%d%%)", c[StatusTaken], logged, pct)
+			if c[StatusSkipped] > 0 {
+				fmt.Fprintf(&b, ", %d skipped", c[StatusSkipped])
+			}
+			if c[StatusMissed] > 0 {
+				fmt.Fprintf(&b, ", %d missed", c[StatusMissed])
+			}
+		}
+		if m.Tracked() {
+			fmt.Fprintf(&b, "; stock %s", formatStock(m))
+		}
+		if !m.Active {
+			b.WriteString(" (paused)")
+		}
+		if c[StatusPending] > 0 {
+			pending = append(pending, m.Label())
+		}
+		if m.Active && s.LowStock(m) {
+			low = append(low, m.Label())
+		}
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(&b, "\nAwaiting reply: %s", strings.Join(pending, ", "))
+	}
+	if len(low) > 0 {
+		fmt.Fprintf(&b, "\nLow stock: %s", strings.Join(low, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,226 @@
+// Package migrate upgrades on-disk data formats in the workspace. Each store
+// (sessions, cron, todo, state) has a schema version recorded in
+// state/schema.json and an ordered list of migrations; pending ones run in
+// order after the store's files are copied to backups/migrations.
+//
+// The todo database schema is versioned separately by db.Migrate; the todo
+// store here covers files outside SQLite such as the legacy tasks.json.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/logger"
+	"localagent/pkg/storage"
+)
+
+// Migration upgrades a store to Version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+// Store is a group of files sharing a schema version.
+type Store struct {
+	Name       string
+	Paths      []string // files or directories backed up before migrating
+	Migrations []Migration
+}
+
+// Step is a migration that ran, or would run in a dry run.
+type Step struct {
+	Store   string `json:"store"`
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// Result describes what Run did.
+type Result struct {
+	Steps   []Step
+	Backups []string // backup directories created
+}
+
+// Runner applies pending migrations for a workspace.
+type Runner struct {
+	workspace string
+	stores    []Store
+	now       func() time.Time
+
+	// DryRun reports the pending migrations without running them or
+	// touching any file.
+	DryRun bool
+}
+
+func NewRunner(workspace string, stores ...Store) *Runner {
+	return &Runner{workspace: workspace, stores: stores, now: time.Now}
+}
+
+func (r *Runner) versionsPath() string {
+	return filepath.Join(r.workspace, "state", "schema.json")
+}
+
+// BackupDir is where pre-migration copies are kept.
+func BackupDir(workspace string) string {
+	return filepath.Join(workspace, "backups", "migrations")
+}
+
+// Versions returns the recorded schema version of each store.
+func (r *Runner) Versions() (map[string]int, error) {
+	versions := make(map[string]int)
+	data, err := os.ReadFile(r.versionsPath())
+	if os.IsNotExist(err) {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", r.versionsPath(), err)
+	}
+	return versions, nil
+}
+
+func (r *Runner) saveVersions(versions map[string]int) error {
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(r.versionsPath(), data, 0644)
+}
+
+// Run applies pending migrations store by store. A failing migration stops
+// the run; earlier migrations stay applied and the store's backup is kept.
+// Only one process migrates a workspace at a time.
+func (r *Runner) Run() (Result, error) {
+	var res Result
+	if !r.DryRun {
+		unlock, err := filelock.Lock(r.versionsPath())
+		if err != nil {
+			return res, fmt.Errorf("lock schema versions: %w", err)
+		}
+		defer unlock()
+	}
+
+	versions, err := r.Versions()
+	if err != nil {
+		return res, err
+	}
+
+	for _, st := range r.stores {
+		pending := pendingMigrations(st.Migrations, versions[st.Name])
+		if len(pending) == 0 {
+			continue
+		}
+		if r.DryRun {
+			for _, m := range pending {
+				res.Steps = append(res.Steps, Step{Store: st.Name, Version: m.Version, Name: m.Name})
+			}
+			continue
+		}
+
+		backup, err := r.backup(st, versions[st.Name])
+		if err != nil {
+			return res, fmt.Errorf("back up %s: %w", st.Name, err)
+		}
+		if backup != "" {
+			res.Backups = append(res.Backups, backup)
+		}
+
+		for _, m := range pending {
+			if err := m.Up(); err != nil {
+				return res, fmt.Errorf("migrate %s to v%d (%s): %w", st.Name, m.Version, m.Name, err)
+			}
+			versions[st.Name] = m.Version
+			if err := r.saveVersions(versions); err != nil {
+				return res, fmt.Errorf("record %s v%d: %w", st.Name, m.Version, err)
+			}
+			res.Steps = append(res.Steps, Step{Store: st.Name, Version: m.Version, Name: m.Name})
+			logger.Info("migrate: %s now at v%d (%s)", st.Name, m.Version, m.Name)
+		}
+	}
+	return res, nil
+}
+
+func pendingMigrations(all []Migration, current int) []Migration {
+	var pending []Migration
+	for _, m := range all {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Version < pending[j].Version })
+	return pending
+}
+
+// backup copies the store's existing paths into a new directory under
+// BackupDir. Returns "" when none of the paths exist.
+func (r *Runner) backup(st Store, from int) (string, error) {
+	var existing []string
+	for _, p := range st.Paths {
+		if _, err := os.Stat(p); err == nil {
+			existing = append(existing, p)
+		}
+	}
+	if len(existing) == 0 {
+		return "", nil
+	}
+
+	dir := filepath.Join(BackupDir(r.workspace), fmt.Sprintf("%s-v%d-%s", st.Name, from, r.now().Format("20060102-150405")))
+	for _, p := range existing {
+		if err := copyTree(p, filepath.Join(dir, filepath.Base(p))); err != nil {
+			return "", err
+		}
+	}
+	logger.Info("migrate: backed up %s to %s", st.Name, dir)
+	return dir, nil
+}
+
+// copyTree copies a file or directory, skipping lock files.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() || filepath.Ext(path) == ".lock" {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
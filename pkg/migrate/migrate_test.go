@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunAppliesPendingInOrder(t *testing.T) {
+	ws := t.TempDir()
+	data := filepath.Join(ws, "jobs.json")
+	os.WriteFile(data, []byte(`{"v":0}`), 0644)
+
+	var ran []int
+	step := func(v int) Migration {
+		return Migration{Version: v, Name: "step", Up: func() error {
+			ran = append(ran, v)
+			return os.WriteFile(data, []byte(`{"v":1}`), 0644)
+		}}
+	}
+	store := Store{Name: "cron", Paths: []string{data}, Migrations: []Migration{step(2), step(1)}}
+
+	dry := NewRunner(ws, store)
+	dry.DryRun = true
+	res, err := dry.Run()
+	if err != nil || len(res.Steps) != 2 || len(ran) != 0 {
+		t.Fatalf("dry run: steps=%v ran=%v err=%v", res.Steps, ran, err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "state", "schema.json")); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote schema versions")
+	}
+
+	res, err = NewRunner(ws, store).Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 2 || ran[0] != 1 || ran[1] != 2 {
+		t.Fatalf("expected migrations 1 then 2, got %v", ran)
+	}
+	if len(res.Backups) != 1 {
+		t.Fatalf("expected one backup, got %v", res.Backups)
+	}
+	if b, _ := os.ReadFile(filepath.Join(res.Backups[0], "jobs.json")); string(b) != `{"v":0}` {
+		t.Errorf("backup has %q, want pre-migration contents", b)
+	}
+
+	versions, _ := NewRunner(ws).Versions()
+	if versions["cron"] != 2 {
+		t.Errorf("cron version = %d, want 2", versions["cron"])
+	}
+
+	// Nothing left to do.
+	ran = nil
+	if res, err := NewRunner(ws, store).Run(); err != nil || len(res.Steps) != 0 || len(ran) != 0 {
+		t.Errorf("second run: steps=%v ran=%v err=%v", res.Steps, ran, err)
+	}
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	ws := t.TempDir()
+	store := Store{Name: "sessions", Migrations: []Migration{
+		{Version: 1, Name: "ok", Up: func() error { return nil }},
+		{Version: 2, Name: "broken", Up: func() error { return errors.New("boom") }},
+		{Version: 3, Name: "never", Up: func() error { t.Error("ran past a failure"); return nil }},
+	}}
+
+	if _, err := NewRunner(ws, store).Run(); err == nil {
+		t.Fatal("expected error")
+	}
+	versions, _ := NewRunner(ws).Versions()
+	if versions["sessions"] != 1 {
+		t.Errorf("sessions version = %d, want 1 (last successful)", versions["sessions"])
+	}
+}
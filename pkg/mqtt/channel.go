@@ -0,0 +1,175 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+// Channel turns messages on <command topic>/<id> into chat messages from
+// <id> and publishes replies to <response topic>/<id>, for Node-RED flows
+// and ESPHome devices. Payloads are plain text or {"text": "..."}; replies
+// are plain text.
+type Channel struct {
+	*channels.BaseChannel
+	host     string
+	useTLS   bool
+	opts     Options
+	command  string
+	response string
+
+	mu      sync.Mutex
+	conn    *Conn // nil while disconnected
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func NewChannel(cfg config.MQTTConfig, msgBus *bus.MessageBus) (*Channel, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "mqtt" && u.Scheme != "mqtts") || u.Hostname() == "" {
+		return nil, fmt.Errorf("url %q: want mqtt:// or mqtts://host[:port]", cfg.URL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	c := &Channel{
+		BaseChannel: channels.NewBaseChannel("mqtt", cfg, msgBus, cfg.AllowFrom),
+		host:        host,
+		useTLS:      u.Scheme == "mqtts",
+		opts:        Options{ClientID: cfg.ClientID, Username: cfg.Username, Password: cfg.ResolvePassword()},
+		command:     strings.TrimSuffix(cfg.CommandTopic, "/"),
+		response:    strings.TrimSuffix(cfg.ResponseTopic, "/"),
+	}
+	if c.opts.ClientID == "" {
+		c.opts.ClientID = "localagent-commands"
+	}
+	if c.command == "" {
+		c.command = "localagent/command"
+	}
+	if c.response == "" {
+		c.response = "localagent/response"
+	}
+	if strings.ContainsAny(c.command+c.response, "+#") {
+		return nil, fmt.Errorf("command and response topics must not contain wildcards")
+	}
+	return c, nil
+}
+
+func (c *Channel) Start(ctx context.Context) error {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.stopped = make(chan struct{})
+	go c.run(ctx)
+	c.SetRunning(true)
+	return nil
+}
+
+func (c *Channel) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.stopped
+	}
+	c.SetRunning(false)
+	return nil
+}
+
+func (c *Channel) Send(_ context.Context, msg bus.OutboundMessage) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("mqtt: not connected to %s", c.host)
+	}
+	return conn.Publish(c.response+"/"+msg.ChatID, []byte(msg.Content))
+}
+
+// run keeps a subscribed connection open until ctx ends.
+func (c *Channel) run(ctx context.Context) {
+	defer close(c.stopped)
+	backoff := time.Second
+	for ctx.Err() == nil {
+		conn, err := Dial(ctx, c.host, c.useTLS, c.opts)
+		if err == nil {
+			// Replies can be sent as soon as the first command arrives.
+			c.setConn(conn)
+			if err = conn.Subscribe(c.command+"/#", c.receive); err != nil {
+				c.setConn(nil)
+				conn.Close()
+			}
+		}
+		if err != nil {
+			logger.Warn("mqtt channel: connecting to %s: %v (retrying in %v)", c.host, err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, time.Minute)
+			continue
+		}
+		logger.Info("mqtt channel: subscribed to %s/# on %s", c.command, c.host)
+		backoff = time.Second
+		err = keepAlive(ctx, conn)
+		c.setConn(nil)
+		conn.Close()
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("mqtt channel: connection to %s lost: %v", c.host, err)
+		}
+	}
+}
+
+func (c *Channel) setConn(conn *Conn) {
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+}
+
+// keepAlive pings conn until it drops or ctx ends.
+func keepAlive(ctx context.Context, conn *Conn) error {
+	ping := time.NewTicker(KeepAlive / 2)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.Done():
+			return conn.Err()
+		case <-ping.C:
+			if err := conn.Ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Channel) receive(m Message) {
+	id, ok := strings.CutPrefix(m.Topic, c.command+"/")
+	if !ok || id == "" {
+		return
+	}
+	text := strings.TrimSpace(string(m.Payload))
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if strings.HasPrefix(text, "{") && json.Unmarshal(m.Payload, &payload) == nil {
+		text = strings.TrimSpace(payload.Text)
+	}
+	if text == "" {
+		return
+	}
+	c.HandleMessage(id, id, text, nil, nil)
+}
@@ -0,0 +1,102 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+)
+
+// fakeBroker acknowledges CONNECT and SUBSCRIBE, then publishes each of
+// deliver to the client as "topic payload" pairs, and reports what the
+// client publishes.
+func fakeBroker(t *testing.T, deliver [][2]string) (string, <-chan Message) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	published := make(chan Message, 10)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			header, body, err := ReadPacket(r)
+			if err != nil {
+				return
+			}
+			switch header & 0xF0 {
+			case 0x10:
+				c.Write([]byte{0x20, 2, 0, 0})
+			case 0x80:
+				c.Write(packet(0x90, append(body[:2:2], 0)))
+				for _, d := range deliver {
+					c.Write(packet(0x30, append(appendString(nil, d[0]), d[1]...)))
+				}
+			case 0x30:
+				n := int(binary.BigEndian.Uint16(body))
+				published <- Message{Topic: string(body[2 : 2+n]), Payload: body[2+n:]}
+			}
+		}
+	}()
+	return ln.Addr().String(), published
+}
+
+func TestChannelCommandsAndReplies(t *testing.T) {
+	addr, published := fakeBroker(t, [][2]string{
+		{"home/cmd/kitchen", "Turn on the kettle"},
+		{"home/cmd/hall", "ignored: not allowed"},
+		{"home/cmd/kitchen", `{"text":"  What's the weather? "}`},
+		{"home/other/kitchen", "ignored: other topic"},
+	})
+	msgBus := bus.NewMessageBus()
+	ch, err := NewChannel(config.MQTTConfig{URL: "mqtt://" + addr, CommandTopic: "home/cmd", ResponseTopic: "home/reply/", AllowFrom: []string{"kitchen"}}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch.Start(ctx)
+	defer ch.Stop(ctx)
+
+	for _, want := range []string{"Turn on the kettle", "What's the weather?"} {
+		msg, ok := msgBus.ConsumeInbound(ctx)
+		if !ok {
+			t.Fatal("no inbound message")
+		}
+		if msg.Content != want || msg.SessionKey != "mqtt:kitchen" || msg.ChatID != "kitchen" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	}
+
+	if err := ch.Send(ctx, bus.OutboundMessage{Channel: "mqtt", ChatID: "kitchen", Content: "Kettle on."}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-published:
+		if m.Topic != "home/reply/kitchen" || string(m.Payload) != "Kettle on." {
+			t.Errorf("published %s %q", m.Topic, m.Payload)
+		}
+	case <-ctx.Done():
+		t.Fatal("reply not published")
+	}
+}
+
+func TestNewChannelRejectsWildcards(t *testing.T) {
+	if _, err := NewChannel(config.MQTTConfig{URL: "mqtt://broker", CommandTopic: "home/+"}, bus.NewMessageBus()); err == nil {
+		t.Error("wildcard command topic should be rejected")
+	}
+	if _, err := NewChannel(config.MQTTConfig{URL: "nats://broker"}, bus.NewMessageBus()); err == nil {
+		t.Error("nats url should be rejected")
+	}
+}
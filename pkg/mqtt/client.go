@@ -0,0 +1,260 @@
+// Package mqtt is a minimal MQTT 3.1.1 client (QoS 0 publish and
+// subscribe) and a chat channel on top of it.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// KeepAlive is the keep-alive interval announced to the broker; call Ping
+// at least this often on an otherwise idle connection.
+const KeepAlive = 60 * time.Second
+
+// Options configure the connection.
+type Options struct {
+	ClientID string
+	Username string
+	Password string
+}
+
+// Message is a message received on a subscription.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Conn is a connection to a broker.
+type Conn struct {
+	conn    net.Conn
+	mu      sync.Mutex // serializes writes
+	handler func(Message)
+	nextID  uint16
+	done    chan struct{}
+	err     error // set before done is closed
+}
+
+// Dial connects to host (host:port), over TLS when useTLS is set.
+func Dial(ctx context.Context, host string, useTLS bool, opts Options) (*Conn, error) {
+	d := net.Dialer{Timeout: 10 * time.Second}
+	var nc net.Conn
+	var err error
+	if useTLS {
+		td := tls.Dialer{NetDialer: &d}
+		nc, err = td.DialContext(ctx, "tcp", host)
+	} else {
+		nc, err = d.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := Connect(nc, opts)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Connect performs the MQTT handshake on an established connection.
+func Connect(conn net.Conn, opts Options) (*Conn, error) {
+	var flags byte = 0x02 // clean session
+	var payload []byte
+	payload = appendString(payload, opts.ClientID)
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags) // protocol level 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(KeepAlive/time.Second))
+	body = append(body, payload...)
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write(packet(0x10, body)); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	header, ack, err := ReadPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading CONNACK: %w", err)
+	}
+	if header&0xF0 != 0x20 || len(ack) != 2 {
+		return nil, fmt.Errorf("unexpected packet 0x%02x instead of CONNACK", header)
+	}
+	if ack[1] != 0 {
+		return nil, fmt.Errorf("broker refused connection (code %d)", ack[1])
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Conn{conn: conn, done: make(chan struct{})}
+	go c.readLoop(r)
+	return c, nil
+}
+
+// Subscribe subscribes to filter at QoS 0. Messages on any subscription
+// go to handler, which runs on the connection's read goroutine.
+func (c *Conn) Subscribe(filter string, handler func(Message)) error {
+	c.mu.Lock()
+	c.handler = handler
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	body := binary.BigEndian.AppendUint16(nil, id)
+	body = appendString(body, filter)
+	body = append(body, 0) // requested QoS
+	return c.write(packet(0x82, body))
+}
+
+func (c *Conn) Publish(topic string, payload []byte) error {
+	body := appendString(nil, topic)
+	return c.write(packet(0x30, append(body, payload...)))
+}
+
+func (c *Conn) Ping() error {
+	return c.write([]byte{0xC0, 0})
+}
+
+// Done is closed when the connection drops; Err then says why.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+func (c *Conn) Close() error {
+	c.write([]byte{0xE0, 0}) // DISCONNECT
+	return c.conn.Close()
+}
+
+func (c *Conn) readLoop(r *bufio.Reader) {
+	for {
+		header, body, err := ReadPacket(r)
+		if err == nil {
+			err = c.handle(header, body)
+		}
+		if err != nil {
+			c.err = err
+			close(c.done)
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *Conn) handle(header byte, body []byte) error {
+	switch header & 0xF0 {
+	case 0x30: // PUBLISH
+		if len(body) < 2 {
+			return fmt.Errorf("malformed PUBLISH")
+		}
+		n := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+n {
+			return fmt.Errorf("malformed PUBLISH")
+		}
+		msg := Message{Topic: string(body[2 : 2+n])}
+		rest := body[2+n:]
+		if qos := (header >> 1) & 3; qos > 0 {
+			// Brokers downgrade to the subscribed QoS 0, but acknowledge
+			// QoS 1 deliveries in case one doesn't.
+			if len(rest) < 2 {
+				return fmt.Errorf("malformed PUBLISH")
+			}
+			if qos == 1 {
+				c.write(append([]byte{0x40, 2}, rest[:2]...))
+			}
+			rest = rest[2:]
+		}
+		msg.Payload = rest
+		c.mu.Lock()
+		h := c.handler
+		c.mu.Unlock()
+		if h != nil {
+			h(msg)
+		}
+	case 0x90: // SUBACK
+		if len(body) >= 3 && body[2] == 0x80 {
+			return fmt.Errorf("broker rejected the subscription")
+		}
+	}
+	return nil
+}
+
+func (c *Conn) write(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(b)
+	return err
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// packet prefixes body with the fixed header: type/flags and the
+// variable-length remaining length.
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+// ReadPacket reads one packet and returns its fixed header byte (type and
+// flags) and body.
+func ReadPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, fmt.Errorf("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7F) * mult
+		mult *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -0,0 +1,245 @@
+// Package openai serves an OpenAI-compatible chat completions API on the
+// gateway so third-party clients can talk to the agent, tools and memory
+// included.
+//
+// The agent keeps its own history per session, so only the last user
+// message of a request is processed; earlier messages the client resends
+// are ignored. The session is chosen by the X-Session-Id header or the
+// request's "user" field.
+package openai
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+// ModelID is the model name clients see.
+const ModelID = "localagent"
+
+// keepAliveInterval is how often a streaming response sends an SSE comment
+// while the agent works, so clients and proxies don't time out.
+const keepAliveInterval = 15 * time.Second
+
+// maxRequestBytes caps the request body; clients resend whole histories.
+const maxRequestBytes = 4 << 20
+
+// RunFunc processes content in session and returns the agent's answer.
+type RunFunc func(ctx context.Context, session, content string) (string, error)
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	User     string        `json:"user"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message content, which is either a string or a list of
+// parts of which only text parts are kept.
+func (m chatMessage) text() string {
+	var s string
+	if json.Unmarshal(m.Content, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(m.Content, &parts) != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+var sessionRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// sessionKey maps a client-chosen id to an agent session.
+func sessionKey(id string) string {
+	id = sessionRe.ReplaceAllString(id, "-")
+	id = strings.Trim(id, "-")
+	if id == "" {
+		id = "default"
+	}
+	return "openai:" + id
+}
+
+type server struct {
+	token string
+	run   RunFunc
+	seq   atomic.Int64
+	now   func() time.Time
+}
+
+// Handler serves /v1/models and /v1/chat/completions. Requests must carry
+// token as their API key.
+func Handler(token string, run RunFunc) http.Handler {
+	s := &server{token: token, run: run, now: time.Now}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", s.auth(s.handleModels))
+	mux.HandleFunc("/v1/chat/completions", s.auth(s.handleCompletions))
+	return mux
+}
+
+func (s *server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid_api_key", "Invalid API key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *server) handleModels(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": ModelID, "object": "model", "created": 0, "owned_by": "localagent"},
+		},
+	})
+}
+
+func (s *server) handleCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "use POST")
+		return
+	}
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON: "+err.Error())
+		return
+	}
+	var content string
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			content = strings.TrimSpace(req.Messages[i].text())
+			break
+		}
+	}
+	if content == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages must end with a user message that has text")
+		return
+	}
+
+	id := r.Header.Get("X-Session-Id")
+	if id == "" {
+		id = req.User
+	}
+	session := sessionKey(id)
+	completionID := fmt.Sprintf("chatcmpl-%d-%d", s.now().Unix(), s.seq.Add(1))
+	logger.Info("openai: completion %s session=%s stream=%v", completionID, session, req.Stream)
+
+	if req.Stream {
+		s.stream(w, r, completionID, session, content)
+		return
+	}
+	answer, err := s.run(r.Context(), session, content)
+	if err != nil {
+		logger.Error("openai: completion %s failed: %v", completionID, err)
+		writeError(w, http.StatusInternalServerError, "server_error", "the agent failed to process the message")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      completionID,
+		"object":  "chat.completion",
+		"created": s.now().Unix(),
+		"model":   ModelID,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": answer},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{"prompt_tokens": 0, "completion_tokens": 0, "total_tokens": 0},
+	})
+}
+
+// stream answers with server-sent chunks. The agent produces its answer in
+// one piece, so the content arrives as a single delta after keep-alives.
+func (s *server) stream(w http.ResponseWriter, r *http.Request, id, session, content string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "server_error", "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	created := s.now().Unix()
+	chunk := func(delta map[string]string, finish any) {
+		data, _ := json.Marshal(map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   ModelID,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+	chunk(map[string]string{"role": "assistant"}, nil)
+
+	type result struct {
+		answer string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		answer, err := s.run(r.Context(), session, content)
+		done <- result{answer, err}
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case res := <-done:
+			if res.err != nil {
+				logger.Error("openai: completion %s failed: %v", id, res.err)
+				data, _ := json.Marshal(map[string]any{"error": map[string]string{
+					"message": "the agent failed to process the message", "type": "server_error",
+				}})
+				fmt.Fprintf(w, "data: %s\n\n", data)
+			} else {
+				chunk(map[string]string{"content": res.answer}, nil)
+				chunk(map[string]string{}, "stop")
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, typ, msg string) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"message": msg, "type": typ}})
+}
@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, srv *httptest.Server, token, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestCompletionUsesLastUserMessage(t *testing.T) {
+	var gotSession, gotContent string
+	srv := httptest.NewServer(Handler("tok", func(_ context.Context, session, content string) (string, error) {
+		gotSession, gotContent = session, content
+		return "hi there", nil
+	}))
+	defer srv.Close()
+
+	resp := post(t, srv, "tok", `{"model":"x","messages":[
+		{"role":"user","content":"old"},
+		{"role":"assistant","content":"old answer"},
+		{"role":"user","content":[{"type":"text","text":"what's up?"},{"type":"image_url","image_url":{"url":"data:"}}]}
+	]}`, map[string]string{"X-Session-Id": "phone/1"})
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var out struct {
+		Choices []struct {
+			Message struct{ Role, Content string }
+		}
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "hi there" {
+		t.Errorf("response = %+v", out)
+	}
+	if gotContent != "what's up?" || gotSession != "openai:phone-1" {
+		t.Errorf("content=%q session=%q", gotContent, gotSession)
+	}
+}
+
+func TestCompletionRequiresToken(t *testing.T) {
+	srv := httptest.NewServer(Handler("tok", func(context.Context, string, string) (string, error) { return "", nil }))
+	defer srv.Close()
+	resp := post(t, srv, "nope", `{"messages":[{"role":"user","content":"hi"}]}`, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestCompletionStream(t *testing.T) {
+	srv := httptest.NewServer(Handler("tok", func(context.Context, string, string) (string, error) {
+		return "streamed", nil
+	}))
+	defer srv.Close()
+
+	resp := post(t, srv, "tok", `{"stream":true,"user":"ed","messages":[{"role":"user","content":"hi"}]}`, nil)
+	defer resp.Body.Close()
+	var content strings.Builder
+	var done bool
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct{ Content string }
+			}
+		}
+		json.Unmarshal([]byte(data), &chunk)
+		for _, c := range chunk.Choices {
+			content.WriteString(c.Delta.Content)
+		}
+	}
+	if !done || content.String() != "streamed" {
+		t.Errorf("done=%v content=%q", done, content.String())
+	}
+}
@@ -0,0 +1,290 @@
+package parcels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+	"localagent/pkg/utils"
+)
+
+// Providers lists the supported tracking services.
+var Providers = []string{"aftership", "17track"}
+
+// New returns the tracker for provider ("aftership" or "17track").
+func New(provider, apiKey string) (Tracker, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s needs an API key", provider)
+	}
+	client := httpclient.New("track_package", httpclient.WithTimeout(30*time.Second))
+	switch provider {
+	case "aftership":
+		return &AfterShip{BaseURL: "https://api.aftership.com/tracking/2024-04", APIKey: apiKey, client: client}, nil
+	case "17track":
+		return &Track17{BaseURL: "https://api.17track.net/track/v2.2", APIKey: apiKey, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown tracking provider %q (use %s)", provider, strings.Join(Providers, " or "))
+}
+
+// Domains lists the hosts the trackers talk to.
+func Domains() []string {
+	return []string{"api.aftership.com", "api.17track.net"}
+}
+
+// doJSON sends body (if not nil) as JSON and decodes the response into out.
+func doJSON(ctx context.Context, client *http.Client, method, endpoint string, header http.Header, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, r)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, utils.Truncate(strings.TrimSpace(string(data)), 300))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// AfterShip uses the AfterShip tracking API. A number is registered on
+// first lookup; AfterShip then keeps polling the carrier.
+type AfterShip struct {
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
+
+type aftershipTracking struct {
+	TrackingNumber string `json:"tracking_number"`
+	Slug           string `json:"slug"`
+	Tag            string `json:"tag"`
+	SubtagMessage  string `json:"subtag_message"`
+	ExpectedDate   string `json:"expected_delivery"`
+	Checkpoints    []struct {
+		Message  string `json:"message"`
+		Location string `json:"location"`
+		City     string `json:"city"`
+		Time     string `json:"checkpoint_time"`
+	} `json:"checkpoints"`
+}
+
+type aftershipResponse struct {
+	Meta struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"meta"`
+	Data json.RawMessage `json:"data"`
+}
+
+func (a *AfterShip) Track(ctx context.Context, number, carrier string) (Tracking, error) {
+	header := http.Header{}
+	header.Set("as-api-key", a.APIKey)
+
+	var list aftershipResponse
+	q := url.Values{"tracking_numbers": {number}}
+	if err := doJSON(ctx, a.client, http.MethodGet, a.BaseURL+"/trackings?"+q.Encode(), header, nil, &list); err != nil {
+		return Tracking{}, err
+	}
+	var found struct {
+		Trackings []aftershipTracking `json:"trackings"`
+	}
+	json.Unmarshal(list.Data, &found)
+	if len(found.Trackings) > 0 {
+		return found.Trackings[0].tracking(), nil
+	}
+
+	body := map[string]string{"tracking_number": number}
+	if carrier != "" {
+		body["slug"] = carrier
+	}
+	var created aftershipResponse
+	if err := doJSON(ctx, a.client, http.MethodPost, a.BaseURL+"/trackings", header, body, &created); err != nil {
+		return Tracking{}, err
+	}
+	if created.Meta.Code >= 300 {
+		return Tracking{}, fmt.Errorf("aftership: %s", created.Meta.Message)
+	}
+	var t aftershipTracking
+	if err := json.Unmarshal(created.Data, &t); err != nil {
+		return Tracking{}, fmt.Errorf("aftership: decode tracking: %w", err)
+	}
+	return t.tracking(), nil
+}
+
+func (t aftershipTracking) tracking() Tracking {
+	out := Tracking{
+		Number:  t.TrackingNumber,
+		Carrier: t.Slug,
+		Status:  aftershipStatus(t.Tag),
+		Detail:  t.SubtagMessage,
+	}
+	if n := len(t.Checkpoints); n > 0 {
+		last := t.Checkpoints[n-1]
+		out.Detail, out.Location = last.Message, last.Location
+		if out.Location == "" {
+			out.Location = last.City
+		}
+		out.Time = parseTime(last.Time)
+	}
+	out.ETA = parseTime(t.ExpectedDate)
+	return out
+}
+
+func aftershipStatus(tag string) Status {
+	switch tag {
+	case "InfoReceived":
+		return InfoReceived
+	case "InTransit":
+		return InTransit
+	case "OutForDelivery":
+		return OutForDelivery
+	case "AvailableForPickup":
+		return PickupReady
+	case "AttemptFail":
+		return FailedAttempt
+	case "Delivered":
+		return Delivered
+	case "Exception":
+		return Exception
+	case "Expired":
+		return Expired
+	}
+	return Pending
+}
+
+// Track17 uses the 17track API. A number is registered on first lookup,
+// after which 17track needs a little while to fetch it from the carrier.
+type Track17 struct {
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
+
+type track17Response struct {
+	Code int `json:"code"`
+	Data struct {
+		Accepted []struct {
+			Number    string `json:"number"`
+			Carrier   int    `json:"carrier"`
+			TrackInfo *struct {
+				LatestStatus struct {
+					Status string `json:"status"`
+				} `json:"latest_status"`
+				LatestEvent *struct {
+					Time        string `json:"time_iso"`
+					Description string `json:"description"`
+					Location    string `json:"location"`
+				} `json:"latest_event"`
+				TimeMetrics struct {
+					EstimatedDelivery struct {
+						From string `json:"from"`
+					} `json:"estimated_delivery_date"`
+				} `json:"time_metrics"`
+			} `json:"track_info"`
+		} `json:"accepted"`
+		Rejected []struct {
+			Number string `json:"number"`
+			Error  struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"rejected"`
+	} `json:"data"`
+}
+
+// track17AlreadyRegistered is the register error for a known number.
+const track17AlreadyRegistered = -18019901
+
+func (t *Track17) Track(ctx context.Context, number, carrier string) (Tracking, error) {
+	header := http.Header{}
+	header.Set("17token", t.APIKey)
+
+	req := []map[string]any{{"number": number}}
+	var info track17Response
+	if err := doJSON(ctx, t.client, http.MethodPost, t.BaseURL+"/gettrackinfo", header, req, &info); err != nil {
+		return Tracking{}, err
+	}
+	if len(info.Data.Accepted) == 0 {
+		// Not registered yet: register it and report it as pending.
+		var reg track17Response
+		if err := doJSON(ctx, t.client, http.MethodPost, t.BaseURL+"/register", header, req, &reg); err != nil {
+			return Tracking{}, err
+		}
+		for _, r := range reg.Data.Rejected {
+			if r.Error.Code != track17AlreadyRegistered {
+				return Tracking{}, fmt.Errorf("17track: %s", r.Error.Message)
+			}
+		}
+		return Tracking{Number: number, Carrier: carrier, Status: Pending, Detail: "Registered; the carrier has not been queried yet"}, nil
+	}
+
+	a := info.Data.Accepted[0]
+	out := Tracking{Number: a.Number, Carrier: carrier, Status: Pending}
+	if a.TrackInfo != nil {
+		out.Status = track17Status(a.TrackInfo.LatestStatus.Status)
+		if e := a.TrackInfo.LatestEvent; e != nil {
+			out.Detail, out.Location, out.Time = e.Description, e.Location, parseTime(e.Time)
+		}
+		out.ETA = parseTime(a.TrackInfo.TimeMetrics.EstimatedDelivery.From)
+	}
+	return out, nil
+}
+
+func track17Status(s string) Status {
+	switch s {
+	case "InfoReceived":
+		return InfoReceived
+	case "InTransit":
+		return InTransit
+	case "OutForDelivery":
+		return OutForDelivery
+	case "AvailableForPickup":
+		return PickupReady
+	case "DeliveryFailure":
+		return FailedAttempt
+	case "Delivered":
+		return Delivered
+	case "Exception":
+		return Exception
+	case "Expired":
+		return Expired
+	}
+	return Pending
+}
+
+// parseTime accepts RFC 3339 timestamps, with or without a zone, and dates.
+func parseTime(s string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
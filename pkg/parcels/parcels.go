@@ -0,0 +1,202 @@
+// Package parcels tracks deliveries through a tracking aggregator
+// (AfterShip or 17track) and watches tracking numbers for status changes.
+package parcels
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/state"
+)
+
+// Status is a normalized delivery status.
+type Status string
+
+const (
+	Pending        Status = "pending" // not scanned by the carrier yet
+	InfoReceived   Status = "info_received"
+	InTransit      Status = "in_transit"
+	OutForDelivery Status = "out_for_delivery"
+	PickupReady    Status = "available_for_pickup"
+	FailedAttempt  Status = "failed_attempt"
+	Delivered      Status = "delivered"
+	Exception      Status = "exception"
+	Expired        Status = "expired" // no updates for a long time
+)
+
+// Text is the status in words, e.g. "out for delivery".
+func (s Status) Text() string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.ReplaceAll(string(s), "_", " ")
+}
+
+// Notable reports whether a change to s is worth interrupting the user for.
+func (s Status) Notable() bool {
+	return slices.Contains([]Status{OutForDelivery, PickupReady, FailedAttempt, Delivered, Exception}, s)
+}
+
+// Final reports whether s won't change any more.
+func (s Status) Final() bool {
+	return s == Delivered || s == Expired
+}
+
+// Tracking is the current state of a shipment.
+type Tracking struct {
+	Number   string
+	Carrier  string
+	Status   Status
+	Detail   string    // latest checkpoint, e.g. "Arrived at sort facility"
+	Location string    // of the latest checkpoint
+	Time     time.Time // of the latest checkpoint
+	ETA      time.Time // estimated delivery, zero if unknown
+}
+
+// Format describes t in a few lines.
+func (t Tracking) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s", t.Number)
+	if t.Carrier != "" {
+		fmt.Fprintf(&sb, " (%s)", t.Carrier)
+	}
+	fmt.Fprintf(&sb, ": %s", t.Status.Text())
+	if t.Detail != "" {
+		fmt.Fprintf(&sb, "\nLatest: %s", t.Detail)
+		if t.Location != "" {
+			fmt.Fprintf(&sb, ", %s", t.Location)
+		}
+		if !t.Time.IsZero() {
+			fmt.Fprintf(&sb, " (%s)", t.Time.Format("Mon Jan 2 15:04"))
+		}
+	}
+	if !t.ETA.IsZero() && t.Status != Delivered {
+		fmt.Fprintf(&sb, "\nExpected: %s", t.ETA.Format("Mon Jan 2"))
+	}
+	return sb.String()
+}
+
+// Tracker looks up a tracking number; carrier may be empty to let the
+// service detect it.
+type Tracker interface {
+	Track(ctx context.Context, number, carrier string) (Tracking, error)
+}
+
+// carrierPatterns recognize tracking numbers whose format is unambiguous.
+var carrierPatterns = []struct {
+	carrier string
+	re      *regexp.Regexp
+}{
+	{"ups", regexp.MustCompile(`^1Z[0-9A-Z]{16}$`)},
+	{"usps", regexp.MustCompile(`^9[2-5][0-9]{20}$`)},
+	{"fedex", regexp.MustCompile(`^[0-9]{12}$|^[0-9]{15}$`)},
+}
+
+// DetectCarrier guesses the carrier from the number's format, or "".
+func DetectCarrier(number string) string {
+	for _, p := range carrierPatterns {
+		if p.re.MatchString(number) {
+			return p.carrier
+		}
+	}
+	return ""
+}
+
+// Normalize strips spaces and dashes and upper-cases a tracking number.
+func Normalize(number string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(number)))
+}
+
+const stateNamespace = "parcels"
+
+// Parcel is a watched tracking number.
+type Parcel struct {
+	Number      string `json:"number"`
+	Carrier     string `json:"carrier,omitempty"`
+	Label       string `json:"label,omitempty"` // what's in it, e.g. "new headphones"
+	Status      Status `json:"status,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+	Channel     string `json:"channel,omitempty"` // where it was added, for the heartbeat event
+	ChatID      string `json:"chat_id,omitempty"`
+	AddedMS     int64  `json:"added_ms"`
+	ChangedMS   int64  `json:"changed_ms,omitempty"`
+	CheckedMS   int64  `json:"checked_ms,omitempty"`
+	CheckErrors int    `json:"check_errors,omitempty"`
+}
+
+// Name is the label, or the number.
+func (p Parcel) Name() string {
+	if p.Label != "" {
+		return p.Label
+	}
+	return p.Number
+}
+
+// Store keeps the watched parcels in the state store.
+type Store struct {
+	state *state.Manager
+}
+
+func NewStore(workspace string) *Store {
+	return &Store{state: state.NewManager(workspace)}
+}
+
+func (s *Store) Get(number string) (Parcel, bool) {
+	var p Parcel
+	ok, err := s.state.Get(stateNamespace, Normalize(number), &p)
+	return p, ok && err == nil
+}
+
+func (s *Store) Put(p Parcel) error {
+	return s.state.Set(stateNamespace, p.Number, p)
+}
+
+// Watch starts watching a parcel, recording t as its current state.
+func (s *Store) Watch(t Tracking, label, channel, chatID string, now time.Time) (Parcel, error) {
+	if t.Number == "" {
+		return Parcel{}, fmt.Errorf("tracking number is required")
+	}
+	p := Parcel{
+		Number:    Normalize(t.Number),
+		Carrier:   t.Carrier,
+		Label:     label,
+		Status:    t.Status,
+		Detail:    t.Detail,
+		Channel:   channel,
+		ChatID:    chatID,
+		AddedMS:   now.UnixMilli(),
+		ChangedMS: now.UnixMilli(),
+		CheckedMS: now.UnixMilli(),
+	}
+	if old, ok := s.Get(p.Number); ok {
+		p.AddedMS = old.AddedMS
+		if p.Label == "" {
+			p.Label = old.Label
+		}
+	}
+	return p, s.Put(p)
+}
+
+// Remove stops watching number and reports whether it was watched.
+func (s *Store) Remove(number string) (bool, error) {
+	if _, ok := s.Get(number); !ok {
+		return false, nil
+	}
+	return true, s.state.Delete(stateNamespace, Normalize(number))
+}
+
+// List returns the watched parcels, oldest first.
+func (s *Store) List() []Parcel {
+	var parcels []Parcel
+	for _, key := range s.state.Keys(stateNamespace) {
+		if p, ok := s.Get(key); ok {
+			parcels = append(parcels, p)
+		}
+	}
+	slices.SortFunc(parcels, func(a, b Parcel) int { return int(a.AddedMS - b.AddedMS) })
+	return parcels
+}
@@ -0,0 +1,148 @@
+package parcels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAfterShipRegistersUnknownNumbers(t *testing.T) {
+	var created map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("as-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/trackings":
+			w.Write([]byte(`{"meta":{"code":200},"data":{"trackings":[]}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/trackings":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"meta":{"code":201},"data":{"tracking_number":"1Z999AA10123456784","slug":"ups","tag":"OutForDelivery",
+				"checkpoints":[{"message":"Origin scan","location":"Louisville, KY","checkpoint_time":"2026-05-01T08:00:00-04:00"},
+				{"message":"Out for delivery","location":"Denver, CO","checkpoint_time":"2026-05-03T07:12:00-06:00"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	a := &AfterShip{BaseURL: srv.URL, APIKey: "key", client: srv.Client()}
+	tr, err := a.Track(context.Background(), "1Z999AA10123456784", "ups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created["tracking_number"] != "1Z999AA10123456784" || created["slug"] != "ups" {
+		t.Errorf("created = %v", created)
+	}
+	if tr.Status != OutForDelivery || tr.Detail != "Out for delivery" || tr.Location != "Denver, CO" || tr.Time.IsZero() {
+		t.Errorf("tracking = %+v", tr)
+	}
+}
+
+func TestTrack17(t *testing.T) {
+	registered := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("17token") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/register":
+			registered = true
+			w.Write([]byte(`{"code":0,"data":{"accepted":[{"number":"RR123456785DE"}],"rejected":[]}}`))
+		case "/gettrackinfo":
+			if !registered {
+				w.Write([]byte(`{"code":0,"data":{"accepted":[],"rejected":[{"number":"RR123456785DE","error":{"code":-18019902,"message":"not registered"}}]}}`))
+				return
+			}
+			w.Write([]byte(`{"code":0,"data":{"accepted":[{"number":"RR123456785DE","carrier":7041,"track_info":{
+				"latest_status":{"status":"InTransit"},
+				"latest_event":{"time_iso":"2026-05-02T10:00:00+02:00","description":"Arrived at sorting centre","location":"Leipzig"},
+				"time_metrics":{"estimated_delivery_date":{"from":"2026-05-05T00:00:00+02:00"}}}}]}}`))
+		}
+	}))
+	defer srv.Close()
+
+	tr17 := &Track17{BaseURL: srv.URL, APIKey: "key", client: srv.Client()}
+	tr, err := tr17.Track(context.Background(), "RR123456785DE", "")
+	if err != nil || tr.Status != Pending || !registered {
+		t.Fatalf("first lookup = %+v, %v", tr, err)
+	}
+	tr, err = tr17.Track(context.Background(), "RR123456785DE", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Status != InTransit || tr.Detail != "Arrived at sorting centre" || tr.ETA.IsZero() {
+		t.Errorf("tracking = %+v", tr)
+	}
+	if got := tr.Format(); !strings.Contains(got, "in transit") || !strings.Contains(got, "Leipzig") || !strings.Contains(got, "Expected:") {
+		t.Errorf("Format = %q", got)
+	}
+}
+
+type fakeTracker map[string]Tracking
+
+func (f fakeTracker) Track(_ context.Context, number, _ string) (Tracking, error) {
+	return f[number], nil
+}
+
+func TestWatcherReportsChanges(t *testing.T) {
+	store := NewStore(t.TempDir())
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := store.Watch(Tracking{Number: "1z999aa10123456784", Carrier: "ups", Status: InTransit}, "headphones", "telegram", "42", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Watch(Tracking{Number: "RR123456785DE", Status: InTransit}, "", "web", "default", now); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := fakeTracker{
+		"1Z999AA10123456784": {Number: "1Z999AA10123456784", Status: OutForDelivery, Detail: "Loaded on delivery vehicle"},
+		"RR123456785DE":      {Number: "RR123456785DE", Status: InTransit},
+	}
+	var updates []Update
+	w := NewWatcher(store, tracker, 0, func(u Update) { updates = append(updates, u) })
+	w.now = func() time.Time { return now }
+
+	w.Check()
+	if len(updates) != 1 || updates[0].From != InTransit || updates[0].Parcel.ChatID != "42" {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if got := updates[0].Message(); got != "Parcel update: headphones (ups) is now out for delivery. Latest: Loaded on delivery vehicle" {
+		t.Errorf("message = %q", got)
+	}
+	if !updates[0].Parcel.Status.Notable() {
+		t.Error("out for delivery is not notable")
+	}
+
+	// Delivered parcels stay listed for a few days, then drop off.
+	tracker["1Z999AA10123456784"] = Tracking{Number: "1Z999AA10123456784", Status: Delivered}
+	w.Check()
+	if len(updates) != 2 || len(store.List()) != 2 {
+		t.Fatalf("updates = %d, watched = %+v", len(updates), store.List())
+	}
+	now = now.Add(4 * 24 * time.Hour)
+	w.Check()
+	if list := store.List(); len(list) != 1 || list[0].Number != "RR123456785DE" {
+		t.Errorf("watched = %+v", list)
+	}
+}
+
+func TestDetectCarrier(t *testing.T) {
+	for number, want := range map[string]string{
+		"1Z999AA10123456784":     "ups",
+		"9400111899223197428490": "usps",
+		"123456789012":           "fedex",
+		"RR123456785DE":          "",
+	} {
+		if got := DetectCarrier(number); got != want {
+			t.Errorf("DetectCarrier(%s) = %q, want %q", number, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package parcels
+
+import (
+	"context"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+const (
+	defaultPoll   = time.Hour
+	keepFinal     = 3 * 24 * time.Hour // delivered parcels are dropped after this
+	maxCheckFails = 48                 // consecutive failed lookups before giving up
+)
+
+// Update is a status change of a watched parcel.
+type Update struct {
+	Parcel Parcel
+	From   Status
+}
+
+// Message describes the change for the heartbeat, e.g. "Parcel update: new
+// headphones (ups) is now out for delivery. Latest: Loaded on vehicle".
+func (u Update) Message() string {
+	msg := "Parcel update: " + u.Parcel.Name()
+	if u.Parcel.Carrier != "" {
+		msg += " (" + u.Parcel.Carrier + ")"
+	}
+	msg += " is now " + u.Parcel.Status.Text()
+	if u.Parcel.Detail != "" {
+		msg += ". Latest: " + u.Parcel.Detail
+	}
+	return msg
+}
+
+// Watcher polls the watched parcels and reports status changes.
+type Watcher struct {
+	store   *Store
+	tracker Tracker
+	poll    time.Duration
+	handle  func(Update)
+	now     func() time.Time
+	stop    chan struct{}
+}
+
+// NewWatcher checks the parcels in store every poll (default an hour).
+func NewWatcher(store *Store, tracker Tracker, poll time.Duration, handle func(Update)) *Watcher {
+	if poll <= 0 {
+		poll = defaultPoll
+	}
+	return &Watcher{store: store, tracker: tracker, poll: poll, handle: handle, now: time.Now, stop: make(chan struct{})}
+}
+
+func (w *Watcher) Start() {
+	ticker := time.NewTicker(w.poll)
+	go func() {
+		w.Check()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("parcels: watching deliveries every %s", w.poll)
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+}
+
+// Check looks up every watched parcel once, reports the ones whose status
+// changed, and drops parcels delivered a while ago or that keep failing.
+func (w *Watcher) Check() {
+	now := w.now()
+	for _, p := range w.store.List() {
+		if p.Status.Final() {
+			if now.Sub(time.UnixMilli(p.ChangedMS)) > keepFinal {
+				w.store.Remove(p.Number)
+			}
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		t, err := w.tracker.Track(ctx, p.Number, p.Carrier)
+		cancel()
+		p.CheckedMS = now.UnixMilli()
+		if err != nil {
+			p.CheckErrors++
+			logger.Warn("parcels: %s: %v", p.Number, err)
+			if p.CheckErrors >= maxCheckFails {
+				logger.Warn("parcels: %s: giving up after %d failed lookups", p.Number, p.CheckErrors)
+				w.store.Remove(p.Number)
+			} else if err := w.store.Put(p); err != nil {
+				logger.Warn("parcels: save: %v", err)
+			}
+			continue
+		}
+		p.CheckErrors = 0
+		from := p.Status
+		if p.Carrier == "" {
+			p.Carrier = t.Carrier
+		}
+		p.Status, p.Detail = t.Status, t.Detail
+		if t.Status != from {
+			p.ChangedMS = now.UnixMilli()
+		}
+		if err := w.store.Put(p); err != nil {
+			logger.Warn("parcels: save: %v", err)
+			continue
+		}
+		if t.Status != from {
+			logger.Info("parcels: %s: %s -> %s", p.Number, from.Text(), t.Status.Text())
+			w.handle(Update{Parcel: p, From: from})
+		}
+	}
+}
@@ -34,3 +34,24 @@ var Heartbeat string
 
 //go:embed heartbeat-system.txt
 var HeartbeatSystem string
+
+//go:embed member-section.txt
+var MemberSection string
+
+//go:embed journal-daily.txt
+var JournalDaily string
+
+//go:embed journal-weekly.txt
+var JournalWeekly string
+
+//go:embed goal-review.txt
+var GoalReview string
+
+//go:embed receipt-extract.txt
+var ReceiptExtract string
+
+//go:embed docsum-map.txt
+var DocsumMap string
+
+//go:embed docsum-reduce.txt
+var DocsumReduce string
@@ -3,14 +3,20 @@ package providers
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"localagent/pkg/errs"
 	"localagent/pkg/logger"
 )
 
@@ -18,6 +24,30 @@ type HTTPProvider struct {
 	apiKey     string
 	apiBase    string
 	httpClient *http.Client
+	capture    CaptureFunc
+	cacheMode  string
+}
+
+// Prompt caching modes for SetPromptCache.
+const (
+	PromptCacheAnthropic = "anthropic" // cache_control on the stable system prompt and the tools
+	PromptCacheOpenAI    = "openai"    // prompt_cache_key routing the same prefix to the same cache
+)
+
+// CaptureFunc receives the raw JSON of every chat completion exchange.
+// response is nil when the request never got an answer.
+type CaptureFunc func(model string, request, response []byte, status int, elapsed time.Duration, err error)
+
+// SetPromptCache adds prompt caching hints of mode (PromptCacheAnthropic or
+// PromptCacheOpenAI) to every request. The first text part of the leading
+// system message is taken as its stable, cacheable prefix.
+func (p *HTTPProvider) SetPromptCache(mode string) {
+	p.cacheMode = mode
+}
+
+// SetCapture records every request/response pair with fn, for debugging.
+func (p *HTTPProvider) SetCapture(fn CaptureFunc) {
+	p.capture = fn
 }
 
 func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
@@ -42,14 +72,50 @@ func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
 }
 
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any) (*LLMResponse, error) {
+	req, jsonData, err := p.newRequest(ctx, messages, tools, model, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		p.captured(model, jsonData, nil, 0, start, err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		p.captured(model, jsonData, nil, resp.StatusCode, start, err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+		p.captured(model, jsonData, body, resp.StatusCode, start, apiErr)
+		return nil, apiErr
+	}
+
+	p.captured(model, jsonData, body, resp.StatusCode, start, nil)
+	return p.finish(body)
+}
+
+// newRequest builds the chat completion request and returns it with its
+// JSON body.
+func (p *HTTPProvider) newRequest(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any, stream bool) (*http.Request, []byte, error) {
 	if p.apiBase == "" {
-		return nil, fmt.Errorf("API base not configured")
+		return nil, nil, ErrNoAPIBase
 	}
 
+	messages, tools = p.withCacheHints(messages, tools)
 	requestBody := map[string]any{
 		"model":    model,
 		"messages": messages,
 	}
+	if p.cacheMode == PromptCacheOpenAI {
+		requestBody["prompt_cache_key"] = cacheKey(messages, tools)
+	}
 
 	if len(tools) > 0 {
 		requestBody["tools"] = tools
@@ -64,37 +130,97 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		requestBody["temperature"] = temperature
 	}
 
+	if stream {
+		requestBody["stream"] = true
+		requestBody["stream_options"] = map[string]any{"include_usage": true}
+	}
+
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	if p.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, jsonData, nil
+}
 
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+// finish parses a complete (non-streamed or reassembled) response body.
+func (p *HTTPProvider) finish(body []byte) (*LLMResponse, error) {
+	llmResp, err := p.parseResponse(body)
+	if err == nil && llmResp.Usage != nil {
+		llmResp.Usage.SavedTokens = savedTokens(p.cacheMode, llmResp.Usage)
 	}
-	defer resp.Body.Close()
+	return llmResp, err
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// withCacheHints returns copies of messages and tools with the system
+// prompt prefix and the tool definitions marked cacheable, in Anthropic
+// mode. Other modes return them unchanged.
+func (p *HTTPProvider) withCacheHints(messages []Message, tools []ToolDefinition) ([]Message, []ToolDefinition) {
+	if p.cacheMode != PromptCacheAnthropic {
+		return messages, tools
+	}
+	ephemeral := &CacheControl{Type: "ephemeral"}
+	if len(messages) > 0 && messages[0].Role == "system" {
+		messages = slices.Clone(messages)
+		sys := messages[0]
+		if len(sys.ContentParts) == 0 {
+			sys.ContentParts = []ContentPart{{Type: "text", Text: sys.Content}}
+		} else {
+			sys.ContentParts = slices.Clone(sys.ContentParts)
+		}
+		sys.ContentParts[0].CacheControl = ephemeral
+		messages[0] = sys
+	}
+	if len(tools) > 0 {
+		tools = slices.Clone(tools)
+		tools[len(tools)-1].CacheControl = ephemeral
 	}
+	return messages, tools
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+// cacheKey identifies the stable prefix (system prompt and tool names), so
+// requests sharing it are routed to the same cache.
+func cacheKey(messages []Message, tools []ToolDefinition) string {
+	h := sha256.New()
+	if len(messages) > 0 && messages[0].Role == "system" {
+		if parts := messages[0].ContentParts; len(parts) > 0 {
+			h.Write([]byte(parts[0].Text))
+		} else {
+			h.Write([]byte(messages[0].Content))
+		}
+	}
+	for _, t := range tools {
+		h.Write([]byte(t.Function.Name))
+	}
+	return "localagent-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// savedTokens estimates the prompt-token equivalents caching saved: cache
+// reads cost 10% of the prompt price with Anthropic (writes 125%) and
+// typically 50% elsewhere.
+func savedTokens(mode string, u *UsageInfo) int {
+	if mode == PromptCacheAnthropic {
+		return int(0.9*float64(u.CachedTokens) - 0.25*float64(u.CacheWriteTokens))
 	}
+	return u.CachedTokens / 2
+}
 
-	return p.parseResponse(body)
+func (p *HTTPProvider) captured(model string, request, response []byte, status int, start time.Time, err error) {
+	if p.capture != nil {
+		p.capture(model, request, response, status, time.Since(start), err)
+	}
 }
 
 func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
@@ -170,3 +296,40 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 func (p *HTTPProvider) GetDefaultModel() string {
 	return ""
 }
+
+// ErrNoAPIBase is returned when provider.api_base is empty.
+var ErrNoAPIBase = errors.New("API base not configured")
+
+// APIError is a non-200 response from the provider.
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed:\n  Status: %d\n  Body:   %s", e.StatusCode, e.Body)
+}
+
+// Unwrap returns the kind of failure (errs.ErrAuth, errs.ErrRateLimited,
+// ...), so callers can test it with errors.Is. An unknown model is
+// errs.ErrNotFound whatever the status.
+func (e *APIError) Unwrap() error {
+	if kind := errs.FromStatus(e.StatusCode); kind != nil {
+		return kind
+	}
+	body := strings.ToLower(e.Body)
+	if strings.Contains(body, "model") && strings.Contains(body, "not found") {
+		return errs.ErrNotFound
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeCompletions records request bodies and answers with usage.
+func fakeCompletions(t *testing.T, usage string) (*HTTPProvider, *map[string]any) {
+	t.Helper()
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"content":"hi"},"finish_reason":"stop"}],"usage":` + usage + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	return NewHTTPProvider("", srv.URL, ""), &got
+}
+
+func TestAnthropicCacheHints(t *testing.T) {
+	p, got := fakeCompletions(t, `{"prompt_tokens":1000,"completion_tokens":5,"total_tokens":1005,
+		"cache_read_input_tokens":800,"cache_creation_input_tokens":100}`)
+	p.SetPromptCache(PromptCacheAnthropic)
+
+	messages := []Message{
+		{Role: "system", Content: "stable\n\nvolatile", ContentParts: []ContentPart{{Type: "text", Text: "stable"}, {Type: "text", Text: "volatile"}}},
+		{Role: "user", Content: "hello"},
+	}
+	tools := []ToolDefinition{{Type: "function", Function: ToolFunctionDefinition{Name: "a"}}, {Type: "function", Function: ToolFunctionDefinition{Name: "b"}}}
+	resp, err := p.Chat(context.Background(), messages, tools, "m", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sys := (*got)["messages"].([]any)[0].(map[string]any)["content"].([]any)
+	if sys[0].(map[string]any)["cache_control"] == nil || sys[1].(map[string]any)["cache_control"] != nil {
+		t.Errorf("system parts = %v", sys)
+	}
+	sentTools := (*got)["tools"].([]any)
+	if sentTools[0].(map[string]any)["cache_control"] != nil || sentTools[1].(map[string]any)["cache_control"] == nil {
+		t.Errorf("tools = %v", sentTools)
+	}
+	if messages[0].ContentParts[0].CacheControl != nil || tools[1].CacheControl != nil {
+		t.Error("caller's messages and tools must not be modified")
+	}
+
+	u := resp.Usage
+	if u.CachedTokens != 800 || u.CacheWriteTokens != 100 || u.SavedTokens != 695 || u.CacheHitRate() != 0.8 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestOpenAICacheKey(t *testing.T) {
+	p, got := fakeCompletions(t, `{"prompt_tokens":2000,"completion_tokens":5,"total_tokens":2005,"prompt_tokens_details":{"cached_tokens":1536}}`)
+	p.SetPromptCache(PromptCacheOpenAI)
+
+	messages := []Message{{Role: "system", Content: "stable"}, {Role: "user", Content: "hello"}}
+	resp, err := p.Chat(context.Background(), messages, nil, "m", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := (*got)["prompt_cache_key"].(string)
+	if key == "" {
+		t.Fatalf("no prompt_cache_key in %v", *got)
+	}
+	if _, isString := (*got)["messages"].([]any)[0].(map[string]any)["content"].(string); !isString {
+		t.Error("openai mode should send the system prompt unchanged")
+	}
+	if resp.Usage.CachedTokens != 1536 || resp.Usage.SavedTokens != 768 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+
+	p.Chat(context.Background(), []Message{{Role: "system", Content: "stable"}, {Role: "user", Content: "again"}}, nil, "m", nil)
+	if (*got)["prompt_cache_key"] != key {
+		t.Error("same prefix should get the same cache key")
+	}
+}
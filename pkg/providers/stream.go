@@ -0,0 +1,182 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// streamChunk is one server-sent event of a streamed chat completion.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+			ToolCalls        []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function *struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *json.RawMessage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamedToolCall accumulates a tool call whose name and arguments arrive
+// in pieces.
+type streamedToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ChatStream is Chat with "stream": true, calling onDelta with every piece
+// of assistant text as it arrives. The stream is reassembled into a regular
+// completion, so captures and the returned response match Chat's. Servers
+// that ignore the stream flag and answer with plain JSON are handled too.
+func (p *HTTPProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any, onDelta func(string)) (*LLMResponse, error) {
+	req, jsonData, err := p.newRequest(ctx, messages, tools, model, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		p.captured(model, jsonData, nil, 0, start, err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			p.captured(model, jsonData, nil, resp.StatusCode, start, err)
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+			p.captured(model, jsonData, body, resp.StatusCode, start, apiErr)
+			return nil, apiErr
+		}
+		p.captured(model, jsonData, body, resp.StatusCode, start, nil)
+		return p.finish(body)
+	}
+
+	body, err := readStream(resp.Body, onDelta)
+	if err != nil {
+		p.captured(model, jsonData, body, resp.StatusCode, start, err)
+		return nil, err
+	}
+	p.captured(model, jsonData, body, resp.StatusCode, start, nil)
+	return p.finish(body)
+}
+
+// readStream consumes an OpenAI-style event stream and returns the
+// equivalent non-streamed response body.
+func readStream(r io.Reader, onDelta func(string)) ([]byte, error) {
+	var (
+		content, reasoning strings.Builder
+		calls              []*streamedToolCall
+		finishReason       string
+		usage              *json.RawMessage
+	)
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		data = bytes.TrimSpace(data)
+		if ok && string(data) == "[DONE]" {
+			break
+		}
+		if ok && len(data) > 0 {
+			var chunk streamChunk
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				return nil, fmt.Errorf("failed to parse stream chunk: %w", err)
+			}
+			if chunk.Error != nil {
+				return nil, fmt.Errorf("stream error: %s", chunk.Error.Message)
+			}
+			if chunk.Usage != nil && string(*chunk.Usage) != "null" {
+				usage = chunk.Usage
+			}
+			for _, choice := range chunk.Choices {
+				d := choice.Delta
+				if d.Content != "" {
+					content.WriteString(d.Content)
+					if onDelta != nil {
+						onDelta(d.Content)
+					}
+				}
+				reasoning.WriteString(d.ReasoningContent)
+				reasoning.WriteString(d.Reasoning)
+				for _, tc := range d.ToolCalls {
+					for len(calls) <= tc.Index {
+						calls = append(calls, &streamedToolCall{Type: "function"})
+					}
+					call := calls[tc.Index]
+					if tc.ID != "" {
+						call.ID = tc.ID
+					}
+					if tc.Type != "" {
+						call.Type = tc.Type
+					}
+					if tc.Function != nil {
+						call.Function.Name += tc.Function.Name
+						call.Function.Arguments += tc.Function.Arguments
+					}
+				}
+				if choice.FinishReason != "" {
+					finishReason = choice.FinishReason
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	type message struct {
+		Content          string              `json:"content"`
+		ReasoningContent string              `json:"reasoning_content,omitempty"`
+		ToolCalls        []*streamedToolCall `json:"tool_calls,omitempty"`
+	}
+	type choice struct {
+		Message      message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	}
+	return json.Marshal(struct {
+		Choices []choice         `json:"choices"`
+		Usage   *json.RawMessage `json:"usage,omitempty"`
+	}{
+		Choices: []choice{{
+			Message:      message{Content: content.String(), ReasoningContent: reasoning.String(), ToolCalls: calls},
+			FinishReason: finishReason,
+		}},
+		Usage: usage,
+	})
+}
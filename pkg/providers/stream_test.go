@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChatStream(t *testing.T) {
+	var got map[string]any
+	chunks := []string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"index":0,"delta":{"reasoning_content":"thinking"}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"web_search","arguments":"{\"que"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ry\":\"go\"}"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}}`,
+		`[DONE]`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+	}))
+	defer srv.Close()
+
+	var captured []byte
+	p := NewHTTPProvider("", srv.URL, "")
+	p.SetCapture(func(_ string, _, response []byte, _ int, _ time.Duration, _ error) { captured = response })
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["stream"] != true {
+		t.Errorf("request = %v", got)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Errorf("deltas = %q", deltas)
+	}
+	if resp.Content != "Hello" || resp.ReasoningContent != "thinking" || resp.FinishReason != "tool_calls" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID != "c1" || resp.ToolCalls[0].Name != "web_search" || resp.ToolCalls[0].Arguments["query"] != "go" {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 14 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+	if !strings.Contains(string(captured), `"content":"Hello"`) {
+		t.Errorf("captured = %s", captured)
+	}
+}
+
+func TestChatStreamPlainJSON(t *testing.T) {
+	p, _ := fakeCompletions(t, `{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}`)
+	called := false
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil, func(string) { called = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "hi" || called {
+		t.Errorf("response = %+v, delta called = %v", resp, called)
+	}
+}
+
+func TestChatStreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"model overloaded\"}}\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider("", srv.URL, "")
+	if _, err := p.ChatStream(context.Background(), nil, nil, "m", nil, nil); err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -30,13 +30,65 @@ type UsageInfo struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`      // prompt tokens read from the provider's cache
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"` // prompt tokens written to the cache
+	SavedTokens      int `json:"saved_tokens,omitempty"`       // prompt-token equivalents saved by caching, net of write surcharges
+}
+
+// UnmarshalJSON reads cache statistics from the shapes providers report
+// them in: OpenAI prompt_tokens_details.cached_tokens, Anthropic
+// cache_read_input_tokens/cache_creation_input_tokens (as passed through by
+// compatible gateways) and DeepSeek prompt_cache_hit_tokens.
+func (u *UsageInfo) UnmarshalJSON(data []byte) error {
+	type alias UsageInfo
+	var raw struct {
+		alias
+		PromptTokensDetails *struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		PromptCacheHitTokens     int `json:"prompt_cache_hit_tokens"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*u = UsageInfo(raw.alias)
+	if u.CachedTokens == 0 {
+		switch {
+		case raw.PromptTokensDetails != nil && raw.PromptTokensDetails.CachedTokens > 0:
+			u.CachedTokens = raw.PromptTokensDetails.CachedTokens
+		case raw.CacheReadInputTokens > 0:
+			u.CachedTokens = raw.CacheReadInputTokens
+		default:
+			u.CachedTokens = raw.PromptCacheHitTokens
+		}
+	}
+	if u.CacheWriteTokens == 0 {
+		u.CacheWriteTokens = raw.CacheCreationInputTokens
+	}
+	return nil
+}
+
+// CacheHitRate is the share of prompt tokens served from the cache.
+func (u *UsageInfo) CacheHitRate() float64 {
+	if u == nil || u.PromptTokens == 0 {
+		return 0
+	}
+	return min(float64(u.CachedTokens)/float64(u.PromptTokens), 1)
+}
+
+// CacheControl marks a prompt prefix as cacheable (Anthropic style).
+type CacheControl struct {
+	Type string `json:"type"` // "ephemeral"
 }
 
 // ContentPart represents a part of a multimodal message content (OpenAI format).
 type ContentPart struct {
-	Type     string    `json:"type"`
-	Text     string    `json:"text,omitempty"`
-	ImageURL *ImageURL `json:"image_url,omitempty"`
+	Type         string        `json:"type"`
+	Text         string        `json:"text,omitempty"`
+	ImageURL     *ImageURL     `json:"image_url,omitempty"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
 // ImageURL holds an image reference for multimodal messages.
@@ -128,9 +180,17 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// StreamingProvider is implemented by providers that can report the
+// assistant's text as it is generated. onDelta receives each new piece of
+// content; the returned response is the same as Chat's.
+type StreamingProvider interface {
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]any, onDelta func(string)) (*LLMResponse, error)
+}
+
 type ToolDefinition struct {
-	Type     string                 `json:"type"`
-	Function ToolFunctionDefinition `json:"function"`
+	Type         string                 `json:"type"`
+	Function     ToolFunctionDefinition `json:"function"`
+	CacheControl *CacheControl          `json:"cache_control,omitempty"`
 }
 
 type ToolFunctionDefinition struct {
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PersistFunc is called after a whitelist change so it can be saved to config.
+// Exactly one of add or remove is non-empty.
+type PersistFunc func(add, remove string) error
+
+// AdminHandler serves runtime whitelist management and the denied-request audit log:
+//
+//	GET    /proxy/whitelist          list entries
+//	POST   /proxy/whitelist          add {"pattern": "..."}
+//	DELETE /proxy/whitelist?pattern= remove an entry
+//	GET    /proxy/denied?limit=N     recent denied requests
+//
+// Only loopback clients are accepted. Cross-site browser requests are refused
+// and POST bodies must be JSON, so a web page can't widen the whitelist.
+func AdminHandler(p *Proxy, persist PersistFunc) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/proxy/whitelist", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]any{"patterns": p.Whitelist().List()})
+		case http.MethodPost:
+			var body struct {
+				Pattern string `json:"pattern"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Pattern) == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "pattern is required"})
+				return
+			}
+			pattern := strings.TrimSpace(body.Pattern)
+			p.Whitelist().Add(pattern)
+			if persist != nil {
+				if err := persist(pattern, ""); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"patterns": p.Whitelist().List()})
+		case http.MethodDelete:
+			pattern := strings.TrimSpace(r.URL.Query().Get("pattern"))
+			if pattern == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "pattern is required"})
+				return
+			}
+			if !p.Whitelist().Remove(pattern) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "pattern not found"})
+				return
+			}
+			if persist != nil {
+				if err := persist("", pattern); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+					return
+				}
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"patterns": p.Whitelist().List()})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/proxy/denied", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		writeJSON(w, http.StatusOK, map[string]any{"denied": p.AuditLog().Recent(limit)})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin API is only available from localhost"})
+			return
+		}
+		if crossSite(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-site requests are not allowed"})
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// crossSite reports whether a browser sent r from a page on another
+// origin. Clients that aren't browsers send neither header.
+func crossSite(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return true
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		return err != nil || u.Host != r.Host
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
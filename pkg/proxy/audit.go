@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+// ToolHeader carries the name of the tool that issued a request so denied
+// requests can be attributed. It is sent on CONNECT and plain HTTP requests.
+const ToolHeader = "X-Localagent-Tool"
+
+type toolCtxKey struct{}
+
+// WithTool returns a context that attributes outgoing requests to the named tool.
+func WithTool(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, toolCtxKey{}, name)
+}
+
+// ToolFromContext returns the tool name set by WithTool, if any.
+func ToolFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(toolCtxKey{}).(string)
+	return name
+}
+
+// AttributeTools makes the transport send ToolHeader on CONNECT requests,
+// using the tool name from the request context.
+func AttributeTools(t *http.Transport) {
+	t.GetProxyConnectHeader = func(ctx context.Context, _ *url.URL, _ string) (http.Header, error) {
+		name := ToolFromContext(ctx)
+		if name == "" {
+			return nil, nil
+		}
+		return http.Header{ToolHeader: []string{name}}, nil
+	}
+}
+
+const (
+	auditRingSize   = 200
+	auditMaxFileLen = 1 << 20 // rotate the audit file after 1MB
+)
+
+// DeniedRequest is a single audit log entry for a request blocked by the whitelist.
+type DeniedRequest struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Host   string    `json:"host"`
+	Path   string    `json:"path,omitempty"`
+	Tool   string    `json:"tool,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// AuditLog keeps recent denied requests in memory and appends them to a
+// JSONL file so they can be inspected from another process.
+type AuditLog struct {
+	mu     sync.Mutex
+	path   string
+	recent []DeniedRequest
+}
+
+// NewAuditLog creates an audit log. If path is empty, entries are only kept in memory.
+func NewAuditLog(path string) *AuditLog {
+	if path != "" {
+		os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	return &AuditLog{path: path}
+}
+
+func (a *AuditLog) Record(entry DeniedRequest) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.recent = append(a.recent, entry)
+	if len(a.recent) > auditRingSize {
+		a.recent = a.recent[len(a.recent)-auditRingSize:]
+	}
+
+	if a.path == "" {
+		return
+	}
+	if info, err := os.Stat(a.path); err == nil && info.Size() > auditMaxFileLen {
+		os.Rename(a.path, a.path+".1")
+	}
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Warn("proxy audit: failed to open %s: %v", a.path, err)
+		return
+	}
+	defer f.Close()
+	data, _ := json.Marshal(entry)
+	f.Write(append(data, '\n'))
+}
+
+// Recent returns up to n of the most recent denied requests, oldest first.
+func (a *AuditLog) Recent(n int) []DeniedRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if n <= 0 || n > len(a.recent) {
+		n = len(a.recent)
+	}
+	out := make([]DeniedRequest, n)
+	copy(out, a.recent[len(a.recent)-n:])
+	return out
+}
+
+// ReadAuditFile returns the last n entries from an audit log file.
+func ReadAuditFile(path string, n int) ([]DeniedRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []DeniedRequest
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e DeniedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+		if n > 0 && len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errPrivateResolution is returned when a public hostname resolves to a
+// private or loopback address, which is how DNS rebinding and SSRF through a
+// whitelisted name usually look.
+var errPrivateResolution = errors.New("hostname resolves to a private address")
+
+// dialContext resolves the target itself and dials the verified IP, so the
+// address that was checked is the address that gets connected to.
+func (p *Proxy) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return p.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := p.lookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+
+	if !isPrivate(host) && !p.privateOK.Allowed(host, "") {
+		for _, ip := range ips {
+			if isPrivateIP(ip) {
+				return nil, fmt.Errorf("%s -> %s: %w", host, ip, errPrivateResolution)
+			}
+		}
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := p.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func defaultLookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,28 +12,60 @@ import (
 	"localagent/pkg/logger"
 )
 
+// DefaultRateLimit is the per-host request limit per minute.
+const DefaultRateLimit = 300
+
 type Proxy struct {
 	whitelist *Whitelist
+	privateOK *Whitelist // hosts allowed to resolve to private addresses
+	audit     *AuditLog
+	limiter   *hostLimiter
 	listener  net.Listener
 	server    *http.Server
 	direct    *http.Transport
+	dialer    *net.Dialer
+	lookupIP  func(ctx context.Context, host string) ([]net.IP, error)
 }
 
 func New(wl *Whitelist) *Proxy {
 	p := &Proxy{
 		whitelist: wl,
-		direct: &http.Transport{
-			Proxy:                 nil, // no proxy — avoids loop
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
+		privateOK: NewWhitelist(),
+		audit:     NewAuditLog(""),
+		limiter:   newHostLimiter(DefaultRateLimit),
+		dialer:    &net.Dialer{Timeout: 10 * time.Second},
+		lookupIP:  defaultLookupIP,
+	}
+	p.direct = &http.Transport{
+		Proxy:                 nil, // no proxy — avoids loop
+		DialContext:           p.dialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
 	}
 	p.server = &http.Server{Handler: p}
 	return p
 }
 
+// AllowPrivate lets the given host patterns resolve to private addresses,
+// e.g. a Home Assistant instance reached by its LAN hostname. Every other
+// hostname that resolves to a private range is refused at dial time.
+// Ports are ignored, since the check happens on the resolved hostname.
+func (p *Proxy) AllowPrivate(patterns ...string) {
+	p.privateOK.addHosts(patterns...)
+}
+
+// SetRateLimit sets the per-host limit in requests per minute.
+// A value <= 0 disables rate limiting.
+func (p *Proxy) SetRateLimit(perMinute int) {
+	if perMinute <= 0 {
+		p.limiter = nil
+		return
+	}
+	p.limiter = newHostLimiter(perMinute)
+}
+
 func (p *Proxy) Start() error {
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -55,6 +88,44 @@ func (p *Proxy) Whitelist() *Whitelist {
 	return p.whitelist
 }
 
+// SetAuditLog replaces the audit log used to record denied requests.
+func (p *Proxy) SetAuditLog(a *AuditLog) {
+	p.audit = a
+}
+
+func (p *Proxy) AuditLog() *AuditLog {
+	return p.audit
+}
+
+func (p *Proxy) recordDenied(r *http.Request, host, path, reason string) {
+	p.audit.Record(DeniedRequest{
+		Method: r.Method,
+		Host:   host,
+		Path:   path,
+		Tool:   r.Header.Get(ToolHeader),
+		Reason: reason,
+	})
+}
+
+// rateLimited reports (and records) whether the request exceeds the per-host limit.
+func (p *Proxy) rateLimited(w http.ResponseWriter, r *http.Request, host, path string) bool {
+	limiter := p.limiter
+	if limiter == nil {
+		return false
+	}
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	if limiter.Allow(name) {
+		return false
+	}
+	logger.Warn("proxy rate limit exceeded: %s (tool=%s)", host, r.Header.Get(ToolHeader))
+	p.recordDenied(r, host, path, "rate limit")
+	http.Error(w, "Too many requests to this host", http.StatusTooManyRequests)
+	return true
+}
+
 func (p *Proxy) Stop(ctx context.Context) error {
 	if ctx == nil {
 		ctx = context.Background()
@@ -73,18 +144,28 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
 	host := r.Host
 	if !p.whitelist.Allowed(host, "") {
-		logger.Info("proxy CONNECT denied: %s", host)
+		logger.Info("proxy CONNECT denied: %s (tool=%s)", host, r.Header.Get(ToolHeader))
+		p.recordDenied(r, host, "", "whitelist")
 		http.Error(w, "Forbidden by domain whitelist", http.StatusForbidden)
 		return
 	}
+	if p.rateLimited(w, r, host, "") {
+		return
+	}
 
 	// Ensure host has a port
 	if _, _, err := net.SplitHostPort(host); err != nil {
 		host = net.JoinHostPort(host, "443")
 	}
 
-	target, err := net.DialTimeout("tcp", host, 10*time.Second)
+	target, err := p.dialContext(r.Context(), "tcp", host)
 	if err != nil {
+		if errors.Is(err, errPrivateResolution) {
+			logger.Warn("proxy CONNECT blocked: %v (tool=%s)", err, r.Header.Get(ToolHeader))
+			p.recordDenied(r, r.Host, "", "private address")
+			http.Error(w, "Forbidden: host resolves to a private address", http.StatusForbidden)
+			return
+		}
 		http.Error(w, fmt.Sprintf("dial target: %v", err), http.StatusBadGateway)
 		return
 	}
@@ -121,10 +202,14 @@ func (p *Proxy) handleHTTP(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 
 	if !p.whitelist.Allowed(host, path) {
-		logger.Info("proxy HTTP denied: %s%s", host, path)
+		logger.Info("proxy HTTP denied: %s%s (tool=%s)", host, path, r.Header.Get(ToolHeader))
+		p.recordDenied(r, host, path, "whitelist")
 		http.Error(w, "Forbidden by domain whitelist", http.StatusForbidden)
 		return
 	}
+	if p.rateLimited(w, r, host, path) {
+		return
+	}
 
 	// Build outgoing request
 	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), r.Body)
@@ -133,9 +218,16 @@ func (p *Proxy) handleHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	outReq.Header = r.Header.Clone()
+	outReq.Header.Del(ToolHeader)
 
 	resp, err := p.direct.RoundTrip(outReq)
 	if err != nil {
+		if errors.Is(err, errPrivateResolution) {
+			logger.Warn("proxy HTTP blocked: %v (tool=%s)", err, r.Header.Get(ToolHeader))
+			p.recordDenied(r, host, path, "private address")
+			http.Error(w, "Forbidden: host resolves to a private address", http.StatusForbidden)
+			return
+		}
 		http.Error(w, fmt.Sprintf("upstream: %v", err), http.StatusBadGateway)
 		return
 	}
@@ -1,12 +1,17 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWhitelist_ExactDomain(t *testing.T) {
@@ -250,3 +255,269 @@ func TestProxy_LocalhostBypass(t *testing.T) {
 		t.Errorf("expected 200 for localhost, got %d", resp.StatusCode)
 	}
 }
+
+func TestWhitelist_Remove(t *testing.T) {
+	wl := NewWhitelist()
+	wl.Add("api.example.com", "*.github.com")
+
+	if !wl.Remove("https://api.example.com") {
+		t.Fatal("expected Remove to report removal")
+	}
+	if wl.Allowed("api.example.com", "/") {
+		t.Error("expected api.example.com to be denied after removal")
+	}
+	if !wl.Allowed("raw.github.com", "/") {
+		t.Error("expected *.github.com to remain")
+	}
+	if wl.Remove("missing.example.com") {
+		t.Error("expected Remove of unknown pattern to return false")
+	}
+}
+
+func TestProxy_DeniedRequestsAudited(t *testing.T) {
+	wl := NewWhitelist()
+
+	p := New(wl)
+	p.SetAuditLog(NewAuditLog(filepath.Join(t.TempDir(), "denied.jsonl")))
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(nil)
+
+	proxyURL, _ := url.Parse(p.Addr())
+	transport := &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	AttributeTools(transport)
+	client := &http.Client{Transport: transport}
+
+	req, _ := http.NewRequestWithContext(WithTool(context.Background(), "web_fetch"), "GET", "https://example.com/", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected error for denied CONNECT")
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.org/secret", nil)
+	req.Header.Set(ToolHeader, "http_tool")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	recent := p.AuditLog().Recent(0)
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(recent))
+	}
+	if recent[0].Host != "example.com:443" || recent[0].Tool != "web_fetch" {
+		t.Errorf("unexpected CONNECT entry: %+v", recent[0])
+	}
+	if recent[1].Host != "example.org" || recent[1].Path != "/secret" || recent[1].Tool != "http_tool" {
+		t.Errorf("unexpected HTTP entry: %+v", recent[1])
+	}
+
+	fromFile, err := ReadAuditFile(p.audit.path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fromFile) != 2 {
+		t.Errorf("expected 2 entries in audit file, got %d", len(fromFile))
+	}
+}
+
+func TestAdminHandler_AddRemove(t *testing.T) {
+	p := New(NewWhitelist())
+
+	var persisted []string
+	h := AdminHandler(p, func(add, remove string) error {
+		persisted = append(persisted, "+"+add+"-"+remove)
+		return nil
+	})
+
+	req := httptest.NewRequest("POST", "/proxy/whitelist", strings.NewReader(`{"pattern":"api.example.com"}`))
+	req.RemoteAddr = "127.0.0.1:5000"
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("add: expected 200, got %d", rec.Code)
+	}
+	if !p.Whitelist().Allowed("api.example.com", "/") {
+		t.Error("expected pattern to be added")
+	}
+
+	req = httptest.NewRequest("DELETE", "/proxy/whitelist?pattern=api.example.com", nil)
+	req.RemoteAddr = "127.0.0.1:5000"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("remove: expected 200, got %d", rec.Code)
+	}
+	if p.Whitelist().Allowed("api.example.com", "/") {
+		t.Error("expected pattern to be removed")
+	}
+	if len(persisted) != 2 || persisted[0] != "+api.example.com-" || persisted[1] != "+-api.example.com" {
+		t.Errorf("unexpected persist calls: %v", persisted)
+	}
+
+	req = httptest.NewRequest("GET", "/proxy/whitelist", nil)
+	req.RemoteAddr = "192.0.2.10:5000"
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for non-loopback client, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_RefusesCrossSite(t *testing.T) {
+	p := New(NewWhitelist())
+	persisted := 0
+	h := AdminHandler(p, func(add, remove string) error {
+		persisted++
+		return nil
+	})
+	p.Whitelist().Add("api.example.com")
+
+	send := func(method, target, body string, header map[string]string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:5000"
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := send("POST", "/proxy/whitelist", `{"pattern":"evil.com"}`, map[string]string{"Content-Type": "text/plain"}); code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain add: expected 415, got %d", code)
+	}
+	crossSite := []map[string]string{
+		{"Content-Type": "application/json", "Origin": "https://evil.example"},
+		{"Content-Type": "application/json", "Sec-Fetch-Site": "cross-site"},
+	}
+	for _, header := range crossSite {
+		if code := send("POST", "/proxy/whitelist", `{"pattern":"evil.com"}`, header); code != http.StatusForbidden {
+			t.Errorf("add with %v: expected 403, got %d", header, code)
+		}
+		if code := send("DELETE", "/proxy/whitelist?pattern=api.example.com", "", header); code != http.StatusForbidden {
+			t.Errorf("remove with %v: expected 403, got %d", header, code)
+		}
+	}
+	if p.Whitelist().Allowed("evil.com", "/") || !p.Whitelist().Allowed("api.example.com", "/") {
+		t.Errorf("whitelist changed: %v", p.Whitelist().List())
+	}
+	if persisted != 0 {
+		t.Errorf("expected no persist calls, got %d", persisted)
+	}
+}
+
+func TestProxy_BlocksPrivateResolution(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	wl := NewWhitelist()
+	wl.Add("rebind.example.com", "lan.example.com")
+
+	p := New(wl)
+	p.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	}
+	// Service URLs carry their port; the dial-time check must still match.
+	p.AllowPrivate("lan.example.com:8123", "http://nas.example.com:2376")
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(nil)
+
+	proxyURL, _ := url.Parse(p.Addr())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get("http://rebind.example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for public name resolving to loopback, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get("http://lan.example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for explicitly allowed private host, got %d", resp.StatusCode)
+	}
+
+	wl.Add("nas.example.com")
+	resp, err = client.Get("http://nas.example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for private host registered with a port, got %d", resp.StatusCode)
+	}
+
+	recent := p.AuditLog().Recent(0)
+	if len(recent) != 1 || recent[0].Reason != "private address" {
+		t.Errorf("expected one private address audit entry, got %+v", recent)
+	}
+}
+
+func TestProxy_RateLimit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	p := New(NewWhitelist())
+	p.SetRateLimit(2)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(nil)
+
+	proxyURL, _ := url.Parse(p.Addr())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	var codes []int
+	for range 3 {
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("expected 200, 200, 429; got %v", codes)
+	}
+}
+
+func TestHostLimiter_Refills(t *testing.T) {
+	now := time.Now()
+	l := newHostLimiter(60)
+	l.now = func() time.Time { return now }
+
+	for range 60 {
+		if !l.Allow("api.example.com") {
+			t.Fatal("expected burst to be allowed")
+		}
+	}
+	if l.Allow("api.example.com") {
+		t.Error("expected limit to be reached")
+	}
+	if !l.Allow("other.example.com") {
+		t.Error("expected limits to be per host")
+	}
+
+	now = now.Add(time.Second)
+	if !l.Allow("api.example.com") {
+		t.Error("expected a token after one second")
+	}
+}
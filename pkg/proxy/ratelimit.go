@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter is a per-host token bucket. It caps how many requests a single
+// destination can receive per minute so a tool stuck in a loop cannot hammer
+// an upstream API.
+type hostLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens per second
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(perMinute int) *hostLimiter {
+	return &hostLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (l *hostLimiter) Allow(host string) bool {
+	host = strings.ToLower(host)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -1,7 +1,6 @@
 package proxy
 
 import (
-	"fmt"
 	"net"
 	"strings"
 	"sync"
@@ -35,6 +34,41 @@ func (wl *Whitelist) Add(patterns ...string) {
 	}
 }
 
+// addHosts adds patterns with any port dropped, so they match the host
+// whatever port it is dialed on.
+func (wl *Whitelist) addHosts(patterns ...string) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	for _, raw := range patterns {
+		if raw == "" {
+			continue
+		}
+		p := parsePattern(raw)
+		p.port = ""
+		wl.patterns = append(wl.patterns, p)
+	}
+}
+
+// Remove deletes all entries equal to the given pattern (after normalization).
+// Returns true if anything was removed.
+func (wl *Whitelist) Remove(pattern string) bool {
+	target := parsePattern(pattern)
+
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	kept := wl.patterns[:0]
+	for _, p := range wl.patterns {
+		if p != target {
+			kept = append(kept, p)
+		}
+	}
+	removed := len(kept) < len(wl.patterns)
+	wl.patterns = kept
+	return removed
+}
+
 func parsePattern(raw string) Pattern {
 	p := Pattern{}
 	raw = strings.ToLower(strings.TrimSpace(raw))
@@ -119,24 +153,14 @@ func isPrivate(host string) bool {
 	if ip == nil {
 		return false
 	}
-	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
-		return true
-	}
-	privateRanges := []struct {
-		network string
-		mask    int
-	}{
-		{"10.0.0.0", 8},
-		{"172.16.0.0", 12},
-		{"192.168.0.0", 16},
-	}
-	for _, r := range privateRanges {
-		_, cidr, _ := net.ParseCIDR(fmt.Sprintf("%s/%d", r.network, r.mask))
-		if cidr.Contains(ip) {
-			return true
-		}
-	}
-	return false
+	return isPrivateIP(ip)
+}
+
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || cgnatRange.Contains(ip)
 }
 
 func (wl *Whitelist) List() []string {
@@ -0,0 +1,140 @@
+// Package readstate tracks, per chat, which assistant messages were
+// delivered and when the user last read the chat, so proactive messages can
+// refer to earlier ones the user has not seen yet.
+package readstate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/utils"
+)
+
+const (
+	stateNamespace = "readstate"
+	// maxDeliveries is how many recent deliveries are kept per chat.
+	maxDeliveries = 10
+	previewLen    = 120
+)
+
+// Delivery is an assistant message handed to a channel.
+type Delivery struct {
+	At      time.Time `json:"at"`
+	Preview string    `json:"preview"`
+}
+
+// Chat is the read state of one channel:chatID.
+type Chat struct {
+	LastRead  time.Time  `json:"last_read,omitzero"`
+	Delivered []Delivery `json:"delivered,omitempty"` // oldest first
+}
+
+// Unread returns deliveries made after the chat was last read.
+func (c Chat) Unread() []Delivery {
+	var out []Delivery
+	for _, d := range c.Delivered {
+		if d.At.After(c.LastRead) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Tracker persists read state in the workspace state under readstate/.
+type Tracker struct {
+	state *state.Manager
+	mu    sync.Mutex
+	now   func() time.Time
+}
+
+func NewTracker(workspace string) *Tracker {
+	return &Tracker{state: state.NewManager(workspace), now: time.Now}
+}
+
+func chatKey(channel, chatID string) string {
+	return channel + ":" + chatID
+}
+
+// Get returns the read state of a chat.
+func (t *Tracker) Get(channel, chatID string) Chat {
+	var c Chat
+	if _, err := t.state.Get(stateNamespace, chatKey(channel, chatID), &c); err != nil {
+		logger.Warn("readstate: %v", err)
+	}
+	return c
+}
+
+// Delivered records an assistant message that a channel accepted at.
+func (t *Tracker) Delivered(channel, chatID, content string, at time.Time) {
+	t.update(channel, chatID, func(c *Chat) {
+		c.Delivered = append(c.Delivered, Delivery{At: at, Preview: utils.Truncate(oneLine(content), previewLen)})
+		if len(c.Delivered) > maxDeliveries {
+			c.Delivered = c.Delivered[len(c.Delivered)-maxDeliveries:]
+		}
+	})
+}
+
+// MarkRead records that the user has seen everything in the chat up to
+// now: the chat was visible, a read receipt arrived or the user replied.
+func (t *Tracker) MarkRead(channel, chatID string) {
+	now := t.now()
+	t.update(channel, chatID, func(c *Chat) {
+		if now.After(c.LastRead) {
+			c.LastRead = now
+		}
+	})
+}
+
+func (t *Tracker) update(channel, chatID string, fn func(*Chat)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.Get(channel, chatID)
+	fn(&c)
+	if err := t.state.Set(stateNamespace, chatKey(channel, chatID), c); err != nil {
+		logger.Warn("readstate: save: %v", err)
+	}
+}
+
+// Describe summarizes a chat's read state for a prompt, or returns "" when
+// there is nothing to report.
+func (c Chat) Describe(now time.Time) string {
+	unread := c.Unread()
+	if len(unread) == 0 {
+		if c.LastRead.IsZero() {
+			return ""
+		}
+		return fmt.Sprintf("Read state: the user has seen all your messages (last read %s).", formatAgo(now, c.LastRead))
+	}
+	var b strings.Builder
+	b.WriteString("Read state: the user has not seen these earlier messages of yours yet")
+	if !c.LastRead.IsZero() {
+		fmt.Fprintf(&b, " (last read %s)", formatAgo(now, c.LastRead))
+	}
+	b.WriteString(":")
+	for _, d := range unread {
+		fmt.Fprintf(&b, "\n- [%s] %s", d.At.Local().Format("2006-01-02 15:04"), d.Preview)
+	}
+	b.WriteString("\nOnly mention a missed message if it is in this list.")
+	return b.String()
+}
+
+func formatAgo(now, t time.Time) string {
+	d := now.Sub(t).Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
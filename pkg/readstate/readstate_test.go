@@ -0,0 +1,38 @@
+package readstate
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnreadAfterLastRead(t *testing.T) {
+	ws := t.TempDir()
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	tr := NewTracker(ws)
+	tr.now = func() time.Time { return now }
+
+	tr.Delivered("telegram", "1", "Your package\narrives today", now.Add(-time.Hour))
+	tr.MarkRead("telegram", "1")
+	tr.Delivered("telegram", "1", "Dentist moved to 3pm", now.Add(time.Minute))
+
+	// Reloaded from state, as the heartbeat would see it.
+	c := NewTracker(ws).Get("telegram", "1")
+	unread := c.Unread()
+	if len(unread) != 1 || unread[0].Preview != "Dentist moved to 3pm" {
+		t.Fatalf("unexpected unread: %+v", unread)
+	}
+	desc := c.Describe(now.Add(10 * time.Minute))
+	if !strings.Contains(desc, "Dentist moved to 3pm") || strings.Contains(desc, "package") || !strings.Contains(desc, "10 minutes ago") {
+		t.Errorf("unexpected description: %q", desc)
+	}
+
+	now = now.Add(time.Hour)
+	tr.MarkRead("telegram", "1")
+	if got := tr.Get("telegram", "1").Unread(); len(got) != 0 {
+		t.Errorf("expected nothing unread after reading, got %+v", got)
+	}
+	if tr.Get("web", "default").Describe(now) != "" {
+		t.Error("unknown chat should describe as empty")
+	}
+}
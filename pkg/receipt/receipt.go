@@ -0,0 +1,121 @@
+// Package receipt reads the vendor, date, line items and totals from a
+// photo of a receipt with a vision-capable model.
+package receipt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"localagent/pkg/prompts"
+	"localagent/pkg/providers"
+	"localagent/pkg/utils"
+)
+
+// maxImageBytes bounds the photo sent to the model.
+const maxImageBytes = 20 << 20
+
+// ErrNotReceipt is returned when the model finds no receipt in the image.
+var ErrNotReceipt = errors.New("the image is not a readable receipt")
+
+type Item struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	Amount      float64 `json:"amount"` // line total; negative for discounts
+}
+
+type Receipt struct {
+	Vendor   string  `json:"vendor"`
+	Date     string  `json:"date,omitempty"` // YYYY-MM-DD
+	Currency string  `json:"currency,omitempty"`
+	Items    []Item  `json:"items"`
+	Subtotal float64 `json:"subtotal,omitempty"`
+	Tax      float64 `json:"tax,omitempty"`
+	Total    float64 `json:"total"`
+}
+
+// ItemsAddUp reports whether the line items sum to the subtotal, or to the
+// total when there is no subtotal, within a cent. Misread digits usually
+// show up here.
+func (r *Receipt) ItemsAddUp() bool {
+	var sum float64
+	for _, it := range r.Items {
+		sum += it.Amount
+	}
+	want := r.Subtotal
+	if want == 0 {
+		want = r.Total
+	}
+	return len(r.Items) == 0 || math.Abs(sum-want) < 0.011 || math.Abs(sum+r.Tax-want) < 0.011
+}
+
+// Parser extracts receipts with one model call per photo.
+type Parser struct {
+	provider providers.LLMProvider
+	model    string
+}
+
+func New(provider providers.LLMProvider, model string) *Parser {
+	return &Parser{provider: provider, model: model}
+}
+
+// Parse reads the receipt in the image at path.
+func (p *Parser) Parse(ctx context.Context, path string) (*Receipt, error) {
+	if !utils.IsImageFile(path) {
+		return nil, fmt.Errorf("%s is not an image", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxImageBytes {
+		return nil, fmt.Errorf("image is over the %d MB limit", maxImageBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dataURL := fmt.Sprintf("data:%s;base64,%s", utils.DetectMIMEType(path), base64.StdEncoding.EncodeToString(data))
+	resp, err := p.provider.Chat(ctx, []providers.Message{{
+		Role: "user",
+		ContentParts: []providers.ContentPart{
+			{Type: "text", Text: strings.TrimSpace(prompts.ReceiptExtract)},
+			{Type: "image_url", ImageURL: &providers.ImageURL{URL: dataURL}},
+		},
+	}}, nil, p.model, map[string]any{
+		"max_tokens":  2048,
+		"temperature": 0.0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decode(resp.Content)
+}
+
+// decode parses the model's answer, which may be wrapped in a code fence
+// or prose despite the prompt.
+func decode(answer string) (*Receipt, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("model answered without JSON: %s", utils.Truncate(answer, 200))
+	}
+	var out struct {
+		Receipt
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("model answered with invalid JSON: %w", err)
+	}
+	if out.Error != "" || (out.Total == 0 && len(out.Items) == 0) {
+		return nil, ErrNotReceipt
+	}
+	r := out.Receipt
+	r.Vendor = strings.TrimSpace(r.Vendor)
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	return &r, nil
+}
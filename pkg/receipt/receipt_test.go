@@ -0,0 +1,67 @@
+package receipt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localagent/pkg/providers"
+)
+
+// fakeVision answers with a fixed reply and records the image it was sent.
+type fakeVision struct {
+	reply string
+	image string
+	model string
+}
+
+func (p *fakeVision) Chat(_ context.Context, messages []providers.Message, _ []providers.ToolDefinition, model string, _ map[string]any) (*providers.LLMResponse, error) {
+	p.model = model
+	for _, part := range messages[0].ContentParts {
+		if part.ImageURL != nil {
+			p.image = part.ImageURL.URL
+		}
+	}
+	return &providers.LLMResponse{Content: p.reply}, nil
+}
+
+func (p *fakeVision) GetDefaultModel() string { return "" }
+
+func TestParse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "receipt.jpg")
+	os.WriteFile(path, []byte("jpeg"), 0644)
+	p := &fakeVision{reply: "Here you go:\n```json\n" +
+		`{"vendor": " Migros ", "date": "2026-10-14", "currency": "chf", "items": [` +
+		`{"description": "Milk", "quantity": 2, "amount": 3.40}, {"description": "Bread", "amount": 4.20}, {"description": "Action", "amount": -1.00}],` +
+		` "total": 6.60}` + "\n```"}
+
+	r, err := New(p, "vision").Parse(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Vendor != "Migros" || r.Currency != "CHF" || r.Date != "2026-10-14" || len(r.Items) != 3 || r.Total != 6.60 {
+		t.Errorf("receipt = %+v", r)
+	}
+	if !r.ItemsAddUp() {
+		t.Error("items should add up to the total")
+	}
+	if p.model != "vision" || !strings.HasPrefix(p.image, "data:image/jpeg;base64,") {
+		t.Errorf("sent model %q, image %.30q", p.model, p.image)
+	}
+
+	r.Items[0].Amount = 34.0
+	if r.ItemsAddUp() {
+		t.Error("a misread amount should not add up")
+	}
+
+	p.reply = `{"error": "not a receipt"}`
+	if _, err := New(p, "vision").Parse(context.Background(), path); !errors.Is(err, ErrNotReceipt) {
+		t.Errorf("err = %v, want ErrNotReceipt", err)
+	}
+	if _, err := New(p, "vision").Parse(context.Background(), filepath.Join(t.TempDir(), "notes.txt")); err == nil {
+		t.Error("a non-image should fail")
+	}
+}
@@ -0,0 +1,334 @@
+package recipes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"localagent/pkg/httpclient"
+)
+
+// ErrNoRecipe means the page has no schema.org Recipe markup.
+var ErrNoRecipe = errors.New("no recipe data found on the page")
+
+// Import fetches url and extracts its recipe. The recipe is not saved.
+func Import(ctx context.Context, client *http.Client, url string) (*Recipe, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, withWhitelistHint(err, req.URL.Hostname())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, withWhitelistHint(fmt.Errorf("HTTP %d", resp.StatusCode), req.URL.Hostname())
+	}
+	body, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	r.SourceURL = url
+	return r, nil
+}
+
+// withWhitelistHint explains a 403 from the egress proxy: recipe sites
+// are arbitrary hosts, so the user has to allow them first.
+func withWhitelistHint(err error, host string) error {
+	msg := err.Error()
+	if strings.Contains(msg, "Forbidden") || strings.Contains(msg, "403") {
+		return fmt.Errorf("%w (if %s is not in allowed_domains, run: localagent proxy add %s)", err, host, host)
+	}
+	return err
+}
+
+// Parse extracts a recipe from an HTML page: schema.org Recipe JSON-LD,
+// which most recipe sites embed, or else recipe microdata.
+func Parse(page io.Reader) (*Recipe, error) {
+	doc, err := html.Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	var scripts []string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "script" && attr(n, "type") == "application/ld+json" {
+			scripts = append(scripts, textContent(n))
+		}
+		return true
+	})
+	for _, s := range scripts {
+		var v any
+		if json.Unmarshal([]byte(s), &v) != nil {
+			continue
+		}
+		if obj := findRecipe(v); obj != nil {
+			if r := fromJSONLD(obj); r.Title != "" && len(r.Ingredients) > 0 {
+				return r, nil
+			}
+		}
+	}
+	var scope *html.Node
+	walk(doc, func(n *html.Node) bool {
+		if scope == nil && n.Type == html.ElementNode && strings.HasSuffix(attr(n, "itemtype"), "schema.org/Recipe") {
+			scope = n
+		}
+		return scope == nil
+	})
+	if scope != nil {
+		if r := fromMicrodata(scope); r.Title != "" && len(r.Ingredients) > 0 {
+			return r, nil
+		}
+	}
+	return nil, ErrNoRecipe
+}
+
+// findRecipe searches decoded JSON-LD for an object of @type Recipe,
+// including inside @graph and arrays.
+func findRecipe(v any) map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if r := findRecipe(item); r != nil {
+				return r
+			}
+		}
+	case map[string]any:
+		if isType(v["@type"], "Recipe") {
+			return v
+		}
+		if g, ok := v["@graph"]; ok {
+			return findRecipe(g)
+		}
+	}
+	return nil
+}
+
+func isType(t any, want string) bool {
+	switch t := t.(type) {
+	case string:
+		return t == want
+	case []any:
+		for _, s := range t {
+			if s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fromJSONLD(obj map[string]any) *Recipe {
+	r := &Recipe{
+		Title:       cleanText(str(obj["name"])),
+		Servings:    parseYield(obj["recipeYield"]),
+		Ingredients: textList(obj["recipeIngredient"]),
+		Steps:       instructions(obj["recipeInstructions"]),
+		Notes:       cleanText(str(obj["description"])),
+	}
+	if len(r.Ingredients) == 0 {
+		r.Ingredients = textList(obj["ingredients"]) // older schema.org name
+	}
+	var tags []string
+	for _, key := range []string{"recipeCategory", "recipeCuisine", "keywords"} {
+		switch v := obj[key].(type) {
+		case string:
+			tags = append(tags, strings.Split(v, ",")...)
+		case []any:
+			tags = append(tags, textList(v)...)
+		}
+	}
+	r.Tags = normalizeTags(tags)
+	if len(r.Tags) > 8 {
+		r.Tags = r.Tags[:8] // keywords are often SEO lists
+	}
+	return r
+}
+
+// instructions flattens recipeInstructions: a string, a list of strings,
+// HowToStep objects or HowToSection objects holding steps.
+func instructions(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return toStringList(cleanText(strings.ReplaceAll(v, "<br>", "\n")))
+	case []any:
+		var steps []string
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				if s := cleanText(item); s != "" {
+					steps = append(steps, s)
+				}
+			case map[string]any:
+				if list, ok := item["itemListElement"]; ok {
+					steps = append(steps, instructions(list)...)
+				} else if s := cleanText(str(item["text"])); s != "" {
+					steps = append(steps, s)
+				} else if s := cleanText(str(item["name"])); s != "" {
+					steps = append(steps, s)
+				}
+			}
+		}
+		return steps
+	case map[string]any:
+		return instructions([]any{v})
+	}
+	return nil
+}
+
+var leadingNumber = regexp.MustCompile(`\d+`)
+
+// parseYield reads servings from recipeYield: 4, "4", "4 servings",
+// "Serves 4-6" or a list of those.
+func parseYield(v any) int {
+	switch v := v.(type) {
+	case float64:
+		return int(v)
+	case string:
+		if m := leadingNumber.FindString(v); m != "" {
+			n, _ := strconv.Atoi(m)
+			return n
+		}
+	case []any:
+		for _, item := range v {
+			if n := parseYield(item); n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// fromMicrodata reads itemprop values inside the Recipe item scope.
+func fromMicrodata(scope *html.Node) *Recipe {
+	r := &Recipe{}
+	walk(scope, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch attr(n, "itemprop") {
+		case "name":
+			if r.Title == "" {
+				r.Title = cleanText(textContent(n))
+			}
+		case "recipeIngredient", "ingredients":
+			if s := cleanText(textContent(n)); s != "" {
+				r.Ingredients = append(r.Ingredients, s)
+			}
+			return false
+		case "recipeInstructions":
+			r.Steps = append(r.Steps, toStringList(blockText(n))...)
+			return false
+		case "recipeYield":
+			if r.Servings == 0 {
+				content := attr(n, "content")
+				if content == "" {
+					content = textContent(n)
+				}
+				r.Servings = parseYield(content)
+			}
+		}
+		return true
+	})
+	return r
+}
+
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// blockText is textContent with a line break after block elements, so
+// list items and paragraphs become separate steps.
+func blockText(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "li", "p", "div", "br", "h2", "h3", "h4":
+				b.WriteString("\n")
+			}
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+var spaceRe = regexp.MustCompile(`[ \t\r\f\v]+`)
+
+// cleanText unescapes entities, drops inline markup that some sites leave
+// in JSON-LD and collapses whitespace.
+func cleanText(s string) string {
+	if strings.Contains(s, "<") {
+		if doc, err := html.Parse(strings.NewReader(s)); err == nil {
+			s = blockText(doc)
+		}
+	}
+	s = html.UnescapeString(s)
+	s = spaceRe.ReplaceAllString(s, " ")
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func textList(v any) []string {
+	list, _ := v.([]any)
+	var out []string
+	for _, item := range list {
+		if s := cleanText(str(item)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package recipes
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ingredient is one parsed ingredient line. Quantity is 0 when the line
+// has none ("salt to taste").
+type Ingredient struct {
+	Quantity float64 `json:"quantity,omitempty"`
+	Unit     string  `json:"unit,omitempty"`
+	Name     string  `json:"name"`
+}
+
+var unitAliases = map[string]string{
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml",
+	"cl": "cl", "dl": "dl",
+	"l": "l", "liter": "l", "liters": "l", "litre": "l", "litres": "l",
+	"tsp": "tsp", "tsps": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"pinch": "pinch", "pinches": "pinch",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"slice": "slice", "slices": "slice",
+	"bunch": "bunch", "bunches": "bunch",
+	"sprig": "sprig", "sprigs": "sprig",
+	"handful": "handful", "handfuls": "handful",
+}
+
+// metric converts mass and volume units to grams and millilitres so
+// "500 g" and "1 kg" add up on the shopping list.
+var metric = map[string]struct {
+	base   string
+	factor float64
+}{
+	"mg": {"g", 0.001},
+	"kg": {"g", 1000},
+	"cl": {"ml", 10},
+	"dl": {"ml", 100},
+	"l":  {"ml", 1000},
+}
+
+var vulgarFractions = strings.NewReplacer(
+	"½", " 1/2", "⅓", " 1/3", "⅔", " 2/3", "¼", " 1/4", "¾", " 3/4", "⅛", " 1/8",
+)
+
+// quantityRe matches a leading quantity: 2, 1.5, 1,5, 1/2, 1 1/2 and an
+// optional range upper bound (2-3, 2 to 3).
+var quantityRe = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?)(?:\s*(?:-|–|to)\s*(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?))?`)
+
+// ParseIngredient splits a line like "1 1/2 cups flour, sifted" into
+// quantity, unit and name. For ranges the upper bound is used so the
+// shopping list errs on the side of enough.
+func ParseIngredient(line string) Ingredient {
+	s := strings.TrimSpace(vulgarFractions.Replace(line))
+	s = strings.TrimLeft(s, "-*• ")
+	var ing Ingredient
+	if m := quantityRe.FindStringSubmatch(s); m != nil {
+		q := m[1]
+		if m[2] != "" {
+			q = m[2]
+		}
+		ing.Quantity = parseNumber(q)
+		s = strings.TrimSpace(s[len(m[0]):])
+	}
+	if ing.Quantity > 0 {
+		word, rest, _ := strings.Cut(s, " ")
+		if unit, ok := unitAliases[strings.ToLower(strings.TrimSuffix(word, "."))]; ok {
+			ing.Unit = unit
+			s = strings.TrimSpace(rest)
+			s = strings.TrimPrefix(s, "of ")
+		}
+	}
+	ing.Name = strings.TrimSpace(s)
+	return ing
+}
+
+func parseNumber(s string) float64 {
+	var total float64
+	for _, part := range strings.Fields(s) {
+		if num, den, ok := strings.Cut(part, "/"); ok {
+			n, _ := strconv.ParseFloat(num, 64)
+			d, _ := strconv.ParseFloat(den, 64)
+			if d != 0 {
+				total += n / d
+			}
+			continue
+		}
+		n, _ := strconv.ParseFloat(strings.Replace(part, ",", ".", 1), 64)
+		total += n
+	}
+	return total
+}
+
+// Scaled returns the ingredient with its quantity multiplied by factor.
+func (i Ingredient) Scaled(factor float64) Ingredient {
+	i.Quantity *= factor
+	return i
+}
+
+// Key identifies the ingredient for merging: the lowercased name without
+// preparation notes ("onion, chopped" and "Onion" merge).
+func (i Ingredient) Key() string {
+	name, _, _ := strings.Cut(strings.ToLower(i.Name), ",")
+	if j := strings.Index(name, "("); j > 0 {
+		name = name[:j]
+	}
+	return strings.TrimSpace(name)
+}
+
+func (i Ingredient) String() string {
+	if i.Quantity == 0 {
+		return i.Name
+	}
+	q := FormatQuantity(i.Quantity, i.Unit)
+	if i.Unit == "" {
+		return q + " " + i.Name
+	}
+	return q + " " + i.Unit + " " + i.Name
+}
+
+// FormatQuantity prints metric amounts as whole numbers and other
+// amounts with common fractions (1 1/2 cups) where they fit.
+func FormatQuantity(q float64, unit string) string {
+	switch unit {
+	case "g", "ml":
+		if q >= 10 {
+			return strconv.FormatFloat(math.Round(q), 'f', -1, 64)
+		}
+	}
+	whole, frac := math.Modf(q)
+	if frac < 0.02 {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	if frac > 0.98 {
+		return strconv.FormatFloat(whole+1, 'f', -1, 64)
+	}
+	for _, f := range []struct {
+		v float64
+		s string
+	}{{0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.5, "1/2"}, {2.0 / 3, "2/3"}, {0.75, "3/4"}} {
+		if math.Abs(frac-f.v) < 0.02 {
+			if whole == 0 {
+				return f.s
+			}
+			return fmt.Sprintf("%.0f %s", whole, f.s)
+		}
+	}
+	return strconv.FormatFloat(math.Round(q*100)/100, 'f', -1, 64)
+}
+
+// ScaleLines rescales ingredient lines by factor. Lines without a
+// quantity are kept as written.
+func ScaleLines(lines []string, factor float64) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		ing := ParseIngredient(line)
+		if ing.Quantity == 0 || factor == 1 {
+			out[i] = line
+			continue
+		}
+		out[i] = ing.Scaled(factor).String()
+	}
+	return out
+}
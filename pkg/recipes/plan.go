@@ -0,0 +1,347 @@
+package recipes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/todo"
+)
+
+const (
+	DefaultSlot     = "dinner"
+	DefaultServings = 2
+
+	// ShoppingTag marks the shopping list tasks.
+	ShoppingTag = "shopping"
+
+	// rotationWeeks is how far back Generate looks to avoid repeats.
+	rotationWeeks = 4
+)
+
+// Meal is one planned meal.
+type Meal struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Slot     string `json:"slot"` // breakfast, lunch, dinner...
+	RecipeID string `json:"recipeId"`
+	Title    string `json:"title"`
+	Servings int    `json:"servings"`
+}
+
+// Plan is the meal plan of one ISO week (YYYY-Www).
+type Plan struct {
+	Week        string `json:"week"`
+	Meals       []Meal `json:"meals"`
+	UpdatedAtMS int64  `json:"updatedAtMs"`
+}
+
+// PlanOptions controls Generate.
+type PlanOptions struct {
+	Days     int      // planned days from Monday, default 7
+	Servings int      // default DefaultServings
+	Slot     string   // default DefaultSlot
+	Tags     []string // only recipes with all these tags
+}
+
+// WeekName returns the ISO week of t as YYYY-Www.
+func WeekName(t time.Time) string {
+	y, w := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", y, w)
+}
+
+// Monday returns midnight of the Monday of t's week.
+func Monday(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// GetPlan returns the plan of week, or nil if none was made.
+func (s *Service) GetPlan(week string) *Plan {
+	row, err := s.q.GetMealPlan(context.Background(), week)
+	if err != nil {
+		return nil
+	}
+	p := Plan{Week: row.Week, UpdatedAtMS: row.UpdatedAtMs}
+	json.Unmarshal([]byte(row.Meals), &p.Meals)
+	return &p
+}
+
+func (s *Service) SavePlan(p *Plan) error {
+	sort.SliceStable(p.Meals, func(i, j int) bool {
+		if p.Meals[i].Date != p.Meals[j].Date {
+			return p.Meals[i].Date < p.Meals[j].Date
+		}
+		return slotOrder(p.Meals[i].Slot) < slotOrder(p.Meals[j].Slot)
+	})
+	p.UpdatedAtMS = s.now().UnixMilli()
+	meals, _ := json.Marshal(p.Meals)
+	if p.Meals == nil {
+		meals = []byte("[]")
+	}
+	return s.q.UpsertMealPlan(context.Background(), dbq.UpsertMealPlanParams{
+		Week:        p.Week,
+		Meals:       string(meals),
+		UpdatedAtMs: p.UpdatedAtMS,
+	})
+}
+
+func (s *Service) ClearPlan(week string) bool {
+	res, err := s.q.DeleteMealPlan(context.Background(), week)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// Generate fills opts.Slot for the days of the week containing day,
+// keeping meals in other slots. Recipes not planned in the last few weeks
+// come first, so the plan rotates through the collection.
+func (s *Service) Generate(day time.Time, opts PlanOptions) (*Plan, error) {
+	if opts.Days <= 0 || opts.Days > 7 {
+		opts.Days = 7
+	}
+	if opts.Servings <= 0 {
+		opts.Servings = DefaultServings
+	}
+	if opts.Slot == "" {
+		opts.Slot = DefaultSlot
+	}
+	candidates := s.List(opts.Tags...)
+	if len(candidates) == 0 {
+		if len(opts.Tags) > 0 {
+			return nil, fmt.Errorf("no recipes tagged %s", strings.Join(opts.Tags, ", "))
+		}
+		return nil, fmt.Errorf("no recipes saved yet")
+	}
+
+	monday := Monday(day)
+	lastUsed := make(map[string]string) // recipe ID -> latest date planned
+	for i := 1; i <= rotationWeeks; i++ {
+		if p := s.GetPlan(WeekName(monday.AddDate(0, 0, -7*i))); p != nil {
+			for _, m := range p.Meals {
+				if m.Date > lastUsed[m.RecipeID] {
+					lastUsed[m.RecipeID] = m.Date
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lastUsed[candidates[i].ID] < lastUsed[candidates[j].ID]
+	})
+
+	week := WeekName(monday)
+	plan := s.GetPlan(week)
+	if plan == nil {
+		plan = &Plan{Week: week}
+	}
+	kept := plan.Meals[:0]
+	for _, m := range plan.Meals {
+		if !strings.EqualFold(m.Slot, opts.Slot) {
+			kept = append(kept, m)
+		}
+	}
+	plan.Meals = kept
+	for i := range opts.Days {
+		r := candidates[i%len(candidates)]
+		plan.Meals = append(plan.Meals, Meal{
+			Date:     monday.AddDate(0, 0, i).Format("2006-01-02"),
+			Slot:     opts.Slot,
+			RecipeID: r.ID,
+			Title:    r.Title,
+			Servings: opts.Servings,
+		})
+	}
+	if err := s.SavePlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// SetMeal plans recipe for date and slot, replacing what was there.
+func (s *Service) SetMeal(date time.Time, slot string, r Recipe, servings int) (*Plan, error) {
+	if slot == "" {
+		slot = DefaultSlot
+	}
+	if servings <= 0 {
+		servings = DefaultServings
+	}
+	week := WeekName(date)
+	plan := s.GetPlan(week)
+	if plan == nil {
+		plan = &Plan{Week: week}
+	}
+	meal := Meal{Date: date.Format("2006-01-02"), Slot: strings.ToLower(slot), RecipeID: r.ID, Title: r.Title, Servings: servings}
+	replaced := false
+	for i, m := range plan.Meals {
+		if m.Date == meal.Date && strings.EqualFold(m.Slot, meal.Slot) {
+			plan.Meals[i] = meal
+			replaced = true
+		}
+	}
+	if !replaced {
+		plan.Meals = append(plan.Meals, meal)
+	}
+	return plan, s.SavePlan(plan)
+}
+
+// RemoveMeal drops the meal at date and slot; an empty slot drops the
+// whole day.
+func (s *Service) RemoveMeal(date time.Time, slot string) (*Plan, error) {
+	plan := s.GetPlan(WeekName(date))
+	day := date.Format("2006-01-02")
+	if plan == nil {
+		return nil, fmt.Errorf("nothing planned on %s", day)
+	}
+	kept := plan.Meals[:0]
+	for _, m := range plan.Meals {
+		if m.Date == day && (slot == "" || strings.EqualFold(m.Slot, slot)) {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	if len(kept) == len(plan.Meals) {
+		return nil, fmt.Errorf("nothing planned on %s %s", day, slot)
+	}
+	plan.Meals = kept
+	return plan, s.SavePlan(plan)
+}
+
+// FormatPlan renders a plan day by day.
+func FormatPlan(p Plan) string {
+	if len(p.Meals) == 0 {
+		return fmt.Sprintf("Meal plan %s is empty.", p.Week)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Meal plan %s:", p.Week)
+	for _, m := range p.Meals {
+		day := m.Date
+		if t, err := time.Parse("2006-01-02", m.Date); err == nil {
+			day = t.Format("Mon 2006-01-02")
+		}
+		fmt.Fprintf(&b, "\n%s %s: %s (%s, %d servings)", day, m.Slot, m.Title, m.RecipeID, m.Servings)
+	}
+	return b.String()
+}
+
+func slotOrder(slot string) int {
+	switch strings.ToLower(slot) {
+	case "breakfast":
+		return 0
+	case "lunch":
+		return 1
+	case "dinner":
+		return 3
+	}
+	return 2
+}
+
+// Item is one line of a shopping list.
+type Item struct {
+	Ingredient
+	Recipes []string `json:"recipes"`
+}
+
+func (i Item) String() string {
+	return fmt.Sprintf("%s (%s)", i.Ingredient, strings.Join(i.Recipes, ", "))
+}
+
+// ShoppingList adds up the ingredients of every planned meal, scaled to
+// its servings. Metric amounts are converted so they merge; amounts in
+// different kinds of units stay on separate lines.
+func (s *Service) ShoppingList(p Plan) []Item {
+	type key struct{ name, unit string }
+	items := make(map[key]*Item)
+	var order []key
+	for _, m := range p.Meals {
+		r := s.Get(m.RecipeID)
+		if r == nil {
+			continue
+		}
+		factor := 1.0
+		if r.Servings > 0 && m.Servings > 0 {
+			factor = float64(m.Servings) / float64(r.Servings)
+		}
+		for _, line := range r.Ingredients {
+			ing := ParseIngredient(line).Scaled(factor)
+			if c, ok := metric[ing.Unit]; ok {
+				ing.Quantity *= c.factor
+				ing.Unit = c.base
+			}
+			k := key{ing.Key(), ing.Unit}
+			if k.name == "" || k.name == "water" {
+				continue
+			}
+			name := k.name
+			// "1 onion" and "3 onions" are the same item, listed as onions.
+			if _, ok := items[k]; !ok {
+				for _, alt := range []string{k.name + "s", strings.TrimSuffix(k.name, "s")} {
+					if _, ok := items[key{alt, k.unit}]; ok {
+						k.name = alt
+						break
+					}
+				}
+			}
+			it := items[k]
+			if it == nil {
+				it = &Item{Ingredient: Ingredient{Unit: ing.Unit, Name: name}}
+				items[k] = it
+				order = append(order, k)
+			}
+			if len(name) > len(it.Name) {
+				it.Name = name
+			}
+			it.Quantity += ing.Quantity
+			if !slices.Contains(it.Recipes, r.Title) {
+				it.Recipes = append(it.Recipes, r.Title)
+			}
+		}
+	}
+	list := make([]Item, 0, len(order))
+	for _, k := range order {
+		it := *items[k]
+		switch {
+		case it.Unit == "g" && it.Quantity >= 1000:
+			it.Unit, it.Quantity = "kg", it.Quantity/1000
+		case it.Unit == "ml" && it.Quantity >= 1000:
+			it.Unit, it.Quantity = "l", it.Quantity/1000
+		}
+		list = append(list, it)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// WriteShoppingList stores items as a "Shopping list YYYY-Www" task
+// tagged shopping with one subtask per item, replacing the week's
+// previous list.
+func (s *Service) WriteShoppingList(week string, items []Item) (*todo.Task, error) {
+	if s.tasks == nil {
+		return nil, fmt.Errorf("task list is not available")
+	}
+	title := "Shopping list " + week
+	for _, t := range s.tasks.QueryTasks(todo.TaskQuery{Tag: ShoppingTag, ParentID: "none"}) {
+		if t.Title == title {
+			s.tasks.RemoveTask(t.ID)
+		}
+	}
+	parent, err := s.tasks.AddTask(todo.Task{
+		Title:       title,
+		Description: fmt.Sprintf("Ingredients for the meal plan of %s.", week),
+		Tags:        []string{ShoppingTag},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		if _, err := s.tasks.AddTask(todo.Task{Title: it.Ingredient.String(), Description: strings.Join(it.Recipes, ", "), ParentID: parent.ID}); err != nil {
+			return nil, err
+		}
+	}
+	return parent, nil
+}
@@ -0,0 +1,301 @@
+// Package recipes stores recipes, weekly meal plans built from them and
+// the shopping lists those plans need. Recipes live in the same SQLite
+// database as tasks; shopping lists are written as tasks.
+package recipes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/todo"
+	"localagent/pkg/utils"
+)
+
+type Recipe struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	SourceURL   string   `json:"sourceUrl,omitempty"`
+	Servings    int      `json:"servings,omitempty"` // 0 = unknown
+	Ingredients []string `json:"ingredients,omitempty"`
+	Steps       []string `json:"steps,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	CreatedAtMS int64    `json:"createdAtMs"`
+	UpdatedAtMS int64    `json:"updatedAtMs"`
+}
+
+// HasTag reports whether the recipe carries tag, ignoring case.
+func (r Recipe) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scale returns the ingredients for servings. Recipes without a known
+// serving count are returned unscaled.
+func (r Recipe) Scale(servings int) []string {
+	if servings <= 0 || r.Servings <= 0 {
+		return r.Ingredients
+	}
+	return ScaleLines(r.Ingredients, float64(servings)/float64(r.Servings))
+}
+
+type Service struct {
+	q     *dbq.Queries
+	tasks *todo.TodoService
+	now   func() time.Time
+}
+
+func NewService(database *sql.DB) *Service {
+	return &Service{q: dbq.New(database), now: time.Now}
+}
+
+// SetTodoService enables writing shopping lists as tasks.
+func (s *Service) SetTodoService(t *todo.TodoService) {
+	s.tasks = t
+}
+
+// List returns the recipes tagged with every tag in tags, by title.
+func (s *Service) List(tags ...string) []Recipe {
+	rows, err := s.q.ListRecipes(context.Background())
+	if err != nil {
+		return nil
+	}
+	var recipes []Recipe
+outer:
+	for _, row := range rows {
+		r := dbRecipeToRecipe(row)
+		for _, tag := range tags {
+			if tag != "" && !r.HasTag(tag) {
+				continue outer
+			}
+		}
+		recipes = append(recipes, r)
+	}
+	return recipes
+}
+
+func (s *Service) Get(id string) *Recipe {
+	row, err := s.q.GetRecipe(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	r := dbRecipeToRecipe(row)
+	return &r
+}
+
+// Find returns the recipe with id, or else the one whose title matches
+// ref (exactly, then as the only partial match).
+func (s *Service) Find(ref string) *Recipe {
+	if r := s.Get(ref); r != nil {
+		return r
+	}
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if ref == "" {
+		return nil
+	}
+	var partial []Recipe
+	for _, r := range s.List() {
+		title := strings.ToLower(r.Title)
+		if title == ref {
+			return &r
+		}
+		if strings.Contains(title, ref) {
+			partial = append(partial, r)
+		}
+	}
+	if len(partial) == 1 {
+		return &partial[0]
+	}
+	return nil
+}
+
+func (s *Service) Add(r Recipe) (*Recipe, error) {
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if r.Servings < 0 {
+		return nil, fmt.Errorf("servings must be positive")
+	}
+	now := s.now().UnixMilli()
+	if r.ID == "" {
+		r.ID = utils.RandHex(8)
+	}
+	r.Tags = normalizeTags(r.Tags)
+	r.CreatedAtMS, r.UpdatedAtMS = now, now
+
+	err := s.q.InsertRecipe(context.Background(), dbq.InsertRecipeParams{
+		ID:          r.ID,
+		Title:       r.Title,
+		SourceUrl:   r.SourceURL,
+		Servings:    int64(r.Servings),
+		Ingredients: marshalList(r.Ingredients),
+		Steps:       marshalList(r.Steps),
+		Tags:        marshalList(r.Tags),
+		Notes:       r.Notes,
+		CreatedAtMs: r.CreatedAtMS,
+		UpdatedAtMs: r.UpdatedAtMS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Update applies patch (title, servings, ingredients, steps, tags, notes).
+func (s *Service) Update(id string, patch map[string]any) (*Recipe, error) {
+	r := s.Get(id)
+	if r == nil {
+		return nil, fmt.Errorf("recipe not found: %s", id)
+	}
+	if v, ok := patch["title"].(string); ok && strings.TrimSpace(v) != "" {
+		r.Title = strings.TrimSpace(v)
+	}
+	if v, ok := patch["servings"].(float64); ok {
+		if v < 0 {
+			return nil, fmt.Errorf("servings must be positive")
+		}
+		r.Servings = int(v)
+	}
+	if v, ok := patch["ingredients"]; ok {
+		r.Ingredients = toStringList(v)
+	}
+	if v, ok := patch["steps"]; ok {
+		r.Steps = toStringList(v)
+	}
+	if v, ok := patch["tags"]; ok {
+		r.Tags = normalizeTags(toStringList(v))
+	}
+	if v, ok := patch["notes"].(string); ok {
+		r.Notes = v
+	}
+	r.UpdatedAtMS = s.now().UnixMilli()
+	err := s.q.UpdateRecipe(context.Background(), dbq.UpdateRecipeParams{
+		Title:       r.Title,
+		SourceUrl:   r.SourceURL,
+		Servings:    int64(r.Servings),
+		Ingredients: marshalList(r.Ingredients),
+		Steps:       marshalList(r.Steps),
+		Tags:        marshalList(r.Tags),
+		Notes:       r.Notes,
+		UpdatedAtMs: r.UpdatedAtMS,
+		ID:          r.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Service) Remove(id string) bool {
+	res, err := s.q.DeleteRecipe(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// FormatRecipe renders a recipe for chat, scaled to servings when > 0.
+func FormatRecipe(r Recipe, servings int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)", r.Title, r.ID)
+	switch {
+	case servings > 0 && r.Servings > 0 && servings != r.Servings:
+		fmt.Fprintf(&b, " - scaled to %d servings (recipe makes %d)", servings, r.Servings)
+	case r.Servings > 0:
+		fmt.Fprintf(&b, " - %d servings", r.Servings)
+	}
+	if len(r.Tags) > 0 {
+		fmt.Fprintf(&b, "\ntags: %s", strings.Join(r.Tags, ", "))
+	}
+	if r.SourceURL != "" {
+		fmt.Fprintf(&b, "\nsource: %s", r.SourceURL)
+	}
+	if len(r.Ingredients) > 0 {
+		b.WriteString("\n\nIngredients:")
+		for _, line := range r.Scale(servings) {
+			fmt.Fprintf(&b, "\n- %s", line)
+		}
+	}
+	if len(r.Steps) > 0 {
+		b.WriteString("\n\nSteps:")
+		for i, step := range r.Steps {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, step)
+		}
+	}
+	if r.Notes != "" {
+		fmt.Fprintf(&b, "\n\nNotes: %s", r.Notes)
+	}
+	return b.String()
+}
+
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func toStringList(v any) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	case string:
+		var out []string
+		for _, line := range strings.Split(v, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				out = append(out, line)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func marshalList(list []string) string {
+	if list == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+func dbRecipeToRecipe(r dbq.Recipe) Recipe {
+	rec := Recipe{
+		ID:          r.ID,
+		Title:       r.Title,
+		SourceURL:   r.SourceUrl,
+		Servings:    int(r.Servings),
+		Notes:       r.Notes,
+		CreatedAtMS: r.CreatedAtMs,
+		UpdatedAtMS: r.UpdatedAtMs,
+	}
+	json.Unmarshal([]byte(r.Ingredients), &rec.Ingredients)
+	json.Unmarshal([]byte(r.Steps), &rec.Steps)
+	json.Unmarshal([]byte(r.Tags), &rec.Tags)
+	return rec
+}
@@ -0,0 +1,169 @@
+package recipes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/db"
+	"localagent/pkg/todo"
+)
+
+func newTestService(t *testing.T) (*Service, *todo.TodoService) {
+	t.Helper()
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	tasks := todo.NewTodoService(database)
+	s := NewService(database)
+	s.SetTodoService(tasks)
+	return s, tasks
+}
+
+func TestParseIngredient(t *testing.T) {
+	for line, want := range map[string]Ingredient{
+		"200 g spaghetti":             {200, "g", "spaghetti"},
+		"200g spaghetti":              {200, "g", "spaghetti"},
+		"1 1/2 cups of flour, sifted": {1.5, "cup", "flour, sifted"},
+		"1½ tbsp olive oil":           {1.5, "tbsp", "olive oil"},
+		"½ onion":                     {0.5, "", "onion"},
+		"2-3 cloves garlic":           {3, "clove", "garlic"},
+		"0,5 l milk":                  {0.5, "l", "milk"},
+		"Salt to taste":               {0, "", "Salt to taste"},
+	} {
+		if got := ParseIngredient(line); got != want {
+			t.Errorf("ParseIngredient(%q) = %+v, want %+v", line, got, want)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	r := Recipe{Servings: 4, Ingredients: []string{"400 g spaghetti", "1 onion", "3/4 cup cream", "salt"}}
+	got := r.Scale(2)
+	// 3/8 has no common fraction and keeps two decimals.
+	want := []string{"200 g spaghetti", "1/2 onion", "0.38 cup cream", "salt"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("scaled[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if got := (Recipe{Ingredients: []string{"1 egg"}}).Scale(6); got[0] != "1 egg" {
+		t.Errorf("recipe without servings should not scale, got %q", got[0])
+	}
+}
+
+const jsonLDPage = `<html><head><title>Best Chili</title>
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+ {"@type":"WebPage","name":"Best Chili"},
+ {"@type":["Recipe"],"name":"Best Chili &amp; Beans","recipeYield":["4","4 servings"],
+  "recipeIngredient":["500 g minced beef","1 can kidney beans","2 tsp chili powder"],
+  "recipeInstructions":[{"@type":"HowToSection","name":"Cook","itemListElement":[
+    {"@type":"HowToStep","text":"Brown the beef."},{"@type":"HowToStep","text":"Add <b>beans</b> and simmer."}]}],
+  "recipeCategory":"Dinner","keywords":"chili, beans"}
+]}</script></head><body></body></html>`
+
+const microdataPage = `<html><body>
+<span itemprop="name">Site name</span>
+<div itemscope itemtype="https://schema.org/Recipe">
+ <h1 itemprop="name">Pancakes</h1>
+ <meta itemprop="recipeYield" content="Serves 2">
+ <ul><li itemprop="recipeIngredient">125 g flour</li><li itemprop="recipeIngredient">2 eggs</li></ul>
+ <ol itemprop="recipeInstructions"><li>Whisk.</li><li>Fry.</li></ol>
+</div></body></html>`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(strings.NewReader(jsonLDPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Title != "Best Chili & Beans" || r.Servings != 4 || len(r.Ingredients) != 3 {
+		t.Errorf("json-ld: %+v", r)
+	}
+	if len(r.Steps) != 2 || r.Steps[1] != "Add beans and simmer." {
+		t.Errorf("steps = %q", r.Steps)
+	}
+	if strings.Join(r.Tags, ",") != "dinner,chili,beans" {
+		t.Errorf("tags = %v", r.Tags)
+	}
+
+	r, err = Parse(strings.NewReader(microdataPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Title != "Pancakes" || r.Servings != 2 || len(r.Ingredients) != 2 || len(r.Steps) != 2 {
+		t.Errorf("microdata: %+v", r)
+	}
+
+	if _, err := Parse(strings.NewReader("<html><body><p>No recipe</p></body></html>")); err != ErrNoRecipe {
+		t.Errorf("err = %v, want ErrNoRecipe", err)
+	}
+}
+
+func TestPlanAndShoppingList(t *testing.T) {
+	s, tasks := newTestService(t)
+	s.Add(Recipe{Title: "Chili", Servings: 4, Tags: []string{"Dinner"}, Ingredients: []string{"500 g minced beef", "1 onion", "water"}})
+	soup, _ := s.Add(Recipe{Title: "Onion soup", Servings: 2, Tags: []string{"dinner"}, Ingredients: []string{"3 onions, sliced", "1 l stock"}})
+	s.Add(Recipe{Title: "Porridge", Servings: 1, Tags: []string{"breakfast"}, Ingredients: []string{"50 g oats"}})
+
+	day := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC) // Wednesday
+	plan, err := s.Generate(day, PlanOptions{Days: 2, Servings: 4, Tags: []string{"dinner"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Week != "2026-W42" || len(plan.Meals) != 2 || plan.Meals[0].Date != "2026-10-12" {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	// Next week's plan starts with the recipe not cooked most recently.
+	next, _ := s.Generate(day.AddDate(0, 0, 7), PlanOptions{Days: 1, Tags: []string{"dinner"}})
+	if next.Meals[0].RecipeID != plan.Meals[0].RecipeID {
+		t.Errorf("rotation picked %s, want %s", next.Meals[0].Title, plan.Meals[0].Title)
+	}
+
+	if _, err := s.SetMeal(day, "breakfast", *soup, 2); err != nil {
+		t.Fatal(err)
+	}
+	plan = s.GetPlan("2026-W42")
+	if len(plan.Meals) != 3 || plan.Meals[2].Slot != "breakfast" {
+		t.Fatalf("meals = %+v", plan.Meals)
+	}
+
+	// Chili x1, soup x2 (4 servings) + soup x1 (2 servings).
+	items := s.ShoppingList(*plan)
+	got := make(map[string]string)
+	for _, it := range items {
+		got[it.Name] = it.Ingredient.String()
+	}
+	for name, want := range map[string]string{
+		"minced beef": "500 g minced beef",
+		"onions":      "10 onions",
+		"stock":       "3 l stock",
+	} {
+		if got[name] != want {
+			t.Errorf("item %s = %q, want %q", name, got[name], want)
+		}
+	}
+	if _, ok := got["water"]; ok {
+		t.Error("water should not be on the shopping list")
+	}
+
+	task, err := s.WriteShoppingList(plan.Week, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteShoppingList(plan.Week, items); err != nil {
+		t.Fatal(err)
+	}
+	lists := tasks.QueryTasks(todo.TaskQuery{Tag: ShoppingTag, ParentID: "none"})
+	if len(lists) != 1 || lists[0].Title != "Shopping list 2026-W42" {
+		t.Fatalf("shopping lists = %+v", lists)
+	}
+	if tasks.GetTask(task.ID) != nil {
+		t.Error("previous list for the week should be replaced")
+	}
+	if n := len(tasks.QueryTasks(todo.TaskQuery{ParentID: lists[0].ID})); n != len(items) {
+		t.Errorf("got %d items, want %d", n, len(items))
+	}
+}
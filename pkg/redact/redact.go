@@ -0,0 +1,242 @@
+// Package redact masks secrets and personal data (emails, phone numbers,
+// card numbers and custom patterns) in log output, activity payloads and,
+// optionally, prompts sent to providers that aren't trusted with it.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"localagent/pkg/config"
+	"localagent/pkg/providers"
+)
+
+type rule struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool // optional check to reduce false positives
+}
+
+var builtins = map[string]rule{
+	"credit_card": {
+		name:  "credit_card",
+		re:    regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid: luhn,
+	},
+	"email": {
+		name: "email",
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	"phone": {
+		name:  "phone",
+		re:    regexp.MustCompile(`\+?\(?\d[\d\s().-]{7,}\d`),
+		valid: plausiblePhone,
+	},
+}
+
+// builtinOrder applies card numbers before phones so long digit runs get
+// the more specific label.
+var builtinOrder = []string{"credit_card", "email", "phone"}
+
+var isoDate = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Redactor replaces matches of its rules. Every Redactor, including a nil
+// one, also masks secrets registered with AddSecrets.
+type Redactor struct {
+	rules []rule
+	strip bool
+}
+
+// New builds a redactor from config. It returns nil when redaction is disabled.
+func New(cfg config.RedactionConfig) (*Redactor, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	enabled := cfg.Patterns
+	if len(enabled) == 0 {
+		enabled = builtinOrder
+	}
+	r := &Redactor{strip: cfg.Mode == "strip"}
+	for _, name := range builtinOrder {
+		for _, want := range enabled {
+			if want == name {
+				r.rules = append(r.rules, builtins[name])
+			}
+		}
+	}
+	for _, want := range enabled {
+		if _, ok := builtins[want]; !ok {
+			return nil, fmt.Errorf("unknown redaction pattern %q (expected email, phone or credit_card)", want)
+		}
+	}
+	for name, expr := range cfg.Custom {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("custom redaction pattern %q: %w", name, err)
+		}
+		r.rules = append(r.rules, rule{name: name, re: re})
+	}
+	return r, nil
+}
+
+// String returns s with all matches replaced.
+func (r *Redactor) String(s string) string {
+	if s == "" {
+		return s
+	}
+	s = MaskSecrets(s)
+	if r == nil {
+		return s
+	}
+	for _, ru := range r.rules {
+		s = ru.re.ReplaceAllStringFunc(s, func(m string) string {
+			if ru.valid != nil && !ru.valid(m) {
+				return m
+			}
+			if r.strip {
+				return ""
+			}
+			return "[REDACTED:" + ru.name + "]"
+		})
+	}
+	return s
+}
+
+// Map returns a redacted deep copy of m.
+func (r *Redactor) Map(m map[string]any) map[string]any {
+	if m == nil {
+		return m
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = r.value(v)
+	}
+	return out
+}
+
+func (r *Redactor) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.String(v)
+	case map[string]any:
+		return r.Map(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = r.value(item)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = r.String(item)
+		}
+		return out
+	}
+	return v
+}
+
+// Messages returns redacted copies of chat messages: text content, reasoning
+// and tool call arguments. Images are passed through.
+func (r *Redactor) Messages(msgs []providers.Message) []providers.Message {
+	if r == nil {
+		return msgs
+	}
+	out := make([]providers.Message, len(msgs))
+	for i, m := range msgs {
+		m.Content = r.String(m.Content)
+		m.ReasoningContent = r.String(m.ReasoningContent)
+		if len(m.ContentParts) > 0 {
+			parts := make([]providers.ContentPart, len(m.ContentParts))
+			for j, p := range m.ContentParts {
+				p.Text = r.String(p.Text)
+				parts[j] = p
+			}
+			m.ContentParts = parts
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]providers.ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				tc.Arguments = r.Map(tc.Arguments)
+				if tc.Function != nil {
+					fn := *tc.Function
+					fn.Arguments = r.String(fn.Arguments)
+					tc.Function = &fn
+				}
+				calls[j] = tc
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+type redactingProvider struct {
+	providers.LLMProvider
+	r *Redactor
+}
+
+// WrapProvider redacts every prompt before it reaches p.
+func WrapProvider(p providers.LLMProvider, r *Redactor) providers.LLMProvider {
+	if r == nil {
+		return p
+	}
+	return &redactingProvider{LLMProvider: p, r: r}
+}
+
+func (p *redactingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]any) (*providers.LLMResponse, error) {
+	return p.LLMProvider.Chat(ctx, p.r.Messages(messages), tools, model, options)
+}
+
+// ChatStream streams from the wrapped provider when it supports streaming,
+// and falls back to Chat otherwise.
+func (p *redactingProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]any, onDelta func(string)) (*providers.LLMResponse, error) {
+	if s, ok := p.LLMProvider.(providers.StreamingProvider); ok {
+		return s.ChatStream(ctx, p.r.Messages(messages), tools, model, options, onDelta)
+	}
+	return p.Chat(ctx, messages, tools, model, options)
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// plausiblePhone rejects dates, IP addresses and bare numbers (ids,
+// timestamps) that the loose phone pattern also matches.
+func plausiblePhone(s string) bool {
+	digits := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits < 9 || digits > 15 {
+		return false
+	}
+	if isoDate.MatchString(s) || net.ParseIP(strings.TrimSpace(s)) != nil {
+		return false
+	}
+	return strings.HasPrefix(s, "+") || strings.ContainsAny(strings.TrimSpace(s), " ().-")
+}
@@ -0,0 +1,75 @@
+package redact
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// minSecretLen keeps short values (placeholder passwords, "1") from masking
+// ordinary text.
+const minSecretLen = 6
+
+const secretMask = "[REDACTED:secret]"
+
+// secrets holds a replacer for every registered secret value; nil until
+// AddSecrets is called.
+var (
+	secretsMu    sync.Mutex
+	secretValues []string
+	secrets      atomic.Pointer[strings.Replacer]
+)
+
+var (
+	// bearerToken and basicAuth match Authorization header values in
+	// request dumps.
+	bearerToken = regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9\-._~+/]{16,}=*`)
+	basicAuth   = regexp.MustCompile(`(?i)\b(authorization:\s*basic)\s+[A-Za-z0-9+/]+=*`)
+	// secretParam matches credentials in query strings and key=value logs.
+	secretParam = regexp.MustCompile(`(?i)\b((?:api[_-]?key|access[_-]?token|token|password|passwd|secret)=)[^&\s"',]+`)
+	// urlPassword matches the password of user:password@host URLs.
+	urlPassword = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+)
+
+// AddSecrets registers values (resolved API keys, tokens, passwords) that
+// are masked by every Redactor, including a nil one, so they never reach
+// logs or activity details even with PII redaction disabled. Values shorter
+// than six characters are ignored. Safe for concurrent use with masking.
+func AddSecrets(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	added := false
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) < minSecretLen || slices.Contains(secretValues, v) {
+			continue
+		}
+		secretValues = append(secretValues, v)
+		added = true
+	}
+	if !added {
+		return
+	}
+	// Longest first, so a key containing another is masked whole.
+	slices.SortFunc(secretValues, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	pairs := make([]string, 0, 2*len(secretValues))
+	for _, v := range secretValues {
+		pairs = append(pairs, v, secretMask)
+	}
+	secrets.Store(strings.NewReplacer(pairs...))
+}
+
+// MaskSecrets replaces registered secrets, bearer tokens, credential
+// parameters and URL passwords in s. Redactor.String applies it first.
+func MaskSecrets(s string) string {
+	if rep := secrets.Load(); rep != nil {
+		s = rep.Replace(s)
+	}
+	s = bearerToken.ReplaceAllString(s, "$1 "+secretMask)
+	s = basicAuth.ReplaceAllString(s, "$1 "+secretMask)
+	s = secretParam.ReplaceAllString(s, "${1}"+secretMask)
+	return urlPassword.ReplaceAllString(s, "${1}"+secretMask+"@")
+}
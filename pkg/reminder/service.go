@@ -9,6 +9,7 @@ import (
 
 	"localagent/pkg/logger"
 	"localagent/pkg/webchat"
+	"localagent/pkg/when"
 )
 
 var offsets = map[string]time.Duration{
@@ -152,7 +153,7 @@ func (s *Service) recordSent(taskID, offset string, fireAtMs, sentAtMs int64) {
 }
 
 func parseDue(due string) (time.Time, bool) {
-	loc := time.Now().Location()
+	loc := when.Location()
 	if strings.Contains(due, "T") {
 		t, err := time.ParseInLocation("2006-01-02T15:04", due, loc)
 		if err != nil {
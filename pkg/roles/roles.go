@@ -0,0 +1,233 @@
+// Package roles maps message senders to household roles and decides which
+// tools each role may use. Non-owner senders also get their own session and
+// memory namespace so their conversations stay out of the owner's USER.md
+// and memories.
+package roles
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"localagent/pkg/channels"
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+type Role string
+
+const (
+	Owner  Role = "owner"
+	Family Role = "family"
+	Guest  Role = "guest"
+)
+
+// Parse returns the role named s.
+func Parse(s string) (Role, error) {
+	switch r := Role(strings.ToLower(strings.TrimSpace(s))); r {
+	case Owner, Family, Guest:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown role %q (expected owner, family or guest)", s)
+}
+
+// Autonomy levels for side-effecting tool calls.
+const (
+	AutonomyAuto    = "auto"    // run them directly
+	AutonomySuggest = "suggest" // describe them and wait for the user to confirm
+)
+
+// Policy limits the tools a role can use. A non-empty Allow list offers only
+// those tools; Deny removes tools from whatever is left. Autonomy, when set,
+// replaces the default autonomy level for the role.
+type Policy struct {
+	Allow    []string
+	Deny     []string
+	Autonomy string
+}
+
+func (p Policy) allows(tool string) bool {
+	if len(p.Allow) > 0 && !slices.Contains(p.Allow, tool) {
+		return false
+	}
+	return !slices.Contains(p.Deny, tool)
+}
+
+// guestTools are the only tools a guest gets: replying and public lookups
+// that touch nothing of the owner's. Tools added later stay owner and
+// family only until they are listed here.
+var guestTools = []string{
+	"message", "world_time", "air_quality", "sports", "tech_news", "ai_papers", "stock_price", "convert_currency",
+}
+
+var defaultPolicies = map[Role]Policy{
+	Owner:  {},
+	Family: {Deny: []string{"exec", "docker", "spawn", "subagent", "allowlist", "email_triage", "screenshot", "clipboard", "write_file", "edit_file", "append_file"}},
+	Guest:  {Allow: guestTools},
+}
+
+// restrictedChannels carry senders that aren't the owner's own accounts
+// (federation peers); they are guests unless roles.channels lists them.
+var restrictedChannels = []string{"federation"}
+
+// Resolver answers role and policy questions for incoming messages.
+type Resolver struct {
+	senders    map[string]map[string]Role // channel -> allowlist entry -> role
+	policies   map[Role]Policy
+	autonomy   string            // default level
+	toolLevels map[string]string // per-tool levels, over role policies
+	ownerOnly  []string          // tools no policy can give to other roles
+}
+
+// NewResolver builds a resolver from config, logging and skipping invalid roles.
+func NewResolver(cfg config.RolesConfig) *Resolver {
+	r := &Resolver{
+		senders:  make(map[string]map[string]Role),
+		policies: make(map[Role]Policy, len(defaultPolicies)),
+	}
+	for role, p := range defaultPolicies {
+		r.policies[role] = p
+	}
+
+	for _, channel := range restrictedChannels {
+		r.senders[channel] = make(map[string]Role)
+	}
+	for channel, entries := range cfg.Channels {
+		// A channel with entries, even invalid ones, no longer treats
+		// unlisted senders as the owner.
+		if r.senders[channel] == nil {
+			r.senders[channel] = make(map[string]Role)
+		}
+		for entry, name := range entries {
+			role, err := Parse(name)
+			if err != nil {
+				logger.Warn("roles: %s sender %s: %v", channel, entry, err)
+				continue
+			}
+			r.senders[channel][entry] = role
+		}
+	}
+	for name, p := range cfg.Policies {
+		role, err := Parse(name)
+		if err != nil {
+			logger.Warn("roles: policy: %v", err)
+			continue
+		}
+		r.policies[role] = Policy{Allow: p.AllowTools, Deny: p.DenyTools, Autonomy: p.Autonomy}
+	}
+	return r
+}
+
+// RoleFor returns the role of a sender on a channel. On a channel with
+// role entries, unlisted senders are guests, so the owner has to be listed
+// too; on other channels every allowed sender is the owner.
+func (r *Resolver) RoleFor(channel, senderID string) Role {
+	entries, ok := r.senders[channel]
+	if !ok {
+		return Owner
+	}
+	for entry, role := range entries {
+		if channels.MatchSender(entry, senderID) {
+			return role
+		}
+	}
+	return Guest
+}
+
+// Allows reports whether role may use the named tool.
+func (r *Resolver) Allows(role Role, tool string) bool {
+	if role == "" || role == Owner {
+		return r.policies[Owner].allows(tool)
+	}
+	if slices.Contains(r.ownerOnly, tool) && !slices.Contains(r.policies[role].Allow, tool) {
+		return false
+	}
+	return r.policies[role].allows(tool)
+}
+
+// SetOwnerOnly keeps the named tools from every role but the owner unless
+// a role's policy lists them in allow_tools. Custom tools that run
+// commands are registered here, since the default family policy only
+// denies built-ins by name.
+func (r *Resolver) SetOwnerOnly(tools []string) {
+	r.ownerOnly = tools
+}
+
+// SetAutonomy sets the default autonomy level and per-tool overrides.
+// Invalid levels are logged and ignored.
+func (r *Resolver) SetAutonomy(cfg config.AutonomyConfig) {
+	if err := cfg.Validate(); err != nil {
+		logger.Warn("roles: autonomy: %v", err)
+	}
+	if config.ValidAutonomy(cfg.Level) {
+		r.autonomy = cfg.Level
+	}
+	r.toolLevels = make(map[string]string, len(cfg.Tools))
+	for tool, level := range cfg.Tools {
+		if level != "" && config.ValidAutonomy(level) {
+			r.toolLevels[tool] = level
+		}
+	}
+}
+
+// NeedsConfirmation reports whether a call to tool by role has to wait for
+// the user's go-ahead. sideEffect tells whether the call changes anything;
+// read-only calls only wait when their tool is set to "suggest" explicitly.
+func (r *Resolver) NeedsConfirmation(role Role, tool string, sideEffect bool) bool {
+	if level, ok := r.toolLevels[tool]; ok {
+		return level == AutonomySuggest
+	}
+	if role == "" {
+		role = Owner
+	}
+	level := r.autonomy
+	if p := r.policies[role].Autonomy; p != "" {
+		level = p
+	}
+	return sideEffect && level == AutonomySuggest
+}
+
+// Namespace returns the session/memory namespace for a sender, or "" for the
+// owner, whose data lives at the top of the workspace. Only [a-z0-9-] is
+// kept since session files map "_" back to ":" when loaded.
+func Namespace(role Role, senderID string) string {
+	if role == "" || role == Owner {
+		return ""
+	}
+	var sb strings.Builder
+	for _, c := range strings.ToLower(senderID) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			sb.WriteRune(c)
+		default:
+			sb.WriteByte('-')
+		}
+	}
+	return string(role) + "-" + sb.String()
+}
+
+// sessionSep separates a base session key from the member namespace.
+const sessionSep = "#"
+
+// SessionKey namespaces a channel session key for a non-owner member.
+func SessionKey(base, namespace string) string {
+	if namespace == "" {
+		return base
+	}
+	return base + sessionSep + namespace
+}
+
+// BaseSessionKey returns a session key without its member namespace.
+func BaseSessionKey(key string) string {
+	base, _, _ := strings.Cut(key, sessionSep)
+	return base
+}
+
+// NamespaceFromSessionKey returns the member namespace of a session key
+// built by SessionKey, or "" for owner sessions.
+func NamespaceFromSessionKey(key string) string {
+	if _, ns, ok := strings.Cut(key, sessionSep); ok {
+		return ns
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package roles
+
+import (
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestRoleForAndPolicies(t *testing.T) {
+	r := NewResolver(config.RolesConfig{
+		Channels: map[string]map[string]string{
+			"telegram": {"123|alice": "family", "@bob": "guest", "999": "wizard"},
+		},
+		Policies: map[string]config.RolePolicy{
+			"family": {DenyTools: []string{"exec", "calendar"}},
+		},
+	})
+
+	if got := r.RoleFor("telegram", "123|alice"); got != Family {
+		t.Errorf("alice: got %q", got)
+	}
+	if got := r.RoleFor("telegram", "456|bob"); got != Guest {
+		t.Errorf("bob: got %q", got)
+	}
+	if got := r.RoleFor("telegram", "999"); got != Guest {
+		t.Errorf("invalid role should be ignored, got %q", got)
+	}
+	if got := r.RoleFor("telegram", "777|mallory"); got != Guest {
+		t.Errorf("unlisted sender on a channel with roles: got %q", got)
+	}
+	if got := r.RoleFor("web", "123|alice"); got != Owner {
+		t.Errorf("roles are per channel, got %q", got)
+	}
+	if got := r.RoleFor("federation", "laptop"); got != Guest {
+		t.Errorf("federation peers should default to guest, got %q", got)
+	}
+
+	if !r.Allows(Owner, "exec") || !r.Allows("", "exec") {
+		t.Error("owner should have every tool")
+	}
+	if r.Allows(Family, "calendar") || !r.Allows(Family, "write_file") {
+		t.Error("family policy override not applied")
+	}
+	for _, tool := range []string{"exec", "read_file", "list_dir", "query_tasks", "net_check", "vars", "run_template", "remote_agent", "my_custom_tool"} {
+		if r.Allows(Guest, tool) {
+			t.Errorf("guest should not have %s", tool)
+		}
+	}
+	if !r.Allows(Guest, "message") {
+		t.Error("guest should be able to reply")
+	}
+}
+
+func TestNamespaceAndSessionKey(t *testing.T) {
+	if ns := Namespace(Owner, "123"); ns != "" {
+		t.Errorf("owner namespace should be empty, got %q", ns)
+	}
+	ns := Namespace(Guest, "456|Bob_B")
+	if ns != "guest-456-bob-b" {
+		t.Errorf("unexpected namespace %q", ns)
+	}
+
+	key := SessionKey("telegram:42", ns)
+	if key != "telegram:42#guest-456-bob-b" {
+		t.Errorf("unexpected session key %q", key)
+	}
+	if got := NamespaceFromSessionKey(key); got != ns {
+		t.Errorf("round trip: got %q", got)
+	}
+	if got := BaseSessionKey(key); got != "telegram:42" {
+		t.Errorf("base key: got %q", got)
+	}
+	if got := NamespaceFromSessionKey("telegram:42"); got != "" {
+		t.Errorf("owner key: got %q", got)
+	}
+}
+
+func TestNeedsConfirmation(t *testing.T) {
+	r := NewResolver(config.RolesConfig{
+		Policies: map[string]config.RolePolicy{"family": {Autonomy: "suggest"}},
+	})
+	r.SetAutonomy(config.AutonomyConfig{Tools: map[string]string{"web_fetch": "suggest", "message": "auto"}})
+
+	if r.NeedsConfirmation(Owner, "write_file", true) {
+		t.Error("owner defaults to auto")
+	}
+	if !r.NeedsConfirmation(Family, "write_file", true) || r.NeedsConfirmation(Family, "read_file", false) {
+		t.Error("family policy should hold only side-effecting calls")
+	}
+	if r.NeedsConfirmation(Family, "message", true) {
+		t.Error("per-tool auto should win over the role level")
+	}
+	if !r.NeedsConfirmation(Owner, "web_fetch", false) {
+		t.Error("per-tool suggest should hold even read-only calls")
+	}
+
+	r.SetAutonomy(config.AutonomyConfig{Level: "suggest"})
+	if !r.NeedsConfirmation("", "edit_file", true) {
+		t.Error("default level should apply to the owner")
+	}
+}
+
+func TestOwnerOnlyTools(t *testing.T) {
+	r := NewResolver(config.RolesConfig{
+		Policies: map[string]config.RolePolicy{"guest": {AllowTools: []string{"message", "lights"}}},
+	})
+	r.SetOwnerOnly([]string{"backup", "lights"})
+
+	if !r.Allows(Owner, "backup") {
+		t.Error("owner should keep owner-only tools")
+	}
+	if r.Allows(Family, "backup") {
+		t.Error("family got an owner-only tool through the default deny list")
+	}
+	if !r.Allows(Guest, "lights") {
+		t.Error("allow_tools should share an owner-only tool")
+	}
+}
@@ -0,0 +1,398 @@
+// Package satellite serves a voice endpoint for satellite devices such as
+// ESP32 speakers or Wyoming satellites, making the gateway a private
+// voice-assistant backend: a device posts what it recorded after its wake
+// word, the audio is transcribed, the agent answers in the device's own
+// session, and the answer comes back as speech.
+//
+// POST /satellite/turn takes the recording as the request body, which may
+// be streamed (chunked) while recording. The body is an audio file (WAV,
+// MP3, Ogg, WebM, FLAC, or multipart form field "file"), or raw signed
+// little-endian PCM with Content-Type audio/pcm or ?format=pcm, described
+// like Wyoming audio chunks by rate (default 16000), width (bytes per
+// sample, default 2) and channels (default 1) query parameters.
+//
+// The device is named by the X-Device-Id header or ?device=. The answer is
+// WAV audio, streamed as it is synthesized, or raw PCM with ?output=pcm
+// (format in X-Audio-Rate/-Width/-Channels headers). The transcript and
+// answer text are in the X-Transcript and X-Response headers,
+// percent-encoded. With Accept: application/json, or when no TTS service
+// is configured, the answer is JSON text only. Nothing heard is 204.
+package satellite
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/tools"
+)
+
+// maxAudioBytes caps a recording: about 13 minutes of 16 kHz 16-bit mono.
+const maxAudioBytes = 25 << 20
+
+// turnTimeout bounds a whole turn: upload, transcription, the agent's
+// answer and speech synthesis.
+const turnTimeout = 5 * time.Minute
+
+// RunFunc processes text said to device and returns the agent's answer.
+type RunFunc func(ctx context.Context, device, text string) (string, error)
+
+// Config locates the speech services.
+type Config struct {
+	Token    string // bearer token devices authenticate with; required
+	STTURL   string
+	STTKey   string
+	TTSURL   string // empty: answers are text only
+	TTSKey   string
+	Speaker  string
+	Language string
+}
+
+type server struct {
+	cfg Config
+	run RunFunc
+
+	mu      sync.Mutex
+	devices map[string]*sync.Mutex // serializes turns per device
+}
+
+// Handler serves /satellite/turn.
+func Handler(cfg Config, run RunFunc) http.Handler {
+	s := &server{cfg: cfg, run: run, devices: make(map[string]*sync.Mutex)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/satellite/turn", s.auth(s.handleTurn))
+	return mux
+}
+
+func (s *server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.cfg.Token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+var deviceRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// deviceID sanitizes a device name for use in a session key.
+func deviceID(r *http.Request) string {
+	id := r.Header.Get("X-Device-Id")
+	if id == "" {
+		id = r.URL.Query().Get("device")
+	}
+	id = strings.Trim(deviceRe.ReplaceAllString(id, "-"), "-")
+	if id == "" {
+		id = "default"
+	}
+	return id
+}
+
+func (s *server) handleTurn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	// The gateway's timeouts are sized for health checks; a turn lasts as
+	// long as the user speaks and the agent thinks.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(turnTimeout))
+	rc.SetWriteDeadline(time.Now().Add(turnTimeout))
+	ctx, cancel := context.WithTimeout(r.Context(), turnTimeout)
+	defer cancel()
+
+	device := deviceID(r)
+	lock := s.deviceLock(device)
+	lock.Lock()
+	defer lock.Unlock()
+
+	path, err := saveRecording(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer os.Remove(path)
+
+	transcript, err := tools.TranscribeAudio(ctx, path, s.cfg.STTURL, s.cfg.STTKey)
+	if err != nil {
+		logger.Error("satellite: %s: transcribe: %v", device, err)
+		writeError(w, http.StatusBadGateway, "transcription failed")
+		return
+	}
+	transcript = strings.TrimSpace(transcript)
+	if transcript == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	logger.Info("satellite: %s heard %q", device, transcript)
+
+	answer, err := s.run(ctx, device, transcript)
+	if err != nil {
+		logger.Error("satellite: %s: agent: %v", device, err)
+		writeError(w, http.StatusInternalServerError, "the agent failed to process the request")
+		return
+	}
+
+	w.Header().Set("X-Transcript", url.PathEscape(transcript))
+	w.Header().Set("X-Response", url.PathEscape(answer))
+	if s.cfg.TTSURL == "" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, http.StatusOK, map[string]string{"device": device, "transcript": transcript, "response": answer})
+		return
+	}
+	if err := s.speak(ctx, w, Spoken(answer), r.URL.Query().Get("output") == "pcm"); err != nil {
+		logger.Error("satellite: %s: tts: %v", device, err)
+	}
+}
+
+func (s *server) deviceLock(device string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.devices[device]
+	if !ok {
+		l = &sync.Mutex{}
+		s.devices[device] = l
+	}
+	return l
+}
+
+// saveRecording writes the request's audio to a temp file the STT service
+// can be sent, wrapping raw PCM in a WAV header.
+func saveRecording(w http.ResponseWriter, r *http.Request) (string, error) {
+	body := http.MaxBytesReader(w, r.Body, maxAudioBytes)
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	q := r.URL.Query()
+
+	var data []byte
+	ext := ".wav"
+	switch {
+	case mediaType == "multipart/form-data":
+		r.Body = body
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return "", fmt.Errorf("no file provided")
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			return "", fmt.Errorf("failed to read audio: %v", err)
+		}
+		if e := extension(header.Header.Get("Content-Type"), header.Filename); e != "" {
+			ext = e
+		}
+
+	case mediaType == "audio/pcm" || q.Get("format") == "pcm":
+		pcm, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read audio: %v", err)
+		}
+		rate, width, channels := intParam(q, params, "rate", 16000), intParam(q, params, "width", 2), intParam(q, params, "channels", 1)
+		if width < 1 || width > 4 || channels < 1 || channels > 8 || rate < 1000 || rate > 192000 {
+			return "", fmt.Errorf("unsupported PCM format: rate %d, width %d, channels %d", rate, width, channels)
+		}
+		data = append(wavHeader(len(pcm), rate, width, channels), pcm...)
+
+	default:
+		var err error
+		if data, err = io.ReadAll(body); err != nil {
+			return "", fmt.Errorf("failed to read audio: %v", err)
+		}
+		if e := extension(mediaType, ""); e != "" {
+			ext = e
+		}
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("no audio received")
+	}
+
+	f, err := os.CreateTemp("", "satellite-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// extension picks the file extension the STT service detects the format
+// by.
+func extension(contentType, filename string) string {
+	if i := strings.LastIndex(filename, "."); i >= 0 {
+		return strings.ToLower(filename[i:])
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	case "audio/webm":
+		return ".webm"
+	case "audio/flac", "audio/x-flac":
+		return ".flac"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		return ".m4a"
+	}
+	return ""
+}
+
+// intParam reads a PCM format parameter from the query, then the
+// Content-Type parameters.
+func intParam(q url.Values, params map[string]string, key string, def int) int {
+	v := q.Get(key)
+	if v == "" {
+		v = params[key]
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	return def
+}
+
+// wavHeader is the 44-byte header of a PCM WAV file holding dataSize bytes.
+func wavHeader(dataSize, rate, width, channels int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))                  // chunk size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))                   // PCM format
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))            // channels
+	binary.Write(&buf, binary.LittleEndian, uint32(rate))                // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(rate*width*channels)) // byte rate
+	binary.Write(&buf, binary.LittleEndian, uint16(width*channels))      // block align
+	binary.Write(&buf, binary.LittleEndian, uint16(width*8))             // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	return buf.Bytes()
+}
+
+// speak streams the TTS service's WAV for text to w, or its PCM samples
+// when pcm is set.
+func (s *server) speak(ctx context.Context, w http.ResponseWriter, text string, pcm bool) error {
+	body, _ := json.Marshal(map[string]string{
+		"text":     text,
+		"speaker":  s.cfg.Speaker,
+		"language": s.cfg.Language,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.TTSURL+"/stream", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.TTSKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.TTSKey)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DisableCompression:    true,
+			ResponseHeaderTimeout: 30 * time.Second,
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "speech synthesis failed")
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		writeError(w, http.StatusBadGateway, "speech synthesis failed")
+		return fmt.Errorf("tts returned %d: %s", resp.StatusCode, string(b))
+	}
+
+	if pcm {
+		header := make([]byte, 44)
+		if _, err := io.ReadFull(resp.Body, header); err != nil {
+			writeError(w, http.StatusBadGateway, "speech synthesis failed")
+			return fmt.Errorf("read wav header: %w", err)
+		}
+		channels := binary.LittleEndian.Uint16(header[22:24])
+		w.Header().Set("Content-Type", "audio/pcm")
+		w.Header().Set("X-Audio-Rate", fmt.Sprint(binary.LittleEndian.Uint32(header[24:28])))
+		w.Header().Set("X-Audio-Width", fmt.Sprint(binary.LittleEndian.Uint16(header[34:36])/8))
+		w.Header().Set("X-Audio-Channels", fmt.Sprint(channels))
+	} else {
+		w.Header().Set("Content-Type", "audio/wav")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 8192)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+var (
+	mdLink     = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	mdMarkers  = regexp.MustCompile("(\\*\\*|__|\\*|`+|~~)")
+	mdHeadings = regexp.MustCompile(`(?m)^\s*(#{1,6}|[-*+]|\d+\.|>)\s+`)
+	urlRe      = regexp.MustCompile(`https?://\S+`)
+)
+
+// Spoken turns a chat answer into text for speech: markdown markup, list
+// bullets and bare URLs are dropped, and lines end as sentences so list
+// items get a pause.
+func Spoken(s string) string {
+	s = mdLink.ReplaceAllString(s, "$1")
+	s = urlRe.ReplaceAllString(s, "")
+	s = mdHeadings.ReplaceAllString(s, "")
+	s = mdMarkers.ReplaceAllString(s, "")
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if !strings.ContainsAny(line[len(line)-1:], ".!?:;,") {
+			line += "."
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, " ")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,122 @@
+package satellite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeSpeech serves STT answering transcript, recording the uploaded file,
+// and TTS answering a 16 kHz WAV of two samples.
+func fakeSpeech(t *testing.T, transcript string) (Config, *[]byte, *string) {
+	t.Helper()
+	var uploaded []byte
+	var spoken string
+	stt := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		uploaded, _ = io.ReadAll(f)
+		json.NewEncoder(w).Encode(map[string]string{"text": transcript})
+	}))
+	tts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&req)
+		spoken = req.Text
+		w.Write(append(wavHeader(4, 16000, 2, 1), 1, 0, 2, 0))
+	}))
+	t.Cleanup(stt.Close)
+	t.Cleanup(tts.Close)
+	return Config{Token: "tok", STTURL: stt.URL, TTSURL: tts.URL}, &uploaded, &spoken
+}
+
+func turn(t *testing.T, h http.Handler, query, contentType string, body []byte, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/satellite/turn"+query, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTurnWithPCM(t *testing.T) {
+	cfg, uploaded, spoken := fakeSpeech(t, " What's on today? ")
+	var gotDevice, gotText string
+	h := Handler(cfg, func(_ context.Context, device, text string) (string, error) {
+		gotDevice, gotText = device, text
+		return "**Two** things:\n- dentist at 3\n- call [Mum](tel:123)", nil
+	})
+
+	pcm := make([]byte, 3200)
+	rec := turn(t, h, "?format=pcm&rate=8000", "application/octet-stream", pcm, map[string]string{"X-Device-Id": "kitchen speaker"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if gotDevice != "kitchen-speaker" || gotText != "What's on today?" {
+		t.Errorf("device=%q text=%q", gotDevice, gotText)
+	}
+	if len(*uploaded) != 44+len(pcm) || string((*uploaded)[:4]) != "RIFF" ||
+		binary.LittleEndian.Uint32((*uploaded)[24:28]) != 8000 {
+		t.Errorf("STT got %d bytes, not an 8 kHz WAV", len(*uploaded))
+	}
+	if *spoken != "Two things: dentist at 3. call Mum." {
+		t.Errorf("spoken = %q", *spoken)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/wav" || rec.Body.Len() != 48 {
+		t.Errorf("response %s, %d bytes", ct, rec.Body.Len())
+	}
+	if got, _ := url.PathUnescape(rec.Header().Get("X-Transcript")); got != "What's on today?" {
+		t.Errorf("X-Transcript = %q", got)
+	}
+}
+
+func TestTurnOutputs(t *testing.T) {
+	cfg, _, _ := fakeSpeech(t, "hello")
+	h := Handler(cfg, func(context.Context, string, string) (string, error) { return "Hi!", nil })
+
+	rec := turn(t, h, "?output=pcm", "audio/wav", []byte("RIFF...."), nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Audio-Rate") != "16000" || rec.Header().Get("X-Audio-Width") != "2" || rec.Body.Len() != 4 {
+		t.Errorf("pcm output: %d %v %d bytes", rec.Code, rec.Header(), rec.Body.Len())
+	}
+
+	rec = turn(t, h, "", "audio/wav", []byte("RIFF...."), map[string]string{"Accept": "application/json"})
+	var out map[string]string
+	json.NewDecoder(rec.Body).Decode(&out)
+	if out["response"] != "Hi!" || out["device"] != "default" {
+		t.Errorf("json output = %v", out)
+	}
+}
+
+func TestTurnNothingHeard(t *testing.T) {
+	cfg, _, _ := fakeSpeech(t, "  ")
+	called := false
+	h := Handler(cfg, func(context.Context, string, string) (string, error) { called = true; return "", nil })
+	if rec := turn(t, h, "", "audio/wav", []byte("RIFF...."), nil); rec.Code != http.StatusNoContent || called {
+		t.Errorf("status %d, agent called %v", rec.Code, called)
+	}
+}
+
+func TestTurnRequiresToken(t *testing.T) {
+	cfg, _, _ := fakeSpeech(t, "hello")
+	h := Handler(cfg, func(context.Context, string, string) (string, error) { return "", nil })
+	req := httptest.NewRequest(http.MethodPost, "/satellite/turn", strings.NewReader("x"))
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d", rec.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package session
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/logger"
+)
+
+// archiveDir holds gzip-compressed files of sessions that went idle.
+func (sm *SessionManager) archiveDir() string {
+	return filepath.Join(sm.storage, "archive")
+}
+
+// Archive compresses sessions inactive for longer than maxIdle into the
+// archive directory and drops them from memory. An archived session is
+// restored as soon as it is used again. It returns how many sessions were
+// archived.
+func (sm *SessionManager) Archive(maxIdle time.Duration) int {
+	if sm.storage == "" {
+		return 0
+	}
+	cutoff := time.Now().Add(-maxIdle)
+	archived := 0
+	for _, info := range sm.ListSessions() {
+		if info.Updated.IsZero() || info.Updated.After(cutoff) {
+			continue
+		}
+		if err := sm.archive(info.Key); err != nil {
+			logger.Warn("session: cannot archive %s: %v", info.Key, err)
+			continue
+		}
+		archived++
+	}
+	if archived > 0 {
+		logger.Info("session: archived %d inactive session(s)", archived)
+	}
+	return archived
+}
+
+func (sm *SessionManager) archive(key string) error {
+	name := sanitizeFilename(key)
+	if !validateFilename(name) {
+		return nil
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	path := filepath.Join(sm.storage, name+".jsonl")
+	defer filelock.Remove(path) // after unlock, once the file is gone
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := copyFile(filepath.Join(sm.archiveDir(), name+".jsonl.gz"), path, gzipCopy); err != nil {
+		return err
+	}
+	delete(sm.sessions, key)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	sm.moveIndex(key, "archive/"+name+".jsonl.gz")
+	return nil
+}
+
+// restore moves the archived session key, stored as name, back into the
+// sessions directory. It does nothing when the session isn't archived. The
+// caller holds sm.mu.
+func (sm *SessionManager) restore(key, name string) error {
+	archived := filepath.Join(sm.archiveDir(), name+".jsonl.gz")
+	if _, err := os.Stat(archived); err != nil {
+		return nil
+	}
+	path := filepath.Join(sm.storage, name+".jsonl")
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		// Written again since it was archived (e.g. by another process);
+		// the live file wins and the archive stays for manual recovery.
+		logger.Warn("session: %s is both live and archived; using the live file", name)
+		return nil
+	}
+	if err := copyFile(path, archived, gunzipCopy); err != nil {
+		return err
+	}
+	logger.Info("session: restored %s from the archive", key)
+	sm.moveIndex(key, name+".jsonl")
+	return os.Remove(archived)
+}
+
+// moveIndex points key's index entry at its file's new location rel.
+func (sm *SessionManager) moveIndex(key, rel string) {
+	sm.indexMu.Lock()
+	e, ok := sm.index[key]
+	sm.indexMu.Unlock()
+	if ok {
+		sm.setIndex(key, rel, *e)
+	}
+}
+
+// copyFile writes src to dst through wrap, via a temp file renamed into
+// place so dst is never partial.
+func copyFile(dst, src string, wrap func(io.Writer, io.Reader) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = wrap(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+func gzipCopy(w io.Writer, r io.Reader) error {
+	zw := gzip.NewWriter(w)
+	if _, err := io.Copy(zw, r); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func gunzipCopy(w io.Writer, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	_, err = io.Copy(w, zr)
+	return err
+}
+
+// RunArchival archives sessions idle for longer than maxIdle every
+// interval until stop is closed.
+func (sm *SessionManager) RunArchival(maxIdle, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sm.Archive(maxIdle)
+		}
+	}
+}
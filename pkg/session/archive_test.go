@@ -0,0 +1,55 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessageWithMedia("web:default", "user", "look at this", []string{"/media/cat.png"})
+	sm.AddMessage("web:default", "assistant", "a cat")
+	sm.SetVar("web:default", "pet", "cat")
+
+	// A new manager loads nothing until a session is used.
+	lazy := NewSessionManager(dir)
+	if len(lazy.sessions) != 0 {
+		t.Fatalf("loaded %d sessions eagerly", len(lazy.sessions))
+	}
+	if got := lazy.ListSessions(); len(got) != 1 || got[0].Messages != 2 || len(lazy.sessions) != 0 {
+		t.Errorf("ListSessions = %+v, loaded %d", got, len(lazy.sessions))
+	}
+
+	if n := sm.Archive(0); n != 1 {
+		t.Fatalf("archived %d sessions", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "web_default.jsonl.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web_default.jsonl.lock")); !os.IsNotExist(err) {
+		t.Errorf("lock file left after archiving: %v", err)
+	}
+	if len(sm.ListSessions()) != 0 {
+		t.Error("archived sessions should not be listed")
+	}
+	if !sm.ReferencedMedia()["/media/cat.png"] {
+		t.Error("media of archived sessions must stay referenced")
+	}
+
+	// Using the session again restores it.
+	sm.AddMessage("web:default", "user", "and now?")
+	if h := sm.GetHistory("web:default"); len(h) != 3 || h[0].Content != "look at this" {
+		t.Errorf("restored history = %+v", h)
+	}
+	if sm.GetVars("web:default")["pet"] != "cat" {
+		t.Error("variables lost in the archive")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "web_default.jsonl.gz")); !os.IsNotExist(err) {
+		t.Errorf("archive not removed after restore: %v", err)
+	}
+	if h := NewSessionManager(dir).GetHistory("web:default"); len(h) != 3 {
+		t.Errorf("reloaded history has %d messages", len(h))
+	}
+}
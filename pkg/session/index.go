@@ -0,0 +1,174 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/logger"
+	"localagent/pkg/storage"
+	"localagent/pkg/vault"
+)
+
+// indexFile lists every session file with what listings and media
+// retention need, so startup doesn't parse the sessions themselves.
+const indexFile = "index.json"
+
+type indexEntry struct {
+	File     string    `json:"file"` // relative to the sessions dir, e.g. "archive/web_default.jsonl.gz"
+	Size     int64     `json:"size"` // of File when indexed; a mismatch means it changed behind our back
+	Updated  time.Time `json:"updated"`
+	Messages int       `json:"messages"`
+	Media    []string  `json:"media,omitempty"`
+}
+
+func (e *indexEntry) archived() bool {
+	return strings.HasPrefix(e.File, "archive/")
+}
+
+func (s *Session) entry() indexEntry {
+	info := s.info()
+	e := indexEntry{Updated: info.Updated, Messages: info.Messages}
+	for _, m := range s.messages {
+		e.Media = append(e.Media, m.Media...)
+		if m.Audio != "" {
+			e.Media = append(e.Media, m.Audio)
+		}
+	}
+	return e
+}
+
+// loadIndex reads the index and brings it in line with the files on
+// disk: new or changed files (e.g. written by another process) are parsed,
+// entries for removed files dropped.
+func (sm *SessionManager) loadIndex() {
+	sm.indexMu.Lock()
+	defer sm.indexMu.Unlock()
+
+	if data, err := vault.ReadFile(filepath.Join(sm.storage, indexFile)); err == nil {
+		if err := json.Unmarshal(data, &sm.index); err != nil {
+			logger.Warn("session: ignoring unreadable %s: %v", indexFile, err)
+		}
+	}
+	if sm.index == nil {
+		sm.index = make(map[string]*indexEntry)
+	}
+	if sm.reconcile(false) {
+		sm.saveIndex()
+	}
+}
+
+// removeStaleLocks deletes lock files whose session file is gone, left by
+// sessions removed by hand or archived by older versions.
+func (sm *SessionManager) removeStaleLocks() {
+	locks, _ := filepath.Glob(filepath.Join(sm.storage, "*.jsonl.lock"))
+	for _, lock := range locks {
+		filelock.Remove(strings.TrimSuffix(lock, ".lock"))
+	}
+}
+
+// Reindex rebuilds the index by parsing every session file, live and
+// archived, and returns how many sessions it holds.
+func (sm *SessionManager) Reindex() (int, error) {
+	if sm.storage == "" {
+		return 0, nil
+	}
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	sm.indexMu.Lock()
+	defer sm.indexMu.Unlock()
+
+	sm.reconcile(true)
+	return len(sm.index), sm.saveIndex()
+}
+
+// reconcile updates the index from the session files, parsing those that
+// are new or changed size, or all of them with full. It reports whether
+// the index changed. The caller holds sm.indexMu.
+func (sm *SessionManager) reconcile(full bool) bool {
+	byFile := make(map[string]string, len(sm.index))
+	for key, e := range sm.index {
+		byFile[e.File] = key
+	}
+
+	changed := false
+	seen := make(map[string]bool)
+	for _, dir := range []string{"archive", ""} { // a live file wins over its archive
+		files, _ := os.ReadDir(filepath.Join(sm.storage, dir))
+		for _, file := range files {
+			name, ok := strings.CutSuffix(file.Name(), ".jsonl")
+			if !ok {
+				name, ok = strings.CutSuffix(file.Name(), ".jsonl.gz")
+			}
+			if file.IsDir() || !ok {
+				continue
+			}
+			rel := filepath.ToSlash(filepath.Join(dir, file.Name()))
+			key, known := byFile[rel]
+			if !known {
+				key = keyFromFilename(name)
+			}
+			seen[key] = true
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			if e := sm.index[key]; !full && e != nil && e.File == rel && e.Size == info.Size() {
+				continue
+			}
+			s, err := readFile(filepath.Join(sm.storage, rel), key)
+			if err != nil {
+				logger.Warn("session: cannot index %s: %v", rel, err)
+				continue
+			}
+			e := s.entry()
+			e.File, e.Size = rel, info.Size()
+			sm.index[key] = &e
+			changed = true
+		}
+	}
+	for key := range sm.index {
+		if !seen[key] {
+			delete(sm.index, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// setIndex records e for key's file at rel. The caller must not hold
+// sm.indexMu.
+func (sm *SessionManager) setIndex(key, rel string, e indexEntry) {
+	if sm.storage == "" {
+		return
+	}
+	e.File = filepath.ToSlash(rel)
+	if info, err := os.Stat(filepath.Join(sm.storage, rel)); err == nil {
+		e.Size = info.Size()
+	}
+
+	sm.indexMu.Lock()
+	defer sm.indexMu.Unlock()
+	sm.index[key] = &e
+	if err := sm.saveIndex(); err != nil {
+		logger.Warn("session: cannot write %s: %v", indexFile, err)
+	}
+}
+
+// saveIndex writes the index, sealed like the sessions it lists when
+// encryption is on. The caller holds sm.indexMu.
+func (sm *SessionManager) saveIndex() error {
+	data, err := json.Marshal(sm.index)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(sm.storage, indexFile)
+	perm := os.FileMode(0644)
+	if vault.Protected(path) {
+		perm = 0600
+	}
+	return storage.WriteFile(path, vault.Encode(path, data), perm)
+}
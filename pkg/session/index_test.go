@@ -0,0 +1,64 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"localagent/pkg/vault"
+)
+
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	sm.AddMessage("openai:trip_june", "user", "hi")
+	sm.AddMessageWithMedia("web:default", "user", "photo", []string{"/media/a.png"})
+
+	// Startup reads the index only; keys with "_" survive via the index.
+	cold := NewSessionManager(dir)
+	list := cold.ListSessions()
+	if len(list) != 2 || list[0].Key != "web:default" || list[1].Key != "openai:trip_june" || len(cold.sessions) != 0 {
+		t.Fatalf("ListSessions = %+v, loaded %d", list, len(cold.sessions))
+	}
+	if !cold.ReferencedMedia()["/media/a.png"] {
+		t.Error("media missing from the index")
+	}
+
+	// A file that changed after the index was written (another process,
+	// a crash in between) is re-read at startup.
+	indexPath := filepath.Join(dir, indexFile)
+	stale, _ := os.ReadFile(indexPath)
+	sm.AddMessage("web:default", "assistant", "nice")
+	os.WriteFile(indexPath, stale, 0644)
+	if list := NewSessionManager(dir).ListSessions(); list[0].Messages != 2 {
+		t.Errorf("stale index not reconciled: %+v", list)
+	}
+
+	os.Remove(indexPath)
+	if n, err := NewSessionManager(dir).Reindex(); err != nil || n != 2 {
+		t.Errorf("Reindex = %d, %v", n, err)
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIndexSealed(t *testing.T) {
+	dir := t.TempDir()
+	key, err := vault.Unlock(filepath.Join(t.TempDir(), "vault.json"), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault.Enable(key, dir)
+	t.Cleanup(func() { vault.Enable(nil) })
+
+	NewSessionManager(dir).AddMessage("telegram:secret_chat", "user", "hi")
+	raw, _ := os.ReadFile(filepath.Join(dir, indexFile))
+	if !vault.IsSealed(raw) || bytes.Contains(raw, []byte("secret_chat")) {
+		t.Fatalf("index written in plaintext: %q", raw)
+	}
+	if list := NewSessionManager(dir).ListSessions(); len(list) != 1 || list[0].Key != "telegram:secret_chat" {
+		t.Errorf("sealed index not read back: %+v", list)
+	}
+}
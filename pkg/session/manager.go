@@ -2,7 +2,11 @@ package session
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,8 +15,16 @@ import (
 	"time"
 
 	"localagent/pkg/activity"
+	"localagent/pkg/filelock"
 	"localagent/pkg/logger"
+	"localagent/pkg/migrate"
 	"localagent/pkg/providers"
+	"localagent/pkg/vault"
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrExists   = errors.New("session already exists")
 )
 
 // JSONL record type discriminators
@@ -20,6 +32,7 @@ const (
 	recMsg = "msg"
 	recAct = "act"
 	recSum = "sum"
+	recVar = "var"
 )
 
 // JSONL record types
@@ -33,6 +46,7 @@ type msgRecord struct {
 	Msg   providers.Message `json:"msg"`
 	Ts    time.Time         `json:"ts"`
 	Media []string          `json:"media,omitempty"`
+	Audio string            `json:"audio,omitempty"` // original recording of a transcribed message
 }
 
 type actRecord struct {
@@ -43,6 +57,15 @@ type actRecord struct {
 	Ts        time.Time      `json:"ts"`
 }
 
+// varRecord sets a session variable; Deleted removes it.
+type varRecord struct {
+	T       string    `json:"t"`
+	Name    string    `json:"name"`
+	Value   string    `json:"value,omitempty"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Ts      time.Time `json:"ts"`
+}
+
 type sumRecord struct {
 	T       string    `json:"t"`
 	Content string    `json:"content"`
@@ -55,6 +78,7 @@ type storedMessage struct {
 	Msg   providers.Message
 	Ts    time.Time
 	Media []string
+	Audio string
 }
 
 type Session struct {
@@ -62,6 +86,8 @@ type Session struct {
 	messages []storedMessage
 	Activity []activity.Event
 	Summary  string
+	Vars     map[string]string
+	synced   int64 // bytes of the file this process has read or written, see catchUp
 }
 
 // TimelineEntry represents a single entry in the interleaved timeline.
@@ -71,12 +97,16 @@ type TimelineEntry struct {
 	Activity  *activity.Event
 	Timestamp time.Time
 	Media     []string
+	Audio     string
 }
 
 type SessionManager struct {
-	sessions map[string]*Session
+	sessions map[string]*Session // loaded sessions
 	mu       sync.RWMutex
 	storage  string
+
+	index   map[string]*indexEntry // every session file, loaded or not
+	indexMu sync.Mutex
 }
 
 func NewSessionManager(storage string) *SessionManager {
@@ -87,8 +117,8 @@ func NewSessionManager(storage string) *SessionManager {
 
 	if storage != "" {
 		os.MkdirAll(storage, 0755)
-		sm.migrateJSON()
-		sm.loadSessions()
+		sm.loadIndex()
+		sm.removeStaleLocks()
 	}
 
 	return sm
@@ -122,22 +152,38 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 }
 
 func (sm *SessionManager) AddFullMessageWithMedia(sessionKey string, msg providers.Message, media []string) {
-	now := time.Now()
+	sm.addStored(sessionKey, storedMessage{Msg: msg, Media: media})
+}
+
+// AddMessageWithAudio records a transcribed message together with the path
+// of the original audio so it can be replayed from history.
+func (sm *SessionManager) AddMessageWithAudio(sessionKey, role, content, audioPath string) {
+	sm.addStored(sessionKey, storedMessage{
+		Msg:   providers.Message{Role: role, Content: content},
+		Audio: audioPath,
+	})
+}
+
+func (sm *SessionManager) addStored(sessionKey string, m storedMessage) {
+	sm.load(sessionKey)
+	m.Ts = time.Now()
 
 	sm.mu.Lock()
 	s := sm.getOrCreate(sessionKey)
-	s.messages = append(s.messages, storedMessage{Msg: msg, Ts: now, Media: media})
+	s.messages = append(s.messages, m)
 	sm.mu.Unlock()
 
 	sm.appendRecord(sessionKey, msgRecord{
 		T:     recMsg,
-		Msg:   msg,
-		Ts:    now,
-		Media: media,
+		Msg:   m.Msg,
+		Ts:    m.Ts,
+		Media: m.Media,
+		Audio: m.Audio,
 	})
 }
 
 func (sm *SessionManager) AddActivity(sessionKey string, evt activity.Event) {
+	sm.load(sessionKey)
 	sm.mu.Lock()
 	s := sm.getOrCreate(sessionKey)
 	s.Activity = append(s.Activity, evt)
@@ -153,6 +199,7 @@ func (sm *SessionManager) AddActivity(sessionKey string, evt activity.Event) {
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
+	sm.load(key)
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -169,6 +216,7 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 }
 
 func (sm *SessionManager) GetActivity(key string) []activity.Event {
+	sm.load(key)
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -183,6 +231,7 @@ func (sm *SessionManager) GetActivity(key string) []activity.Event {
 }
 
 func (sm *SessionManager) GetTimeline(key string) []TimelineEntry {
+	sm.load(key)
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -199,6 +248,7 @@ func (sm *SessionManager) GetTimeline(key string) []TimelineEntry {
 			Message:   &msg,
 			Timestamp: s.messages[i].Ts,
 			Media:     s.messages[i].Media,
+			Audio:     s.messages[i].Audio,
 		})
 	}
 	for i := range s.Activity {
@@ -217,7 +267,77 @@ func (sm *SessionManager) GetTimeline(key string) []TimelineEntry {
 	return entries
 }
 
+// SessionInfo describes a stored session for listings.
+type SessionInfo struct {
+	Key      string    `json:"key"`
+	Messages int       `json:"messages"`
+	Updated  time.Time `json:"updated"`
+}
+
+// ListSessions returns all sessions except archived ones, most recently
+// active first. Sessions that aren't loaded are described from the index.
+func (sm *SessionManager) ListSessions() []SessionInfo {
+	sm.mu.RLock()
+	out := make([]SessionInfo, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		out = append(out, s.info())
+	}
+	sm.indexMu.Lock()
+	for key, e := range sm.index {
+		if sm.sessions[key] == nil && !e.archived() {
+			out = append(out, SessionInfo{Key: key, Messages: e.Messages, Updated: e.Updated})
+		}
+	}
+	sm.indexMu.Unlock()
+	sm.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Updated.Equal(out[j].Updated) {
+			return out[i].Updated.After(out[j].Updated)
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (s *Session) info() SessionInfo {
+	info := SessionInfo{Key: s.Key, Messages: len(s.messages)}
+	if n := len(s.messages); n > 0 {
+		info.Updated = s.messages[n-1].Ts
+	}
+	if n := len(s.Activity); n > 0 && s.Activity[n-1].Timestamp.After(info.Updated) {
+		info.Updated = s.Activity[n-1].Timestamp
+	}
+	return info
+}
+
+// ReferencedMedia returns the set of media paths referenced by any session
+// message, archived sessions included.
+func (sm *SessionManager) ReferencedMedia() map[string]bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	refs := make(map[string]bool)
+	for _, s := range sm.sessions {
+		for _, path := range s.entry().Media {
+			refs[filepath.Clean(path)] = true
+		}
+	}
+	sm.indexMu.Lock()
+	defer sm.indexMu.Unlock()
+	for key, e := range sm.index {
+		if sm.sessions[key] != nil {
+			continue
+		}
+		for _, path := range e.Media {
+			refs[filepath.Clean(path)] = true
+		}
+	}
+	return refs
+}
+
 func (sm *SessionManager) GetSummary(key string) string {
+	sm.load(key)
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
@@ -229,6 +349,7 @@ func (sm *SessionManager) GetSummary(key string) string {
 }
 
 func (sm *SessionManager) SetSummary(key string, summary string) {
+	sm.load(key)
 	now := time.Now()
 
 	sm.mu.Lock()
@@ -248,6 +369,7 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 }
 
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
+	sm.load(key)
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -277,6 +399,113 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.rewriteFile(key, s)
 }
 
+// Fork copies the first n messages of src (all of them when n < 0), the
+// activity up to the last copied message, the summary and the variables
+// into the new session dst, and returns how many messages were copied. A
+// tool-call exchange left unfinished by the cut is dropped so the fork ends
+// on a complete turn. The source session is not changed.
+func (sm *SessionManager) Fork(src, dst string, n int) (int, error) {
+	if !validateFilename(sanitizeFilename(dst)) || dst == src {
+		return 0, fmt.Errorf("invalid session key %q", dst)
+	}
+	sm.load(src)
+	sm.load(dst)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	s, ok := sm.sessions[src]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, src)
+	}
+	if d, ok := sm.sessions[dst]; ok && (len(d.messages) > 0 || len(d.Activity) > 0) {
+		return 0, fmt.Errorf("%w: %s", ErrExists, dst)
+	}
+
+	if n < 0 || n > len(s.messages) {
+		n = len(s.messages)
+	}
+	for n > 0 && !turnComplete(s.messages[:n]) {
+		n--
+	}
+
+	fork := &Session{Key: dst, Summary: s.Summary}
+	fork.messages = make([]storedMessage, n)
+	copy(fork.messages, s.messages[:n])
+	for _, a := range s.Activity {
+		if n == len(s.messages) || n > 0 && !a.Timestamp.After(s.messages[n-1].Ts) {
+			fork.Activity = append(fork.Activity, a)
+		}
+	}
+	if len(s.Vars) > 0 {
+		fork.Vars = make(map[string]string, len(s.Vars))
+		for k, v := range s.Vars {
+			fork.Vars[k] = v
+		}
+	}
+	sm.sessions[dst] = fork
+	sm.rewriteFile(dst, fork)
+	return n, nil
+}
+
+// turnComplete reports whether msgs end outside a tool-call exchange: on a
+// user message or on an assistant message that calls no tools.
+func turnComplete(msgs []storedMessage) bool {
+	last := msgs[len(msgs)-1].Msg
+	return last.Role == "user" || last.Role == "assistant" && len(last.ToolCalls) == 0
+}
+
+// SetVar stores a session variable. Variables are kept across
+// summarization and history truncation.
+func (sm *SessionManager) SetVar(key, name, value string) {
+	sm.load(key)
+	sm.mu.Lock()
+	s := sm.getOrCreate(key)
+	if s.Vars == nil {
+		s.Vars = make(map[string]string)
+	}
+	s.Vars[name] = value
+	sm.mu.Unlock()
+
+	sm.appendRecord(key, varRecord{T: recVar, Name: name, Value: value, Ts: time.Now()})
+}
+
+// DeleteVar removes a session variable and reports whether it existed.
+func (sm *SessionManager) DeleteVar(key, name string) bool {
+	sm.load(key)
+	sm.mu.Lock()
+	s, ok := sm.sessions[key]
+	if !ok {
+		sm.mu.Unlock()
+		return false
+	}
+	_, existed := s.Vars[name]
+	delete(s.Vars, name)
+	sm.mu.Unlock()
+
+	if existed {
+		sm.appendRecord(key, varRecord{T: recVar, Name: name, Deleted: true, Ts: time.Now()})
+	}
+	return existed
+}
+
+// GetVars returns a copy of the session's variables.
+func (sm *SessionManager) GetVars(key string) map[string]string {
+	sm.load(key)
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	s, ok := sm.sessions[key]
+	if !ok || len(s.Vars) == 0 {
+		return nil
+	}
+	vars := make(map[string]string, len(s.Vars))
+	for k, v := range s.Vars {
+		vars[k] = v
+	}
+	return vars
+}
+
 // Save is a no-op; writes are now immediate via append.
 func (sm *SessionManager) Save(key string) error {
 	return nil
@@ -307,19 +536,45 @@ func (sm *SessionManager) appendRecord(key string, record any) {
 		logger.Warn("session: failed to marshal record for %s: %v", key, err)
 		return
 	}
-	data = append(data, '\n')
-
 	path := filepath.Join(sm.storage, filename+".jsonl")
+	data = append(vault.EncodeLine(path, data), '\n')
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		logger.Warn("session: %v", err)
+		return
+	}
+	defer unlock()
 	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		logger.Warn("session: failed to open %s for append: %v", path, err)
 		return
 	}
-	defer f.Close()
-
+	var before int64
+	if info, err := f.Stat(); err == nil {
+		before = info.Size()
+	}
 	f.Write(data)
+	f.Close()
+
+	sm.mu.Lock()
+	s, ok := sm.sessions[key]
+	var e indexEntry
+	if ok {
+		// Records appended by others in between stay unsynced, for the
+		// next rewrite to merge.
+		if s.synced == before {
+			s.synced += int64(len(data))
+		}
+		e = s.entry()
+	}
+	sm.mu.Unlock()
+	if ok {
+		sm.setIndex(key, filename+".jsonl", e)
+	}
 }
 
+// rewriteFile replaces key's file with s, after merging in what other
+// processes appended since this one last synced. The caller holds sm.mu.
 func (sm *SessionManager) rewriteFile(key string, s *Session) {
 	if sm.storage == "" {
 		return
@@ -333,19 +588,38 @@ func (sm *SessionManager) rewriteFile(key string, s *Session) {
 	path := filepath.Join(sm.storage, filename+".jsonl")
 	tmpPath := path + ".tmp"
 
+	// Hold the file lock so another process can't append between our
+	// write and the rename.
+	unlock, err := filelock.Lock(path)
+	if err != nil {
+		logger.Warn("session: %v", err)
+		return
+	}
+	defer unlock()
+	catchUp(path, s)
+
 	f, err := os.Create(tmpPath)
 	if err != nil {
 		logger.Warn("session: failed to create temp file for rewrite: %v", err)
 		return
 	}
 
-	enc := json.NewEncoder(f)
+	enc := vault.NewLineEncoder(f, path)
 
 	// Write summary first
 	if s.Summary != "" {
 		enc.Encode(sumRecord{T: recSum, Content: s.Summary, Ts: time.Now()})
 	}
 
+	names := make([]string, 0, len(s.Vars))
+	for name := range s.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		enc.Encode(varRecord{T: recVar, Name: name, Value: s.Vars[name], Ts: time.Now()})
+	}
+
 	// Interleave messages and activity by timestamp
 	mi, ai := 0, 0
 	for mi < len(s.messages) || ai < len(s.Activity) {
@@ -358,7 +632,7 @@ func (sm *SessionManager) rewriteFile(key string, s *Session) {
 
 		if writeMsg {
 			m := s.messages[mi]
-			enc.Encode(msgRecord{T: recMsg, Msg: m.Msg, Ts: m.Ts, Media: m.Media})
+			enc.Encode(msgRecord{T: recMsg, Msg: m.Msg, Ts: m.Ts, Media: m.Media, Audio: m.Audio})
 			mi++
 		} else {
 			a := s.Activity[ai]
@@ -378,43 +652,156 @@ func (sm *SessionManager) rewriteFile(key string, s *Session) {
 	if err := os.Rename(tmpPath, path); err != nil {
 		logger.Warn("session: failed to rename temp file: %v", err)
 		os.Remove(tmpPath)
+		return
 	}
+	if info, err := os.Stat(path); err == nil {
+		s.synced = info.Size()
+	}
+	sm.setIndex(key, filename+".jsonl", s.entry())
 }
 
-// Loading
-
-func (sm *SessionManager) loadSessions() {
-	files, err := os.ReadDir(sm.storage)
+// catchUp merges into s the records other processes appended to path
+// since this process last read or wrote it, so a rewrite doesn't drop
+// them. Records s already has (its own appends) are skipped. A file that
+// shrank was rewritten elsewhere and is replaced as is. The caller holds
+// sm.mu and the file lock.
+func catchUp(path string, s *Session) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == s.synced {
+		return
+	}
+	if info.Size() < s.synced {
+		logger.Warn("session: %s was rewritten by another process; keeping this process's version", path)
+		return
+	}
+	f, err := os.Open(path)
 	if err != nil {
 		return
 	}
+	defer f.Close()
+	if _, err := f.Seek(s.synced, io.SeekStart); err != nil {
+		return
+	}
 
-	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".jsonl" {
-			continue
+	tail := &Session{Key: s.Key, Vars: s.Vars}
+	if err := tail.readJSONL(f); err != nil {
+		logger.Warn("session: cannot merge new records from %s: %v", path, err)
+		return
+	}
+	s.Vars = tail.Vars
+	if tail.Summary != "" {
+		s.Summary = tail.Summary
+	}
+
+	type msgID struct {
+		ts            int64
+		role, content string
+	}
+	known := make(map[msgID]bool, len(s.messages))
+	for _, m := range s.messages {
+		known[msgID{m.Ts.UnixNano(), m.Msg.Role, m.Msg.Content}] = true
+	}
+	for _, m := range tail.messages {
+		if !known[msgID{m.Ts.UnixNano(), m.Msg.Role, m.Msg.Content}] {
+			s.messages = append(s.messages, m)
 		}
-		path := filepath.Join(sm.storage, file.Name())
-		sm.loadJSONL(path)
 	}
+	sort.SliceStable(s.messages, func(i, j int) bool { return s.messages[i].Ts.Before(s.messages[j].Ts) })
+
+	seen := make(map[msgID]bool, len(s.Activity))
+	for _, a := range s.Activity {
+		seen[msgID{a.Timestamp.UnixNano(), string(a.Type), a.Message}] = true
+	}
+	for _, a := range tail.Activity {
+		if !seen[msgID{a.Timestamp.UnixNano(), string(a.Type), a.Message}] {
+			s.Activity = append(s.Activity, a)
+		}
+	}
+	sort.SliceStable(s.Activity, func(i, j int) bool { return s.Activity[i].Timestamp.Before(s.Activity[j].Timestamp) })
+	s.synced = info.Size()
+}
+
+// Loading
+
+// load reads key's file into memory on first access, restoring it from
+// the archive first if it was archived.
+func (sm *SessionManager) load(key string) {
+	if sm.storage == "" {
+		return
+	}
+	sm.mu.RLock()
+	_, ok := sm.sessions[key]
+	sm.mu.RUnlock()
+	if ok {
+		return
+	}
+
+	name := sanitizeFilename(key)
+	if !validateFilename(name) {
+		return
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.sessions[key]; ok {
+		return
+	}
+	path := filepath.Join(sm.storage, name+".jsonl")
+	if err := sm.restore(key, name); err != nil {
+		logger.Error("session: cannot restore %s from the archive: %v", key, err)
+		return
+	}
+	if s, err := readFile(path, key); err == nil {
+		sm.sessions[key] = s
+	} else if !os.IsNotExist(err) {
+		// Don't load a partial session that a later rewrite would
+		// persist, dropping the sealed records.
+		logger.Error("session: cannot read %s: %v", path, err)
+	}
+}
+
+// keyFromFilename reverses sanitizeFilename. Keys containing "_" don't
+// round-trip; callers that know the key pass it instead.
+func keyFromFilename(name string) string {
+	return strings.ReplaceAll(name, "_", ":")
 }
 
-func (sm *SessionManager) loadJSONL(path string) {
+// readFile reads a session file, gzip-compressed when it ends in ".gz".
+func readFile(path, key string) (*Session, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer f.Close()
 
-	name := strings.TrimSuffix(filepath.Base(path), ".jsonl")
-	key := strings.ReplaceAll(name, "_", ":")
-
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
 	s := &Session{Key: key}
+	if err := s.readJSONL(r); err != nil {
+		return nil, err
+	}
+	if r == f {
+		s.synced, _ = f.Seek(0, io.SeekCurrent)
+	}
+	return s, nil
+}
 
-	scanner := bufio.NewScanner(f)
+// readJSONL applies the records read from r to s.
+func (s *Session) readJSONL(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 0, 4096), 10*1024*1024) // 10MB max line
 
 	for scanner.Scan() {
-		line := scanner.Bytes()
+		line, err := vault.DecodeLine(scanner.Bytes())
+		if err != nil {
+			return err
+		}
 		if len(line) == 0 {
 			continue
 		}
@@ -430,7 +817,7 @@ func (sm *SessionManager) loadJSONL(path string) {
 			if err := json.Unmarshal(line, &rec); err != nil {
 				continue
 			}
-			s.messages = append(s.messages, storedMessage{Msg: rec.Msg, Ts: rec.Ts, Media: rec.Media})
+			s.messages = append(s.messages, storedMessage{Msg: rec.Msg, Ts: rec.Ts, Media: rec.Media, Audio: rec.Audio})
 
 		case recAct:
 			var rec actRecord
@@ -450,22 +837,48 @@ func (sm *SessionManager) loadJSONL(path string) {
 				continue
 			}
 			s.Summary = rec.Content // last summary wins
+
+		case recVar:
+			var rec varRecord
+			if err := json.Unmarshal(line, &rec); err != nil {
+				continue
+			}
+			if rec.Deleted {
+				delete(s.Vars, rec.Name)
+				continue
+			}
+			if s.Vars == nil {
+				s.Vars = make(map[string]string)
+			}
+			s.Vars[rec.Name] = rec.Value
 		}
 	}
-
-	sm.sessions[key] = s
+	return scanner.Err()
 }
 
-// Migration from old JSON format
+// Migrations returns the on-disk format migrations for sessions stored in
+// dir, run by the migrate package before a SessionManager is opened.
+func Migrations(dir string) []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "json to jsonl", Up: func() error {
+			sm := &SessionManager{sessions: make(map[string]*Session), storage: dir}
+			return sm.migrateJSON()
+		}},
+	}
+}
 
-func (sm *SessionManager) migrateJSON() {
+// migrateJSON converts sessions from the old one-JSON-file format.
+func (sm *SessionManager) migrateJSON() error {
 	files, err := os.ReadDir(sm.storage)
 	if err != nil {
-		return
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
 	}
 
 	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" || file.Name() == indexFile {
 			continue
 		}
 
@@ -506,8 +919,12 @@ func (sm *SessionManager) migrateJSON() {
 
 		sm.sessions[old.Key] = s
 		sm.rewriteFile(old.Key, s)
+		if _, err := os.Stat(filepath.Join(sm.storage, sanitizeFilename(old.Key)+".jsonl")); err != nil {
+			return fmt.Errorf("migrate %s: %w", old.Key, err)
+		}
 
 		os.Remove(jsonPath)
 		logger.Info("session: migrated %s from JSON to JSONL", old.Key)
 	}
+	return nil
 }
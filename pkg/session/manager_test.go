@@ -0,0 +1,49 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteKeepsOtherProcessAppends(t *testing.T) {
+	dir := t.TempDir()
+	gateway := NewSessionManager(dir)
+	gateway.AddMessage("cli:default", "user", "one")
+	gateway.AddMessage("cli:default", "assistant", "two")
+	gateway.SetVar("cli:default", "city", "Paris")
+
+	// Another process (e.g. `localagent agent`) appends to the same session.
+	other := NewSessionManager(dir)
+	other.AddMessage("cli:default", "user", "three")
+	other.SetVar("cli:default", "city", "Lyon")
+	gateway.AddMessage("cli:default", "assistant", "four")
+
+	gateway.TruncateHistory("cli:default", 10)
+
+	h := NewSessionManager(dir).GetHistory("cli:default")
+	var got []string
+	for _, m := range h {
+		got = append(got, m.Content)
+	}
+	if len(got) != 4 || got[0] != "one" || got[2] != "three" || got[3] != "four" {
+		t.Errorf("history after rewrite = %v", got)
+	}
+	if v := NewSessionManager(dir).GetVars("cli:default")["city"]; v != "Lyon" {
+		t.Errorf("city = %q", v)
+	}
+}
+
+func TestStaleLocksRemoved(t *testing.T) {
+	dir := t.TempDir()
+	NewSessionManager(dir).AddMessage("web:default", "user", "hi")
+	os.WriteFile(filepath.Join(dir, "gone.jsonl.lock"), nil, 0644)
+
+	NewSessionManager(dir)
+	if _, err := os.Stat(filepath.Join(dir, "gone.jsonl.lock")); !os.IsNotExist(err) {
+		t.Errorf("stale lock kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web_default.jsonl.lock")); err != nil {
+		t.Errorf("lock of a live session removed: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package sleep
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+	"localagent/pkg/logger"
+)
+
+const sensorPollInterval = 5 * time.Minute
+
+// DefaultAsleepStates are sensor states that mean the user is asleep,
+// covering bed occupancy binary sensors and sleep-tracking apps.
+var DefaultAsleepStates = []string{"on", "asleep", "sleeping", "sleep", "in_bed"}
+
+// SensorWatcher polls a Home Assistant entity and records sleep from its
+// state changes.
+type SensorWatcher struct {
+	tracker      *Tracker
+	url          string
+	apiKey       string
+	entity       string
+	asleepStates []string
+	client       *http.Client
+
+	stop chan struct{}
+}
+
+// NewSensorWatcher watches entity (e.g. "binary_sensor.bed_occupancy") on
+// the Home Assistant at haURL. asleepStates defaults to DefaultAsleepStates.
+func NewSensorWatcher(tracker *Tracker, haURL, apiKey, entity string, asleepStates []string) *SensorWatcher {
+	if len(asleepStates) == 0 {
+		asleepStates = DefaultAsleepStates
+	}
+	return &SensorWatcher{
+		tracker:      tracker,
+		url:          strings.TrimRight(haURL, "/"),
+		apiKey:       apiKey,
+		entity:       entity,
+		asleepStates: asleepStates,
+		client:       httpclient.New("sleep", httpclient.WithTimeout(10*time.Second)),
+		stop:         make(chan struct{}),
+	}
+}
+
+func (w *SensorWatcher) Start() {
+	ticker := time.NewTicker(sensorPollInterval)
+	go func() {
+		w.poll()
+		for {
+			select {
+			case <-ticker.C:
+				w.poll()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("sleep sensor watcher started (%s)", w.entity)
+}
+
+func (w *SensorWatcher) Stop() {
+	close(w.stop)
+}
+
+func (w *SensorWatcher) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := w.Check(ctx); err != nil {
+		logger.Warn("sleep: %v", err)
+	}
+}
+
+// Check reads the entity once and records a change.
+func (w *SensorWatcher) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/states/%s", w.url, w.entity), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.apiKey)
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", w.entity, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("read %s: Home Assistant returned status %d", w.entity, resp.StatusCode)
+	}
+	body, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return err
+	}
+	var data struct {
+		State       string    `json:"state"`
+		LastChanged time.Time `json:"last_changed"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("parse %s: %w", w.entity, err)
+	}
+	return w.record(data.State, data.LastChanged)
+}
+
+// record applies a sensor state that has held since changed.
+func (w *SensorWatcher) record(state string, changed time.Time) error {
+	state = strings.ToLower(state)
+	if state == "unavailable" || state == "unknown" {
+		return nil
+	}
+	if changed.IsZero() {
+		changed = w.tracker.now()
+	}
+	if slices.Contains(w.asleepStates, state) {
+		return w.tracker.SetAsleep(changed, SourceSensor)
+	}
+	n, err := w.tracker.SetAwake(changed, SourceSensor)
+	if n != nil {
+		logger.Info("sleep: recorded %s of sleep", n.Duration().Round(time.Minute))
+	}
+	return err
+}
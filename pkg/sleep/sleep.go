@@ -0,0 +1,323 @@
+// Package sleep keeps track of when the user sleeps. The schedule is set
+// explicitly (config or the sleep tool) or learned from recorded nights;
+// nights are recorded from a Home Assistant sensor or from the user saying
+// they go to bed and get up. The heartbeat uses it as its active hours and
+// mentions last night's sleep in the first heartbeat of the morning.
+package sleep
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+const (
+	stateNamespace = "sleep"
+
+	// maxNights is how many recorded nights are kept.
+	maxNights = 30
+
+	// learnFrom is how many recent nights the schedule is learned from,
+	// and minLearnNights how many are needed before it is.
+	learnFrom      = 14
+	minLearnNights = 3
+
+	// Periods outside this range are naps or a forgotten "awake", not nights.
+	minNight = 2 * time.Hour
+	maxNight = 16 * time.Hour
+
+	// staleAsleep is when an "asleep" without a schedule stops counting.
+	staleAsleep = 12 * time.Hour
+
+	// sensorFresh is how long a sensor reading is trusted without a poll.
+	sensorFresh = 30 * time.Minute
+
+	// morningWindow is how long after waking the morning note is offered.
+	morningWindow = 6 * time.Hour
+)
+
+const (
+	SourceManual = "manual"
+	SourceSensor = "sensor"
+)
+
+// Night is one recorded sleep.
+type Night struct {
+	StartMS int64  `json:"startMs"`
+	EndMS   int64  `json:"endMs"`
+	Source  string `json:"source"`
+}
+
+func (n Night) Start() time.Time        { return time.UnixMilli(n.StartMS).In(when.Location()) }
+func (n Night) End() time.Time          { return time.UnixMilli(n.EndMS).In(when.Location()) }
+func (n Night) Duration() time.Duration { return time.Duration(n.EndMS-n.StartMS) * time.Millisecond }
+
+// current is the last observed state.
+type current struct {
+	Asleep    bool   `json:"asleep"`
+	SinceMS   int64  `json:"sinceMs"`
+	Source    string `json:"source"`
+	CheckedMS int64  `json:"checkedMs,omitempty"` // last sensor poll
+}
+
+// Schedule is a bedtime and wake time as "HH:MM".
+type Schedule struct {
+	Bedtime  string `json:"bedtime"`
+	WakeTime string `json:"wakeTime"`
+	Source   string `json:"source,omitempty"` // "config", "manual" or "learned"
+}
+
+// Tracker records sleep in the workspace state.
+type Tracker struct {
+	state    *state.Manager
+	fallback Schedule // from config
+	now      func() time.Time
+}
+
+// NewTracker creates a tracker. bedtime and wakeTime ("HH:MM") set the
+// schedule until the user sets one or enough nights are recorded; either
+// may be empty.
+func NewTracker(workspace, bedtime, wakeTime string) (*Tracker, error) {
+	t := &Tracker{state: state.NewManager(workspace), now: when.Now}
+	if bedtime != "" || wakeTime != "" {
+		if minutes(bedtime) < 0 || minutes(wakeTime) < 0 {
+			return nil, fmt.Errorf("invalid sleep schedule %q-%q (want HH:MM)", bedtime, wakeTime)
+		}
+		t.fallback = Schedule{Bedtime: bedtime, WakeTime: wakeTime, Source: "config"}
+	}
+	return t, nil
+}
+
+// SetAsleep records that the user fell asleep at at. Repeated calls keep
+// the earliest time.
+func (t *Tracker) SetAsleep(at time.Time, source string) error {
+	cur := t.current()
+	if cur.Asleep && cur.Source != "" {
+		return t.touch(cur, source)
+	}
+	return t.state.Set(stateNamespace, "current", current{Asleep: true, SinceMS: at.UnixMilli(), Source: source, CheckedMS: t.now().UnixMilli()})
+}
+
+// SetAwake records that the user woke up at at. If they were asleep, the
+// night is recorded and returned.
+func (t *Tracker) SetAwake(at time.Time, source string) (*Night, error) {
+	cur := t.current()
+	if !cur.Asleep && cur.Source != "" {
+		return nil, t.touch(cur, source)
+	}
+	err := t.state.Set(stateNamespace, "current", current{Asleep: false, SinceMS: at.UnixMilli(), Source: source, CheckedMS: t.now().UnixMilli()})
+	if err != nil || !cur.Asleep {
+		return nil, err
+	}
+	n := Night{StartMS: cur.SinceMS, EndMS: at.UnixMilli(), Source: source}
+	if d := n.Duration(); d < minNight || d > maxNight {
+		logger.Info("sleep: ignoring %s of sleep", d.Round(time.Minute))
+		return nil, nil
+	}
+	nights := append(t.Nights(), n)
+	if len(nights) > maxNights {
+		nights = nights[len(nights)-maxNights:]
+	}
+	return &n, t.state.Set(stateNamespace, "nights", nights)
+}
+
+// touch notes a sensor poll that confirmed the current state.
+func (t *Tracker) touch(cur current, source string) error {
+	if source != SourceSensor {
+		return nil
+	}
+	cur.Source = source
+	cur.CheckedMS = t.now().UnixMilli()
+	return t.state.Set(stateNamespace, "current", cur)
+}
+
+// SetSchedule sets the schedule explicitly; empty values clear it so the
+// configured or learned one applies again.
+func (t *Tracker) SetSchedule(bedtime, wakeTime string) error {
+	if bedtime == "" && wakeTime == "" {
+		return t.state.Delete(stateNamespace, "schedule")
+	}
+	if minutes(bedtime) < 0 || minutes(wakeTime) < 0 {
+		return fmt.Errorf("invalid schedule %q-%q (want HH:MM)", bedtime, wakeTime)
+	}
+	return t.state.Set(stateNamespace, "schedule", Schedule{Bedtime: bedtime, WakeTime: wakeTime, Source: SourceManual})
+}
+
+// Schedule returns the schedule set by the user, else the configured one,
+// else one learned from recent nights. ok is false when none is known.
+func (t *Tracker) Schedule() (s Schedule, ok bool) {
+	if found, _ := t.state.Get(stateNamespace, "schedule", &s); found {
+		return s, true
+	}
+	if t.fallback.Bedtime != "" {
+		return t.fallback, true
+	}
+	return t.learned()
+}
+
+// learned takes the median bedtime and wake time of recent nights.
+func (t *Tracker) learned() (Schedule, bool) {
+	nights := t.Nights()
+	if len(nights) > learnFrom {
+		nights = nights[len(nights)-learnFrom:]
+	}
+	if len(nights) < minLearnNights {
+		return Schedule{}, false
+	}
+	var beds, wakes []int
+	for _, n := range nights {
+		// Bedtimes are counted from noon so 23:00 and 01:00 sort in order.
+		s := n.Start()
+		beds = append(beds, (s.Hour()*60+s.Minute()+12*60)%(24*60))
+		e := n.End()
+		wakes = append(wakes, e.Hour()*60+e.Minute())
+	}
+	return Schedule{
+		Bedtime:  clock(median(beds) - 12*60),
+		WakeTime: clock(median(wakes)),
+		Source:   "learned",
+	}, true
+}
+
+// Nights returns recorded nights, oldest first.
+func (t *Tracker) Nights() []Night {
+	var nights []Night
+	t.state.Get(stateNamespace, "nights", &nights)
+	return nights
+}
+
+func (t *Tracker) current() current {
+	var cur current
+	t.state.Get(stateNamespace, "current", &cur)
+	return cur
+}
+
+// Asleep reports whether the user is probably asleep at now. A fresh
+// sensor reading wins; otherwise the last observation counts until the
+// schedule's next bedtime or wake time. known is false when there is
+// neither a recent observation nor a schedule.
+func (t *Tracker) Asleep(now time.Time) (asleep, known bool) {
+	cur := t.current()
+	if cur.Source == SourceSensor && now.Sub(time.UnixMilli(cur.CheckedMS)) < sensorFresh {
+		return cur.Asleep, true
+	}
+	s, ok := t.Schedule()
+	if !ok {
+		if cur.Source != "" && (!cur.Asleep || now.Sub(time.UnixMilli(cur.SinceMS)) < staleAsleep) {
+			return cur.Asleep, true
+		}
+		return false, false
+	}
+	scheduled, changed := s.at(now)
+	if cur.Source != "" && !time.UnixMilli(cur.SinceMS).Before(changed) {
+		return cur.Asleep, true
+	}
+	return scheduled, true
+}
+
+// at reports whether now falls in the sleep window and when the window
+// last started or ended.
+func (s Schedule) at(now time.Time) (asleep bool, changed time.Time) {
+	bed, wake := minutes(s.Bedtime), minutes(s.WakeTime)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var last time.Time
+	for _, b := range []struct {
+		at     time.Time
+		asleep bool
+	}{
+		{day.Add(time.Duration(bed) * time.Minute), true},
+		{day.Add(time.Duration(wake) * time.Minute), false},
+		{day.AddDate(0, 0, -1).Add(time.Duration(bed) * time.Minute), true},
+		{day.AddDate(0, 0, -1).Add(time.Duration(wake) * time.Minute), false},
+	} {
+		if !b.at.After(now) && b.at.After(last) {
+			last, asleep = b.at, b.asleep
+		}
+	}
+	return asleep, last
+}
+
+// MorningNote describes last night's sleep for the first heartbeat after
+// the user woke up, or returns "" when they haven't woken recently.
+func (t *Tracker) MorningNote(now time.Time) string {
+	nights := t.Nights()
+	if len(nights) == 0 {
+		return ""
+	}
+	n := nights[len(nights)-1]
+	if since := now.Sub(n.End()); since < 0 || since > morningWindow {
+		return ""
+	}
+	note := fmt.Sprintf("Sleep: the user slept ~%s last night (%s-%s)", hours(n.Duration()), n.Start().Format("15:04"), n.End().Format("15:04"))
+	if prev := nights[:len(nights)-1]; len(prev) >= minLearnNights {
+		if len(prev) > learnFrom {
+			prev = prev[len(prev)-learnFrom:]
+		}
+		var total time.Duration
+		for _, p := range prev {
+			total += p.Duration()
+		}
+		note += fmt.Sprintf(", usually ~%s", hours(total/time.Duration(len(prev))))
+	}
+	return note + ". Mention it briefly if you message them this morning."
+}
+
+// Status describes the schedule, current state and recent nights.
+func (t *Tracker) Status(now time.Time) string {
+	var out string
+	if s, ok := t.Schedule(); ok {
+		out = fmt.Sprintf("Schedule: %s-%s (%s)", s.Bedtime, s.WakeTime, s.Source)
+	} else {
+		out = fmt.Sprintf("Schedule: unknown (set one, or record %d nights to learn it)", minLearnNights)
+	}
+	if asleep, known := t.Asleep(now); known {
+		state := "awake"
+		if asleep {
+			state = "asleep"
+		}
+		out += "\nNow: " + state
+	}
+	nights := t.Nights()
+	if len(nights) > 7 {
+		nights = nights[len(nights)-7:]
+	}
+	for i := len(nights) - 1; i >= 0; i-- {
+		n := nights[i]
+		out += fmt.Sprintf("\n- %s: %s-%s, ~%s", n.End().Format("Mon 01-02"), n.Start().Format("15:04"), n.End().Format("15:04"), hours(n.Duration()))
+	}
+	return out
+}
+
+// hours rounds d to the half hour, e.g. "6.5h".
+func hours(d time.Duration) string {
+	h := math.Round(d.Hours()*2) / 2
+	return fmt.Sprintf("%gh", h)
+}
+
+func median(v []int) int {
+	slices.Sort(v)
+	if len(v)%2 == 1 {
+		return v[len(v)/2]
+	}
+	return (v[len(v)/2-1] + v[len(v)/2]) / 2
+}
+
+// minutes parses "HH:MM" into minutes since midnight, or -1.
+func minutes(hm string) int {
+	t, err := time.Parse("15:04", hm)
+	if err != nil {
+		return -1
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+// clock formats minutes since midnight (wrapping) as "HH:MM".
+func clock(m int) string {
+	m = ((m % (24 * 60)) + 24*60) % (24 * 60)
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
@@ -0,0 +1,144 @@
+package sleep
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(day, hour, minute int) time.Time {
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+}
+
+func newTestTracker(t *testing.T, bedtime, wake string) *Tracker {
+	t.Helper()
+	tr, err := NewTracker(t.TempDir(), bedtime, wake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestLearnedSchedule(t *testing.T) {
+	tr := newTestTracker(t, "", "")
+	if _, ok := tr.Schedule(); ok {
+		t.Fatal("no schedule should be known yet")
+	}
+	for _, n := range [][2]time.Time{
+		{at(10, 23, 30), at(11, 7, 0)},
+		{at(12, 0, 30), at(12, 7, 30)},
+		{at(12, 23, 0), at(13, 6, 30)},
+		{at(13, 14, 0), at(13, 14, 40)}, // nap, not a night
+	} {
+		tr.SetAsleep(n[0], SourceManual)
+		tr.SetAwake(n[1], SourceManual)
+	}
+	if got := len(tr.Nights()); got != 3 {
+		t.Fatalf("recorded %d nights, want 3", got)
+	}
+	s, ok := tr.Schedule()
+	if !ok || s.Bedtime != "23:30" || s.WakeTime != "07:00" || s.Source != "learned" {
+		t.Errorf("schedule = %+v, %v", s, ok)
+	}
+
+	if err := tr.SetSchedule("22:45", "06:15"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := tr.Schedule(); s.Bedtime != "22:45" || s.Source != SourceManual {
+		t.Errorf("manual schedule = %+v", s)
+	}
+	if err := tr.SetSchedule("late", "06:15"); err == nil {
+		t.Error("invalid schedule should fail")
+	}
+}
+
+func TestAsleep(t *testing.T) {
+	tr := newTestTracker(t, "23:00", "07:00")
+	for _, c := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{at(14, 2, 0), true},
+		{at(14, 7, 0), false},
+		{at(14, 22, 59), false},
+		{at(14, 23, 0), true},
+	} {
+		if asleep, known := tr.Asleep(c.now); !known || asleep != c.want {
+			t.Errorf("Asleep(%s) = %v, %v", c.now.Format("15:04"), asleep, known)
+		}
+	}
+
+	// Getting up early counts until the next bedtime.
+	tr.SetAwake(at(15, 5, 30), SourceManual)
+	if asleep, _ := tr.Asleep(at(15, 6, 0)); asleep {
+		t.Error("user said they're awake")
+	}
+	if asleep, _ := tr.Asleep(at(15, 23, 30)); !asleep {
+		t.Error("bedtime should apply again after an earlier awake")
+	}
+
+	// An early night counts until the next wake time.
+	tr.SetAsleep(at(16, 21, 0), SourceManual)
+	if asleep, _ := tr.Asleep(at(16, 21, 30)); !asleep {
+		t.Error("user said they're asleep")
+	}
+
+	// A fresh sensor reading wins over the schedule.
+	tr.now = func() time.Time { return at(17, 7, 30) }
+	tr.SetAsleep(at(16, 21, 0), SourceSensor)
+	if asleep, _ := tr.Asleep(at(17, 7, 40)); !asleep {
+		t.Error("sensor says still in bed")
+	}
+	if asleep, _ := tr.Asleep(at(17, 9, 0)); asleep {
+		t.Error("stale sensor reading should fall back to the schedule")
+	}
+}
+
+func TestMorningNote(t *testing.T) {
+	tr := newTestTracker(t, "", "")
+	for day := 10; day < 13; day++ {
+		tr.SetAsleep(at(day, 23, 0), SourceManual)
+		tr.SetAwake(at(day+1, 7, 0), SourceManual)
+	}
+	tr.SetAsleep(at(13, 23, 40), SourceManual)
+	n, err := tr.SetAwake(at(14, 6, 10), SourceManual)
+	if err != nil || n == nil {
+		t.Fatalf("night = %v, %v", n, err)
+	}
+	note := tr.MorningNote(at(14, 8, 0))
+	if !strings.Contains(note, "slept ~6.5h last night (23:40-06:10), usually ~8h") {
+		t.Errorf("note = %q", note)
+	}
+	if note := tr.MorningNote(at(14, 13, 0)); note != "" {
+		t.Errorf("afternoon note = %q", note)
+	}
+}
+
+func TestSensorWatcher(t *testing.T) {
+	state := `{"state":"on","last_changed":"` + at(14, 23, 15).Format(time.RFC3339) + `"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/states/binary_sensor.bed" || r.Header.Get("Authorization") != "Bearer key" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(state))
+	}))
+	defer srv.Close()
+
+	tr := newTestTracker(t, "", "")
+	w := NewSensorWatcher(tr, srv.URL, "key", "binary_sensor.bed", nil)
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	state = `{"state":"off","last_changed":"` + at(15, 6, 45).Format(time.RFC3339) + `"}`
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	nights := tr.Nights()
+	if len(nights) != 1 || nights[0].Duration() != 7*time.Hour+30*time.Minute || nights[0].Source != SourceSensor {
+		t.Errorf("nights = %+v", nights)
+	}
+}
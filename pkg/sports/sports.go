@@ -0,0 +1,269 @@
+// Package sports reads team results and fixtures from TheSportsDB and
+// watches followed teams for finished matches.
+package sports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/when"
+)
+
+var baseURL = "https://www.thesportsdb.com/api/v1/json"
+
+// Domain is the host results and fixtures are fetched from.
+const Domain = "www.thesportsdb.com"
+
+// freeKey is TheSportsDB's public key, enough for a few followed teams.
+const freeKey = "123"
+
+// Team is a team on TheSportsDB.
+type Team struct {
+	ID      string
+	Name    string
+	League  string
+	Sport   string
+	Country string
+}
+
+// Match is a past or upcoming event of a team.
+type Match struct {
+	ID        string
+	League    string
+	Home      string
+	Away      string
+	HomeScore int
+	AwayScore int
+	Scored    bool      // the scores are known
+	Time      time.Time // kick-off; midnight UTC when only the date is known
+	Status    string    // e.g. "Match Finished", "FT", "NS"; often empty
+	Venue     string
+}
+
+// Finished reports whether m is over and has a final score.
+func (m Match) Finished() bool {
+	if !m.Scored {
+		return false
+	}
+	switch strings.ToUpper(m.Status) {
+	case "", "FT", "AET", "PEN", "AOT", "AP", "MATCH FINISHED", "FINISHED", "FINAL":
+		return true
+	}
+	return false
+}
+
+// Result is the score line, e.g. "Arsenal 2-1 Everton".
+func (m Match) Result() string {
+	return fmt.Sprintf("%s %d-%d %s", m.Home, m.HomeScore, m.AwayScore, m.Away)
+}
+
+// Outcome is "won", "drew" or "lost" from team's side, or "" when team did
+// not play or the match has no score.
+func (m Match) Outcome(team string) string {
+	if !m.Scored {
+		return ""
+	}
+	diff := m.HomeScore - m.AwayScore
+	switch {
+	case strings.EqualFold(team, m.Away):
+		diff = -diff
+	case !strings.EqualFold(team, m.Home):
+		return ""
+	}
+	switch {
+	case diff > 0:
+		return "won"
+	case diff < 0:
+		return "lost"
+	}
+	return "drew"
+}
+
+// Format describes m on one line, e.g. "Sun May 19: Arsenal 2-1 Everton
+// (English Premier League)" or "Sat Aug 24 16:00: Arsenal vs Wolves (...)".
+func (m Match) Format() string {
+	var sb strings.Builder
+	if !m.Time.IsZero() {
+		t := m.Time.In(when.Location())
+		if m.Scored || (m.Time.Hour() == 0 && m.Time.Minute() == 0) {
+			sb.WriteString(t.Format("Mon Jan 2") + ": ")
+		} else {
+			sb.WriteString(t.Format("Mon Jan 2 15:04") + ": ")
+		}
+	}
+	if m.Scored {
+		sb.WriteString(m.Result())
+		if !m.Finished() && m.Status != "" {
+			fmt.Fprintf(&sb, " (%s)", m.Status)
+		}
+	} else {
+		fmt.Fprintf(&sb, "%s vs %s", m.Home, m.Away)
+	}
+	if m.League != "" {
+		fmt.Fprintf(&sb, " (%s)", m.League)
+	}
+	return sb.String()
+}
+
+// Client queries TheSportsDB and caches team lookups by name.
+type Client struct {
+	key    string
+	client *http.Client
+
+	mu    sync.Mutex
+	teams map[string]Team // lower-cased name -> team
+}
+
+// NewClient uses key, or the free key when it is empty.
+func NewClient(key string) *Client {
+	if key == "" {
+		key = freeKey
+	}
+	return &Client{
+		key:    key,
+		client: httpclient.New("sports", httpclient.WithTimeout(15*time.Second)),
+		teams:  make(map[string]Team),
+	}
+}
+
+// Resolve returns the configured team, looking it up by name when it has
+// no ID.
+func (c *Client) Resolve(ctx context.Context, cfg config.SportsTeamConfig) (Team, error) {
+	if cfg.ID != "" {
+		return Team{ID: cfg.ID, Name: cfg.Name}, nil
+	}
+	return c.Search(ctx, cfg.Name)
+}
+
+// Search finds a team by name, preferring an exact match.
+func (c *Client) Search(ctx context.Context, name string) (Team, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	c.mu.Lock()
+	team, ok := c.teams[key]
+	c.mu.Unlock()
+	if ok {
+		return team, nil
+	}
+	var data struct {
+		Teams []struct {
+			ID      string `json:"idTeam"`
+			Name    string `json:"strTeam"`
+			League  string `json:"strLeague"`
+			Sport   string `json:"strSport"`
+			Country string `json:"strCountry"`
+		} `json:"teams"`
+	}
+	if err := c.get(ctx, "searchteams.php", url.Values{"t": {name}}, &data); err != nil {
+		return Team{}, err
+	}
+	if len(data.Teams) == 0 {
+		return Team{}, fmt.Errorf("no team called %q", name)
+	}
+	best := data.Teams[0]
+	for _, t := range data.Teams {
+		if strings.EqualFold(t.Name, name) {
+			best = t
+			break
+		}
+	}
+	team = Team{ID: best.ID, Name: best.Name, League: best.League, Sport: best.Sport, Country: best.Country}
+	c.mu.Lock()
+	c.teams[key] = team
+	c.mu.Unlock()
+	return team, nil
+}
+
+// Last returns the team's most recent matches, newest first.
+func (c *Client) Last(ctx context.Context, teamID string) ([]Match, error) {
+	var data struct {
+		Results []event `json:"results"`
+	}
+	if err := c.get(ctx, "eventslast.php", url.Values{"id": {teamID}}, &data); err != nil {
+		return nil, err
+	}
+	return matches(data.Results), nil
+}
+
+// Next returns the team's upcoming matches, soonest first.
+func (c *Client) Next(ctx context.Context, teamID string) ([]Match, error) {
+	var data struct {
+		Events []event `json:"events"`
+	}
+	if err := c.get(ctx, "eventsnext.php", url.Values{"id": {teamID}}, &data); err != nil {
+		return nil, err
+	}
+	return matches(data.Events), nil
+}
+
+func (c *Client) get(ctx context.Context, endpoint string, q url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/"+url.PathEscape(c.key)+"/"+endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// event is a TheSportsDB event; numbers come as strings or null.
+type event struct {
+	ID        string  `json:"idEvent"`
+	League    string  `json:"strLeague"`
+	Home      string  `json:"strHomeTeam"`
+	Away      string  `json:"strAwayTeam"`
+	HomeScore *string `json:"intHomeScore"`
+	AwayScore *string `json:"intAwayScore"`
+	Timestamp string  `json:"strTimestamp"` // UTC, e.g. "2024-05-19T15:00:00"
+	Date      string  `json:"dateEvent"`
+	Status    string  `json:"strStatus"`
+	Venue     string  `json:"strVenue"`
+}
+
+func matches(events []event) []Match {
+	out := make([]Match, 0, len(events))
+	for _, e := range events {
+		m := Match{ID: e.ID, League: e.League, Home: e.Home, Away: e.Away, Status: e.Status, Venue: e.Venue, Time: e.time()}
+		if e.HomeScore != nil && e.AwayScore != nil {
+			home, err1 := strconv.Atoi(*e.HomeScore)
+			away, err2 := strconv.Atoi(*e.AwayScore)
+			if err1 == nil && err2 == nil {
+				m.HomeScore, m.AwayScore, m.Scored = home, away, true
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func (e event) time() time.Time {
+	ts := strings.TrimSuffix(strings.TrimSuffix(e.Timestamp, "Z"), "+00:00")
+	if t, err := time.Parse("2006-01-02T15:04:05", ts); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", e.Date); err == nil {
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,110 @@
+package sports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/when"
+)
+
+// fakeAPI serves a team search and the team's last and next events; last
+// is swapped by tests to simulate a match finishing.
+func fakeAPI(t *testing.T, last *string) *int {
+	t.Helper()
+	searches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/123/") {
+			t.Errorf("path = %s, want the free key", r.URL.Path)
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/searchteams.php"):
+			searches++
+			w.Write([]byte(`{"teams":[{"idTeam":"1","strTeam":"Arsenal Tula","strLeague":"Russian FNL"},
+				{"idTeam":"133604","strTeam":"Arsenal","strLeague":"English Premier League","strSport":"Soccer"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/eventslast.php") && r.URL.Query().Get("id") == "133604":
+			w.Write([]byte(*last))
+		case strings.HasSuffix(r.URL.Path, "/eventsnext.php"):
+			w.Write([]byte(`{"events":[{"idEvent":"9","strLeague":"English Premier League","strHomeTeam":"Arsenal","strAwayTeam":"Wolves",
+				"intHomeScore":null,"intAwayScore":null,"strTimestamp":"2026-08-22T14:00:00","strStatus":"NS"}]}`))
+		default:
+			w.Write([]byte(`{"results":null}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	old := baseURL
+	baseURL = srv.URL
+	t.Cleanup(func() { baseURL = old })
+	return &searches
+}
+
+const lastResults = `{"results":[
+	{"idEvent":"7","strLeague":"English Premier League","strHomeTeam":"Everton","strAwayTeam":"Arsenal","intHomeScore":"1","intAwayScore":"2","strTimestamp":"2026-08-15T11:30:00","strStatus":"Match Finished"},
+	{"idEvent":"6","strLeague":"Club Friendlies","strHomeTeam":"Arsenal","strAwayTeam":"Lyon","intHomeScore":"0","intAwayScore":"0","dateEvent":"2026-08-09","strStatus":""}]}`
+
+func TestClient(t *testing.T) {
+	when.SetLocation(time.UTC)
+	last := lastResults
+	searches := fakeAPI(t, &last)
+	c := NewClient("")
+	team, err := c.Search(context.Background(), "arsenal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if team.ID != "133604" || team.League != "English Premier League" {
+		t.Errorf("team = %+v, want the exact match", team)
+	}
+	c.Resolve(context.Background(), config.SportsTeamConfig{Name: "Arsenal"})
+	if *searches != 1 {
+		t.Errorf("searched %d times, want 1 (cached)", *searches)
+	}
+
+	matches, err := c.Last(context.Background(), team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matches[0].Format(); got != "Sat Aug 15: Everton 1-2 Arsenal (English Premier League)" {
+		t.Errorf("format = %q", got)
+	}
+	if !matches[1].Finished() || matches[1].Outcome("Arsenal") != "drew" || matches[0].Outcome("Arsenal") != "won" {
+		t.Errorf("matches = %+v", matches)
+	}
+	next, err := c.Next(context.Background(), team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := next[0].Format(); got != "Sat Aug 22 14:00: Arsenal vs Wolves (English Premier League)" || next[0].Finished() {
+		t.Errorf("fixture = %q", got)
+	}
+}
+
+func TestWatcherReportsNewResults(t *testing.T) {
+	last := lastResults
+	fakeAPI(t, &last)
+	var finals []Final
+	w := NewWatcher(NewClient(""), []config.SportsTeamConfig{{Name: "Arsenal", ID: "133604"}}, t.TempDir(), 0, func(f Final) {
+		finals = append(finals, f)
+	})
+	w.now = func() time.Time { return time.Date(2026, 8, 22, 16, 0, 0, 0, time.UTC) }
+
+	w.Check()
+	if len(finals) != 0 {
+		t.Fatalf("first check reported %+v; it should only record past results", finals)
+	}
+	last = `{"results":[
+		{"idEvent":"9","strLeague":"English Premier League","strHomeTeam":"Arsenal","strAwayTeam":"Wolves","intHomeScore":"0","intAwayScore":"1","strTimestamp":"2026-08-22T14:00:00","strStatus":"FT"},
+		{"idEvent":"8","strLeague":"Carabao Cup","strHomeTeam":"Arsenal","strAwayTeam":"Leeds","intHomeScore":"1","intAwayScore":"1","strTimestamp":"2026-08-22T15:30:00","strStatus":"2H"},
+		` + lastResults[len(`{"results":[`):]
+	w.Check()
+	w.Check()
+	if len(finals) != 1 {
+		t.Fatalf("finals = %+v, want the Wolves match once", finals)
+	}
+	if got := finals[0].Message(); got != "Full time: Arsenal 0-1 Wolves (English Premier League). Arsenal lost." {
+		t.Errorf("message = %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package sports
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+const (
+	stateNamespace = "sports"
+	defaultPoll    = 15 * time.Minute
+	// recent bounds how long after kick-off a newly seen result is still
+	// reported; older ones (a late-updated feed) are only recorded.
+	recent = 24 * time.Hour
+	// keepSeen is how many reported match IDs are remembered per team.
+	keepSeen = 20
+)
+
+// Final is a finished match of a followed team.
+type Final struct {
+	Team  Team
+	Match Match
+}
+
+// Message describes the result for the heartbeat, e.g. "Full time:
+// Arsenal 2-1 Everton (English Premier League). Arsenal won."
+func (f Final) Message() string {
+	msg := "Full time: " + f.Match.Result()
+	if f.Match.League != "" {
+		msg += " (" + f.Match.League + ")"
+	}
+	if outcome := f.Match.Outcome(f.Team.Name); outcome != "" {
+		msg += fmt.Sprintf(". %s %s.", f.Team.Name, outcome)
+	}
+	return msg
+}
+
+// Watcher polls the followed teams' latest matches and reports each one
+// that finishes.
+type Watcher struct {
+	client *Client
+	teams  []config.SportsTeamConfig
+	state  *state.Manager
+	poll   time.Duration
+	handle func(Final)
+	now    func() time.Time
+	stop   chan struct{}
+}
+
+// NewWatcher checks teams every poll (default 15 minutes).
+func NewWatcher(client *Client, teams []config.SportsTeamConfig, workspace string, poll time.Duration, handle func(Final)) *Watcher {
+	if poll <= 0 {
+		poll = defaultPoll
+	}
+	return &Watcher{client: client, teams: teams, state: state.NewManager(workspace), poll: poll, handle: handle, now: time.Now, stop: make(chan struct{})}
+}
+
+func (w *Watcher) Start() {
+	ticker := time.NewTicker(w.poll)
+	go func() {
+		w.Check()
+		for {
+			select {
+			case <-ticker.C:
+				w.Check()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("sports: watching %d teams for results every %s", len(w.teams), w.poll)
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+}
+
+// Check reports finished matches not seen before. The first check of a
+// team only records its past results.
+func (w *Watcher) Check() {
+	now := w.now()
+	for _, cfg := range w.teams {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		team, err := w.client.Resolve(ctx, cfg)
+		var last []Match
+		if err == nil {
+			last, err = w.client.Last(ctx, team.ID)
+		}
+		cancel()
+		if err != nil {
+			logger.Warn("sports: %s: %v", cfg.Name, err)
+			continue
+		}
+		var seen []string
+		known, _ := w.state.Get(stateNamespace, "seen:"+team.ID, &seen)
+		changed := false
+		// Oldest first, so several new results are reported in order.
+		for _, m := range slices.Backward(last) {
+			if !m.Finished() || slices.Contains(seen, m.ID) {
+				continue
+			}
+			seen, changed = append(seen, m.ID), true
+			if known && now.Sub(m.Time) < recent {
+				logger.Info("sports: %s: %s", team.Name, m.Result())
+				w.handle(Final{Team: team, Match: m})
+			}
+		}
+		if !known || changed {
+			if len(seen) > keepSeen {
+				seen = seen[len(seen)-keepSeen:]
+			}
+			if err := w.state.Set(stateNamespace, "seen:"+team.ID, seen); err != nil {
+				logger.Warn("sports: save: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/migrate"
+)
+
+// Migrations returns the on-disk format migrations for workspace state.
+func Migrations(workspace string) []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "import calendar_reminders.json", Up: func() error {
+			return importLegacyFile(workspace, filepath.Join(workspace, "calendar_reminders.json"), "calendar", "notified")
+		}},
+	}
+}
+
+// importLegacyFile moves a standalone JSON file into namespace/key and
+// removes it. A missing file is not an error; an unreadable one is dropped,
+// as the old loader did.
+func importLegacyFile(workspace, path, namespace, key string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		logger.Warn("state: dropping unreadable %s", path)
+		return os.Remove(path)
+	}
+	if err := NewManager(workspace).Set(namespace, key, json.RawMessage(data)); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -1,16 +1,24 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"localagent/pkg/filelock"
+	"localagent/pkg/logger"
+	"localagent/pkg/storage"
 )
 
 // State represents the persistent state for a workspace.
-// It includes information about the last active channel/chat.
+// It includes information about the last active channel/chat and namespaced
+// values owned by other subsystems.
 type State struct {
 	// LastChannel is the last channel used for communication
 	LastChannel string `json:"last_channel,omitempty"`
@@ -20,13 +28,21 @@ type State struct {
 
 	// Timestamp is the last time this state was updated
 	Timestamp time.Time `json:"timestamp"`
+
+	// Namespaces holds JSON values by namespace and key, e.g.
+	// "heartbeat" -> "last_alert". Use Get/Set rather than a new file.
+	Namespaces map[string]map[string]json.RawMessage `json:"namespaces,omitempty"`
 }
 
 // Manager manages persistent state with atomic saves.
+//
+// Several managers (in one process or several) may share a workspace: every
+// read reloads the file and every write is a locked read-modify-write, so
+// they always see each other's changes.
 type Manager struct {
 	workspace string
 	state     *State
-	mu        sync.RWMutex
+	mu        sync.Mutex
 	stateFile string
 }
 
@@ -52,105 +68,205 @@ func NewManager(workspace string) *Manager {
 // This method uses a temp file + rename pattern for atomic writes,
 // ensuring that the state file is never corrupted even if the process crashes.
 func (sm *Manager) SetLastChannel(channel string) error {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	// Update state
-	sm.state.LastChannel = channel
-	sm.state.Timestamp = time.Now()
-
-	// Atomic save using temp file + rename
-	if err := sm.saveAtomic(); err != nil {
-		return fmt.Errorf("failed to save state atomically: %w", err)
-	}
-
-	return nil
+	return sm.update(func(s *State) error {
+		s.LastChannel = channel
+		return nil
+	})
 }
 
 // SetLastChatID atomically updates the last chat ID and saves the state.
 func (sm *Manager) SetLastChatID(chatID string) error {
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
-
-	// Update state
-	sm.state.LastChatID = chatID
-	sm.state.Timestamp = time.Now()
-
-	// Atomic save using temp file + rename
-	if err := sm.saveAtomic(); err != nil {
-		return fmt.Errorf("failed to save state atomically: %w", err)
-	}
-
-	return nil
+	return sm.update(func(s *State) error {
+		s.LastChatID = chatID
+		return nil
+	})
 }
 
 // GetLastChannel returns the last channel from the state.
 func (sm *Manager) GetLastChannel() string {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.state.LastChannel
+	return sm.snapshot().LastChannel
 }
 
 // GetLastChatID returns the last chat ID from the state.
 func (sm *Manager) GetLastChatID() string {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.state.LastChatID
+	return sm.snapshot().LastChatID
 }
 
 // GetTimestamp returns the timestamp of the last state update.
 func (sm *Manager) GetTimestamp() time.Time {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-	return sm.state.Timestamp
+	return sm.snapshot().Timestamp
 }
 
-// saveAtomic performs an atomic save using temp file + rename.
-// This ensures that the state file is never corrupted:
-// 1. Write to a temp file
-// 2. Rename temp file to target (atomic on POSIX systems)
-// 3. If rename fails, cleanup the temp file
-//
-// Must be called with the lock held.
-func (sm *Manager) saveAtomic() error {
-	// Create temp file in the same directory as the target
-	tempFile := sm.stateFile + ".tmp"
+// Get decodes the value stored under namespace/key into v.
+// Returns false if the key is not set.
+func (sm *Manager) Get(namespace, key string, v any) (bool, error) {
+	raw, ok := sm.snapshot().Namespaces[namespace][key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("decode %s/%s: %w", namespace, key, err)
+	}
+	return true, nil
+}
 
-	// Marshal state to JSON
-	data, err := json.MarshalIndent(sm.state, "", "  ")
+// Set stores v under namespace/key.
+func (sm *Manager) Set(namespace, key string, v any) error {
+	raw, err := json.Marshal(v)
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return fmt.Errorf("encode %s/%s: %w", namespace, key, err)
+	}
+	return sm.update(func(s *State) error {
+		setRaw(s, namespace, key, raw)
+		return nil
+	})
+}
+
+// Delete removes namespace/key. Deleting a missing key is not an error.
+func (sm *Manager) Delete(namespace, key string) error {
+	return sm.update(func(s *State) error {
+		ns := s.Namespaces[namespace]
+		delete(ns, key)
+		if len(ns) == 0 {
+			delete(s.Namespaces, namespace)
+		}
+		return nil
+	})
+}
+
+// Keys returns the keys set in namespace, sorted.
+func (sm *Manager) Keys(namespace string) []string {
+	ns := sm.snapshot().Namespaces[namespace]
+	keys := make([]string, 0, len(ns))
+	for k := range ns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CompareAndSwap sets namespace/key to new only if its current value equals
+// old, compared by JSON encoding. A nil old means the key must be unset.
+// Returns whether the swap happened.
+func (sm *Manager) CompareAndSwap(namespace, key string, old, new any) (bool, error) {
+	var oldRaw []byte
+	if old != nil {
+		var err error
+		if oldRaw, err = json.Marshal(old); err != nil {
+			return false, fmt.Errorf("encode %s/%s: %w", namespace, key, err)
+		}
+	}
+	newRaw, err := json.Marshal(new)
+	if err != nil {
+		return false, fmt.Errorf("encode %s/%s: %w", namespace, key, err)
 	}
 
-	// Write to temp file
-	if err := os.WriteFile(tempFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write temp file: %w", err)
+	swapped := false
+	err = sm.update(func(s *State) error {
+		cur, ok := s.Namespaces[namespace][key]
+		if ok != (old != nil) || (ok && !jsonEqual(cur, oldRaw)) {
+			return errNoChange
+		}
+		setRaw(s, namespace, key, newRaw)
+		swapped = true
+		return nil
+	})
+	return swapped, err
+}
+
+// errNoChange aborts an update without writing.
+var errNoChange = errors.New("no change")
+
+func setRaw(s *State, namespace, key string, raw json.RawMessage) {
+	if s.Namespaces == nil {
+		s.Namespaces = make(map[string]map[string]json.RawMessage)
+	}
+	if s.Namespaces[namespace] == nil {
+		s.Namespaces[namespace] = make(map[string]json.RawMessage)
 	}
+	s.Namespaces[namespace][key] = raw
+}
 
-	// Atomic rename from temp to target
-	if err := os.Rename(tempFile, sm.stateFile); err != nil {
-		// Cleanup temp file if rename fails
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temp file: %w", err)
+// jsonEqual compares two JSON documents ignoring formatting.
+func jsonEqual(a, b []byte) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
 	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
 
+// snapshot reloads the state under a shared file lock and returns a copy.
+func (sm *Manager) snapshot() State {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if unlock, err := filelock.RLock(sm.stateFile); err == nil {
+		sm.load()
+		unlock()
+	}
+	return *sm.state
+}
+
+// update applies fn to freshly loaded state and saves it, holding an
+// exclusive file lock so concurrent writers don't lose each other's keys.
+func (sm *Manager) update(fn func(*State) error) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	unlock, err := filelock.Lock(sm.stateFile)
+	if err != nil {
+		return fmt.Errorf("failed to lock state: %w", err)
+	}
+	defer unlock()
+
+	if err := sm.load(); err != nil {
+		// Keep the last good copy; the save below replaces the bad file.
+		logger.Warn("state: %v", err)
+	}
+	if err := fn(sm.state); err != nil {
+		if err == errNoChange {
+			return nil
+		}
+		return err
+	}
+	sm.state.Timestamp = time.Now()
+
+	// Atomic save using temp file + rename
+	if err := sm.saveAtomic(); err != nil {
+		return fmt.Errorf("failed to save state atomically: %w", err)
+	}
 	return nil
 }
 
-// load loads the state from disk.
+// saveAtomic performs an atomic save using temp file + rename
+// (storage.WriteFile), so the state file is never corrupted.
+//
+// Must be called with the lock held.
+func (sm *Manager) saveAtomic() error {
+	data, err := json.MarshalIndent(sm.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return storage.WriteFile(sm.stateFile, data, 0644)
+}
+
+// load loads the state from disk, replacing the in-memory copy.
 func (sm *Manager) load() error {
 	data, err := os.ReadFile(sm.stateFile)
 	if err != nil {
 		// File doesn't exist yet, that's OK
 		if os.IsNotExist(err) {
+			sm.state = &State{}
 			return nil
 		}
 		return fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	if err := json.Unmarshal(data, sm.state); err != nil {
+	loaded := &State{}
+	if err := json.Unmarshal(data, loaded); err != nil {
 		return fmt.Errorf("failed to unmarshal state: %w", err)
 	}
+	sm.state = loaded
 
 	return nil
 }
@@ -0,0 +1,110 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNamespacedKeys(t *testing.T) {
+	ws := t.TempDir()
+	sm := NewManager(ws)
+
+	if err := sm.Set("heartbeat", "sent", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.SetLastChannel("telegram:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second manager on the same workspace sees both writes.
+	other := NewManager(ws)
+	var sent int
+	if ok, err := other.Get("heartbeat", "sent", &sent); !ok || err != nil || sent != 2 {
+		t.Fatalf("Get = %d, %v, %v", sent, ok, err)
+	}
+	if other.GetLastChannel() != "telegram:1" {
+		t.Fatalf("last channel lost: %q", other.GetLastChannel())
+	}
+
+	if err := other.Delete("heartbeat", "sent"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := sm.Keys("heartbeat"); len(keys) != 0 {
+		t.Fatalf("expected no keys after delete, got %v", keys)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	sm := NewManager(t.TempDir())
+
+	if ok, err := sm.CompareAndSwap("cursor", "web", nil, 1); !ok || err != nil {
+		t.Fatalf("swap from unset: %v %v", ok, err)
+	}
+	if ok, _ := sm.CompareAndSwap("cursor", "web", nil, 5); ok {
+		t.Fatal("swap from unset should fail once the key exists")
+	}
+	if ok, _ := sm.CompareAndSwap("cursor", "web", 3, 5); ok {
+		t.Fatal("swap with stale value should fail")
+	}
+	if ok, _ := sm.CompareAndSwap("cursor", "web", 1, 2); !ok {
+		t.Fatal("swap with current value should succeed")
+	}
+}
+
+func TestConcurrentManagers(t *testing.T) {
+	ws := t.TempDir()
+	a, b := NewManager(ws), NewManager(ws)
+
+	// Increment through CAS from two managers; no update may be lost.
+	var wg sync.WaitGroup
+	for _, sm := range []*Manager{a, b} {
+		wg.Go(func() {
+			for range 20 {
+				for {
+					var n int
+					ok, _ := sm.Get("test", "counter", &n)
+					var old any
+					if ok {
+						old = n
+					}
+					if swapped, err := sm.CompareAndSwap("test", "counter", old, n+1); err != nil {
+						t.Error(err)
+						return
+					} else if swapped {
+						break
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	var n int
+	a.Get("test", "counter", &n)
+	if n != 40 {
+		t.Fatalf("expected 40 increments, got %d", n)
+	}
+}
+
+func TestMigrationImportsLegacyCalendarFile(t *testing.T) {
+	ws := t.TempDir()
+	legacy := filepath.Join(ws, "calendar_reminders.json")
+	os.WriteFile(legacy, []byte(`{"dentist@2025-01-15T09:25:00Z":"2025-01-15T09:25:00Z"}`), 0644)
+
+	for _, m := range Migrations(ws) {
+		if err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var notified map[string]time.Time
+	if ok, err := NewManager(ws).Get("calendar", "notified", &notified); !ok || err != nil || len(notified) != 1 {
+		t.Fatalf("imported = %v, ok=%v err=%v", notified, ok, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("legacy file not removed")
+	}
+}
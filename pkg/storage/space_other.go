@@ -0,0 +1,8 @@
+//go:build !unix
+
+package storage
+
+// Space is not implemented on this platform and reports an unknown size.
+func Space(path string) (DiskSpace, error) {
+	return DiskSpace{}, nil
+}
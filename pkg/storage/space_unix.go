@@ -0,0 +1,15 @@
+//go:build unix
+
+package storage
+
+import "syscall"
+
+// Space reports the size and free space of the filesystem holding path.
+func Space(path string) (DiskSpace, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return DiskSpace{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return DiskSpace{Total: st.Blocks * bsize, Free: st.Bavail * bsize}, nil
+}
@@ -0,0 +1,59 @@
+// Package storage has helpers for writing the small JSON stores kept in the
+// workspace without risking a truncated file on crash.
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile atomically replaces path with data: it writes a temp file in the
+// same directory, syncs it and renames it over path. Readers see either the
+// old or the new contents, never a partial write.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// BackupPath is where WriteFileWithBackup keeps the previous version.
+func BackupPath(path string) string {
+	return path + ".bak"
+}
+
+// WriteFileWithBackup is WriteFile that first copies the current contents
+// of path to BackupPath(path), keeping one previous version for recovery.
+func WriteFileWithBackup(path string, data []byte, perm os.FileMode) error {
+	prev, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := WriteFile(BackupPath(path), prev, perm); err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	return WriteFile(path, data, perm)
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileWithBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cron", "jobs.json")
+
+	if err := WriteFileWithBackup(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BackupPath(path)); !os.IsNotExist(err) {
+		t.Fatalf("first write should not create a backup: %v", err)
+	}
+
+	for _, v := range []string{"v2", "v3"} {
+		if err := WriteFileWithBackup(path, []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cur, _ := os.ReadFile(path)
+	bak, _ := os.ReadFile(BackupPath(path))
+	if string(cur) != "v3" || string(bak) != "v2" {
+		t.Fatalf("got current %q backup %q", cur, bak)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatal("temp file left behind")
+	}
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Usage categories reported by Scan. Files not matched by a directory in
+// the layout, a backup suffix or a database suffix count as CategoryOther.
+const (
+	CategorySessions  = "sessions"
+	CategoryMedia     = "media"
+	CategoryImageJobs = "image_jobs"
+	CategoryMemory    = "memory"
+	CategoryBackups   = "backups"
+	CategoryDatabase  = "database"
+	CategoryOther     = "other"
+)
+
+// CategoryUsage is the size of one category of workspace data.
+type CategoryUsage struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+// Usage is a disk usage report for the workspace and data directory.
+type Usage struct {
+	Categories []CategoryUsage `json:"categories"` // largest first
+	Total      int64           `json:"total"`
+	Disk       DiskSpace       `json:"disk"`
+}
+
+// DiskSpace describes the filesystem holding the workspace. Zero values
+// mean the platform can't report it.
+type DiskSpace struct {
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+}
+
+// FreePercent returns the free share of the disk in percent, or -1 if
+// unknown.
+func (d DiskSpace) FreePercent() float64 {
+	if d.Total == 0 {
+		return -1
+	}
+	return float64(d.Free) * 100 / float64(d.Total)
+}
+
+// Layout tells Scan where to look and how to categorize what it finds.
+type Layout struct {
+	Roots []string          // directories to walk; nested roots are walked once
+	Dirs  map[string]string // directory -> category for everything below it
+}
+
+// Scan walks the layout and sums file sizes per category. Unreadable
+// entries are skipped. Disk space is reported for the first root.
+func Scan(l Layout) Usage {
+	sizes := make(map[string]*CategoryUsage)
+	add := func(cat string, n int64) {
+		c := sizes[cat]
+		if c == nil {
+			c = &CategoryUsage{Name: cat}
+			sizes[cat] = c
+		}
+		c.Bytes += n
+		c.Files++
+	}
+
+	seen := make(map[string]bool)
+	for _, root := range l.Roots {
+		root = filepath.Clean(root)
+		if seen[root] || coveredBy(root, l.Roots) {
+			continue
+		}
+		seen[root] = true
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			add(l.categorize(path), info.Size())
+			return nil
+		})
+	}
+
+	u := Usage{}
+	for _, c := range sizes {
+		u.Categories = append(u.Categories, *c)
+		u.Total += c.Bytes
+	}
+	sort.Slice(u.Categories, func(i, j int) bool {
+		if u.Categories[i].Bytes != u.Categories[j].Bytes {
+			return u.Categories[i].Bytes > u.Categories[j].Bytes
+		}
+		return u.Categories[i].Name < u.Categories[j].Name
+	})
+	if len(l.Roots) > 0 {
+		u.Disk, _ = Space(l.Roots[0])
+	}
+	return u
+}
+
+// coveredBy reports whether root lies strictly inside another root.
+func coveredBy(root string, roots []string) bool {
+	for _, other := range roots {
+		other = filepath.Clean(other)
+		if other != root && within(root, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (l Layout) categorize(path string) string {
+	base := filepath.Base(path)
+	if strings.HasSuffix(base, ".bak") {
+		return CategoryBackups
+	}
+	for _, suffix := range []string{".db", ".db-wal", ".db-shm"} {
+		if strings.HasSuffix(base, suffix) {
+			return CategoryDatabase
+		}
+	}
+	// The deepest matching directory wins, so a category nested inside
+	// another one (e.g. media under the workspace) is reported on its own.
+	best, bestLen := CategoryOther, -1
+	for dir, cat := range l.Dirs {
+		dir = filepath.Clean(dir)
+		if within(path, dir) && len(dir) > bestLen {
+			best, bestLen = cat, len(dir)
+		}
+	}
+	return best
+}
+
+// FormatBytes renders n for display, e.g. "1.5 GB".
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
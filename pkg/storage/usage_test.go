@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanCategorizes(t *testing.T) {
+	data := t.TempDir()
+	ws := filepath.Join(data, "workspace")
+	write := func(rel string, n int) {
+		p := filepath.Join(data, rel)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, make([]byte, n), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("workspace/sessions/a.jsonl", 100)
+	write("workspace/sessions/a.jsonl.bak", 10)
+	write("workspace/media/x.png", 300)
+	write("workspace/localagent.db-wal", 40)
+	write("webchat/images/job1/0.png", 500)
+	write("config.json", 5)
+
+	u := Scan(Layout{
+		Roots: []string{ws, data}, // ws is inside data and must not be counted twice
+		Dirs: map[string]string{
+			filepath.Join(ws, "sessions"):            CategorySessions,
+			filepath.Join(ws, "media"):               CategoryMedia,
+			filepath.Join(data, "webchat", "images"): CategoryImageJobs,
+		},
+	})
+
+	got := make(map[string]int64)
+	for _, c := range u.Categories {
+		got[c.Name] = c.Bytes
+	}
+	want := map[string]int64{
+		CategorySessions:  100,
+		CategoryBackups:   10,
+		CategoryMedia:     300,
+		CategoryDatabase:  40,
+		CategoryImageJobs: 500,
+		CategoryOther:     5,
+	}
+	for name, n := range want {
+		if got[name] != n {
+			t.Errorf("%s = %d, want %d", name, got[name], n)
+		}
+	}
+	if u.Total != 955 {
+		t.Errorf("total = %d, want 955", u.Total)
+	}
+	if u.Categories[0].Name != CategoryImageJobs {
+		t.Errorf("expected largest category first, got %s", u.Categories[0].Name)
+	}
+}
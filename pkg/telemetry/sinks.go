@@ -0,0 +1,220 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+)
+
+// maxBuffered caps events held between flushes; older ones are dropped if a
+// collector is down for long.
+const maxBuffered = 10000
+
+// Sink buffers events and periodically hands them to a writer.
+type Sink struct {
+	name     string
+	write    func([]Event) error
+	interval time.Duration
+
+	mu     sync.Mutex
+	buf    []Event
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+func newSink(name string, interval time.Duration, write func([]Event) error) *Sink {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	s := &Sink{
+		name:     name,
+		write:    write,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *Sink) Record(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.buf = append(s.buf, e)
+	if len(s.buf) > maxBuffered {
+		s.buf = s.buf[len(s.buf)-maxBuffered:]
+	}
+}
+
+func (s *Sink) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.stop:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *Sink) flush() {
+	s.mu.Lock()
+	batch := s.buf
+	s.buf = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.write(batch); err != nil {
+		logger.Warn("telemetry %s: %v", s.name, err)
+		// Keep the batch for the next attempt.
+		s.mu.Lock()
+		s.buf = append(batch, s.buf...)
+		if len(s.buf) > maxBuffered {
+			s.buf = s.buf[len(s.buf)-maxBuffered:]
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Close flushes buffered events and stops the sink.
+func (s *Sink) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+}
+
+// NewFileSink appends events as JSON lines to path.
+func NewFileSink(path string, interval time.Duration) (*Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return newSink("file", interval, func(events []Event) error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		enc := json.NewEncoder(f)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}), nil
+}
+
+// NewLineProtocolSink posts events in InfluxDB line protocol to endpoint,
+// which works for InfluxDB (/write, /api/v2/write) and VictoriaMetrics
+// (/write). The endpoint must be on this machine or the local network.
+func NewLineProtocolSink(endpoint, token string, interval time.Duration) (*Sink, error) {
+	if err := CheckLocal(endpoint); err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{Proxy: nil}, // local target, bypass the egress proxy
+	}
+	return newSink("line-protocol", interval, func(events []Event) error {
+		var body bytes.Buffer
+		for _, e := range events {
+			writeLine(&body, e)
+		}
+		req, err := http.NewRequest(http.MethodPost, endpoint, &body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		if token != "" {
+			req.Header.Set("Authorization", "Token "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned %d", endpoint, resp.StatusCode)
+		}
+		return nil
+	}), nil
+}
+
+// CheckLocal rejects endpoints whose host isn't loopback or a private address,
+// so metrics never leave the user's own network.
+func CheckLocal(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid telemetry endpoint %q", endpoint)
+	}
+	host := u.Hostname()
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return fmt.Errorf("telemetry endpoint %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() {
+			return fmt.Errorf("telemetry endpoint %s resolves to %s, which is not a local address", host, ip)
+		}
+	}
+	return nil
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// writeLine formats e as "name,tag=v field=1 <ns>" with sorted keys.
+func writeLine(w *bytes.Buffer, e Event) {
+	w.WriteString(measurementEscaper.Replace("localagent_" + e.Name))
+	for _, k := range sortedKeys(e.Tags) {
+		if v := e.Tags[k]; v != "" {
+			fmt.Fprintf(w, ",%s=%s", tagEscaper.Replace(k), tagEscaper.Replace(v))
+		}
+	}
+	w.WriteByte(' ')
+	for i, k := range sortedKeys(e.Fields) {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, "%s=%s", tagEscaper.Replace(k), strconv.FormatFloat(e.Fields[k], 'f', -1, 64))
+	}
+	fmt.Fprintf(w, " %d\n", e.Time.UnixNano())
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,59 @@
+// Package telemetry is an opt-in hook for self-metrics: the agent emits
+// events (messages processed, LLM token usage, tool runs) and registered
+// hooks forward them to local collectors such as InfluxDB or
+// VictoriaMetrics. Nothing is recorded unless a hook is added.
+package telemetry
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is a single measurement. Tags are indexed dimensions (channel, tool,
+// model), Fields the measured values.
+type Event struct {
+	Name   string             `json:"name"`
+	Time   time.Time          `json:"time"`
+	Tags   map[string]string  `json:"tags,omitempty"`
+	Fields map[string]float64 `json:"fields"`
+}
+
+// Hook receives events. Record is called on the emitting goroutine and must
+// not block; sinks buffer and push in the background.
+type Hook interface {
+	Record(Event)
+}
+
+var (
+	mu    sync.RWMutex
+	hooks []Hook
+)
+
+// AddHook registers a hook for all subsequent events.
+func AddHook(h Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = append(hooks, h)
+}
+
+// Enabled reports whether any hook is registered, so callers can skip
+// building events nobody receives.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(hooks) > 0
+}
+
+// Emit sends an event to every registered hook.
+func Emit(name string, tags map[string]string, fields map[string]float64) {
+	mu.RLock()
+	hs := hooks
+	mu.RUnlock()
+	if len(hs) == 0 {
+		return
+	}
+	e := Event{Name: name, Time: time.Now(), Tags: tags, Fields: fields}
+	for _, h := range hs {
+		h.Record(e)
+	}
+}
@@ -0,0 +1,67 @@
+package telemetry
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteLine(t *testing.T) {
+	var buf bytes.Buffer
+	writeLine(&buf, Event{
+		Name:   "tool_exec",
+		Time:   time.Unix(0, 42),
+		Tags:   map[string]string{"tool": "web fetch", "status": "ok", "empty": ""},
+		Fields: map[string]float64{"duration_ms": 12, "bytes": 1.5},
+	})
+	want := "localagent_tool_exec,status=ok,tool=web\\ fetch bytes=1.5,duration_ms=12 42\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCheckLocal(t *testing.T) {
+	for _, ep := range []string{"http://127.0.0.1:8086/write", "http://localhost:8428/write", "http://192.168.1.10/api/v2/write"} {
+		if err := CheckLocal(ep); err != nil {
+			t.Errorf("CheckLocal(%s) = %v", ep, err)
+		}
+	}
+	for _, ep := range []string{"http://8.8.8.8/write", "ftp://localhost/write", "not a url"} {
+		if err := CheckLocal(ep); err == nil {
+			t.Errorf("CheckLocal(%s) should fail", ep)
+		}
+	}
+}
+
+func TestLineProtocolSink(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			t.Errorf("missing token header")
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies <- string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, err := NewLineProtocolSink(srv.URL+"/write", "secret", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record(Event{Name: "llm_call", Time: time.Unix(1, 0), Fields: map[string]float64{"total_tokens": 30}})
+	s.Close()
+
+	select {
+	case body := <-bodies:
+		if !strings.HasPrefix(body, "localagent_llm_call total_tokens=30 ") {
+			t.Fatalf("unexpected body %q", body)
+		}
+	default:
+		t.Fatal("Close should flush buffered events")
+	}
+}
@@ -0,0 +1,245 @@
+// Package templates stores named prompt templates in the workspace.
+// A template is a markdown file with an optional frontmatter description
+// and a body containing {{placeholder}} or {{placeholder|default}} fields.
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"localagent/pkg/storage"
+)
+
+var (
+	namePattern        = regexp.MustCompile(`^[a-zA-Z0-9]+(-[a-zA-Z0-9]+)*$`)
+	placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_]+)\s*(?:\|([^}]*))?\}\}`)
+	frontmatterPattern = regexp.MustCompile(`(?s)^---\n(.*?)\n---\n?`)
+)
+
+const MaxNameLength = 64
+
+type Template struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Body        string `json:"body"`
+}
+
+// Placeholder is a field referenced by a template body.
+type Placeholder struct {
+	Name     string `json:"name"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required"`
+}
+
+// Placeholders lists the template's fields in order of first appearance.
+func (t Template) Placeholders() []Placeholder {
+	var out []Placeholder
+	seen := map[string]bool{}
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(t.Body, -1) {
+		name := t.Body[m[2]:m[3]]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		p := Placeholder{Name: name, Required: m[4] < 0}
+		if m[4] >= 0 {
+			p.Default = strings.TrimSpace(t.Body[m[4]:m[5]])
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// Render fills placeholders from values, falling back to defaults. It
+// fails listing every required placeholder without a value.
+func (t Template) Render(values map[string]string) (string, error) {
+	var missing []string
+	for _, p := range t.Placeholders() {
+		if p.Required && strings.TrimSpace(values[p.Name]) == "" {
+			missing = append(missing, p.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("template %q needs values for: %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(t.Body, func(match string) string {
+		sub := placeholderPattern.FindStringSubmatch(match)
+		if v := values[sub[1]]; strings.TrimSpace(v) != "" {
+			return v
+		}
+		return strings.TrimSpace(sub[2])
+	}), nil
+}
+
+// Store keeps templates as <name>.md files in a directory.
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("name exceeds %d characters", MaxNameLength)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("name must be alphanumeric with hyphens")
+	}
+	return nil
+}
+
+func (s *Store) List() []Template {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return []Template{}
+	}
+	list := make([]Template, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".md" {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ".md")
+		if t, err := s.read(name); err == nil {
+			list = append(list, t)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *Store) Get(name string) (Template, error) {
+	if err := ValidateName(name); err != nil {
+		return Template{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.read(name)
+}
+
+func (s *Store) Save(t Template) error {
+	if err := ValidateName(t.Name); err != nil {
+		return err
+	}
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+
+	var b strings.Builder
+	if t.Description != "" {
+		fmt.Fprintf(&b, "---\ndescription: %s\n---\n", strings.ReplaceAll(t.Description, "\n", " "))
+	}
+	b.WriteString(t.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return storage.WriteFile(s.path(t.Name), []byte(b.String()), 0644)
+}
+
+func (s *Store) Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("template %q not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Expand parses a "/template <name> key=value ..." command. Values may be
+// quoted to include spaces. ok is false when text isn't a template command.
+func (s *Store) Expand(text string) (rendered string, ok bool, err error) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(text), "/template")
+	if !found || (rest != "" && rest[0] != ' ') {
+		return "", false, nil
+	}
+	fields := splitArgs(strings.TrimSpace(rest))
+	if len(fields) == 0 {
+		return "", true, fmt.Errorf("usage: /template <name> [key=value ...]")
+	}
+
+	t, err := s.Get(fields[0])
+	if err != nil {
+		return "", true, err
+	}
+	values := map[string]string{}
+	for _, f := range fields[1:] {
+		k, v, found := strings.Cut(f, "=")
+		if !found {
+			return "", true, fmt.Errorf("expected key=value, got %q", f)
+		}
+		values[k] = v
+	}
+	rendered, err = t.Render(values)
+	return rendered, true, err
+}
+
+func (s *Store) read(name string) (Template, error) {
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Template{}, fmt.Errorf("template %q not found", name)
+		}
+		return Template{}, err
+	}
+	t := Template{Name: name, Body: string(data)}
+	if m := frontmatterPattern.FindStringSubmatch(t.Body); m != nil {
+		for line := range strings.SplitSeq(m[1], "\n") {
+			if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == "description" {
+				t.Description = strings.Trim(strings.TrimSpace(v), "\"'")
+			}
+		}
+		t.Body = t.Body[len(m[0]):]
+	}
+	return t, nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".md")
+}
+
+// splitArgs splits on spaces, keeping double-quoted sections together.
+func splitArgs(s string) []string {
+	var out []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == ' ' && !inQuote:
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDefaultsAndRequired(t *testing.T) {
+	tpl := Template{Name: "review", Body: "Review {{period|this week}} for {{project}}."}
+
+	if _, err := tpl.Render(nil); err == nil || !strings.Contains(err.Error(), "project") {
+		t.Fatalf("expected missing project error, got %v", err)
+	}
+
+	out, err := tpl.Render(map[string]string{"project": "localagent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Review this week for localagent." {
+		t.Errorf("unexpected render: %q", out)
+	}
+}
+
+func TestStoreRoundTripAndExpand(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := s.Save(Template{Name: "weekly-review", Description: "Weekly review", Body: "Summarize {{topic|everything}}."}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get("weekly-review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Description != "Weekly review" || got.Body != "Summarize {{topic|everything}}." {
+		t.Errorf("unexpected template: %+v", got)
+	}
+
+	out, ok, err := s.Expand(`/template weekly-review topic="open tasks"`)
+	if err != nil || !ok {
+		t.Fatalf("expand failed: ok=%v err=%v", ok, err)
+	}
+	if out != "Summarize open tasks." {
+		t.Errorf("unexpected expansion: %q", out)
+	}
+
+	if _, ok, _ := s.Expand("hello"); ok {
+		t.Error("plain message should not expand")
+	}
+
+	if err := s.Delete("weekly-review"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.List()) != 0 {
+		t.Error("expected empty store after delete")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"", "../x", "a b"} {
+		if ValidateName(name) == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"golang.org/x/net/html"
+
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
 )
 
 type AIPapersTool struct {
@@ -55,6 +58,9 @@ func (t *AIPapersTool) DeclaredDomains() []string {
 	return []string{"huggingface.co"}
 }
 
+// SideEffect reports that AIPapersTool calls only read.
+func (t *AIPapersTool) SideEffect(map[string]any) bool { return false }
+
 func (t *AIPapersTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	period := "daily"
 	if p, ok := args["period"].(string); ok && p != "" {
@@ -90,7 +96,7 @@ func (t *AIPapersTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	result, err = t.fetchFromHTML(ctx, path, label, count)
 
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to fetch %s papers: %v", period, err))
+		return ErrorResult(fmt.Sprintf("failed to fetch %s papers: %v", period, err)).WithError(err)
 	}
 
 	return SilentResult(result)
@@ -103,7 +109,7 @@ func (t *AIPapersTool) fetchFromHTML(ctx context.Context, path string, label str
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client := httpclient.New("ai_papers", httpclient.WithTimeout(15*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
@@ -111,7 +117,7 @@ func (t *AIPapersTool) fetchFromHTML(ctx context.Context, path string, label str
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("page returned status %d", resp.StatusCode)
+		return "", errs.Wrap(errs.FromStatus(resp.StatusCode), fmt.Errorf("page returned status %d", resp.StatusCode))
 	}
 
 	papers, err := extractPapersFromHTML(resp.Body)
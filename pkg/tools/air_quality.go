@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"localagent/pkg/airquality"
+	"localagent/pkg/config"
+	"localagent/pkg/travel"
+	"localagent/pkg/when"
+)
+
+// AirQualityTool reports air quality and pollen from Open-Meteo for the
+// configured locations or any place.
+type AirQualityTool struct {
+	locations []config.AirQualityLocation
+	allergens []string
+	client    *http.Client
+}
+
+func NewAirQualityTool(cfg config.AirQualityConfig) *AirQualityTool {
+	return &AirQualityTool{locations: cfg.Locations, allergens: cfg.Allergens, client: airquality.NewClient()}
+}
+
+func (t *AirQualityTool) Name() string {
+	return "air_quality"
+}
+
+func (t *AirQualityTool) Description() string {
+	desc := "Current air quality (European and US AQI, PM2.5, PM10, ozone) and today's pollen levels " +
+		"(alder, birch, grass, mugwort, olive, ragweed; Europe only, in season) at a place."
+	if len(t.locations) > 0 {
+		names := make([]string, len(t.locations))
+		for i, l := range t.locations {
+			names[i] = l.Name
+		}
+		desc += " Default locations: " + strings.Join(names, ", ") + "."
+	}
+	if len(t.allergens) > 0 {
+		desc += " The user is allergic to " + strings.Join(t.allergens, ", ") + " pollen."
+	}
+	return desc
+}
+
+func (t *AirQualityTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"location": map[string]any{
+				"type":        "string",
+				"description": "A configured location or any city; default the configured locations.",
+			},
+		},
+	}
+}
+
+func (t *AirQualityTool) DeclaredDomains() []string {
+	return append([]string{airquality.Domain}, travel.Domains...)
+}
+
+// SideEffect reports that AirQualityTool calls only read.
+func (t *AirQualityTool) SideEffect(map[string]any) bool { return false }
+
+func (t *AirQualityTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	locations := t.locations
+	if name, _ := args["location"].(string); strings.TrimSpace(name) != "" {
+		locations = []config.AirQualityLocation{{Name: strings.TrimSpace(name)}}
+		for _, l := range t.locations {
+			if strings.EqualFold(l.Name, strings.TrimSpace(name)) {
+				locations = []config.AirQualityLocation{l}
+			}
+		}
+	}
+	if len(locations) == 0 {
+		return ErrorResult("location is required (no default locations are configured)")
+	}
+
+	now := when.Now()
+	var reports []string
+	for _, cfg := range locations {
+		loc, err := airquality.Resolve(ctx, t.client, cfg)
+		if err != nil {
+			if len(locations) == 1 {
+				return ErrorResult(fmt.Sprintf("cannot find %q: %v", cfg.Name, err)).WithError(err)
+			}
+			reports = append(reports, fmt.Sprintf("%s: cannot find it: %v", cfg.Name, err))
+			continue
+		}
+		r, err := loc.Report(ctx, t.client, now)
+		if err != nil {
+			if len(locations) == 1 {
+				return ErrorResult(fmt.Sprintf("air quality for %s failed: %v", loc.Name, err)).WithError(err)
+			}
+			reports = append(reports, fmt.Sprintf("%s: lookup failed: %v", loc.Name, err))
+			continue
+		}
+		reports = append(reports, r.Format(nil))
+	}
+	return SilentResult(strings.Join(reports, "\n\n"))
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"localagent/pkg/channels"
+)
+
+// AllowlistTool lets the owner see and change which senders each channel
+// accepts, and answer access requests from unknown senders.
+type AllowlistTool struct {
+	allowlist *channels.Allowlist
+}
+
+func NewAllowlistTool(allowlist *channels.Allowlist) *AllowlistTool {
+	return &AllowlistTool{allowlist: allowlist}
+}
+
+func (t *AllowlistTool) Name() string {
+	return "allowlist"
+}
+
+func (t *AllowlistTool) Description() string {
+	return "Manage who may message the agent on each channel. " +
+		"Actions: list (entries per channel and pending access requests), add, remove (channel and entry), " +
+		"approve, deny (channel and sender_id of a pending request). A channel with no entries accepts anyone."
+}
+
+func (t *AllowlistTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "add", "remove", "approve", "deny"},
+				"description": "Action to perform.",
+			},
+			"channel": map[string]any{
+				"type":        "string",
+				"description": "Channel name, e.g. \"mqtt\" (for add, remove, approve, deny).",
+			},
+			"entry": map[string]any{
+				"type":        "string",
+				"description": "Sender ID or @username to allow or remove (for add, remove).",
+			},
+			"sender_id": map[string]any{
+				"type":        "string",
+				"description": "Sender of a pending request (for approve, deny).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; list only read.
+func (t *AllowlistTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list")
+}
+
+func (t *AllowlistTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	channel, _ := args["channel"].(string)
+	entry, _ := args["entry"].(string)
+	sender, _ := args["sender_id"].(string)
+
+	var err error
+	switch action {
+	case "list":
+		return SilentResult(t.list())
+	case "add":
+		restricts := len(t.allowlist.Channels()[channel]) == 0
+		if err = t.allowlist.Add(channel, entry); err == nil && restricts {
+			return SilentResult(fmt.Sprintf("Added %s. %s now only accepts listed senders.\n\n%s", entry, channel, t.list()))
+		}
+	case "remove":
+		err = t.allowlist.Remove(channel, entry)
+	case "approve":
+		err = t.allowlist.Approve(channel, sender)
+	case "deny":
+		err = t.allowlist.Deny(channel, sender)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+	if errors.Is(err, channels.ErrNotPending) {
+		return ErrorResult(fmt.Sprintf("%v on %s\n\n%s", err, channel, t.list()))
+	}
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(t.list())
+}
+
+func (t *AllowlistTool) list() string {
+	lists := t.allowlist.Channels()
+	var sb strings.Builder
+	for _, channel := range slices.Sorted(maps.Keys(lists)) {
+		entries := "anyone"
+		if len(lists[channel]) > 0 {
+			entries = strings.Join(lists[channel], ", ")
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", channel, entries)
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("No channels with an allowlist.\n")
+	}
+	pending := t.allowlist.Pending("")
+	if len(pending) == 0 {
+		sb.WriteString("No pending requests.")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Pending requests (%d):", len(pending))
+	for _, p := range pending {
+		fmt.Fprintf(&sb, "\n- %s %s at %s: %q", p.Channel, p.SenderID, p.At.Format("2006-01-02 15:04"), p.Message)
+	}
+	return sb.String()
+}
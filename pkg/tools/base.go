@@ -1,6 +1,9 @@
 package tools
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 // Tool is the interface that all tools must implement.
 type Tool interface {
@@ -10,6 +13,38 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]any) *ToolResult
 }
 
+type sessionKeyCtx struct{}
+
+// WithSessionKey attaches the session being processed to ctx so tools
+// with per-session state can find it.
+func WithSessionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, key)
+}
+
+// SessionKeyFromContext returns the session key set by WithSessionKey.
+func SessionKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+type roleCtx struct{}
+
+// WithRole attaches the sender's household role to ctx so tools that act
+// later on the sender's behalf (e.g. scheduled agent turns) can keep it.
+// An empty role is the owner.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleCtx{}, role)
+}
+
+// RoleFromContext returns the role set by WithRole, or "" for the owner.
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleCtx{}).(string)
+	if role == "owner" {
+		return ""
+	}
+	return role
+}
+
 // ContextualTool is an optional interface that tools can implement
 // to receive the current message context (channel, chatID)
 type ContextualTool interface {
@@ -75,6 +110,59 @@ type DomainDeclarer interface {
 	DeclaredDomains() []string
 }
 
+// RepairHinter is an optional interface that tools can implement to
+// register hints appended to their error results, steering the model
+// towards a working call.
+type RepairHinter interface {
+	RepairHints() []RepairHint
+}
+
+// Audited is an optional interface for tools with side effects. AuditAction
+// names the action a call performs and what it touches, or returns an empty
+// action for read-only calls, which are not recorded.
+type Audited interface {
+	AuditAction(args map[string]any) (action, target string)
+}
+
+// SideEffecting is an optional interface declaring per call whether a tool
+// changes anything outside the conversation: files, schedules, stored data,
+// messages or devices. Calls that do wait for the user's confirmation under
+// the "suggest" autonomy level. Tools that implement neither this nor
+// Audited are assumed to have side effects.
+type SideEffecting interface {
+	SideEffect(args map[string]any) bool
+}
+
+// readOnlyAction reports whether args' "action" is one of actions.
+func readOnlyAction(args map[string]any, actions ...string) bool {
+	action, _ := args["action"].(string)
+	return slices.Contains(actions, action)
+}
+
+// Sensitive is an optional interface for tools whose arguments can hold
+// secrets or private values. Exported transcripts hide the named arguments.
+type Sensitive interface {
+	SensitiveArgs() []string
+}
+
+// Grouped is an optional interface that places a tool in a tool group,
+// overriding DefaultGroups. An empty group keeps the tool always offered.
+type Grouped interface {
+	Group() string
+}
+
+// SensitiveArgs maps each tool implementing Sensitive to its sensitive
+// argument names.
+func SensitiveArgs(ts []Tool) map[string][]string {
+	out := make(map[string][]string)
+	for _, t := range ts {
+		if s, ok := t.(Sensitive); ok {
+			out[t.Name()] = s.SensitiveArgs()
+		}
+	}
+	return out
+}
+
 func ToolToSchema(tool Tool) map[string]any {
 	return map[string]any{
 		"type": "function",
@@ -11,16 +11,19 @@ import (
 	"github.com/emersion/go-ical"
 	"github.com/emersion/go-webdav"
 	"github.com/emersion/go-webdav/caldav"
+
+	"localagent/pkg/when"
 )
 
 type CalendarTool struct {
-	url      string
-	username string
-	password string
+	workspace string
+	url       string
+	username  string
+	password  string
 }
 
-func NewCalendarTool(url, username, password string) *CalendarTool {
-	return &CalendarTool{url: url, username: username, password: password}
+func NewCalendarTool(workspace, url, username, password string) *CalendarTool {
+	return &CalendarTool{workspace: workspace, url: url, username: username, password: password}
 }
 
 func (t *CalendarTool) Name() string {
@@ -28,7 +31,7 @@ func (t *CalendarTool) Name() string {
 }
 
 func (t *CalendarTool) Description() string {
-	return "Manage calendar events via CalDAV. Actions: list_calendars, list_events, get_event, create_event, update_event, delete_event."
+	return "Manage calendar events via CalDAV. Actions: list_calendars, list_events, get_event, create_event, update_event, delete_event, find_free_slots (open slots within working hours, and conflicts for a proposed start/end), import_ics (create events from an .ics file), export_event and export_range (write .ics files into the workspace for sharing)."
 }
 
 func (t *CalendarTool) Parameters() map[string]any {
@@ -37,8 +40,8 @@ func (t *CalendarTool) Parameters() map[string]any {
 		"properties": map[string]any{
 			"action": map[string]any{
 				"type":        "string",
-				"description": "The action to perform: list_calendars, list_events, get_event, create_event, update_event, delete_event",
-				"enum":        []string{"list_calendars", "list_events", "get_event", "create_event", "update_event", "delete_event"},
+				"description": "The action to perform: list_calendars, list_events, get_event, create_event, update_event, delete_event, find_free_slots, import_ics, export_event, export_range",
+				"enum":        []string{"list_calendars", "list_events", "get_event", "create_event", "update_event", "delete_event", "find_free_slots", "import_ics", "export_event", "export_range"},
 			},
 			"calendars": map[string]any{
 				"type":        "array",
@@ -47,15 +50,15 @@ func (t *CalendarTool) Parameters() map[string]any {
 			},
 			"start_date": map[string]any{
 				"type":        "string",
-				"description": "Start date for listing events, ISO 8601 format (e.g. 2025-01-15)",
+				"description": "Start date for list_events and find_free_slots, ISO 8601 format (e.g. 2025-01-15 or 2025-01-15T12:00)",
 			},
 			"end_date": map[string]any{
 				"type":        "string",
-				"description": "End date for listing events, ISO 8601 format (e.g. 2025-01-31)",
+				"description": "End date (exclusive) for list_events and find_free_slots, ISO 8601 format (e.g. 2025-01-31). find_free_slots defaults to one day after start_date",
 			},
 			"event_path": map[string]any{
 				"type":        "string",
-				"description": "Event resource path (for get_event, update_event, delete_event). Returned by list_events.",
+				"description": "Event resource path (for get_event, update_event, delete_event, export_event). Returned by list_events.",
 			},
 			"title": map[string]any{
 				"type":        "string",
@@ -63,11 +66,11 @@ func (t *CalendarTool) Parameters() map[string]any {
 			},
 			"start": map[string]any{
 				"type":        "string",
-				"description": "Event start datetime, ISO 8601 (e.g. 2025-01-15T09:00:00Z). For all-day events use date only: 2025-01-15",
+				"description": "Event start datetime, ISO 8601 (e.g. 2025-01-15T09:00:00Z). For all-day events use date only: 2025-01-15. For find_free_slots, a proposed start to check for conflicts",
 			},
 			"end": map[string]any{
 				"type":        "string",
-				"description": "Event end datetime, ISO 8601 (e.g. 2025-01-15T10:00:00Z). For all-day events use date only: 2025-01-16",
+				"description": "Event end datetime, ISO 8601 (e.g. 2025-01-15T10:00:00Z). For all-day events use date only: 2025-01-16. For find_free_slots, the proposed end (defaults to start + duration_minutes)",
 			},
 			"location": map[string]any{
 				"type":        "string",
@@ -81,11 +84,44 @@ func (t *CalendarTool) Parameters() map[string]any {
 				"type":        "boolean",
 				"description": "If true, create an all-day event using date values for start/end",
 			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to the .ics file to import (for import_ics), e.g. an attachment from the media directory",
+			},
+			"filename": map[string]any{
+				"type":        "string",
+				"description": "Output file name for export_event/export_range, written to the workspace exports directory. Defaults to the event title or date range",
+			},
+			"duration_minutes": map[string]any{
+				"type":        "integer",
+				"description": "Minimum slot length for find_free_slots (default 30)",
+			},
+			"work_start": map[string]any{
+				"type":        "string",
+				"description": "Start of working hours for find_free_slots, HH:MM local time (default 09:00)",
+			},
+			"work_end": map[string]any{
+				"type":        "string",
+				"description": "End of working hours for find_free_slots, HH:MM local time (default 18:00)",
+			},
 		},
 		"required": []string{"action"},
 	}
 }
 
+func (t *CalendarTool) RepairHints() []RepairHint {
+	return []RepairHint{
+		HintOn("event_path must come from list_events; call calendar with action list_events first and copy the event_path exactly.",
+			"event_path is required", "failed to get event", "no event found", "failed to delete event"),
+		HintOn("pass the attachment's full path as path; .ics attachments show their path in the message.",
+			"failed to open ics file"),
+		HintOn("use ISO 8601 datetimes such as 2025-01-15T09:00:00Z, or YYYY-MM-DD with all_day=true.",
+			"invalid start", "invalid end", "cannot parse datetime"),
+		HintOn("calendar names must match exactly; call calendar with action list_calendars to see them.",
+			"calendar(s) not found"),
+	}
+}
+
 func (t *CalendarTool) DeclaredDomains() []string {
 	u, err := url.Parse(t.url)
 	if err != nil || u.Host == "" {
@@ -94,6 +130,27 @@ func (t *CalendarTool) DeclaredDomains() []string {
 	return []string{u.Host}
 }
 
+// AuditAction records event changes and the files import/export touch.
+func (t *CalendarTool) AuditAction(args map[string]any) (string, string) {
+	action, _ := args["action"].(string)
+	switch action {
+	case "create_event":
+		title, _ := args["title"].(string)
+		return "calendar.create_event", title
+	case "update_event", "delete_event", "export_event":
+		eventPath, _ := args["event_path"].(string)
+		return "calendar." + action, eventPath
+	case "import_ics":
+		path, _ := args["path"].(string)
+		return "calendar.import_ics", path
+	case "export_range":
+		start, _ := args["start_date"].(string)
+		end, _ := args["end_date"].(string)
+		return "calendar.export_range", strings.TrimSpace(start + " " + end)
+	}
+	return "", ""
+}
+
 func (t *CalendarTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	action, ok := args["action"].(string)
 	if !ok || action == "" {
@@ -118,6 +175,14 @@ func (t *CalendarTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return t.updateEvent(ctx, client, args)
 	case "delete_event":
 		return t.deleteEvent(ctx, client, args)
+	case "find_free_slots":
+		return t.findFreeSlots(ctx, client, args)
+	case "import_ics":
+		return t.importICS(ctx, client, args)
+	case "export_event":
+		return t.exportEvent(ctx, client, args)
+	case "export_range":
+		return t.exportRange(ctx, client, args)
 	default:
 		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
 	}
@@ -239,49 +304,12 @@ func (t *CalendarTool) listEvents(ctx context.Context, client *caldav.Client, ar
 		return ErrorResult(err.Error())
 	}
 
-	startStr, _ := args["start_date"].(string)
-	endStr, _ := args["end_date"].(string)
-
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-	end := start.AddDate(0, 0, 7)
-
-	if startStr != "" {
-		if parsed, err := time.Parse("2006-01-02", startStr); err == nil {
-			start = parsed
-		} else if parsed, err := time.Parse(time.RFC3339, startStr); err == nil {
-			start = parsed
-		}
-	}
-	if endStr != "" {
-		if parsed, err := time.Parse("2006-01-02", endStr); err == nil {
-			end = parsed
-		} else if parsed, err := time.Parse(time.RFC3339, endStr); err == nil {
-			end = parsed
-		}
-	}
-
-	query := &caldav.CalendarQuery{
-		CompRequest: caldav.CalendarCompRequest{
-			Name:    ical.CompCalendar,
-			AllProps: true,
-			Comps: []caldav.CalendarCompRequest{{
-				Name:     ical.CompEvent,
-				AllProps: true,
-			}},
-		},
-		CompFilter: caldav.CompFilter{
-			Name: ical.CompCalendar,
-			Comps: []caldav.CompFilter{{
-				Name:  ical.CompEvent,
-				Start: start,
-				End:   end,
-			}},
-		},
-	}
+	start, end := parseEventRange(args)
+	query := eventQuery(start, end)
 
 	var b strings.Builder
 	totalEvents := 0
+	var rows []eventRow
 
 	for _, cal := range calendars {
 		objects, err := client.QueryCalendar(ctx, cal.Path, query)
@@ -301,6 +329,7 @@ func (t *CalendarTool) listEvents(ctx context.Context, client *caldav.Client, ar
 			}
 			for _, event := range obj.Data.Events() {
 				formatEventSummary(&b, obj.Path, &event)
+				rows = append(rows, newEventRow(cal.Name, obj.Path, &event))
 				totalEvents++
 			}
 		}
@@ -315,7 +344,137 @@ func (t *CalendarTool) listEvents(ctx context.Context, client *caldav.Client, ar
 	}
 
 	header := fmt.Sprintf("Events from %s to %s:\n\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
-	return SilentResult(header + b.String())
+	return SilentResult(header + b.String()).
+		WithTitle(fmt.Sprintf("%d event(s) from %s to %s", totalEvents, start.Format("2006-01-02"), end.Format("2006-01-02"))).
+		WithData(rows)
+}
+
+// CalendarEvent is an event returned by Upcoming or LongEvents.
+type CalendarEvent struct {
+	UID      string
+	Title    string
+	Location string
+	Start    time.Time
+	End      time.Time
+}
+
+// Upcoming returns timed events across all calendars starting in [from, to).
+// Used by background reminders, outside of the tool-call path.
+func (t *CalendarTool) Upcoming(ctx context.Context, from, to time.Time) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+	err := t.queryEvents(ctx, from, to, func(occ occurrence) {
+		iv, ok := busyFromEvent(occ)
+		if !ok || iv.Start.Before(from) || !iv.Start.Before(to) {
+			return
+		}
+		events = append(events, calendarEvent(occ.Event, iv.Title, iv.Start, iv.End))
+	})
+	return events, err
+}
+
+// BusyUntil reports whether a busy event (timed, not transparent or
+// cancelled) is in progress at now, and when the run of back-to-back busy
+// events it belongs to ends. Used by do-not-disturb.
+func (t *CalendarTool) BusyUntil(ctx context.Context, now time.Time) (time.Time, bool, error) {
+	var busy []busyInterval
+	err := t.queryEvents(ctx, now.Add(-24*time.Hour), now.Add(24*time.Hour), func(occ occurrence) {
+		if iv, ok := busyFromEvent(occ); ok {
+			busy = append(busy, iv)
+		}
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	for _, iv := range mergeBusy(busy) {
+		if !iv.Start.After(now) && iv.End.After(now) {
+			return iv.End, true, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// LongEvents returns events overlapping [from, to) that last at least
+// minDur, all-day ones included. Travel mode reads trips from these.
+func (t *CalendarTool) LongEvents(ctx context.Context, from, to time.Time, minDur time.Duration) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+	err := t.queryEvents(ctx, from, to, func(occ occurrence) {
+		if status, _ := occ.Event.Props.Text(ical.PropStatus); strings.EqualFold(status, "CANCELLED") {
+			return
+		}
+		if occ.End.Sub(occ.Start) < minDur || !occ.End.After(from) {
+			return
+		}
+		title, _ := occ.Event.Props.Text(ical.PropSummary)
+		events = append(events, calendarEvent(occ.Event, title, occ.Start, occ.End))
+	})
+	return events, err
+}
+
+// queryEvents calls fn for every event occurrence across all calendars in
+// [from, to), recurring events expanded.
+func (t *CalendarTool) queryEvents(ctx context.Context, from, to time.Time, fn func(occurrence)) error {
+	client, err := t.newClient()
+	if err != nil {
+		return fmt.Errorf("failed to create CalDAV client: %w", err)
+	}
+	calendars, err := t.discoverCalendars(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, cal := range calendars {
+		objects, err := client.QueryCalendar(ctx, cal.Path, eventQuery(from, to))
+		if err != nil {
+			return fmt.Errorf("querying %q: %w", cal.Name, err)
+		}
+		for _, obj := range objects {
+			if obj.Data == nil {
+				continue
+			}
+			for _, occ := range eventOccurrences(obj.Data, from, to, when.Location()) {
+				fn(occ)
+			}
+		}
+	}
+	return nil
+}
+
+func calendarEvent(event *ical.Event, title string, start, end time.Time) CalendarEvent {
+	uid, _ := event.Props.Text(ical.PropUID)
+	location, _ := event.Props.Text(ical.PropLocation)
+	return CalendarEvent{UID: uid, Title: title, Location: location, Start: start, End: end}
+}
+
+// parseEventRange reads start_date/end_date, defaulting to the next 7 days.
+func parseEventRange(args map[string]any) (time.Time, time.Time) {
+	startStr, _ := args["start_date"].(string)
+	endStr, _ := args["end_date"].(string)
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+
+	if startStr != "" {
+		if parsed, err := time.Parse("2006-01-02", startStr); err == nil {
+			start = parsed
+		} else if parsed, err := time.Parse(time.RFC3339, startStr); err == nil {
+			start = parsed
+		} else if r, err := when.Resolve(startStr); err == nil {
+			start = r.Time
+			if endStr == "" {
+				end = start.AddDate(0, 0, 7)
+			}
+		}
+	}
+	if endStr != "" {
+		if parsed, err := time.Parse("2006-01-02", endStr); err == nil {
+			end = parsed
+		} else if parsed, err := time.Parse(time.RFC3339, endStr); err == nil {
+			end = parsed
+		} else if r, err := when.Resolve(endStr); err == nil {
+			end = r.Time
+		}
+	}
+	return start, end
 }
 
 func (t *CalendarTool) getEvent(ctx context.Context, client *caldav.Client, args map[string]any) *ToolResult {
@@ -526,6 +685,35 @@ func (t *CalendarTool) deleteEvent(ctx context.Context, client *caldav.Client, a
 	return SilentResult(fmt.Sprintf("Event deleted: %s", eventPath))
 }
 
+// eventRow is the machine-readable form of an event in list_events results.
+type eventRow struct {
+	Calendar string `json:"calendar"`
+	Title    string `json:"title"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	AllDay   bool   `json:"all_day,omitempty"`
+	Location string `json:"location,omitempty"`
+	Path     string `json:"event_path"`
+}
+
+func newEventRow(calendar, path string, event *ical.Event) eventRow {
+	summary, _ := event.Props.Text(ical.PropSummary)
+	location, _ := event.Props.Text(ical.PropLocation)
+	startTime, _ := event.DateTimeStart(nil)
+	endTime, _ := event.DateTimeEnd(nil)
+
+	row := eventRow{Calendar: calendar, Title: summary, Location: location, Path: path}
+	if prop := event.Props.Get(ical.PropDateTimeStart); prop != nil && prop.ValueType() == ical.ValueDate {
+		row.AllDay = true
+		row.Start = startTime.Format("2006-01-02")
+		row.End = endTime.Format("2006-01-02")
+	} else {
+		row.Start = startTime.Format(time.RFC3339)
+		row.End = endTime.Format(time.RFC3339)
+	}
+	return row
+}
+
 func formatEventSummary(b *strings.Builder, path string, event *ical.Event) {
 	summary, _ := event.Props.Text(ical.PropSummary)
 	uid, _ := event.Props.Text(ical.PropUID)
@@ -618,5 +806,8 @@ func parseDateTime(s string) (time.Time, error) {
 			return t, nil
 		}
 	}
-	return time.Time{}, fmt.Errorf("cannot parse datetime %q (expected ISO 8601 format)", s)
+	if r, err := when.Resolve(s); err == nil {
+		return r.Time, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse datetime %q (expected ISO 8601 or an expression like \"tomorrow 3pm\")", s)
 }
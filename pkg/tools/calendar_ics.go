@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+)
+
+const exportDir = "exports"
+
+func (t *CalendarTool) importICS(ctx context.Context, client *caldav.Client, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	if path == "" {
+		return ErrorResult("path is required for import_ics")
+	}
+	resolved, err := validatePath(path, t.workspace)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open ics file: %v", err))
+	}
+	defer f.Close()
+
+	var sources []*ical.Calendar
+	dec := ical.NewDecoder(f)
+	for {
+		c, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to parse ics file: %v", err))
+		}
+		sources = append(sources, c)
+	}
+
+	calendars, err := t.resolveCalendars(ctx, client, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	cal := &calendars[0]
+
+	var b strings.Builder
+	var paths []string
+	imported := 0
+	for _, src := range sources {
+		var timezones []*ical.Component
+		byUID := map[string][]*ical.Component{}
+		var order []string
+		for _, child := range src.Children {
+			switch child.Name {
+			case ical.CompTimezone:
+				timezones = append(timezones, child)
+			case ical.CompEvent:
+				uid, _ := child.Props.Text(ical.PropUID)
+				if uid == "" {
+					uid = newUID()
+					child.Props.SetText(ical.PropUID, uid)
+				}
+				if _, seen := byUID[uid]; !seen {
+					order = append(order, uid)
+				}
+				// Recurrence overrides share the UID and belong in the same object.
+				byUID[uid] = append(byUID[uid], child)
+			}
+		}
+
+		for _, uid := range order {
+			calData := ical.NewCalendar()
+			calData.Props.SetText(ical.PropVersion, "2.0")
+			calData.Props.SetText(ical.PropProductID, "-//localagent//EN")
+			calData.Children = append(calData.Children, timezones...)
+			calData.Children = append(calData.Children, byUID[uid]...)
+
+			eventPath := cal.Path + safeObjectName(uid) + ".ics"
+			if _, err := client.PutCalendarObject(ctx, eventPath, calData); err != nil {
+				fmt.Fprintf(&b, "- failed to import %s: %v\n", uid, err)
+				continue
+			}
+			summary, _ := byUID[uid][0].Props.Text(ical.PropSummary)
+			fmt.Fprintf(&b, "- %s\n  Path: %s\n", summary, eventPath)
+			paths = append(paths, eventPath)
+			imported++
+		}
+	}
+
+	if imported == 0 && b.Len() == 0 {
+		return ErrorResult("no events found in ics file")
+	}
+	header := fmt.Sprintf("Imported %d event(s) into %s:\n", imported, cal.Name)
+	result := SilentResult(header + b.String()).WithTitle(fmt.Sprintf("Imported %d event(s)", imported))
+	if imported == 0 {
+		result.IsError = true
+	}
+	return result
+}
+
+func (t *CalendarTool) exportEvent(ctx context.Context, client *caldav.Client, args map[string]any) *ToolResult {
+	eventPath, _ := args["event_path"].(string)
+	if eventPath == "" {
+		return ErrorResult("event_path is required for export_event")
+	}
+	obj, err := client.GetCalendarObject(ctx, eventPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to get event: %v", err))
+	}
+	if obj.Data == nil || len(obj.Data.Events()) == 0 {
+		return ErrorResult("no event found at path")
+	}
+
+	name, _ := args["filename"].(string)
+	if name == "" {
+		summary, _ := obj.Data.Events()[0].Props.Text(ical.PropSummary)
+		name = summary
+	}
+	return t.writeICS(obj.Data, name, 1)
+}
+
+func (t *CalendarTool) exportRange(ctx context.Context, client *caldav.Client, args map[string]any) *ToolResult {
+	calendars, err := t.resolveCalendars(ctx, client, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	start, end := parseEventRange(args)
+
+	out := ical.NewCalendar()
+	out.Props.SetText(ical.PropVersion, "2.0")
+	out.Props.SetText(ical.PropProductID, "-//localagent//EN")
+	seenTZ := map[string]bool{}
+	count := 0
+	for _, cal := range calendars {
+		objects, err := client.QueryCalendar(ctx, cal.Path, eventQuery(start, end))
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to query %q: %v", cal.Name, err))
+		}
+		for _, obj := range objects {
+			if obj.Data == nil {
+				continue
+			}
+			for _, child := range obj.Data.Children {
+				switch child.Name {
+				case ical.CompTimezone:
+					tzid, _ := child.Props.Text(ical.PropTimezoneID)
+					if seenTZ[tzid] {
+						continue
+					}
+					seenTZ[tzid] = true
+				case ical.CompEvent:
+					count++
+				default:
+					continue
+				}
+				out.Children = append(out.Children, child)
+			}
+		}
+	}
+	if count == 0 {
+		return SilentResult(fmt.Sprintf("No events found from %s to %s; nothing exported.", start.Format("2006-01-02"), end.Format("2006-01-02")))
+	}
+
+	name, _ := args["filename"].(string)
+	if name == "" {
+		name = fmt.Sprintf("events-%s-to-%s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	return t.writeICS(out, name, count)
+}
+
+// writeICS encodes cal into the workspace export directory.
+func (t *CalendarTool) writeICS(cal *ical.Calendar, name string, events int) *ToolResult {
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to encode ics: %v", err))
+	}
+
+	name = safeObjectName(strings.TrimSuffix(filepath.Base(name), ".ics"))
+	if name == "" {
+		name = "event"
+	}
+	dir := filepath.Join(t.workspace, exportDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
+	}
+	path := filepath.Join(dir, name+".ics")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write ics file: %v", err))
+	}
+
+	return SilentResult(fmt.Sprintf("Exported %d event(s) to %s", events, path)).
+		WithTitle(filepath.Base(path)).
+		WithArtifacts(path)
+}
+
+// safeObjectName maps s to characters safe in file names and CalDAV paths.
+func safeObjectName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == '@':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-.")
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+func TestSafeObjectName(t *testing.T) {
+	cases := map[string]string{
+		"Dentist appointment":    "Dentist-appointment",
+		"../../etc/passwd":       "etc-passwd",
+		"abc-123@example.com":    "abc-123@example.com",
+		"  Team sync (weekly)  ": "Team-sync--weekly",
+	}
+	for in, want := range cases {
+		if got := safeObjectName(in); got != want {
+			t.Errorf("safeObjectName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewCalendarTool(dir, "", "", "")
+
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, "uid-1")
+	event.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	event.Props.SetText(ical.PropSummary, "Dentist")
+	event.Props.SetDateTime(ical.PropDateTimeStart, time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC))
+	event.Props.SetDateTime(ical.PropDateTimeEnd, time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//localagent//EN")
+	cal.Children = append(cal.Children, event.Component)
+
+	result := tool.writeICS(cal, "../Dentist.ics", 1)
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	want := filepath.Join(dir, exportDir, "Dentist.ics")
+	if len(result.Artifacts) != 1 || result.Artifacts[0] != want {
+		t.Fatalf("expected artifact %s, got %v", want, result.Artifacts)
+	}
+
+	f, err := os.Open(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	decoded, err := ical.NewDecoder(f).Decode()
+	if err != nil {
+		t.Fatalf("exported file does not parse: %v", err)
+	}
+	if events := decoded.Events(); len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	} else if s, _ := events[0].Props.Text(ical.PropSummary); !strings.EqualFold(s, "Dentist") {
+		t.Errorf("unexpected summary %q", s)
+	}
+}
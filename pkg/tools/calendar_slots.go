@@ -0,0 +1,373 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+
+	"localagent/pkg/when"
+)
+
+const (
+	defaultSlotMinutes = 30
+	defaultWorkStart   = "09:00"
+	defaultWorkEnd     = "18:00"
+	slotAlign          = 15 * time.Minute
+)
+
+type busyInterval struct {
+	Start time.Time
+	End   time.Time
+	Title string
+}
+
+type freeSlot struct {
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Minutes int    `json:"minutes"`
+}
+
+func (t *CalendarTool) findFreeSlots(ctx context.Context, client *caldav.Client, args map[string]any) *ToolResult {
+	calendars, err := t.resolveCalendars(ctx, client, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	loc := when.Location()
+	minutes := defaultSlotMinutes
+	if v, ok := args["duration_minutes"].(float64); ok && v > 0 {
+		minutes = int(v)
+	}
+	duration := time.Duration(minutes) * time.Minute
+
+	workStartStr, _ := args["work_start"].(string)
+	if workStartStr == "" {
+		workStartStr = defaultWorkStart
+	}
+	workEndStr, _ := args["work_end"].(string)
+	if workEndStr == "" {
+		workEndStr = defaultWorkEnd
+	}
+	workStart, err := parseClock(workStartStr)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid work_start: %v", err))
+	}
+	workEnd, err := parseClock(workEndStr)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid work_end: %v", err))
+	}
+	if workEnd <= workStart {
+		return ErrorResult("work_end must be after work_start")
+	}
+
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if s, _ := args["start_date"].(string); s != "" {
+		if from, err = parseLocalDateTime(s, loc); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid start_date: %v", err))
+		}
+	}
+	to := from.AddDate(0, 0, 1)
+	if s, _ := args["end_date"].(string); s != "" {
+		if to, err = parseLocalDateTime(s, loc); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid end_date: %v", err))
+		}
+	}
+	if !to.After(from) {
+		return ErrorResult("end_date must be after start_date")
+	}
+
+	// A proposed time widens the query so its conflicts are always found.
+	var proposedStart, proposedEnd time.Time
+	startStr, _ := args["start"].(string)
+	endStr, _ := args["end"].(string)
+	if startStr != "" {
+		if proposedStart, err = parseLocalDateTime(startStr, loc); err != nil {
+			return ErrorResult(fmt.Sprintf("invalid start datetime: %v", err))
+		}
+		proposedEnd = proposedStart.Add(duration)
+		if endStr != "" {
+			if proposedEnd, err = parseLocalDateTime(endStr, loc); err != nil {
+				return ErrorResult(fmt.Sprintf("invalid end datetime: %v", err))
+			}
+		}
+	}
+	queryFrom, queryTo := from, to
+	if !proposedStart.IsZero() {
+		queryFrom = minTime(queryFrom, proposedStart)
+		queryTo = maxTime(queryTo, proposedEnd)
+	}
+
+	var busy []busyInterval
+	var queryErrs []string
+	for _, cal := range calendars {
+		objects, err := client.QueryCalendar(ctx, cal.Path, eventQuery(queryFrom, queryTo))
+		if err != nil {
+			queryErrs = append(queryErrs, fmt.Sprintf("%s: %v", cal.Name, err))
+			continue
+		}
+		for _, obj := range objects {
+			if obj.Data == nil {
+				continue
+			}
+			for _, occ := range eventOccurrences(obj.Data, queryFrom, queryTo, loc) {
+				if iv, ok := busyFromEvent(occ); ok {
+					busy = append(busy, iv)
+				}
+			}
+		}
+	}
+
+	// Never offer slots that have already started.
+	slotFrom := from
+	if now.After(slotFrom) {
+		slotFrom = now
+	}
+	slots := computeFreeSlots(busy, slotFrom, to, workStart, workEnd, duration, loc)
+
+	var b strings.Builder
+	if !proposedStart.IsZero() {
+		clashes := conflictsWith(busy, proposedStart, proposedEnd)
+		if len(clashes) == 0 {
+			fmt.Fprintf(&b, "Proposed time %s–%s is free.\n\n", proposedStart.Format("Mon 2006-01-02 15:04"), proposedEnd.Format("15:04"))
+		} else {
+			fmt.Fprintf(&b, "Proposed time %s–%s conflicts with:\n", proposedStart.Format("Mon 2006-01-02 15:04"), proposedEnd.Format("15:04"))
+			for _, c := range clashes {
+				fmt.Fprintf(&b, "- %s (%s–%s)\n", c.Title, c.Start.Format("Mon 15:04"), c.End.Format("Mon 15:04"))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "Free slots of at least %s between %s and %s (working hours %s–%s, %s):\n",
+		duration, from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"), workStartStr, workEndStr, loc)
+	if len(slots) == 0 {
+		b.WriteString("- none\n")
+	}
+	for _, s := range slots {
+		start, _ := time.Parse(time.RFC3339, s.Start)
+		end, _ := time.Parse(time.RFC3339, s.End)
+		fmt.Fprintf(&b, "- %s–%s (%dm)\n", start.Format("Mon 2006-01-02 15:04"), end.Format("15:04"), s.Minutes)
+	}
+	for _, e := range queryErrs {
+		fmt.Fprintf(&b, "\nError querying %s", e)
+	}
+
+	return SilentResult(b.String()).
+		WithTitle(fmt.Sprintf("%d free slot(s)", len(slots))).
+		WithData(slots)
+}
+
+// eventQuery builds a CalDAV query for events overlapping [start, end).
+func eventQuery(start, end time.Time) *caldav.CalendarQuery {
+	return &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{
+			Name:     ical.CompCalendar,
+			AllProps: true,
+			Comps: []caldav.CalendarCompRequest{{
+				Name:     ical.CompEvent,
+				AllProps: true,
+			}},
+		},
+		CompFilter: caldav.CompFilter{
+			Name: ical.CompCalendar,
+			Comps: []caldav.CompFilter{{
+				Name:  ical.CompEvent,
+				Start: start,
+				End:   end,
+			}},
+		},
+	}
+}
+
+// occurrence is one instance of an event: the event itself, or one date
+// of a recurring event's RRULE/RDATE set.
+type occurrence struct {
+	Event      *ical.Event
+	Start, End time.Time
+}
+
+// eventOccurrences expands the events of a calendar object into their
+// instances overlapping [from, to). The server returns recurring events
+// once with their rule, so the dates have to be generated here; instances
+// overridden by a RECURRENCE-ID event are replaced by the override.
+func eventOccurrences(cal *ical.Calendar, from, to time.Time, loc *time.Location) []occurrence {
+	events := cal.Events()
+	overridden := make(map[string]bool)
+	for _, event := range events {
+		if prop := event.Props.Get(ical.PropRecurrenceID); prop != nil {
+			if at, err := prop.DateTime(loc); err == nil {
+				uid, _ := event.Props.Text(ical.PropUID)
+				overridden[uid+"@"+at.UTC().Format(time.RFC3339)] = true
+			}
+		}
+	}
+
+	overlaps := func(start, end time.Time) bool {
+		return start.Before(to) && (end.After(from) || !start.Before(from))
+	}
+	var out []occurrence
+	for i := range events {
+		event := &events[i]
+		start, err := event.DateTimeStart(loc)
+		if err != nil {
+			continue
+		}
+		end, err := event.DateTimeEnd(loc)
+		if err != nil || end.Before(start) {
+			end = start
+		}
+
+		set, err := event.RecurrenceSet(loc)
+		if err != nil || set == nil || event.Props.Get(ical.PropRecurrenceID) != nil {
+			if overlaps(start, end) {
+				out = append(out, occurrence{Event: event, Start: start, End: end})
+			}
+			continue
+		}
+		uid, _ := event.Props.Text(ical.PropUID)
+		length := end.Sub(start)
+		for _, at := range set.Between(from.Add(-length), to, true) {
+			if overridden[uid+"@"+at.UTC().Format(time.RFC3339)] || !overlaps(at, at.Add(length)) {
+				continue
+			}
+			out = append(out, occurrence{Event: event, Start: at, End: at.Add(length)})
+		}
+	}
+	return out
+}
+
+// busyFromEvent converts an event occurrence into a busy interval. All-day,
+// transparent and cancelled events don't block time.
+func busyFromEvent(occ occurrence) (busyInterval, bool) {
+	event := occ.Event
+	if prop := event.Props.Get(ical.PropDateTimeStart); prop == nil || prop.ValueType() == ical.ValueDate {
+		return busyInterval{}, false
+	}
+	if transp, _ := event.Props.Text(ical.PropTransparency); strings.EqualFold(transp, "TRANSPARENT") {
+		return busyInterval{}, false
+	}
+	if status, _ := event.Props.Text(ical.PropStatus); strings.EqualFold(status, "CANCELLED") {
+		return busyInterval{}, false
+	}
+	title, _ := event.Props.Text(ical.PropSummary)
+	return busyInterval{Start: occ.Start, End: occ.End, Title: title}, true
+}
+
+// computeFreeSlots returns the gaps of at least minDur within working hours
+// (offsets from local midnight) between from and to.
+func computeFreeSlots(busy []busyInterval, from, to time.Time, workStart, workEnd, minDur time.Duration, loc *time.Location) []freeSlot {
+	merged := mergeBusy(busy)
+	var slots []freeSlot
+
+	day := time.Date(from.In(loc).Year(), from.In(loc).Month(), from.In(loc).Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		winStart := maxTime(day.Add(workStart), from)
+		winEnd := minTime(day.Add(workEnd), to)
+		if !winEnd.After(winStart) {
+			continue
+		}
+
+		cursor := alignUp(winStart)
+		for _, iv := range merged {
+			if !iv.End.After(cursor) {
+				continue
+			}
+			if !iv.Start.Before(winEnd) {
+				break
+			}
+			if iv.Start.Sub(cursor) >= minDur {
+				slots = append(slots, newFreeSlot(cursor, iv.Start))
+			}
+			cursor = alignUp(maxTime(cursor, iv.End))
+		}
+		if winEnd.Sub(cursor) >= minDur {
+			slots = append(slots, newFreeSlot(cursor, winEnd))
+		}
+	}
+	return slots
+}
+
+func mergeBusy(busy []busyInterval) []busyInterval {
+	sorted := make([]busyInterval, len(busy))
+	copy(sorted, busy)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var merged []busyInterval
+	for _, iv := range sorted {
+		if n := len(merged); n > 0 && !iv.Start.After(merged[n-1].End) {
+			merged[n-1].End = maxTime(merged[n-1].End, iv.End)
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+func conflictsWith(busy []busyInterval, start, end time.Time) []busyInterval {
+	var out []busyInterval
+	for _, iv := range busy {
+		if iv.Start.Before(end) && iv.End.After(start) {
+			out = append(out, iv)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+func newFreeSlot(start, end time.Time) freeSlot {
+	return freeSlot{
+		Start:   start.Format(time.RFC3339),
+		End:     end.Format(time.RFC3339),
+		Minutes: int(end.Sub(start) / time.Minute),
+	}
+}
+
+func alignUp(t time.Time) time.Time {
+	if r := t.Truncate(slotAlign); r.Before(t) {
+		return r.Add(slotAlign)
+	}
+	return t
+}
+
+// parseClock parses "HH:MM" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseLocalDateTime is parseDateTime for values without an offset,
+// which are interpreted in loc rather than UTC. Natural expressions like
+// "tomorrow afternoon" are resolved as well.
+func parseLocalDateTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(loc), nil
+	}
+	r, err := when.Parse(s, time.Now().In(loc))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return r.Time, nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
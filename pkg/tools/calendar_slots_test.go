@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+func TestComputeFreeSlots(t *testing.T) {
+	loc := time.UTC
+	day := time.Date(2025, 1, 15, 0, 0, 0, 0, loc)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	busy := []busyInterval{
+		{Start: at(10, 0), End: at(11, 0), Title: "standup"},
+		{Start: at(10, 30), End: at(12, 10), Title: "overlap"},
+		{Start: at(14, 0), End: at(15, 0), Title: "review"},
+	}
+	slots := computeFreeSlots(busy, day, day.AddDate(0, 0, 1), 9*time.Hour, 18*time.Hour, 45*time.Minute, loc)
+
+	want := []freeSlot{
+		newFreeSlot(at(9, 0), at(10, 0)),
+		newFreeSlot(at(12, 15), at(14, 0)),
+		newFreeSlot(at(15, 0), at(18, 0)),
+	}
+	if len(slots) != len(want) {
+		t.Fatalf("expected %d slots, got %v", len(want), slots)
+	}
+	for i := range want {
+		if slots[i] != want[i] {
+			t.Errorf("slot %d: expected %v, got %v", i, want[i], slots[i])
+		}
+	}
+
+	// A window that starts mid-afternoon only offers what's left of the day.
+	slots = computeFreeSlots(busy, at(16, 50), day.AddDate(0, 0, 1), 9*time.Hour, 18*time.Hour, 45*time.Minute, loc)
+	if len(slots) != 1 || slots[0] != newFreeSlot(at(17, 0), at(18, 0)) {
+		t.Errorf("unexpected slots for late window: %v", slots)
+	}
+}
+
+func TestConflictsWith(t *testing.T) {
+	base := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	busy := []busyInterval{
+		{Start: base, End: base.Add(time.Hour), Title: "a"},
+		{Start: base.Add(2 * time.Hour), End: base.Add(3 * time.Hour), Title: "b"},
+	}
+	if got := conflictsWith(busy, base.Add(time.Hour), base.Add(2*time.Hour)); len(got) != 0 {
+		t.Errorf("back-to-back slot should not conflict, got %v", got)
+	}
+	if got := conflictsWith(busy, base.Add(30*time.Minute), base.Add(150*time.Minute)); len(got) != 2 {
+		t.Errorf("expected 2 conflicts, got %v", got)
+	}
+}
+
+func TestEventOccurrencesExpandsRecurrence(t *testing.T) {
+	const ics = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:test\r\n" +
+		"BEGIN:VEVENT\r\nUID:standup\r\nDTSTAMP:20250101T000000Z\r\nSUMMARY:Standup\r\n" +
+		"DTSTART:20250106T090000Z\r\nDTEND:20250106T093000Z\r\nRRULE:FREQ=DAILY;COUNT=10\r\n" +
+		"EXDATE:20250108T090000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:standup\r\nDTSTAMP:20250101T000000Z\r\nSUMMARY:Standup (moved)\r\n" +
+		"RECURRENCE-ID:20250109T090000Z\r\nDTSTART:20250109T140000Z\r\nDTEND:20250109T143000Z\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	cal, err := ical.NewDecoder(strings.NewReader(ics)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	var got []string
+	for _, occ := range eventOccurrences(cal, from, to, time.UTC) {
+		iv, ok := busyFromEvent(occ)
+		if !ok {
+			t.Fatalf("occurrence not busy: %+v", occ)
+		}
+		got = append(got, iv.Start.Format("02 15:04")+" "+iv.Title)
+	}
+	want := []string{"07 09:00 Standup", "09 14:00 Standup (moved)"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("occurrences = %v, want %v", got, want)
+	}
+
+	// An instance already running at the start of the window counts.
+	from = time.Date(2025, 1, 10, 9, 15, 0, 0, time.UTC)
+	if occs := eventOccurrences(cal, from, from.Add(time.Hour), time.UTC); len(occs) != 1 || !occs[0].Start.Equal(from.Add(-15*time.Minute)) {
+		t.Errorf("in-progress instance = %+v", occs)
+	}
+}
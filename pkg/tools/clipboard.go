@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"localagent/pkg/config"
+	"localagent/pkg/utils"
+)
+
+const (
+	clipboardTimeout  = 10 * time.Second
+	clipboardMaxChars = 20000 // of clipboard text returned to the model
+)
+
+// clipboardBackend reads and writes the clipboard with a pair of commands;
+// write gets the text on stdin.
+type clipboardBackend struct {
+	read, write []string
+}
+
+// clipboardBackends lists the backends to try on goos, in order.
+func clipboardBackends(goos string) []clipboardBackend {
+	switch goos {
+	case "darwin":
+		return []clipboardBackend{{[]string{"pbpaste"}, []string{"pbcopy"}}}
+	case "windows":
+		return []clipboardBackend{{
+			[]string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw"},
+			[]string{"powershell", "-NoProfile", "-NonInteractive", "-Command", "[Console]::In.ReadToEnd() | Set-Clipboard"},
+		}}
+	}
+	var backends []clipboardBackend
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		backends = append(backends, clipboardBackend{[]string{"wl-paste", "--no-newline"}, []string{"wl-copy"}})
+	}
+	return append(backends,
+		clipboardBackend{[]string{"xclip", "-selection", "clipboard", "-o"}, []string{"xclip", "-selection", "clipboard"}},
+		clipboardBackend{[]string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"}},
+	)
+}
+
+// ClipboardTool reads and writes the clipboard of the machine the gateway
+// runs on.
+type ClipboardTool struct {
+	goos      string
+	writeOnly bool
+}
+
+func NewClipboardTool(cfg config.ClipboardConfig) *ClipboardTool {
+	return &ClipboardTool{goos: runtime.GOOS, writeOnly: cfg.WriteOnly}
+}
+
+func (t *ClipboardTool) Name() string {
+	return "clipboard"
+}
+
+func (t *ClipboardTool) Description() string {
+	if t.writeOnly {
+		return "Put text on the user's clipboard (on the computer the agent runs on), e.g. a command they asked for."
+	}
+	return "Read or replace the text on the user's clipboard (on the computer the agent runs on). " +
+		"Use read when the user refers to what they copied, write when they ask to put something on their clipboard."
+}
+
+func (t *ClipboardTool) Parameters() map[string]any {
+	actions := []string{"read", "write"}
+	if t.writeOnly {
+		actions = []string{"write"}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        actions,
+				"description": "read the clipboard, or write text to it.",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Text to put on the clipboard (for write).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; read only read.
+func (t *ClipboardTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "read")
+}
+
+func (t *ClipboardTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	text, _ := args["text"].(string)
+	backend, ok := t.backend()
+	if !ok {
+		return ErrorResult(fmt.Sprintf("no clipboard command found on %s (install wl-clipboard, xclip or xsel)", t.goos))
+	}
+	ctx, cancel := context.WithTimeout(ctx, clipboardTimeout)
+	defer cancel()
+
+	switch action {
+	case "read":
+		if t.writeOnly {
+			return ErrorResult("reading the clipboard is disabled (tools.clipboard.write_only)")
+		}
+		cmd := exec.CommandContext(ctx, backend.read[0], backend.read[1:]...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%v: %s", err, utils.Truncate(msg, 300))
+			}
+			return ErrorResult(fmt.Sprintf("reading the clipboard failed: %v", err)).WithError(err)
+		}
+		if !utf8.Valid(stdout.Bytes()) {
+			return ErrorResult("the clipboard does not hold text")
+		}
+		content := stdout.String()
+		if strings.TrimSpace(content) == "" {
+			return SilentResult("The clipboard is empty.")
+		}
+		return SilentResult(utils.Truncate(content, clipboardMaxChars))
+	case "write":
+		if text == "" {
+			return ErrorResult("text is required for write")
+		}
+		// No output pipes: xclip and wl-copy stay in the background to
+		// serve the selection and would keep them open.
+		cmd := exec.CommandContext(ctx, backend.write[0], backend.write[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			return ErrorResult(fmt.Sprintf("writing the clipboard failed: %v", err)).WithError(err)
+		}
+		return SilentResult(fmt.Sprintf("Copied %d characters to the clipboard.", utf8.RuneCountInString(text)))
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
+
+// backend returns the first backend whose commands are installed.
+func (t *ClipboardTool) backend() (clipboardBackend, bool) {
+	for _, b := range clipboardBackends(t.goos) {
+		if _, err := exec.LookPath(b.read[0]); err != nil {
+			continue
+		}
+		if _, err := exec.LookPath(b.write[0]); err != nil {
+			continue
+		}
+		return b, true
+	}
+	return clipboardBackend{}, false
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestClipboardTool(t *testing.T) {
+	bin := t.TempDir()
+	store := filepath.Join(t.TempDir(), "clipboard")
+	// A fake xclip: "-o" prints the stored text, otherwise stdin is stored.
+	script := "#!/bin/sh\ncase \"$*\" in *-o*) cat " + shellQuote(store) + " ;; *) cat > " + shellQuote(store) + " ;; esac\n"
+	if err := os.WriteFile(filepath.Join(bin, "xclip"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+":/usr/bin:/bin")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	tool := NewClipboardTool(config.ClipboardConfig{})
+	tool.goos = "linux"
+	if res := tool.Execute(context.Background(), map[string]any{"action": "write", "text": "kubectl get pods -A"}); res.IsError {
+		t.Fatal(res.ForLLM)
+	}
+	res := tool.Execute(context.Background(), map[string]any{"action": "read"})
+	if res.IsError || res.ForLLM != "kubectl get pods -A" {
+		t.Fatalf("read = %+v", res)
+	}
+
+	writeOnly := NewClipboardTool(config.ClipboardConfig{WriteOnly: true})
+	writeOnly.goos = "linux"
+	if res := writeOnly.Execute(context.Background(), map[string]any{"action": "read"}); !res.IsError || !strings.Contains(res.ForLLM, "write_only") {
+		t.Errorf("write-only read = %+v", res)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if res := tool.Execute(context.Background(), map[string]any{"action": "read"}); !res.IsError || !strings.Contains(res.ForLLM, "xclip") {
+		t.Errorf("without a backend = %+v", res)
+	}
+}
@@ -11,12 +11,16 @@ import (
 	"localagent/pkg/bus"
 	"localagent/pkg/cron"
 	"localagent/pkg/session"
+	"localagent/pkg/when"
 )
 
 const defaultJobTimeout = 10 * time.Minute
 
 type JobExecutor interface {
 	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
+	// ProcessDirectAs runs content with a household role other than the
+	// owner's, for jobs created by other members.
+	ProcessDirectAs(ctx context.Context, content, sessionKey, channel, chatID, role string) (string, error)
 	WasMessageToolCalled() bool
 }
 
@@ -28,6 +32,7 @@ type CronTool struct {
 	msgBus       *bus.MessageBus
 	sessions     *session.SessionManager
 	enqueueEvent EventEnqueuer
+	deliver      func(bus.OutboundMessage)
 	channel      string
 	chatID       string
 	mu           sync.RWMutex
@@ -47,6 +52,14 @@ func (t *CronTool) SetEventEnqueuer(fn EventEnqueuer) {
 	t.enqueueEvent = fn
 }
 
+// SetDeliver routes announced job results through fn (e.g. a
+// do-not-disturb outbox) instead of publishing them directly.
+func (t *CronTool) SetDeliver(fn func(bus.OutboundMessage)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deliver = fn
+}
+
 func (t *CronTool) SetSessionManager(sm *session.SessionManager) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -81,7 +94,7 @@ JOB SCHEMA (for add action):
 
 SCHEDULE TYPES (schedule.kind):
 - "at": One-shot at absolute time
-  { "kind": "at", "at": "<ISO-8601 timestamp>" }
+  { "kind": "at", "at": "<ISO-8601 timestamp or expression like 'tomorrow 9am', 'in 2 hours'>" }
 - "every": Recurring interval
   { "kind": "every", "everyMs": <ms> }
 - "cron": Cron expression
@@ -92,6 +105,8 @@ PAYLOAD TYPES (payload.kind):
   { "kind": "systemEvent", "text": "<message>" }
 - "agentTurn": Runs agent with message (isolated sessions only)
   { "kind": "agentTurn", "message": "<prompt>" }
+- "message": Delivers text verbatim to the channel, without running the agent
+  { "kind": "message", "text": "<message>" }
 
 DELIVERY (top-level):
   { "mode": "none|announce", "channel": "<optional>", "to": "<optional>" }
@@ -185,6 +200,31 @@ func (t *CronTool) SetContext(channel, chatID string) {
 	t.chatID = chatID
 }
 
+// AuditAction records schedule changes and manual runs; status, list and
+// wake are not audited.
+func (t *CronTool) AuditAction(args map[string]any) (string, string) {
+	action, _ := args["action"].(string)
+	switch action {
+	case "add":
+		job, _ := args["job"].(map[string]any)
+		name, _ := job["name"].(string)
+		if name == "" {
+			name, _ = args["name"].(string)
+		}
+		return "cron.add", name
+	case "update", "remove", "run":
+		jobID, _ := args["jobId"].(string)
+		return "cron." + action, jobID
+	}
+	return "", ""
+}
+
+// SideEffect reports whether the call changes anything; status and list
+// only read.
+func (t *CronTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "status", "list")
+}
+
 func (t *CronTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	action, ok := args["action"].(string)
 	if !ok {
@@ -197,15 +237,15 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	case "list":
 		return t.listAction(args)
 	case "add":
-		return t.addAction(args)
+		return t.addAction(RoleFromContext(ctx), args)
 	case "update":
-		return t.updateAction(args)
+		return t.updateAction(RoleFromContext(ctx), args)
 	case "remove":
-		return t.removeAction(args)
+		return t.removeAction(RoleFromContext(ctx), args)
 	case "run":
-		return t.runAction(args)
+		return t.runAction(RoleFromContext(ctx), args)
 	case "wake":
-		return t.wakeAction(args)
+		return t.wakeAction(RoleFromContext(ctx), args)
 	default:
 		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
 	}
@@ -229,7 +269,24 @@ func (t *CronTool) listAction(args map[string]any) *ToolResult {
 	return SilentResult(string(data))
 }
 
-func (t *CronTool) addAction(args map[string]any) *ToolResult {
+// checkOwnJob keeps non-owner members to the jobs they created, so they
+// can't rewrite or trigger the owner's agent turns. role "" is the owner.
+func (t *CronTool) checkOwnJob(role, jobID string) *ToolResult {
+	if role == "" {
+		return nil
+	}
+	for _, job := range t.cronService.ListJobs(true) {
+		if job.ID == jobID {
+			if job.Role != role {
+				return ErrorResult(fmt.Sprintf("Job %s was not created by you; only the owner can change it", jobID))
+			}
+			return nil
+		}
+	}
+	return ErrorResult(fmt.Sprintf("Job %s not found", jobID))
+}
+
+func (t *CronTool) addAction(role string, args map[string]any) *ToolResult {
 	args = recoverFlatJobParams(args)
 
 	t.mu.RLock()
@@ -251,11 +308,25 @@ func (t *CronTool) addAction(args map[string]any) *ToolResult {
 	if err := json.Unmarshal(data, &job); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to parse job: %v", err))
 	}
+	// System events are handled by the heartbeat with the owner's tools.
+	if role != "" && job.Payload.Kind == "systemEvent" {
+		return ErrorResult("systemEvent jobs can only be created by the owner; use a message or agentTurn job")
+	}
+	job.Role = role
+
+	if job.Schedule.Kind == "at" {
+		at, err := resolveAt(job.Schedule.At)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		job.Schedule.At = at
+	}
 
 	if job.SessionTarget == "" {
-		if job.Payload.Kind == "systemEvent" {
+		switch job.Payload.Kind {
+		case "systemEvent":
 			job.SessionTarget = "main"
-		} else {
+		case "agentTurn":
 			job.SessionTarget = "isolated"
 		}
 	}
@@ -290,7 +361,27 @@ func (t *CronTool) addAction(args map[string]any) *ToolResult {
 	return SilentResult(fmt.Sprintf("Cron job added: %s (id: %s)", created.Name, created.ID))
 }
 
-func (t *CronTool) updateAction(args map[string]any) *ToolResult {
+// resolveAt turns a one-shot schedule time into RFC 3339, accepting
+// natural expressions. Date-only values are rejected since the job would
+// fire at midnight.
+func resolveAt(raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("schedule.at is required for kind \"at\"")
+	}
+	if _, err := time.Parse(time.RFC3339, raw); err == nil {
+		return raw, nil
+	}
+	r, err := when.Resolve(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid schedule.at: %v", err)
+	}
+	if r.DateOnly {
+		return "", fmt.Errorf("schedule.at %q has no time of day; add one, e.g. %q", raw, raw+" 9am")
+	}
+	return r.Time.Format(time.RFC3339), nil
+}
+
+func (t *CronTool) updateAction(role string, args map[string]any) *ToolResult {
 	jobID, ok := args["jobId"].(string)
 	if !ok || jobID == "" {
 		return ErrorResult("'jobId' is required for update action")
@@ -300,6 +391,22 @@ func (t *CronTool) updateAction(args map[string]any) *ToolResult {
 	if !ok {
 		return ErrorResult("'patch' object is required for update action")
 	}
+	if res := t.checkOwnJob(role, jobID); res != nil {
+		return res
+	}
+	if payload, ok := patch["payload"].(map[string]any); ok && role != "" && payload["kind"] == "systemEvent" {
+		return ErrorResult("systemEvent jobs can only be created by the owner")
+	}
+
+	if sched, ok := patch["schedule"].(map[string]any); ok {
+		if raw, ok := sched["at"].(string); ok {
+			at, err := resolveAt(raw)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			sched["at"] = at
+		}
+	}
 
 	job, err := t.cronService.PatchJob(jobID, patch)
 	if err != nil {
@@ -309,11 +416,14 @@ func (t *CronTool) updateAction(args map[string]any) *ToolResult {
 	return SilentResult(fmt.Sprintf("Cron job updated: %s (id: %s)", job.Name, job.ID))
 }
 
-func (t *CronTool) removeAction(args map[string]any) *ToolResult {
+func (t *CronTool) removeAction(role string, args map[string]any) *ToolResult {
 	jobID, ok := args["jobId"].(string)
 	if !ok || jobID == "" {
 		return ErrorResult("'jobId' is required for remove action")
 	}
+	if res := t.checkOwnJob(role, jobID); res != nil {
+		return res
+	}
 
 	if t.cronService.RemoveJob(jobID) {
 		return SilentResult(fmt.Sprintf("Cron job removed: %s", jobID))
@@ -321,11 +431,14 @@ func (t *CronTool) removeAction(args map[string]any) *ToolResult {
 	return ErrorResult(fmt.Sprintf("Job %s not found", jobID))
 }
 
-func (t *CronTool) runAction(args map[string]any) *ToolResult {
+func (t *CronTool) runAction(role string, args map[string]any) *ToolResult {
 	jobID, ok := args["jobId"].(string)
 	if !ok || jobID == "" {
 		return ErrorResult("'jobId' is required for run action")
 	}
+	if res := t.checkOwnJob(role, jobID); res != nil {
+		return res
+	}
 
 	runMode, _ := args["runMode"].(string)
 	force := runMode == "force"
@@ -337,7 +450,10 @@ func (t *CronTool) runAction(args map[string]any) *ToolResult {
 	return SilentResult(fmt.Sprintf("Job %s triggered", jobID))
 }
 
-func (t *CronTool) wakeAction(args map[string]any) *ToolResult {
+func (t *CronTool) wakeAction(role string, args map[string]any) *ToolResult {
+	if role != "" {
+		return ErrorResult("wake events can only be sent by the owner")
+	}
 	text, _ := args["text"].(string)
 	if text == "" {
 		return ErrorResult("'text' is required for wake action")
@@ -401,7 +517,13 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 
 	if job.Payload.Kind == "agentTurn" {
 		sessionKey := fmt.Sprintf("cron-%s", job.ID)
-		response, err := t.executor.ProcessDirectWithChannel(ctx, job.Payload.Message, sessionKey, channel, chatID)
+		var response string
+		var err error
+		if job.Role != "" {
+			response, err = t.executor.ProcessDirectAs(ctx, job.Payload.Message, sessionKey, channel, chatID, job.Role)
+		} else {
+			response, err = t.executor.ProcessDirectWithChannel(ctx, job.Payload.Message, sessionKey, channel, chatID)
+		}
 		if err != nil {
 			return fmt.Sprintf("Error: %v", err)
 		}
@@ -413,6 +535,11 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		return "ok"
 	}
 
+	if job.Payload.Kind == "message" {
+		t.deliverMessage(channel, chatID, job.Payload.Text, job.Delivery != nil && job.Delivery.Mode == "immediate")
+		return "ok"
+	}
+
 	return fmt.Sprintf("unknown payload kind: %s", job.Payload.Kind)
 }
 
@@ -424,10 +551,15 @@ func (t *CronTool) announceResult(channel, chatID string, job *cron.CronJob, res
 		content.WriteString(response)
 	}
 
-	msg := content.String()
+	t.deliverMessage(channel, chatID, content.String(), false)
+}
 
+// deliverMessage records msg in the chat's session and sends it; immediate
+// bypasses the deliver hook (e.g. for timers during do-not-disturb).
+func (t *CronTool) deliverMessage(channel, chatID, msg string, immediate bool) {
 	t.mu.RLock()
 	sm := t.sessions
+	deliver := t.deliver
 	t.mu.RUnlock()
 
 	if sm != nil {
@@ -435,7 +567,10 @@ func (t *CronTool) announceResult(channel, chatID string, job *cron.CronJob, res
 		sm.AddMessage(sessionKey, "assistant", msg)
 	}
 
-	t.msgBus.PublishOutbound(bus.OutboundMessage{
+	if deliver == nil || immediate {
+		deliver = t.msgBus.PublishOutbound
+	}
+	deliver(bus.OutboundMessage{
 		Channel: channel,
 		ChatID:  chatID,
 		Content: msg,
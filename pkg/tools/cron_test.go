@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"localagent/pkg/cron"
+)
+
+type recordingExecutor struct {
+	role string
+}
+
+func (e *recordingExecutor) ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	e.role = "owner"
+	return "", nil
+}
+
+func (e *recordingExecutor) ProcessDirectAs(ctx context.Context, content, sessionKey, channel, chatID, role string) (string, error) {
+	e.role = role
+	return "", nil
+}
+
+func (e *recordingExecutor) WasMessageToolCalled() bool { return false }
+
+func TestCronToolKeepsCreatorRole(t *testing.T) {
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	exec := &recordingExecutor{}
+	tool := NewCronTool(cs, exec, nil)
+	tool.SetContext("telegram", "42")
+	owner := context.Background()
+	family := WithRole(owner, "family")
+
+	add := func(ctx context.Context, kind string) *ToolResult {
+		payload := map[string]any{"kind": kind, "message": "check the server", "text": "check the server"}
+		return tool.Execute(ctx, map[string]any{"action": "add", "job": map[string]any{
+			"name": kind, "schedule": map[string]any{"kind": "every", "everyMs": 60000}, "payload": payload, "role": "owner",
+		}})
+	}
+	if res := add(family, "systemEvent"); !res.IsError {
+		t.Error("family member created a system event")
+	}
+	if res := tool.Execute(family, map[string]any{"action": "wake", "text": "hi"}); !res.IsError {
+		t.Error("family member sent a wake event")
+	}
+	if res := add(owner, "agentTurn"); res.IsError {
+		t.Fatalf("owner add: %s", res.ForLLM)
+	}
+	if res := add(family, "agentTurn"); res.IsError {
+		t.Fatalf("family add: %s", res.ForLLM)
+	}
+
+	var ownerJob, familyJob cron.CronJob
+	for _, job := range cs.ListJobs(true) {
+		if job.Role == "" {
+			ownerJob = job
+		} else {
+			familyJob = job
+		}
+	}
+	if familyJob.Role != "family" {
+		t.Fatalf("family job role = %q", familyJob.Role)
+	}
+
+	patch := map[string]any{"payload": map[string]any{"kind": "agentTurn", "message": "rm -rf"}}
+	if res := tool.Execute(family, map[string]any{"action": "update", "jobId": ownerJob.ID, "patch": patch}); !res.IsError {
+		t.Error("family member rewrote the owner's job")
+	}
+	if res := tool.Execute(family, map[string]any{"action": "update", "jobId": familyJob.ID, "patch": patch}); res.IsError {
+		t.Errorf("family member could not update own job: %s", res.ForLLM)
+	}
+
+	tool.ExecuteJob(owner, &familyJob)
+	if exec.role != "family" {
+		t.Errorf("family job ran as %q", exec.role)
+	}
+	tool.ExecuteJob(owner, &ownerJob)
+	if exec.role != "owner" {
+		t.Errorf("owner job ran as %q", exec.role)
+	}
+}
@@ -50,6 +50,9 @@ func (t *CurrencyTool) DeclaredDomains() []string {
 	return []string{"query2.finance.yahoo.com", "fc.yahoo.com"}
 }
 
+// SideEffect reports that CurrencyTool calls only read.
+func (t *CurrencyTool) SideEffect(map[string]any) bool { return false }
+
 func (t *CurrencyTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	from, _ := args["from"].(string)
 	to, _ := args["to"].(string)
@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/utils"
+)
+
+// placeholder matches {{name}} in custom tool templates.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// CustomTool runs a command or HTTP request defined in tools.custom.
+type CustomTool struct {
+	cfg        config.CustomToolConfig
+	workingDir string
+	timeout    time.Duration
+	match      *regexp.Regexp
+	apiKey     string
+}
+
+// NewCustomTool validates def and returns its tool. Commands run in
+// workingDir.
+func NewCustomTool(def config.CustomToolConfig, workingDir string) (*CustomTool, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	t := &CustomTool{cfg: def, workingDir: workingDir, timeout: 30 * time.Second, apiKey: def.ResolveAPIKey()}
+	if def.TimeoutSecs > 0 {
+		t.timeout = time.Duration(def.TimeoutSecs) * time.Second
+	}
+	if def.Output.Match != "" {
+		t.match = regexp.MustCompile(def.Output.Match)
+	}
+	return t, nil
+}
+
+func (t *CustomTool) Name() string {
+	return t.cfg.Name
+}
+
+func (t *CustomTool) Description() string {
+	return t.cfg.Description
+}
+
+func (t *CustomTool) Parameters() map[string]any {
+	if t.cfg.Parameters != nil {
+		return t.cfg.Parameters
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+func (t *CustomTool) Group() string {
+	return t.cfg.Group
+}
+
+func (t *CustomTool) DeclaredDomains() []string {
+	if t.cfg.HTTP == nil {
+		return nil
+	}
+	u, err := url.Parse(t.cfg.HTTP.URL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	return []string{u.Hostname()}
+}
+
+// AuditAction records commands and requests that can change something;
+// GET requests are treated as read-only.
+func (t *CustomTool) AuditAction(args map[string]any) (string, string) {
+	if t.cfg.Command != "" {
+		return "custom_exec", t.cfg.Name + ": " + expand(t.cfg.Command, args, shellQuote)
+	}
+	if method := t.method(); method != http.MethodGet {
+		return "custom_http", method + " " + expand(t.cfg.HTTP.URL, args, url.QueryEscape)
+	}
+	return "", ""
+}
+
+func (t *CustomTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var out string
+	var err error
+	if t.cfg.Command != "" {
+		out, err = t.run(ctx, args)
+	} else {
+		out, err = t.request(ctx, args)
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrorResult(fmt.Sprintf("%s timed out after %v", t.cfg.Name, t.timeout)).WithError(errs.Wrap(errs.ErrTimeout, err))
+		}
+		return ErrorResult(fmt.Sprintf("%s failed: %v", t.cfg.Name, err)).WithError(err)
+	}
+
+	out, err = t.postProcess(out)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("%s: %v", t.cfg.Name, err))
+	}
+	if out == "" {
+		out = "(no output)"
+	}
+	return SilentResult(out)
+}
+
+func (t *CustomTool) run(ctx context.Context, args map[string]any) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", expand(t.cfg.Command, args, shellQuote))
+	cmd.Dir = t.workingDir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, utils.Truncate(msg, 500))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (t *CustomTool) request(ctx context.Context, args map[string]any) (string, error) {
+	h := t.cfg.HTTP
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(expand(h.Body, args, jsonEscape))
+	}
+	req, err := http.NewRequestWithContext(ctx, t.method(), expand(h.URL, args, url.QueryEscape), body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, expand(v, args, headerValue))
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := httpclient.New(t.cfg.Name).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", errs.Wrap(errs.FromStatus(resp.StatusCode), fmt.Errorf("status %d: %s", resp.StatusCode, utils.Truncate(strings.TrimSpace(string(data)), 500)))
+	}
+	return string(data), nil
+}
+
+func (t *CustomTool) method() string {
+	if m := strings.ToUpper(t.cfg.HTTP.Method); m != "" {
+		return m
+	}
+	return http.MethodGet
+}
+
+// postProcess applies the output settings: JSON path, line match, length.
+func (t *CustomTool) postProcess(out string) (string, error) {
+	if path := t.cfg.Output.JSONPath; path != "" {
+		var v any
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			return "", fmt.Errorf("output is not JSON: %v", err)
+		}
+		v, err := jsonPath(v, path)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := v.(string); ok {
+			out = s
+		} else {
+			b, _ := json.MarshalIndent(v, "", "  ")
+			out = string(b)
+		}
+	}
+	if t.match != nil {
+		var kept []string
+		for line := range strings.SplitSeq(out, "\n") {
+			m := t.match.FindStringSubmatch(line)
+			switch {
+			case m == nil:
+			case len(m) > 1:
+				kept = append(kept, m[1])
+			default:
+				kept = append(kept, m[0])
+			}
+		}
+		out = strings.Join(kept, "\n")
+	}
+	maxChars := t.cfg.Output.MaxChars
+	if maxChars == 0 {
+		maxChars = 4000
+	}
+	return utils.Truncate(strings.TrimSpace(out), maxChars), nil
+}
+
+// jsonPath walks a dotted path of object keys and array indexes.
+func jsonPath(v any, path string) (any, error) {
+	for key := range strings.SplitSeq(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("json_path %q: no key %q", path, key)
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("json_path %q: no index %q in array of %d", path, key, len(node))
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("json_path %q: %q is not an object or array", path, key)
+		}
+	}
+	return v, nil
+}
+
+// expand replaces {{name}} with the escaped argument value; missing
+// arguments expand to an empty (escaped) string.
+func expand(tmpl string, args map[string]any, escape func(string) string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		var s string
+		switch v := args[name].(type) {
+		case nil:
+		case string:
+			s = v
+		case float64, bool, int:
+			s = fmt.Sprint(v)
+		default:
+			b, _ := json.Marshal(v)
+			s = string(b)
+		}
+		return escape(s)
+	})
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func jsonEscape(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	out := strings.TrimSuffix(b.String(), "\n")
+	return out[1 : len(out)-1]
+}
+
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestCustomCommandTool(t *testing.T) {
+	tool, err := NewCustomTool(config.CustomToolConfig{
+		Name:        "greet",
+		Description: "Greet someone",
+		Command:     "printf 'hello %s\\nbye\\n' {{name}}",
+		Output:      config.CustomOutput{Match: `^hello (.*)$`},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := tool.Execute(context.Background(), map[string]any{"name": "Ann'; echo pwned"})
+	if res.IsError || res.ForLLM != "Ann'; echo pwned" {
+		t.Errorf("result = %+v", res)
+	}
+	if action, target := tool.AuditAction(map[string]any{"name": "x"}); action != "custom_exec" || !strings.Contains(target, "'x'") {
+		t.Errorf("audit = %q %q", action, target)
+	}
+}
+
+func TestCustomHTTPTool(t *testing.T) {
+	var gotQuery, gotBody, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"results":[{"title":"Bern weather","temp":12.5}]}`))
+	}))
+	defer srv.Close()
+	t.Setenv("CUSTOM_TOKEN", "secret-token")
+
+	tool, err := NewCustomTool(config.CustomToolConfig{
+		Name:        "weather",
+		Description: "Weather lookup",
+		HTTP: &config.CustomHTTP{
+			Method:    "post",
+			URL:       srv.URL + "/search?q={{city}}",
+			Body:      `{"city":"{{city}}","days":{{days}}}`,
+			APIKeyEnv: "CUSTOM_TOKEN",
+		},
+		Output: config.CustomOutput{JSONPath: "results.0.temp"},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := tool.Execute(context.Background(), map[string]any{"city": `Bern "old" & town`, "days": float64(2)})
+	if res.IsError || res.ForLLM != "12.5" {
+		t.Fatalf("result = %+v", res)
+	}
+	if gotQuery != `Bern "old" & town` || gotBody != `{"city":"Bern \"old\" & town","days":2}` || gotAuth != "Bearer secret-token" {
+		t.Errorf("query=%q body=%q auth=%q", gotQuery, gotBody, gotAuth)
+	}
+}
+
+func TestCustomToolValidate(t *testing.T) {
+	bad := []config.CustomToolConfig{
+		{Name: "Bad Name", Description: "x", Command: "true"},
+		{Name: "both", Description: "x", Command: "true", HTTP: &config.CustomHTTP{URL: "https://example.com"}},
+		{Name: "neither", Description: "x"},
+		{Name: "host", Description: "x", HTTP: &config.CustomHTTP{URL: "https://{{host}}/api"}},
+		{Name: "schema", Description: "x", Command: "true", Parameters: map[string]any{"type": "string"}},
+	}
+	for _, def := range bad {
+		if _, err := NewCustomTool(def, ""); err == nil {
+			t.Errorf("%s should be rejected", def.Name)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/errs"
+)
+
+const dockerAPIVersion = "v1.43"
+
+type DockerTool struct {
+	host      string // unix:///var/run/docker.sock or tcp://host:2376
+	client    *http.Client
+	baseURL   string
+	allowlist []string
+}
+
+// NewDockerTool creates a Docker tool talking to the Engine API at host.
+// When certPath is set, the connection uses mutual TLS with ca.pem,
+// cert.pem and key.pem from that directory (the DOCKER_CERT_PATH layout).
+func NewDockerTool(host, certPath string, restartAllowlist []string) (*DockerTool, error) {
+	t := &DockerTool{host: host, allowlist: restartAllowlist}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		t.client = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+			},
+		}
+		t.baseURL = "http://docker"
+	case "tcp", "https", "http":
+		transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+		scheme := "http"
+		if certPath != "" {
+			tlsCfg, err := loadDockerTLS(certPath)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = tlsCfg
+			scheme = "https"
+		} else if u.Scheme == "https" {
+			scheme = "https"
+		}
+		t.client = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+		t.baseURL = scheme + "://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q (use unix:// or tcp://)", u.Scheme)
+	}
+
+	return t, nil
+}
+
+func loadDockerTLS(certPath string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certPath+"/cert.pem", certPath+"/key.pem")
+	if err != nil {
+		return nil, fmt.Errorf("load docker client cert: %w", err)
+	}
+	caData, err := os.ReadFile(certPath + "/ca.pem")
+	if err != nil {
+		return nil, fmt.Errorf("read docker CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in %s/ca.pem", certPath)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func (t *DockerTool) Name() string {
+	return "docker"
+}
+
+func (t *DockerTool) Description() string {
+	return "Manage Docker containers on the host. Actions: list (containers and status), logs (tail of a container's logs), restart (only containers in the configured allowlist), check_updates (compare local images against the registry)."
+}
+
+func (t *DockerTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"description": "The action to perform",
+				"enum":        []string{"list", "logs", "restart", "check_updates"},
+			},
+			"container": map[string]any{
+				"type":        "string",
+				"description": "Container name or ID (for logs, restart)",
+			},
+			"all": map[string]any{
+				"type":        "boolean",
+				"description": "For list: include stopped containers",
+			},
+			"lines": map[string]any{
+				"type":        "integer",
+				"description": "For logs: number of lines from the end (default 50, max 500)",
+				"minimum":     1.0,
+				"maximum":     500.0,
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *DockerTool) DeclaredDomains() []string {
+	u, err := url.Parse(t.host)
+	if err != nil || u.Scheme == "unix" || u.Host == "" {
+		return nil
+	}
+	return []string{u.Host}
+}
+
+func (t *DockerTool) AuditAction(args map[string]any) (string, string) {
+	if action, _ := args["action"].(string); action == "restart" {
+		container, _ := args["container"].(string)
+		return "docker.restart", container
+	}
+	return "", ""
+}
+
+func (t *DockerTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "list":
+		all, _ := args["all"].(bool)
+		return t.list(ctx, all)
+	case "logs":
+		return t.logs(ctx, args)
+	case "restart":
+		return t.restart(ctx, args)
+	case "check_updates":
+		return t.checkUpdates(ctx)
+	case "":
+		return ErrorResult("action is required")
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+type dockerContainer struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Image   string   `json:"Image"`
+	ImageID string   `json:"ImageID"`
+	State   string   `json:"State"`
+	Status  string   `json:"Status"`
+}
+
+func (c dockerContainer) name() string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	return c.ID[:min(12, len(c.ID))]
+}
+
+func (t *DockerTool) do(ctx context.Context, method, path string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+"/"+dockerAPIVersion+path, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("docker API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		kind := errs.FromStatus(resp.StatusCode)
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return nil, resp.StatusCode, errs.Wrap(kind, fmt.Errorf("docker API: %s", apiErr.Message))
+		}
+		return nil, resp.StatusCode, errs.Wrap(kind, fmt.Errorf("docker API returned status %d", resp.StatusCode))
+	}
+	return body, resp.StatusCode, nil
+}
+
+func (t *DockerTool) listContainers(ctx context.Context, all bool) ([]dockerContainer, error) {
+	path := "/containers/json"
+	if all {
+		path += "?all=true"
+	}
+	body, _, err := t.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return nil, err
+	}
+	var containers []dockerContainer
+	if err := json.Unmarshal(body, &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse containers: %w", err)
+	}
+	return containers, nil
+}
+
+func (t *DockerTool) list(ctx context.Context, all bool) *ToolResult {
+	containers, err := t.listContainers(ctx, all)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	if len(containers) == 0 {
+		return SilentResult("No containers found.")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d container(s):\n\n", len(containers))
+	for _, c := range containers {
+		restartable := ""
+		if t.isAllowed(c.name()) {
+			restartable = " [restartable]"
+		}
+		fmt.Fprintf(&b, "- %s (%s): %s — %s%s\n", c.name(), c.Image, c.State, c.Status, restartable)
+	}
+	return SilentResult(b.String())
+}
+
+func (t *DockerTool) logs(ctx context.Context, args map[string]any) *ToolResult {
+	container, _ := args["container"].(string)
+	if container == "" {
+		return ErrorResult("container is required for logs")
+	}
+	lines := 50
+	if l, ok := args["lines"].(float64); ok && int(l) > 0 {
+		lines = min(int(l), 500)
+	}
+
+	path := fmt.Sprintf("/containers/%s/logs?stdout=true&stderr=true&timestamps=true&tail=%d", url.PathEscape(container), lines)
+	body, _, err := t.do(ctx, http.MethodGet, path)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+
+	out := demuxDockerLogs(body)
+	if strings.TrimSpace(out) == "" {
+		out = "(no log output)"
+	}
+	if len(out) > 10000 {
+		out = "... (truncated)\n" + out[len(out)-10000:]
+	}
+	return SilentResult(fmt.Sprintf("Last %d log lines of %s:\n\n%s", lines, container, out))
+}
+
+// demuxDockerLogs strips the 8-byte stream headers Docker prepends to each
+// frame for non-TTY containers. TTY output is returned unchanged.
+func demuxDockerLogs(data []byte) string {
+	if len(data) < 8 || data[0] > 2 || data[1] != 0 || data[2] != 0 || data[3] != 0 {
+		return string(data)
+	}
+	var out bytes.Buffer
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			size = len(data)
+		}
+		out.Write(data[:size])
+		data = data[size:]
+	}
+	return out.String()
+}
+
+func (t *DockerTool) isAllowed(name string) bool {
+	return slices.Contains(t.allowlist, name)
+}
+
+func (t *DockerTool) restart(ctx context.Context, args map[string]any) *ToolResult {
+	container, _ := args["container"].(string)
+	if container == "" {
+		return ErrorResult("container is required for restart")
+	}
+
+	containers, err := t.listContainers(ctx, true)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	var target *dockerContainer
+	for i, c := range containers {
+		if c.name() == container || strings.HasPrefix(c.ID, container) {
+			target = &containers[i]
+			break
+		}
+	}
+	if target == nil {
+		return ErrorResult(fmt.Sprintf("container %q not found", container))
+	}
+	if !t.isAllowed(target.name()) {
+		return ErrorResult(fmt.Sprintf("container %q is not in the restart allowlist (allowed: %s)", target.name(), strings.Join(t.allowlist, ", ")))
+	}
+
+	if _, _, err := t.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(target.ID)+"/restart?t=10"); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to restart %s: %v", target.name(), err))
+	}
+
+	msg := fmt.Sprintf("Restarted container %s", target.name())
+	return &ToolResult{ForLLM: msg, ForUser: msg}
+}
+
+func (t *DockerTool) checkUpdates(ctx context.Context) *ToolResult {
+	containers, err := t.listContainers(ctx, false)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+
+	var b strings.Builder
+	seen := make(map[string]bool)
+	updates := 0
+	for _, c := range containers {
+		if seen[c.Image] || strings.HasPrefix(c.Image, "sha256:") {
+			continue
+		}
+		seen[c.Image] = true
+
+		local, err := t.localDigests(ctx, c.ImageID)
+		if err != nil {
+			fmt.Fprintf(&b, "- %s: could not inspect local image (%v)\n", c.Image, err)
+			continue
+		}
+		remote, err := t.remoteDigest(ctx, c.Image)
+		if err != nil {
+			fmt.Fprintf(&b, "- %s: could not query registry (%v)\n", c.Image, err)
+			continue
+		}
+
+		upToDate := false
+		for _, d := range local {
+			if strings.HasSuffix(d, "@"+remote) {
+				upToDate = true
+				break
+			}
+		}
+		if upToDate {
+			fmt.Fprintf(&b, "- %s: up to date\n", c.Image)
+		} else {
+			updates++
+			fmt.Fprintf(&b, "- %s: UPDATE AVAILABLE (used by %s)\n", c.Image, c.name())
+		}
+	}
+
+	if len(seen) == 0 {
+		return SilentResult("No running containers to check.")
+	}
+	return SilentResult(fmt.Sprintf("Checked %d image(s), %d update(s) available:\n\n%s", len(seen), updates, b.String()))
+}
+
+func (t *DockerTool) localDigests(ctx context.Context, imageID string) ([]string, error) {
+	body, _, err := t.do(ctx, http.MethodGet, "/images/"+url.PathEscape(imageID)+"/json")
+	if err != nil {
+		return nil, err
+	}
+	var img struct {
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	if err := json.Unmarshal(body, &img); err != nil {
+		return nil, fmt.Errorf("failed to parse image: %w", err)
+	}
+	return img.RepoDigests, nil
+}
+
+// remoteDigest asks the daemon to resolve the image's manifest digest from
+// its registry, so registry credentials configured in the daemon are reused.
+func (t *DockerTool) remoteDigest(ctx context.Context, image string) (string, error) {
+	body, _, err := t.do(ctx, http.MethodGet, "/distribution/"+image+"/json")
+	if err != nil {
+		return "", err
+	}
+	var dist struct {
+		Descriptor struct {
+			Digest string `json:"digest"`
+		} `json:"Descriptor"`
+	}
+	if err := json.Unmarshal(body, &dist); err != nil {
+		return "", fmt.Errorf("failed to parse distribution info: %w", err)
+	}
+	if dist.Descriptor.Digest == "" {
+		return "", fmt.Errorf("registry returned no digest")
+	}
+	return dist.Descriptor.Digest, nil
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func dockerFrame(stream byte, payload string) []byte {
+	hdr := make([]byte, 8)
+	hdr[0] = stream
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func TestDemuxDockerLogs(t *testing.T) {
+	data := append(dockerFrame(1, "out line\n"), dockerFrame(2, "err line\n")...)
+	got := demuxDockerLogs(data)
+	if got != "out line\nerr line\n" {
+		t.Errorf("unexpected demux output: %q", got)
+	}
+
+	// TTY containers have no frame headers
+	if got := demuxDockerLogs([]byte("plain output\n")); got != "plain output\n" {
+		t.Errorf("expected raw passthrough, got %q", got)
+	}
+}
+
+func newTestDockerTool(t *testing.T, allowlist []string) (*DockerTool, *[]string) {
+	t.Helper()
+	var restarted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[{"Id":"abc123","Names":["/jellyfin"],"Image":"jellyfin/jellyfin","State":"running","Status":"Up 2 days"},
+				{"Id":"def456","Names":["/postgres"],"Image":"postgres:16","State":"running","Status":"Up 5 days"}]`))
+		case strings.HasSuffix(r.URL.Path, "/restart") && r.Method == http.MethodPost:
+			restarted = append(restarted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	tool, err := NewDockerTool("tcp://"+strings.TrimPrefix(srv.URL, "http://"), "", allowlist)
+	if err != nil {
+		t.Fatalf("NewDockerTool: %v", err)
+	}
+	return tool, &restarted
+}
+
+func TestDockerTool_RestartAllowlist(t *testing.T) {
+	tool, restarted := newTestDockerTool(t, []string{"jellyfin"})
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]any{"action": "restart", "container": "postgres"})
+	if !result.IsError {
+		t.Fatal("expected restart of non-allowlisted container to fail")
+	}
+	if !strings.Contains(result.ForLLM, "allowlist") {
+		t.Errorf("expected allowlist error, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]any{"action": "restart", "container": "jellyfin"})
+	if result.IsError {
+		t.Fatalf("expected restart to succeed, got: %s", result.ForLLM)
+	}
+	if len(*restarted) != 1 || !strings.Contains((*restarted)[0], "abc123") {
+		t.Errorf("expected one restart of abc123, got %v", *restarted)
+	}
+}
+
+func TestDockerTool_List(t *testing.T) {
+	tool, _ := newTestDockerTool(t, []string{"jellyfin"})
+
+	result := tool.Execute(context.Background(), map[string]any{"action": "list"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "jellyfin (jellyfin/jellyfin): running") {
+		t.Errorf("expected jellyfin in listing, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "[restartable]") {
+		t.Errorf("expected allowlisted container to be marked restartable, got: %s", result.ForLLM)
+	}
+}
+
+func TestNewDockerTool_InvalidScheme(t *testing.T) {
+	if _, err := NewDockerTool("ftp://host", "", nil); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
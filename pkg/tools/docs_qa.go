@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/docindex"
+	"localagent/pkg/when"
+)
+
+const (
+	docsQADefaultLimit = 5
+	docsQAMaxLimit     = 20
+	// docsQAListStale is how many changed files are named in a status.
+	docsQAListStale = 10
+)
+
+// DocsQATool answers questions from indexed workspace documents by
+// returning the most relevant excerpts with their sources.
+type DocsQATool struct {
+	index *docindex.Index
+}
+
+func NewDocsQATool(index *docindex.Index) *DocsQATool {
+	return &DocsQATool{index: index}
+}
+
+func (t *DocsQATool) Name() string {
+	return "docs_qa"
+}
+
+func (t *DocsQATool) Description() string {
+	return fmt.Sprintf("Search the user's indexed documents (%s) for passages relevant to a question. Returns excerpts with their file and line numbers; answer from them and cite the sources. action=refresh re-indexes changed files, action=status shows what changed since the last refresh.",
+		strings.Join(t.index.Folders(), ", "))
+}
+
+func (t *DocsQATool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"ask", "refresh", "status"},
+				"description": "ask (default), refresh or status",
+			},
+			"question": map[string]any{
+				"type":        "string",
+				"description": "The question, for ask",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of excerpts (default %d, max %d)", docsQADefaultLimit, docsQAMaxLimit),
+			},
+		},
+	}
+}
+
+// SideEffect reports that DocsQATool calls only read.
+func (t *DocsQATool) SideEffect(map[string]any) bool { return false }
+
+func (t *DocsQATool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "", "ask":
+		return t.ask(ctx, args)
+	case "refresh":
+		stats, err := t.index.Refresh(ctx)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("refresh failed after %d files: %v", stats.Indexed, err)).WithError(err)
+		}
+		return SilentResult(fmt.Sprintf("Index refreshed: %d files embedded (%d chunks), %d unchanged, %d removed.",
+			stats.Indexed, stats.Chunks, stats.Unchanged, stats.Removed))
+	case "status":
+		st, err := t.index.Status()
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("status failed: %v", err)).WithError(err)
+		}
+		return SilentResult(FormatDocsStatus(st))
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action %q (want ask, refresh or status)", action))
+	}
+}
+
+func (t *DocsQATool) ask(ctx context.Context, args map[string]any) *ToolResult {
+	question, _ := args["question"].(string)
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return ErrorResult("question is required")
+	}
+	limit := docsQADefaultLimit
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = min(int(n), docsQAMaxLimit)
+	}
+
+	hits, err := t.index.Search(ctx, question, limit)
+	if errors.Is(err, docindex.ErrNotIndexed) {
+		return ErrorResult("no documents are indexed yet; run docs_qa with action=refresh first")
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("search failed: %v", err)).WithError(err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Excerpts for %q:\n", question)
+	for i, h := range hits {
+		fmt.Fprintf(&sb, "\n[%d] %s (score %.2f)\n%s\n", i+1, h.Cite(), h.Score, h.Text)
+	}
+	sb.WriteString("\nAnswer from these excerpts and cite them as [n] with the file; say so if they don't cover the question.")
+	if st, err := t.index.Status(); err == nil && st.Stale() {
+		sb.WriteString("\n\nThe index is out of date, so results may be too. " + FormatDocsStatus(st))
+	}
+	return SilentResult(sb.String())
+}
+
+// FormatDocsStatus describes the index and what is out of date, for the
+// tool and `localagent docs status`.
+func FormatDocsStatus(st docindex.Status) string {
+	if st.Updated.IsZero() {
+		return fmt.Sprintf("Not fully indexed yet: %d files indexed, %d to go; refresh to finish.", st.Files, len(st.Added)+len(st.Changed))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d files, %d chunks, refreshed on %s.", st.Files, st.Chunks, st.Updated.In(when.Location()).Format("Mon Jan 2 15:04"))
+	if !st.Stale() {
+		sb.WriteString(" Up to date.")
+	} else {
+		sb.WriteString(" Refresh to update.")
+	}
+	list := func(label string, names []string) {
+		if len(names) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n%s (%d): %s", label, len(names), strings.Join(names[:min(len(names), docsQAListStale)], ", "))
+		if len(names) > docsQAListStale {
+			sb.WriteString(", ...")
+		}
+	}
+	list("Changed", st.Changed)
+	list("New", st.Added)
+	list("Removed", st.Removed)
+	return sb.String()
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"localagent/pkg/vault"
 )
 
 // EditFileTool edits a file by replacing old_text with new_text.
@@ -47,6 +49,21 @@ func (t *EditFileTool) Parameters() map[string]any {
 	}
 }
 
+func (t *EditFileTool) RepairHints() []RepairHint {
+	return []RepairHint{
+		HintOn("call read_file on this path first and copy old_text verbatim, including whitespace and indentation.",
+			"old_text not found"),
+		HintOn("include the surrounding lines in old_text so it matches exactly one place.",
+			"appears"),
+		HintOn("call list_dir to find the correct path.", "file not found"),
+	}
+}
+
+func (t *EditFileTool) AuditAction(args map[string]any) (string, string) {
+	path, _ := args["path"].(string)
+	return "file_edit", path
+}
+
 func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
@@ -72,7 +89,7 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult(fmt.Sprintf("file not found: %s", path))
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := vault.ReadFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -90,11 +107,11 @@ func (t *EditFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 
 	newContent := strings.Replace(contentStr, oldText, newText, 1)
 
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
+	if err := vault.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
-	return SilentResult(fmt.Sprintf("File edited: %s", path))
+	return SilentResult(fmt.Sprintf("File edited: %s", path)).WithArtifacts(path)
 }
 
 type AppendFileTool struct {
@@ -130,6 +147,11 @@ func (t *AppendFileTool) Parameters() map[string]any {
 	}
 }
 
+func (t *AppendFileTool) AuditAction(args map[string]any) (string, string) {
+	path, _ := args["path"].(string)
+	return "file_append", path
+}
+
 func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
@@ -150,15 +172,9 @@ func (t *AppendFileTool) Execute(ctx context.Context, args map[string]any) *Tool
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	f, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to open file: %v", err))
-	}
-	defer f.Close()
-
-	if _, err := f.WriteString(content); err != nil {
+	if err := vault.AppendFile(resolvedPath, []byte(content), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to append to file: %v", err))
 	}
 
-	return SilentResult(fmt.Sprintf("Appended to %s", path))
+	return SilentResult(fmt.Sprintf("Appended to %s", path)).WithArtifacts(path)
 }
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/email"
+)
+
+// EmailTriageTool sorts the user's unread email into action needed, FYI
+// and newsletters. The mailbox is only read: nothing is marked read,
+// moved or sent.
+type EmailTriageTool struct {
+	triager *email.Triager
+}
+
+func NewEmailTriageTool(triager *email.Triager) *EmailTriageTool {
+	return &EmailTriageTool{triager: triager}
+}
+
+func (t *EmailTriageTool) Name() string {
+	return "email_triage"
+}
+
+func (t *EmailTriageTool) Description() string {
+	return "Triage the user's unread email (read-only: nothing is marked read or sent). " +
+		"Actions: triage (classify recent unread mail as action needed, FYI or newsletter and summarize it), " +
+		"set_rule (always file mail from a sender address or @domain under a category), remove_rule, list_rules. " +
+		"Use set_rule when the user corrects a classification."
+}
+
+func (t *EmailTriageTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"triage", "set_rule", "remove_rule", "list_rules"},
+				"description": "Action to perform (default triage).",
+			},
+			"sender": map[string]any{
+				"type":        "string",
+				"description": "Sender address or @domain (for set_rule, remove_rule).",
+			},
+			"category": map[string]any{
+				"type":        "string",
+				"enum":        []string{string(email.ActionNeeded), string(email.FYI), string(email.Newsletter)},
+				"description": "Category for the sender's mail (for set_rule).",
+			},
+		},
+	}
+}
+
+// SideEffect reports whether the call changes anything; triage and list_rules only read.
+func (t *EmailTriageTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "", "triage", "list_rules")
+}
+
+func (t *EmailTriageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	sender, _ := args["sender"].(string)
+	category, _ := args["category"].(string)
+
+	switch action {
+	case "", "triage":
+		s, err := t.triager.Triage(ctx)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("email triage failed: %v", err))
+		}
+		return SilentResult(s.Format())
+	case "set_rule":
+		c, ok := email.ParseCategory(category)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown category %q: use action, fyi or newsletter", category))
+		}
+		key, err := t.triager.SetRule(sender, c)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Mail from %s is now filed under %s.", key, c))
+	case "remove_rule":
+		if err := t.triager.RemoveRule(sender); err != nil {
+			return ErrorResult(fmt.Sprintf("%v: %s", err, sender))
+		}
+		return SilentResult(fmt.Sprintf("Removed the rule for %s.", sender))
+	case "list_rules":
+		rules := t.triager.RuleList()
+		if len(rules) == 0 {
+			return SilentResult("No sender rules; mail is classified by its headers and content.")
+		}
+		return SilentResult("Sender rules:\n" + strings.Join(rules, "\n"))
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"localagent/pkg/vault"
 )
 
 // validatePath resolves the given path. Relative paths are resolved against workspace.
@@ -60,6 +62,16 @@ func (t *ReadFileTool) Parameters() map[string]any {
 	}
 }
 
+func (t *ReadFileTool) RepairHints() []RepairHint {
+	return []RepairHint{
+		HintOn("call list_dir on the parent directory to find the correct path.", "no such file"),
+		HintOn("this path is a directory; use list_dir instead.", "is a directory"),
+	}
+}
+
+// SideEffect reports that ReadFileTool calls only read.
+func (t *ReadFileTool) SideEffect(map[string]any) bool { return false }
+
 func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
@@ -71,7 +83,7 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	content, err := vault.ReadFile(resolvedPath)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
@@ -112,6 +124,11 @@ func (t *WriteFileTool) Parameters() map[string]any {
 	}
 }
 
+func (t *WriteFileTool) AuditAction(args map[string]any) (string, string) {
+	path, _ := args["path"].(string)
+	return "file_write", path
+}
+
 func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
@@ -133,11 +150,11 @@ func (t *WriteFileTool) Execute(ctx context.Context, args map[string]any) *ToolR
 		return ErrorResult(fmt.Sprintf("failed to create directory: %v", err))
 	}
 
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	if err := vault.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
 		return ErrorResult(fmt.Sprintf("failed to write file: %v", err))
 	}
 
-	return SilentResult(fmt.Sprintf("File written: %s", path))
+	return SilentResult(fmt.Sprintf("File written: %s", path)).WithArtifacts(path)
 }
 
 type ListDirTool struct {
@@ -169,6 +186,15 @@ func (t *ListDirTool) Parameters() map[string]any {
 	}
 }
 
+func (t *ListDirTool) RepairHints() []RepairHint {
+	return []RepairHint{
+		HintOn("paths are relative to the workspace; call list_dir with path \".\" to start from the root.", "no such file"),
+	}
+}
+
+// SideEffect reports that ListDirTool calls only read.
+func (t *ListDirTool) SideEffect(map[string]any) bool { return false }
+
 func (t *ListDirTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
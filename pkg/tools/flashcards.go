@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"localagent/pkg/flashcards"
+	"localagent/pkg/when"
+)
+
+// FlashcardsTool manages spaced-repetition cards and runs quizzes.
+type FlashcardsTool struct {
+	cards *flashcards.Service
+}
+
+func NewFlashcardsTool(svc *flashcards.Service) *FlashcardsTool {
+	return &FlashcardsTool{cards: svc}
+}
+
+func (t *FlashcardsTool) Name() string {
+	return "flashcards"
+}
+
+func (t *FlashcardsTool) Description() string {
+	return "Spaced-repetition flashcards (SM-2). Create cards when the user asks to remember something (e.g. a word and its translation). " +
+		"Actions: add, list, remove, quiz (next due card: ask the user its front only), answer (grade the user's recall; returns the correct answer and next review date), stats."
+}
+
+func (t *FlashcardsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"add", "list", "remove", "quiz", "answer", "stats"},
+				"description": "Action to perform.",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Card ID (for remove, answer).",
+			},
+			"front": map[string]any{
+				"type":        "string",
+				"description": "The question side, e.g. \"der Schmetterling\" (for add).",
+			},
+			"back": map[string]any{
+				"type":        "string",
+				"description": "The answer side, e.g. \"butterfly\" (for add).",
+			},
+			"deck": map[string]any{
+				"type":        "string",
+				"description": "Deck name like \"german\" (for add; filters list, quiz, stats).",
+			},
+			"grade": map[string]any{
+				"type":        "string",
+				"description": "How well the user recalled the card (for answer): again (wrong), hard, good, easy, or SM-2 quality 0-5.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; list, quiz and stats only read.
+func (t *FlashcardsTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list", "quiz", "stats")
+}
+
+func (t *FlashcardsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	id, _ := args["id"].(string)
+	deck, _ := args["deck"].(string)
+
+	switch action {
+	case "add":
+		front, _ := args["front"].(string)
+		back, _ := args["back"].(string)
+		c, err := t.cards.Add(deck, front, back)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to add card: %v", err))
+		}
+		return SilentResult(fmt.Sprintf("Card added: %s", formatCard(*c)))
+
+	case "list":
+		cards := t.cards.List(deck)
+		if len(cards) == 0 {
+			return SilentResult("No flashcards.")
+		}
+		lines := make([]string, len(cards))
+		for i, c := range cards {
+			lines[i] = formatCard(c)
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+
+	case "remove":
+		if !t.cards.Remove(id) {
+			return ErrorResult(fmt.Sprintf("card not found: %s", id))
+		}
+		return SilentResult(fmt.Sprintf("Card %s removed", id))
+
+	case "quiz":
+		due := t.cards.Due(deck)
+		if len(due) == 0 {
+			return SilentResult("No cards are due.")
+		}
+		c := due[0]
+		label := ""
+		if c.Deck != "" {
+			label = fmt.Sprintf(" (%s)", c.Deck)
+		}
+		return SilentResult(fmt.Sprintf("Card %s%s, %d due in total.\nFront: %s\nAsk the user for the answer; don't reveal it before they reply.",
+			c.ID, label, len(due), c.Front))
+
+	case "answer":
+		var grade string
+		switch v := args["grade"].(type) {
+		case string:
+			grade = v
+		case float64:
+			grade = fmt.Sprintf("%d", int(v))
+		}
+		q, err := flashcards.ParseGrade(grade)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		c, err := t.cards.Answer(id, q)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		next := time.UnixMilli(c.DueAtMS).In(when.Location()).Format("Mon 2006-01-02")
+		remaining := len(t.cards.Due(deck))
+		return SilentResult(fmt.Sprintf("Answer: %s\nNext review: %s (in %d days). %d cards still due.", c.Back, next, c.IntervalDays, remaining))
+
+	case "stats":
+		return SilentResult(flashcardStats(t.cards.List(deck), when.Now()))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func formatCard(c flashcards.Card) string {
+	deck := ""
+	if c.Deck != "" {
+		deck = fmt.Sprintf("[%s] ", c.Deck)
+	}
+	due := time.UnixMilli(c.DueAtMS).In(when.Location()).Format("2006-01-02")
+	return fmt.Sprintf("%s: %s%s → %s (due %s, reps %d)", c.ID, deck, c.Front, c.Back, due, c.Reps)
+}
+
+// flashcardStats summarizes card counts per deck.
+func flashcardStats(cards []flashcards.Card, now time.Time) string {
+	if len(cards) == 0 {
+		return "No flashcards."
+	}
+	type counts struct{ total, due, learned, lapses int }
+	decks := make(map[string]*counts)
+	for _, c := range cards {
+		name := c.Deck
+		if name == "" {
+			name = "(no deck)"
+		}
+		d := decks[name]
+		if d == nil {
+			d = &counts{}
+			decks[name] = d
+		}
+		d.total++
+		d.lapses += c.Lapses
+		if c.Due(now) {
+			d.due++
+		}
+		if c.IntervalDays >= 21 {
+			d.learned++
+		}
+	}
+	names := make([]string, 0, len(decks))
+	for name := range decks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		d := decks[name]
+		lines[i] = fmt.Sprintf("%s: %d cards, %d due, %d learned (interval ≥ 21 days), %d lapses", name, d.total, d.due, d.learned, d.lapses)
+	}
+	return strings.Join(lines, "\n")
+}
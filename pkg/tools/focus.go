@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"localagent/pkg/dnd"
+)
+
+// FocusTool turns focus mode on and off: a do-not-disturb window during
+// which proactive messages are held and delivered as a digest afterwards.
+type FocusTool struct {
+	focus *dnd.Focus
+}
+
+func NewFocusTool(focus *dnd.Focus) *FocusTool {
+	return &FocusTool{focus: focus}
+}
+
+func (t *FocusTool) Name() string {
+	return "focus"
+}
+
+func (t *FocusTool) Description() string {
+	return "Focus mode: hold non-urgent proactive messages (heartbeats, reminders, job announcements) for a while and deliver a digest when it ends. " +
+		"Use when the user wants to concentrate or not be disturbed. Urgent events still come through. " +
+		"Actions: start (minutes, optional reason), end, status."
+}
+
+func (t *FocusTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"start", "end", "status"},
+				"description": "Action to perform.",
+			},
+			"minutes": map[string]any{
+				"type":        "integer",
+				"description": "How long to focus (for start).",
+			},
+			"reason": map[string]any{
+				"type":        "string",
+				"description": "What the user is focusing on (for start, optional).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; status only read.
+func (t *FocusTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "status")
+}
+
+func (t *FocusTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "start":
+		minutes, _ := args["minutes"].(float64)
+		reason, _ := args["reason"].(string)
+		st, err := t.focus.Start(time.Duration(minutes)*time.Minute, reason)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Focus on until %s. Non-urgent messages are held until then.", st.Until.Format("15:04")))
+
+	case "end":
+		was, err := t.focus.End()
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to save: %v", err))
+		}
+		if !was {
+			return SilentResult("Focus was not on.")
+		}
+		return SilentResult("Focus ended. Held messages are being delivered.")
+
+	case "status":
+		st := t.focus.Status()
+		if !st.Active {
+			return SilentResult("Focus is off.")
+		}
+		msg := fmt.Sprintf("Focus on since %s until %s", st.Started.Format("15:04"), st.Until.Format("15:04"))
+		if st.Reason != "" {
+			msg += " (" + st.Reason + ")"
+		}
+		return SilentResult(msg + ".")
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
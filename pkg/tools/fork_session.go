@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"localagent/pkg/providers"
+	"localagent/pkg/roles"
+	"localagent/pkg/session"
+	"localagent/pkg/utils"
+)
+
+// sessionNameRe matches characters not allowed in a fork's name.
+var sessionNameRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ForkSessionTool copies the current conversation, optionally without its
+// latest turns, into a new session so an alternative direction can be
+// explored without touching the original thread.
+type ForkSessionTool struct {
+	sessions *session.SessionManager
+}
+
+func NewForkSessionTool(sessions *session.SessionManager) *ForkSessionTool {
+	return &ForkSessionTool{sessions: sessions}
+}
+
+func (t *ForkSessionTool) Name() string {
+	return "fork_session"
+}
+
+func (t *ForkSessionTool) Description() string {
+	return "Fork this conversation into a new session to explore an alternative (e.g. \"what if we planned the trip for June instead\") while keeping the original thread. Copies the history, optionally leaving out the most recent user turns."
+}
+
+func (t *ForkSessionTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Short name for the new session, e.g. \"trip-june\". Defaults to a generated name.",
+			},
+			"turns_back": map[string]any{
+				"type":        "integer",
+				"description": "Number of most recent user messages (and the replies to them) to leave out of the fork. 0 copies the whole conversation.",
+			},
+		},
+	}
+}
+
+func (t *ForkSessionTool) AuditAction(args map[string]any) (string, string) {
+	name, _ := args["name"].(string)
+	return "fork session", name
+}
+
+func (t *ForkSessionTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	src := SessionKeyFromContext(ctx)
+	if src == "" {
+		return ErrorResult("no active session")
+	}
+
+	turnsBack := 0
+	if v, ok := args["turns_back"].(float64); ok {
+		turnsBack = int(v)
+	}
+	if turnsBack < 0 {
+		return ErrorResult("turns_back must be 0 or more")
+	}
+	n := forkPoint(t.sessions.GetHistory(src), turnsBack)
+	if n < 0 {
+		return ErrorResult(fmt.Sprintf("the conversation has fewer than %d user messages", turnsBack))
+	}
+
+	// A family member's fork stays in their namespace.
+	base := roles.BaseSessionKey(src)
+	prefix, id, ok := strings.Cut(base, ":")
+	if !ok {
+		prefix, id = "fork", base
+	}
+	name, _ := args["name"].(string)
+	name = strings.Trim(sessionNameRe.ReplaceAllString(strings.TrimSpace(name), "-"), "-")
+	if name == "" {
+		name = id + "-fork-" + utils.RandHex(2)
+	}
+	dst := roles.SessionKey(prefix+":"+name, roles.NamespaceFromSessionKey(src))
+
+	copied, err := t.sessions.Fork(src, dst, n)
+	if err != nil {
+		return ErrorResult(err.Error()).WithError(err)
+	}
+	return NewToolResult(fmt.Sprintf("Forked this conversation into session %s (%d messages copied). The current conversation is unchanged; the fork can be opened from the session list.", dst, copied))
+}
+
+// forkPoint returns how many messages to keep so the last turnsBack user
+// messages and everything after them are left out, or -1 when there are
+// fewer user messages than that.
+func forkPoint(history []providers.Message, turnsBack int) int {
+	if turnsBack == 0 {
+		return len(history)
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			turnsBack--
+			if turnsBack == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
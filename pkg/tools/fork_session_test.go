@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"localagent/pkg/providers"
+	"localagent/pkg/session"
+)
+
+func TestForkSessionTool(t *testing.T) {
+	dir := t.TempDir()
+	sm := session.NewSessionManager(dir)
+	src := "openai:trip"
+	sm.AddMessage(src, "user", "plan a trip to Lisbon")
+	sm.AddMessage(src, "assistant", "How about May?")
+	sm.AddMessage(src, "user", "book May 3-10")
+	sm.AddFullMessage(src, providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "1", Name: "travel"}}})
+	sm.AddFullMessage(src, providers.Message{Role: "tool", ToolCallID: "1", Content: "booked"})
+	sm.SetVar(src, "destination", "Lisbon")
+
+	tool := NewForkSessionTool(sm)
+	ctx := WithSessionKey(context.Background(), src)
+
+	r := tool.Execute(ctx, map[string]any{"name": "trip june", "turns_back": float64(1)})
+	if r.IsError || !strings.Contains(r.ForLLM, "openai:trip-june") {
+		t.Fatalf("fork = %+v", r)
+	}
+	fork := session.NewSessionManager(dir).GetHistory("openai:trip-june")
+	if len(fork) != 2 || fork[1].Content != "How about May?" {
+		t.Errorf("fork history = %+v", fork)
+	}
+	if sm.GetVars("openai:trip-june")["destination"] != "Lisbon" || len(sm.GetHistory(src)) != 5 {
+		t.Error("fork should copy variables and leave the source untouched")
+	}
+
+	// Forking everything drops the unfinished tool exchange at the end.
+	r = tool.Execute(ctx, map[string]any{})
+	if r.IsError {
+		t.Fatal(r.ForLLM)
+	}
+	if !strings.Contains(r.ForLLM, "3 messages copied") {
+		t.Errorf("whole fork = %q", r.ForLLM)
+	}
+
+	if r := tool.Execute(ctx, map[string]any{"name": "trip-june"}); !r.IsError {
+		t.Error("forking onto an existing session should fail")
+	}
+	if r := tool.Execute(ctx, map[string]any{"turns_back": float64(5)}); !r.IsError {
+		t.Error("turns_back beyond the history should fail")
+	}
+}
+
+func TestForkSessionToolKeepsNamespace(t *testing.T) {
+	sm := session.NewSessionManager(t.TempDir())
+	src := "telegram:123#family-456-alice"
+	sm.AddMessage(src, "user", "plan a trip")
+	sm.AddMessage(src, "assistant", "Where to?")
+
+	tool := NewForkSessionTool(sm)
+	ctx := WithSessionKey(context.Background(), src)
+
+	r := tool.Execute(ctx, map[string]any{"name": "trip"})
+	if r.IsError || !strings.Contains(r.ForLLM, "telegram:trip#family-456-alice") {
+		t.Fatalf("named fork = %+v", r)
+	}
+	if len(sm.GetHistory("telegram:trip#family-456-alice")) != 2 || len(sm.GetHistory("telegram:trip")) != 0 {
+		t.Error("named fork should stay in the member's namespace")
+	}
+
+	r = tool.Execute(ctx, map[string]any{})
+	if r.IsError || !strings.Contains(r.ForLLM, "telegram:123-fork-") || !strings.Contains(r.ForLLM, "#family-456-alice ") {
+		t.Errorf("generated fork = %+v", r)
+	}
+}
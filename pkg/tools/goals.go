@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/cron"
+	"localagent/pkg/goals"
+	"localagent/pkg/journal"
+	"localagent/pkg/prompts"
+	"localagent/pkg/when"
+
+	"github.com/adhocore/gronx"
+)
+
+// goalReviewJob names the cron job that runs goal reviews.
+const goalReviewJob = "Goal review"
+
+// defaultReviewSchedule is Sunday at 18:00.
+const defaultReviewSchedule = "0 18 * * 0"
+
+// GoalsTool manages goals and schedules periodic reviews through cron.
+type GoalsTool struct {
+	goals   *goals.Service
+	cron    *cron.CronService
+	journal *journal.Store
+	channel string
+	chatID  string
+}
+
+// NewGoalsTool creates the tool. cronService and journalStore may be nil;
+// reviews then can't be scheduled or don't look at the journal.
+func NewGoalsTool(svc *goals.Service, cronService *cron.CronService, journalStore *journal.Store) *GoalsTool {
+	return &GoalsTool{goals: svc, cron: cronService, journal: journalStore}
+}
+
+func (t *GoalsTool) Name() string {
+	return "goals"
+}
+
+func (t *GoalsTool) Description() string {
+	return "Track the user's goals (title, target date, key results, status). Tasks tagged with a goal's tag count toward it. " +
+		"Actions: add, list, update, progress (update one key result), remove, review (progress report with stalling goals flagged), " +
+		"schedule_review (run a review periodically and send it to this chat; schedule \"off\" stops it)."
+}
+
+func (t *GoalsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"add", "list", "update", "progress", "remove", "review", "schedule_review"},
+				"description": "Action to perform.",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Goal ID (for update, progress, remove).",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Goal title (for add, update).",
+			},
+			"description": map[string]any{
+				"type":        "string",
+				"description": "Why the goal matters or what done looks like (for add, update).",
+			},
+			"target_date": map[string]any{
+				"type":        "string",
+				"description": "Target date as YYYY-MM-DD or an expression like \"next month\" (for add, update).",
+			},
+			"key_results": map[string]any{
+				"type":        "array",
+				"description": "Measurable outcomes (for add, update; replaces the list). A number to reach uses target and unit.",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"title":  map[string]any{"type": "string"},
+						"target": map[string]any{"type": "number"},
+						"unit":   map[string]any{"type": "string"},
+					},
+					"required": []string{"title"},
+				},
+			},
+			"tag": map[string]any{
+				"type":        "string",
+				"description": "Task tag whose tasks count toward the goal (for add, update).",
+			},
+			"status": map[string]any{
+				"type":        "string",
+				"enum":        []string{goals.StatusActive, goals.StatusAchieved, goals.StatusAbandoned},
+				"description": "For update, or to filter list.",
+			},
+			"key_result": map[string]any{
+				"type":        "string",
+				"description": "Key result number (1-based) or title (for progress).",
+			},
+			"value": map[string]any{
+				"type":        "number",
+				"description": "Current value of the key result (for progress).",
+			},
+			"done": map[string]any{
+				"type":        "boolean",
+				"description": "Mark the key result done or not (for progress).",
+			},
+			"schedule": map[string]any{
+				"type":        "string",
+				"description": "Cron expression for schedule_review (default \"0 18 * * 0\", Sundays 18:00), or \"off\".",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GoalsTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// SideEffect reports whether the call changes anything; list and review only read.
+func (t *GoalsTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list", "review")
+}
+
+func (t *GoalsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	id, _ := args["id"].(string)
+
+	switch action {
+	case "add":
+		title, _ := args["title"].(string)
+		g := goals.Goal{Title: title}
+		g.Description, _ = args["description"].(string)
+		g.Tag, _ = args["tag"].(string)
+		if raw, _ := args["target_date"].(string); raw != "" {
+			date, err := resolveDate(raw)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			g.TargetDate = date
+		}
+		if v, ok := args["key_results"]; ok {
+			krs, err := goals.ParseKeyResults(v)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			g.KeyResults = krs
+		}
+		created, err := t.goals.Add(g)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to add goal: %v", err))
+		}
+		return SilentResult("Goal added:\n" + goals.FormatGoal(*created))
+
+	case "list":
+		status, _ := args["status"].(string)
+		list := t.goals.List(status)
+		if len(list) == 0 {
+			return SilentResult("No goals.")
+		}
+		lines := make([]string, len(list))
+		for i, g := range list {
+			lines[i] = goals.FormatGoal(g)
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+
+	case "update":
+		patch := make(map[string]any)
+		for _, k := range []string{"title", "description", "status", "tag", "key_results"} {
+			if v, ok := args[k]; ok {
+				patch[k] = v
+			}
+		}
+		if raw, ok := args["target_date"].(string); ok {
+			date := ""
+			if raw != "" {
+				var err error
+				if date, err = resolveDate(raw); err != nil {
+					return ErrorResult(err.Error())
+				}
+			}
+			patch["target_date"] = date
+		}
+		g, err := t.goals.Update(id, patch)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to update goal: %v", err))
+		}
+		return SilentResult("Goal updated:\n" + goals.FormatGoal(*g))
+
+	case "progress":
+		ref, _ := args["key_result"].(string)
+		var value *float64
+		if v, ok := args["value"].(float64); ok {
+			value = &v
+		}
+		var done *bool
+		if v, ok := args["done"].(bool); ok {
+			done = &v
+		}
+		if value == nil && done == nil {
+			return ErrorResult("value or done is required for progress")
+		}
+		g, err := t.goals.SetProgress(id, ref, value, done)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult("Progress recorded:\n" + goals.FormatGoal(*g))
+
+	case "remove":
+		if !t.goals.Remove(id) {
+			return ErrorResult(fmt.Sprintf("goal not found: %s", id))
+		}
+		return SilentResult(fmt.Sprintf("Goal %s removed", id))
+
+	case "review":
+		now := when.Now()
+		return SilentResult(goals.FormatReview(t.goals.Review(now, t.journal), now))
+
+	case "schedule_review":
+		schedule, _ := args["schedule"].(string)
+		return t.scheduleReview(strings.TrimSpace(schedule))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// scheduleReview replaces the goal review cron job. The job runs an
+// isolated agent turn and announces the report to the current chat.
+func (t *GoalsTool) scheduleReview(expr string) *ToolResult {
+	if t.cron == nil {
+		return ErrorResult("scheduling is not available (cron service not running)")
+	}
+	if expr == "" {
+		expr = defaultReviewSchedule
+	}
+	if expr != "off" && !gronx.New().IsValid(expr) {
+		return ErrorResult(fmt.Sprintf("invalid cron expression %q", expr))
+	}
+	removed := false
+	for _, job := range t.cron.ListJobs(true) {
+		if job.Name == goalReviewJob {
+			removed = t.cron.RemoveJob(job.ID) || removed
+		}
+	}
+	if expr == "off" {
+		if !removed {
+			return SilentResult("No goal review was scheduled.")
+		}
+		return SilentResult("Goal reviews stopped.")
+	}
+
+	job, err := t.cron.AddJob(cron.CronJob{
+		Name:          goalReviewJob,
+		Schedule:      cron.CronSchedule{Kind: "cron", Expr: expr, TZ: when.Location().String()},
+		Payload:       cron.CronPayload{Kind: "agentTurn", Message: strings.TrimSpace(prompts.GoalReview)},
+		Delivery:      &cron.CronDelivery{Mode: "announce", Channel: t.channel, To: t.chatID},
+		SessionTarget: "isolated",
+		WakeMode:      "now",
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to schedule review: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Goal review scheduled (%s, cron job %s)", expr, job.ID))
+}
+
+// resolveDate turns a date argument into YYYY-MM-DD.
+func resolveDate(raw string) (string, error) {
+	r, err := when.Resolve(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: %v", raw, err)
+	}
+	return r.Time.Format("2006-01-02"), nil
+}
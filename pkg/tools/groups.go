@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"localagent/pkg/providers"
+)
+
+// OpenToolsName is the gateway definition offered in place of closed tool
+// groups. The agent loop handles calls to it; it is not a registered tool.
+const OpenToolsName = "open_tools"
+
+// DefaultGroups assigns built-in tools to groups for the "groups" tool
+// definition mode. Tools not listed here (message, spawn, vars, ...) are
+// always offered in full.
+var DefaultGroups = map[string]string{
+	"read_file":         "files",
+	"write_file":        "files",
+	"edit_file":         "files",
+	"append_file":       "files",
+	"list_dir":          "files",
+	"pdf_to_text":       "files",
+	"transcribe_audio":  "files",
+	"calendar":          "calendar",
+	"cron":              "calendar",
+	"travel":            "calendar",
+	"query_tasks":       "tasks",
+	"add_task":          "tasks",
+	"modify_tasks":      "tasks",
+	"add_block":         "tasks",
+	"remove_block":      "tasks",
+	"goals":             "tasks",
+	"stock_price":       "finance",
+	"convert_currency":  "finance",
+	"get_user_location": "home",
+	"medications":       "home",
+	"sleep":             "home",
+	"recipes":           "home",
+	"meal_plan":         "home",
+	"journal":           "notes",
+	"flashcards":        "notes",
+	"add_link":          "notes",
+	"remove_link":       "notes",
+	"tech_news":         "news",
+	"ai_papers":         "news",
+	"exec":              "system",
+	"docker":            "system",
+	"net_check":         "system",
+}
+
+// Groups maps each grouped tool in the registry to its group.
+func (r *ToolRegistry) Groups() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make(map[string]string)
+	for name, tool := range r.tools {
+		group := DefaultGroups[name]
+		if g, ok := tool.(Grouped); ok {
+			group = g.Group()
+		}
+		if group != "" {
+			groups[name] = group
+		}
+	}
+	return groups
+}
+
+// CollapseGroups keeps the definitions of ungrouped tools and of tools in
+// open groups, and replaces the rest with a single open_tools definition
+// listing each closed group and its tools.
+func CollapseGroups(defs []providers.ToolDefinition, groups map[string]string, open map[string]bool) []providers.ToolDefinition {
+	out := make([]providers.ToolDefinition, 0, len(defs)+1)
+	closed := make(map[string][]string)
+	for _, d := range defs {
+		group := groups[d.Function.Name]
+		if group == "" || open[group] {
+			out = append(out, d)
+			continue
+		}
+		closed[group] = append(closed[group], d.Function.Name)
+	}
+	if len(closed) == 0 {
+		return out
+	}
+
+	names := make([]string, 0, len(closed))
+	for g := range closed {
+		names = append(names, g)
+	}
+	slices.Sort(names)
+	var desc strings.Builder
+	desc.WriteString("Load the tools of a group; their definitions are available on your next step. Groups:")
+	for _, g := range names {
+		slices.Sort(closed[g])
+		fmt.Fprintf(&desc, "\n- %s: %s", g, strings.Join(closed[g], ", "))
+	}
+	return append(out, providers.ToolDefinition{
+		Type: "function",
+		Function: providers.ToolFunctionDefinition{
+			Name:        OpenToolsName,
+			Description: desc.String(),
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"group": map[string]any{
+						"type":        "string",
+						"enum":        names,
+						"description": "Tool group to load",
+					},
+				},
+				"required": []string{"group"},
+			},
+		},
+	})
+}
+
+// GroupMembers returns the sorted names of the tools in group.
+func GroupMembers(groups map[string]string, group string) []string {
+	var names []string
+	for name, g := range groups {
+		if g == group {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"localagent/pkg/providers"
+)
+
+func TestCollapseGroups(t *testing.T) {
+	def := func(name string) providers.ToolDefinition {
+		return providers.ToolDefinition{Type: "function", Function: providers.ToolFunctionDefinition{Name: name}}
+	}
+	defs := []providers.ToolDefinition{def("message"), def("read_file"), def("list_dir"), def("stock_price")}
+	groups := map[string]string{"read_file": "files", "list_dir": "files", "stock_price": "finance"}
+
+	got := CollapseGroups(defs, groups, map[string]bool{"finance": true})
+	if len(got) != 3 || got[0].Function.Name != "message" || got[1].Function.Name != "stock_price" {
+		t.Fatalf("defs = %+v", got)
+	}
+	gw := got[2].Function
+	if gw.Name != OpenToolsName || !strings.Contains(gw.Description, "- files: list_dir, read_file") || strings.Contains(gw.Description, "finance") {
+		t.Errorf("gateway = %+v", gw)
+	}
+
+	got = CollapseGroups(defs, groups, map[string]bool{"finance": true, "files": true})
+	if len(got) != 4 {
+		t.Errorf("no gateway expected once every group is open, got %d defs", len(got))
+	}
+}
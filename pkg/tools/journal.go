@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/journal"
+	"localagent/pkg/when"
+)
+
+// maxJournalList is how many entry names list returns per kind.
+const maxJournalList = 30
+
+// JournalTool reads the daily journal and weekly rollups, and can write an
+// entry on demand.
+type JournalTool struct {
+	gen *journal.Generator
+}
+
+func NewJournalTool(gen *journal.Generator) *JournalTool {
+	return &JournalTool{gen: gen}
+}
+
+func (t *JournalTool) Name() string {
+	return "journal"
+}
+
+func (t *JournalTool) Description() string {
+	return "The user's journal: one entry per day (conversation highlights, completed tasks, calendar events, notable actions) and a weekly rollup. " +
+		"Actions: read (a day's entry), week (the rollup of the week containing date), list (available entries), generate (write or rewrite a day's entry now; weekly=true for the week's rollup)."
+}
+
+func (t *JournalTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"read", "week", "list", "generate"},
+				"description": "Action to perform.",
+			},
+			"date": map[string]any{
+				"type":        "string",
+				"description": "Day as YYYY-MM-DD or an expression like \"yesterday\" or \"last friday\" (default today). For week, any day in the week or YYYY-Www.",
+			},
+			"weekly": map[string]any{
+				"type":        "boolean",
+				"description": "For generate: write the weekly rollup instead of the day's entry.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; read, week and list only read.
+func (t *JournalTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "read", "week", "list")
+}
+
+func (t *JournalTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	date, _ := args["date"].(string)
+	date = strings.TrimSpace(date)
+	store := t.gen.Store()
+
+	switch action {
+	case "list":
+		days, weeks, err := store.List()
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to list journal: %v", err)).WithError(err)
+		}
+		if len(days) == 0 && len(weeks) == 0 {
+			return SilentResult("The journal is empty.")
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "Days: %s\n", strings.Join(days[:min(len(days), maxJournalList)], ", "))
+		fmt.Fprintf(&b, "Weeks: %s", strings.Join(weeks[:min(len(weeks), maxJournalList)], ", "))
+		return SilentResult(b.String())
+
+	case "read":
+		day, err := journalDay(date)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return t.read(journal.DayName(day))
+
+	case "week":
+		if journal.IsWeek(date) {
+			return t.read(date)
+		}
+		day, err := journalDay(date)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return t.read(journal.WeekName(day))
+
+	case "generate":
+		day, err := journalDay(date)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		var entry string
+		if weekly, _ := args["weekly"].(bool); weekly {
+			entry, err = t.gen.Weekly(ctx, day)
+		} else {
+			entry, err = t.gen.Daily(ctx, day)
+		}
+		if errors.Is(err, journal.ErrEmpty) {
+			return SilentResult("Nothing to write about for that period.")
+		}
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to write journal entry: %v", err)).WithError(err)
+		}
+		return SilentResult(entry)
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *JournalTool) read(name string) *ToolResult {
+	entry, err := t.gen.Store().Read(name)
+	if errors.Is(err, journal.ErrNotFound) {
+		return SilentResult(fmt.Sprintf("No journal entry for %s. Use generate to write it.", name))
+	}
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(entry)
+}
+
+// journalDay resolves a date argument; empty means today.
+func journalDay(date string) (time.Time, error) {
+	if date == "" {
+		return when.Now(), nil
+	}
+	r, err := when.Resolve(date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: %v", date, err)
+	}
+	return r.Time, nil
+}
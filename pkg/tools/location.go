@@ -3,11 +3,14 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"time"
+
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
 )
 
 type LocationTool struct {
@@ -43,6 +46,9 @@ func (t *LocationTool) DeclaredDomains() []string {
 	return []string{u.Host}
 }
 
+// SideEffect reports that LocationTool calls only read.
+func (t *LocationTool) SideEffect(map[string]any) bool { return false }
+
 func (t *LocationTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	apiURL := fmt.Sprintf("%s/api/states/person.%s", t.haURL, t.user)
 
@@ -52,18 +58,19 @@ func (t *LocationTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	}
 	req.Header.Set("Authorization", "Bearer "+t.apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := httpclient.New("get_user_location", httpclient.WithTimeout(10*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to fetch location: %v", err))
+		return ErrorResult(fmt.Sprintf("failed to fetch location: %v", err)).WithError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return ErrorResult(fmt.Sprintf("Home Assistant returned status %d", resp.StatusCode))
+		msg := fmt.Sprintf("Home Assistant returned status %d", resp.StatusCode)
+		return ErrorResult(msg).WithError(errs.Wrap(errs.FromStatus(resp.StatusCode), errors.New(msg)))
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
 	}
@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/constants"
+	"localagent/pkg/medications"
+	"localagent/pkg/when"
+)
+
+// MedicationsTool manages medications, logs doses and reports adherence.
+// Reminders go to the chat a medication was added from.
+type MedicationsTool struct {
+	meds    *medications.Service
+	channel string
+	chatID  string
+}
+
+func NewMedicationsTool(svc *medications.Service) *MedicationsTool {
+	return &MedicationsTool{meds: svc}
+}
+
+func (t *MedicationsTool) Name() string {
+	return "medications"
+}
+
+func (t *MedicationsTool) Description() string {
+	return "Medications with dose times and stock. Reminders are sent at each dose time and the user's \"taken\"/\"skip\" replies are logged automatically. " +
+		"Actions: add, list, update, remove, refill (add units to stock), log (record a dose as taken or skipped), summary (adherence, stock, low-stock warnings). " +
+		"Not medical advice: never suggest changing a dose."
+}
+
+func (t *MedicationsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"add", "list", "update", "remove", "refill", "log", "summary"},
+				"description": "Action to perform.",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Medication ID or name (for update, remove, refill, log).",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Medication name (for add, update).",
+			},
+			"dosage": map[string]any{
+				"type":        "string",
+				"description": "Dosage, e.g. \"100 mg\" (for add, update).",
+			},
+			"times": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Dose times as HH:MM (for add, update). Empty = as needed, no reminders.",
+			},
+			"days": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Weekdays like \"mon\" (for add, update). Empty = every day.",
+			},
+			"stock": map[string]any{
+				"type":        "number",
+				"description": "Units on hand, e.g. pills (for add, update). Omit to not track stock.",
+			},
+			"dose_units": map[string]any{
+				"type":        "number",
+				"description": "Units per dose (for add, update; default 1).",
+			},
+			"units": map[string]any{
+				"type":        "number",
+				"description": "Units added (for refill).",
+			},
+			"notes": map[string]any{
+				"type":        "string",
+				"description": "Notes like \"with food\" (for add, update).",
+			},
+			"active": map[string]any{
+				"type":        "boolean",
+				"description": "false pauses reminders (for update).",
+			},
+			"status": map[string]any{
+				"type":        "string",
+				"enum":        []string{"taken", "skipped"},
+				"description": "Dose status (for log).",
+			},
+			"days_back": map[string]any{
+				"type":        "number",
+				"description": "Days covered by summary (default 7).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *MedicationsTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// SideEffect reports whether the call changes anything; list and summary only read.
+func (t *MedicationsTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list", "summary")
+}
+
+func (t *MedicationsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	ref, _ := args["id"].(string)
+
+	switch action {
+	case "add":
+		m := medications.Medication{Stock: -1, DoseUnits: 1}
+		m.Name, _ = args["name"].(string)
+		m.Dosage, _ = args["dosage"].(string)
+		m.Notes, _ = args["notes"].(string)
+		m.Times = toStringSliceFromAny(args["times"])
+		m.Days = toStringSliceFromAny(args["days"])
+		if v, ok := args["stock"].(float64); ok {
+			m.Stock = int(v)
+		}
+		if v, ok := args["dose_units"].(float64); ok {
+			m.DoseUnits = int(v)
+		}
+		if !constants.IsInternalChannel(t.channel) {
+			m.Channel, m.ChatID = t.channel, t.chatID
+		}
+		added, err := t.meds.Add(m)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to add medication: %v", err))
+		}
+		return SilentResult("Medication added: " + medications.FormatMedication(*added))
+
+	case "list":
+		meds := t.meds.List()
+		if len(meds) == 0 {
+			return SilentResult("No medications.")
+		}
+		lines := make([]string, len(meds))
+		for i, m := range meds {
+			lines[i] = medications.FormatMedication(m)
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+
+	case "update":
+		m := t.meds.Find(ref)
+		if m == nil {
+			return ErrorResult(fmt.Sprintf("medication not found: %s", ref))
+		}
+		updated, err := t.meds.Update(m.ID, args)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to update medication: %v", err))
+		}
+		return SilentResult("Medication updated: " + medications.FormatMedication(*updated))
+
+	case "remove":
+		m := t.meds.Find(ref)
+		if m == nil || !t.meds.Remove(m.ID) {
+			return ErrorResult(fmt.Sprintf("medication not found: %s", ref))
+		}
+		return SilentResult(fmt.Sprintf("Medication %s removed with its dose log", m.Label()))
+
+	case "refill":
+		m := t.meds.Find(ref)
+		if m == nil {
+			return ErrorResult(fmt.Sprintf("medication not found: %s", ref))
+		}
+		units, _ := args["units"].(float64)
+		refilled, err := t.meds.Refill(m.ID, int(units))
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult("Refilled: " + medications.FormatMedication(*refilled))
+
+	case "log":
+		m := t.meds.Find(ref)
+		if m == nil {
+			return ErrorResult(fmt.Sprintf("medication not found: %s", ref))
+		}
+		status, _ := args["status"].(string)
+		logged, err := t.meds.Record(m.ID, status)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Logged %s as %s. %s", logged.Label(), status, medications.FormatMedication(*logged)))
+
+	case "summary":
+		days, _ := args["days_back"].(float64)
+		return SilentResult(t.meds.Summary(int(days), when.Now()))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
@@ -51,6 +51,12 @@ func (t *MessageTool) WasCalled() bool {
 	return t.called
 }
 
+// AuditAction names the chat the message would go to now; Execute sets the
+// one it was actually sent to as the result's AuditTarget.
+func (t *MessageTool) AuditAction(args map[string]any) (string, string) {
+	return "message", t.defaultChannel + ":" + t.defaultChatID
+}
+
 func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	content, ok := args["content"].(string)
 	if !ok {
@@ -78,7 +84,8 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 	t.called = true
 
 	return &ToolResult{
-		ForLLM: content,
-		Silent: true,
+		ForLLM:      content,
+		Silent:      true,
+		AuditTarget: channel + ":" + chatID,
 	}
 }
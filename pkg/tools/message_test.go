@@ -2,8 +2,10 @@ package tools
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
+	"localagent/pkg/audit"
 	"localagent/pkg/bus"
 )
 
@@ -127,3 +129,24 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected 'chat_id' property to be removed")
 	}
 }
+
+func TestMessageTool_AuditsActualTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	registry := NewToolRegistry()
+	registry.SetAuditLog(audit.NewLog(path, 0))
+	tool := NewMessageTool(bus.NewMessageBus(), nil)
+	registry.Register(tool)
+
+	registry.ExecuteWithContext(context.Background(), "message", map[string]any{"content": "hi"}, "telegram", "42", nil)
+	// A later turn in another chat moves the defaults before the next call.
+	tool.SetContext("web", "default")
+	registry.ExecuteWithContext(context.Background(), "message", map[string]any{"content": "hello"}, "discord", "7", nil)
+
+	entries, err := audit.ReadFile(path, audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Target != "telegram:42" || entries[1].Target != "discord:7" {
+		t.Errorf("audit entries = %+v", entries)
+	}
+}
@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+)
+
+const (
+	defaultIPLookupURL  = "https://api.ipify.org"
+	defaultSpeedTestURL = "https://speed.cloudflare.com/__down?bytes=10000000"
+)
+
+var defaultNetCheckHosts = []string{"1.1.1.1:443", "8.8.8.8:443", "google.com:443"}
+
+type NetCheckTool struct {
+	hosts        []string
+	speedTestURL string
+	timeout      time.Duration
+}
+
+func NewNetCheckTool(hosts []string, speedTestURL string) *NetCheckTool {
+	if len(hosts) == 0 {
+		hosts = defaultNetCheckHosts
+	}
+	if speedTestURL == "" {
+		speedTestURL = defaultSpeedTestURL
+	}
+	return &NetCheckTool{
+		hosts:        hosts,
+		speedTestURL: speedTestURL,
+		timeout:      5 * time.Second,
+	}
+}
+
+func (t *NetCheckTool) Name() string {
+	return "net_check"
+}
+
+func (t *NetCheckTool) Description() string {
+	return "Diagnose network connectivity: DNS resolution, TCP latency to hosts, external IP lookup, and optional download bandwidth measurement. Use this to answer questions like 'is my internet slow or is it the VPN?' with real data."
+}
+
+func (t *NetCheckTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"checks": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string", "enum": []string{"dns", "latency", "ip", "bandwidth"}},
+				"description": "Checks to run. Defaults to dns, latency and ip. Bandwidth downloads ~10MB and is only run when requested.",
+			},
+			"hosts": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Hosts to check (host or host:port). Defaults to the configured host list.",
+			},
+		},
+	}
+}
+
+func (t *NetCheckTool) DeclaredDomains() []string {
+	domains := []string{"api.ipify.org"}
+	if u, err := url.Parse(t.speedTestURL); err == nil && u.Host != "" {
+		domains = append(domains, u.Host)
+	}
+	return domains
+}
+
+// SideEffect reports that NetCheckTool calls only read.
+func (t *NetCheckTool) SideEffect(map[string]any) bool { return false }
+
+func (t *NetCheckTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	checks := toStringSliceFromAny(args["checks"])
+	if len(checks) == 0 {
+		checks = []string{"dns", "latency", "ip"}
+	}
+	hosts := toStringSliceFromAny(args["hosts"])
+	if len(hosts) == 0 {
+		hosts = t.hosts
+	}
+
+	var sections []string
+	for _, check := range checks {
+		switch check {
+		case "dns":
+			sections = append(sections, t.checkDNS(ctx, hosts))
+		case "latency":
+			sections = append(sections, t.checkLatency(ctx, hosts))
+		case "ip":
+			sections = append(sections, t.checkExternalIP(ctx))
+		case "bandwidth":
+			sections = append(sections, t.checkBandwidth(ctx))
+		default:
+			return ErrorResult(fmt.Sprintf("unknown check: %s (use dns, latency, ip, or bandwidth)", check))
+		}
+	}
+
+	return SilentResult(strings.Join(sections, "\n\n"))
+}
+
+func (t *NetCheckTool) checkDNS(ctx context.Context, hosts []string) string {
+	var b strings.Builder
+	b.WriteString("## DNS\n")
+	for _, h := range hosts {
+		host := hostOnly(h)
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		lookupCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		start := time.Now()
+		addrs, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+		elapsed := time.Since(start)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(&b, "- %s: FAILED after %dms (%v)\n", host, elapsed.Milliseconds(), err)
+			continue
+		}
+		fmt.Fprintf(&b, "- %s: %dms -> %s\n", host, elapsed.Milliseconds(), strings.Join(addrs, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (t *NetCheckTool) checkLatency(ctx context.Context, hosts []string) string {
+	var b strings.Builder
+	b.WriteString("## Latency (TCP connect)\n")
+	dialer := &net.Dialer{Timeout: t.timeout}
+	for _, h := range hosts {
+		addr := h
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "443")
+		}
+
+		var samples []time.Duration
+		var lastErr error
+		for range 3 {
+			start := time.Now()
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			samples = append(samples, time.Since(start))
+			conn.Close()
+		}
+
+		if len(samples) == 0 {
+			fmt.Fprintf(&b, "- %s: unreachable (%v)\n", addr, lastErr)
+			continue
+		}
+		minD, maxD, total := samples[0], samples[0], time.Duration(0)
+		for _, s := range samples {
+			minD = min(minD, s)
+			maxD = max(maxD, s)
+			total += s
+		}
+		avg := total / time.Duration(len(samples))
+		fmt.Fprintf(&b, "- %s: avg %dms (min %dms, max %dms, %d/3 ok)\n",
+			addr, avg.Milliseconds(), minD.Milliseconds(), maxD.Milliseconds(), len(samples))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (t *NetCheckTool) checkExternalIP(ctx context.Context) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", defaultIPLookupURL, nil)
+	if err != nil {
+		return fmt.Sprintf("## External IP\nFAILED: %v", err)
+	}
+
+	client := httpclient.New("net_check", httpclient.WithTimeout(10*time.Second))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("## External IP\nFAILED: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("## External IP\nFAILED: status %d", resp.StatusCode)
+	}
+	return fmt.Sprintf("## External IP\n%s", strings.TrimSpace(string(body)))
+}
+
+func (t *NetCheckTool) checkBandwidth(ctx context.Context) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.speedTestURL, nil)
+	if err != nil {
+		return fmt.Sprintf("## Bandwidth\nFAILED: %v", err)
+	}
+
+	client := httpclient.New("net_check", httpclient.WithTimeout(60*time.Second), httpclient.WithRetries(0))
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("## Bandwidth\nFAILED: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("## Bandwidth\nFAILED: status %d", resp.StatusCode)
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(start)
+	if err != nil {
+		return fmt.Sprintf("## Bandwidth\nFAILED after %d bytes: %v", n, err)
+	}
+	if elapsed <= 0 || n == 0 {
+		return "## Bandwidth\nFAILED: no data received"
+	}
+
+	mbps := float64(n*8) / elapsed.Seconds() / 1e6
+	return fmt.Sprintf("## Bandwidth\nDownload: %.1f Mbit/s (%.1f MB in %.1fs)", mbps, float64(n)/1e6, elapsed.Seconds())
+}
+
+// hostOnly strips an optional port from a host string.
+func hostOnly(h string) string {
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		return host
+	}
+	return h
+}
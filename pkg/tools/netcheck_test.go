@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNetCheckArguments(t *testing.T) {
+	tool := NewNetCheckTool([]string{"127.0.0.1:1"}, "")
+	tool.timeout = time.Second
+
+	cases := []struct {
+		name    string
+		args    map[string]any
+		wantErr bool
+		want    string
+		notWant string
+	}{
+		{"unknown check", map[string]any{"checks": []any{"ping"}}, true, "unknown check: ping", ""},
+		{"unknown after valid", map[string]any{"checks": []any{"dns", "traceroute"}}, true, "unknown check: traceroute", ""},
+		{"dns skips IPs", map[string]any{"checks": []any{"dns"}}, false, "## DNS", "127.0.0.1"},
+		{"explicit hosts", map[string]any{"checks": []any{"dns"}, "hosts": []any{"localhost:8080"}}, false, "- localhost:", "8080"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := tool.Execute(context.Background(), tc.args)
+			if res.IsError != tc.wantErr || !strings.Contains(res.ForLLM, tc.want) || (tc.notWant != "" && strings.Contains(res.ForLLM, tc.notWant)) {
+				t.Errorf("Execute(%v) = %+v", tc.args, res)
+			}
+		})
+	}
+}
+
+func TestNetCheckLatency(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := ln.Addr().String()
+
+	// A port that was open and is now closed refuses connections.
+	closedLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := closedLn.Addr().String()
+	closedLn.Close()
+	defer ln.Close()
+
+	tool := NewNetCheckTool(nil, "")
+	tool.timeout = time.Second
+	out := tool.checkLatency(context.Background(), []string{open, closed})
+	if !strings.Contains(out, "- "+open+": avg") || !strings.Contains(out, "3/3 ok") {
+		t.Errorf("open port not measured:\n%s", out)
+	}
+	if !strings.Contains(out, "- "+closed+": unreachable") {
+		t.Errorf("closed port not reported:\n%s", out)
+	}
+}
+
+func TestHostOnly(t *testing.T) {
+	cases := map[string]string{
+		"example.com":     "example.com",
+		"example.com:443": "example.com",
+		"1.1.1.1:53":      "1.1.1.1",
+		"[::1]:8080":      "::1",
+		"::1":             "::1",
+	}
+	for in, want := range cases {
+		if got := hostOnly(in); got != want {
+			t.Errorf("hostOnly(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/receipt"
+	"localagent/pkg/session"
+	"localagent/pkg/utils"
+)
+
+// ParseReceiptTool reads a photographed receipt into structured data.
+// Without a path it takes the latest image the user sent in the session.
+type ParseReceiptTool struct {
+	parser    *receipt.Parser
+	sessions  *session.SessionManager
+	workspace string
+}
+
+func NewParseReceiptTool(parser *receipt.Parser, sessions *session.SessionManager, workspace string) *ParseReceiptTool {
+	return &ParseReceiptTool{parser: parser, sessions: sessions, workspace: workspace}
+}
+
+func (t *ParseReceiptTool) Name() string {
+	return "parse_receipt"
+}
+
+func (t *ParseReceiptTool) Description() string {
+	return "Read a photo of a receipt into vendor, date, currency, line items and totals. Uses the latest image the user sent unless a path is given."
+}
+
+func (t *ParseReceiptTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Image file, relative to the workspace or absolute. Defaults to the latest image in this conversation.",
+			},
+		},
+	}
+}
+
+// SideEffect reports that ParseReceiptTool calls only read.
+func (t *ParseReceiptTool) SideEffect(map[string]any) bool { return false }
+
+func (t *ParseReceiptTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, _ := args["path"].(string)
+	if path != "" {
+		resolved, err := validatePath(path, t.workspace)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		path = resolved
+	} else if path = t.latestImage(SessionKeyFromContext(ctx)); path == "" {
+		return ErrorResult("no image in this conversation; ask the user to send a photo of the receipt")
+	}
+
+	r, err := t.parser.Parse(ctx, path)
+	if errors.Is(err, receipt.ErrNotReceipt) {
+		return ErrorResult("the image doesn't look like a readable receipt; ask for a sharper, flat photo")
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("receipt parsing failed: %v", err)).WithError(err)
+	}
+	return SilentResult(formatReceipt(r)).WithData(r).WithTitle(fmt.Sprintf("Receipt: %s %.2f", r.Vendor, r.Total))
+}
+
+// latestImage returns the newest image attached to a message in the
+// session, or "".
+func (t *ParseReceiptTool) latestImage(sessionKey string) string {
+	if sessionKey == "" {
+		return ""
+	}
+	latest := ""
+	for _, e := range t.sessions.GetTimeline(sessionKey) {
+		for _, m := range e.Media {
+			if utils.IsImageFile(m) {
+				latest = m
+			}
+		}
+	}
+	return latest
+}
+
+func formatReceipt(r *receipt.Receipt) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Vendor: %s\n", r.Vendor)
+	if r.Date != "" {
+		fmt.Fprintf(&sb, "Date: %s\n", r.Date)
+	}
+	for _, it := range r.Items {
+		if it.Quantity > 1 {
+			fmt.Fprintf(&sb, "- %s x%g: %.2f\n", it.Description, it.Quantity, it.Amount)
+		} else {
+			fmt.Fprintf(&sb, "- %s: %.2f\n", it.Description, it.Amount)
+		}
+	}
+	if r.Subtotal != 0 {
+		fmt.Fprintf(&sb, "Subtotal: %.2f\n", r.Subtotal)
+	}
+	if r.Tax != 0 {
+		fmt.Fprintf(&sb, "Tax: %.2f\n", r.Tax)
+	}
+	fmt.Fprintf(&sb, "Total: %.2f %s", r.Total, r.Currency)
+	if !r.ItemsAddUp() {
+		sb.WriteString("\nThe line items don't add up to the total; some amounts may be misread, so check them with the user.")
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -10,6 +11,9 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
 )
 
 type PDFToTextTool struct {
@@ -47,6 +51,9 @@ func (t *PDFToTextTool) Parameters() map[string]any {
 	}
 }
 
+// SideEffect reports that PDFToTextTool calls only read.
+func (t *PDFToTextTool) SideEffect(map[string]any) bool { return false }
+
 func (t *PDFToTextTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok || path == "" {
@@ -59,7 +66,7 @@ func (t *PDFToTextTool) Execute(ctx context.Context, args map[string]any) *ToolR
 
 	text, err := ConvertPDF(ctx, path, t.serviceURL, t.apiKey)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("PDF conversion failed: %v", err))
+		return ErrorResult(fmt.Sprintf("PDF conversion failed: %v", err)).WithError(err)
 	}
 
 	return SilentResult(text)
@@ -94,21 +101,25 @@ func ConvertPDF(ctx context.Context, filePath, serviceURL, apiKey string) (strin
 		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	client := httpclient.New("pdf_to_text", httpclient.WithTimeout(120*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	body, err := httpclient.ReadBody(resp)
+	var tooLarge *httpclient.TooLargeError
+	if err != nil && !errors.As(err, &tooLarge) {
 		return "", fmt.Errorf("read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("service returned %d: %s", resp.StatusCode, string(body))
+		return "", errs.Wrap(errs.FromStatus(resp.StatusCode), fmt.Errorf("service returned %d: %s", resp.StatusCode, string(body)))
 	}
 
+	if tooLarge != nil {
+		return string(body) + "\n\n[" + tooLarge.Error() + "]", nil
+	}
 	return string(body), nil
 }
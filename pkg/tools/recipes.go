@@ -0,0 +1,332 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/httpclient"
+	"localagent/pkg/recipes"
+	"localagent/pkg/when"
+)
+
+// RecipesTool manages the recipe collection.
+type RecipesTool struct {
+	recipes *recipes.Service
+}
+
+func NewRecipesTool(svc *recipes.Service) *RecipesTool {
+	return &RecipesTool{recipes: svc}
+}
+
+func (t *RecipesTool) Name() string {
+	return "recipes"
+}
+
+func (t *RecipesTool) Description() string {
+	return "The user's recipe collection. " +
+		"Actions: add, import (save a recipe from a web page URL), list (optionally by tags), show (optionally scaled to servings), update, remove."
+}
+
+func (t *RecipesTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"add", "import", "list", "show", "update", "remove"},
+				"description": "Action to perform.",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Recipe ID or title (for show, update, remove).",
+			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Recipe page URL (for import).",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Recipe title (for add, update).",
+			},
+			"servings": map[string]any{
+				"type":        "integer",
+				"description": "Servings the recipe makes (for add, update), or servings to scale to (for show).",
+			},
+			"ingredients": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Ingredient lines with quantity first, e.g. \"200 g spaghetti\" (for add, update).",
+			},
+			"steps": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Method steps (for add, update).",
+			},
+			"tags": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Tags like \"vegetarian\" or \"quick\" (for add, import, update; filters list).",
+			},
+			"notes": map[string]any{
+				"type":        "string",
+				"description": "Free-form notes (for add, update).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; list and show only read.
+func (t *RecipesTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list", "show")
+}
+
+func (t *RecipesTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	ref, _ := args["id"].(string)
+	tags := toStringSliceFromAny(args["tags"])
+
+	switch action {
+	case "add":
+		r := recipes.Recipe{Tags: tags}
+		r.Title, _ = args["title"].(string)
+		r.Notes, _ = args["notes"].(string)
+		if v, ok := args["servings"].(float64); ok {
+			r.Servings = int(v)
+		}
+		r.Ingredients = toStringSliceFromAny(args["ingredients"])
+		r.Steps = toStringSliceFromAny(args["steps"])
+		created, err := t.recipes.Add(r)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to add recipe: %v", err))
+		}
+		return SilentResult("Recipe added:\n" + recipes.FormatRecipe(*created, 0))
+
+	case "import":
+		url, _ := args["url"].(string)
+		if url == "" {
+			return ErrorResult("url is required for import")
+		}
+		r, err := recipes.Import(ctx, httpclient.New("recipes"), url)
+		if errors.Is(err, recipes.ErrNoRecipe) {
+			return ErrorResult(fmt.Sprintf("%s has no structured recipe data; read the page and use action add instead", url))
+		}
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to import recipe: %v", err)).WithError(err)
+		}
+		r.Tags = append(r.Tags, tags...)
+		created, err := t.recipes.Add(*r)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to save recipe: %v", err))
+		}
+		return SilentResult("Recipe imported:\n" + recipes.FormatRecipe(*created, 0))
+
+	case "list":
+		list := t.recipes.List(tags...)
+		if len(list) == 0 {
+			return SilentResult("No recipes.")
+		}
+		lines := make([]string, len(list))
+		for i, r := range list {
+			lines[i] = fmt.Sprintf("%s: %s", r.ID, r.Title)
+			if len(r.Tags) > 0 {
+				lines[i] += " [" + strings.Join(r.Tags, ", ") + "]"
+			}
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+
+	case "show":
+		r := t.recipes.Find(ref)
+		if r == nil {
+			return ErrorResult(fmt.Sprintf("recipe not found: %s", ref))
+		}
+		servings := 0
+		if v, ok := args["servings"].(float64); ok {
+			servings = int(v)
+		}
+		return SilentResult(recipes.FormatRecipe(*r, servings))
+
+	case "update":
+		r := t.recipes.Find(ref)
+		if r == nil {
+			return ErrorResult(fmt.Sprintf("recipe not found: %s", ref))
+		}
+		patch := make(map[string]any)
+		for _, k := range []string{"title", "servings", "ingredients", "steps", "tags", "notes"} {
+			if v, ok := args[k]; ok {
+				patch[k] = v
+			}
+		}
+		updated, err := t.recipes.Update(r.ID, patch)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to update recipe: %v", err))
+		}
+		return SilentResult("Recipe updated:\n" + recipes.FormatRecipe(*updated, 0))
+
+	case "remove":
+		r := t.recipes.Find(ref)
+		if r == nil || !t.recipes.Remove(r.ID) {
+			return ErrorResult(fmt.Sprintf("recipe not found: %s", ref))
+		}
+		return SilentResult(fmt.Sprintf("Recipe %q removed", r.Title))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// MealPlanTool builds weekly meal plans from the recipe collection and
+// turns them into shopping lists.
+type MealPlanTool struct {
+	recipes *recipes.Service
+}
+
+func NewMealPlanTool(svc *recipes.Service) *MealPlanTool {
+	return &MealPlanTool{recipes: svc}
+}
+
+func (t *MealPlanTool) Name() string {
+	return "meal_plan"
+}
+
+func (t *MealPlanTool) Description() string {
+	return "Weekly meal plans built from the recipes collection. " +
+		"Actions: generate (fill a slot, dinner by default, for the week with recipes not cooked recently), show, set (plan a recipe on a date), " +
+		"remove (drop a meal), clear (delete the week's plan), shopping_list (consolidated ingredients scaled to servings, saved as a task list tagged \"shopping\")."
+}
+
+func (t *MealPlanTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"generate", "show", "set", "remove", "clear", "shopping_list"},
+				"description": "Action to perform.",
+			},
+			"date": map[string]any{
+				"type":        "string",
+				"description": "A day as YYYY-MM-DD or an expression like \"next monday\". For set and remove the meal's day; otherwise any day in the week (default this week).",
+			},
+			"recipe": map[string]any{
+				"type":        "string",
+				"description": "Recipe ID or title (for set).",
+			},
+			"slot": map[string]any{
+				"type":        "string",
+				"description": "Meal slot like breakfast, lunch or dinner (default dinner; for remove, omit to drop the whole day).",
+			},
+			"servings": map[string]any{
+				"type":        "integer",
+				"description": "Servings to cook (for generate, set; default 2).",
+			},
+			"days": map[string]any{
+				"type":        "integer",
+				"description": "Days to plan from Monday (for generate; default 7, 5 for weekdays only).",
+			},
+			"tags": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Only use recipes with these tags (for generate).",
+			},
+			"save": map[string]any{
+				"type":        "boolean",
+				"description": "For shopping_list: save it as tasks (default true).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; show and shopping_list only read.
+func (t *MealPlanTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "show", "shopping_list")
+}
+
+func (t *MealPlanTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	slot, _ := args["slot"].(string)
+	servings := 0
+	if v, ok := args["servings"].(float64); ok {
+		servings = int(v)
+	}
+	day := when.Now()
+	if raw, _ := args["date"].(string); raw != "" {
+		r, err := when.Resolve(raw)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid date %q: %v", raw, err))
+		}
+		day = r.Time.In(when.Location())
+	}
+	week := recipes.WeekName(day)
+
+	switch action {
+	case "generate":
+		opts := recipes.PlanOptions{Servings: servings, Slot: strings.ToLower(slot), Tags: toStringSliceFromAny(args["tags"])}
+		if v, ok := args["days"].(float64); ok {
+			opts.Days = int(v)
+		}
+		plan, err := t.recipes.Generate(day, opts)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to plan meals: %v", err))
+		}
+		return SilentResult(recipes.FormatPlan(*plan))
+
+	case "show":
+		plan := t.recipes.GetPlan(week)
+		if plan == nil {
+			return SilentResult(fmt.Sprintf("No meal plan for %s.", week))
+		}
+		return SilentResult(recipes.FormatPlan(*plan))
+
+	case "set":
+		ref, _ := args["recipe"].(string)
+		r := t.recipes.Find(ref)
+		if r == nil {
+			return ErrorResult(fmt.Sprintf("recipe not found: %s", ref))
+		}
+		plan, err := t.recipes.SetMeal(day, slot, *r, servings)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to plan meal: %v", err))
+		}
+		return SilentResult(recipes.FormatPlan(*plan))
+
+	case "remove":
+		plan, err := t.recipes.RemoveMeal(day, slot)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(recipes.FormatPlan(*plan))
+
+	case "clear":
+		if !t.recipes.ClearPlan(week) {
+			return SilentResult(fmt.Sprintf("No meal plan for %s.", week))
+		}
+		return SilentResult(fmt.Sprintf("Meal plan %s cleared.", week))
+
+	case "shopping_list":
+		plan := t.recipes.GetPlan(week)
+		if plan == nil || len(plan.Meals) == 0 {
+			return ErrorResult(fmt.Sprintf("no meal plan for %s; generate one first", week))
+		}
+		items := t.recipes.ShoppingList(*plan)
+		lines := make([]string, len(items))
+		for i, it := range items {
+			lines[i] = "- " + it.String()
+		}
+		text := fmt.Sprintf("Shopping list %s (%d items):\n%s", week, len(items), strings.Join(lines, "\n"))
+		if save, ok := args["save"].(bool); ok && !save {
+			return SilentResult(text)
+		}
+		task, err := t.recipes.WriteShoppingList(week, items)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to save shopping list: %v", err))
+		}
+		return SilentResult(fmt.Sprintf("%s\n\nSaved as task %s with one subtask per item.", text, task.ID))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
@@ -3,21 +3,31 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"localagent/pkg/audit"
 	"localagent/pkg/logger"
 	"localagent/pkg/providers"
+	"localagent/pkg/proxy"
+	"localagent/pkg/redact"
+	"localagent/pkg/telemetry"
+	"localagent/pkg/utils"
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools     map[string]Tool
+	hints     map[string][]RepairHint
+	repairOff bool
+	audit     *audit.Log
+	mu        sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
 		tools: make(map[string]Tool),
+		hints: make(map[string][]RepairHint),
 	}
 }
 
@@ -25,6 +35,9 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.tools[tool.Name()] = tool
+	if rh, ok := tool.(RepairHinter); ok {
+		r.hints[tool.Name()] = append(r.hints[tool.Name()], rh.RepairHints()...)
+	}
 }
 
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
@@ -44,6 +57,12 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	args, problems := ValidateArgs(tool.Parameters(), args)
+	if len(problems) > 0 {
+		logger.Warn("tool %s: invalid arguments: %s", name, strings.Join(problems, "; "))
+		return ErrorResult(FormatValidationError(name, problems)).WithError(fmt.Errorf("invalid arguments"))
+	}
+
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
 	}
@@ -52,11 +71,25 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		asyncTool.SetCallback(asyncCallback)
 	}
 
+	ctx = proxy.WithTool(ctx, name)
+
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
 
+	r.recordAudit(ctx, tool, args, result, channel, chatID)
+
+	if telemetry.Enabled() {
+		status := "ok"
+		if result.IsError {
+			status = "error"
+		}
+		telemetry.Emit("tool_exec", map[string]string{"tool": name, "status": status},
+			map[string]float64{"duration_ms": float64(duration.Milliseconds())})
+	}
+
 	if result.IsError {
+		r.applyRepairHints(name, args, result)
 		logger.Error("tool %s failed (%dms): %s", name, duration.Milliseconds(), result.ForLLM)
 	} else if result.Async {
 		logger.Info("tool %s started async (%dms)", name, duration.Milliseconds())
@@ -67,6 +100,84 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	return result
 }
 
+// AuditAction returns what a call to the named tool would change, or an
+// empty action for read-only calls and tools that aren't Audited.
+func (r *ToolRegistry) AuditAction(name string, args map[string]any) (action, target string) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return "", ""
+	}
+	if at, ok := tool.(Audited); ok {
+		return at.AuditAction(args)
+	}
+	return "", ""
+}
+
+// SideEffect reports whether a call to the named tool changes anything:
+// SideEffecting tools say so per call, Audited ones by a non-empty action,
+// and other tools are assumed to. Unknown tools have none since they
+// can't run.
+func (r *ToolRegistry) SideEffect(name string, args map[string]any) bool {
+	tool, ok := r.Get(name)
+	if !ok {
+		return false
+	}
+	if se, ok := tool.(SideEffecting); ok {
+		return se.SideEffect(args)
+	}
+	if at, ok := tool.(Audited); ok {
+		action, _ := at.AuditAction(args)
+		return action != ""
+	}
+	return true
+}
+
+// SetAuditLog records calls to Audited tools in log. Nil disables auditing.
+func (r *ToolRegistry) SetAuditLog(log *audit.Log) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = log
+}
+
+func (r *ToolRegistry) recordAudit(ctx context.Context, tool Tool, args map[string]any, result *ToolResult, channel, chatID string) {
+	r.mu.RLock()
+	log := r.audit
+	r.mu.RUnlock()
+	if log == nil {
+		return
+	}
+	at, ok := tool.(Audited)
+	if !ok {
+		return
+	}
+	action, target := at.AuditAction(args)
+	if action == "" {
+		return
+	}
+	if result.AuditTarget != "" {
+		target = result.AuditTarget
+	}
+
+	e := audit.Entry{
+		Action:   action,
+		Tool:     tool.Name(),
+		Target:   utils.Truncate(redact.MaskSecrets(target), 200),
+		Session:  SessionKeyFromContext(ctx),
+		Channel:  channel,
+		ChatID:   chatID,
+		ArgsHash: audit.HashArgs(args),
+		Status:   "ok",
+	}
+	switch {
+	case result.IsError:
+		e.Status = "error"
+		e.Error = utils.Truncate(redact.MaskSecrets(result.ForLLM), 200)
+	case result.Async:
+		e.Status = "async"
+	}
+	log.Record(e)
+}
+
 func (r *ToolRegistry) ToProviderDefs() []providers.ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/bus"
+	"localagent/pkg/config"
+	"localagent/pkg/federation"
+	"localagent/pkg/httpclient"
+	"localagent/pkg/logger"
+)
+
+// remoteTaskTimeout bounds how long a peer may work on a delegated task.
+const remoteTaskTimeout = 15 * time.Minute
+
+// maxRemoteSteps is how many status lines from the peer are kept for the
+// answer message.
+const maxRemoteSteps = 10
+
+// RemoteAgentTool delegates a task to another localagent instance. It
+// returns at once; the peer's answer arrives later as an inbound message in
+// the conversation that asked, so the agent can pass it on.
+type RemoteAgentTool struct {
+	bus      *bus.MessageBus
+	peers    map[string]config.PeerConfig
+	client   *http.Client
+	callback AsyncCallback
+	channel  string
+	chatID   string
+}
+
+// NewRemoteAgentTool allows delegating to the peers that have a URL.
+func NewRemoteAgentTool(msgBus *bus.MessageBus, peers []config.PeerConfig) *RemoteAgentTool {
+	t := &RemoteAgentTool{
+		bus:    msgBus,
+		peers:  make(map[string]config.PeerConfig),
+		client: httpclient.New("remote_agent", httpclient.WithTimeout(remoteTaskTimeout), httpclient.WithRetries(0)),
+	}
+	for _, p := range peers {
+		if p.URL != "" && p.Name != "" {
+			t.peers[p.Name] = p
+		}
+	}
+	return t
+}
+
+func (t *RemoteAgentTool) Name() string {
+	return "remote_agent"
+}
+
+func (t *RemoteAgentTool) Description() string {
+	return "Delegate a task to another of the user's agents running on a different machine (e.g. to read files or check services only it can reach). " +
+		"Runs in the background; its answer arrives as a new message in this conversation."
+}
+
+func (t *RemoteAgentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"peer": map[string]any{
+				"type":        "string",
+				"description": "Which agent to ask",
+				"enum":        t.peerNames(),
+			},
+			"task": map[string]any{
+				"type":        "string",
+				"description": "Self-contained task for the other agent; it does not see this conversation",
+			},
+		},
+		"required": []string{"peer", "task"},
+	}
+}
+
+func (t *RemoteAgentTool) peerNames() []string {
+	names := make([]string, 0, len(t.peers))
+	for name := range t.peers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DeclaredDomains lets the egress proxy reach the peers.
+func (t *RemoteAgentTool) DeclaredDomains() []string {
+	var domains []string
+	for _, p := range t.peers {
+		if u, err := url.Parse(p.URL); err == nil && u.Host != "" {
+			domains = append(domains, u.Host)
+		}
+	}
+	return domains
+}
+
+func (t *RemoteAgentTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *RemoteAgentTool) SetCallback(cb AsyncCallback) {
+	t.callback = cb
+}
+
+func (t *RemoteAgentTool) AuditAction(args map[string]any) (string, string) {
+	peer, _ := args["peer"].(string)
+	return "delegate", peer
+}
+
+func (t *RemoteAgentTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	name, _ := args["peer"].(string)
+	task, _ := args["task"].(string)
+	peer, ok := t.peers[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("unknown peer %q; available: %s", name, strings.Join(t.peerNames(), ", ")))
+	}
+	if strings.TrimSpace(task) == "" {
+		return ErrorResult("task is required")
+	}
+
+	origin := bus.InboundMessage{
+		Channel:    t.channel,
+		ChatID:     t.chatID,
+		SenderID:   federation.SenderID(name),
+		SessionKey: SessionKeyFromContext(ctx),
+	}
+	// The run that called us ends long before the peer answers.
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), remoteTaskTimeout)
+	go func() {
+		defer cancel()
+		t.run(runCtx, peer, task, origin)
+	}()
+	return AsyncResult(fmt.Sprintf("Delegated to %q. Its answer will arrive as a new message in this conversation; tell the user you asked it.", name))
+}
+
+func (t *RemoteAgentTool) run(ctx context.Context, peer config.PeerConfig, task string, origin bus.InboundMessage) {
+	var steps []string
+	answer, err := federation.Delegate(ctx, t.client, peer, task, func(status string) {
+		logger.Info("remote_agent %s: %s", peer.Name, status)
+		steps = append(steps, status)
+		if len(steps) > maxRemoteSteps {
+			steps = steps[1:]
+		}
+	})
+
+	var result *ToolResult
+	if err != nil {
+		logger.Error("remote_agent %s: %v", peer.Name, err)
+		origin.Content = fmt.Sprintf("[Remote agent %q could not complete the delegated task]\n\nTask: %s\n\nError: %v", peer.Name, task, err)
+		result = ErrorResult(origin.Content).WithError(err)
+	} else {
+		origin.Content = fmt.Sprintf("[Answer from remote agent %q to the delegated task]\n\nTask: %s", peer.Name, task)
+		if len(steps) > 0 {
+			origin.Content += "\n\nSteps: " + strings.Join(steps, "; ")
+		}
+		origin.Content += "\n\nAnswer:\n" + answer
+		result = &ToolResult{ForLLM: origin.Content, ForUser: answer}
+	}
+
+	if t.callback != nil {
+		t.callback(ctx, result)
+	}
+	if origin.Channel == "" || origin.ChatID == "" {
+		logger.Warn("remote_agent %s: no conversation to deliver the answer to", peer.Name)
+		return
+	}
+	t.bus.PublishInbound(origin)
+}
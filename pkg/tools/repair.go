@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"strings"
+
+	"localagent/pkg/errs"
+)
+
+// kindHints steer the model by the kind of a failure, whatever the tool.
+var kindHints = map[error]string{
+	errs.ErrRateLimited: "The service is rate limiting requests. Don't retry this call now; tell the user and try again later.",
+	errs.ErrAuth:        "The service rejected the credentials, so retrying won't help. Tell the user to check this tool's API key in the config.",
+	errs.ErrNotFound:    "The service says it doesn't exist. Check the name, ID or URL instead of repeating the call.",
+	errs.ErrTimeout:     "The call timed out. Retry at most once; if it fails again, tell the user the service is slow.",
+}
+
+// RepairHint inspects a failed tool call and returns guidance that helps the
+// model fix it on the next iteration, or "" when it has nothing to add.
+type RepairHint func(args map[string]any, result *ToolResult) string
+
+// HintOn returns a RepairHint that fires when the error text contains any of
+// the given substrings (case-insensitive).
+func HintOn(hint string, substrs ...string) RepairHint {
+	return func(_ map[string]any, result *ToolResult) string {
+		msg := strings.ToLower(result.ForLLM)
+		for _, s := range substrs {
+			if strings.Contains(msg, strings.ToLower(s)) {
+				return hint
+			}
+		}
+		return ""
+	}
+}
+
+// AddRepairHint registers an extra hint for the named tool. Hints are
+// evaluated in registration order and every non-empty one is appended.
+func (r *ToolRegistry) AddRepairHint(toolName string, hint RepairHint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hints[toolName] = append(r.hints[toolName], hint)
+}
+
+// SetRepairHints enables or disables augmenting failed results with hints.
+func (r *ToolRegistry) SetRepairHints(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repairOff = !enabled
+}
+
+func (r *ToolRegistry) applyRepairHints(name string, args map[string]any, result *ToolResult) {
+	r.mu.RLock()
+	hints := r.hints[name]
+	off := r.repairOff
+	r.mu.RUnlock()
+	if off {
+		return
+	}
+
+	var notes []string
+	if h := kindHints[errs.Kind(result.Err)]; h != "" && !strings.Contains(result.ForLLM, h) {
+		notes = append(notes, h)
+	}
+	for _, hint := range hints {
+		if h := hint(args, result); h != "" && !strings.Contains(result.ForLLM, h) {
+			notes = append(notes, h)
+		}
+	}
+	if len(notes) == 0 {
+		return
+	}
+	result.ForLLM += "\n\nHint: " + strings.Join(notes, "\nHint: ")
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairHintsAppendedOnError(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello world"), 0644)
+
+	r := NewToolRegistry()
+	r.Register(NewEditFileTool(dir))
+
+	result := r.Execute(context.Background(), "edit_file", map[string]any{
+		"path": "a.txt", "old_text": "goodbye", "new_text": "hi",
+	})
+	if !result.IsError {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(result.ForLLM, "Hint: call read_file") {
+		t.Errorf("expected repair hint, got: %s", result.ForLLM)
+	}
+
+	r.SetRepairHints(false)
+	result = r.Execute(context.Background(), "edit_file", map[string]any{
+		"path": "a.txt", "old_text": "goodbye", "new_text": "hi",
+	})
+	if strings.Contains(result.ForLLM, "Hint:") {
+		t.Errorf("hints should be disabled, got: %s", result.ForLLM)
+	}
+}
+
+func TestAddRepairHint(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(NewReadFileTool(t.TempDir()))
+	r.AddRepairHint("read_file", func(args map[string]any, _ *ToolResult) string {
+		return "custom hint for " + args["path"].(string)
+	})
+
+	result := r.Execute(context.Background(), "read_file", map[string]any{"path": "missing.txt"})
+	if !strings.Contains(result.ForLLM, "Hint: custom hint for missing.txt") {
+		t.Errorf("expected custom hint, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "Hint: call list_dir") {
+		t.Errorf("expected built-in hint, got: %s", result.ForLLM)
+	}
+}
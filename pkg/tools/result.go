@@ -27,6 +27,31 @@ type ToolResult struct {
 	// When true, the tool will complete later and notify via callback.
 	Async bool `json:"async"`
 
+	// Title is a short user-visible heading for the result,
+	// e.g. "3 events this week". Shown by rich clients.
+	Title string `json:"title,omitempty"`
+
+	// Data is an optional machine-readable payload (rows, records)
+	// that clients can render as tables instead of plain text.
+	// Must be JSON serializable.
+	Data any `json:"data,omitempty"`
+
+	// Artifacts lists file paths produced or modified by the tool.
+	Artifacts []string `json:"artifacts,omitempty"`
+
+	// Images lists image files the model should look at (e.g. a
+	// screenshot). Tool messages carry text only, so they are attached to
+	// a message after the tool results.
+	Images []string `json:"images,omitempty"`
+
+	// FollowUps suggests tools the model may want to call next.
+	// They are appended to the content sent to the LLM.
+	FollowUps []string `json:"follow_ups,omitempty"`
+
+	// AuditTarget, when set, is what the call actually acted on and
+	// replaces the target from AuditAction in the audit log.
+	AuditTarget string `json:"-"`
+
 	// Err is the underlying error (not JSON serialized).
 	// Used for internal error handling and logging.
 	Err error `json:"-"`
@@ -120,3 +145,59 @@ func (tr *ToolResult) WithError(err error) *ToolResult {
 	tr.Err = err
 	return tr
 }
+
+// WithTitle sets a user-visible title and returns the result for chaining.
+func (tr *ToolResult) WithTitle(title string) *ToolResult {
+	tr.Title = title
+	return tr
+}
+
+// WithData attaches a machine-readable payload and returns the result for chaining.
+//
+// Example:
+//
+//	result := SilentResult(text).WithData(rows).WithTitle("5 events")
+func (tr *ToolResult) WithData(data any) *ToolResult {
+	tr.Data = data
+	return tr
+}
+
+// WithArtifacts records files produced by the tool and returns the result for chaining.
+func (tr *ToolResult) WithArtifacts(paths ...string) *ToolResult {
+	tr.Artifacts = append(tr.Artifacts, paths...)
+	return tr
+}
+
+// WithImages attaches images for the model to see and returns the result for chaining.
+func (tr *ToolResult) WithImages(paths ...string) *ToolResult {
+	tr.Images = append(tr.Images, paths...)
+	return tr
+}
+
+// WithFollowUps suggests tools to call next and returns the result for chaining.
+func (tr *ToolResult) WithFollowUps(tools ...string) *ToolResult {
+	tr.FollowUps = append(tr.FollowUps, tools...)
+	return tr
+}
+
+// Detail returns the structured fields as an activity detail map,
+// omitting the ones that are unset.
+func (tr *ToolResult) Detail() map[string]any {
+	d := map[string]any{}
+	if tr.Title != "" {
+		d["title"] = tr.Title
+	}
+	if tr.Data != nil {
+		d["data"] = tr.Data
+	}
+	if len(tr.Artifacts) > 0 {
+		d["artifacts"] = tr.Artifacts
+	}
+	if len(tr.Images) > 0 {
+		d["images"] = tr.Images
+	}
+	if len(tr.FollowUps) > 0 {
+		d["follow_ups"] = tr.FollowUps
+	}
+	return d
+}
@@ -203,3 +203,37 @@ func TestToolResultJSONStructure(t *testing.T) {
 		t.Errorf("Expected silent false, got %v", parsed["silent"])
 	}
 }
+
+func TestStructuredResultFields(t *testing.T) {
+	result := SilentResult("2 rows").
+		WithTitle("Two rows").
+		WithData([]map[string]any{{"a": 1}, {"a": 2}}).
+		WithArtifacts("out/report.md").
+		WithFollowUps("read_file")
+
+	detail := result.Detail()
+	if detail["title"] != "Two rows" {
+		t.Errorf("Expected title in detail, got %v", detail["title"])
+	}
+	if _, ok := detail["data"]; !ok {
+		t.Error("Expected data in detail")
+	}
+	if len(NewToolResult("plain").Detail()) != 0 {
+		t.Error("Expected empty detail for plain result")
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+	if arts, _ := decoded["artifacts"].([]any); len(arts) != 1 || arts[0] != "out/report.md" {
+		t.Errorf("Expected artifacts in JSON, got %v", decoded["artifacts"])
+	}
+
+	msg := BuildToolResultMessage("call_1", "write_file", result)
+	if msg.Content != "2 rows\n\nSuggested next tools: read_file" {
+		t.Errorf("Unexpected tool message content: %q", msg.Content)
+	}
+}
@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/utils"
+)
+
+const (
+	screenshotTimeout = 30 * time.Second
+	screenshotMaxSide = 1920 // longer side after downscaling, in pixels
+	screenshotMaxWait = 10   // seconds of delay_seconds allowed
+)
+
+// defaultScreenshotCommands are the capture commands per OS, whole screen
+// and focused window. {file} is replaced with the (quoted) output path.
+var defaultScreenshotCommands = map[string][2]string{
+	"darwin": {"screencapture -x -t png {file}", ""},
+	"linux": {
+		`if [ -n "$WAYLAND_DISPLAY" ] && command -v grim >/dev/null; then grim {file}; ` +
+			`elif command -v gnome-screenshot >/dev/null; then gnome-screenshot -f {file}; ` +
+			`elif command -v scrot >/dev/null; then scrot -o {file}; ` +
+			`else import -window root {file}; fi`,
+		`if command -v scrot >/dev/null; then scrot -u -o {file}; ` +
+			`elif command -v gnome-screenshot >/dev/null; then gnome-screenshot -w -f {file}; ` +
+			`else import -window "$(xdotool getactivewindow)" {file}; fi`,
+	},
+	"windows": {
+		`Add-Type -AssemblyName System.Windows.Forms,System.Drawing; ` +
+			`$b = [System.Windows.Forms.SystemInformation]::VirtualScreen; ` +
+			`$bmp = New-Object System.Drawing.Bitmap $b.Width, $b.Height; ` +
+			`[System.Drawing.Graphics]::FromImage($bmp).CopyFromScreen($b.Location, [System.Drawing.Point]::Empty, $b.Size); ` +
+			`$bmp.Save({file}, [System.Drawing.Imaging.ImageFormat]::Png)`,
+		"",
+	},
+}
+
+// ScreenshotTool captures the host's screen, or its focused window, into
+// the media directory and shows it to the model.
+type ScreenshotTool struct {
+	mediaDir string
+	screen   string
+	window   string
+	goos     string
+}
+
+func NewScreenshotTool(cfg config.ScreenshotConfig, mediaDir string) *ScreenshotTool {
+	t := &ScreenshotTool{mediaDir: mediaDir, screen: cfg.Command, window: cfg.WindowCommand, goos: runtime.GOOS}
+	defaults := defaultScreenshotCommands[t.goos]
+	if t.screen == "" {
+		t.screen = defaults[0]
+	}
+	if t.window == "" {
+		t.window = defaults[1]
+	}
+	return t
+}
+
+func (t *ScreenshotTool) Name() string {
+	return "screenshot"
+}
+
+func (t *ScreenshotTool) Description() string {
+	return "Take a screenshot of the user's computer (the machine the agent runs on) and look at it. " +
+		"Use when the user asks you to look at their screen, e.g. to explain an error dialog. " +
+		"target: screen (default) or window (the focused window)."
+}
+
+func (t *ScreenshotTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"target": map[string]any{
+				"type":        "string",
+				"enum":        []string{"screen", "window"},
+				"description": "Capture the whole screen or only the focused window.",
+			},
+			"delay_seconds": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Wait before capturing, so the user can switch windows (0-%d).", screenshotMaxWait),
+			},
+		},
+	}
+}
+
+// SideEffect reports that ScreenshotTool calls only read.
+func (t *ScreenshotTool) SideEffect(map[string]any) bool { return false }
+
+func (t *ScreenshotTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	target, _ := args["target"].(string)
+	command, key := t.screen, "command"
+	switch target {
+	case "", "screen":
+		target = "screen"
+	case "window":
+		command, key = t.window, "window_command"
+	default:
+		return ErrorResult(fmt.Sprintf("unknown target: %s", target))
+	}
+	if command == "" {
+		return ErrorResult(fmt.Sprintf("no %s capture command on %s; set tools.screenshot.%s", target, t.goos, key))
+	}
+
+	if delay, ok := args["delay_seconds"].(float64); ok && delay > 0 {
+		select {
+		case <-time.After(time.Duration(min(delay, screenshotMaxWait)) * time.Second):
+		case <-ctx.Done():
+			return ErrorResult("cancelled")
+		}
+	}
+
+	if err := os.MkdirAll(t.mediaDir, 0700); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create media directory: %v", err))
+	}
+	raw := filepath.Join(t.mediaDir, fmt.Sprintf("capture-%d.png", time.Now().UnixNano()))
+	defer os.Remove(raw)
+	if err := t.capture(ctx, command, raw); err != nil {
+		return ErrorResult(fmt.Sprintf("screenshot failed: %v", err)).WithError(err)
+	}
+
+	path := filepath.Join(t.mediaDir, "screenshot-"+time.Now().Format("20060102-150405")+".jpg")
+	w, h, err := saveJPEG(raw, path, screenshotMaxSide)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("screenshot failed: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Captured the %s (%dx%d): %s", target, w, h, path)).
+		WithArtifacts(path).
+		WithImages(path)
+}
+
+// capture runs command with {file} set to path.
+func (t *ScreenshotTool) capture(ctx context.Context, command, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, screenshotTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if t.goos == "windows" {
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command",
+			strings.ReplaceAll(command, "{file}", "'"+strings.ReplaceAll(path, "'", "''")+"'"))
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", strings.ReplaceAll(command, "{file}", shellQuote(path)))
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", err, utils.Truncate(msg, 500))
+		}
+		return err
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() == 0 {
+		return fmt.Errorf("the capture command wrote no image (is a display available?)")
+	}
+	return nil
+}
+
+// saveJPEG re-encodes the image at src as a JPEG at dst, scaled down so
+// its longer side is at most maxSide, and returns the final size.
+func saveJPEG(src, dst string, maxSide int) (int, int, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, 0, fmt.Errorf("decode capture: %w", err)
+	}
+	img = downscale(img, maxSide)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := jpeg.Encode(out, img, &jpeg.Options{Quality: 85}); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return 0, 0, err
+	}
+	b := img.Bounds()
+	return b.Dx(), b.Dy(), out.Close()
+}
+
+// downscale shrinks img by box sampling so its longer side is maxSide.
+func downscale(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if max(w, h) <= maxSide {
+		return img
+	}
+	nw, nh := max(w*maxSide/max(w, h), 1), max(h*maxSide/max(w, h), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := range dst.Rect.Dy() {
+		y0, y1 := b.Min.Y+y*h/nh, b.Min.Y+max((y+1)*h/nh, y*h/nh+1)
+		for x := range dst.Rect.Dx() {
+			x0, x1 := b.Min.X+x*w/nw, b.Min.X+max((x+1)*w/nw, x*w/nw+1)
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := img.At(sx, sy).RGBA()
+					r, g, bl, n = r+pr, g+pg, bl+pb, n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n>>8), uint8(g/n>>8), uint8(bl/n>>8), 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localagent/pkg/config"
+)
+
+func TestScreenshotTool(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "screen.png")
+	img := image.NewRGBA(image.Rect(0, 0, 3840, 200))
+	for x := range 3840 {
+		for y := range 200 {
+			img.Set(x, y, color.RGBA{200, 30, 30, 255})
+		}
+	}
+	f, _ := os.Create(src)
+	png.Encode(f, img)
+	f.Close()
+
+	media := filepath.Join(dir, "media")
+	tool := NewScreenshotTool(config.ScreenshotConfig{Command: "cp " + shellQuote(src) + " {file}"}, media)
+	tool.goos = "linux"
+	tool.window = ""
+
+	res := tool.Execute(context.Background(), map[string]any{})
+	if res.IsError {
+		t.Fatal(res.ForLLM)
+	}
+	if len(res.Images) != 1 || !strings.Contains(res.ForLLM, "1920x100") {
+		t.Fatalf("result = %+v", res)
+	}
+	out, err := os.Open(res.Images[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	shot, err := jpeg.Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := shot.At(960, 50).RGBA(); r>>8 < 180 {
+		t.Errorf("colour lost in downscaling: r=%d", r>>8)
+	}
+	if entries, _ := os.ReadDir(media); len(entries) != 1 {
+		t.Errorf("media dir has %d files, want only the screenshot", len(entries))
+	}
+
+	if res := tool.Execute(context.Background(), map[string]any{"target": "window"}); !res.IsError || !strings.Contains(res.ForLLM, "window_command") {
+		t.Errorf("window without a command = %+v", res)
+	}
+	tool.screen = "true"
+	if res := tool.Execute(context.Background(), map[string]any{}); !res.IsError || !strings.Contains(res.ForLLM, "no image") {
+		t.Errorf("command writing nothing = %+v", res)
+	}
+}
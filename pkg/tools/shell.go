@@ -59,6 +59,11 @@ func (t *ExecTool) Parameters() map[string]any {
 	}
 }
 
+func (t *ExecTool) AuditAction(args map[string]any) (string, string) {
+	command, _ := args["command"].(string)
+	return "exec", command
+}
+
 func (t *ExecTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	command, ok := args["command"].(string)
 	if !ok {
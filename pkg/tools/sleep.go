@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"localagent/pkg/sleep"
+	"localagent/pkg/when"
+)
+
+// SleepTool records when the user goes to bed and gets up, and sets the
+// sleep schedule the heartbeat uses as its quiet hours.
+type SleepTool struct {
+	tracker *sleep.Tracker
+}
+
+func NewSleepTool(tracker *sleep.Tracker) *SleepTool {
+	return &SleepTool{tracker: tracker}
+}
+
+func (t *SleepTool) Name() string {
+	return "sleep"
+}
+
+func (t *SleepTool) Description() string {
+	return "The user's sleep schedule. Proactive messages are held while they sleep. " +
+		"Use asleep/awake when the user says they're going to bed or just got up (\"at\" for an earlier time, e.g. \"23:30\"). " +
+		"Actions: status (schedule, current state, recent nights), asleep, awake, set_schedule (bedtime and wake_time as HH:MM; both empty to go back to the configured or learned schedule)."
+}
+
+func (t *SleepTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"status", "asleep", "awake", "set_schedule"},
+				"description": "Action to perform.",
+			},
+			"at": map[string]any{
+				"type":        "string",
+				"description": "When it happened, e.g. \"23:30\" or \"30 minutes ago\" (for asleep, awake; default now).",
+			},
+			"bedtime": map[string]any{
+				"type":        "string",
+				"description": "Usual bedtime as HH:MM (for set_schedule).",
+			},
+			"wake_time": map[string]any{
+				"type":        "string",
+				"description": "Usual wake time as HH:MM (for set_schedule).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SideEffect reports whether the call changes anything; status only read.
+func (t *SleepTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "status")
+}
+
+func (t *SleepTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	now := when.Now()
+
+	switch action {
+	case "status":
+		return SilentResult(t.tracker.Status(now))
+
+	case "asleep", "awake":
+		at := now
+		if expr, _ := args["at"].(string); expr != "" {
+			r, err := when.Resolve(expr)
+			if err != nil {
+				return ErrorResult(fmt.Sprintf("invalid time %q: %v", expr, err))
+			}
+			at = r.Time
+			// "23:30" said in the morning means last night.
+			if at.After(now) {
+				at = at.AddDate(0, 0, -1)
+			}
+		}
+		if action == "asleep" {
+			if err := t.tracker.SetAsleep(at, sleep.SourceManual); err != nil {
+				return ErrorResult(fmt.Sprintf("failed to record: %v", err))
+			}
+			return SilentResult(fmt.Sprintf("Recorded asleep since %s. Non-urgent messages are held until morning.", at.Format("15:04")))
+		}
+		n, err := t.tracker.SetAwake(at, sleep.SourceManual)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to record: %v", err))
+		}
+		if n == nil {
+			return SilentResult(fmt.Sprintf("Recorded awake since %s.", at.Format("15:04")))
+		}
+		return SilentResult(fmt.Sprintf("Recorded awake since %s. Slept %s-%s (%s).",
+			at.Format("15:04"), n.Start().Format("15:04"), n.End().Format("15:04"), n.Duration().Round(time.Minute)))
+
+	case "set_schedule":
+		bedtime, _ := args["bedtime"].(string)
+		wake, _ := args["wake_time"].(string)
+		if err := t.tracker.SetSchedule(bedtime, wake); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(t.tracker.Status(now))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
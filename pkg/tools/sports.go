@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/config"
+	"localagent/pkg/sports"
+)
+
+const sportsListLimit = 5
+
+// SportsTool reports recent results and upcoming fixtures of the user's
+// followed teams or any team on TheSportsDB.
+type SportsTool struct {
+	client *sports.Client
+	teams  []config.SportsTeamConfig
+}
+
+func NewSportsTool(client *sports.Client, teams []config.SportsTeamConfig) *SportsTool {
+	return &SportsTool{client: client, teams: teams}
+}
+
+func (t *SportsTool) Name() string {
+	return "sports"
+}
+
+func (t *SportsTool) Description() string {
+	desc := "Recent results and upcoming fixtures of a sports team (football, basketball, hockey and more, from TheSportsDB)."
+	if len(t.teams) > 0 {
+		names := make([]string, len(t.teams))
+		for i, team := range t.teams {
+			names[i] = team.Name
+		}
+		desc += " The user follows " + strings.Join(names, ", ") + "."
+	}
+	return desc
+}
+
+func (t *SportsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"team": map[string]any{
+				"type":        "string",
+				"description": "Team name; default the followed teams.",
+			},
+			"show": map[string]any{
+				"type":        "string",
+				"enum":        []string{"results", "fixtures", "both"},
+				"description": "Recent results, upcoming fixtures or both (default).",
+			},
+		},
+	}
+}
+
+func (t *SportsTool) DeclaredDomains() []string {
+	return []string{sports.Domain}
+}
+
+// SideEffect reports that SportsTool calls only read.
+func (t *SportsTool) SideEffect(map[string]any) bool { return false }
+
+func (t *SportsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	show, _ := args["show"].(string)
+	if show == "" {
+		show = "both"
+	}
+	if show != "results" && show != "fixtures" && show != "both" {
+		return ErrorResult(fmt.Sprintf("unknown show %q (want results, fixtures or both)", show))
+	}
+	teams := t.teams
+	if name, _ := args["team"].(string); strings.TrimSpace(name) != "" {
+		teams = []config.SportsTeamConfig{{Name: strings.TrimSpace(name)}}
+		for _, team := range t.teams {
+			if strings.EqualFold(team.Name, strings.TrimSpace(name)) {
+				teams = []config.SportsTeamConfig{team}
+			}
+		}
+	}
+	if len(teams) == 0 {
+		return ErrorResult("team is required (no followed teams are configured)")
+	}
+
+	var reports []string
+	for _, cfg := range teams {
+		report, err := t.report(ctx, cfg, show)
+		if err != nil {
+			if len(teams) == 1 {
+				return ErrorResult(fmt.Sprintf("%s: %v", cfg.Name, err)).WithError(err)
+			}
+			report = fmt.Sprintf("%s: lookup failed: %v", cfg.Name, err)
+		}
+		reports = append(reports, report)
+	}
+	return SilentResult(strings.Join(reports, "\n\n"))
+}
+
+func (t *SportsTool) report(ctx context.Context, cfg config.SportsTeamConfig, show string) (string, error) {
+	team, err := t.client.Resolve(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	sb.WriteString(team.Name)
+	if team.League != "" {
+		fmt.Fprintf(&sb, " (%s)", team.League)
+	}
+	list := func(title string, matches []sports.Match) {
+		fmt.Fprintf(&sb, "\n%s:", title)
+		if len(matches) == 0 {
+			sb.WriteString(" none listed")
+		}
+		for _, m := range matches[:min(len(matches), sportsListLimit)] {
+			sb.WriteString("\n- " + m.Format())
+		}
+	}
+	if show != "fixtures" {
+		last, err := t.client.Last(ctx, team.ID)
+		if err != nil {
+			return "", err
+		}
+		list("Recent results", last)
+	}
+	if show != "results" {
+		next, err := t.client.Next(ctx, team.ID)
+		if err != nil {
+			return "", err
+		}
+		list("Upcoming", next)
+	}
+	return sb.String(), nil
+}
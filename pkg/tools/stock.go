@@ -42,6 +42,9 @@ func (t *StockTool) DeclaredDomains() []string {
 	return []string{"query2.finance.yahoo.com", "fc.yahoo.com"}
 }
 
+// SideEffect reports that StockTool calls only read.
+func (t *StockTool) SideEffect(map[string]any) bool { return false }
+
 func (t *StockTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	symbol, ok := args["symbol"].(string)
 	if !ok || symbol == "" {
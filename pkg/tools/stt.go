@@ -11,6 +11,9 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"localagent/pkg/errs"
+	"localagent/pkg/httpclient"
 )
 
 type TranscribeAudioTool struct {
@@ -48,6 +51,9 @@ func (t *TranscribeAudioTool) Parameters() map[string]any {
 	}
 }
 
+// SideEffect reports that TranscribeAudioTool calls only read.
+func (t *TranscribeAudioTool) SideEffect(map[string]any) bool { return false }
+
 func (t *TranscribeAudioTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok || path == "" {
@@ -60,7 +66,7 @@ func (t *TranscribeAudioTool) Execute(ctx context.Context, args map[string]any)
 
 	text, err := TranscribeAudio(ctx, path, t.serviceURL, t.apiKey)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("transcription failed: %v", err))
+		return ErrorResult(fmt.Sprintf("transcription failed: %v", err)).WithError(err)
 	}
 
 	return SilentResult(text)
@@ -98,20 +104,20 @@ func TranscribeAudio(ctx context.Context, filePath, serviceURL, apiKey string) (
 		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	client := httpclient.New("transcribe_audio", httpclient.WithTimeout(120*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("service returned %d: %s", resp.StatusCode, string(body))
+		return "", errs.Wrap(errs.FromStatus(resp.StatusCode), fmt.Errorf("service returned %d: %s", resp.StatusCode, string(body)))
 	}
 
 	var result struct {
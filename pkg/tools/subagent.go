@@ -30,6 +30,7 @@ type SubagentManager struct {
 	bus           *bus.MessageBus
 	workspace     string
 	tools         *ToolRegistry
+	gate          ToolGate
 	maxIterations int
 	nextID        int
 }
@@ -53,6 +54,14 @@ func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
 	sm.tools = tools
 }
 
+// SetGate checks every tool call subagents make with gate, e.g. against
+// the role and autonomy level of the run that started them.
+func (sm *SubagentManager) SetGate(gate ToolGate) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.gate = gate
+}
+
 func (sm *SubagentManager) RegisterTool(tool Tool) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -108,6 +117,7 @@ func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, call
 
 	sm.mu.RLock()
 	tools := sm.tools
+	gate := sm.gate
 	maxIter := sm.maxIterations
 	sm.mu.RUnlock()
 
@@ -120,6 +130,7 @@ func (sm *SubagentManager) runTask(ctx context.Context, task *SubagentTask, call
 			"max_tokens":  4096,
 			"temperature": 0.7,
 		},
+		Gate: gate,
 	}, messages, task.OriginChannel, task.OriginChatID)
 
 	sm.mu.Lock()
@@ -256,6 +267,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	sm := t.manager
 	sm.mu.RLock()
 	tools := sm.tools
+	gate := sm.gate
 	maxIter := sm.maxIterations
 	sm.mu.RUnlock()
 
@@ -268,6 +280,7 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 			"max_tokens":  4096,
 			"temperature": 0.7,
 		},
+		Gate: gate,
 	}, messages, t.originChannel, t.originChatID)
 
 	if err != nil {
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -313,3 +315,47 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+// writeOnceProvider asks to write note.txt once, then answers with text.
+type writeOnceProvider struct{ MockLLMProvider }
+
+func (p *writeOnceProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]any) (*providers.LLMResponse, error) {
+	if last := messages[len(messages)-1]; last.Role == "tool" {
+		return &providers.LLMResponse{Content: last.Content}, nil
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "1", Name: "write_file", Arguments: map[string]any{"path": "note.txt", "content": "milk"}}}}, nil
+}
+
+func TestRunToolLoopGate(t *testing.T) {
+	dir := t.TempDir()
+	registry := NewToolRegistry()
+	registry.Register(NewWriteFileTool(dir))
+	registry.Register(NewReadFileTool(dir))
+
+	if !registry.SideEffect("write_file", nil) || registry.SideEffect("read_file", nil) {
+		t.Error("write_file should have side effects and read_file should not")
+	}
+
+	var gated []string
+	result, err := RunToolLoop(context.Background(), ToolLoopConfig{
+		Provider:      &writeOnceProvider{},
+		Tools:         registry,
+		MaxIterations: 3,
+		Gate: func(_ context.Context, name string, args map[string]any) *ToolResult {
+			gated = append(gated, name)
+			if registry.SideEffect(name, args) {
+				return ErrorResult("refused")
+			}
+			return nil
+		},
+	}, []providers.Message{{Role: "user", Content: "note milk"}}, "cli", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Content != "refused" || len(gated) != 1 {
+		t.Errorf("content = %q, gate calls = %v", result.Content, gated)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "note.txt")); err == nil {
+		t.Error("gated write_file ran")
+	}
+}
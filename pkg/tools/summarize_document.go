@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"localagent/pkg/docsum"
+	"localagent/pkg/utils"
+)
+
+// SummarizeDocumentTool summarizes PDFs and text files that don't fit in
+// the context, leaving the chunks in the workspace for follow-up reads.
+type SummarizeDocumentTool struct {
+	workspace  string
+	summarizer *docsum.Summarizer
+	pdfURL     string
+	pdfKey     string
+}
+
+// NewSummarizeDocumentTool converts PDFs with the pdf_to_text service at
+// pdfURL; without it only text files are accepted.
+func NewSummarizeDocumentTool(workspace string, summarizer *docsum.Summarizer, pdfURL, pdfKey string) *SummarizeDocumentTool {
+	return &SummarizeDocumentTool{workspace: workspace, summarizer: summarizer, pdfURL: pdfURL, pdfKey: pdfKey}
+}
+
+func (t *SummarizeDocumentTool) Name() string {
+	return "summarize_document"
+}
+
+func (t *SummarizeDocumentTool) Description() string {
+	return "Summarize a long PDF or text file that is too large to read whole. Returns the summary and the saved chunk files with a summary of each; read a chunk with read_file to answer detailed questions. Summaries are cached, so calling again is cheap."
+}
+
+func (t *SummarizeDocumentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to the PDF or text file (relative to workspace or absolute)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// SideEffect reports that SummarizeDocumentTool calls only read.
+func (t *SummarizeDocumentTool) SideEffect(map[string]any) bool { return false }
+
+func (t *SummarizeDocumentTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return ErrorResult("path is required")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.workspace, path)
+	}
+
+	var text string
+	if utils.IsPDFFile(path) {
+		if t.pdfURL == "" {
+			return ErrorResult("PDFs need tools.pdf.url (the pdf_to_text service)")
+		}
+		converted, err := ConvertPDF(ctx, path, t.pdfURL, t.pdfKey)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("PDF conversion failed: %v", err)).WithError(err)
+		}
+		text = converted
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err)).WithError(err)
+		}
+		text = string(data)
+	}
+
+	doc, cached, err := t.summarizer.Summarize(ctx, filepath.Base(path), text)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("summarizing %s failed: %v", filepath.Base(path), err)).WithError(err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Summary of %s (%d chunks", doc.Name, len(doc.Chunks))
+	if cached {
+		sb.WriteString(", cached")
+	}
+	fmt.Fprintf(&sb, "):\n\n%s\n\nChunks (read_file for details):", doc.Summary)
+	for _, c := range doc.Chunks {
+		fmt.Fprintf(&sb, "\n- %s: %s", c.Path, utils.Truncate(strings.Join(strings.Fields(c.Summary), " "), 150))
+	}
+	return SilentResult(sb.String())
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"localagent/pkg/todo"
+	"localagent/pkg/when"
 )
 
 type baseTodoTool struct {
@@ -58,11 +60,11 @@ func (t *QueryTasksTool) Parameters() map[string]any {
 			},
 			"dueAfter": map[string]any{
 				"type":        "string",
-				"description": "Only tasks with due date >= this (YYYY-MM-DD).",
+				"description": "Only tasks with due date >= this (YYYY-MM-DD or e.g. 'today').",
 			},
 			"dueBefore": map[string]any{
 				"type":        "string",
-				"description": "Only tasks with due date <= this (YYYY-MM-DD).",
+				"description": "Only tasks with due date <= this (YYYY-MM-DD or e.g. 'next sunday').",
 			},
 			"limit": map[string]any{
 				"type":        "number",
@@ -83,6 +85,9 @@ type queryResult struct {
 	Links  []todo.Link  `json:"links,omitempty"`
 }
 
+// SideEffect reports that QueryTasksTool calls only read.
+func (t *QueryTasksTool) SideEffect(map[string]any) bool { return false }
+
 func (t *QueryTasksTool) Execute(_ context.Context, args map[string]any) *ToolResult {
 	q := todo.TaskQuery{}
 
@@ -105,10 +110,18 @@ func (t *QueryTasksTool) Execute(_ context.Context, args map[string]any) *ToolRe
 		q.Search = v
 	}
 	if v, ok := args["dueAfter"].(string); ok {
-		q.DueAfter = v
+		d, err := normalizeDueDate(v)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid dueAfter: %v", err))
+		}
+		q.DueAfter = d
 	}
 	if v, ok := args["dueBefore"].(string); ok {
-		q.DueBefore = v
+		d, err := normalizeDueDate(v)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid dueBefore: %v", err))
+		}
+		q.DueBefore = d
 	}
 	if v, ok := args["limit"].(float64); ok {
 		q.Limit = int(v)
@@ -132,7 +145,9 @@ func (t *QueryTasksTool) Execute(_ context.Context, args map[string]any) *ToolRe
 	}
 
 	data, _ := json.MarshalIndent(result, "", "  ")
-	return SilentResult(string(data))
+	return SilentResult(string(data)).
+		WithTitle(fmt.Sprintf("%d task(s)", len(result.Tasks))).
+		WithData(result.Tasks)
 }
 
 // --- add_task ---
@@ -165,7 +180,7 @@ func (t *AddTaskTool) Parameters() map[string]any {
 			},
 			"due": map[string]any{
 				"type":        "string",
-				"description": "Due date as YYYY-MM-DD.",
+				"description": "Due date as YYYY-MM-DD or YYYY-MM-DDTHH:MM. Natural expressions like 'next friday' or 'tomorrow 5pm' are also accepted.",
 			},
 			"recurrence": map[string]any{
 				"type":        "string",
@@ -199,7 +214,11 @@ func (t *AddTaskTool) Execute(_ context.Context, args map[string]any) *ToolResul
 		task.Priority = v
 	}
 	if v, ok := args["due"].(string); ok {
-		task.Due = v
+		due, err := normalizeDue(v)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid due: %v", err))
+		}
+		task.Due = due
 	}
 	if v, ok := args["recurrence"].(string); ok {
 		task.Recurrence = v
@@ -262,7 +281,7 @@ func (t *ModifyTasksTool) Parameters() map[string]any {
 			},
 			"due": map[string]any{
 				"type":        "string",
-				"description": "New due date as YYYY-MM-DD (action=update).",
+				"description": "New due date as YYYY-MM-DD or YYYY-MM-DDTHH:MM, or a natural expression like 'next monday' (action=update).",
 			},
 			"recurrence": map[string]any{
 				"type":        "string",
@@ -321,6 +340,13 @@ func (t *ModifyTasksTool) Execute(_ context.Context, args map[string]any) *ToolR
 		result.Failed = len(errs)
 
 	case "update":
+		if v, ok := args["due"].(string); ok {
+			due, err := normalizeDue(v)
+			if err != nil {
+				return ErrorResult(fmt.Sprintf("invalid due: %v", err))
+			}
+			args["due"] = due
+		}
 		patch := buildPatch(args)
 		if len(patch) == 0 {
 			return ErrorResult("no fields to update — provide at least one of: title, description, priority, due, recurrence, status, tags, parentId")
@@ -344,6 +370,39 @@ func (t *ModifyTasksTool) Execute(_ context.Context, args map[string]any) *ToolR
 	return SilentResult(string(data))
 }
 
+// normalizeDue converts a due value into the stored YYYY-MM-DD or
+// YYYY-MM-DDTHH:MM form, resolving natural expressions via pkg/when.
+// An empty value is kept so updates can clear the due date.
+func normalizeDue(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		return s, nil
+	}
+	if _, err := time.Parse("2006-01-02T15:04", s); err == nil {
+		return s, nil
+	}
+	r, err := when.Resolve(s)
+	if err != nil {
+		return "", err
+	}
+	if r.DateOnly {
+		return r.Time.Format("2006-01-02"), nil
+	}
+	return r.Time.In(when.Location()).Format("2006-01-02T15:04"), nil
+}
+
+// normalizeDueDate is normalizeDue for date-only filters.
+func normalizeDueDate(s string) (string, error) {
+	due, err := normalizeDue(s)
+	if len(due) > len("2006-01-02") {
+		due = due[:len("2006-01-02")]
+	}
+	return due, err
+}
+
 func buildPatch(args map[string]any) map[string]any {
 	patch := make(map[string]any)
 	for _, key := range []string{"title", "description", "priority", "due", "recurrence", "status", "parentId"} {
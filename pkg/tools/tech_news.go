@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"localagent/pkg/httpclient"
 )
 
 type NewsTool struct {
@@ -53,6 +54,9 @@ func (t *NewsTool) DeclaredDomains() []string {
 	return []string{"hn.algolia.com", "lobste.rs"}
 }
 
+// SideEffect reports that NewsTool calls only read.
+func (t *NewsTool) SideEffect(map[string]any) bool { return false }
+
 func (t *NewsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	source := "all"
 	if s, ok := args["source"].(string); ok && s != "" {
@@ -70,13 +74,13 @@ func (t *NewsTool) Execute(ctx context.Context, args map[string]any) *ToolResult
 	case "hackernews":
 		hn, err := t.fetchHackerNews(ctx, count)
 		if err != nil {
-			return ErrorResult(fmt.Sprintf("failed to fetch Hacker News: %v", err))
+			return ErrorResult(fmt.Sprintf("failed to fetch Hacker News: %v", err)).WithError(err)
 		}
 		sections = append(sections, hn)
 	case "lobsters":
 		lb, err := t.fetchLobsters(ctx, count)
 		if err != nil {
-			return ErrorResult(fmt.Sprintf("failed to fetch Lobsters: %v", err))
+			return ErrorResult(fmt.Sprintf("failed to fetch Lobsters: %v", err)).WithError(err)
 		}
 		sections = append(sections, lb)
 	case "all":
@@ -105,14 +109,14 @@ func (t *NewsTool) fetchHackerNews(ctx context.Context, count int) (string, erro
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := httpclient.New("tech_news", httpclient.WithTimeout(10*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
@@ -154,14 +158,14 @@ func (t *NewsTool) fetchLobsters(ctx context.Context, count int) (string, error)
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := httpclient.New("tech_news", httpclient.WithTimeout(10*time.Second))
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := httpclient.ReadBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/templates"
+)
+
+// RunTemplateTool expands a saved prompt template so the agent can carry
+// out a recurring request (e.g. a weekly review) in one step.
+type RunTemplateTool struct {
+	store *templates.Store
+}
+
+func NewRunTemplateTool(store *templates.Store) *RunTemplateTool {
+	return &RunTemplateTool{store: store}
+}
+
+func (t *RunTemplateTool) Name() string {
+	return "run_template"
+}
+
+func (t *RunTemplateTool) Description() string {
+	return "Run a saved prompt template from the workspace templates directory. Omit name to list templates and their placeholders. Returns the expanded instructions, which you should then carry out."
+}
+
+func (t *RunTemplateTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Template name, e.g. weekly-review. Omit to list templates.",
+			},
+			"values": map[string]any{
+				"type":                 "object",
+				"description":          "Placeholder values, e.g. {\"project\": \"website\"}.",
+				"additionalProperties": true,
+			},
+		},
+	}
+}
+
+// SideEffect reports that RunTemplateTool calls only read.
+func (t *RunTemplateTool) SideEffect(map[string]any) bool { return false }
+
+func (t *RunTemplateTool) Execute(_ context.Context, args map[string]any) *ToolResult {
+	name, _ := args["name"].(string)
+	if name == "" {
+		return SilentResult(t.list())
+	}
+
+	tmpl, err := t.store.Get(name)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	values := map[string]string{}
+	if raw, ok := args["values"].(map[string]any); ok {
+		for k, v := range raw {
+			values[k] = fmt.Sprint(v)
+		}
+	}
+	rendered, err := tmpl.Render(values)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	return SilentResult(fmt.Sprintf("Template %q expanded. Carry out these instructions now:\n\n%s", name, rendered)).
+		WithTitle(name)
+}
+
+func (t *RunTemplateTool) list() string {
+	list := t.store.List()
+	if len(list) == 0 {
+		return "No templates saved."
+	}
+	var b strings.Builder
+	b.WriteString("Templates:\n")
+	for _, tmpl := range list {
+		fmt.Fprintf(&b, "- %s", tmpl.Name)
+		if tmpl.Description != "" {
+			fmt.Fprintf(&b, ": %s", tmpl.Description)
+		}
+		var fields []string
+		for _, p := range tmpl.Placeholders() {
+			if p.Required {
+				fields = append(fields, p.Name)
+			} else {
+				fields = append(fields, p.Name+"?")
+			}
+		}
+		if len(fields) > 0 {
+			fmt.Fprintf(&b, " (placeholders: %s)", strings.Join(fields, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/cron"
+	"localagent/pkg/state"
+)
+
+const (
+	maxTimer           = 24 * time.Hour // longer waits belong in cron reminders
+	stopwatchNamespace = "stopwatches"
+)
+
+// stopwatch is a running stopwatch, kept in the state store.
+type stopwatch struct {
+	Name      string `json:"name"`
+	StartedMS int64  `json:"started_ms"`
+}
+
+// TimerTool starts kitchen-style timers and stopwatches. Timers are precise
+// cron jobs, so they ring to the second and survive a restart.
+type TimerTool struct {
+	cron    *cron.CronService
+	state   *state.Manager
+	now     func() time.Time
+	mu      sync.RWMutex
+	channel string
+	chatID  string
+}
+
+func NewTimerTool(cronService *cron.CronService, workspace string) *TimerTool {
+	return &TimerTool{cron: cronService, state: state.NewManager(workspace), now: time.Now}
+}
+
+func (t *TimerTool) Name() string {
+	return "timer"
+}
+
+func (t *TimerTool) Description() string {
+	return "Kitchen timers and stopwatches, to the second. " +
+		"start with a duration (Go format: 90s, 10m, 1h30m) sets a timer that messages this chat when it runs out, or every time with repeat; " +
+		"start without a duration starts a stopwatch. list shows what is running, cancel stops a timer or stopwatch by name " +
+		"(a stopwatch reports its time). For reminders at a time of day, use cron."
+}
+
+func (t *TimerTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"start", "list", "cancel"},
+				"description": "Action to perform.",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "What the timer is for, e.g. \"pasta\" (for start and cancel).",
+			},
+			"duration": map[string]any{
+				"type":        "string",
+				"description": "How long, e.g. \"8m\" or \"1m30s\" (for start; omit for a stopwatch).",
+			},
+			"repeat": map[string]any{
+				"type":        "boolean",
+				"description": "Ring every duration until cancelled (for start).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *TimerTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// SideEffect reports whether the call changes anything; list only read.
+func (t *TimerTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "list")
+}
+
+func (t *TimerTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+	switch action {
+	case "start":
+		raw, _ := args["duration"].(string)
+		repeat, _ := args["repeat"].(bool)
+		if strings.TrimSpace(raw) == "" {
+			return t.startStopwatch(name)
+		}
+		return t.startTimer(name, raw, repeat)
+	case "list":
+		return t.list()
+	case "cancel":
+		return t.cancel(name)
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
+
+func (t *TimerTool) startTimer(name, raw string, repeat bool) *ToolResult {
+	d, err := time.ParseDuration(strings.ReplaceAll(strings.TrimSpace(raw), " ", ""))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid duration %q: use e.g. 90s, 10m or 1h30m", raw))
+	}
+	if d < time.Second || d > maxTimer {
+		return ErrorResult(fmt.Sprintf("duration must be between 1s and %s; use cron for longer waits", cron.ShortDuration(maxTimer)))
+	}
+	if name == "" {
+		name = "timer"
+	}
+	if t.taken(name) {
+		return ErrorResult(fmt.Sprintf("a timer or stopwatch named %q is already running", name))
+	}
+
+	t.mu.RLock()
+	channel, chatID := t.channel, t.chatID
+	t.mu.RUnlock()
+	now := t.now()
+	if _, err := t.cron.AddJob(cron.NewTimer(name, d, repeat, channel, chatID, now)); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to start the timer: %v", err)).WithError(err)
+	}
+	if repeat {
+		return SilentResult(fmt.Sprintf("Timer %q rings every %s, first at %s.", name, cron.ShortDuration(d), now.Add(d).Format("15:04:05")))
+	}
+	return SilentResult(fmt.Sprintf("Timer %q set for %s; it rings at %s.", name, cron.ShortDuration(d), now.Add(d).Format("15:04:05")))
+}
+
+func (t *TimerTool) startStopwatch(name string) *ToolResult {
+	if name == "" {
+		name = "stopwatch"
+	}
+	if t.taken(name) {
+		return ErrorResult(fmt.Sprintf("a timer or stopwatch named %q is already running", name))
+	}
+	sw := stopwatch{Name: name, StartedMS: t.now().UnixMilli()}
+	if err := t.state.Set(stopwatchNamespace, strings.ToLower(name), sw); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to save: %v", err)).WithError(err)
+	}
+	return SilentResult(fmt.Sprintf("Stopwatch %q started.", name))
+}
+
+func (t *TimerTool) list() *ToolResult {
+	now := t.now()
+	var lines []string
+	for _, job := range t.timers() {
+		left := time.UnixMilli(*job.State.NextRunAtMS).Sub(now)
+		line := fmt.Sprintf("- timer %q: %s left", cron.TimerLabel(&job), cron.ShortDuration(max(left, 0)))
+		if job.Schedule.Kind == "every" {
+			line += fmt.Sprintf(" (repeats every %s)", cron.ShortDuration(time.Duration(*job.Schedule.EveryMS)*time.Millisecond))
+		}
+		lines = append(lines, line)
+	}
+	for _, sw := range t.stopwatches() {
+		lines = append(lines, fmt.Sprintf("- stopwatch %q: %s", sw.Name, cron.ShortDuration(now.Sub(time.UnixMilli(sw.StartedMS)))))
+	}
+	if len(lines) == 0 {
+		return SilentResult("No timers or stopwatches running.")
+	}
+	return SilentResult(strings.Join(lines, "\n"))
+}
+
+func (t *TimerTool) cancel(name string) *ToolResult {
+	if name == "" {
+		return ErrorResult("name is required for cancel")
+	}
+	for _, job := range t.timers() {
+		if strings.EqualFold(cron.TimerLabel(&job), name) {
+			t.cron.RemoveJob(job.ID)
+			return SilentResult(fmt.Sprintf("Timer %q cancelled.", cron.TimerLabel(&job)))
+		}
+	}
+	for _, sw := range t.stopwatches() {
+		if strings.EqualFold(sw.Name, name) {
+			if err := t.state.Delete(stopwatchNamespace, strings.ToLower(sw.Name)); err != nil {
+				return ErrorResult(fmt.Sprintf("failed to save: %v", err)).WithError(err)
+			}
+			elapsed := t.now().Sub(time.UnixMilli(sw.StartedMS))
+			return SilentResult(fmt.Sprintf("Stopwatch %q stopped at %s.", sw.Name, cron.ShortDuration(elapsed)))
+		}
+	}
+	return ErrorResult(fmt.Sprintf("no timer or stopwatch named %q", name))
+}
+
+func (t *TimerTool) taken(name string) bool {
+	return slices.ContainsFunc(t.timers(), func(job cron.CronJob) bool { return strings.EqualFold(cron.TimerLabel(&job), name) }) ||
+		slices.ContainsFunc(t.stopwatches(), func(sw stopwatch) bool { return strings.EqualFold(sw.Name, name) })
+}
+
+// timers lists the pending timers, soonest first.
+func (t *TimerTool) timers() []cron.CronJob {
+	var timers []cron.CronJob
+	for _, job := range t.cron.ListJobs(false) {
+		if cron.IsTimer(&job) && job.State.NextRunAtMS != nil {
+			timers = append(timers, job)
+		}
+	}
+	slices.SortFunc(timers, func(a, b cron.CronJob) int {
+		return int(*a.State.NextRunAtMS - *b.State.NextRunAtMS)
+	})
+	return timers
+}
+
+// stopwatches lists the running stopwatches, oldest first.
+func (t *TimerTool) stopwatches() []stopwatch {
+	var sws []stopwatch
+	for _, key := range t.state.Keys(stopwatchNamespace) {
+		var sw stopwatch
+		if ok, err := t.state.Get(stopwatchNamespace, key, &sw); err == nil && ok {
+			sws = append(sws, sw)
+		}
+	}
+	slices.SortFunc(sws, func(a, b stopwatch) int { return int(a.StartedMS - b.StartedMS) })
+	return sws
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/cron"
+)
+
+func TestTimerTool(t *testing.T) {
+	ws := t.TempDir()
+	cs := cron.NewCronService(filepath.Join(ws, "cron", "jobs.json"), nil)
+	tool := NewTimerTool(cs, ws)
+	now := time.Now().Truncate(time.Second) // the cron service compares with the real clock
+	tool.now = func() time.Time { return now }
+	tool.SetContext("telegram", "42")
+	run := func(args map[string]any) *ToolResult {
+		t.Helper()
+		return tool.Execute(context.Background(), args)
+	}
+
+	res := run(map[string]any{"action": "start", "name": "pasta", "duration": "8m"})
+	if res.IsError || !strings.Contains(res.ForLLM, now.Add(8*time.Minute).Format("15:04:05")) {
+		t.Fatalf("start = %+v", res)
+	}
+	if res := run(map[string]any{"action": "start", "name": "Pasta", "duration": "1m"}); !res.IsError {
+		t.Error("duplicate name accepted")
+	}
+	if res := run(map[string]any{"action": "start", "duration": "3 days"}); !res.IsError {
+		t.Error("bad duration accepted")
+	}
+	if res := run(map[string]any{"action": "start", "name": "run"}); res.IsError {
+		t.Fatalf("stopwatch = %+v", res)
+	}
+
+	jobs := cs.ListJobs(false)
+	if len(jobs) != 1 || !jobs[0].Precise || jobs[0].Delivery.Channel != "telegram" || jobs[0].Delivery.To != "42" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+
+	now = now.Add(3*time.Minute + 12*time.Second)
+	res = run(map[string]any{"action": "list"})
+	if !strings.Contains(res.ForLLM, `timer "pasta": 4m48s left`) || !strings.Contains(res.ForLLM, `stopwatch "run": 3m12s`) {
+		t.Errorf("list = %q", res.ForLLM)
+	}
+
+	if res := run(map[string]any{"action": "cancel", "name": "RUN"}); res.IsError || !strings.Contains(res.ForLLM, "3m12s") {
+		t.Errorf("cancel stopwatch = %+v", res)
+	}
+	if res := run(map[string]any{"action": "cancel", "name": "pasta"}); res.IsError {
+		t.Errorf("cancel timer = %+v", res)
+	}
+	if res := run(map[string]any{"action": "list"}); res.ForLLM != "No timers or stopwatches running." {
+		t.Errorf("list after cancel = %q", res.ForLLM)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"localagent/pkg/logger"
 	"localagent/pkg/providers"
@@ -15,8 +16,14 @@ type ToolLoopConfig struct {
 	Tools         *ToolRegistry
 	MaxIterations int
 	LLMOptions    map[string]any
+	// Gate, when set, is asked before each call; a non-nil result is
+	// returned to the model instead of running the tool.
+	Gate ToolGate
 }
 
+// ToolGate decides whether a tool call may run, see ToolLoopConfig.Gate.
+type ToolGate func(ctx context.Context, name string, args map[string]any) *ToolResult
+
 type ToolLoopResult struct {
 	Content    string
 	Iterations int
@@ -49,6 +56,9 @@ func BuildToolResultMessage(toolCallID, toolName string, result *ToolResult) pro
 	if contentForLLM == "" && result.Err != nil {
 		contentForLLM = result.Err.Error()
 	}
+	if len(result.FollowUps) > 0 && !result.IsError {
+		contentForLLM += "\n\nSuggested next tools: " + strings.Join(result.FollowUps, ", ")
+	}
 	return providers.Message{
 		Role:       "tool",
 		Content:    contentForLLM,
@@ -102,7 +112,12 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 			logger.Info("toolloop: tool call %s(%s)", tc.Name, preview)
 
 			var toolResult *ToolResult
-			if config.Tools != nil {
+			if config.Gate != nil {
+				toolResult = config.Gate(ctx, tc.Name, tc.Arguments)
+			}
+			if toolResult != nil {
+				logger.Info("toolloop: tool call %s refused", tc.Name)
+			} else if config.Tools != nil {
 				toolResult = config.Tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, channel, chatID, nil)
 			} else {
 				toolResult = ErrorResult("No tools available")
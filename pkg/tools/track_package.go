@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/parcels"
+)
+
+// TrackPackageTool looks up shipments and keeps a watch list that the
+// parcels watcher polls for status changes.
+type TrackPackageTool struct {
+	tracker parcels.Tracker
+	store   *parcels.Store
+	mu      sync.RWMutex
+	channel string
+	chatID  string
+}
+
+func NewTrackPackageTool(tracker parcels.Tracker, store *parcels.Store) *TrackPackageTool {
+	return &TrackPackageTool{tracker: tracker, store: store}
+}
+
+func (t *TrackPackageTool) Name() string {
+	return "track_package"
+}
+
+func (t *TrackPackageTool) Description() string {
+	return "Track parcels by tracking number (UPS, USPS, FedEx, DHL, postal services and most other carriers). " +
+		"track looks up the current status; watch also keeps checking and tells the user when it changes " +
+		"(e.g. out for delivery, delivered); unwatch stops that; list shows the watched parcels."
+}
+
+func (t *TrackPackageTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"track", "watch", "unwatch", "list"},
+				"description": "Action to perform.",
+			},
+			"number": map[string]any{
+				"type":        "string",
+				"description": "Tracking number (for track, watch and unwatch).",
+			},
+			"carrier": map[string]any{
+				"type":        "string",
+				"description": "Carrier slug such as ups, usps, fedex or dhl, if known; usually detected.",
+			},
+			"label": map[string]any{
+				"type":        "string",
+				"description": "What the parcel is, e.g. \"new headphones\" (for watch).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *TrackPackageTool) DeclaredDomains() []string {
+	return parcels.Domains()
+}
+
+func (t *TrackPackageTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// AuditAction records changes to the watch list.
+func (t *TrackPackageTool) AuditAction(args map[string]any) (string, string) {
+	action, _ := args["action"].(string)
+	number, _ := args["number"].(string)
+	if action == "watch" || action == "unwatch" {
+		return "track_package." + action, parcels.Normalize(number)
+	}
+	return "", ""
+}
+
+func (t *TrackPackageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	raw, _ := args["number"].(string)
+	number := parcels.Normalize(raw)
+	carrier, _ := args["carrier"].(string)
+	carrier = strings.ToLower(strings.TrimSpace(carrier))
+	if carrier == "" {
+		carrier = parcels.DetectCarrier(number)
+	}
+
+	switch action {
+	case "track", "watch":
+		if number == "" {
+			return ErrorResult("number is required")
+		}
+		tr, err := t.tracker.Track(ctx, number, carrier)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("tracking %s failed: %v", number, err)).WithError(err)
+		}
+		if tr.Carrier == "" {
+			tr.Carrier = carrier
+		}
+		if action == "track" {
+			return SilentResult(tr.Format())
+		}
+		label, _ := args["label"].(string)
+		t.mu.RLock()
+		channel, chatID := t.channel, t.chatID
+		t.mu.RUnlock()
+		if _, err := t.store.Watch(tr, strings.TrimSpace(label), channel, chatID, time.Now()); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to save: %v", err)).WithError(err)
+		}
+		msg := tr.Format() + "\nWatching it; the user will hear when the status changes."
+		if tr.Status.Final() {
+			msg = tr.Format() + "\nIt is already " + tr.Status.Text() + "; watching it anyway."
+		}
+		return SilentResult(msg)
+
+	case "unwatch":
+		if number == "" {
+			return ErrorResult("number is required")
+		}
+		ok, err := t.store.Remove(number)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to save: %v", err)).WithError(err)
+		}
+		if !ok {
+			return ErrorResult(fmt.Sprintf("%s is not being watched", number))
+		}
+		return SilentResult(fmt.Sprintf("Stopped watching %s.", number))
+
+	case "list":
+		list := t.store.List()
+		if len(list) == 0 {
+			return SilentResult("No parcels are being watched.")
+		}
+		var sb strings.Builder
+		for _, p := range list {
+			fmt.Fprintf(&sb, "- %s", p.Number)
+			if p.Label != "" {
+				fmt.Fprintf(&sb, " (%s)", p.Label)
+			}
+			if p.Carrier != "" {
+				fmt.Fprintf(&sb, " via %s", p.Carrier)
+			}
+			fmt.Fprintf(&sb, ": %s", p.Status.Text())
+			if p.Detail != "" {
+				fmt.Fprintf(&sb, " - %s", p.Detail)
+			}
+			if p.CheckedMS > 0 {
+				fmt.Fprintf(&sb, " (checked %s)", time.UnixMilli(p.CheckedMS).Format("Jan 2 15:04"))
+			}
+			sb.WriteString("\n")
+		}
+		return SilentResult(strings.TrimRight(sb.String(), "\n"))
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"localagent/pkg/transit"
+	"localagent/pkg/when"
+)
+
+// TransitTool answers "when's the next bus?" from the user's configured
+// stops and plans journeys when a trip planner is configured.
+type TransitTool struct {
+	source  transit.Source
+	planner *transit.Planner // nil without tools.transit.plan_url
+	stops   []transit.Stop
+	domains []string
+}
+
+func NewTransitTool(source transit.Source, planner *transit.Planner, stops []transit.Stop, domains []string) *TransitTool {
+	return &TransitTool{source: source, planner: planner, stops: stops, domains: domains}
+}
+
+func (t *TransitTool) Name() string {
+	return "transit"
+}
+
+func (t *TransitTool) Description() string {
+	names := make([]string, len(t.stops))
+	for i, s := range t.stops {
+		names[i] = s.Name
+	}
+	desc := "Public transport: departures lists the next buses, trams and trains from the user's stops (" +
+		strings.Join(names, ", ") + ") with real-time delays where available."
+	if t.planner != nil {
+		desc += " plan finds connections between two of those stops or \"lat,lon\" points, leaving now or at a given time, or arriving by it."
+	}
+	return desc
+}
+
+func (t *TransitTool) Parameters() map[string]any {
+	actions := []string{"departures"}
+	if t.planner != nil {
+		actions = append(actions, "plan")
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        actions,
+				"description": "Action to perform.",
+			},
+			"stop": map[string]any{
+				"type":        "string",
+				"description": "Stop name for departures; default all stops.",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Departures per stop (default 5).",
+			},
+			"from": map[string]any{
+				"type":        "string",
+				"description": "Start of the journey: a stop name or \"lat,lon\" (for plan).",
+			},
+			"to": map[string]any{
+				"type":        "string",
+				"description": "Destination: a stop name or \"lat,lon\" (for plan).",
+			},
+			"at": map[string]any{
+				"type":        "string",
+				"description": "When to leave, e.g. \"8am\" or \"tomorrow 7:30\"; default now (for plan).",
+			},
+			"arrive_by": map[string]any{
+				"type":        "boolean",
+				"description": "Treat at as the time to arrive by (for plan).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *TransitTool) DeclaredDomains() []string {
+	return t.domains
+}
+
+// SideEffect reports that TransitTool calls only read.
+func (t *TransitTool) SideEffect(map[string]any) bool { return false }
+
+func (t *TransitTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "departures":
+		return t.departures(ctx, args)
+	case "plan":
+		if t.planner == nil {
+			return ErrorResult("journey planning needs tools.transit.plan_url")
+		}
+		return t.plan(ctx, args)
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
+
+func (t *TransitTool) departures(ctx context.Context, args map[string]any) *ToolResult {
+	stops := t.stops
+	if name, _ := args["stop"].(string); strings.TrimSpace(name) != "" {
+		s, ok := transit.Lookup(t.stops, name)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("unknown stop %q", name))
+		}
+		stops = []transit.Stop{s}
+	}
+	limit := 5
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = int(n)
+	}
+
+	now := when.Now()
+	var sb strings.Builder
+	for _, s := range stops {
+		deps, err := t.source.Departures(ctx, s, now)
+		if err != nil {
+			if len(stops) == 1 {
+				return ErrorResult(fmt.Sprintf("departures from %s failed: %v", s.Name, err)).WithError(err)
+			}
+			fmt.Fprintf(&sb, "%s: lookup failed: %v\n\n", s.Name, err)
+			continue
+		}
+		deps = transit.Upcoming(deps, s, now, limit)
+		if len(deps) == 0 {
+			fmt.Fprintf(&sb, "%s: no departures in the next 90 minutes.\n\n", s.Name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s:\n%s\n\n", s.Name, transit.Format(deps, now))
+	}
+	return SilentResult(strings.TrimRight(sb.String(), "\n"))
+}
+
+func (t *TransitTool) plan(ctx context.Context, args map[string]any) *ToolResult {
+	rawFrom, _ := args["from"].(string)
+	rawTo, _ := args["to"].(string)
+	from, err := t.place(rawFrom)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	to, err := t.place(rawTo)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	at := when.Now()
+	if raw, _ := args["at"].(string); strings.TrimSpace(raw) != "" {
+		r, err := when.Parse(raw, at)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid time %q: %v", raw, err))
+		}
+		at = r.Time
+	}
+	arriveBy, _ := args["arrive_by"].(bool)
+
+	itineraries, err := t.planner.Plan(ctx, from, to, at, arriveBy)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("journey planning failed: %v", err)).WithError(err)
+	}
+	if len(itineraries) == 0 {
+		return SilentResult(fmt.Sprintf("No connections found from %s to %s.", from.Name, to.Name))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s to %s:\n", from.Name, to.Name)
+	for _, it := range itineraries {
+		fmt.Fprintf(&sb, "- %s\n", it.Format())
+	}
+	return SilentResult(strings.TrimRight(sb.String(), "\n"))
+}
+
+// place resolves a stop name or "lat,lon".
+func (t *TransitTool) place(raw string) (transit.Stop, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return transit.Stop{}, fmt.Errorf("from and to are required")
+	}
+	if s, ok := transit.Lookup(t.stops, raw); ok {
+		return s, nil
+	}
+	if lat, lon, ok := strings.Cut(raw, ","); ok {
+		la, err1 := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		lo, err2 := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if err1 == nil && err2 == nil {
+			return transit.Stop{Name: raw, Lat: la, Lon: lo}, nil
+		}
+	}
+	return transit.Stop{}, fmt.Errorf("unknown place %q: use a stop name or \"lat,lon\"", raw)
+}
@@ -0,0 +1,469 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"localagent/pkg/travel"
+	"localagent/pkg/when"
+)
+
+// TravelTool manages trips. While one is under way the agent works in the
+// destination timezone.
+type TravelTool struct {
+	trips     *travel.Service
+	mode      *travel.Mode
+	workspace string
+}
+
+func NewTravelTool(mode *travel.Mode, svc *travel.Service, workspace string) *TravelTool {
+	return &TravelTool{trips: svc, mode: mode, workspace: workspace}
+}
+
+func (t *TravelTool) Name() string {
+	return "travel"
+}
+
+func (t *TravelTool) Description() string {
+	return "The user's trips. While a trip is under way, times, reminders and scheduled jobs follow the destination timezone, and briefings include its weather and itinerary. " +
+		"Trips are also created from multi-day calendar events with a location. " +
+		"Actions: status, start (travel mode now), end (the trip under way), plan (a future trip), list, update, remove, " +
+		"import (bookings from a forwarded confirmation email: text, or path to a saved .eml/.html file), add_item (a booking by hand, e.g. when import finds no booking data), weather."
+}
+
+func (t *TravelTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"status", "start", "end", "plan", "list", "update", "remove", "import", "add_item", "weather"},
+				"description": "Action to perform.",
+			},
+			"trip": map[string]any{
+				"type":        "string",
+				"description": "Trip ID or destination (for update, remove, add_item, weather; optional for import).",
+			},
+			"destination": map[string]any{
+				"type":        "string",
+				"description": "City, e.g. \"Tokyo, Japan\" (for start, plan, update; weather without a trip).",
+			},
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "IANA timezone of the destination; looked up from the destination when omitted (for start, plan, update).",
+			},
+			"start": map[string]any{
+				"type":        "string",
+				"description": "When the trip or booking starts, e.g. \"2026-11-02 10:40\" or \"next friday\" (for plan, update, add_item).",
+			},
+			"end": map[string]any{
+				"type":        "string",
+				"description": "When it ends; a date alone means the end of that day (for start, plan, update, add_item).",
+			},
+			"kind": map[string]any{
+				"type":        "string",
+				"enum":        []string{"flight", "hotel", "train", "bus", "car", "event", "restaurant", "other"},
+				"description": "Booking kind (for add_item).",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Booking title, e.g. \"LX 160 ZRH → NRT\" or the hotel name (for add_item).",
+			},
+			"location": map[string]any{
+				"type":        "string",
+				"description": "Departure airport or station, or address (for add_item).",
+			},
+			"reference": map[string]any{
+				"type":        "string",
+				"description": "Confirmation code (for add_item).",
+			},
+			"details": map[string]any{
+				"type":        "string",
+				"description": "Seat, gate, terminal and the like (for add_item).",
+			},
+			"notes": map[string]any{
+				"type":        "string",
+				"description": "Free-form notes (for start, plan, update).",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Confirmation email or page content (for import).",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Saved confirmation file in the workspace (for import).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *TravelTool) DeclaredDomains() []string {
+	return travel.Domains
+}
+
+// SideEffect reports whether the call changes anything; status, list and weather only read.
+func (t *TravelTool) SideEffect(args map[string]any) bool {
+	return !readOnlyAction(args, "status", "list", "weather")
+}
+
+func (t *TravelTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "status":
+		return SilentResult(t.status())
+	case "start", "plan":
+		return t.add(ctx, action, args)
+	case "end":
+		return t.end()
+	case "list":
+		return t.list()
+	case "update":
+		return t.update(ctx, args)
+	case "remove":
+		trip := t.find(args)
+		if trip == nil {
+			return ErrorResult("trip not found")
+		}
+		t.trips.Remove(trip.ID)
+		t.mode.Apply()
+		return SilentResult(fmt.Sprintf("Removed trip to %s.", trip.Destination))
+	case "import":
+		return t.importConfirmation(ctx, args)
+	case "add_item":
+		return t.addItem(args)
+	case "weather":
+		return t.weather(ctx, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+func (t *TravelTool) status() string {
+	now := time.Now()
+	loc := when.Location()
+	var b strings.Builder
+	if trip := t.trips.Active(now); trip != nil {
+		fmt.Fprintf(&b, "Travel mode on: %s\n", travel.FormatTrip(*trip, loc))
+	} else {
+		b.WriteString("Travel mode off: not on a trip.\n")
+	}
+	fmt.Fprintf(&b, "Timezone: %s (local time %s), home %s.", loc, now.In(loc).Format("15:04"), t.mode.Home())
+	if next := t.trips.Next(now); next != nil {
+		fmt.Fprintf(&b, "\nNext trip: %s", travel.FormatTrip(*next, loc))
+	}
+	return b.String()
+}
+
+func (t *TravelTool) add(ctx context.Context, action string, args map[string]any) *ToolResult {
+	trip := travel.Trip{}
+	trip.Destination, _ = args["destination"].(string)
+	trip.Timezone, _ = args["timezone"].(string)
+	trip.Notes, _ = args["notes"].(string)
+	if action == "start" {
+		trip.StartMS = time.Now().UnixMilli()
+	} else {
+		start, ok := args["start"].(string)
+		if !ok || start == "" {
+			return ErrorResult("start is required for plan")
+		}
+		at, err := parseLocalDateTime(start, when.Location())
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid start: %v", err))
+		}
+		trip.StartMS = at.UnixMilli()
+	}
+	if end, _ := args["end"].(string); end != "" {
+		ms, err := parseTripEnd(end)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid end: %v", err))
+		}
+		trip.EndMS = ms
+	}
+	note := t.locate(ctx, &trip)
+	added, err := t.trips.Add(trip)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	t.mode.Apply()
+	return SilentResult(t.withTimezone(travel.FormatTrip(*added, when.Location()), note))
+}
+
+func (t *TravelTool) end() *ToolResult {
+	now := time.Now()
+	trip := t.trips.Active(now)
+	if trip == nil {
+		return ErrorResult("no trip is under way")
+	}
+	trip.EndMS = max(now.UnixMilli(), trip.StartMS+1)
+	if err := t.trips.Save(trip); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to end trip: %v", err))
+	}
+	loc := t.mode.Apply()
+	return SilentResult(fmt.Sprintf("Ended the trip to %s. Timezone is back to %s.", trip.Destination, loc))
+}
+
+func (t *TravelTool) list() *ToolResult {
+	trips := t.trips.List()
+	if len(trips) == 0 {
+		return SilentResult("No trips.")
+	}
+	lines := make([]string, len(trips))
+	for i, trip := range trips {
+		lines[i] = travel.FormatTrip(trip, when.Location())
+	}
+	return SilentResult(strings.Join(lines, "\n"))
+}
+
+func (t *TravelTool) update(ctx context.Context, args map[string]any) *ToolResult {
+	trip := t.find(args)
+	if trip == nil {
+		return ErrorResult("trip not found")
+	}
+	if v, ok := args["destination"].(string); ok && v != "" && v != trip.Destination {
+		trip.Destination = v
+		trip.Latitude, trip.Longitude = 0, 0
+		if _, ok := args["timezone"]; !ok {
+			trip.Timezone = ""
+		}
+	}
+	if v, ok := args["timezone"].(string); ok {
+		trip.Timezone = v
+	}
+	if v, ok := args["notes"].(string); ok {
+		trip.Notes = v
+	}
+	if v, _ := args["start"].(string); v != "" {
+		at, err := parseLocalDateTime(v, when.Location())
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid start: %v", err))
+		}
+		trip.StartMS = at.UnixMilli()
+	}
+	if v, _ := args["end"].(string); v != "" {
+		ms, err := parseTripEnd(v)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid end: %v", err))
+		}
+		trip.EndMS = ms
+	}
+	note := t.locate(ctx, trip)
+	if err := t.trips.Save(trip); err != nil {
+		return ErrorResult(err.Error())
+	}
+	t.mode.Apply()
+	return SilentResult(t.withTimezone(travel.FormatTrip(*trip, when.Location()), note))
+}
+
+func (t *TravelTool) importConfirmation(ctx context.Context, args map[string]any) *ToolResult {
+	var data []byte
+	if text, _ := args["text"].(string); text != "" {
+		data = []byte(text)
+	} else if path, _ := args["path"].(string); path != "" {
+		resolved, err := validatePath(path, t.workspace)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		data, err = os.ReadFile(resolved)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read confirmation: %v", err))
+		}
+	} else {
+		return ErrorResult("text or path is required for import")
+	}
+
+	items, err := travel.ParseConfirmation(data)
+	if errors.Is(err, travel.ErrNoItinerary) {
+		return ErrorResult("No booking data found in the confirmation. Read the bookings from its text and add each with add_item.")
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to parse confirmation: %v", err))
+	}
+
+	trip := t.find(args)
+	if trip == nil {
+		trip = t.trips.TripFor(time.UnixMilli(items[0].StartMS))
+	}
+	var note string
+	if trip == nil {
+		dest, _ := args["destination"].(string)
+		guessed := dest == ""
+		if guessed {
+			dest = guessDestination(items)
+		}
+		if dest == "" {
+			return ErrorResult("no trip matches these bookings; pass destination to create one")
+		}
+		nt := travel.Trip{Destination: dest, StartMS: items[0].StartMS, Source: travel.SourceItinerary}
+		if guessed {
+			// A hotel address or airport code makes a poor trip name.
+			if place, err := t.mode.Geocode(ctx, dest); err == nil {
+				nt.Destination, nt.Timezone = place.Label(), place.Timezone
+				nt.Latitude, nt.Longitude = place.Latitude, place.Longitude
+			}
+		}
+		note = t.locate(ctx, &nt)
+		if trip, err = t.trips.Add(nt); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+	added, err := t.trips.AddItems(trip, items)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to save bookings: %v", err))
+	}
+	t.mode.Apply()
+	msg := fmt.Sprintf("Added %d of %d booking(s) to the trip to %s.\n%s", added, len(items), trip.Destination,
+		travel.FormatTrip(*trip, when.Location()))
+	return SilentResult(t.withTimezone(msg, note))
+}
+
+func (t *TravelTool) addItem(args map[string]any) *ToolResult {
+	it := travel.Item{}
+	it.Kind, _ = args["kind"].(string)
+	it.Title, _ = args["title"].(string)
+	it.Location, _ = args["location"].(string)
+	it.Reference, _ = args["reference"].(string)
+	it.Details, _ = args["details"].(string)
+	if it.Kind == "" {
+		it.Kind = "other"
+	}
+	if it.Title == "" {
+		return ErrorResult("title is required for add_item")
+	}
+	start, _ := args["start"].(string)
+	if start == "" {
+		return ErrorResult("start is required for add_item")
+	}
+	at, err := parseLocalDateTime(start, when.Location())
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid start: %v", err))
+	}
+	it.StartMS = at.UnixMilli()
+	if end, _ := args["end"].(string); end != "" {
+		e, err := parseLocalDateTime(end, when.Location())
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid end: %v", err))
+		}
+		it.EndMS = e.UnixMilli()
+	}
+
+	trip := t.find(args)
+	if trip == nil {
+		trip = t.trips.TripFor(at)
+	}
+	if trip == nil {
+		return ErrorResult("no trip covers this booking; plan the trip first or pass trip")
+	}
+	if _, err := t.trips.AddItems(trip, []travel.Item{it}); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to save booking: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Added to the trip to %s: %s", trip.Destination, travel.FormatItem(it, when.Location())))
+}
+
+func (t *TravelTool) weather(ctx context.Context, args map[string]any) *ToolResult {
+	ref, _ := args["trip"].(string)
+	dest, _ := args["destination"].(string)
+	trip := t.find(args)
+	switch {
+	case ref != "" && trip == nil:
+		return ErrorResult("trip not found")
+	case trip == nil && dest == "":
+		if trip = t.trips.Active(time.Now()); trip == nil {
+			trip = t.trips.Next(time.Now())
+		}
+	}
+	if trip != nil {
+		days, err := t.mode.Forecast(trip)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to get weather for %s: %v", trip.Destination, err))
+		}
+		return SilentResult(formatForecast(trip.Destination, days))
+	}
+	if dest == "" {
+		return ErrorResult("trip or destination is required for weather")
+	}
+	place, err := t.mode.Geocode(ctx, dest)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to find %s: %v", dest, err))
+	}
+	days, err := t.mode.Weather(ctx, place)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to get weather for %s: %v", place.Label(), err))
+	}
+	return SilentResult(formatForecast(place.Label(), days))
+}
+
+// find returns the trip named by the trip argument.
+func (t *TravelTool) find(args map[string]any) *travel.Trip {
+	ref, _ := args["trip"].(string)
+	if ref == "" {
+		return nil
+	}
+	return t.trips.Find(ref)
+}
+
+// locate fills in a trip's timezone and coordinates from its destination
+// when no timezone was given, and returns a note when that failed.
+func (t *TravelTool) locate(ctx context.Context, trip *travel.Trip) string {
+	if trip.Timezone != "" || strings.TrimSpace(trip.Destination) == "" {
+		return ""
+	}
+	place, err := t.mode.Geocode(ctx, trip.Destination)
+	if err != nil {
+		return fmt.Sprintf("Could not look up the timezone of %s (%v); set it with update.", trip.Destination, err)
+	}
+	trip.Timezone = place.Timezone
+	trip.Latitude, trip.Longitude = place.Latitude, place.Longitude
+	return ""
+}
+
+func (t *TravelTool) withTimezone(msg, note string) string {
+	msg += fmt.Sprintf("\nTimezone: %s.", when.Location())
+	if note != "" {
+		msg += "\n" + note
+	}
+	return msg
+}
+
+// parseTripEnd reads a trip end; a date alone ends the trip after that day.
+func parseTripEnd(s string) (int64, error) {
+	at, err := parseLocalDateTime(s, when.Location())
+	if err != nil {
+		return 0, err
+	}
+	if at.Hour() == 0 && at.Minute() == 0 {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at.UnixMilli(), nil
+}
+
+// guessDestination names a new trip after its hotel's address, or where
+// its first flight or train goes.
+func guessDestination(items []travel.Item) string {
+	for _, it := range items {
+		if it.Kind == "hotel" && it.Location != "" {
+			return it.Location
+		}
+	}
+	for _, it := range items {
+		if _, to, ok := strings.Cut(it.Title, "→"); ok && (it.Kind == "flight" || it.Kind == "train") {
+			return strings.TrimSpace(to)
+		}
+	}
+	return ""
+}
+
+func formatForecast(place string, days []travel.Day) string {
+	if len(days) == 0 {
+		return fmt.Sprintf("No forecast for %s.", place)
+	}
+	lines := []string{fmt.Sprintf("Weather in %s:", place)}
+	for _, d := range days {
+		lines = append(lines, "- "+d.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidateArgs checks LLM-supplied arguments against a tool's JSON schema.
+// Common model mistakes are coerced rather than rejected ("5" for 5,
+// "true" for true, a bare value where an array is expected). It returns the
+// coerced arguments and a list of problems; an empty list means valid.
+func ValidateArgs(schema map[string]any, args map[string]any) (map[string]any, []string) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	v, problems := validateValue(schema, args, "")
+	out, _ := v.(map[string]any)
+	if out == nil {
+		out = args
+	}
+	return out, problems
+}
+
+func validateValue(schema map[string]any, value any, path string) (any, []string) {
+	if schema == nil {
+		return value, nil
+	}
+
+	typ, _ := schema["type"].(string)
+	var problems []string
+
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			if s, isStr := value.(string); isStr && json.Unmarshal([]byte(s), &obj) == nil {
+				ok = true
+			}
+		}
+		if !ok {
+			return value, []string{fmt.Sprintf("%s: expected object, got %s", displayPath(path), describe(value))}
+		}
+
+		out := make(map[string]any, len(obj))
+		for k, v := range obj {
+			out[k] = v
+		}
+		for _, name := range requiredFields(schema) {
+			if v, present := out[name]; !present || v == nil {
+				problems = append(problems, fmt.Sprintf("%s: missing required field", joinPath(path, name)))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, propSchema := range props {
+			v, present := out[name]
+			if !present || v == nil {
+				continue
+			}
+			ps, _ := propSchema.(map[string]any)
+			coerced, sub := validateValue(ps, v, joinPath(path, name))
+			out[name] = coerced
+			problems = append(problems, sub...)
+		}
+		return out, problems
+
+	case "array":
+		var arr []any
+		switch v := value.(type) {
+		case []any:
+			arr = v
+		case []string:
+			for _, s := range v {
+				arr = append(arr, s)
+			}
+		case string:
+			if json.Unmarshal([]byte(v), &arr) != nil {
+				arr = []any{v}
+			}
+		default:
+			arr = []any{v}
+		}
+		items, _ := schema["items"].(map[string]any)
+		out := make([]any, len(arr))
+		for i, item := range arr {
+			coerced, sub := validateValue(items, item, fmt.Sprintf("%s[%d]", displayPath(path), i))
+			out[i] = coerced
+			problems = append(problems, sub...)
+		}
+		return out, problems
+
+	case "string":
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			s = strconv.FormatBool(v)
+		default:
+			return value, []string{fmt.Sprintf("%s: expected string, got %s", displayPath(path), describe(value))}
+		}
+		if enum := enumValues(schema); len(enum) > 0 && !slices.Contains(enum, s) {
+			return s, []string{fmt.Sprintf("%s: %q is not one of %s", displayPath(path), s, strings.Join(enum, ", "))}
+		}
+		return s, nil
+
+	case "number", "integer":
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return value, []string{fmt.Sprintf("%s: expected %s, got %q", displayPath(path), typ, v)}
+			}
+			n = f
+		default:
+			return value, []string{fmt.Sprintf("%s: expected %s, got %s", displayPath(path), typ, describe(value))}
+		}
+		if typ == "integer" && n != math.Trunc(n) {
+			return n, []string{fmt.Sprintf("%s: expected integer, got %v", displayPath(path), n)}
+		}
+		return n, nil
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return value, []string{fmt.Sprintf("%s: expected boolean, got %s", displayPath(path), describe(value))}
+	}
+
+	return value, nil
+}
+
+// FormatValidationError builds the tool result sent back to the model so it
+// can correct its call on the next iteration.
+func FormatValidationError(toolName string, problems []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Invalid arguments for %s:\n", toolName)
+	for _, p := range problems {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	b.WriteString("Fix the arguments to match the tool's parameter schema and call it again.")
+	return b.String()
+}
+
+func requiredFields(schema map[string]any) []string {
+	switch r := schema["required"].(type) {
+	case []string:
+		return r
+	case []any:
+		out := make([]string, 0, len(r))
+		for _, v := range r {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func enumValues(schema map[string]any) []string {
+	switch e := schema["enum"].(type) {
+	case []string:
+		return e
+	case []any:
+		out := make([]string, 0, len(e))
+		for _, v := range e {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, int:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func joinPath(base, name string) string {
+	if base == "" {
+		return name
+	}
+	return base + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "arguments"
+	}
+	return path
+}
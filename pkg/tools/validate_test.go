@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+var validateSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"name":   map[string]any{"type": "string"},
+		"count":  map[string]any{"type": "integer"},
+		"ratio":  map[string]any{"type": "number"},
+		"force":  map[string]any{"type": "boolean"},
+		"mode":   map[string]any{"type": "string", "enum": []string{"fast", "slow"}},
+		"labels": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+	"required": []string{"name"},
+}
+
+func TestValidateArgsCoercion(t *testing.T) {
+	args, problems := ValidateArgs(validateSchema, map[string]any{
+		"name":   "x",
+		"count":  "5",
+		"ratio":  "0.5",
+		"force":  "true",
+		"labels": "single",
+	})
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if args["count"] != 5.0 || args["ratio"] != 0.5 || args["force"] != true {
+		t.Errorf("coercion failed: %#v", args)
+	}
+	labels, ok := args["labels"].([]any)
+	if !ok || len(labels) != 1 || labels[0] != "single" {
+		t.Errorf("expected wrapped array, got %#v", args["labels"])
+	}
+
+	args, _ = ValidateArgs(validateSchema, map[string]any{"name": 42.0, "labels": `["a","b"]`})
+	if args["name"] != "42" {
+		t.Errorf("expected number coerced to string, got %#v", args["name"])
+	}
+	if labels, _ := args["labels"].([]any); len(labels) != 2 {
+		t.Errorf("expected JSON array string to be parsed, got %#v", args["labels"])
+	}
+}
+
+func TestValidateArgsProblems(t *testing.T) {
+	_, problems := ValidateArgs(validateSchema, map[string]any{
+		"count": 2.5,
+		"force": "maybe",
+		"mode":  "medium",
+	})
+	want := []string{"name: missing required", "count: expected integer", "force: expected boolean", "mode: \"medium\" is not one of"}
+	joined := strings.Join(problems, "\n")
+	for _, w := range want {
+		if !strings.Contains(joined, w) {
+			t.Errorf("expected problem %q in:\n%s", w, joined)
+		}
+	}
+}
+
+func TestRegistryRejectsInvalidArgs(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(NewReadFileTool(t.TempDir()))
+
+	result := r.Execute(context.Background(), "read_file", map[string]any{})
+	if !result.IsError {
+		t.Fatal("expected validation error")
+	}
+	if !strings.Contains(result.ForLLM, "Invalid arguments for read_file") || !strings.Contains(result.ForLLM, "path: missing required") {
+		t.Errorf("unexpected message: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,141 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"localagent/pkg/session"
+)
+
+const (
+	maxSessionVars   = 50
+	maxVarValueChars = 1000
+)
+
+// VarsTool reads and writes per-session variables. Variables are injected
+// into the system prompt on every turn, so stable context like the current
+// project path doesn't have to be restated.
+type VarsTool struct {
+	sessions *session.SessionManager
+}
+
+func NewVarsTool(sessions *session.SessionManager) *VarsTool {
+	return &VarsTool{sessions: sessions}
+}
+
+func (t *VarsTool) Name() string {
+	return "vars"
+}
+
+func (t *VarsTool) Description() string {
+	return "Session variables: remember stable facts for this conversation (e.g. current_project=/work/app, preferred_calendar=Work). They are shown in the system prompt every turn and survive summarization. Actions: set, delete, list."
+}
+
+func (t *VarsTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"set", "delete", "list"},
+				"description": "Action to perform.",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Variable name, lowercase with underscores (for set, delete).",
+			},
+			"value": map[string]any{
+				"type":        "string",
+				"description": "Variable value (for set).",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// SensitiveArgs hides values since variables often hold tokens or
+// account numbers.
+func (t *VarsTool) SensitiveArgs() []string {
+	return []string{"value"}
+}
+
+// SideEffect reports that VarsTool calls only read.
+func (t *VarsTool) SideEffect(map[string]any) bool { return false }
+
+func (t *VarsTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	key := SessionKeyFromContext(ctx)
+	if key == "" {
+		return ErrorResult("no active session")
+	}
+
+	action, _ := args["action"].(string)
+	name, _ := args["name"].(string)
+	name = strings.TrimSpace(name)
+
+	switch action {
+	case "list":
+		return SilentResult(FormatVars(t.sessions.GetVars(key), "No session variables set."))
+
+	case "set":
+		if !validVarName(name) {
+			return ErrorResult("name is required and may only contain letters, digits, '_', '-' and '.'")
+		}
+		value, _ := args["value"].(string)
+		if len(value) > maxVarValueChars {
+			return ErrorResult(fmt.Sprintf("value too long (%d chars, max %d); store large content in a file and save its path instead", len(value), maxVarValueChars))
+		}
+		vars := t.sessions.GetVars(key)
+		if _, exists := vars[name]; !exists && len(vars) >= maxSessionVars {
+			return ErrorResult(fmt.Sprintf("too many variables (max %d); delete unused ones first", maxSessionVars))
+		}
+		t.sessions.SetVar(key, name, value)
+		return SilentResult(fmt.Sprintf("Set %s = %s", name, value))
+
+	case "delete":
+		if name == "" {
+			return ErrorResult("name is required for delete")
+		}
+		if !t.sessions.DeleteVar(key, name) {
+			return ErrorResult(fmt.Sprintf("variable %q is not set", name))
+		}
+		return SilentResult(fmt.Sprintf("Deleted %s", name))
+
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+}
+
+// FormatVars renders variables as a sorted "- name: value" list, or empty
+// when there are none.
+func FormatVars(vars map[string]string, empty string) string {
+	if len(vars) == 0 {
+		return empty
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "- %s: %s\n", name, vars[name])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func validVarName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"localagent/pkg/session"
+)
+
+func TestVarsTool(t *testing.T) {
+	dir := t.TempDir()
+	sm := session.NewSessionManager(dir)
+	tool := NewVarsTool(sm)
+	ctx := WithSessionKey(context.Background(), "web:default")
+
+	if r := tool.Execute(context.Background(), map[string]any{"action": "list"}); !r.IsError {
+		t.Error("expected error without a session in context")
+	}
+
+	tool.Execute(ctx, map[string]any{"action": "set", "name": "project", "value": "/work/app"})
+	tool.Execute(ctx, map[string]any{"action": "set", "name": "calendar", "value": "Work"})
+	if r := tool.Execute(ctx, map[string]any{"action": "set", "name": "bad name", "value": "x"}); !r.IsError {
+		t.Error("expected invalid name to be rejected")
+	}
+
+	r := tool.Execute(ctx, map[string]any{"action": "list"})
+	if r.ForLLM != "- calendar: Work\n- project: /work/app" {
+		t.Errorf("unexpected list output: %q", r.ForLLM)
+	}
+
+	// Variables survive history truncation (as done after summarization)
+	// and a reload from disk; deletions are persisted too.
+	sm.AddMessage("web:default", "user", "hi")
+	sm.TruncateHistory("web:default", 0)
+	tool.Execute(ctx, map[string]any{"action": "delete", "name": "calendar"})
+
+	reloaded := session.NewSessionManager(dir)
+	vars := reloaded.GetVars("web:default")
+	if len(vars) != 1 || vars["project"] != "/work/app" {
+		t.Errorf("unexpected vars after reload: %v", vars)
+	}
+
+	if r := tool.Execute(ctx, map[string]any{"action": "delete", "name": "calendar"}); !r.IsError || !strings.Contains(r.ForLLM, "not set") {
+		t.Errorf("expected error deleting missing var, got %q", r.ForLLM)
+	}
+}
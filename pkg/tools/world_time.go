@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"localagent/pkg/when"
+	"localagent/pkg/worldclock"
+)
+
+// WorldTimeTool tells the local time at the user's places (config places)
+// or any city, now or at a time in the user's timezone.
+type WorldTimeTool struct {
+	places []worldclock.Place
+}
+
+func NewWorldTimeTool(places []worldclock.Place) *WorldTimeTool {
+	return &WorldTimeTool{places: places}
+}
+
+func (t *WorldTimeTool) Name() string {
+	return "world_time"
+}
+
+func (t *WorldTimeTool) Description() string {
+	desc := "Local time at other places and how far ahead or behind the user they are, now or at a given time of the user's. " +
+		"Flags times outside the hours it's fine to call or message there. " +
+		"Use for questions like \"is it too late to call mom?\" or \"what time is 3pm for the Tokyo team?\"."
+	if len(t.places) > 0 {
+		names := make([]string, len(t.places))
+		for i, p := range t.places {
+			names[i] = p.Name
+		}
+		desc += " Known places: " + strings.Join(names, ", ") + "."
+	}
+	return desc
+}
+
+func (t *WorldTimeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"places": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Known place names, cities or IANA timezones; default all known places.",
+			},
+			"at": map[string]any{
+				"type":        "string",
+				"description": "A time in the user's timezone, e.g. \"8pm\" or \"tomorrow 9am\"; default now.",
+			},
+		},
+	}
+}
+
+// SideEffect reports that WorldTimeTool calls only read.
+func (t *WorldTimeTool) SideEffect(map[string]any) bool { return false }
+
+func (t *WorldTimeTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	now := when.Now()
+	at := now
+	if raw, _ := args["at"].(string); strings.TrimSpace(raw) != "" {
+		r, err := when.Parse(raw, now)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid time %q: %v", raw, err))
+		}
+		if r.DateOnly {
+			return ErrorResult(fmt.Sprintf("%q has no time of day", raw))
+		}
+		at = r.Time
+	}
+
+	places := t.places
+	if raw, ok := args["places"].([]any); ok && len(raw) > 0 {
+		places = nil
+		for _, v := range raw {
+			name, _ := v.(string)
+			p, err := worldclock.Lookup(t.places, name)
+			if err != nil {
+				return ErrorResult(err.Error())
+			}
+			places = append(places, p)
+		}
+	}
+	if len(places) == 0 {
+		return ErrorResult("no places configured; name a city or timezone")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Your time: %s\n", at.Format("Mon Jan 2 15:04 MST"))
+	for _, p := range places {
+		sb.WriteString("- " + p.Describe(at, now.Location()) + "\n")
+	}
+	return SilentResult(strings.TrimRight(sb.String(), "\n"))
+}
@@ -0,0 +1,148 @@
+package transcript
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMarkdown renders turns as markdown. Tool traces go in <details>
+// blocks, which GitHub and most viewers render collapsed.
+func WriteMarkdown(w io.Writer, turns []Turn, opts Options) error {
+	bw := bufio.NewWriter(w)
+	if opts.Title != "" {
+		fmt.Fprintf(bw, "# %s\n\n", opts.Title)
+	}
+	for _, t := range turns {
+		fmt.Fprintf(bw, "### %s", roleLabel(t.Role))
+		if ts := opts.format(t.Time); ts != "" {
+			fmt.Fprintf(bw, " · %s", ts)
+		}
+		bw.WriteString("\n\n")
+
+		if len(t.Tools) > 0 {
+			fmt.Fprintf(bw, "<details>\n<summary>%s</summary>\n\n", template.HTMLEscapeString(toolSummary(t.Tools)))
+			for _, c := range t.Tools {
+				fmt.Fprintf(bw, "- **%s**", c.Name)
+				if c.Args != "" {
+					fmt.Fprintf(bw, " `%s`", strings.ReplaceAll(c.Args, "`", "'"))
+				}
+				bw.WriteString("\n")
+				if c.Result != "" {
+					fence := codeFence(c.Result)
+					fmt.Fprintf(bw, "\n  %s\n%s\n  %s\n", fence, indent(c.Result, "  "), fence)
+				}
+			}
+			bw.WriteString("\n</details>\n\n")
+		}
+		if t.Content != "" {
+			bw.WriteString(t.Content)
+			bw.WriteString("\n\n")
+		}
+		for _, m := range t.Media {
+			if uri := dataURI(m); uri != "" {
+				fmt.Fprintf(bw, "![%s](%s)\n\n", filepath.Base(m), uri)
+			} else {
+				fmt.Fprintf(bw, "*Attachment: %s*\n\n", filepath.Base(m))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// codeFence returns a backtick fence longer than any run in s.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+type htmlImage struct {
+	Name string
+	URI  template.URL
+}
+
+type htmlTurn struct {
+	Turn
+	Label   string
+	Time    string
+	Summary string
+	Images  []htmlImage
+	Files   []string
+}
+
+// WriteHTML renders turns as a standalone HTML page.
+func WriteHTML(w io.Writer, turns []Turn, opts Options) error {
+	data := struct {
+		Title string
+		Turns []htmlTurn
+	}{Title: opts.Title}
+	if data.Title == "" {
+		data.Title = "Conversation"
+	}
+	for _, t := range turns {
+		ht := htmlTurn{Turn: t, Label: roleLabel(t.Role), Time: opts.format(t.Time)}
+		if len(t.Tools) > 0 {
+			ht.Summary = toolSummary(t.Tools)
+		}
+		for _, m := range t.Media {
+			if uri := dataURI(m); uri != "" {
+				ht.Images = append(ht.Images, htmlImage{Name: filepath.Base(m), URI: template.URL(uri)})
+			} else {
+				ht.Files = append(ht.Files, filepath.Base(m))
+			}
+		}
+		data.Turns = append(data.Turns, ht)
+	}
+	return htmlTemplate.Execute(w, data)
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; line-height: 1.5; }
+.turn { margin: 1.25rem 0; padding: .75rem 1rem; border-radius: .5rem; }
+.user { background: #eef4ff; }
+.assistant { background: #f6f8fa; }
+.meta { font-size: .8rem; color: #59636e; margin-bottom: .4rem; }
+.content { white-space: pre-wrap; overflow-wrap: anywhere; }
+details { margin: .4rem 0 .6rem; font-size: .85rem; }
+summary { cursor: pointer; color: #59636e; }
+pre { background: #fff; border: 1px solid #d1d9e0; border-radius: .375rem; padding: .5rem; overflow-x: auto; white-space: pre-wrap; }
+code { font-size: .8rem; }
+img { max-width: 100%; border-radius: .375rem; margin-top: .5rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Turns}}<div class="turn {{.Role}}">
+<div class="meta"><strong>{{.Label}}</strong>{{if .Time}} · {{.Time}}{{end}}</div>
+{{if .Summary}}<details><summary>{{.Summary}}</summary>
+{{range .Tools}}<div><strong>{{.Name}}</strong>{{if .Args}} <code>{{.Args}}</code>{{end}}</div>
+{{if .Result}}<pre>{{.Result}}</pre>{{end}}
+{{end}}</details>
+{{end}}{{if .Content}}<div class="content">{{.Content}}</div>
+{{end}}{{range .Images}}<img src="{{.URI}}" alt="{{.Name}}">
+{{end}}{{range .Files}}<div class="meta">Attachment: {{.}}</div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
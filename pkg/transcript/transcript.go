@@ -0,0 +1,261 @@
+// Package transcript renders a session as shareable markdown or HTML:
+// user and assistant turns with timestamps, the tool calls behind each
+// answer collapsed underneath it, and images embedded inline. Arguments
+// that tools mark sensitive are hidden.
+package transcript
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/providers"
+	"localagent/pkg/session"
+	"localagent/pkg/utils"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Placeholder replaces hidden argument values.
+const Placeholder = "[redacted]"
+
+// maxEmbedBytes is the largest image embedded inline; bigger ones are
+// referenced by file name only.
+const maxEmbedBytes = 5 << 20
+
+// maxToolText caps tool arguments and results in the trace.
+const maxToolText = 2000
+
+// secretArgRe matches argument names that are hidden even when the tool
+// does not declare them sensitive.
+var secretArgRe = regexp.MustCompile(`(?i)password|passwd|secret|token|api_?key|credential`)
+
+type Options struct {
+	Title     string
+	Sensitive map[string][]string // tool name -> argument names to hide, see tools.SensitiveArgs
+	Redact    func(string) string // applied to all exported text, may be nil
+	Location  *time.Location      // for timestamps, default local
+}
+
+// ToolCall is one step of the trace behind an answer.
+type ToolCall struct {
+	Name   string
+	Args   string
+	Result string
+}
+
+// Turn is a visible message with the tool calls that led to it.
+type Turn struct {
+	Role    string // "user" or "assistant"
+	Content string
+	Time    time.Time
+	Media   []string
+	Tools   []ToolCall
+}
+
+// Turns groups a session timeline into turns. Tool calls and results attach
+// to the next assistant message; text sent through the message tool counts
+// as an assistant message, as in webchat.
+func Turns(timeline []session.TimelineEntry, opts Options) []Turn {
+	type building struct {
+		Turn
+		calls []*ToolCall
+	}
+	var turns []building
+	var pending []*ToolCall
+	// Results can arrive after their turn was emitted, e.g. when the message
+	// tool ran first in a batch, so calls are filled in by ID until the end.
+	byID := make(map[string]*ToolCall)
+	flush := func(t Turn) {
+		turns = append(turns, building{Turn: t, calls: pending})
+		pending = nil
+	}
+
+	for _, e := range timeline {
+		if e.Kind != "message" || e.Message == nil {
+			continue
+		}
+		m := e.Message
+		switch m.Role {
+		case "user":
+			if len(pending) > 0 {
+				flush(Turn{Role: "assistant", Time: e.Timestamp})
+			}
+			flush(Turn{Role: "user", Content: opts.redact(m.Content), Time: e.Timestamp, Media: e.Media})
+		case "assistant":
+			for _, tc := range m.ToolCalls {
+				name, args := callArgs(tc)
+				if name == "message" {
+					continue // its text becomes the turn itself
+				}
+				call := &ToolCall{Name: name, Args: opts.redact(hideArgs(name, args, opts.Sensitive))}
+				byID[tc.ID] = call
+				pending = append(pending, call)
+			}
+			if len(m.ToolCalls) == 0 && strings.TrimSpace(m.Content) != "" {
+				flush(Turn{Role: "assistant", Content: opts.redact(m.Content), Time: e.Timestamp, Media: e.Media})
+			}
+		case "tool":
+			if m.ToolName == "message" {
+				flush(Turn{Role: "assistant", Content: opts.redact(m.Content), Time: e.Timestamp})
+				continue
+			}
+			if call, ok := byID[m.ToolCallID]; ok {
+				call.Result = opts.redact(utils.Truncate(m.Content, maxToolText))
+			}
+		}
+	}
+	if len(pending) > 0 {
+		flush(Turn{Role: "assistant"})
+	}
+
+	out := make([]Turn, len(turns))
+	for i, b := range turns {
+		out[i] = b.Turn
+		for _, c := range b.calls {
+			out[i].Tools = append(out[i].Tools, *c)
+		}
+	}
+	return out
+}
+
+func (o Options) redact(s string) string {
+	if o.Redact == nil {
+		return s
+	}
+	return o.Redact(s)
+}
+
+func (o Options) format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	loc := o.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format("2006-01-02 15:04")
+}
+
+func callArgs(tc providers.ToolCall) (string, map[string]any) {
+	name, args := tc.Name, tc.Arguments
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if args == nil && tc.Function.Arguments != "" {
+			json.Unmarshal([]byte(tc.Function.Arguments), &args)
+		}
+	}
+	return name, args
+}
+
+// hideArgs renders args as JSON with sensitive values replaced.
+func hideArgs(tool string, args map[string]any, sensitive map[string][]string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	shown := make(map[string]any, len(args))
+	for k, v := range args {
+		if slices.Contains(sensitive[tool], k) || secretArgRe.MatchString(k) {
+			shown[k] = Placeholder
+			continue
+		}
+		shown[k] = v
+	}
+	data, err := json.Marshal(shown)
+	if err != nil {
+		return Placeholder
+	}
+	return utils.Truncate(string(data), maxToolText)
+}
+
+// ParseFormat accepts "markdown", "md" or "html"; empty means markdown.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case FormatMarkdown, "md", "":
+		return FormatMarkdown, nil
+	case FormatHTML, "htm":
+		return FormatHTML, nil
+	}
+	return "", fmt.Errorf("unknown format %q (use markdown or html)", s)
+}
+
+// Extension is the file extension for a format from ParseFormat.
+func Extension(format string) string {
+	if format == FormatHTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+// ErrNoSession is returned by Export for an unknown or empty session.
+var ErrNoSession = errors.New("session not found")
+
+// Export renders session key from sm in format. The title defaults to the
+// session key.
+func Export(w io.Writer, sm *session.SessionManager, key, format string, opts Options) error {
+	timeline := sm.GetTimeline(key)
+	if len(timeline) == 0 {
+		return ErrNoSession
+	}
+	if opts.Title == "" {
+		opts.Title = key
+	}
+	turns := Turns(timeline, opts)
+	if format == FormatHTML {
+		return WriteHTML(w, turns, opts)
+	}
+	return WriteMarkdown(w, turns, opts)
+}
+
+func roleLabel(role string) string {
+	if role == "user" {
+		return "You"
+	}
+	return "Assistant"
+}
+
+func toolSummary(calls []ToolCall) string {
+	names := make([]string, 0, len(calls))
+	for _, c := range calls {
+		if !slices.Contains(names, c.Name) {
+			names = append(names, c.Name)
+		}
+	}
+	noun := "tool calls"
+	if len(calls) == 1 {
+		noun = "tool call"
+	}
+	return fmt.Sprintf("%d %s: %s", len(calls), noun, strings.Join(names, ", "))
+}
+
+// dataURI returns an inline image for path, or "" when the file is not an
+// image, is missing or is too large.
+func dataURI(path string) string {
+	typ := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if !strings.HasPrefix(typ, "image/") {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxEmbedBytes {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,127 @@
+package transcript
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/providers"
+	"localagent/pkg/session"
+)
+
+func entry(m providers.Message) session.TimelineEntry {
+	return session.TimelineEntry{Kind: "message", Message: &m, Timestamp: time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)}
+}
+
+func sampleTimeline() []session.TimelineEntry {
+	return []session.TimelineEntry{
+		entry(providers.Message{Role: "user", Content: "store my key"}),
+		entry(providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{
+			{ID: "1", Name: "vars", Arguments: map[string]any{"action": "set", "name": "gh", "value": "hunter2"}},
+			{ID: "2", Name: "web_fetch", Arguments: map[string]any{"url": "https://x", "api_key": "abc"}},
+		}}),
+		entry(providers.Message{Role: "tool", ToolCallID: "1", Content: "stored"}),
+		entry(providers.Message{Role: "tool", ToolCallID: "2", Content: "page"}),
+		entry(providers.Message{Role: "assistant", Content: "Done."}),
+	}
+}
+
+func TestTurnsGroupsToolCallsAndHidesSensitiveArgs(t *testing.T) {
+	turns := Turns(sampleTimeline(), Options{Sensitive: map[string][]string{"vars": {"value"}}})
+	if len(turns) != 2 {
+		t.Fatalf("got %d turns, want 2: %+v", len(turns), turns)
+	}
+	if turns[0].Role != "user" || turns[1].Role != "assistant" || turns[1].Content != "Done." {
+		t.Fatalf("unexpected turns: %+v", turns)
+	}
+	calls := turns[1].Tools
+	if len(calls) != 2 || calls[0].Result != "stored" || calls[1].Result != "page" {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+	for _, c := range calls {
+		if strings.Contains(c.Args, "hunter2") || strings.Contains(c.Args, "abc") {
+			t.Errorf("%s args leak a secret: %s", c.Name, c.Args)
+		}
+	}
+	if !strings.Contains(calls[0].Args, `"name":"gh"`) {
+		t.Errorf("non-sensitive args should be kept: %s", calls[0].Args)
+	}
+}
+
+func TestTurnsMessageToolResultArrivingLater(t *testing.T) {
+	timeline := []session.TimelineEntry{
+		entry(providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{
+			{ID: "1", Name: "message", Arguments: map[string]any{"content": "On it"}},
+			{ID: "2", Name: "exec", Arguments: map[string]any{"command": "ls"}},
+		}}),
+		entry(providers.Message{Role: "tool", ToolCallID: "1", ToolName: "message", Content: "On it"}),
+		entry(providers.Message{Role: "tool", ToolCallID: "2", Content: "a.txt"}),
+	}
+	turns := Turns(timeline, Options{})
+	if len(turns) != 1 || turns[0].Content != "On it" {
+		t.Fatalf("unexpected turns: %+v", turns)
+	}
+	if len(turns[0].Tools) != 1 || turns[0].Tools[0].Result != "a.txt" {
+		t.Fatalf("late result not attached: %+v", turns[0].Tools)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	img := filepath.Join(t.TempDir(), "cat.png")
+	os.WriteFile(img, []byte("\x89PNG"), 0644)
+	turns := Turns(sampleTimeline(), Options{})
+	turns[0].Media = []string{img, "/missing/notes.pdf"}
+
+	var buf bytes.Buffer
+	opts := Options{Title: "web:default", Location: time.UTC}
+	if err := WriteMarkdown(&buf, turns, opts); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# web:default",
+		"### You · 2026-03-01 09:30",
+		"<summary>2 tool calls: vars, web_fetch</summary>",
+		"![cat.png](data:image/png;base64,",
+		"*Attachment: notes.pdf*",
+		"Done.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTMLEscapes(t *testing.T) {
+	turns := []Turn{{Role: "assistant", Content: "<script>alert(1)</script>"}}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, turns, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<script>alert") {
+		t.Errorf("content not escaped:\n%s", buf.String())
+	}
+}
+
+func TestExportUnknownSession(t *testing.T) {
+	sm := session.NewSessionManager(t.TempDir())
+	err := Export(&bytes.Buffer{}, sm, "nope", FormatMarkdown, Options{})
+	if !errors.Is(err, ErrNoSession) {
+		t.Fatalf("got %v, want ErrNoSession", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]string{"": FormatMarkdown, "md": FormatMarkdown, "HTML": FormatHTML} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("expected error for pdf")
+	}
+}
@@ -0,0 +1,60 @@
+package transit
+
+import (
+	"context"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+const (
+	stateNamespace  = "transit"
+	briefingTimeout = 15 * time.Second
+	briefingLimit   = 6
+)
+
+// Briefing adds the next departures from one stop to the first heartbeat
+// of the morning.
+type Briefing struct {
+	source     Source
+	stop       Stop
+	start, end time.Duration // morning window, offsets from local midnight
+	state      *state.Manager
+}
+
+func NewBriefing(source Source, stop Stop, start, end time.Duration, workspace string) *Briefing {
+	return &Briefing{source: source, stop: stop, start: start, end: end, state: state.NewManager(workspace)}
+}
+
+// Briefing returns the departures once a day within the morning window,
+// or "". A failed lookup is retried on the next heartbeat.
+func (b *Briefing) Briefing(now time.Time) string {
+	local := now.In(when.Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	if offset := local.Sub(midnight); offset < b.start || offset >= b.end {
+		return ""
+	}
+	today := local.Format("2006-01-02")
+	var briefed string
+	b.state.Get(stateNamespace, "briefed", &briefed)
+	if briefed == today {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), briefingTimeout)
+	defer cancel()
+	deps, err := b.source.Departures(ctx, b.stop, now)
+	if err != nil {
+		logger.Warn("transit: departures for briefing failed: %v", err)
+		return ""
+	}
+	if err := b.state.Set(stateNamespace, "briefed", today); err != nil {
+		logger.Warn("transit: failed to save briefing state: %v", err)
+	}
+	deps = Upcoming(deps, b.stop, now, briefingLimit)
+	if len(deps) == 0 {
+		return ""
+	}
+	return "Next departures from " + b.stop.Name + " (mention them in a morning briefing): " + Summary(deps, now)
+}
@@ -0,0 +1,247 @@
+package transit
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/httpclient"
+)
+
+// apiKeyPlaceholder in a feed URL is replaced with the API key, for feeds
+// that take it as a query parameter; otherwise it is sent as x-api-key.
+const apiKeyPlaceholder = "{api_key}"
+
+// feedTTL is how long a fetched feed is reused, so looking up several
+// stops costs one download.
+const feedTTL = 30 * time.Second
+
+// GTFSRT reads departures from a GTFS-Realtime TripUpdates feed. Only
+// predicted times are known, so routes are reported by route_id and there
+// are no headsigns or scheduled-only departures.
+type GTFSRT struct {
+	FeedURL string
+	APIKey  string
+	client  *http.Client
+
+	mu      sync.Mutex
+	updates []tripUpdate
+	fetched time.Time
+}
+
+func NewGTFSRT(feedURL, apiKey string) *GTFSRT {
+	return &GTFSRT{
+		FeedURL: feedURL,
+		APIKey:  apiKey,
+		client:  httpclient.New("transit", httpclient.WithTimeout(20*time.Second)),
+	}
+}
+
+// tripUpdate is the part of a GTFS-RT TripUpdate used here.
+type tripUpdate struct {
+	RouteID string
+	Stops   []stopTimeUpdate
+}
+
+type stopTimeUpdate struct {
+	StopID string
+	Time   int64 // unix seconds, departure or else arrival
+	Delay  int32 // seconds
+}
+
+func (g *GTFSRT) Departures(ctx context.Context, stop Stop, now time.Time) ([]Departure, error) {
+	updates, err := g.feed(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var deps []Departure
+	for _, u := range updates {
+		for _, s := range u.Stops {
+			if s.StopID != stop.ID || s.Time == 0 {
+				continue
+			}
+			deps = append(deps, Departure{
+				Route:    u.RouteID,
+				Time:     time.Unix(s.Time, 0),
+				Delay:    time.Duration(s.Delay) * time.Second,
+				Realtime: true,
+			})
+		}
+	}
+	return deps, nil
+}
+
+// feed returns the decoded feed, fetching it when the copy is stale.
+func (g *GTFSRT) feed(ctx context.Context, now time.Time) ([]tripUpdate, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.updates != nil && now.Sub(g.fetched) < feedTTL {
+		return g.updates, nil
+	}
+	endpoint := g.FeedURL
+	if strings.Contains(endpoint, apiKeyPlaceholder) {
+		endpoint = strings.ReplaceAll(endpoint, apiKeyPlaceholder, g.APIKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.APIKey != "" && endpoint == g.FeedURL {
+		req.Header.Set("x-api-key", g.APIKey)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gtfs-rt feed: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	updates, err := decodeFeed(data)
+	if err != nil {
+		return nil, fmt.Errorf("gtfs-rt feed: %w", err)
+	}
+	g.updates, g.fetched = updates, now
+	return updates, nil
+}
+
+// Field numbers from gtfs-realtime.proto.
+const (
+	feedMessageEntity       = 2 // FeedMessage.entity
+	feedEntityTripUpdate    = 3 // FeedEntity.trip_update
+	tripUpdateTrip          = 1 // TripUpdate.trip
+	tripUpdateStopTime      = 2 // TripUpdate.stop_time_update
+	tripDescriptorRouteID   = 5 // TripDescriptor.route_id
+	stopTimeUpdateArrival   = 2 // StopTimeUpdate.arrival
+	stopTimeUpdateDeparture = 3 // StopTimeUpdate.departure
+	stopTimeUpdateStopID    = 4 // StopTimeUpdate.stop_id
+	stopTimeEventDelay      = 1 // StopTimeEvent.delay
+	stopTimeEventTime       = 2 // StopTimeEvent.time
+)
+
+// decodeFeed extracts the trip updates from a serialized FeedMessage.
+func decodeFeed(data []byte) ([]tripUpdate, error) {
+	updates := []tripUpdate{}
+	err := eachField(data, func(field int, _ uint64, msg []byte) error {
+		if field != feedMessageEntity {
+			return nil
+		}
+		return eachField(msg, func(field int, _ uint64, msg []byte) error {
+			if field != feedEntityTripUpdate {
+				return nil
+			}
+			u, err := decodeTripUpdate(msg)
+			if err == nil {
+				updates = append(updates, u)
+			}
+			return err
+		})
+	})
+	return updates, err
+}
+
+func decodeTripUpdate(data []byte) (tripUpdate, error) {
+	var u tripUpdate
+	err := eachField(data, func(field int, _ uint64, msg []byte) error {
+		switch field {
+		case tripUpdateTrip:
+			return eachField(msg, func(field int, _ uint64, b []byte) error {
+				if field == tripDescriptorRouteID {
+					u.RouteID = string(b)
+				}
+				return nil
+			})
+		case tripUpdateStopTime:
+			var s stopTimeUpdate
+			var arrival stopTimeUpdate
+			err := eachField(msg, func(field int, _ uint64, b []byte) error {
+				switch field {
+				case stopTimeUpdateStopID:
+					s.StopID = string(b)
+				case stopTimeUpdateDeparture:
+					return decodeStopTimeEvent(b, &s)
+				case stopTimeUpdateArrival:
+					return decodeStopTimeEvent(b, &arrival)
+				}
+				return nil
+			})
+			if s.Time == 0 {
+				s.Time, s.Delay = arrival.Time, arrival.Delay
+			}
+			u.Stops = append(u.Stops, s)
+			return err
+		}
+		return nil
+	})
+	return u, err
+}
+
+func decodeStopTimeEvent(data []byte, s *stopTimeUpdate) error {
+	return eachField(data, func(field int, v uint64, _ []byte) error {
+		switch field {
+		case stopTimeEventDelay:
+			s.Delay = int32(v)
+		case stopTimeEventTime:
+			s.Time = int64(v)
+		}
+		return nil
+	})
+}
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// eachField walks the fields of a protobuf message, calling fn with the
+// value of varint and fixed-size fields and the bytes of length-delimited
+// ones.
+func eachField(data []byte, fn func(field int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field, wire := int(key>>3), key&7
+		var v uint64
+		var b []byte
+		switch wire {
+		case 0: // varint
+			v, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case 1: // fixed64
+			if len(data) < 8 {
+				return errTruncated
+			}
+			v, data = binary.LittleEndian.Uint64(data), data[8:]
+		case 2: // length-delimited
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			b, data = data[n:n+int(l)], data[n+int(l):]
+		case 5: // fixed32
+			if len(data) < 4 {
+				return errTruncated
+			}
+			v, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wire)
+		}
+		if err := fn(field, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
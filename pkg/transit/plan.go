@@ -0,0 +1,181 @@
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+	"localagent/pkg/utils"
+	"localagent/pkg/when"
+)
+
+// Planner plans journeys with an OpenTripPlanner server's plan endpoint.
+type Planner struct {
+	URL    string
+	client *http.Client
+}
+
+func NewPlanner(planURL string) *Planner {
+	return &Planner{URL: planURL, client: httpclient.New("transit", httpclient.WithTimeout(30*time.Second))}
+}
+
+// Leg is one part of an itinerary: a walk or a ride.
+type Leg struct {
+	Mode     string // WALK, BUS, TRAM, RAIL, SUBWAY, ...
+	Route    string
+	Headsign string
+	From     string
+	To       string
+	Start    time.Time
+	End      time.Time
+}
+
+// Itinerary is one way to make the journey.
+type Itinerary struct {
+	Start     time.Time
+	End       time.Time
+	Transfers int
+	Legs      []Leg
+}
+
+// Format describes the itinerary on one line, e.g. "08:05-08:41 (36 min,
+// 1 transfer): walk 4 min; bus 12 to Downtown 08:09 Main St -> 08:30
+// Central; walk 6 min".
+func (it Itinerary) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s-%s (%d min", it.Start.In(when.Location()).Format("15:04"), it.End.In(when.Location()).Format("15:04"), int(it.End.Sub(it.Start)/time.Minute))
+	switch it.Transfers {
+	case 0:
+	case 1:
+		sb.WriteString(", 1 transfer")
+	default:
+		fmt.Fprintf(&sb, ", %d transfers", it.Transfers)
+	}
+	sb.WriteString("): ")
+	for i, l := range it.Legs {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		if l.Mode == "WALK" {
+			fmt.Fprintf(&sb, "walk %d min", max(int(l.End.Sub(l.Start)/time.Minute), 1))
+			continue
+		}
+		fmt.Fprintf(&sb, "%s %s", strings.ToLower(l.Mode), l.Route)
+		if l.Headsign != "" {
+			fmt.Fprintf(&sb, " to %s", l.Headsign)
+		}
+		fmt.Fprintf(&sb, " %s %s -> %s %s", l.Start.In(when.Location()).Format("15:04"), l.From, l.End.In(when.Location()).Format("15:04"), l.To)
+	}
+	return sb.String()
+}
+
+type otpPlace struct {
+	Name string `json:"name"`
+}
+
+type otpResponse struct {
+	Plan *struct {
+		Itineraries []struct {
+			StartTime int64 `json:"startTime"`
+			EndTime   int64 `json:"endTime"`
+			Transfers int   `json:"transfers"`
+			Legs      []struct {
+				Mode           string   `json:"mode"`
+				Route          string   `json:"route"`
+				RouteShortName string   `json:"routeShortName"`
+				Headsign       string   `json:"headsign"`
+				StartTime      int64    `json:"startTime"`
+				EndTime        int64    `json:"endTime"`
+				From           otpPlace `json:"from"`
+				To             otpPlace `json:"to"`
+			} `json:"legs"`
+		} `json:"itineraries"`
+	} `json:"plan"`
+	Error *struct {
+		Msg     string `json:"msg"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Plan finds up to three itineraries from one stop to another, leaving at
+// at, or arriving by it when arriveBy is set. Both stops need coordinates.
+func (p *Planner) Plan(ctx context.Context, from, to Stop, at time.Time, arriveBy bool) ([]Itinerary, error) {
+	for _, s := range []Stop{from, to} {
+		if !s.Located() {
+			return nil, fmt.Errorf("%s has no coordinates (set lat and lon)", s.Name)
+		}
+	}
+	local := at.In(when.Location())
+	q := url.Values{
+		"fromPlace":      {fmt.Sprintf("%f,%f", from.Lat, from.Lon)},
+		"toPlace":        {fmt.Sprintf("%f,%f", to.Lat, to.Lon)},
+		"date":           {local.Format("01-02-2006")},
+		"time":           {local.Format("15:04")},
+		"arriveBy":       {fmt.Sprint(arriveBy)},
+		"mode":           {"TRANSIT,WALK"},
+		"numItineraries": {"3"},
+	}
+	sep := "?"
+	if strings.Contains(p.URL, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL+sep+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("trip planner: HTTP %d: %s", resp.StatusCode, utils.Truncate(strings.TrimSpace(string(data)), 300))
+	}
+	var r otpResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("trip planner: decode response: %w", err)
+	}
+	if r.Error != nil {
+		msg := r.Error.Message
+		if msg == "" {
+			msg = r.Error.Msg
+		}
+		return nil, fmt.Errorf("trip planner: %s", msg)
+	}
+	if r.Plan == nil {
+		return nil, nil
+	}
+
+	var out []Itinerary
+	for _, it := range r.Plan.Itineraries {
+		itin := Itinerary{Start: time.UnixMilli(it.StartTime), End: time.UnixMilli(it.EndTime), Transfers: it.Transfers}
+		for _, l := range it.Legs {
+			route := l.RouteShortName
+			if route == "" {
+				route = l.Route
+			}
+			itin.Legs = append(itin.Legs, Leg{
+				Mode:     l.Mode,
+				Route:    route,
+				Headsign: l.Headsign,
+				From:     l.From.Name,
+				To:       l.To.Name,
+				Start:    time.UnixMilli(l.StartTime),
+				End:      time.UnixMilli(l.EndTime),
+			})
+		}
+		out = append(out, itin)
+	}
+	return out, nil
+}
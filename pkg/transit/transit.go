@@ -0,0 +1,165 @@
+// Package transit looks up the next public transport departures from the
+// user's stops, through Transitland or a GTFS-RT feed, and plans journeys
+// through an OpenTripPlanner server.
+package transit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/config"
+)
+
+// Stop is a configured stop, e.g. "home".
+type Stop struct {
+	Name   string
+	ID     string
+	Routes []string // only these routes when set
+	Lat    float64
+	Lon    float64
+}
+
+// Located reports whether the stop has coordinates for journey planning.
+func (s Stop) Located() bool {
+	return s.Lat != 0 || s.Lon != 0
+}
+
+// Stops converts the configured stops.
+func Stops(cfgs []config.TransitStopConfig) []Stop {
+	stops := make([]Stop, len(cfgs))
+	for i, c := range cfgs {
+		stops[i] = Stop{Name: c.Name, ID: c.StopID, Routes: c.Routes, Lat: c.Lat, Lon: c.Lon}
+	}
+	return stops
+}
+
+// Lookup finds a stop by name, ignoring case.
+func Lookup(stops []Stop, name string) (Stop, bool) {
+	for _, s := range stops {
+		if strings.EqualFold(s.Name, strings.TrimSpace(name)) {
+			return s, true
+		}
+	}
+	return Stop{}, false
+}
+
+// Departure is a vehicle leaving a stop.
+type Departure struct {
+	Route    string
+	Headsign string
+	Time     time.Time     // expected departure, real-time when known
+	Delay    time.Duration // against the schedule, when known
+	Realtime bool
+}
+
+// Source looks up departures from a stop.
+type Source interface {
+	Departures(ctx context.Context, stop Stop, now time.Time) ([]Departure, error)
+}
+
+// New returns the departure source for cfg.Provider.
+func New(cfg config.TransitConfig) (Source, error) {
+	switch cfg.Provider {
+	case "transitland":
+		key := cfg.ResolveAPIKey()
+		if key == "" {
+			return nil, fmt.Errorf("transitland needs an API key")
+		}
+		return NewTransitland(key), nil
+	case "gtfs_rt":
+		if cfg.FeedURL == "" {
+			return nil, fmt.Errorf("gtfs_rt needs feed_url")
+		}
+		return NewGTFSRT(cfg.FeedURL, cfg.ResolveAPIKey()), nil
+	}
+	return nil, fmt.Errorf("unknown transit provider %q (use transitland or gtfs_rt)", cfg.Provider)
+}
+
+// Domains lists the hosts cfg makes the transit tool talk to.
+func Domains(cfg config.TransitConfig) []string {
+	var domains []string
+	if cfg.Provider == "transitland" {
+		domains = append(domains, "transit.land")
+	}
+	for _, raw := range []string{cfg.FeedURL, cfg.PlanURL} {
+		if u, err := url.Parse(strings.ReplaceAll(raw, apiKeyPlaceholder, "")); err == nil && u.Host != "" {
+			domains = append(domains, u.Host)
+		}
+	}
+	return domains
+}
+
+// Upcoming returns the departures on the stop's routes that haven't left
+// by now, soonest first, at most limit of them.
+func Upcoming(deps []Departure, stop Stop, now time.Time, limit int) []Departure {
+	var out []Departure
+	for _, d := range deps {
+		if d.Time.Before(now.Add(-30 * time.Second)) {
+			continue
+		}
+		if len(stop.Routes) > 0 && !slices.ContainsFunc(stop.Routes, func(r string) bool { return strings.EqualFold(r, d.Route) }) {
+			continue
+		}
+		out = append(out, d)
+	}
+	slices.SortFunc(out, func(a, b Departure) int { return a.Time.Compare(b.Time) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// minutes is how many whole minutes from now t is, never negative.
+func minutes(t, now time.Time) int {
+	return max(int(t.Sub(now)/time.Minute), 0)
+}
+
+// Format lists departures one per line, e.g.
+// "- 12 to Downtown in 12 min (08:12, 2 min late)".
+func Format(deps []Departure, now time.Time) string {
+	var sb strings.Builder
+	for _, d := range deps {
+		fmt.Fprintf(&sb, "- %s", d.Route)
+		if d.Headsign != "" {
+			fmt.Fprintf(&sb, " to %s", d.Headsign)
+		}
+		fmt.Fprintf(&sb, " in %d min (%s", minutes(d.Time, now), d.Time.Format("15:04"))
+		switch {
+		case d.Delay >= time.Minute:
+			fmt.Fprintf(&sb, ", %d min late", int(d.Delay/time.Minute))
+		case d.Delay <= -time.Minute:
+			fmt.Fprintf(&sb, ", %d min early", int(-d.Delay/time.Minute))
+		case !d.Realtime:
+			sb.WriteString(", scheduled")
+		}
+		sb.WriteString(")\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Summary groups departures by route in one line, e.g.
+// "12 in 12 and 27 min; N4 in 5 min".
+func Summary(deps []Departure, now time.Time) string {
+	var routes []string
+	waits := make(map[string][]string)
+	for _, d := range deps {
+		if _, ok := waits[d.Route]; !ok {
+			routes = append(routes, d.Route)
+		}
+		waits[d.Route] = append(waits[d.Route], fmt.Sprint(minutes(d.Time, now)))
+	}
+	parts := make([]string, len(routes))
+	for i, r := range routes {
+		w := waits[r]
+		list := w[0]
+		if n := len(w); n > 1 {
+			list = strings.Join(w[:n-1], ", ") + " and " + w[n-1]
+		}
+		parts[i] = fmt.Sprintf("%s in %s min", r, list)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,199 @@
+package transit
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/when"
+)
+
+// pb appends protobuf fields: ints as varints, strings and []byte as
+// length-delimited fields.
+func pb(fields ...any) []byte {
+	var out []byte
+	for i := 0; i < len(fields); i += 2 {
+		field := uint64(fields[i].(int))
+		switch v := fields[i+1].(type) {
+		case int:
+			out = binary.AppendUvarint(out, field<<3)
+			out = binary.AppendUvarint(out, uint64(int64(v)))
+		case string:
+			out = binary.AppendUvarint(out, field<<3|2)
+			out = binary.AppendUvarint(out, uint64(len(v)))
+			out = append(out, v...)
+		case []byte:
+			out = binary.AppendUvarint(out, field<<3|2)
+			out = binary.AppendUvarint(out, uint64(len(v)))
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+func TestGTFSRTDepartures(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	stopTime := func(stop string, at time.Time, delay int) []byte {
+		return pb(stopTimeUpdateStopID, stop, stopTimeUpdateDeparture, pb(stopTimeEventDelay, delay, stopTimeEventTime, int(at.Unix())))
+	}
+	trip := func(route string, stops ...[]byte) []byte {
+		fields := []any{tripUpdateTrip, pb(1, "trip-"+route, tripDescriptorRouteID, route)}
+		for _, s := range stops {
+			fields = append(fields, tripUpdateStopTime, s)
+		}
+		return pb(feedEntityTripUpdate, pb(fields...))
+	}
+	feed := pb(
+		1, pb(1, "2.0"),
+		feedMessageEntity, trip("12", stopTime("A", now.Add(12*time.Minute), 120), stopTime("B", now.Add(20*time.Minute), 0)),
+		feedMessageEntity, trip("N4", stopTime("A", now.Add(5*time.Minute), -60)),
+		feedMessageEntity, trip("12", stopTime("A", now.Add(27*time.Minute), 0)),
+		feedMessageEntity, trip("7", stopTime("A", now.Add(-5*time.Minute), 0)),
+	)
+
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.Header.Get("x-api-key") != "k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(feed)
+	}))
+	defer srv.Close()
+
+	g := NewGTFSRT(srv.URL, "k")
+	stop := Stop{Name: "home", ID: "A"}
+	deps, err := g.Departures(context.Background(), stop, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps = Upcoming(deps, stop, now, 5)
+	if got := Summary(deps, now); got != "N4 in 5 min; 12 in 12 and 27 min" {
+		t.Errorf("summary = %q", got)
+	}
+	if deps[0].Delay != -time.Minute || deps[1].Delay != 2*time.Minute {
+		t.Errorf("delays = %v, %v", deps[0].Delay, deps[1].Delay)
+	}
+	if got := Format(deps[1:2], now); !strings.Contains(got, "12 in 12 min") || !strings.Contains(got, "2 min late") {
+		t.Errorf("format = %q", got)
+	}
+
+	stop.Routes = []string{"12"}
+	deps, _ = g.Departures(context.Background(), stop, now.Add(time.Second))
+	if got := Summary(Upcoming(deps, stop, now, 5), now); got != "12 in 12 and 27 min" {
+		t.Errorf("filtered summary = %q", got)
+	}
+	if fetches != 1 {
+		t.Errorf("feed fetched %d times, want 1 (cached)", fetches)
+	}
+}
+
+func TestDecodeFeedTruncated(t *testing.T) {
+	feed := pb(feedMessageEntity, pb(feedEntityTripUpdate, pb(tripUpdateTrip, pb(tripDescriptorRouteID, "12"))))
+	if _, err := decodeFeed(feed[:len(feed)-1]); err == nil {
+		t.Error("expected an error for a truncated feed")
+	}
+}
+
+func TestServiceTimePastMidnight(t *testing.T) {
+	when.SetLocation(time.UTC)
+	got := serviceTime("2026-03-02", "25:10:00")
+	if want := time.Date(2026, 3, 3, 1, 10, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("serviceTime = %v, want %v", got, want)
+	}
+}
+
+func TestTransitland(t *testing.T) {
+	when.SetLocation(time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("apikey") != "k" || !strings.HasSuffix(r.URL.Path, "/stops/s-abc/departures") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"stops":[{"departures":[
+			{"service_date":"2026-03-02","departure_time":"08:12:00","departure":{"scheduled":"08:12:00","estimated":"08:14:00","delay":120},
+			 "trip":{"trip_headsign":"Downtown","route":{"route_short_name":"12"}}},
+			{"service_date":"2026-03-02","departure_time":"08:30:00","departure":{},
+			 "trip":{"trip_headsign":"Airport","route":{"route_long_name":"Airport Express"}}}]}]}`))
+	}))
+	defer srv.Close()
+
+	tl := NewTransitland("k")
+	tl.BaseURL = srv.URL
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	deps, err := tl.Departures(context.Background(), Stop{ID: "s-abc"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Format(deps, now)
+	want := "- 12 to Downtown in 14 min (08:14, 2 min late)\n- Airport Express to Airport in 30 min (08:30, scheduled)"
+	if got != want {
+		t.Errorf("format =\n%s\nwant\n%s", got, want)
+	}
+}
+
+type fakeSource struct {
+	deps  []Departure
+	calls int
+}
+
+func (f *fakeSource) Departures(context.Context, Stop, time.Time) ([]Departure, error) {
+	f.calls++
+	return f.deps, nil
+}
+
+func TestBriefingOncePerMorning(t *testing.T) {
+	when.SetLocation(time.UTC)
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	src := &fakeSource{deps: []Departure{{Route: "12", Time: day.Add(7*time.Hour + 12*time.Minute)}}}
+	b := NewBriefing(src, Stop{Name: "home"}, 6*time.Hour, 9*time.Hour+30*time.Minute, t.TempDir())
+
+	if got := b.Briefing(day.Add(5 * time.Hour)); got != "" {
+		t.Errorf("before the window: %q", got)
+	}
+	if got := b.Briefing(day.Add(7 * time.Hour)); !strings.Contains(got, "home") || !strings.Contains(got, "12 in 12 min") {
+		t.Errorf("briefing = %q", got)
+	}
+	if got := b.Briefing(day.Add(8 * time.Hour)); got != "" {
+		t.Errorf("second briefing the same day: %q", got)
+	}
+	if src.calls != 1 {
+		t.Errorf("source called %d times", src.calls)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	when.SetLocation(time.UTC)
+	start := time.Date(2026, 3, 2, 8, 5, 0, 0, time.UTC)
+	ms := func(d time.Duration) int64 { return start.Add(d).UnixMilli() }
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{"plan":{"itineraries":[{"startTime":` + strconv.FormatInt(ms(0), 10) + `,"endTime":` + strconv.FormatInt(ms(36*time.Minute), 10) + `,"transfers":0,"legs":[
+			{"mode":"WALK","startTime":` + strconv.FormatInt(ms(0), 10) + `,"endTime":` + strconv.FormatInt(ms(4*time.Minute), 10) + `,"from":{"name":"Origin"},"to":{"name":"Main St"}},
+			{"mode":"BUS","routeShortName":"12","headsign":"Downtown","startTime":` + strconv.FormatInt(ms(4*time.Minute), 10) + `,"endTime":` + strconv.FormatInt(ms(36*time.Minute), 10) + `,"from":{"name":"Main St"},"to":{"name":"Central"}}]}]}}`))
+	}))
+	defer srv.Close()
+
+	p := NewPlanner(srv.URL + "/otp/routers/default/plan")
+	if _, err := p.Plan(context.Background(), Stop{Name: "home"}, Stop{Name: "work", Lat: 1, Lon: 2}, start, false); err == nil {
+		t.Error("expected an error for a stop without coordinates")
+	}
+	its, err := p.Plan(context.Background(), Stop{Name: "home", Lat: 47.5, Lon: 8.5}, Stop{Name: "work", Lat: 47.4, Lon: 8.6}, start, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "arriveBy=true") || !strings.Contains(query, "fromPlace=47.500000%2C8.500000") {
+		t.Errorf("query = %s", query)
+	}
+	want := "08:05-08:41 (36 min): walk 4 min; bus 12 to Downtown 08:09 Main St -> 08:41 Central"
+	if len(its) != 1 || its[0].Format() != want {
+		t.Errorf("itineraries = %+v", its)
+	}
+}
@@ -0,0 +1,146 @@
+package transit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+	"localagent/pkg/utils"
+	"localagent/pkg/when"
+)
+
+// Transitland uses the Transitland REST API, which merges schedules with
+// real-time updates where the agency publishes them.
+type Transitland struct {
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
+
+func NewTransitland(apiKey string) *Transitland {
+	return &Transitland{
+		BaseURL: "https://transit.land/api/v2/rest",
+		APIKey:  apiKey,
+		client:  httpclient.New("transit", httpclient.WithTimeout(20*time.Second)),
+	}
+}
+
+type transitlandTime struct {
+	Scheduled    string `json:"scheduled"`
+	Estimated    string `json:"estimated"`
+	EstimatedUTC string `json:"estimated_utc"`
+	ScheduledUTC string `json:"scheduled_utc"`
+	Delay        *int   `json:"delay"` // seconds
+}
+
+type transitlandResponse struct {
+	Stops []struct {
+		Departures []struct {
+			ServiceDate   string          `json:"service_date"`
+			DepartureTime string          `json:"departure_time"`
+			Departure     transitlandTime `json:"departure"`
+			Trip          struct {
+				Headsign string `json:"trip_headsign"`
+				Route    struct {
+					ShortName string `json:"route_short_name"`
+					LongName  string `json:"route_long_name"`
+				} `json:"route"`
+			} `json:"trip"`
+		} `json:"departures"`
+	} `json:"stops"`
+}
+
+func (t *Transitland) Departures(ctx context.Context, stop Stop, now time.Time) ([]Departure, error) {
+	q := url.Values{"next": {"5400"}, "limit": {"30"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.BaseURL+"/stops/"+url.PathEscape(stop.ID)+"/departures?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", t.APIKey)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transitland: HTTP %d: %s", resp.StatusCode, utils.Truncate(strings.TrimSpace(string(data)), 300))
+	}
+	var r transitlandResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("transitland: decode response: %w", err)
+	}
+	if len(r.Stops) == 0 {
+		return nil, fmt.Errorf("transitland: unknown stop %q", stop.ID)
+	}
+
+	var deps []Departure
+	for _, s := range r.Stops {
+		for _, d := range s.Departures {
+			dep := Departure{Route: d.Trip.Route.ShortName, Headsign: d.Trip.Headsign}
+			if dep.Route == "" {
+				dep.Route = d.Trip.Route.LongName
+			}
+			scheduled := d.Departure.Scheduled
+			if scheduled == "" {
+				scheduled = d.DepartureTime
+			}
+			switch {
+			case d.Departure.EstimatedUTC != "":
+				dep.Time, _ = time.Parse(time.RFC3339, d.Departure.EstimatedUTC)
+				dep.Realtime = true
+			case d.Departure.Estimated != "":
+				dep.Time = serviceTime(d.ServiceDate, d.Departure.Estimated)
+				dep.Realtime = true
+			case d.Departure.ScheduledUTC != "":
+				dep.Time, _ = time.Parse(time.RFC3339, d.Departure.ScheduledUTC)
+			default:
+				dep.Time = serviceTime(d.ServiceDate, scheduled)
+			}
+			if dep.Time.IsZero() {
+				continue
+			}
+			if d.Departure.Delay != nil {
+				dep.Delay = time.Duration(*d.Departure.Delay) * time.Second
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// serviceTime resolves a GTFS "HH:MM:SS" time, which may pass 24:00 for
+// trips running after midnight, on the service date in the user's zone.
+func serviceTime(date, clock string) time.Time {
+	day, err := time.ParseInLocation("2006-01-02", date, when.Location())
+	if err != nil {
+		return time.Time{}
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 {
+		return time.Time{}
+	}
+	var offset time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		if i >= len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return time.Time{}
+		}
+		offset += time.Duration(n) * unit
+	}
+	// Noon minus 12h is the GTFS service day start, correct across DST changes.
+	return time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location()).Add(-12 * time.Hour).Add(offset)
+}
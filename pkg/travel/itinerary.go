@@ -0,0 +1,348 @@
+package travel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"localagent/pkg/when"
+)
+
+// ErrNoItinerary means the confirmation has no schema.org reservation
+// markup; the bookings have to be read from its text instead.
+var ErrNoItinerary = errors.New("no reservation data found in the confirmation")
+
+// ParseConfirmation extracts bookings from a forwarded confirmation email
+// (raw .eml), an HTML page or bare JSON-LD. Airlines, hotels and booking
+// sites embed schema.org Reservation JSON-LD in their emails for calendar
+// and assistant integrations; that is what is read here.
+func ParseConfirmation(data []byte) ([]Item, error) {
+	var docs [][]byte
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		docs = [][]byte{trimmed}
+	default:
+		for _, page := range emailBodies(data) {
+			docs = append(docs, jsonLDScripts(page)...)
+		}
+	}
+	var items []Item
+	for _, d := range docs {
+		var v any
+		if json.Unmarshal(d, &v) != nil {
+			continue
+		}
+		for _, r := range findReservations(v) {
+			if it, ok := reservationItem(r); ok {
+				items = append(items, it)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNoItinerary
+	}
+	sortItems(items)
+	return items, nil
+}
+
+// emailBodies returns the HTML and text parts of a MIME message, decoded.
+// Data that isn't an email is returned as is.
+func emailBodies(data []byte) [][]byte {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil || msg.Header.Get("Content-Type") == "" && msg.Header.Get("Subject") == "" {
+		return [][]byte{data}
+	}
+	var out [][]byte
+	collectParts(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, &out)
+	return out
+}
+
+func collectParts(contentType, encoding string, body io.Reader, out *[][]byte) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			collectParts(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p, out)
+		}
+	}
+	if !strings.HasPrefix(mediaType, "text/") && mediaType != "application/ld+json" {
+		return
+	}
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return
+	}
+	if mediaType == "application/ld+json" {
+		data = []byte(`<script type="application/ld+json">` + string(data) + `</script>`)
+	}
+	*out = append(*out, data)
+}
+
+// newlineStripper drops line breaks, which base64 bodies are wrapped with.
+type newlineStripper struct{ r io.Reader }
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, c := range p[:n] {
+		if c != '\r' && c != '\n' {
+			p[j] = c
+			j++
+		}
+	}
+	return j, err
+}
+
+func jsonLDScripts(page []byte) [][]byte {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	var out [][]byte
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "script" && attr(n, "type") == "application/ld+json" {
+			out = append(out, []byte(textContent(n)))
+		}
+		return true
+	})
+	return out
+}
+
+// findReservations collects objects whose @type is a schema.org
+// Reservation, including inside @graph and arrays.
+func findReservations(v any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		var out []map[string]any
+		for _, item := range v {
+			out = append(out, findReservations(item)...)
+		}
+		return out
+	case map[string]any:
+		if strings.HasSuffix(typeName(v), "Reservation") {
+			return []map[string]any{v}
+		}
+		if g, ok := v["@graph"]; ok {
+			return findReservations(g)
+		}
+	}
+	return nil
+}
+
+// reservationItem turns one reservation into a booking. Cancelled ones
+// are skipped.
+func reservationItem(r map[string]any) (Item, bool) {
+	if strings.HasSuffix(str(r["reservationStatus"]), "Cancelled") {
+		return Item{}, false
+	}
+	it := Item{Reference: str(r["reservationNumber"])}
+	f := obj(r["reservationFor"])
+
+	switch typeName(r) {
+	case "FlightReservation":
+		it.Kind = "flight"
+		from, to := obj(f["departureAirport"]), obj(f["arrivalAirport"])
+		it.Title = strings.TrimSpace(fmt.Sprintf("%s%s %s → %s",
+			first(str(obj(f["airline"])["iataCode"]), str(f["airlineCode"])), str(f["flightNumber"]),
+			first(str(from["iataCode"]), str(from["name"])), first(str(to["iataCode"]), str(to["name"]))))
+		it.StartMS, it.EndMS = parseTime(str(f["departureTime"])), parseTime(str(f["arrivalTime"]))
+		it.Location = first(str(from["name"]), str(from["iataCode"]))
+		if seat := str(obj(r["reservedTicket"])["ticketedSeat"]); seat != "" {
+			it.Details = "seat " + seat
+		} else if seat := str(obj(obj(r["reservedTicket"])["ticketedSeat"])["seatNumber"]); seat != "" {
+			it.Details = "seat " + seat
+		}
+		if gate := str(f["departureGate"]); gate != "" {
+			it.Details = strings.TrimPrefix(it.Details+", gate "+gate, ", ")
+		}
+
+	case "TrainReservation", "BusReservation":
+		it.Kind = "train"
+		fromKey, toKey, number := "departureStation", "arrivalStation", first(str(f["trainNumber"]), str(f["trainName"]))
+		if typeName(r) == "BusReservation" {
+			it.Kind = "bus"
+			fromKey, toKey, number = "departureBusStop", "arrivalBusStop", first(str(f["busNumber"]), str(f["busName"]))
+		}
+		from, to := obj(f[fromKey]), obj(f[toKey])
+		it.Title = strings.TrimSpace(fmt.Sprintf("%s %s → %s", number, str(from["name"]), str(to["name"])))
+		it.StartMS, it.EndMS = parseTime(str(f["departureTime"])), parseTime(str(f["arrivalTime"]))
+		it.Location = str(from["name"])
+
+	case "LodgingReservation":
+		it.Kind = "hotel"
+		it.Title = str(f["name"])
+		it.StartMS = parseTime(first(str(r["checkinTime"]), str(r["checkinDate"])))
+		it.EndMS = parseTime(first(str(r["checkoutTime"]), str(r["checkoutDate"])))
+		it.Location = address(f["address"])
+
+	case "RentalCarReservation":
+		it.Kind = "car"
+		it.Title = strings.TrimSpace(str(obj(f["brand"])["name"]) + " " + str(f["name"]))
+		if it.Title == "" {
+			it.Title = "Rental car"
+		}
+		if company := str(obj(f["rentalCompany"])["name"]); company != "" {
+			it.Title += " (" + company + ")"
+		}
+		it.StartMS, it.EndMS = parseTime(str(r["pickupTime"])), parseTime(str(r["dropoffTime"]))
+		pickup := obj(r["pickupLocation"])
+		it.Location = first(str(pickup["name"]), address(pickup["address"]))
+
+	case "FoodEstablishmentReservation":
+		it.Kind = "restaurant"
+		it.Title = str(f["name"])
+		it.StartMS, it.EndMS = parseTime(str(r["startTime"])), parseTime(str(r["endTime"]))
+		it.Location = address(f["address"])
+
+	default:
+		it.Kind = "event"
+		if typeName(r) != "EventReservation" {
+			it.Kind = "other"
+		}
+		it.Title = str(f["name"])
+		it.StartMS = parseTime(first(str(f["startDate"]), str(r["startTime"])))
+		it.EndMS = parseTime(first(str(f["endDate"]), str(r["endTime"])))
+		loc := obj(f["location"])
+		it.Location = first(str(loc["name"]), address(loc["address"]))
+	}
+	if it.Title == "" || it.StartMS == 0 {
+		return Item{}, false
+	}
+	if it.EndMS <= it.StartMS {
+		it.EndMS = 0
+	}
+	return it, true
+}
+
+// parseTime reads a schema.org DateTime or Date. Values without an offset
+// are taken to be in the agent's timezone.
+func parseTime(s string) int64 {
+	if s == "" {
+		return 0
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UnixMilli()
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, when.Location()); err == nil {
+			return t.UnixMilli()
+		}
+	}
+	return 0
+}
+
+// address flattens a PostalAddress or plain string.
+func address(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	a := obj(v)
+	var parts []string
+	for _, key := range []string{"streetAddress", "addressLocality", "addressCountry"} {
+		val := a[key]
+		if c := obj(val); c != nil {
+			val = c["name"]
+		}
+		if s := str(val); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func typeName(v map[string]any) string {
+	switch t := v["@type"].(type) {
+	case string:
+		return strings.TrimPrefix(t, "http://schema.org/")
+	case []any:
+		if len(t) > 0 {
+			return typeName(map[string]any{"@type": t[0]})
+		}
+	}
+	return ""
+}
+
+func obj(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case []any:
+		if len(v) > 0 {
+			return obj(v[0])
+		}
+	}
+	return nil
+}
+
+func str(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func first(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func walk(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
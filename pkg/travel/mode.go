@@ -0,0 +1,401 @@
+package travel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+	"localagent/pkg/when"
+)
+
+const (
+	modeCheckInterval = 5 * time.Minute
+	calendarHorizon   = 14 * 24 * time.Hour
+	// minTripLength keeps meetings with a location from becoming trips;
+	// all-day and multi-day events qualify.
+	minTripLength = 20 * time.Hour
+	briefingLead  = 48 * time.Hour
+	itemLead      = 3 * time.Hour
+	forecastTTL   = 3 * time.Hour
+	forecastDays  = 3
+
+	stateNamespace = "travel"
+)
+
+// Event is a calendar event that may be a trip.
+type Event struct {
+	UID      string
+	Title    string
+	Location string
+	Start    time.Time
+	End      time.Time
+}
+
+// EventsFunc returns events of at least minDur overlapping [from, to).
+type EventsFunc func(ctx context.Context, from, to time.Time, minDur time.Duration) ([]Event, error)
+
+type cachedForecast struct {
+	days    []Day
+	fetched time.Time
+}
+
+// Mode switches the agent's timezone to the destination of the trip under
+// way and back home when it ends, creates trips from calendar events with
+// a location, and briefs the heartbeat about the trip.
+type Mode struct {
+	svc    *Service
+	home   *time.Location
+	state  *state.Manager
+	client *http.Client
+	events EventsFunc
+	now    func() time.Time
+
+	mu        sync.Mutex
+	current   *time.Location
+	onChange  []func(*time.Location)
+	forecasts map[string]cachedForecast // trip ID
+	unknown   map[string]bool           // calendar locations that didn't geocode
+	stop      chan struct{}
+}
+
+// NewMode creates travel mode with home as the timezone outside trips.
+// Briefing state lives in workspace.
+func NewMode(svc *Service, home *time.Location, workspace string) *Mode {
+	return &Mode{
+		svc:       svc,
+		home:      home,
+		state:     state.NewManager(workspace),
+		client:    newClient(),
+		now:       time.Now,
+		current:   home,
+		forecasts: make(map[string]cachedForecast),
+		unknown:   make(map[string]bool),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Home is the timezone outside trips.
+func (m *Mode) Home() *time.Location {
+	return m.home
+}
+
+// OnChange registers fn to run after the timezone switched.
+func (m *Mode) OnChange(fn func(*time.Location)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = append(m.onChange, fn)
+}
+
+// SetCalendar makes Check create trips from calendar events.
+func (m *Mode) SetCalendar(events EventsFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = events
+}
+
+func (m *Mode) Start() {
+	ticker := time.NewTicker(modeCheckInterval)
+	go func() {
+		m.poll()
+		for {
+			select {
+			case <-ticker.C:
+				m.poll()
+			case <-m.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("travel mode started (home %s)", m.home)
+}
+
+func (m *Mode) Stop() {
+	close(m.stop)
+}
+
+func (m *Mode) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := m.Check(ctx); err != nil {
+		logger.Warn("travel: %v", err)
+	}
+}
+
+// Check syncs trips from the calendar, then applies the active trip's
+// timezone. The timezone is applied even when the calendar fails.
+func (m *Mode) Check(ctx context.Context) error {
+	m.mu.Lock()
+	events := m.events
+	m.mu.Unlock()
+	var err error
+	if events != nil {
+		err = m.syncCalendar(ctx, events)
+	}
+	m.Apply()
+	return err
+}
+
+// syncCalendar creates a trip for each long event away from home in the
+// next two weeks, follows moved events and drops trips whose event was
+// deleted.
+func (m *Mode) syncCalendar(ctx context.Context, events EventsFunc) error {
+	now := m.now()
+	evs, err := events(ctx, now, now.Add(calendarHorizon), minTripLength)
+	if err != nil {
+		return fmt.Errorf("calendar: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, ev := range evs {
+		if ev.UID == "" || strings.TrimSpace(ev.Location) == "" {
+			continue
+		}
+		seen[ev.UID] = true
+		if t := m.svc.ByCalendarUID(ev.UID); t != nil {
+			if t.StartMS != ev.Start.UnixMilli() || t.EndMS != ev.End.UnixMilli() {
+				t.StartMS, t.EndMS = ev.Start.UnixMilli(), ev.End.UnixMilli()
+				if err := m.svc.Save(t); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := m.tripFromEvent(ctx, ev); err != nil {
+			return err
+		}
+	}
+	for _, t := range m.svc.List() {
+		if t.Source == SourceCalendar && !seen[t.CalendarUID] &&
+			t.StartMS > now.UnixMilli() && t.StartMS < now.Add(calendarHorizon).UnixMilli() {
+			m.svc.Remove(t.ID)
+			logger.Info("travel: removed trip to %s, its calendar event is gone", t.Destination)
+		}
+	}
+	return nil
+}
+
+func (m *Mode) tripFromEvent(ctx context.Context, ev Event) error {
+	m.mu.Lock()
+	skip := m.unknown[ev.Location]
+	m.mu.Unlock()
+	if skip {
+		return nil
+	}
+	place, err := Geocode(ctx, m.client, ev.Location)
+	if errors.Is(err, ErrPlaceNotFound) {
+		m.mu.Lock()
+		m.unknown[ev.Location] = true
+		m.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Events in the home timezone don't need travel mode.
+	if place.Timezone == "" || place.Timezone == m.home.String() {
+		m.mu.Lock()
+		m.unknown[ev.Location] = true
+		m.mu.Unlock()
+		return nil
+	}
+	t, err := m.svc.Add(Trip{
+		Destination: place.Label(),
+		Timezone:    place.Timezone,
+		Latitude:    place.Latitude,
+		Longitude:   place.Longitude,
+		StartMS:     ev.Start.UnixMilli(),
+		EndMS:       ev.End.UnixMilli(),
+		Source:      SourceCalendar,
+		CalendarUID: ev.UID,
+		Notes:       ev.Title,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("travel: added trip to %s from calendar event %q", t.Destination, ev.Title)
+	return nil
+}
+
+// Apply sets the agent's timezone to the active trip's, or home, and
+// returns it. OnChange callbacks run when it changed.
+func (m *Mode) Apply() *time.Location {
+	loc := m.home
+	if t := m.svc.Active(m.now()); t != nil {
+		if tl := t.Location(); tl != nil {
+			loc = tl
+		}
+	}
+	m.mu.Lock()
+	changed := loc.String() != m.current.String()
+	m.current = loc
+	callbacks := m.onChange
+	m.mu.Unlock()
+
+	if !changed {
+		return loc
+	}
+	when.SetLocation(loc)
+	logger.Info("travel: timezone is now %s", loc)
+	for _, fn := range callbacks {
+		fn(loc)
+	}
+	return loc
+}
+
+// Note describes travel mode for the system prompt: the trip under way or
+// one starting within two days. Empty when there is neither.
+func (m *Mode) Note() string {
+	now := m.now()
+	if t := m.svc.Active(now); t != nil {
+		s := fmt.Sprintf("Travel mode: the user is in %s", t.Destination)
+		if t.EndMS != 0 {
+			s += " until " + time.UnixMilli(t.EndMS).In(when.Location()).Format("Mon Jan 2 15:04")
+		}
+		if t.Location() != nil {
+			s += fmt.Sprintf(". Times are in destination time (%s); home is %s", t.Timezone, m.home)
+		}
+		return s + "."
+	}
+	if t := m.svc.Next(now); t != nil && time.UnixMilli(t.StartMS).Sub(now) <= briefingLead {
+		return fmt.Sprintf("Upcoming trip: %s from %s.", t.Destination,
+			time.UnixMilli(t.StartMS).In(when.Location()).Format("Mon Jan 2 15:04"))
+	}
+	return ""
+}
+
+// Briefing returns trip details for the heartbeat: once a day the
+// destination weather and the next two days of the itinerary, and
+// bookings starting within three hours that weren't mentioned yet.
+// Empty when there is no trip under way or starting within two days.
+func (m *Mode) Briefing(now time.Time) string {
+	t := m.svc.Active(now)
+	if t == nil {
+		if next := m.svc.Next(now); next != nil && time.UnixMilli(next.StartMS).Sub(now) <= briefingLead {
+			t = next
+		}
+	}
+	if t == nil {
+		return ""
+	}
+	loc := itemLocation(*t, when.Location())
+
+	var announced map[string]int64
+	m.state.Get(stateNamespace, "announced", &announced)
+	if announced == nil {
+		announced = make(map[string]int64)
+	}
+	for k, start := range announced {
+		if start < now.UnixMilli() {
+			delete(announced, k)
+		}
+	}
+
+	var lines []string
+	day := t.ID + " " + now.In(loc).Format("2006-01-02")
+	var briefed string
+	m.state.Get(stateNamespace, "briefed", &briefed)
+	if briefed != day {
+		lines = append(lines, m.tripSummary(t, now, loc)...)
+		for _, it := range t.Upcoming(now, now.Add(briefingLead)) {
+			lines = append(lines, "- "+FormatItem(it, loc))
+			announced[it.key()] = it.StartMS
+		}
+		if err := m.state.Set(stateNamespace, "briefed", day); err != nil {
+			logger.Warn("travel: failed to save briefing state: %v", err)
+		}
+	} else {
+		var soon []string
+		for _, it := range t.Upcoming(now, now.Add(itemLead)) {
+			if _, ok := announced[it.key()]; ok || it.StartMS < now.UnixMilli() {
+				continue
+			}
+			soon = append(soon, "- "+FormatItem(it, loc))
+			announced[it.key()] = it.StartMS
+		}
+		if len(soon) > 0 {
+			lines = append([]string{fmt.Sprintf("Travel (%s): coming up soon:", t.Destination)}, soon...)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if err := m.state.Set(stateNamespace, "announced", announced); err != nil {
+		logger.Warn("travel: failed to save briefing state: %v", err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *Mode) tripSummary(t *Trip, now time.Time, loc *time.Location) []string {
+	var lines []string
+	if t.ActiveAt(now) {
+		lines = append(lines, fmt.Sprintf("Travel: the user is in %s (local time %s).", t.Destination, now.In(loc).Format("15:04")))
+	} else {
+		lines = append(lines, fmt.Sprintf("Travel: trip to %s starts %s.", t.Destination,
+			time.UnixMilli(t.StartMS).In(when.Location()).Format("Mon Jan 2 15:04")))
+	}
+	days, err := m.Forecast(t)
+	if err != nil {
+		logger.Warn("travel: weather for %s: %v", t.Destination, err)
+	}
+	if len(days) > 0 {
+		lines = append(lines, "Weather:")
+		for _, d := range days {
+			lines = append(lines, "- "+d.String())
+		}
+	}
+	if len(t.Upcoming(now, now.Add(briefingLead))) > 0 {
+		lines = append(lines, "Itinerary, next two days:")
+	}
+	return lines
+}
+
+// Forecast returns the destination weather, cached for a few hours. A trip
+// without coordinates is geocoded and saved with them first.
+func (m *Mode) Forecast(t *Trip) ([]Day, error) {
+	m.mu.Lock()
+	c, ok := m.forecasts[t.ID]
+	m.mu.Unlock()
+	if ok && m.now().Sub(c.fetched) < forecastTTL {
+		return c.days, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if t.Latitude == 0 && t.Longitude == 0 {
+		place, err := Geocode(ctx, m.client, t.Destination)
+		if err != nil {
+			return nil, err
+		}
+		t.Latitude, t.Longitude = place.Latitude, place.Longitude
+		if t.Timezone == "" {
+			t.Timezone = place.Timezone
+		}
+		if err := m.svc.Save(t); err != nil {
+			return nil, err
+		}
+	}
+	days, err := Forecast(ctx, m.client, t.Latitude, t.Longitude, t.Timezone, forecastDays)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.forecasts[t.ID] = cachedForecast{days: days, fetched: m.now()}
+	m.mu.Unlock()
+	return days, nil
+}
+
+// Weather returns the forecast at a place, uncached.
+func (m *Mode) Weather(ctx context.Context, p *Place) ([]Day, error) {
+	return Forecast(ctx, m.client, p.Latitude, p.Longitude, p.Timezone, 5)
+}
+
+// Geocode looks up a destination with the mode's client.
+func (m *Mode) Geocode(ctx context.Context, name string) (*Place, error) {
+	return Geocode(ctx, m.client, name)
+}
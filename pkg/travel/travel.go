@@ -0,0 +1,384 @@
+// Package travel keeps trips and their itineraries. While a trip is under
+// way, Mode moves the agent's timezone (when.Location) to the destination,
+// so displayed times, cron jobs and reminders follow the user, and the
+// heartbeat gets the destination weather and upcoming itinerary items.
+package travel
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"localagent/pkg/db/dbq"
+	"localagent/pkg/utils"
+)
+
+const (
+	SourceManual    = "manual"
+	SourceCalendar  = "calendar"
+	SourceItinerary = "itinerary"
+)
+
+// Trip is a stay away from home. EndMS 0 means open-ended: the trip lasts
+// until it is ended.
+type Trip struct {
+	ID          string  `json:"id"`
+	Destination string  `json:"destination"`
+	Timezone    string  `json:"timezone,omitempty"` // IANA name; empty = not known
+	Latitude    float64 `json:"latitude,omitempty"`
+	Longitude   float64 `json:"longitude,omitempty"`
+	StartMS     int64   `json:"startMs"`
+	EndMS       int64   `json:"endMs,omitempty"`
+	Source      string  `json:"source"`
+	CalendarUID string  `json:"calendarUid,omitempty"`
+	Itinerary   []Item  `json:"itinerary,omitempty"`
+	Notes       string  `json:"notes,omitempty"`
+	CreatedAtMS int64   `json:"createdAtMs"`
+	UpdatedAtMS int64   `json:"updatedAtMs"`
+}
+
+// Location returns the trip's timezone, or nil when it isn't known.
+func (t Trip) Location() *time.Location {
+	if t.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// ActiveAt reports whether the trip is under way at now.
+func (t Trip) ActiveAt(now time.Time) bool {
+	ms := now.UnixMilli()
+	return t.StartMS <= ms && (t.EndMS == 0 || ms < t.EndMS)
+}
+
+// Item is a booking on the itinerary: a flight, hotel stay, train, rental
+// car or event.
+type Item struct {
+	Kind      string `json:"kind"`  // flight, hotel, train, bus, car, event, restaurant, other
+	Title     string `json:"title"` // e.g. "LX 160 ZRH → NRT", "Hotel Gracery"
+	StartMS   int64  `json:"startMs"`
+	EndMS     int64  `json:"endMs,omitempty"`
+	Location  string `json:"location,omitempty"`  // departure airport or station, hotel address
+	Reference string `json:"reference,omitempty"` // confirmation code
+	Details   string `json:"details,omitempty"`
+}
+
+func (it Item) key() string {
+	return fmt.Sprintf("%s|%s|%s|%d", it.Kind, strings.ToLower(it.Title), it.Reference, it.StartMS)
+}
+
+type Service struct {
+	q   *dbq.Queries
+	now func() time.Time
+}
+
+func NewService(database *sql.DB) *Service {
+	return &Service{q: dbq.New(database), now: time.Now}
+}
+
+// List returns all trips, earliest first.
+func (s *Service) List() []Trip {
+	rows, err := s.q.ListTrips(context.Background())
+	if err != nil {
+		return nil
+	}
+	trips := make([]Trip, len(rows))
+	for i, r := range rows {
+		trips[i] = dbTripToTrip(r)
+	}
+	return trips
+}
+
+func (s *Service) Get(id string) *Trip {
+	row, err := s.q.GetTrip(context.Background(), id)
+	if err != nil {
+		return nil
+	}
+	t := dbTripToTrip(row)
+	return &t
+}
+
+// Find returns the trip with id or, failing that, the latest one to
+// destination (ignoring case).
+func (s *Service) Find(ref string) *Trip {
+	if t := s.Get(ref); t != nil {
+		return t
+	}
+	trips := s.List()
+	for i := len(trips) - 1; i >= 0; i-- {
+		if strings.EqualFold(trips[i].Destination, strings.TrimSpace(ref)) {
+			return &trips[i]
+		}
+	}
+	return nil
+}
+
+// ByCalendarUID returns the trip created from a calendar event.
+func (s *Service) ByCalendarUID(uid string) *Trip {
+	row, err := s.q.GetTripByCalendarUID(context.Background(), uid)
+	if err != nil {
+		return nil
+	}
+	t := dbTripToTrip(row)
+	return &t
+}
+
+// Active returns the trip under way at now; with overlapping trips the
+// one that started last wins.
+func (s *Service) Active(now time.Time) *Trip {
+	var active *Trip
+	for _, t := range s.List() {
+		if t.ActiveAt(now) {
+			active = &t
+		}
+	}
+	return active
+}
+
+// Next returns the first trip starting after now.
+func (s *Service) Next(now time.Time) *Trip {
+	for _, t := range s.List() {
+		if t.StartMS > now.UnixMilli() {
+			return &t
+		}
+	}
+	return nil
+}
+
+func (s *Service) Add(t Trip) (*Trip, error) {
+	t.Destination = strings.TrimSpace(t.Destination)
+	if t.Destination == "" {
+		return nil, fmt.Errorf("destination is required")
+	}
+	if err := validate(t); err != nil {
+		return nil, err
+	}
+	now := s.now().UnixMilli()
+	if t.ID == "" {
+		t.ID = utils.RandHex(8)
+	}
+	if t.StartMS == 0 {
+		t.StartMS = now
+	}
+	if t.Source == "" {
+		t.Source = SourceManual
+	}
+	sortItems(t.Itinerary)
+	t.CreatedAtMS, t.UpdatedAtMS = now, now
+
+	err := s.q.InsertTrip(context.Background(), dbq.InsertTripParams{
+		ID:          t.ID,
+		Destination: t.Destination,
+		Timezone:    t.Timezone,
+		Latitude:    t.Latitude,
+		Longitude:   t.Longitude,
+		StartAtMs:   t.StartMS,
+		EndAtMs:     t.EndMS,
+		Source:      t.Source,
+		CalendarUid: t.CalendarUID,
+		Itinerary:   marshalItems(t.Itinerary),
+		Notes:       t.Notes,
+		CreatedAtMs: t.CreatedAtMS,
+		UpdatedAtMs: t.UpdatedAtMS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Save writes back a trip changed by the caller.
+func (s *Service) Save(t *Trip) error {
+	if err := validate(*t); err != nil {
+		return err
+	}
+	sortItems(t.Itinerary)
+	t.UpdatedAtMS = s.now().UnixMilli()
+	return s.q.UpdateTrip(context.Background(), dbq.UpdateTripParams{
+		Destination: t.Destination,
+		Timezone:    t.Timezone,
+		Latitude:    t.Latitude,
+		Longitude:   t.Longitude,
+		StartAtMs:   t.StartMS,
+		EndAtMs:     t.EndMS,
+		Itinerary:   marshalItems(t.Itinerary),
+		Notes:       t.Notes,
+		UpdatedAtMs: t.UpdatedAtMS,
+		ID:          t.ID,
+	})
+}
+
+func (s *Service) Remove(id string) bool {
+	res, err := s.q.DeleteTrip(context.Background(), id)
+	if err != nil {
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n > 0
+}
+
+// AddItems adds bookings to a trip, skipping ones it already has, and
+// returns how many were new. A trip built from an itinerary grows to
+// cover its items.
+func (s *Service) AddItems(t *Trip, items []Item) (int, error) {
+	have := make(map[string]bool, len(t.Itinerary))
+	for _, it := range t.Itinerary {
+		have[it.key()] = true
+	}
+	added := 0
+	for _, it := range items {
+		if have[it.key()] {
+			continue
+		}
+		have[it.key()] = true
+		t.Itinerary = append(t.Itinerary, it)
+		added++
+		if t.Source == SourceItinerary {
+			t.StartMS = min(t.StartMS, it.StartMS)
+			t.EndMS = max(t.EndMS, it.StartMS, it.EndMS)
+		}
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, s.Save(t)
+}
+
+// TripFor returns the trip bookings starting at start belong to: one
+// covering start, else the next trip starting within two days after it.
+func (s *Service) TripFor(start time.Time) *Trip {
+	for _, t := range s.List() {
+		if t.ActiveAt(start) {
+			return &t
+		}
+	}
+	for _, t := range s.List() {
+		if d := time.Duration(t.StartMS-start.UnixMilli()) * time.Millisecond; d >= 0 && d <= 48*time.Hour {
+			return &t
+		}
+	}
+	return nil
+}
+
+// Upcoming returns itinerary items of t starting in [from, to), or still
+// running at from.
+func (t Trip) Upcoming(from, to time.Time) []Item {
+	var out []Item
+	for _, it := range t.Itinerary {
+		end := max(it.EndMS, it.StartMS)
+		if it.StartMS < to.UnixMilli() && end >= from.UnixMilli() {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// FormatTrip renders a trip for chat, with times in loc.
+func FormatTrip(t Trip, loc *time.Location) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s, %s", t.ID, t.Destination, formatRange(t.StartMS, t.EndMS, loc))
+	if t.Timezone != "" {
+		fmt.Fprintf(&b, " (%s)", t.Timezone)
+	}
+	if t.Source != SourceManual {
+		fmt.Fprintf(&b, " [%s]", t.Source)
+	}
+	if t.Notes != "" {
+		fmt.Fprintf(&b, "\n  %s", t.Notes)
+	}
+	for _, it := range t.Itinerary {
+		fmt.Fprintf(&b, "\n  - %s", FormatItem(it, itemLocation(t, loc)))
+	}
+	return b.String()
+}
+
+// FormatItem renders one booking, with times in loc.
+func FormatItem(it Item, loc *time.Location) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %s", formatRange(it.StartMS, it.EndMS, loc), it.Kind, it.Title)
+	if it.Location != "" {
+		fmt.Fprintf(&b, " @ %s", it.Location)
+	}
+	if it.Reference != "" {
+		fmt.Fprintf(&b, " (ref %s)", it.Reference)
+	}
+	if it.Details != "" {
+		fmt.Fprintf(&b, ". %s", it.Details)
+	}
+	return b.String()
+}
+
+// itemLocation shows bookings in destination time when it's known.
+func itemLocation(t Trip, fallback *time.Location) *time.Location {
+	if loc := t.Location(); loc != nil {
+		return loc
+	}
+	return fallback
+}
+
+func formatRange(startMS, endMS int64, loc *time.Location) string {
+	start := time.UnixMilli(startMS).In(loc)
+	out := start.Format("Mon Jan 2 15:04")
+	if endMS == 0 {
+		return out + " (open-ended)"
+	}
+	end := time.UnixMilli(endMS).In(loc)
+	if end.Format("2006-01-02") == start.Format("2006-01-02") {
+		return out + "-" + end.Format("15:04")
+	}
+	return out + " - " + end.Format("Mon Jan 2 15:04")
+}
+
+func validate(t Trip) error {
+	if t.Timezone != "" {
+		if _, err := time.LoadLocation(t.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", t.Timezone)
+		}
+	}
+	if t.EndMS != 0 && t.EndMS <= t.StartMS {
+		return fmt.Errorf("trip ends before it starts")
+	}
+	return nil
+}
+
+func sortItems(items []Item) {
+	slices.SortStableFunc(items, func(a, b Item) int {
+		return cmp.Compare(a.StartMS, b.StartMS)
+	})
+}
+
+func marshalItems(items []Item) string {
+	if items == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+func dbTripToTrip(r dbq.Trip) Trip {
+	t := Trip{
+		ID:          r.ID,
+		Destination: r.Destination,
+		Timezone:    r.Timezone,
+		Latitude:    r.Latitude,
+		Longitude:   r.Longitude,
+		StartMS:     r.StartAtMs,
+		EndMS:       r.EndAtMs,
+		Source:      r.Source,
+		CalendarUID: r.CalendarUid,
+		Notes:       r.Notes,
+		CreatedAtMS: r.CreatedAtMs,
+		UpdatedAtMS: r.UpdatedAtMs,
+	}
+	json.Unmarshal([]byte(r.Itinerary), &t.Itinerary)
+	return t
+}
@@ -0,0 +1,235 @@
+package travel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/db"
+	"localagent/pkg/when"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewService(database)
+}
+
+func newTestMode(t *testing.T, now *time.Time) (*Mode, *Service) {
+	t.Helper()
+	home, _ := time.LoadLocation("Europe/Zurich")
+	prev := when.Location()
+	when.SetLocation(home)
+	t.Cleanup(func() { when.SetLocation(prev) })
+
+	svc := newTestService(t)
+	svc.now = func() time.Time { return *now }
+	m := NewMode(svc, home, t.TempDir())
+	m.now = svc.now
+	return m, svc
+}
+
+// fakeOpenMeteo serves geocoding for Tokyo and a two-day forecast.
+func fakeOpenMeteo(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			if r.URL.Query().Get("name") != "Tokyo" {
+				w.Write([]byte(`{}`))
+				return
+			}
+			w.Write([]byte(`{"results":[{"name":"Tokyo","country":"Japan","latitude":35.69,"longitude":139.69,"timezone":"Asia/Tokyo"}]}`))
+		case "/forecast":
+			w.Write([]byte(`{"daily":{"time":["2026-11-02","2026-11-03"],"weather_code":[0,61],
+				"temperature_2m_max":[18.2,15.1],"temperature_2m_min":[9.6,10.4],"precipitation_probability_max":[0,80]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	prevGeo, prevForecast := geocodeURL, forecastURL
+	geocodeURL, forecastURL = srv.URL+"/search", srv.URL+"/forecast"
+	t.Cleanup(func() { geocodeURL, forecastURL = prevGeo, prevForecast })
+}
+
+func TestApplySwitchesTimezone(t *testing.T) {
+	now := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	m, svc := newTestMode(t, &now)
+	var changes []string
+	m.OnChange(func(loc *time.Location) { changes = append(changes, loc.String()) })
+
+	trip, err := svc.Add(Trip{
+		Destination: "Tokyo",
+		Timezone:    "Asia/Tokyo",
+		StartMS:     now.Add(24 * time.Hour).UnixMilli(),
+		EndMS:       now.Add(7 * 24 * time.Hour).UnixMilli(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc := m.Apply(); loc.String() != "Europe/Zurich" || len(changes) != 0 {
+		t.Fatalf("before the trip: %s, changes %v", loc, changes)
+	}
+	if note := m.Note(); !strings.HasPrefix(note, "Upcoming trip: Tokyo") {
+		t.Errorf("note = %q", note)
+	}
+
+	now = now.Add(2 * 24 * time.Hour)
+	m.Apply()
+	if when.Location().String() != "Asia/Tokyo" || len(changes) != 1 {
+		t.Fatalf("during the trip: %s, changes %v", when.Location(), changes)
+	}
+	if note := m.Note(); !strings.Contains(note, "in Tokyo") || !strings.Contains(note, "home is Europe/Zurich") {
+		t.Errorf("note = %q", note)
+	}
+	m.Apply()
+	if len(changes) != 1 {
+		t.Errorf("unchanged timezone fired OnChange: %v", changes)
+	}
+
+	trip.EndMS = now.UnixMilli()
+	if err := svc.Save(trip); err != nil {
+		t.Fatal(err)
+	}
+	m.Apply()
+	if when.Location().String() != "Europe/Zurich" || len(changes) != 2 {
+		t.Errorf("after the trip: %s, changes %v", when.Location(), changes)
+	}
+}
+
+func TestAddItems(t *testing.T) {
+	svc := newTestService(t)
+	start := time.Date(2026, 11, 2, 10, 40, 0, 0, time.UTC)
+	trip, err := svc.Add(Trip{Destination: "Tokyo", StartMS: start.UnixMilli(), Source: SourceItinerary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flight := Item{Kind: "flight", Title: "LX160 ZRH → NRT", StartMS: start.UnixMilli(), EndMS: start.Add(13 * time.Hour).UnixMilli(), Reference: "ABC123"}
+	hotel := Item{Kind: "hotel", Title: "Hotel Gracery", StartMS: start.Add(20 * time.Hour).UnixMilli(), EndMS: start.Add(5 * 24 * time.Hour).UnixMilli()}
+
+	if n, err := svc.AddItems(trip, []Item{hotel, flight}); err != nil || n != 2 {
+		t.Fatalf("added %d, %v", n, err)
+	}
+	if n, _ := svc.AddItems(trip, []Item{flight}); n != 0 {
+		t.Errorf("duplicate booking added")
+	}
+	got := svc.Get(trip.ID)
+	if len(got.Itinerary) != 2 || got.Itinerary[0].Kind != "flight" {
+		t.Fatalf("itinerary = %+v", got.Itinerary)
+	}
+	if got.EndMS != hotel.EndMS {
+		t.Errorf("trip should end at hotel checkout, ends %d", got.EndMS)
+	}
+	if tf := svc.TripFor(start.Add(-24 * time.Hour)); tf == nil || tf.ID != trip.ID {
+		t.Errorf("TripFor the day before = %v", tf)
+	}
+}
+
+const confirmationEmail = "From: bookings@example.com\r\n" +
+	"Subject: Your trip to Tokyo\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Your booking is confirmed.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"<html><head><script type=3D\"application/ld+json\">[\r\n" +
+	"{\"@context\":\"http://schema.org\",\"@type\":\"FlightReservation\",\"reservationNumber\":\"ABC123\",=\r\n" +
+	"\"reservationFor\":{\"@type\":\"Flight\",\"flightNumber\":\"160\",\"airline\":{\"iataCode\":\"LX\"},=\r\n" +
+	"\"departureAirport\":{\"name\":\"Zurich Airport\",\"iataCode\":\"ZRH\"},\"arrivalAirport\":{\"iataCode\":\"NRT\"},=\r\n" +
+	"\"departureTime\":\"2026-11-02T13:00:00+01:00\",\"arrivalTime\":\"2026-11-03T08:55:00+09:00\"}},\r\n" +
+	"{\"@context\":\"http://schema.org\",\"@type\":\"LodgingReservation\",\"reservationNumber\":\"H-77\",=\r\n" +
+	"\"reservationFor\":{\"@type\":\"LodgingBusiness\",\"name\":\"Hotel Gracery\",=\r\n" +
+	"\"address\":{\"streetAddress\":\"1-19-1 Kabukicho\",\"addressLocality\":\"Tokyo\",\"addressCountry\":\"JP\"}},=\r\n" +
+	"\"checkinTime\":\"2026-11-03T15:00:00+09:00\",\"checkoutTime\":\"2026-11-08T11:00:00+09:00\"},\r\n" +
+	"{\"@type\":\"EventReservation\",\"reservationStatus\":\"http://schema.org/ReservationCancelled\",=\r\n" +
+	"\"reservationFor\":{\"name\":\"Sumo\",\"startDate\":\"2026-11-05T13:00:00+09:00\"}}\r\n" +
+	"]</script></head><body>Booked.</body></html>\r\n" +
+	"--b1--\r\n"
+
+func TestParseConfirmation(t *testing.T) {
+	items, err := ParseConfirmation([]byte(confirmationEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("items = %+v", items)
+	}
+	f, h := items[0], items[1]
+	if f.Kind != "flight" || f.Title != "LX160 ZRH → NRT" || f.Reference != "ABC123" || f.Location != "Zurich Airport" {
+		t.Errorf("flight = %+v", f)
+	}
+	if f.StartMS != time.Date(2026, 11, 2, 12, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("flight departs %s", time.UnixMilli(f.StartMS).UTC())
+	}
+	if h.Kind != "hotel" || h.Title != "Hotel Gracery" || h.Location != "1-19-1 Kabukicho, Tokyo, JP" || h.EndMS == 0 {
+		t.Errorf("hotel = %+v", h)
+	}
+
+	if _, err := ParseConfirmation([]byte("Subject: Hi\r\n\r\nSee you in Tokyo!")); err != ErrNoItinerary {
+		t.Errorf("plain email: %v", err)
+	}
+}
+
+func TestCalendarTripAndBriefing(t *testing.T) {
+	fakeOpenMeteo(t)
+	now := time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)
+	m, svc := newTestMode(t, &now)
+	start := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{UID: "trip-1", Title: "Japan", Location: "Hotel Gracery, 1-19-1 Kabukicho, Tokyo", Start: start, End: start.Add(6 * 24 * time.Hour)},
+		{UID: "retreat", Title: "Retreat", Location: "Atlantis", Start: start, End: start.Add(48 * time.Hour)},
+	}
+	m.SetCalendar(func(ctx context.Context, from, to time.Time, minDur time.Duration) ([]Event, error) {
+		return events, nil
+	})
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	trips := svc.List()
+	if len(trips) != 1 || trips[0].Destination != "Tokyo, Japan" || trips[0].Timezone != "Asia/Tokyo" || trips[0].CalendarUID != "trip-1" {
+		t.Fatalf("trips = %+v", trips)
+	}
+	trip := &trips[0]
+	svc.AddItems(trip, []Item{{Kind: "flight", Title: "LX160 ZRH → NRT", StartMS: start.Add(2 * time.Hour).UnixMilli()}})
+
+	b := m.Briefing(now)
+	for _, want := range []string{"trip to Tokyo, Japan starts", "clear, 10-18°C", "80% rain", "flight: LX160 ZRH → NRT"} {
+		if !strings.Contains(b, want) {
+			t.Errorf("briefing lacks %q:\n%s", want, b)
+		}
+	}
+	if b := m.Briefing(now.Add(time.Hour)); b != "" {
+		t.Errorf("second briefing the same day = %q", b)
+	}
+
+	// The event moved a day later; the trip follows.
+	events = events[:1]
+	events[0].Start, events[0].End = start.Add(24*time.Hour), start.Add(7*24*time.Hour)
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.Get(trip.ID); got.StartMS != events[0].Start.UnixMilli() {
+		t.Errorf("trip start = %d", got.StartMS)
+	}
+
+	// The event was deleted.
+	events = nil
+	m.Check(context.Background())
+	if len(svc.List()) != 0 {
+		t.Errorf("trip of a deleted event kept")
+	}
+}
@@ -0,0 +1,188 @@
+package travel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"localagent/pkg/httpclient"
+)
+
+// Open-Meteo needs no API key. The travel tool declares both hosts.
+var (
+	geocodeURL  = "https://geocoding-api.open-meteo.com/v1/search"
+	forecastURL = "https://api.open-meteo.com/v1/forecast"
+)
+
+// Domains are the hosts geocoding and forecasts are fetched from.
+var Domains = []string{"geocoding-api.open-meteo.com", "api.open-meteo.com"}
+
+// ErrPlaceNotFound is returned when geocoding finds nothing.
+var ErrPlaceNotFound = errors.New("place not found")
+
+// Place is a geocoded destination.
+type Place struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+}
+
+// Label is "City, Country".
+func (p Place) Label() string {
+	if p.Country == "" {
+		return p.Name
+	}
+	return p.Name + ", " + p.Country
+}
+
+// Geocode looks up a place name. Addresses are tried part by part ("Hotel
+// X, 3-2-1 Shinjuku, Tokyo, Japan" finds Tokyo), skipping parts with
+// digits.
+func Geocode(ctx context.Context, client *http.Client, name string) (*Place, error) {
+	candidates := []string{strings.TrimSpace(name)}
+	if parts := strings.Split(name, ","); len(parts) > 1 {
+		for _, p := range parts {
+			p = strings.TrimSpace(p)
+			if p != "" && !strings.ContainsAny(p, "0123456789") {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	for _, c := range candidates {
+		p, err := geocode(ctx, client, c)
+		if errors.Is(err, ErrPlaceNotFound) {
+			continue
+		}
+		return p, err
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPlaceNotFound, name)
+}
+
+func geocode(ctx context.Context, client *http.Client, name string) (*Place, error) {
+	q := url.Values{"name": {name}, "count": {"1"}, "language": {"en"}, "format": {"json"}}
+	var data struct {
+		Results []Place `json:"results"`
+	}
+	if err := getJSON(ctx, client, geocodeURL+"?"+q.Encode(), &data); err != nil {
+		return nil, err
+	}
+	if len(data.Results) == 0 {
+		return nil, ErrPlaceNotFound
+	}
+	return &data.Results[0], nil
+}
+
+// Day is one day of a forecast.
+type Day struct {
+	Date         string  `json:"date"`
+	Summary      string  `json:"summary"`
+	MinC         float64 `json:"minC"`
+	MaxC         float64 `json:"maxC"`
+	PrecipChance int     `json:"precipChance"` // percent
+}
+
+func (d Day) String() string {
+	s := fmt.Sprintf("%s: %s, %.0f-%.0f°C", d.Date, d.Summary, d.MinC, d.MaxC)
+	if d.PrecipChance > 0 {
+		s += fmt.Sprintf(", %d%% rain", d.PrecipChance)
+	}
+	return s
+}
+
+// Forecast returns the daily forecast at lat/lon for the next days, with
+// dates in tz.
+func Forecast(ctx context.Context, client *http.Client, lat, lon float64, tz string, days int) ([]Day, error) {
+	if tz == "" {
+		tz = "auto"
+	}
+	q := url.Values{
+		"latitude":      {fmt.Sprintf("%.4f", lat)},
+		"longitude":     {fmt.Sprintf("%.4f", lon)},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max"},
+		"timezone":      {tz},
+		"forecast_days": {fmt.Sprint(days)},
+	}
+	var data struct {
+		Daily struct {
+			Time   []string  `json:"time"`
+			Code   []int     `json:"weather_code"`
+			Max    []float64 `json:"temperature_2m_max"`
+			Min    []float64 `json:"temperature_2m_min"`
+			Precip []*int    `json:"precipitation_probability_max"`
+		} `json:"daily"`
+	}
+	if err := getJSON(ctx, client, forecastURL+"?"+q.Encode(), &data); err != nil {
+		return nil, err
+	}
+	d := data.Daily
+	out := make([]Day, 0, len(d.Time))
+	for i, date := range d.Time {
+		if i >= len(d.Code) || i >= len(d.Max) || i >= len(d.Min) {
+			break
+		}
+		day := Day{Date: date, Summary: weatherCode(d.Code[i]), MinC: d.Min[i], MaxC: d.Max[i]}
+		if i < len(d.Precip) && d.Precip[i] != nil {
+			day.PrecipChance = *d.Precip[i]
+		}
+		out = append(out, day)
+	}
+	return out, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	body, err := httpclient.ReadBody(resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// weatherCode describes a WMO weather interpretation code.
+func weatherCode(code int) string {
+	switch {
+	case code == 0:
+		return "clear"
+	case code <= 2:
+		return "partly cloudy"
+	case code == 3:
+		return "overcast"
+	case code <= 48:
+		return "fog"
+	case code <= 57:
+		return "drizzle"
+	case code <= 67:
+		return "rain"
+	case code <= 77:
+		return "snow"
+	case code <= 82:
+		return "rain showers"
+	case code <= 86:
+		return "snow showers"
+	default:
+		return "thunderstorms"
+	}
+}
+
+// newClient returns the client for Open-Meteo requests.
+func newClient() *http.Client {
+	return httpclient.New("travel", httpclient.WithTimeout(15*time.Second))
+}
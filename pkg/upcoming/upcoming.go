@@ -0,0 +1,183 @@
+// Package upcoming writes the short "Upcoming" note the agent gets with
+// every message: the next calendar events, tasks due today and running
+// timers, so "what's next?" needs no tool round trip.
+package upcoming
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/cron"
+	"localagent/pkg/logger"
+	"localagent/pkg/todo"
+	"localagent/pkg/utils"
+	"localagent/pkg/when"
+)
+
+const (
+	maxEvents   = 2
+	maxTasks    = 4
+	maxTitle    = 60
+	maxChars    = 600 // the whole note, ~200 tokens
+	lookahead   = 7 * 24 * time.Hour
+	calendarTTL = 5 * time.Minute
+)
+
+// Event is a calendar event.
+type Event struct {
+	Title    string
+	Location string
+	Start    time.Time
+}
+
+// EventsFunc lists the events starting in [from, to), in order.
+type EventsFunc func(ctx context.Context, from, to time.Time) ([]Event, error)
+
+// Note builds the note. Calendar events are fetched in the background and
+// cached, so building it never waits on the network.
+type Note struct {
+	events EventsFunc
+	tasks  *todo.TodoService
+	cron   *cron.CronService
+	now    func() time.Time
+
+	mu       sync.Mutex
+	cached   []Event
+	fetched  time.Time
+	fetching bool
+}
+
+// New returns a note over the given sources; either may be nil.
+func New(tasks *todo.TodoService, cronService *cron.CronService) *Note {
+	return &Note{tasks: tasks, cron: cronService, now: when.Now}
+}
+
+// SetCalendar adds the next events from fn and starts fetching them.
+func (n *Note) SetCalendar(fn EventsFunc) {
+	n.mu.Lock()
+	n.events = fn
+	n.mu.Unlock()
+	n.refresh()
+}
+
+// String returns the note, or "" when nothing is coming up.
+func (n *Note) String() string {
+	now := n.now()
+	var lines []string
+	for _, e := range n.nextEvents(now) {
+		line := fmt.Sprintf("- %s %s", eventTime(e.Start, now), utils.Truncate(e.Title, maxTitle))
+		if e.Location != "" {
+			line += " @ " + utils.Truncate(e.Location, maxTitle/2)
+		}
+		lines = append(lines, line+" (in "+cron.ShortDuration(e.Start.Sub(now).Truncate(time.Minute))+")")
+	}
+	if line := n.dueTasks(now); line != "" {
+		lines = append(lines, line)
+	}
+	if n.cron != nil {
+		for _, job := range n.cron.ListJobs(false) {
+			if !cron.IsTimer(&job) || job.State.NextRunAtMS == nil {
+				continue
+			}
+			left := max(time.UnixMilli(*job.State.NextRunAtMS).Sub(now), 0)
+			lines = append(lines, fmt.Sprintf("- Timer %q: %s left", utils.Truncate(cron.TimerLabel(&job), maxTitle), cron.ShortDuration(left)))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return utils.Truncate(strings.Join(lines, "\n"), maxChars)
+}
+
+// nextEvents returns the cached events still ahead, refreshing the cache
+// when it is stale.
+func (n *Note) nextEvents(now time.Time) []Event {
+	n.mu.Lock()
+	stale := n.events != nil && now.Sub(n.fetched) >= calendarTTL
+	var next []Event
+	for _, e := range n.cached {
+		if e.Start.After(now) && len(next) < maxEvents {
+			next = append(next, e)
+		}
+	}
+	n.mu.Unlock()
+	if stale {
+		n.refresh()
+	}
+	return next
+}
+
+// refresh fetches the events in the background unless a fetch is running.
+func (n *Note) refresh() {
+	n.mu.Lock()
+	if n.events == nil || n.fetching {
+		n.mu.Unlock()
+		return
+	}
+	n.fetching = true
+	fn := n.events
+	n.mu.Unlock()
+
+	go func() {
+		now := n.now()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		events, err := fn(ctx, now, now.Add(lookahead))
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.fetching = false
+		n.fetched = now
+		if err != nil {
+			// Keep the last events; try again after the TTL.
+			logger.Warn("upcoming: calendar lookup failed: %v", err)
+			return
+		}
+		n.cached = events
+	}()
+}
+
+// dueTasks summarizes the open tasks due today or earlier.
+func (n *Note) dueTasks(now time.Time) string {
+	if n.tasks == nil {
+		return ""
+	}
+	today := now.Format("2006-01-02")
+	var titles []string
+	overdue := 0
+	for _, t := range n.tasks.QueryTasks(todo.TaskQuery{DueBefore: today}) {
+		if t.Status == "done" {
+			continue
+		}
+		if t.Due[:min(len(t.Due), 10)] < today {
+			overdue++
+			continue
+		}
+		titles = append(titles, utils.Truncate(t.Title, maxTitle))
+	}
+	if len(titles) == 0 && overdue == 0 {
+		return ""
+	}
+	line := "- Due today: none"
+	if len(titles) > 0 {
+		line = "- Due today: " + strings.Join(titles[:min(len(titles), maxTasks)], "; ")
+		if more := len(titles) - maxTasks; more > 0 {
+			line += fmt.Sprintf(" (+%d more)", more)
+		}
+	}
+	if overdue > 0 {
+		line += fmt.Sprintf(" (%d overdue)", overdue)
+	}
+	return line
+}
+
+// eventTime gives the start time, with the weekday when it isn't today.
+func eventTime(t, now time.Time) string {
+	t = t.In(now.Location())
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Format("15:04")
+	}
+	return t.Format("Mon 15:04")
+}
@@ -0,0 +1,79 @@
+package upcoming
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localagent/pkg/cron"
+	"localagent/pkg/db"
+	"localagent/pkg/todo"
+)
+
+func TestNote(t *testing.T) {
+	database, err := db.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	tasks := todo.NewTodoService(database)
+	cs := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+
+	now := time.Now().Truncate(time.Minute)
+	n := New(tasks, cs)
+	n.now = func() time.Time { return now }
+	if got := n.String(); got != "" {
+		t.Fatalf("empty note = %q", got)
+	}
+
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	tomorrow := now.AddDate(0, 0, 1).Format("2006-01-02")
+	for _, task := range []todo.Task{
+		{Title: "Pay rent", Due: today},
+		{Title: "Call the bank", Due: today + "T16:00"},
+		{Title: "File taxes", Due: yesterday},
+		{Title: "Buy milk", Due: tomorrow},
+	} {
+		if _, err := tasks.AddTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done, _ := tasks.AddTask(todo.Task{Title: "Water plants", Due: today})
+	tasks.CompleteTask(done.ID)
+
+	if _, err := cs.AddJob(cron.NewTimer("pasta", 8*time.Minute, false, "web", "default", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	n.SetCalendar(func(_ context.Context, from, to time.Time) ([]Event, error) {
+		return []Event{
+			{Title: "Standup", Start: now.Add(-10 * time.Minute)}, // started
+			{Title: "Dentist", Location: "Main St", Start: now.Add(80 * time.Minute)},
+			{Title: "Review", Start: now.Add(26 * time.Hour)},
+			{Title: "Dinner", Start: now.Add(30 * time.Hour)},
+		}, nil
+	})
+	got := n.String()
+	for deadline := time.Now().Add(time.Second); !strings.Contains(got, "Dentist") && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond) // the events are fetched in the background
+		got = n.String()
+	}
+	for _, want := range []string{
+		"- " + now.Add(80*time.Minute).Format("15:04") + " Dentist @ Main St (in 1h20m)",
+		"- " + now.Add(26*time.Hour).Format("Mon 15:04") + " Review (in 26h)",
+		"- Due today: Pay rent; Call the bank (1 overdue)",
+		`- Timer "pasta": `,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("note lacks %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Standup", "Dinner", "Buy milk", "Water plants"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("note has %q:\n%s", unwanted, got)
+		}
+	}
+}
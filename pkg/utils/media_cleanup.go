@@ -3,40 +3,148 @@ package utils
 import (
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"localagent/pkg/logger"
 )
 
-// CleanOldMedia removes files in mediaDir older than ttl based on modification time.
-func CleanOldMedia(mediaDir string, ttl time.Duration) {
-	entries, err := os.ReadDir(mediaDir)
-	if err != nil {
-		return
+// MediaRetention prunes media directories according to a retention policy:
+// unreferenced files are removed once older than MaxAge, and when the total
+// size exceeds MaxTotalBytes the least recently used files are evicted,
+// referenced or not. Files still referenced by a session survive age pruning.
+type MediaRetention struct {
+	MaxAge        time.Duration
+	MaxTotalBytes int64 // 0 = no size cap
+
+	mu         sync.Mutex
+	dirs       []string
+	referenced func() map[string]bool
+}
+
+func NewMediaRetention(maxAge time.Duration, maxTotalBytes int64, dirs ...string) *MediaRetention {
+	return &MediaRetention{
+		MaxAge:        maxAge,
+		MaxTotalBytes: maxTotalBytes,
+		dirs:          dirs,
 	}
+}
 
-	cutoff := time.Now().Add(-ttl)
-	removed := 0
+// AddDir adds another directory to be pruned under the same policy.
+func (r *MediaRetention) AddDir(dir string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirs = append(r.dirs, dir)
+}
+
+// SetReferenced sets the function that reports which media paths are still
+// referenced (e.g. by session history) and must not be aged out.
+func (r *MediaRetention) SetReferenced(fn func() map[string]bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.referenced = fn
+}
+
+// Touch marks a file as recently used so LRU eviction keeps it longer.
+func Touch(path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+type mediaFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
+// Prune applies the retention policy to all configured directories.
+func (r *MediaRetention) Prune() {
+	r.mu.Lock()
+	dirs := append([]string(nil), r.dirs...)
+	refFn := r.referenced
+	r.mu.Unlock()
+
+	var refs map[string]bool
+	if refFn != nil {
+		refs = refFn()
+	}
+
+	var files []mediaFile
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
 			continue
 		}
-		info, err := entry.Info()
-		if err != nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, mediaFile{
+				path:    filepath.Join(dir, entry.Name()),
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-r.MaxAge)
+	kept := files[:0]
+	for _, f := range files {
+		if r.MaxAge > 0 && f.modTime.Before(cutoff) && !refs[f.path] {
+			if removeMedia(f.path) {
+				removed++
+			}
 			continue
 		}
-		if info.ModTime().Before(cutoff) {
-			path := filepath.Join(mediaDir, entry.Name())
-			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-				logger.Warn("media cleanup: failed to remove %s: %v", path, err)
-			} else {
+		kept = append(kept, f)
+	}
+
+	if r.MaxTotalBytes > 0 {
+		var total int64
+		for _, f := range kept {
+			total += f.size
+		}
+		sort.Slice(kept, func(i, j int) bool { return kept[i].modTime.Before(kept[j].modTime) })
+		for _, f := range kept {
+			if total <= r.MaxTotalBytes {
+				break
+			}
+			if removeMedia(f.path) {
 				removed++
+				total -= f.size
 			}
 		}
 	}
 
 	if removed > 0 {
-		logger.Info("media cleanup: removed %d expired file(s)", removed)
+		logger.Info("media cleanup: removed %d file(s)", removed)
+	}
+}
+
+// Run prunes on the given interval until stop is closed.
+func (r *MediaRetention) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.Prune()
+		}
+	}
+}
+
+func removeMedia(path string) bool {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("media cleanup: failed to remove %s: %v", path, err)
+		return false
 	}
+	return true
 }
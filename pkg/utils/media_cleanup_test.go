@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeMedia(t *testing.T, dir, name string, size int, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mt := time.Now().Add(-age)
+	os.Chtimes(path, mt, mt)
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestMediaRetention_KeepsReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := writeMedia(t, dir, "old.png", 10, 2*time.Hour)
+	referenced := writeMedia(t, dir, "ref.png", 10, 2*time.Hour)
+	fresh := writeMedia(t, dir, "new.png", 10, time.Minute)
+
+	r := NewMediaRetention(time.Hour, 0, dir)
+	r.SetReferenced(func() map[string]bool { return map[string]bool{referenced: true} })
+	r.Prune()
+
+	if exists(old) {
+		t.Error("expected unreferenced old file to be removed")
+	}
+	if !exists(referenced) {
+		t.Error("expected referenced file to be kept")
+	}
+	if !exists(fresh) {
+		t.Error("expected fresh file to be kept")
+	}
+}
+
+func TestMediaRetention_EvictsLRUOverSizeCap(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	oldest := writeMedia(t, dirA, "a.png", 100, 30*time.Minute)
+	middle := writeMedia(t, dirB, "b.png", 100, 20*time.Minute)
+	newest := writeMedia(t, dirA, "c.png", 100, 10*time.Minute)
+
+	r := NewMediaRetention(time.Hour, 250, dirA)
+	r.AddDir(dirB)
+	r.SetReferenced(func() map[string]bool { return map[string]bool{oldest: true} })
+	r.Prune()
+
+	if exists(oldest) {
+		t.Error("expected least recently used file to be evicted even if referenced")
+	}
+	if !exists(middle) || !exists(newest) {
+		t.Error("expected newer files to be kept")
+	}
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// keyCheck is sealed with the derived key so a wrong passphrase is detected
+// before anything is written with it.
+const keyCheck = "localagent-vault"
+
+// KeychainService is the service name used to look up the passphrase in the
+// OS keychain.
+const KeychainService = "localagent"
+
+// params is persisted next to the data so the key can be re-derived.
+type params struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// DeriveKey derives a key from a passphrase with argon2id.
+func DeriveKey(passphrase string, salt []byte) *Key {
+	var k Key
+	copy(k[:], argon2.IDKey([]byte(passphrase), salt, 3, 64*1024, 4, uint32(len(k))))
+	return &k
+}
+
+// Unlock derives the key for the vault described by paramsPath, creating a
+// new salt on first use. It fails with ErrWrongPassphrase if the passphrase
+// doesn't match the existing vault.
+func Unlock(paramsPath, passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("vault: empty passphrase")
+	}
+
+	var p params
+	data, err := os.ReadFile(paramsPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("vault: invalid %s: %w", paramsPath, err)
+		}
+		key := DeriveKey(passphrase, p.Salt)
+		if pt, err := open(key, p.Check); err != nil || string(pt) != keyCheck {
+			return nil, ErrWrongPassphrase
+		}
+		return key, nil
+	case os.IsNotExist(err):
+		p.Salt = make([]byte, 16)
+		rand.Read(p.Salt)
+		key := DeriveKey(passphrase, p.Salt)
+		p.Check = seal(key, []byte(keyCheck))
+		data, _ := json.MarshalIndent(p, "", "  ")
+		if err := os.MkdirAll(filepath.Dir(paramsPath), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(paramsPath, data, 0600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	default:
+		return nil, err
+	}
+}
+
+// KeychainPassphrase reads the passphrase from the OS keychain: the macOS
+// login keychain or the freedesktop Secret Service via secret-tool.
+func KeychainPassphrase() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", KeychainService, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", KeychainService)
+	default:
+		return "", fmt.Errorf("vault: no keychain support on %s", runtime.GOOS)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("vault: keychain lookup failed: %w", err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
@@ -0,0 +1,278 @@
+// Package vault encrypts files at rest with XChaCha20-Poly1305 using a key
+// derived from a passphrase (argon2id). Storage layers read through it
+// unconditionally, so plaintext files keep working; writes under a protected
+// directory are sealed once a key is enabled.
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"localagent/pkg/storage"
+)
+
+// fileMagic prefixes sealed whole files; lineMagic prefixes sealed JSONL lines.
+var (
+	fileMagic = []byte("LAVAULT1")
+	lineMagic = []byte("LAV1:")
+)
+
+// ErrLocked is returned when reading sealed data without an enabled key.
+var ErrLocked = errors.New("vault: data is encrypted but no key is enabled")
+
+// ErrWrongPassphrase is returned when a passphrase doesn't match the one the
+// vault was created with.
+var ErrWrongPassphrase = errors.New("vault: wrong passphrase")
+
+type Key [chacha20poly1305.KeySize]byte
+
+var (
+	mu        sync.RWMutex
+	activeKey *Key
+	protected []string
+)
+
+// Enable sets the key used for sealing and opening and the directories whose
+// files are written encrypted.
+func Enable(key *Key, dirs ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	activeKey = key
+	protected = protected[:0]
+	for _, d := range dirs {
+		if abs, err := filepath.Abs(d); err == nil {
+			protected = append(protected, abs)
+		}
+	}
+}
+
+// Enabled reports whether a key is active.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return activeKey != nil
+}
+
+// keyFor returns the active key if path should be written sealed.
+func keyFor(path string) *Key {
+	mu.RLock()
+	defer mu.RUnlock()
+	if activeKey == nil {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for _, dir := range protected {
+		if abs == dir || strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+			return activeKey
+		}
+	}
+	return nil
+}
+
+func currentKey() *Key {
+	mu.RLock()
+	defer mu.RUnlock()
+	return activeKey
+}
+
+// Protected reports whether files written to path are sealed.
+func Protected(path string) bool {
+	return keyFor(path) != nil
+}
+
+func seal(key *Key, plaintext []byte) []byte {
+	aead, _ := chacha20poly1305.NewX(key[:])
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	rand.Read(nonce)
+	return aead.Seal(nonce, nonce, plaintext, nil)
+}
+
+func open(key *Key, sealed []byte) ([]byte, error) {
+	aead, _ := chacha20poly1305.NewX(key[:])
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("vault: ciphertext too short")
+	}
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	pt, err := aead.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return nil, fmt.Errorf("vault: decrypt failed: %w", err)
+	}
+	return pt, nil
+}
+
+// IsSealed reports whether data is a sealed file.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic)
+}
+
+// Decode returns the plaintext of data, which may be sealed or plain.
+func Decode(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	key := currentKey()
+	if key == nil {
+		return nil, ErrLocked
+	}
+	return open(key, data[len(fileMagic):])
+}
+
+// Encode seals data if path is protected, otherwise returns it unchanged.
+func Encode(path string, data []byte) []byte {
+	key := keyFor(path)
+	if key == nil {
+		return data
+	}
+	return append(append([]byte{}, fileMagic...), seal(key, data)...)
+}
+
+// ReadFile is os.ReadFile that transparently decrypts sealed files.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// WriteFile is os.WriteFile that seals data under protected directories.
+// Sealed files are always written with mode 0600.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if Protected(path) {
+		perm = 0600
+	}
+	return os.WriteFile(path, Encode(path, data), perm)
+}
+
+// WriteFileAtomic is WriteFile through storage.WriteFileWithBackup: the
+// file is replaced atomically and the previous version kept as a backup.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if Protected(path) {
+		perm = 0600
+	}
+	return storage.WriteFileWithBackup(path, Encode(path, data), perm)
+}
+
+// AppendFile appends data to path. Sealed files are decrypted, extended and
+// rewritten since ciphertext can't be appended to.
+func AppendFile(path string, data []byte, perm os.FileMode) error {
+	if !Protected(path) {
+		existing, err := os.ReadFile(path)
+		if err != nil || !IsSealed(existing) {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = f.Write(data)
+			return err
+		}
+	}
+	existing, err := ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return WriteFile(path, append(existing, data...), perm)
+}
+
+// EncodeLine seals a single JSONL line (without its newline) if path is protected.
+func EncodeLine(path string, line []byte) []byte {
+	key := keyFor(path)
+	if key == nil {
+		return line
+	}
+	sealed := seal(key, line)
+	out := make([]byte, len(lineMagic)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, lineMagic)
+	base64.StdEncoding.Encode(out[len(lineMagic):], sealed)
+	return out
+}
+
+// DecodeLine returns the plaintext of a JSONL line, which may be sealed or plain.
+func DecodeLine(line []byte) ([]byte, error) {
+	if !bytes.HasPrefix(line, lineMagic) {
+		return line, nil
+	}
+	key := currentKey()
+	if key == nil {
+		return nil, ErrLocked
+	}
+	raw, err := base64.StdEncoding.DecodeString(string(line[len(lineMagic):]))
+	if err != nil {
+		return nil, fmt.Errorf("vault: bad line encoding: %w", err)
+	}
+	return open(key, raw)
+}
+
+// LineEncoder writes JSON values as JSONL, sealing each line when the
+// target path is protected. It mirrors json.Encoder's Encode.
+type LineEncoder struct {
+	f    *os.File
+	path string
+}
+
+// NewLineEncoder returns an encoder writing to f, whose final location is path.
+func NewLineEncoder(f *os.File, path string) *LineEncoder {
+	return &LineEncoder{f: f, path: path}
+}
+
+func (e *LineEncoder) Encode(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.f.Write(append(EncodeLine(e.path, data), '\n'))
+	return err
+}
+
+// SealFile rewrites a plaintext file under a protected directory in sealed
+// form. JSONL files are sealed line by line so they can still be appended to.
+// It reports whether the file was changed.
+func SealFile(path string) (bool, error) {
+	if !Protected(path) {
+		return false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var out []byte
+	if filepath.Ext(path) == ".jsonl" {
+		changed := false
+		for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+			if len(line) > 0 && !bytes.HasPrefix(line, lineMagic) {
+				line = EncodeLine(path, line)
+				changed = true
+			}
+			out = append(append(out, line...), '\n')
+		}
+		if !changed {
+			return false, nil
+		}
+	} else {
+		if IsSealed(data) {
+			return false, nil
+		}
+		out = Encode(path, data)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0600); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, path)
+}
@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func enableForTest(t *testing.T, dirs ...string) {
+	t.Helper()
+	key, err := Unlock(filepath.Join(t.TempDir(), "vault.json"), "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Enable(key, dirs...)
+	t.Cleanup(func() { Enable(nil) })
+}
+
+func TestWriteReadProtected(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "memory")
+	os.MkdirAll(secret, 0755)
+	enableForTest(t, secret)
+
+	path := filepath.Join(secret, "MEMORY.md")
+	if err := WriteFile(path, []byte("likes tea"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if !IsSealed(raw) || bytes.Contains(raw, []byte("tea")) {
+		t.Fatalf("file should be sealed on disk: %q", raw)
+	}
+	if err := AppendFile(path, []byte(", hates coffee"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil || string(got) != "likes tea, hates coffee" {
+		t.Fatalf("ReadFile = %q, %v", got, err)
+	}
+
+	plain := filepath.Join(dir, "notes.txt")
+	WriteFile(plain, []byte("public"), 0644)
+	if raw, _ := os.ReadFile(plain); string(raw) != "public" {
+		t.Errorf("unprotected file should stay plain, got %q", raw)
+	}
+
+	Enable(nil)
+	if _, err := ReadFile(path); err != ErrLocked {
+		t.Errorf("expected ErrLocked without a key, got %v", err)
+	}
+}
+
+func TestLinesAndSealFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "web_default.jsonl")
+	os.WriteFile(path, []byte(`{"t":"msg"}`+"\n"+`{"t":"sum"}`+"\n"), 0644)
+	enableForTest(t, dir)
+
+	changed, err := SealFile(path)
+	if err != nil || !changed {
+		t.Fatalf("SealFile = %v, %v", changed, err)
+	}
+	raw, _ := os.ReadFile(path)
+	lines := bytes.Split(bytes.TrimSpace(raw), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 sealed lines, got %q", raw)
+	}
+	for i, want := range []string{`{"t":"msg"}`, `{"t":"sum"}`} {
+		got, err := DecodeLine(lines[i])
+		if err != nil || string(got) != want {
+			t.Errorf("line %d: %q, %v", i, got, err)
+		}
+	}
+	if changed, _ := SealFile(path); changed {
+		t.Error("sealing twice should be a no-op")
+	}
+}
+
+func TestUnlockRejectsWrongPassphrase(t *testing.T) {
+	params := filepath.Join(t.TempDir(), "vault.json")
+	k1, err := Unlock(params, "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := Unlock(params, "one")
+	if err != nil || *k1 != *k2 {
+		t.Fatal("same passphrase should derive the same key")
+	}
+	if _, err := Unlock(params, "two"); err != ErrWrongPassphrase {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestProtectSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "USER.md")
+	enableForTest(t, user)
+
+	if !Protected(user) {
+		t.Error("a listed file should be protected")
+	}
+	if Protected(filepath.Join(dir, "SOUL.md")) || Protected(user+".bak") {
+		t.Error("siblings of a listed file should stay plain")
+	}
+}
@@ -0,0 +1,297 @@
+// Package watch polls directories for new or changed files and hands each
+// one, once it has settled, to a trigger handler (a heartbeat event or an
+// agent workflow). Polling keeps it dependency-free and works on network
+// mounts where inotify doesn't.
+package watch
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+	"localagent/pkg/state"
+)
+
+const (
+	pollInterval    = 2 * time.Second
+	defaultDebounce = 5 * time.Second
+	maxPerPoll      = 20   // triggers per poll; the rest wait for the next one
+	maxFiles        = 2000 // files tracked per watcher; the list is kept in the state store
+	stateNamespace  = "watch"
+)
+
+// defaultIgnore skips hidden files and the usual partial-download and
+// editor temp names.
+var defaultIgnore = []string{".*", "*.part", "*.crdownload", "*.download", "*.tmp", "*.swp", "~*", "*~"}
+
+// Change is what happened to a file.
+type Change string
+
+const (
+	Created  Change = "created"
+	Modified Change = "modified"
+)
+
+// Trigger is a settled file change.
+type Trigger struct {
+	Watcher string // the watcher's label
+	Path    string // absolute
+	Name    string
+	Change  Change
+}
+
+// Expand replaces {path}, {name} and {change} in tmpl. An empty tmpl
+// gives a plain note about the file.
+func (t Trigger) Expand(tmpl string) string {
+	if tmpl == "" {
+		tmpl = "File {change} in the watched folder " + t.Watcher + ": {path}"
+	}
+	return strings.NewReplacer("{path}", t.Path, "{name}", t.Name, "{change}", string(t.Change)).Replace(tmpl)
+}
+
+// fileInfo identifies a version of a file.
+type fileInfo struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"` // unix nanoseconds
+}
+
+type pendingChange struct {
+	info   fileInfo
+	since  time.Time
+	change Change
+}
+
+// Watcher watches one directory.
+type Watcher struct {
+	cfg      config.WatcherConfig
+	dir      string
+	label    string
+	debounce time.Duration
+	ignore   []string
+	handle   func(Trigger)
+	state    *state.Manager
+	now      func() time.Time
+
+	mu      sync.Mutex
+	seen    map[string]fileInfo // relative path -> last version triggered or baselined
+	pending map[string]pendingChange
+	scanErr bool
+	stop    chan struct{}
+}
+
+// New creates a watcher for cfg; relative paths are inside workspace.
+// Files already there when the directory is first watched don't trigger;
+// changes made while the gateway was down do, on start.
+func New(cfg config.WatcherConfig, workspace string, handle func(Trigger)) (*Watcher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	debounce := defaultDebounce
+	if cfg.DebounceSeconds > 0 {
+		debounce = time.Duration(cfg.DebounceSeconds) * time.Second
+	}
+	return &Watcher{
+		cfg:      cfg,
+		dir:      cfg.Dir(workspace),
+		label:    cfg.Label(),
+		debounce: debounce,
+		ignore:   slices.Concat(defaultIgnore, cfg.Ignore),
+		handle:   handle,
+		state:    state.NewManager(workspace),
+		now:      time.Now,
+		pending:  make(map[string]pendingChange),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Dir is the watched directory.
+func (w *Watcher) Dir() string {
+	return w.dir
+}
+
+func (w *Watcher) Start() {
+	w.load()
+	ticker := time.NewTicker(pollInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				w.Poll()
+			case <-w.stop:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	logger.Info("watch: %s: watching %s", w.label, w.dir)
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+}
+
+// load restores the files seen before a restart, or takes the current
+// contents as the baseline the first time.
+func (w *Watcher) load() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if ok, err := w.state.Get(stateNamespace, w.dir, &w.seen); err != nil {
+		logger.Warn("watch: %s: ignoring corrupt state: %v", w.label, err)
+	} else if ok && w.seen != nil {
+		return
+	}
+	files, err := w.scan()
+	if err != nil {
+		// Watch from when the directory shows up: everything in it is new.
+		logger.Warn("watch: %s: %v", w.label, err)
+		files = make(map[string]fileInfo)
+	}
+	w.seen = files
+	w.save()
+}
+
+// Poll scans the directory once and runs the handler for files that have
+// been created or changed and stayed unchanged for the debounce time.
+func (w *Watcher) Poll() {
+	w.mu.Lock()
+	if w.seen == nil {
+		w.mu.Unlock()
+		w.load()
+		w.mu.Lock()
+	}
+	files, err := w.scan()
+	if err != nil {
+		// Leave what was seen alone, so an unmounted drive doesn't make
+		// every file look new when it comes back.
+		if !w.scanErr {
+			logger.Warn("watch: %s: %v", w.label, err)
+		}
+		w.scanErr = true
+		w.mu.Unlock()
+		return
+	}
+	w.scanErr = false
+
+	now := w.now()
+	changed := false
+	for rel := range w.seen {
+		if _, ok := files[rel]; !ok {
+			delete(w.seen, rel)
+			changed = true
+		}
+	}
+	var ready []string
+	for rel, info := range files {
+		old, known := w.seen[rel]
+		if known && old == info {
+			delete(w.pending, rel)
+			continue
+		}
+		p, ok := w.pending[rel]
+		if !ok || p.info != info {
+			change := Created
+			if known {
+				change = Modified
+			}
+			w.pending[rel] = pendingChange{info: info, since: now, change: change}
+			continue
+		}
+		if now.Sub(p.since) >= w.debounce {
+			ready = append(ready, rel)
+		}
+	}
+	for rel := range w.pending {
+		if _, ok := files[rel]; !ok {
+			delete(w.pending, rel)
+		}
+	}
+
+	slices.Sort(ready)
+	if len(ready) > maxPerPoll {
+		ready = ready[:maxPerPoll]
+	}
+	triggers := make([]Trigger, 0, len(ready))
+	for _, rel := range ready {
+		p := w.pending[rel]
+		w.seen[rel] = p.info
+		delete(w.pending, rel)
+		triggers = append(triggers, Trigger{
+			Watcher: w.label,
+			Path:    filepath.Join(w.dir, rel),
+			Name:    filepath.Base(rel),
+			Change:  p.change,
+		})
+		changed = true
+	}
+	if changed {
+		w.save()
+	}
+	w.mu.Unlock()
+
+	for _, t := range triggers {
+		logger.Info("watch: %s: %s %s", w.label, t.Change, t.Path)
+		w.handle(t)
+	}
+}
+
+// scan lists the matching files under dir. The caller holds w.mu.
+func (w *Watcher) scan() (map[string]fileInfo, error) {
+	files := make(map[string]fileInfo)
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.dir {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != w.dir && (!w.cfg.Recursive || w.ignored(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || w.ignored(d.Name()) || !w.matches(d.Name()) {
+			return nil
+		}
+		if len(files) >= maxFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(w.dir, path)
+		files[rel] = fileInfo{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		return nil
+	})
+	return files, err
+}
+
+func (w *Watcher) ignored(name string) bool {
+	return slices.ContainsFunc(w.ignore, func(p string) bool {
+		ok, _ := filepath.Match(p, name)
+		return ok
+	})
+}
+
+func (w *Watcher) matches(name string) bool {
+	if len(w.cfg.Patterns) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(w.cfg.Patterns, func(p string) bool {
+		ok, _ := filepath.Match(p, name)
+		return ok
+	})
+}
+
+// save persists the files seen. The caller holds w.mu.
+func (w *Watcher) save() {
+	if err := w.state.Set(stateNamespace, w.dir, w.seen); err != nil {
+		logger.Warn("watch: %s: save state: %v", w.label, err)
+	}
+}
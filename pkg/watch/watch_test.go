@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"localagent/pkg/config"
+)
+
+func TestWatcherDebounceAndIgnore(t *testing.T) {
+	ws := t.TempDir()
+	inbox := filepath.Join(ws, "inbox")
+	os.MkdirAll(inbox, 0755)
+	os.WriteFile(filepath.Join(inbox, "old.pdf"), []byte("x"), 0644)
+
+	var got []Trigger
+	cfg := config.WatcherConfig{Path: "inbox", Patterns: []string{"*.pdf"}, DebounceSeconds: 10}
+	w, err := New(cfg, ws, func(tr Trigger) { got = append(got, tr) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	w.now = func() time.Time { return now }
+	w.load()
+
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(inbox, name), []byte(content), 0644)
+	}
+	write("report.pdf", "partial")
+	write("notes.txt", "not a pdf")
+	write("scan.pdf.part", "downloading")
+	w.Poll()
+
+	// Still being written: the debounce restarts.
+	now = now.Add(8 * time.Second)
+	write("report.pdf", "partial, more")
+	w.Poll()
+	now = now.Add(8 * time.Second)
+	w.Poll()
+	if len(got) != 0 {
+		t.Fatalf("triggered before the file settled: %+v", got)
+	}
+	now = now.Add(3 * time.Second)
+	w.Poll()
+	if len(got) != 1 || got[0].Name != "report.pdf" || got[0].Change != Created || got[0].Path != filepath.Join(inbox, "report.pdf") {
+		t.Fatalf("triggers = %+v, want report.pdf created", got)
+	}
+	w.Poll()
+	if len(got) != 1 {
+		t.Errorf("triggered twice: %+v", got)
+	}
+
+	// Changes while stopped are picked up after a restart; the baseline
+	// file never triggers.
+	write("report.pdf", "final version")
+	w2, _ := New(cfg, ws, func(tr Trigger) { got = append(got, tr) })
+	w2.now = func() time.Time { return now }
+	w2.load()
+	w2.Poll()
+	now = now.Add(11 * time.Second)
+	w2.Poll()
+	if len(got) != 2 || got[1].Name != "report.pdf" || got[1].Change != Modified {
+		t.Fatalf("triggers after restart = %+v", got)
+	}
+	if tr := got[1]; tr.Expand("Summarize {path} ({change})") != "Summarize "+tr.Path+" (modified)" {
+		t.Errorf("expand = %q", tr.Expand("Summarize {path} ({change})"))
+	}
+}
+
+func TestWatcherConfigValidate(t *testing.T) {
+	for _, c := range []config.WatcherConfig{
+		{},
+		{Path: "inbox", Action: "email"},
+		{Path: "inbox", Action: "agent"},
+		{Path: "inbox", Patterns: []string{"[pdf"}},
+	} {
+		if _, err := New(c, t.TempDir(), nil); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+	if dir := (config.WatcherConfig{Path: "inbox"}).Dir("/ws"); dir != "/ws/inbox" {
+		t.Errorf("relative dir = %s", dir)
+	}
+}
@@ -3,6 +3,8 @@ package webchat
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,22 +13,36 @@ import (
 	"localagent/pkg/bus"
 	"localagent/pkg/channels"
 	"localagent/pkg/config"
+	"localagent/pkg/cron"
+	"localagent/pkg/dnd"
+	"localagent/pkg/llmcapture"
 	"localagent/pkg/logger"
+	"localagent/pkg/readstate"
 	"localagent/pkg/session"
+	"localagent/pkg/storage"
+	"localagent/pkg/templates"
 	"localagent/pkg/todo"
+	"localagent/pkg/tools"
+	"localagent/pkg/utils"
 )
 
 type OutgoingEvent struct {
-	Type       string        `json:"type"`
-	Role       string        `json:"role,omitempty"`
-	Content    string        `json:"content,omitempty"`
-	Event      *ActivityData `json:"event,omitempty"`
-	Processing *bool         `json:"processing,omitempty"`
-	ClientID   string        `json:"client_id,omitempty"`
-	Action     string        `json:"action,omitempty"`
-	TaskData   *todo.Task    `json:"task,omitempty"`
-	BlockData  *todo.Block   `json:"block,omitempty"`
-	LinkData   *todo.Link    `json:"link,omitempty"`
+	ID         uint64           `json:"-"` // SSE event id, 0 = not replayable
+	Type       string           `json:"type"`
+	Role       string           `json:"role,omitempty"`
+	Content    string           `json:"content,omitempty"`
+	Event      *ActivityData    `json:"event,omitempty"`
+	Processing *bool            `json:"processing,omitempty"`
+	MessageID  string           `json:"message_id,omitempty"`
+	Status     string           `json:"status,omitempty"`
+	ReplyTo    string           `json:"reply_to,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	ClientID   string           `json:"client_id,omitempty"`
+	Action     string           `json:"action,omitempty"`
+	TaskData   *todo.Task       `json:"task,omitempty"`
+	BlockData  *todo.Block      `json:"block,omitempty"`
+	LinkData   *todo.Link       `json:"link,omitempty"`
+	FocusData  *dnd.FocusStatus `json:"focus,omitempty"`
 }
 
 type ActivityData struct {
@@ -36,7 +52,12 @@ type ActivityData struct {
 	Detail    map[string]any `json:"detail,omitempty"`
 }
 
-type sseClient struct {
+// eventLogSize is how many recent events are kept for Last-Event-ID replay.
+const eventLogSize = 256
+
+// streamClient is a connected event subscriber. It is transport-agnostic:
+// SSE and WebSocket handlers both drain events from it.
+type streamClient struct {
 	id     string
 	events chan OutgoingEvent
 	active bool
@@ -44,17 +65,31 @@ type sseClient struct {
 
 type WebChatChannel struct {
 	*channels.BaseChannel
-	config      *config.WebChatConfig
-	server      *Server
-	sessions    *session.SessionManager
-	todoService *todo.TodoService
-	dataDir     string
-	stt         config.STTConfig
-	tts         config.TTSConfig
-	image       config.ImageConfig
-	clients     map[string]*sseClient
-	mu          sync.RWMutex
-	processing  atomic.Bool
+	config       *config.WebChatConfig
+	server       *Server
+	sessions     *session.SessionManager
+	todoService  *todo.TodoService
+	media        *utils.MediaRetention
+	canceller    func(sessionKey string) bool
+	templates    *templates.Store
+	cron         *cron.CronService
+	toolLister   func() []tools.Tool
+	storage      func() storage.Usage
+	llmCapture   *llmcapture.Store
+	readState    *readstate.Tracker
+	focus        *dnd.Focus
+	exportRedact func(string) string
+	imageQuota   int64
+	dataDir      string
+	stt          config.STTConfig
+	tts          config.TTSConfig
+	image        config.ImageConfig
+	clients      map[string]*streamClient
+	eventLog     []OutgoingEvent // ring of recent events for reconnect replay
+	lastEventID  uint64
+	epoch        string // changes on every start, prefixes event IDs on the wire
+	mu           sync.RWMutex
+	processing   atomic.Bool
 
 	// voiceResponseCh captures assistant responses for the active voice session.
 	// When non-nil, Send() will also deliver the response text here.
@@ -70,8 +105,10 @@ func NewWebChatChannel(cfg *config.WebChatConfig, msgBus *bus.MessageBus, dataDi
 		stt:         stt,
 		tts:         tts,
 		image:       image,
-		clients:     make(map[string]*sseClient),
+		clients:     make(map[string]*streamClient),
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
 	}
+	msgBus.SubscribeStatus(ch.Name(), ch.onMessageStatus)
 	return ch
 }
 
@@ -90,6 +127,51 @@ func (ch *WebChatChannel) SetTodoService(ts *todo.TodoService) {
 	ch.todoService = ts
 }
 
+// SetMediaRetention shares the agent's media retention policy so uploads
+// are pruned under the same rules. Must be called before Start.
+func (ch *WebChatChannel) SetMediaRetention(r *utils.MediaRetention) {
+	ch.media = r
+}
+
+// SetTemplates enables "/template <name>" expansion and the template API.
+func (ch *WebChatChannel) SetTemplates(store *templates.Store) {
+	ch.templates = store
+}
+
+// SetCronService exposes cron jobs as command palette triggers.
+func (ch *WebChatChannel) SetCronService(cs *cron.CronService) {
+	ch.cron = cs
+}
+
+// SetToolLister provides the agent's tools for command palette shortcuts.
+func (ch *WebChatChannel) SetToolLister(fn func() []tools.Tool) {
+	ch.toolLister = fn
+}
+
+// SetStorage sets the image job quota in bytes (0 = unlimited) and the
+// function that reports disk usage for /api/storage. Must be called before
+// Start.
+func (ch *WebChatChannel) SetStorage(imageJobsMaxBytes int64, usage func() storage.Usage) {
+	ch.imageQuota = imageJobsMaxBytes
+	ch.storage = usage
+}
+
+// SetLLMCapture enables /api/debug/llm for browsing captured provider
+// exchanges.
+func (ch *WebChatChannel) SetLLMCapture(store *llmcapture.Store) {
+	ch.llmCapture = store
+}
+
+// SetReadTracker marks the chat read whenever a tab is visible.
+func (ch *WebChatChannel) SetReadTracker(t *readstate.Tracker) {
+	ch.readState = t
+}
+
+// SetCanceller sets the function used by /api/cancel to stop in-flight processing.
+func (ch *WebChatChannel) SetCanceller(fn func(sessionKey string) bool) {
+	ch.canceller = fn
+}
+
 func (ch *WebChatChannel) Start(ctx context.Context) error {
 	addr := fmt.Sprintf("%s:%d", ch.config.Host, ch.config.Port)
 	ch.server = NewServer(addr, ch)
@@ -122,6 +204,7 @@ func (ch *WebChatChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		Type:    "message",
 		Role:    "assistant",
 		Content: msg.Content,
+		ReplyTo: msg.ReplyTo,
 	}
 	ch.broadcast(event)
 
@@ -136,6 +219,10 @@ func (ch *WebChatChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		}
 	}
 
+	if ch.hasActiveClient() {
+		ch.markRead()
+	}
+
 	if ch.server != nil && ch.server.pushManager != nil && !ch.hasActiveClient() {
 		body := msg.Content
 		if len(body) > 200 {
@@ -165,7 +252,7 @@ func (ch *WebChatChannel) Emit(evt activity.Event) {
 		ch.processing.Store(true)
 		return
 	}
-	if evt.Type == activity.Complete {
+	if evt.Type == activity.Complete || evt.Type == activity.Cancelled {
 		ch.processing.Store(false)
 	}
 
@@ -181,6 +268,24 @@ func (ch *WebChatChannel) Emit(evt activity.Event) {
 	ch.broadcast(event)
 }
 
+// StreamDelta sends a piece of the assistant's reply in a web session to
+// the connected clients as a "delta" event. Deltas are not kept for
+// replay: the complete reply follows as a regular message.
+func (ch *WebChatChannel) StreamDelta(sessionKey, delta string) {
+	if !strings.HasPrefix(sessionKey, ch.Name()+":") {
+		return
+	}
+	event := OutgoingEvent{Type: "delta", Content: delta}
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+	for _, client := range ch.clients {
+		select {
+		case client.events <- event:
+		default:
+		}
+	}
+}
+
 func (ch *WebChatChannel) BroadcastTaskEvent(evt todo.TaskEvent) {
 	ch.broadcast(OutgoingEvent{
 		Type:     "task",
@@ -209,41 +314,112 @@ func (ch *WebChatChannel) IsAllowed(senderID string) bool {
 	return true
 }
 
-func (ch *WebChatChannel) HandleIncoming(content string, media []string, metadata map[string]string) {
+// HandleIncoming persists and publishes a user message, returning its ID.
+// Slash commands are answered directly and persisted with their reply.
+// Delivery status updates for the ID are broadcast as "message_status" events.
+// A retry carrying the same metadata["message_id"] is not processed again;
+// the first message's ID is returned.
+func (ch *WebChatChannel) HandleIncoming(content string, media []string, metadata map[string]string) string {
 	if !ch.IsAllowed("web-user") {
-		return
+		return ""
 	}
 
 	sessionKey := fmt.Sprintf("%s:default", ch.Name())
+	id := utils.RandHex(8)
+
+	msg := bus.InboundMessage{
+		ID:             id,
+		Channel:        ch.Name(),
+		SenderID:       "web-user",
+		ChatID:         "default",
+		Content:        content,
+		Media:          media,
+		SessionKey:     sessionKey,
+		Metadata:       metadata,
+		Persisted:      true,
+		IdempotencyKey: metadata[bus.MetadataMessageID],
+	}
+	if firstID, fresh := ch.Bus().Claim(msg); !fresh {
+		logger.Info("web: dropping duplicate message %s", msg.IdempotencyKey)
+		return firstID
+	}
 
 	// Persist user message to session immediately so it survives page refresh
 	// even if the agent hasn't picked it up from the bus yet.
 	if ch.sessions != nil {
-		ch.sessions.AddMessageWithMedia(sessionKey, "user", content, media)
+		if audio := metadata["audio"]; audio != "" && len(media) == 0 {
+			ch.sessions.AddMessageWithAudio(sessionKey, "user", content, audio)
+		} else {
+			ch.sessions.AddMessageWithMedia(sessionKey, "user", content, media)
+		}
 	}
 
-	ch.Bus().PublishInbound(bus.InboundMessage{
-		Channel:    ch.Name(),
-		SenderID:   "web-user",
-		ChatID:     "default",
-		Content:    content,
-		Media:      media,
-		SessionKey: sessionKey,
-		Metadata:   metadata,
-		Persisted:  true,
+	if reply, ok := ch.HandleCommand("default", "web-user", content); ok {
+		if ch.sessions != nil {
+			ch.sessions.AddMessage(sessionKey, "assistant", reply)
+		}
+		ch.Bus().PublishOutbound(bus.OutboundMessage{Channel: ch.Name(), ChatID: "default", Content: reply, ReplyTo: id})
+		ch.onMessageStatus(bus.StatusUpdate{MessageID: id, Channel: ch.Name(), ChatID: "default", Status: bus.StatusAnswered})
+		return id
+	}
+
+	ch.onMessageStatus(bus.StatusUpdate{MessageID: id, Channel: ch.Name(), ChatID: "default", Status: bus.StatusQueued})
+
+	ch.Bus().PublishInbound(msg)
+	return id
+}
+
+func (ch *WebChatChannel) onMessageStatus(update bus.StatusUpdate) {
+	ch.broadcast(OutgoingEvent{
+		Type:      "message_status",
+		MessageID: update.MessageID,
+		Status:    string(update.Status),
+		Error:     update.Error,
 	})
 }
 
-func (ch *WebChatChannel) registerClient(id string) *sseClient {
-	client := &sseClient{
+// wireEventID is the event ID sent to clients: the sequence number
+// prefixed with the epoch, so IDs from before a restart are recognized.
+// Events without a sequence number get none.
+func (ch *WebChatChannel) wireEventID(seq uint64) string {
+	if seq == 0 {
+		return ""
+	}
+	return ch.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// registerClient adds a stream client. If lastEventID (a wire event ID) is
+// set, events broadcast after it are returned for replay; ok is false when
+// some of them have already fallen out of the log, or the ID is from
+// before a restart, and the client must resync.
+func (ch *WebChatChannel) registerClient(id, lastEventID string) (client *streamClient, missed []OutgoingEvent, ok bool) {
+	client = &streamClient{
 		id:     id,
 		events: make(chan OutgoingEvent, 64),
 	}
 	ch.mu.Lock()
 	ch.clients[id] = client
+	ok = true
+	if lastEventID != "" {
+		epoch, n, _ := strings.Cut(lastEventID, "-")
+		seq, err := strconv.ParseUint(n, 10, 64)
+		switch {
+		case err != nil || epoch != ch.epoch || seq > ch.lastEventID:
+			ok = false
+		case seq < ch.lastEventID:
+			if len(ch.eventLog) == 0 || ch.eventLog[0].ID > seq+1 {
+				ok = false
+			}
+			for _, e := range ch.eventLog {
+				if e.ID > seq {
+					missed = append(missed, e)
+				}
+			}
+		}
+	}
 	ch.mu.Unlock()
-	logger.Info("webchat SSE client connected: %s", id)
-	return client
+	logger.Info("webchat client connected: %s (replaying %d)", id, len(missed))
+	return client, missed, ok
 }
 
 func (ch *WebChatChannel) unregisterClient(id string) {
@@ -253,18 +429,28 @@ func (ch *WebChatChannel) unregisterClient(id string) {
 		delete(ch.clients, id)
 	}
 	ch.mu.Unlock()
-	logger.Info("webchat SSE client disconnected: %s", id)
+	logger.Info("webchat client disconnected: %s", id)
 }
 
 func (ch *WebChatChannel) setClientActive(id string, active bool) bool {
 	ch.mu.Lock()
-	defer ch.mu.Unlock()
 	client, ok := ch.clients[id]
-	if !ok {
-		return false
+	if ok {
+		client.active = active
+	}
+	ch.mu.Unlock()
+	if ok && active {
+		ch.markRead()
+	}
+	return ok
+}
+
+// markRead records that the user has seen the chat. Called when a tab
+// becomes visible and when a message arrives while one is.
+func (ch *WebChatChannel) markRead() {
+	if ch.readState != nil {
+		ch.readState.MarkRead(ch.Name(), "default")
 	}
-	client.active = active
-	return true
 }
 
 func (ch *WebChatChannel) hasActiveClient() bool {
@@ -279,13 +465,21 @@ func (ch *WebChatChannel) hasActiveClient() bool {
 }
 
 func (ch *WebChatChannel) broadcast(event OutgoingEvent) {
-	ch.mu.RLock()
-	defer ch.mu.RUnlock()
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.lastEventID++
+	event.ID = ch.lastEventID
+	ch.eventLog = append(ch.eventLog, event)
+	if len(ch.eventLog) > eventLogSize {
+		ch.eventLog = ch.eventLog[len(ch.eventLog)-eventLogSize:]
+	}
+
 	for _, client := range ch.clients {
 		select {
 		case client.events <- event:
 		default:
-			logger.Warn("webchat SSE client %s buffer full, dropping message", client.id)
+			logger.Warn("webchat client %s buffer full, dropping message", client.id)
 		}
 	}
 }
@@ -0,0 +1,216 @@
+package webchat
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+)
+
+// Command kinds exposed to the command palette.
+const (
+	commandTemplate = "template"
+	commandCron     = "cron"
+	commandTool     = "tool"
+	commandSession  = "session"
+)
+
+type commandParam struct {
+	Name     string `json:"name"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// paletteCommand is a quick action listed by /api/commands. ID is
+// "<kind>:<target>" and is passed back to /api/commands/invoke.
+type paletteCommand struct {
+	ID          string         `json:"id"`
+	Kind        string         `json:"kind"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Keywords    []string       `json:"keywords,omitempty"`
+	Params      []commandParam `json:"params,omitempty"`
+}
+
+type invokeCommandRequest struct {
+	ID   string            `json:"id"`
+	Args map[string]string `json:"args"`
+}
+
+// invokeCommandResponse tells the frontend what happened: "message" means a
+// user message was submitted, "session" asks it to load that session's
+// history, "cron" means the job was triggered.
+type invokeCommandResponse struct {
+	OK        bool   `json:"ok"`
+	Action    string `json:"action"`
+	MessageID string `json:"message_id,omitempty"`
+	Session   string `json:"session,omitempty"`
+}
+
+// commands assembles the palette from the channel's current sources so the
+// list always reflects server state.
+func (s *Server) commands() []paletteCommand {
+	ch := s.channel
+	out := []paletteCommand{}
+
+	if ch.templates != nil {
+		for _, t := range ch.templates.List() {
+			var params []commandParam
+			for _, p := range t.Placeholders() {
+				params = append(params, commandParam{Name: p.Name, Default: p.Default, Required: p.Required})
+			}
+			out = append(out, paletteCommand{
+				ID:          commandTemplate + ":" + t.Name,
+				Kind:        commandTemplate,
+				Title:       "Template: " + t.Name,
+				Description: t.Description,
+				Keywords:    []string{"template", "prompt"},
+				Params:      params,
+			})
+		}
+	}
+
+	if ch.cron != nil {
+		for _, job := range ch.cron.ListJobs(true) {
+			desc := job.Description
+			if !job.Enabled {
+				desc = strings.TrimSpace("(disabled) " + desc)
+			}
+			out = append(out, paletteCommand{
+				ID:          commandCron + ":" + job.ID,
+				Kind:        commandCron,
+				Title:       "Run now: " + job.Name,
+				Description: desc,
+				Keywords:    []string{"cron", "job", "run"},
+			})
+		}
+	}
+
+	if ch.toolLister != nil {
+		for _, t := range ch.toolLister() {
+			out = append(out, paletteCommand{
+				ID:          commandTool + ":" + t.Name(),
+				Kind:        commandTool,
+				Title:       "Use tool: " + t.Name(),
+				Description: t.Description(),
+				Keywords:    []string{"tool"},
+				Params:      []commandParam{{Name: "input", Required: true}},
+			})
+		}
+	}
+
+	if ch.sessions != nil {
+		for _, info := range ch.sessions.ListSessions() {
+			desc := fmt.Sprintf("%d messages", info.Messages)
+			if !info.Updated.IsZero() {
+				desc += ", last active " + info.Updated.Format("2006-01-02 15:04")
+			}
+			out = append(out, paletteCommand{
+				ID:          commandSession + ":" + info.Key,
+				Kind:        commandSession,
+				Title:       "Open session: " + info.Key,
+				Description: desc,
+				Keywords:    []string{"session", "switch", "history"},
+			})
+		}
+	}
+
+	return out
+}
+
+func (s *Server) handleCommandList(c *echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{"commands": s.commands()})
+}
+
+func (s *Server) handleCommandInvoke(c *echo.Context) error {
+	var req invokeCommandRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	resp, err := s.invokeCommand(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) invokeCommand(req invokeCommandRequest) (invokeCommandResponse, error) {
+	kind, target, ok := strings.Cut(req.ID, ":")
+	if !ok || target == "" {
+		return invokeCommandResponse{}, fmt.Errorf("invalid command id %q", req.ID)
+	}
+	ch := s.channel
+
+	switch kind {
+	case commandTemplate:
+		if ch.templates == nil {
+			return invokeCommandResponse{}, fmt.Errorf("templates not available")
+		}
+		t, err := ch.templates.Get(target)
+		if err != nil {
+			return invokeCommandResponse{}, err
+		}
+		content, err := t.Render(req.Args)
+		if err != nil {
+			return invokeCommandResponse{}, err
+		}
+		id, err := s.submitMessage(sendMessageRequest{Content: content})
+		if err != nil {
+			return invokeCommandResponse{}, err
+		}
+		return invokeCommandResponse{OK: true, Action: "message", MessageID: id}, nil
+
+	case commandCron:
+		if ch.cron == nil {
+			return invokeCommandResponse{}, fmt.Errorf("cron not available")
+		}
+		if err := ch.cron.RunJob(target, true); err != nil {
+			return invokeCommandResponse{}, err
+		}
+		return invokeCommandResponse{OK: true, Action: "cron"}, nil
+
+	case commandTool:
+		input := strings.TrimSpace(req.Args["input"])
+		if input == "" {
+			return invokeCommandResponse{}, fmt.Errorf("input is required")
+		}
+		if !s.hasTool(target) {
+			return invokeCommandResponse{}, fmt.Errorf("unknown tool %q", target)
+		}
+		// Tools run through the agent rather than directly so the usual
+		// argument handling and activity reporting apply.
+		content := fmt.Sprintf("Use the `%s` tool: %s", target, input)
+		id, err := s.submitMessage(sendMessageRequest{Content: content})
+		if err != nil {
+			return invokeCommandResponse{}, err
+		}
+		return invokeCommandResponse{OK: true, Action: "message", MessageID: id}, nil
+
+	case commandSession:
+		if ch.sessions == nil {
+			return invokeCommandResponse{}, fmt.Errorf("sessions not available")
+		}
+		for _, info := range ch.sessions.ListSessions() {
+			if info.Key == target {
+				return invokeCommandResponse{OK: true, Action: "session", Session: target}, nil
+			}
+		}
+		return invokeCommandResponse{}, fmt.Errorf("session not found: %s", target)
+	}
+
+	return invokeCommandResponse{}, fmt.Errorf("unknown command kind %q", kind)
+}
+
+func (s *Server) hasTool(name string) bool {
+	if s.channel.toolLister == nil {
+		return false
+	}
+	for _, t := range s.channel.toolLister() {
+		if t.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package webchat
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"localagent/pkg/tools"
+	"localagent/pkg/transcript"
+
+	"github.com/labstack/echo/v5"
+)
+
+var exportNameRe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// SetExportRedactor applies PII redaction to exported transcripts.
+func (ch *WebChatChannel) SetExportRedactor(fn func(string) string) {
+	ch.exportRedact = fn
+}
+
+// handleExport downloads a session as markdown or HTML
+// (?session=, default web:default; ?format=markdown|html).
+func (s *Server) handleExport(c *echo.Context) error {
+	if s.channel.sessions == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no sessions"})
+	}
+	format, err := transcript.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	key := c.QueryParam("session")
+	if key == "" {
+		key = "web:default"
+	}
+
+	opts := transcript.Options{Redact: s.channel.exportRedact}
+	if s.channel.toolLister != nil {
+		opts.Sensitive = tools.SensitiveArgs(s.channel.toolLister())
+	}
+	var buf bytes.Buffer
+	if err := transcript.Export(&buf, s.channel.sessions, key, format, opts); err != nil {
+		if errors.Is(err, transcript.ErrNoSession) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	contentType := "text/markdown; charset=utf-8"
+	if format == transcript.FormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	filename := exportNameRe.ReplaceAllString(key, "-") + transcript.Extension(format)
+	c.Response().Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
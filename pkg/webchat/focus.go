@@ -0,0 +1,52 @@
+package webchat
+
+import (
+	"net/http"
+	"time"
+
+	"localagent/pkg/dnd"
+
+	"github.com/labstack/echo/v5"
+)
+
+// SetFocus enables /api/focus and broadcasts "focus" events when focus
+// starts or ends.
+func (ch *WebChatChannel) SetFocus(f *dnd.Focus) {
+	ch.focus = f
+	f.OnChange(ch.BroadcastFocusEvent)
+}
+
+func (ch *WebChatChannel) BroadcastFocusEvent(st dnd.FocusStatus) {
+	ch.broadcast(OutgoingEvent{Type: "focus", FocusData: &st})
+}
+
+type focusRequest struct {
+	Minutes int    `json:"minutes"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// handleFocus serves GET (status), POST (start for minutes) and DELETE
+// (end) on /api/focus.
+func (s *Server) handleFocus(c *echo.Context) error {
+	f := s.channel.focus
+	if f == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "focus mode not available"})
+	}
+	switch c.Request().Method {
+	case http.MethodPost:
+		var req focusRequest
+		if err := c.Bind(&req); err != nil || req.Minutes <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "minutes must be positive"})
+		}
+		st, err := f.Start(time.Duration(req.Minutes)*time.Minute, req.Reason)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusOK, st)
+	case http.MethodDelete:
+		if _, err := f.End(); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+	}
+	return c.JSON(http.StatusOK, f.Status())
+}
@@ -0,0 +1,59 @@
+package webchat
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"localagent/pkg/session"
+
+	"github.com/labstack/echo/v5"
+)
+
+type forkRequest struct {
+	Session    string `json:"session"`     // default web:default
+	NewSession string `json:"new_session"` // required
+	// At is the RFC3339 timestamp of the last timeline item to keep, as
+	// returned by /api/history. Empty copies the whole session.
+	At string `json:"at,omitempty"`
+}
+
+// handleFork copies a session up to a point in its timeline into a new
+// session, leaving the original untouched.
+func (s *Server) handleFork(c *echo.Context) error {
+	if s.channel.sessions == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "no sessions"})
+	}
+	var req forkRequest
+	if err := c.Bind(&req); err != nil || req.NewSession == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "new_session is required"})
+	}
+	if req.Session == "" {
+		req.Session = "web:default"
+	}
+
+	n := -1
+	if req.At != "" {
+		at, err := time.Parse(time.RFC3339, req.At)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "at must be an RFC3339 timestamp"})
+		}
+		n = 0
+		for _, entry := range s.channel.sessions.GetTimeline(req.Session) {
+			if entry.Kind == "message" && !entry.Timestamp.Truncate(time.Second).After(at) {
+				n++
+			}
+		}
+	}
+
+	copied, err := s.channel.sessions.Fork(req.Session, req.NewSession, n)
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, session.ErrExists):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "session": req.NewSession, "messages": copied})
+}
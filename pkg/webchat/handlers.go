@@ -2,6 +2,7 @@ package webchat
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"localagent/pkg/bus"
+	"localagent/pkg/llmcapture"
 	"localagent/pkg/logger"
 	"localagent/pkg/todo"
 	"localagent/pkg/tools"
@@ -23,6 +26,10 @@ import (
 type sendMessageRequest struct {
 	Content string   `json:"content"`
 	Media   []string `json:"media"`
+	Audio   string   `json:"audio,omitempty"` // recording returned by /api/transcribe
+	// IdempotencyKey is generated by the client and reused on retries so a
+	// resent message is answered once.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type uploadResponse struct {
@@ -39,6 +46,7 @@ type timelineItem struct {
 	Role      string         `json:"role,omitempty"`
 	Content   string         `json:"content,omitempty"`
 	Media     []string       `json:"media,omitempty"`
+	Audio     string         `json:"audio,omitempty"`
 	EventType string         `json:"event_type,omitempty"`
 	Message   string         `json:"message,omitempty"`
 	Detail    map[string]any `json:"detail,omitempty"`
@@ -79,12 +87,49 @@ func (s *Server) handleSendMessage(c *echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
+	id, err := s.submitMessage(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"ok": true, "id": id})
+}
+
+// submitMessage validates a user message and hands it to the channel,
+// returning the message ID. Shared by the HTTP and WebSocket transports.
+func (s *Server) submitMessage(req sendMessageRequest) (string, error) {
 	if req.Content == "" && len(req.Media) == 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "empty message"})
+		return "", fmt.Errorf("empty message")
 	}
 
-	s.channel.HandleIncoming(req.Content, req.Media, nil)
-	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+	var metadata map[string]string
+	if req.Audio != "" {
+		audio := filepath.Clean(req.Audio)
+		if filepath.Dir(audio) != filepath.Clean(s.mediaDir) {
+			return "", fmt.Errorf("invalid audio reference")
+		}
+		if _, err := os.Stat(audio); err != nil {
+			return "", fmt.Errorf("audio not found")
+		}
+		metadata = map[string]string{"audio": audio}
+	}
+	if req.IdempotencyKey != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[bus.MetadataMessageID] = req.IdempotencyKey
+	}
+
+	if s.channel.templates != nil {
+		rendered, ok, err := s.channel.templates.Expand(req.Content)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			req.Content = rendered
+		}
+	}
+
+	return s.channel.HandleIncoming(req.Content, req.Media, metadata), nil
 }
 
 func (s *Server) handleUpload(c *echo.Context) error {
@@ -98,7 +143,9 @@ func (s *Server) handleUpload(c *echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create media directory"})
 	}
 
-	utils.CleanOldMedia(mediaDir, 10*time.Minute)
+	if s.channel.media != nil {
+		go s.channel.media.Prune()
+	}
 
 	safeName := utils.SanitizeFilename(file.Filename)
 	localPath := filepath.Join(mediaDir, safeName)
@@ -142,6 +189,7 @@ func (s *Server) handleMedia(c *echo.Context) error {
 		return echo.ErrNotFound
 	}
 	filePath := filepath.Join(s.mediaDir, name)
+	utils.Touch(filePath)
 	return c.File(filePath)
 }
 
@@ -166,7 +214,14 @@ func (s *Server) handleTranscribe(c *echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create temp file"})
 	}
 	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
+	// The recording is kept on success so the message can reference it in
+	// history; the media retention policy removes it if it is never sent.
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(tmpPath)
+		}
+	}()
 
 	src, err := file.Open()
 	if err != nil {
@@ -188,7 +243,8 @@ func (s *Server) handleTranscribe(c *echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "transcription failed"})
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"text": text})
+	keep = true
+	return c.JSON(http.StatusOK, map[string]string{"text": text, "audio": tmpPath})
 }
 
 func (s *Server) handleHistory(c *echo.Context) error {
@@ -196,8 +252,13 @@ func (s *Server) handleHistory(c *echo.Context) error {
 		return c.JSON(http.StatusOK, historyResponse{Items: []timelineItem{}})
 	}
 
-	timeline := s.channel.sessions.GetTimeline("web:default")
-	summary := s.channel.sessions.GetSummary("web:default")
+	// ?session= lets the command palette open other sessions read-only.
+	key := c.QueryParam("session")
+	if key == "" {
+		key = "web:default"
+	}
+	timeline := s.channel.sessions.GetTimeline(key)
+	summary := s.channel.sessions.GetSummary(key)
 
 	items := make([]timelineItem, 0, len(timeline))
 	for _, entry := range timeline {
@@ -216,6 +277,7 @@ func (s *Server) handleHistory(c *echo.Context) error {
 					Role:      msg.Role,
 					Content:   msg.Content,
 					Media:     entry.Media,
+					Audio:     entry.Audio,
 					Timestamp: entry.Timestamp.Format(time.RFC3339),
 				})
 			}
@@ -237,9 +299,19 @@ func (s *Server) handleHistory(c *echo.Context) error {
 	})
 }
 
+// sseKeepalive is how often a comment is sent on idle SSE connections so
+// intermediate proxies don't drop them.
+const sseKeepalive = 20 * time.Second
+
 func (s *Server) handleSSE(c *echo.Context) error {
+	// EventSource sends Last-Event-ID on automatic reconnects; clients that
+	// reconnect manually can pass it as a query parameter instead.
+	lastID := c.Request().Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.QueryParam("last_event_id")
+	}
 	clientID := utils.RandHex(16)
-	client := s.channel.registerClient(clientID)
+	client, missed, complete := s.channel.registerClient(clientID, lastID)
 
 	w := c.Response()
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -255,29 +327,49 @@ func (s *Server) handleSSE(c *echo.Context) error {
 	if data, err := json.Marshal(statusEvent); err == nil {
 		fmt.Fprintf(w, "data: %s\n\n", data)
 	}
+	if !complete {
+		// Some events were lost; tell the client to reload history.
+		fmt.Fprintf(w, "data: {\"type\":\"resync\"}\n\n")
+	}
+	for _, event := range missed {
+		s.writeSSEEvent(w, event)
+	}
 	rc.Flush()
 
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+
 	ctx := c.Request().Context()
 	for {
 		select {
 		case <-ctx.Done():
 			s.channel.unregisterClient(clientID)
 			return nil
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			rc.Flush()
 		case event, ok := <-client.events:
 			if !ok {
 				return nil
 			}
-			data, err := json.Marshal(event)
-			if err != nil {
-				logger.Error("webchat SSE marshal error: %v", err)
-				continue
-			}
-			fmt.Fprintf(w, "data: %s\n\n", data)
+			s.writeSSEEvent(w, event)
 			rc.Flush()
 		}
 	}
 }
 
+func (s *Server) writeSSEEvent(w io.Writer, event OutgoingEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("webchat SSE marshal error: %v", err)
+		return
+	}
+	if event.ID > 0 {
+		fmt.Fprintf(w, "id: %s\n", s.channel.wireEventID(event.ID))
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
 func (s *Server) handleActive(c *echo.Context) error {
 	var req struct {
 		ClientID string `json:"client_id"`
@@ -290,6 +382,52 @@ func (s *Server) handleActive(c *echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
 }
 
+func (s *Server) handleCancel(c *echo.Context) error {
+	var req struct {
+		SessionKey string `json:"session_key"`
+	}
+	c.Bind(&req)
+	if req.SessionKey == "" {
+		req.SessionKey = "web:default"
+	}
+	if s.channel.canceller == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "cancellation not available"})
+	}
+	if !s.channel.canceller(req.SessionKey) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "nothing is running for this session"})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *Server) handleStorage(c *echo.Context) error {
+	if s.channel.storage == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "storage usage not available"})
+	}
+	return c.JSON(http.StatusOK, s.channel.storage())
+}
+
+func (s *Server) handleLLMCaptureList(c *echo.Context) error {
+	if s.channel.llmCapture == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "LLM capture is disabled (provider.capture)"})
+	}
+	return c.JSON(http.StatusOK, map[string]any{"captures": s.channel.llmCapture.List()})
+}
+
+func (s *Server) handleLLMCaptureGet(c *echo.Context) error {
+	if s.channel.llmCapture == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "LLM capture is disabled (provider.capture)"})
+	}
+	data, err := s.channel.llmCapture.Get(c.Param("name"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, llmcapture.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		return c.JSON(status, map[string]string{"error": err.Error()})
+	}
+	return c.Blob(http.StatusOK, "application/json", data)
+}
+
 func (s *Server) handleVAPIDPublicKey(c *echo.Context) error {
 	if s.pushManager == nil {
 		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "push not available"})
@@ -13,6 +13,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"localagent/pkg/config"
@@ -54,6 +55,8 @@ type ImageJobStore struct {
 	baseDir string
 	queue   chan imageJobEntry
 	done    chan struct{}
+
+	maxBytes atomic.Int64 // 0 = no quota
 }
 
 func NewImageJobStore(baseDir string) *ImageJobStore {
@@ -72,7 +75,57 @@ func (s *ImageJobStore) worker() {
 	defer close(s.done)
 	for entry := range s.queue {
 		s.processJob(entry.job, entry.cfg)
+		s.Prune()
+	}
+}
+
+// SetMaxBytes sets the quota for all stored jobs and applies it right away.
+// n <= 0 disables the quota.
+func (s *ImageJobStore) SetMaxBytes(n int64) {
+	s.maxBytes.Store(max(n, 0))
+	s.Prune()
+}
+
+// Prune deletes the oldest finished jobs until the store fits its quota.
+// Jobs still pending or generating are never removed.
+func (s *ImageJobStore) Prune() {
+	limit := s.maxBytes.Load()
+	if limit <= 0 {
+		return
+	}
+	jobs := s.All() // oldest first
+	sizes := make([]int64, len(jobs))
+	var total int64
+	for i, job := range jobs {
+		sizes[i] = dirSize(s.jobDir(job.ID))
+		total += sizes[i]
+	}
+	for i, job := range jobs {
+		if total <= limit {
+			break
+		}
+		if job.Status == "pending" || job.Status == "generating" {
+			continue
+		}
+		if s.Delete(job.ID) {
+			total -= sizes[i]
+			logger.Info("image jobs over quota, pruned %s (%d bytes)", job.ID, sizes[i])
+		}
+	}
+}
+
+func dirSize(dir string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var n int64
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			n += info.Size()
+		}
 	}
+	return n
 }
 
 func (s *ImageJobStore) Stop() {
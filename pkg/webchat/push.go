@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"localagent/pkg/logger"
+	"localagent/pkg/storage"
 
 	webpush "github.com/SherClockHolmes/webpush-go"
 )
@@ -146,7 +147,7 @@ func (pm *PushManager) loadVAPID() error {
 	if err != nil {
 		return err
 	}
-	return atomicWrite(path, out)
+	return storage.WriteFile(path, out, 0600)
 }
 
 func (pm *PushManager) loadSubscriptions() {
@@ -164,17 +165,5 @@ func (pm *PushManager) saveSubscriptions() error {
 	if err != nil {
 		return err
 	}
-	return atomicWrite(path, data)
-}
-
-func atomicWrite(path string, data []byte) error {
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0600); err != nil {
-		return err
-	}
-	if err := os.Rename(tmp, path); err != nil {
-		os.Remove(tmp)
-		return err
-	}
-	return nil
+	return storage.WriteFile(path, data, 0600)
 }
@@ -28,11 +28,21 @@ type Server struct {
 	todoService *todo.TodoService
 }
 
+// MediaDir is where uploads are kept and served from under /api/media;
+// tools that produce files for the web chat to show write them here too.
+func MediaDir(dataDir string) string {
+	return filepath.Join(dataDir, "webchat", "media")
+}
+
 func NewServer(addr string, channel *WebChatChannel) *Server {
 	e := echo.New()
 	e.Use(middleware.Recover())
 	e.Use(middleware.Secure())
-	e.Use(middleware.BodyLimit(10 * 1024 * 1024))
+	maxUploadMB := channel.config.MaxUploadMB
+	if maxUploadMB <= 0 {
+		maxUploadMB = 10
+	}
+	e.Use(middleware.BodyLimit(int64(maxUploadMB) * 1024 * 1024))
 	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Skipper: func(c *echo.Context) bool {
 			p := c.Request().URL.Path
@@ -51,12 +61,17 @@ func NewServer(addr string, channel *WebChatChannel) *Server {
 		echo:        e,
 		addr:        addr,
 		channel:     channel,
-		mediaDir:    filepath.Join(webchatDir, "media"),
+		mediaDir:    MediaDir(channel.dataDir),
 		imageJobs:   NewImageJobStore(filepath.Join(webchatDir, "images")),
 		pushManager: pm,
 		todoService: channel.todoService,
 	}
 
+	if channel.media != nil {
+		channel.media.AddDir(s.mediaDir)
+	}
+	s.imageJobs.SetMaxBytes(channel.imageQuota)
+
 	s.setupRoutes()
 	return s
 }
@@ -69,12 +84,16 @@ func (s *Server) setupRoutes() {
 	s.echo.POST("/api/messages", s.handleSendMessage)
 	s.echo.POST("/api/upload", s.handleUpload)
 	s.echo.GET("/api/history", s.handleHistory)
+	s.echo.GET("/api/export", s.handleExport)
+	s.echo.POST("/api/sessions/fork", s.handleFork)
 	s.echo.GET("/api/events", s.handleSSE)
+	s.echo.GET("/api/ws", s.handleWebSocket)
 	s.echo.GET("/api/media/:filename", s.handleMedia)
 	s.echo.POST("/api/transcribe", s.handleTranscribe)
 	s.echo.GET("/api/voice", s.handleVoice)
 	s.echo.POST("/api/tts", s.handleTTS)
 	s.echo.POST("/api/active", s.handleActive)
+	s.echo.POST("/api/cancel", s.handleCancel)
 
 	s.echo.GET("/api/image/models", s.handleImageModels)
 	s.echo.POST("/api/image/unload", s.handleImageUnload)
@@ -110,6 +129,22 @@ func (s *Server) setupRoutes() {
 	s.echo.PUT("/api/links/:id", s.handleLinkUpdate)
 	s.echo.DELETE("/api/links/:id", s.handleLinkDelete)
 
+	s.echo.GET("/api/templates", s.handleTemplateList)
+	s.echo.GET("/api/templates/:name", s.handleTemplateGet)
+	s.echo.PUT("/api/templates/:name", s.handleTemplateSave)
+	s.echo.DELETE("/api/templates/:name", s.handleTemplateDelete)
+
+	s.echo.GET("/api/storage", s.handleStorage)
+	s.echo.GET("/api/focus", s.handleFocus)
+	s.echo.POST("/api/focus", s.handleFocus)
+	s.echo.DELETE("/api/focus", s.handleFocus)
+
+	s.echo.GET("/api/debug/llm", s.handleLLMCaptureList)
+	s.echo.GET("/api/debug/llm/:name", s.handleLLMCaptureGet)
+
+	s.echo.GET("/api/commands", s.handleCommandList)
+	s.echo.POST("/api/commands/invoke", s.handleCommandInvoke)
+
 	s.echo.GET("/*", s.handleSPA)
 }
 
@@ -0,0 +1,77 @@
+package webchat
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+
+	"localagent/pkg/templates"
+)
+
+type templateResponse struct {
+	templates.Template
+	Placeholders []templates.Placeholder `json:"placeholders"`
+}
+
+func newTemplateResponse(t templates.Template) templateResponse {
+	p := t.Placeholders()
+	if p == nil {
+		p = []templates.Placeholder{}
+	}
+	return templateResponse{Template: t, Placeholders: p}
+}
+
+func (s *Server) handleTemplateList(c *echo.Context) error {
+	store := s.channel.templates
+	if store == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "templates not available"})
+	}
+	list := store.List()
+	out := make([]templateResponse, len(list))
+	for i, t := range list {
+		out[i] = newTemplateResponse(t)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"templates": out})
+}
+
+func (s *Server) handleTemplateGet(c *echo.Context) error {
+	store := s.channel.templates
+	if store == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "templates not available"})
+	}
+	t, err := store.Get(c.Param("name"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, newTemplateResponse(t))
+}
+
+func (s *Server) handleTemplateSave(c *echo.Context) error {
+	store := s.channel.templates
+	if store == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "templates not available"})
+	}
+	var req struct {
+		Description string `json:"description"`
+		Body        string `json:"body"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	t := templates.Template{Name: c.Param("name"), Description: req.Description, Body: req.Body}
+	if err := store.Save(t); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, newTemplateResponse(t))
+}
+
+func (s *Server) handleTemplateDelete(c *echo.Context) error {
+	store := s.channel.templates
+	if store == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "templates not available"})
+	}
+	if err := store.Delete(c.Param("name")); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
+}
@@ -186,7 +186,12 @@ func (vs *voiceSession) handleAudio(ctx context.Context, b64Audio string) {
 		return
 	}
 	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
+	keepAudio := false
+	defer func() {
+		if !keepAudio {
+			os.Remove(tmpPath)
+		}
+	}()
 
 	if _, err := tmpFile.Write(wavData); err != nil {
 		tmpFile.Close()
@@ -227,7 +232,8 @@ func (vs *voiceSession) handleAudio(ctx context.Context, b64Audio string) {
 	vs.channel.setVoiceResponseCh(responseCh)
 	defer vs.channel.setVoiceResponseCh(nil)
 
-	vs.channel.HandleIncoming(text, nil, nil)
+	keepAudio = true
+	vs.channel.HandleIncoming(text, nil, map[string]string{"audio": tmpPath})
 
 	// Wait for response with timeout
 	var response string
@@ -0,0 +1,150 @@
+package webchat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"localagent/pkg/logger"
+	"localagent/pkg/utils"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v5"
+)
+
+// wsInbound is a client->server WebSocket frame.
+type wsInbound struct {
+	Type string `json:"type"` // "message", "active" or "cancel"
+	sendMessageRequest
+	Active bool `json:"active,omitempty"`
+}
+
+// wsOutbound wraps an OutgoingEvent with its replay id, which SSE carries
+// in the "id:" field instead.
+type wsOutbound struct {
+	ID string `json:"id,omitempty"`
+	OutgoingEvent
+}
+
+// chatUpgrader only accepts same-origin browsers: the socket carries the
+// whole conversation and takes messages for an agent with exec tools, so
+// another site open in the user's browser must not connect to it.
+var chatUpgrader = websocket.Upgrader{
+	CheckOrigin:     sameOrigin,
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// sameOrigin reports whether the request's Origin, if any, names the host
+// it was sent to. Clients that aren't browsers send no Origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// handleWebSocket is an alternative to SSE + POST /api/messages for clients
+// behind proxies that buffer or kill event streams. It carries the same
+// event stream and accepts inbound messages on the same connection.
+func (s *Server) handleWebSocket(c *echo.Context) error {
+	lastEventID := c.QueryParam("last_event_id")
+
+	conn, err := chatUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return fmt.Errorf("websocket upgrade: %w", err)
+	}
+	defer conn.Close()
+	conn.SetReadLimit(1024 * 1024)
+
+	clientID := utils.RandHex(16)
+	client, missed, complete := s.channel.registerClient(clientID, lastEventID)
+	defer s.channel.unregisterClient(clientID)
+
+	var writeMu sync.Mutex
+	write := func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	processing := s.channel.processing.Load()
+	write(OutgoingEvent{Type: "status", Processing: &processing, ClientID: clientID})
+	if !complete {
+		write(OutgoingEvent{Type: "resync"})
+	}
+	for _, event := range missed {
+		write(wsOutbound{ID: s.channel.wireEventID(event.ID), OutgoingEvent: event})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					logger.Debug("webchat ws read: %v", err)
+				}
+				return
+			}
+			var msg wsInbound
+			if err := json.Unmarshal(data, &msg); err != nil {
+				write(OutgoingEvent{Type: "error", Content: "invalid message"})
+				continue
+			}
+			switch msg.Type {
+			case "message":
+				id, err := s.submitMessage(msg.sendMessageRequest)
+				if err != nil {
+					write(OutgoingEvent{Type: "error", Content: err.Error()})
+					continue
+				}
+				write(OutgoingEvent{Type: "ack", MessageID: id})
+			case "active":
+				s.channel.setClientActive(clientID, msg.Active)
+			case "cancel":
+				if s.channel.canceller != nil {
+					s.channel.canceller(fmt.Sprintf("%s:default", s.channel.Name()))
+				}
+			default:
+				write(OutgoingEvent{Type: "error", Content: "unknown message type: " + msg.Type})
+			}
+		}
+	}()
+
+	ping := time.NewTicker(sseKeepalive)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ping.C:
+			writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second))
+			writeMu.Unlock()
+			if err != nil {
+				return nil
+			}
+		case event, ok := <-client.events:
+			if !ok {
+				return nil
+			}
+			if err := write(wsOutbound{ID: s.channel.wireEventID(event.ID), OutgoingEvent: event}); err != nil {
+				return nil
+			}
+		}
+	}
+}
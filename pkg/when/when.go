@@ -0,0 +1,506 @@
+// Package when resolves natural-language date and time expressions such as
+// "next Tuesday at 3", "tomorrow 9am" or "in 2 hours" against a reference
+// time, so tools don't depend on the LLM producing exact ISO strings.
+package when
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	locMu sync.RWMutex
+	loc   = time.Local
+)
+
+// SetLocation sets the timezone used to interpret expressions.
+// A nil location resets it to the system local time.
+func SetLocation(l *time.Location) {
+	locMu.Lock()
+	defer locMu.Unlock()
+	if l == nil {
+		l = time.Local
+	}
+	loc = l
+}
+
+// Location returns the configured timezone.
+func Location() *time.Location {
+	locMu.RLock()
+	defer locMu.RUnlock()
+	return loc
+}
+
+// Now returns the current time in the configured timezone.
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// Result is a resolved expression. DateOnly is set when the expression
+// named a day but no time of day; Time is then midnight of that day.
+type Result struct {
+	Time     time.Time
+	DateOnly bool
+}
+
+// Format renders the result as YYYY-MM-DD for dates and RFC 3339 otherwise.
+func (r Result) Format() string {
+	if r.DateOnly {
+		return r.Time.Format("2006-01-02")
+	}
+	return r.Time.Format(time.RFC3339)
+}
+
+// Resolve parses expr relative to the current time in the configured timezone.
+func Resolve(expr string) (Result, error) {
+	return Parse(expr, Now())
+}
+
+var isoLayouts = []struct {
+	layout   string
+	dateOnly bool
+}{
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02 15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02 15:04", false},
+	{"2006-01-02", true},
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var dayParts = map[string]int{
+	"morning":   9,
+	"noon":      12,
+	"midday":    12,
+	"afternoon": 15,
+	"evening":   19,
+	"tonight":   20,
+	"night":     21,
+	"midnight":  0,
+}
+
+var fillers = map[string]bool{"at": true, "on": true, "the": true, "of": true, "by": true, "o'clock": true}
+
+// Parse resolves expr relative to ref, in ref's location. ISO 8601 inputs
+// are accepted unchanged. Supported forms include "today", "tomorrow",
+// "day after tomorrow", weekday names with optional "this"/"next"/"last",
+// "next week|month", "March 5", "5 March 2026", "in 2 hours", "3 days ago",
+// and times like "3pm", "15:30", "noon" or "at 3". A bare hour from 1 to 7
+// without am/pm is read as afternoon ("at 3" is 15:00).
+func Parse(expr string, ref time.Time) (Result, error) {
+	s := strings.TrimSpace(expr)
+	if s == "" {
+		return Result{}, fmt.Errorf("empty date expression")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return Result{Time: t}, nil
+	}
+	for _, l := range isoLayouts {
+		if t, err := time.ParseInLocation(l.layout, s, ref.Location()); err == nil {
+			return Result{Time: t, DateOnly: l.dateOnly}, nil
+		}
+	}
+
+	p := &parser{ref: ref, day: ref, hour: -1}
+	if err := p.run(tokenize(s)); err != nil {
+		return Result{}, fmt.Errorf("cannot understand %q: %w", expr, err)
+	}
+	return p.result(), nil
+}
+
+func tokenize(s string) []string {
+	s = strings.ToLower(s)
+	r := strings.NewReplacer(",", " ", "a.m.", "am", "p.m.", "pm")
+	return strings.Fields(r.Replace(s))
+}
+
+type parser struct {
+	ref     time.Time
+	day     time.Time // date component (time of day ignored unless exact)
+	hour    int       // -1 when no time of day was given
+	minute  int
+	exact   bool // day holds an exact instant ("now", "in 2 hours")
+	daySet  bool
+	timeSet bool
+}
+
+func (p *parser) run(toks []string) error {
+	for i := 0; i < len(toks); {
+		n, err := p.step(toks, i)
+		if err != nil {
+			return err
+		}
+		i += n
+	}
+	if !p.daySet && !p.timeSet && !p.exact {
+		return fmt.Errorf("no date or time found")
+	}
+	return nil
+}
+
+// step consumes one construct starting at toks[i] and returns how many
+// tokens it used.
+func (p *parser) step(toks []string, i int) (int, error) {
+	tok := toks[i]
+	next := func(k int) string {
+		if i+k < len(toks) {
+			return toks[i+k]
+		}
+		return ""
+	}
+
+	if fillers[tok] {
+		return 1, nil
+	}
+
+	switch tok {
+	case "now":
+		p.day, p.exact = p.ref, true
+		return 1, nil
+	case "today":
+		return 1, p.setDay(p.ref)
+	case "tomorrow":
+		return 1, p.setDay(p.ref.AddDate(0, 0, 1))
+	case "yesterday":
+		return 1, p.setDay(p.ref.AddDate(0, 0, -1))
+	case "day":
+		if next(1) == "after" && next(2) == "tomorrow" {
+			return 3, p.setDay(p.ref.AddDate(0, 0, 2))
+		}
+	case "tonight":
+		if err := p.setDay(p.ref); err != nil {
+			return 0, err
+		}
+		if !p.timeSet {
+			p.setTime(dayParts["tonight"], 0)
+		}
+		return 1, nil
+	case "in":
+		if n, d, ok := parseAmount(toks[i+1:]); ok {
+			p.shift(d, 1)
+			return 1 + n, nil
+		}
+	case "this", "next", "coming", "last":
+		if wd, ok := weekdays[next(1)]; ok {
+			return 2, p.setDay(weekdayFrom(p.ref, wd, tok))
+		}
+		sign := 1
+		if tok == "last" {
+			sign = -1
+		}
+		switch next(1) {
+		case "week":
+			if tok == "this" {
+				return 2, p.setDay(p.ref)
+			}
+			// Monday of the next (or previous) week.
+			offset := (int(time.Monday) - int(p.ref.Weekday()) - 7) % 7
+			return 2, p.setDay(p.ref.AddDate(0, 0, offset+7*sign))
+		case "month":
+			if tok == "this" {
+				return 2, p.setDay(p.ref)
+			}
+			return 2, p.setDay(time.Date(p.ref.Year(), p.ref.Month()+time.Month(sign), 1, 0, 0, 0, 0, p.ref.Location()))
+		case "year":
+			if tok == "this" {
+				return 2, p.setDay(p.ref)
+			}
+			return 2, p.setDay(time.Date(p.ref.Year()+sign, time.January, 1, 0, 0, 0, 0, p.ref.Location()))
+		}
+		if h, ok := dayParts[next(1)]; ok && tok == "this" {
+			p.setTime(h, 0)
+			return 2, p.setDay(p.ref)
+		}
+	}
+
+	if wd, ok := weekdays[tok]; ok {
+		return 1, p.setDay(weekdayFrom(p.ref, wd, ""))
+	}
+	if h, ok := dayParts[tok]; ok {
+		p.setTime(h, 0)
+		return 1, nil
+	}
+
+	// "3 days ago", "2 hours from now", "10 minutes later"
+	if n, d, ok := parseAmount(toks[i:]); ok {
+		switch {
+		case next(n) == "ago":
+			p.shift(d, -1)
+			return n + 1, nil
+		case next(n) == "from" && next(n+1) == "now":
+			p.shift(d, 1)
+			return n + 2, nil
+		case next(n) == "later":
+			p.shift(d, 1)
+			return n + 1, nil
+		}
+	}
+
+	// "March 5", "March 5th 2026"
+	if m, ok := months[tok]; ok {
+		day, err := parseOrdinal(next(1))
+		if err != nil {
+			return 0, fmt.Errorf("expected a day after %q", tok)
+		}
+		used := 2
+		year, hasYear := parseYear(next(2))
+		if hasYear {
+			used++
+		}
+		return used, p.setMonthDay(m, day, year, hasYear)
+	}
+	// "5 March", "5th of March 2026"
+	if day, err := parseOrdinal(tok); err == nil {
+		j := 1
+		if next(j) == "of" {
+			j++
+		}
+		if m, ok := months[next(j)]; ok {
+			year, hasYear := parseYear(next(j + 1))
+			used := j + 1
+			if hasYear {
+				used++
+			}
+			return used, p.setMonthDay(m, day, year, hasYear)
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", tok, p.ref.Location()); err == nil {
+		return 1, p.setDay(t)
+	}
+
+	// Times: "3pm", "3:30", "15:30", "3 pm", or a bare hour after "at".
+	explicit := i > 0 && toks[i-1] == "at"
+	if h, m, used, ok := parseClock(tok, next(1), explicit); ok {
+		p.setTime(h, m)
+		return used, nil
+	}
+
+	return 0, fmt.Errorf("unrecognized word %q", tok)
+}
+
+func (p *parser) setDay(t time.Time) error {
+	if p.daySet {
+		return fmt.Errorf("more than one date given")
+	}
+	p.day = t
+	p.daySet = true
+	return nil
+}
+
+func (p *parser) setTime(h, m int) {
+	p.hour, p.minute = h, m
+	p.timeSet = true
+}
+
+func (p *parser) setMonthDay(m time.Month, day, year int, hasYear bool) error {
+	if day < 1 || day > 31 {
+		return fmt.Errorf("invalid day %d", day)
+	}
+	if !hasYear {
+		year = p.ref.Year()
+	}
+	t := time.Date(year, m, day, 0, 0, 0, 0, p.ref.Location())
+	if t.Month() != m {
+		return fmt.Errorf("invalid date %s %d", m, day)
+	}
+	// Without a year, a date already past this year means next year.
+	if !hasYear && t.Before(truncateDay(p.ref)) {
+		t = t.AddDate(1, 0, 0)
+	}
+	return p.setDay(t)
+}
+
+// shift moves the reference by d (sign applied). Sub-day amounts yield an
+// exact instant; day-based amounts only set the date.
+func (p *parser) shift(d amount, sign int) {
+	if d.dur > 0 {
+		p.day = p.ref.Add(time.Duration(sign) * d.dur)
+		p.exact = true
+		return
+	}
+	p.day = p.ref.AddDate(sign*d.years, sign*d.months, sign*d.days)
+	p.daySet = true
+}
+
+func (p *parser) result() Result {
+	if p.timeSet {
+		t := time.Date(p.day.Year(), p.day.Month(), p.day.Day(), p.hour, p.minute, 0, 0, p.ref.Location())
+		// A bare time that has already passed today means tomorrow.
+		if !p.daySet && !p.exact && t.Before(p.ref) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return Result{Time: t}
+	}
+	if p.exact {
+		return Result{Time: p.day.Truncate(time.Minute)}
+	}
+	return Result{Time: truncateDay(p.day), DateOnly: true}
+}
+
+type amount struct {
+	dur                 time.Duration
+	years, months, days int
+}
+
+// parseAmount reads "<n> <unit>", "a/an <unit>" or "half an hour" and
+// returns the number of tokens consumed.
+func parseAmount(toks []string) (int, amount, bool) {
+	if len(toks) >= 3 && toks[0] == "half" && (toks[1] == "an" || toks[1] == "a") && strings.HasPrefix(toks[2], "hour") {
+		return 3, amount{dur: 30 * time.Minute}, true
+	}
+	if len(toks) < 2 {
+		return 0, amount{}, false
+	}
+	var n int
+	switch toks[0] {
+	case "a", "an", "one":
+		n = 1
+	default:
+		v, err := strconv.Atoi(toks[0])
+		if err != nil || v < 0 {
+			return 0, amount{}, false
+		}
+		n = v
+	}
+	unit := strings.TrimSuffix(toks[1], "s")
+	switch unit {
+	case "min", "minute":
+		return 2, amount{dur: time.Duration(n) * time.Minute}, true
+	case "hr", "hour":
+		return 2, amount{dur: time.Duration(n) * time.Hour}, true
+	case "day":
+		return 2, amount{days: n}, true
+	case "week":
+		return 2, amount{days: 7 * n}, true
+	case "month":
+		return 2, amount{months: n}, true
+	case "year":
+		return 2, amount{years: n}, true
+	}
+	return 0, amount{}, false
+}
+
+// weekdayFrom returns the date of wd relative to ref. "next" is the first
+// occurrence strictly after today, "last" strictly before; plain names and
+// "this" include today.
+func weekdayFrom(ref time.Time, wd time.Weekday, qualifier string) time.Time {
+	diff := (int(wd) - int(ref.Weekday()) + 7) % 7
+	switch qualifier {
+	case "next", "coming":
+		if diff == 0 {
+			diff = 7
+		}
+	case "last":
+		diff = -((int(ref.Weekday()) - int(wd) + 7) % 7)
+		if diff == 0 {
+			diff = -7
+		}
+	}
+	return ref.AddDate(0, 0, diff)
+}
+
+func parseOrdinal(s string) (int, error) {
+	for _, suf := range []string{"st", "nd", "rd", "th"} {
+		s = strings.TrimSuffix(s, suf)
+	}
+	return strconv.Atoi(s)
+}
+
+func parseYear(s string) (int, bool) {
+	if len(s) != 4 {
+		return 0, false
+	}
+	y, err := strconv.Atoi(s)
+	return y, err == nil
+}
+
+// parseClock reads a time of day from tok, optionally followed by an
+// "am"/"pm" token. Bare numbers are only accepted when explicit.
+func parseClock(tok, next string, explicit bool) (hour, minute, used int, ok bool) {
+	used = 1
+	suffix := ""
+	switch {
+	case strings.HasSuffix(tok, "am"), strings.HasSuffix(tok, "pm"):
+		suffix = tok[len(tok)-2:]
+		tok = tok[:len(tok)-2]
+	case next == "am" || next == "pm":
+		suffix = next
+		used = 2
+	}
+	if tok == "" {
+		return 0, 0, 0, false
+	}
+
+	hasColon := strings.Contains(tok, ":")
+	if !hasColon && suffix == "" && !explicit {
+		return 0, 0, 0, false
+	}
+	hs, ms, _ := strings.Cut(tok, ":")
+	h, err := strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	m := 0
+	if hasColon {
+		if m, err = strconv.Atoi(ms); err != nil || m < 0 || m > 59 {
+			return 0, 0, 0, false
+		}
+	}
+
+	switch suffix {
+	case "am":
+		if h < 1 || h > 12 {
+			return 0, 0, 0, false
+		}
+		if h == 12 {
+			h = 0
+		}
+	case "pm":
+		if h < 1 || h > 12 {
+			return 0, 0, 0, false
+		}
+		if h != 12 {
+			h += 12
+		}
+	default:
+		if h < 0 || h > 23 {
+			return 0, 0, 0, false
+		}
+		if !hasColon && h >= 1 && h <= 7 {
+			h += 12
+		}
+	}
+	return h, m, used, true
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
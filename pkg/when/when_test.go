@@ -0,0 +1,78 @@
+package when
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// Wednesday 2025-01-15 10:30
+	ref := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	date := func(y int, m time.Month, d int) Result {
+		return Result{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), DateOnly: true}
+	}
+	at := func(y int, m time.Month, d, h, min int) Result {
+		return Result{Time: time.Date(y, m, d, h, min, 0, 0, time.UTC)}
+	}
+
+	cases := map[string]Result{
+		"2025-02-01":                 date(2025, 2, 1),
+		"2025-02-01T09:00":           at(2025, 2, 1, 9, 0),
+		"2025-02-01T09:00:00Z":       at(2025, 2, 1, 9, 0),
+		"today":                      date(2025, 1, 15),
+		"tomorrow":                   date(2025, 1, 16),
+		"day after tomorrow":         date(2025, 1, 17),
+		"tomorrow 9am":               at(2025, 1, 16, 9, 0),
+		"at 3pm tomorrow":            at(2025, 1, 16, 15, 0),
+		"next Tuesday at 3":          at(2025, 1, 21, 15, 0),
+		"friday":                     date(2025, 1, 17),
+		"wednesday":                  date(2025, 1, 15),
+		"next wednesday":             date(2025, 1, 22),
+		"last monday":                date(2025, 1, 13),
+		"next week":                  date(2025, 1, 20),
+		"next month":                 date(2025, 2, 1),
+		"March 5":                    date(2025, 3, 5),
+		"5th of March 2026 at 14:15": at(2026, 3, 5, 14, 15),
+		"Jan 2":                      date(2026, 1, 2),
+		"in 2 hours":                 at(2025, 1, 15, 12, 30),
+		"in half an hour":            at(2025, 1, 15, 11, 0),
+		"in 3 days":                  date(2025, 1, 18),
+		"2 weeks ago":                date(2025, 1, 1),
+		"tonight":                    at(2025, 1, 15, 20, 0),
+		"this afternoon":             at(2025, 1, 15, 15, 0),
+		"tomorrow morning":           at(2025, 1, 16, 9, 0),
+		"noon":                       at(2025, 1, 15, 12, 0),
+		"9am":                        at(2025, 1, 16, 9, 0), // already past today
+		"12am":                       at(2025, 1, 16, 0, 0),
+	}
+	for expr, want := range cases {
+		got, err := Parse(expr, ref)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", expr, err)
+			continue
+		}
+		if !got.Time.Equal(want.Time) || got.DateOnly != want.DateOnly {
+			t.Errorf("Parse(%q) = %v (date only %v), want %v (date only %v)", expr, got.Time, got.DateOnly, want.Time, want.DateOnly)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	ref := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	for _, expr := range []string{"", "soonish", "tomorrow yesterday", "February 30", "at 25"} {
+		if got, err := Parse(expr, ref); err == nil {
+			t.Errorf("Parse(%q) = %v, expected error", expr, got.Time)
+		}
+	}
+}
+
+func TestResultFormat(t *testing.T) {
+	r := Result{Time: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), DateOnly: true}
+	if r.Format() != "2025-01-15" {
+		t.Errorf("unexpected date format %q", r.Format())
+	}
+	r.DateOnly = false
+	if r.Format() != "2025-01-15T00:00:00Z" {
+		t.Errorf("unexpected datetime format %q", r.Format())
+	}
+}
@@ -0,0 +1,127 @@
+// Package worldclock tells the local time at the user's important places
+// (config places) and other cities, relative to the agent's timezone.
+package worldclock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"localagent/pkg/config"
+	"localagent/pkg/logger"
+)
+
+// regions are the IANA areas tried when looking up a bare city name.
+var regions = []string{"Europe", "America", "Asia", "Africa", "Australia", "Pacific", "Atlantic", "Indian",
+	"America/Argentina", "America/Indiana"}
+
+// Place is a named location with its timezone and reachable hours.
+type Place struct {
+	Name       string
+	Loc        *time.Location
+	start, end time.Duration // reachable hours, from local midnight
+}
+
+// Places turns the configured places into Places, skipping invalid ones.
+func Places(cfgs []config.PlaceConfig) []Place {
+	var places []Place
+	for _, c := range cfgs {
+		if err := c.Validate(); err != nil {
+			logger.Warn("places: %v", err)
+			continue
+		}
+		loc, _ := time.LoadLocation(c.Timezone)
+		start, end, _ := c.Hours()
+		places = append(places, Place{Name: c.Name, Loc: loc, start: start, end: end})
+	}
+	return places
+}
+
+// Lookup finds a configured place by name, or else a timezone by IANA name
+// or city ("Tokyo", "new york"). Places found by timezone have the default
+// reachable hours.
+func Lookup(places []Place, name string) (Place, error) {
+	name = strings.TrimSpace(name)
+	for _, p := range places {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	start, end, _ := config.PlaceConfig{}.Hours()
+	if loc, err := time.LoadLocation(name); err == nil && name != "" && name != "Local" {
+		return Place{Name: name, Loc: loc, start: start, end: end}, nil
+	}
+	words := strings.Fields(strings.ToLower(name))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	city := strings.Join(words, "_")
+	if city != "" {
+		for _, r := range regions {
+			if loc, err := time.LoadLocation(r + "/" + city); err == nil {
+				return Place{Name: strings.Join(words, " "), Loc: loc, start: start, end: end}, nil
+			}
+		}
+	}
+	return Place{}, fmt.Errorf("unknown place or timezone %q; use a configured place, a city or an IANA name like Asia/Tokyo", name)
+}
+
+// Reachable reports whether t falls in the place's reachable hours.
+func (p Place) Reachable(t time.Time) bool {
+	t = t.In(p.Loc)
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if p.start <= p.end {
+		return since >= p.start && since < p.end
+	}
+	return since >= p.start || since < p.end // overnight hours
+}
+
+// Hours formats the reachable hours, e.g. "08:00-21:00".
+func (p Place) Hours() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(p.start) + "-" + clock(p.end)
+}
+
+// Describe gives the place's local time at t with its offset from home,
+// e.g. "Mom (America/Toronto): Tue 08:05, 6h behind you".
+func (p Place) Describe(t time.Time, home *time.Location) string {
+	local := t.In(p.Loc)
+	s := fmt.Sprintf("%s (%s): %s, %s", p.Name, p.Loc, local.Format("Mon 15:04"), offset(t, p.Loc, home))
+	if !p.Reachable(t) {
+		s += ", outside " + p.Hours()
+	}
+	return s
+}
+
+// Note lists every place's local time at now, one per line, for the
+// system prompt; "" without places.
+func Note(places []Place, now time.Time, home *time.Location) string {
+	lines := make([]string, len(places))
+	for i, p := range places {
+		lines[i] = "- " + p.Describe(now, home)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// offset says how far loc is ahead of or behind home at t.
+func offset(t time.Time, loc, home *time.Location) string {
+	_, there := t.In(loc).Zone()
+	_, here := t.In(home).Zone()
+	d := time.Duration(there-here) * time.Second
+	switch {
+	case d == 0:
+		return "same time as you"
+	case d > 0:
+		return short(d) + " ahead of you"
+	}
+	return short(-d) + " behind you"
+}
+
+func short(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
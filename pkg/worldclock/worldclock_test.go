@@ -0,0 +1,64 @@
+package worldclock
+
+import (
+	"testing"
+	"time"
+
+	"localagent/pkg/config"
+)
+
+func TestDescribe(t *testing.T) {
+	places := Places([]config.PlaceConfig{
+		{Name: "Mom", Timezone: "America/Toronto"},
+		{Name: "Bangalore team", Timezone: "Asia/Kolkata", Start: "09:30", End: "18:00"},
+		{Name: "Broken", Timezone: "Mars/Olympus"},
+	})
+	if len(places) != 2 {
+		t.Fatalf("places = %+v", places)
+	}
+	home, _ := time.LoadLocation("Europe/Zurich")
+	at := time.Date(2026, 3, 3, 22, 30, 0, 0, home) // a Tuesday, winter time
+
+	want := []string{
+		"Mom (America/Toronto): Tue 16:30, 6h behind you",
+		"Bangalore team (Asia/Kolkata): Wed 03:00, 4h30m ahead of you, outside 09:30-18:00",
+	}
+	for i, p := range places {
+		if got := p.Describe(at, home); got != want[i] {
+			t.Errorf("Describe = %q, want %q", got, want[i])
+		}
+	}
+	if got := Note(nil, at, home); got != "" {
+		t.Errorf("Note without places = %q", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	places := Places([]config.PlaceConfig{{Name: "Mom", Timezone: "America/Toronto"}})
+	for name, zone := range map[string]string{
+		"mom":        "America/Toronto",
+		"Asia/Tokyo": "Asia/Tokyo",
+		"new york":   "America/New_York",
+		"Sao Paulo":  "America/Sao_Paulo",
+		"UTC":        "UTC",
+	} {
+		p, err := Lookup(places, name)
+		if err != nil || p.Loc.String() != zone {
+			t.Errorf("Lookup(%q) = %v, %v; want %s", name, p.Loc, err, zone)
+		}
+	}
+	for _, name := range []string{"", "Atlantis", "Local"} {
+		if _, err := Lookup(places, name); err == nil {
+			t.Errorf("Lookup(%q) succeeded", name)
+		}
+	}
+}
+
+func TestReachableOvernight(t *testing.T) {
+	p := Places([]config.PlaceConfig{{Name: "Night shift", Timezone: "UTC", Start: "20:00", End: "04:00"}})[0]
+	for hour, want := range map[int]bool{19: false, 20: true, 23: true, 3: true, 4: false, 12: false} {
+		if got := p.Reachable(time.Date(2026, 1, 1, hour, 0, 0, 0, time.UTC)); got != want {
+			t.Errorf("Reachable at %d:00 = %v", hour, got)
+		}
+	}
+}
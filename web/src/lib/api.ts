@@ -258,16 +258,32 @@ function mockSSE(
 
 // --- Real API ---
 
+// sendMessage retries once on a network error. Both attempts carry the same
+// idempotency key, so the server answers the message only once even if the
+// first request did arrive.
 export async function sendMessage(
   content: string,
   media: string[],
+  audio?: string,
 ): Promise<void> {
   if (DEV) return;
-  await fetch("/api/messages", {
-    method: "POST",
-    headers: { "Content-Type": "application/json" },
-    body: JSON.stringify({ content, media }),
+  const body = JSON.stringify({
+    content,
+    media,
+    audio,
+    idempotency_key: `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`,
   });
+  const post = () =>
+    fetch("/api/messages", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body,
+    });
+  try {
+    await post();
+  } catch {
+    await post();
+  }
 }
 
 export async function uploadFile(
@@ -287,8 +303,12 @@ export async function uploadFile(
   }
 }
 
-export async function transcribeAudio(file: File): Promise<string | null> {
-  if (DEV) return "mock transcription";
+// transcribeAudio returns the text and the server path of the saved
+// recording, which sendMessage attaches so history can play it back.
+export async function transcribeAudio(
+  file: File,
+): Promise<{ text: string; audio: string } | null> {
+  if (DEV) return { text: "mock transcription", audio: "" };
   const form = new FormData();
   form.append("file", file);
   try {
@@ -298,15 +318,17 @@ export async function transcribeAudio(file: File): Promise<string | null> {
     });
     if (!res.ok) return null;
     const data = await res.json();
-    return data.text || null;
+    if (!data.text) return null;
+    return { text: data.text, audio: data.audio ?? "" };
   } catch {
     return null;
   }
 }
 
-export async function getHistory(): Promise<HistoryResponse> {
+export async function getHistory(session?: string): Promise<HistoryResponse> {
   if (DEV) return { items: [] };
-  const res = await fetch("/api/history");
+  const qs = session ? `?session=${encodeURIComponent(session)}` : "";
+  const res = await fetch(`/api/history${qs}`);
   if (!res.ok) return { items: [] };
   return res.json();
 }
@@ -702,6 +724,62 @@ export async function deleteLink(id: string): Promise<boolean> {
   }
 }
 
+// --- Command palette API ---
+
+export interface CommandParam {
+  name: string;
+  default?: string;
+  required?: boolean;
+}
+
+export interface PaletteCommand {
+  id: string;
+  kind: "template" | "cron" | "tool" | "session";
+  title: string;
+  description?: string;
+  keywords?: string[];
+  params?: CommandParam[];
+}
+
+export interface InvokeCommandResult {
+  ok: boolean;
+  action: "message" | "cron" | "session";
+  message_id?: string;
+  session?: string;
+  error?: string;
+}
+
+export async function getCommands(): Promise<PaletteCommand[]> {
+  if (DEV) return [];
+  try {
+    const res = await fetch("/api/commands");
+    if (!res.ok) return [];
+    const data = await res.json();
+    return data.commands || [];
+  } catch {
+    return [];
+  }
+}
+
+export async function invokeCommand(
+  id: string,
+  args: Record<string, string> = {},
+): Promise<InvokeCommandResult> {
+  if (DEV) return { ok: false, action: "message", error: "dev mode" };
+  try {
+    const res = await fetch("/api/commands/invoke", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ id, args }),
+    });
+    const data = await res.json();
+    if (!res.ok) return { ok: false, action: "message", error: data.error };
+    return data;
+  } catch {
+    return { ok: false, action: "message", error: "network error" };
+  }
+}
+
 // --- Image API ---
 
 export interface ImageJob {
@@ -909,6 +987,54 @@ export async function deleteImageJob(id: string): Promise<boolean> {
   }
 }
 
+// --- Focus mode ---
+
+export interface FocusStatus {
+  active: boolean;
+  started?: string;
+  until?: string;
+  reason?: string;
+}
+
+export async function getFocus(): Promise<FocusStatus> {
+  if (DEV) return { active: false };
+  try {
+    const res = await fetch("/api/focus");
+    if (!res.ok) return { active: false };
+    return await res.json();
+  } catch {
+    return { active: false };
+  }
+}
+
+export async function startFocus(
+  minutes: number,
+  reason?: string,
+): Promise<FocusStatus | null> {
+  if (DEV) return null;
+  try {
+    const res = await fetch("/api/focus", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ minutes, reason }),
+    });
+    if (!res.ok) return null;
+    return await res.json();
+  } catch {
+    return null;
+  }
+}
+
+export async function endFocus(): Promise<boolean> {
+  if (DEV) return false;
+  try {
+    const res = await fetch("/api/focus", { method: "DELETE" });
+    return res.ok;
+  } catch {
+    return false;
+  }
+}
+
 export function connectSSE(
   onMessage: (msg: HistoryMessage) => void,
   onActivity: (evt: ActivityEventData) => void,
@@ -918,6 +1044,8 @@ export function connectSSE(
   onTask?: (action: string, task: Task) => void,
   onBlock?: (action: string, block: Block) => void,
   onLink?: (action: string, link: Link) => void,
+  onFocus?: (status: FocusStatus) => void,
+  onDelta?: (text: string) => void,
 ): EventSource {
   if (DEV) return mockSSE(onMessage, onActivity);
 
@@ -937,6 +1065,9 @@ export function connectSSE(
         if (data.client_id && onClientId) {
           onClientId(data.client_id);
         }
+      } else if (data.type === "resync") {
+        // Missed events can't be replayed (e.g. the server restarted).
+        onReconnect?.();
       } else if (data.type === "task" && data.action && data.task && onTask) {
         onTask(data.action, data.task);
       } else if (
@@ -948,8 +1079,12 @@ export function connectSSE(
         onBlock(data.action, data.block);
       } else if (data.type === "link" && data.action && data.link && onLink) {
         onLink(data.action, data.link);
+      } else if (data.type === "focus" && onFocus) {
+        onFocus(data.focus ?? { active: false });
       } else if (data.type === "activity" && data.event) {
         onActivity(data.event);
+      } else if (data.type === "delta" && data.content) {
+        onDelta?.(data.content);
       } else if (data.role && data.content) {
         onMessage({ role: data.role, content: data.content });
       }
@@ -12,6 +12,7 @@ import {
 import { taskStore } from "$lib/stores/task.svelte";
 import { blockStore } from "$lib/stores/block.svelte";
 import { linkStore } from "$lib/stores/link.svelte";
+import { focus } from "$lib/stores/focus.svelte";
 import { nowTimestamp } from "$lib/utils";
 
 export type TimelineItem =
@@ -23,6 +24,7 @@ export type TimelineItem =
       timestamp: string;
       media?: string[];
       queued?: boolean;
+      streaming?: boolean;
     }
   | ({ kind: "activity"; id: number } & ActivityEventData);
 
@@ -37,6 +39,8 @@ function createChat() {
   let loading = $state(false);
   let recording = $state(false);
   let pendingMedia = $state<string[]>([]);
+  // Recording behind the dictated input, sent along with the next message.
+  let pendingAudio = "";
   let dragging = $state(false);
   let expandedGroups = $state<Record<string, boolean>>({});
   let clientId: string | null = null;
@@ -45,6 +49,38 @@ function createChat() {
   let mediaStream = $state<MediaStream | null>(null);
   let onSend: (() => void) | null = null;
 
+  // The assistant reply being streamed. It is closed by the next activity
+  // event and replaced once the complete message arrives.
+  let draftId: number | null = null;
+  let draftOpen = false;
+
+  function removeDraft() {
+    if (draftId === null) return;
+    const id = draftId;
+    timeline = timeline.filter((item) => item.id !== id);
+    draftId = null;
+    draftOpen = false;
+  }
+
+  function addDelta(text: string) {
+    const draft = timeline.find((item) => item.id === draftId);
+    if (draftOpen && draft?.kind === "message") {
+      draft.content += text;
+      return;
+    }
+    removeDraft();
+    draftId = ++nextId;
+    draftOpen = true;
+    timeline.push({
+      kind: "message",
+      role: "assistant",
+      content: text,
+      timestamp: nowTimestamp(),
+      streaming: true,
+      id: draftId,
+    });
+  }
+
   function addMessage(msg: HistoryMessage) {
     if (!msg.content && (!msg.media || msg.media.length === 0)) return;
     timeline.push({
@@ -63,6 +99,7 @@ function createChat() {
         break;
       }
     }
+    draftOpen = false;
     timeline.push({ kind: "activity", ...evt, id: ++nextId });
   }
 
@@ -104,6 +141,7 @@ function createChat() {
     eventSource = connectSSE(
       (msg) => {
         loading = false;
+        if (msg.role === "assistant") removeDraft();
         addMessage(msg);
       },
       (evt) => {
@@ -128,6 +166,12 @@ function createChat() {
       (action, link) => {
         linkStore.applyEvent(action, link);
       },
+      (status) => {
+        focus.apply(status);
+      },
+      (text) => {
+        addDelta(text);
+      },
     );
   }
 
@@ -145,13 +189,15 @@ function createChat() {
       media: media.length > 0 ? media : undefined,
       queued: loading ? true : undefined,
     });
+    const audio = pendingAudio || undefined;
     input = "";
     pendingMedia = [];
+    pendingAudio = "";
     loading = true;
     onSend?.();
 
     try {
-      await sendMessage(content, media);
+      await sendMessage(content, media, audio);
     } catch {
       loading = false;
     }
@@ -191,10 +237,11 @@ function createChat() {
         transcribing = true;
         const shouldSend = sendAfterTranscribe;
         sendAfterTranscribe = false;
-        const text = await transcribeAudio(file);
+        const result = await transcribeAudio(file);
         transcribing = false;
         const prefix = inputBeforeRecording.trim();
-        const transcribed = text?.trim() ?? "";
+        const transcribed = result?.text.trim() ?? "";
+        if (result?.audio) pendingAudio = result.audio;
         if (prefix && transcribed) {
           input = prefix + " " + transcribed;
         } else {
@@ -235,6 +282,8 @@ function createChat() {
   }
 
   async function sync() {
+    draftId = null;
+    draftOpen = false;
     try {
       const history = await getHistory();
       timeline = history.items
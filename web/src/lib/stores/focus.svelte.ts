@@ -0,0 +1,54 @@
+import { getFocus, startFocus, endFocus, type FocusStatus } from "$lib/api";
+
+function createFocus() {
+  let status = $state<FocusStatus>({ active: false });
+
+  async function load() {
+    status = await getFocus();
+  }
+
+  async function start(minutes: number) {
+    const started = await startFocus(minutes);
+    if (started) status = started;
+  }
+
+  async function end() {
+    if (await endFocus()) status = { active: false };
+  }
+
+  // Prompts for a duration when off, ends focus when on.
+  async function toggle() {
+    if (status.active) {
+      await end();
+      return;
+    }
+    const answer = window.prompt("Focus for how many minutes?", "45");
+    const minutes = Number(answer);
+    if (answer && minutes > 0) await start(minutes);
+  }
+
+  function apply(next: FocusStatus) {
+    status = next;
+  }
+
+  return {
+    get status() {
+      return status;
+    },
+    get until() {
+      return status.until
+        ? new Date(status.until).toLocaleTimeString([], {
+            hour: "2-digit",
+            minute: "2-digit",
+          })
+        : "";
+    },
+    load,
+    start,
+    end,
+    toggle,
+    apply,
+  };
+}
+
+export const focus = createFocus();